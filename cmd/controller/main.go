@@ -15,10 +15,17 @@ limitations under the License.
 package main
 
 import (
+	"github.com/samber/lo"
+
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider/extension"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers"
 	"github.com/aws/karpenter-provider-aws/pkg/operator"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/debug"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
 
+	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/cloudprovider/metrics"
 	corecontrollers "sigs.k8s.io/karpenter/pkg/controllers"
 	coreoperator "sigs.k8s.io/karpenter/pkg/operator"
@@ -34,8 +41,19 @@ func main() {
 		op.GetClient(),
 		op.AMIProvider,
 		op.SecurityGroupProvider,
+		op.CarbonIntensityProvider,
+		op.VersionProvider,
 	)
-	cloudProvider := metrics.Decorate(awsCloudProvider)
+	var extendedCloudProvider corecloudprovider.CloudProvider = awsCloudProvider
+	if url := options.FromContext(ctx).OfferingsWebhookURL; url != "" {
+		extendedCloudProvider = extension.Decorate(extendedCloudProvider, op.GetClient(), op.Clock, webhook.NewDefaultProvider(url, options.FromContext(ctx).OfferingsWebhookTimeout))
+	}
+	cloudProvider := metrics.Decorate(extendedCloudProvider)
+	if token := options.FromContext(ctx).DebugEndpointToken; token != "" {
+		lo.Must0(op.AddMetricsServerExtraHandler(debug.InstanceTypesPath, debug.NewInstanceTypesHandler(token, op.GetClient(), cloudProvider)))
+		lo.Must0(op.AddMetricsServerExtraHandler(debug.SnapshotPath, debug.NewSnapshotHandler(token, op.Clock, op.GetClient(), cloudProvider, op.UnavailableOfferingsCache)))
+		lo.Must0(op.AddMetricsServerExtraHandler(debug.SimulatePath, debug.NewSimulateHandler(token, op.Clock, op.GetClient(), cloudProvider)))
+	}
 
 	op.
 		WithControllers(ctx, corecontrollers.NewControllers(
@@ -52,6 +70,7 @@ func main() {
 			op.Config,
 			op.Clock,
 			op.GetClient(),
+			op.KubernetesInterface,
 			op.EventRecorder,
 			op.UnavailableOfferingsCache,
 			op.SSMCache,
@@ -61,7 +80,17 @@ func main() {
 			op.InstanceProfileProvider,
 			op.InstanceProvider,
 			op.PricingProvider,
+			op.QuotaProvider,
+			op.CarbonIntensityProvider,
+			op.ElasticIPProvider,
+			op.LeakedResourceProvider,
+			op.KMSProvider,
+			op.CapacityReservationProvider,
+			op.VPCEndpointProvider,
+			op.AccessEntryProvider,
+			op.DiagnosticsProvider,
 			op.AMIProvider,
+			op.AMIProvenanceProvider,
 			op.LaunchTemplateProvider,
 			op.VersionProvider,
 			op.InstanceTypesProvider,
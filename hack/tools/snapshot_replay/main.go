@@ -0,0 +1,293 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider/fake"
+	"sigs.k8s.io/karpenter/pkg/controllers/disruption"
+	"sigs.k8s.io/karpenter/pkg/controllers/disruption/orchestration"
+	"sigs.k8s.io/karpenter/pkg/controllers/provisioning"
+	pscheduling "sigs.k8s.io/karpenter/pkg/controllers/provisioning/scheduling"
+	"sigs.k8s.io/karpenter/pkg/controllers/state"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/debug"
+)
+
+// snapshot_replay runs Karpenter's scheduler against a snapshot read from the /debug/karpenter/snapshot endpoint. It
+// reports the NodeClaims that would be launched for the snapshot's pending pods, and for each node, whether single-node
+// consolidation could delete or replace it.
+func main() {
+	path := flag.String("snapshot", "", "The snapshot archive read from the /debug/karpenter/snapshot endpoint")
+	mode := flag.String("mode", "all", "The simulations to run. Can be one of 'provisioning', 'consolidation' or 'all'")
+	flag.Parse()
+	if *path == "" {
+		log.Fatal("--snapshot is required")
+	}
+	f, err := os.Open(*path)
+	if err != nil {
+		log.Fatalf("opening snapshot, %s", err)
+	}
+	defer f.Close()
+	snapshot, err := debug.ReadSnapshot(f)
+	if err != nil {
+		log.Fatalf("reading snapshot, %s", err)
+	}
+	ctx := coreoptions.ToContext(context.Background(), coretest.Options())
+	fmt.Printf("Snapshot taken at %s with %d nodepools, %d nodes and %d pods\n\n", snapshot.Time, len(snapshot.NodePools), len(snapshot.Nodes), len(snapshot.Pods))
+	if *mode == "all" || *mode == "provisioning" {
+		if err = provisioningReport(ctx, snapshot); err != nil {
+			log.Fatalf("simulating provisioning, %s", err)
+		}
+	}
+	if *mode == "all" || *mode == "consolidation" {
+		if err = consolidationReport(ctx, snapshot); err != nil {
+			log.Fatalf("simulating consolidation, %s", err)
+		}
+	}
+}
+
+// cloudProvider serves the snapshot's instance types. The fake cloud provider only supports the test NodeClass, so the
+// supported NodeClasses are overridden for NodePools that reference EC2NodeClasses to be considered.
+type cloudProvider struct {
+	*fake.CloudProvider
+}
+
+func (c *cloudProvider) GetSupportedNodeClasses() []status.Object {
+	return []status.Object{&v1.EC2NodeClass{}}
+}
+
+// simulation is an in-memory copy of the snapshot's cluster that the core provisioner and disruption helpers run against.
+// Simulations are stateful, e.g. scheduling nominates nodes, so each report builds its own.
+type simulation struct {
+	kubeClient    client.Client
+	cluster       *state.Cluster
+	cloudProvider *cloudProvider
+	provisioner   *provisioning.Provisioner
+	recorder      events.Recorder
+	clk           *clock.FakeClock
+	instanceTypes map[string][]*cloudprovider.InstanceType
+}
+
+func newSimulation(ctx context.Context, snapshot *debug.Snapshot) (*simulation, error) {
+	var objects []client.Object
+	objects = append(objects, toObjects(snapshot.NodePools)...)
+	objects = append(objects, toObjects(snapshot.EC2NodeClasses)...)
+	objects = append(objects, toObjects(snapshot.NodeClaims)...)
+	objects = append(objects, toObjects(snapshot.Nodes)...)
+	objects = append(objects, toObjects(snapshot.Pods)...)
+	objects = append(objects, toObjects(snapshot.DaemonSets)...)
+	objects = append(objects, toObjects(snapshot.PodDisruptionBudgets)...)
+	objects = append(objects, toObjects(snapshot.PersistentVolumeClaims)...)
+	objects = append(objects, toObjects(snapshot.PersistentVolumes)...)
+	objects = append(objects, toObjects(snapshot.StorageClasses)...)
+	// The core helpers list objects by the same fields that Karpenter indexes in its cache
+	kubeClient := fakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(objects...).
+		WithIndex(&corev1.Pod{}, "spec.nodeName", func(o client.Object) []string { return []string{o.(*corev1.Pod).Spec.NodeName} }).
+		WithIndex(&corev1.Node{}, "spec.providerID", func(o client.Object) []string { return []string{o.(*corev1.Node).Spec.ProviderID} }).
+		WithIndex(&karpv1.NodeClaim{}, "status.providerID", func(o client.Object) []string {
+			return []string{o.(*karpv1.NodeClaim).Status.ProviderID}
+		}).
+		WithIndex(&storagev1.VolumeAttachment{}, "spec.nodeName", func(o client.Object) []string {
+			return []string{o.(*storagev1.VolumeAttachment).Spec.NodeName}
+		}).
+		Build()
+
+	s := &simulation{
+		kubeClient:    kubeClient,
+		cloudProvider: &cloudProvider{CloudProvider: fake.NewCloudProvider()},
+		recorder:      events.NewRecorder(&record.FakeRecorder{}),
+		clk:           clock.NewFakeClock(snapshot.Time),
+		instanceTypes: lo.MapValues(snapshot.InstanceTypes, func(its []debug.SnapshotInstanceType, _ string) []*cloudprovider.InstanceType {
+			return lo.Map(its, func(it debug.SnapshotInstanceType, _ int) *cloudprovider.InstanceType { return it.InstanceType() })
+		}),
+	}
+	s.cloudProvider.InstanceTypesForNodePool = s.instanceTypes
+	// NodePools whose instance types couldn't be resolved when the snapshot was taken are skipped, as they are when scheduling
+	s.cloudProvider.ErrorsForNodePool = lo.SliceToMap(lo.Filter(snapshot.NodePools, func(np karpv1.NodePool, _ int) bool {
+		_, ok := snapshot.InstanceTypes[np.Name]
+		return !ok
+	}), func(np karpv1.NodePool) (string, error) {
+		return np.Name, fmt.Errorf("instance types weren't resolved in the snapshot")
+	})
+	s.cluster = state.NewCluster(s.clk, kubeClient, s.cloudProvider)
+	for i := range snapshot.NodeClaims {
+		s.cluster.UpdateNodeClaim(&snapshot.NodeClaims[i])
+	}
+	for i := range snapshot.Nodes {
+		if err := s.cluster.UpdateNode(ctx, &snapshot.Nodes[i]); err != nil {
+			return nil, fmt.Errorf("tracking node %s, %w", snapshot.Nodes[i].Name, err)
+		}
+	}
+	for i := range snapshot.Pods {
+		if err := s.cluster.UpdatePod(ctx, &snapshot.Pods[i]); err != nil {
+			return nil, fmt.Errorf("tracking pod %s, %w", client.ObjectKeyFromObject(&snapshot.Pods[i]), err)
+		}
+	}
+	for i := range snapshot.DaemonSets {
+		if err := s.cluster.UpdateDaemonSet(ctx, &snapshot.DaemonSets[i]); err != nil {
+			return nil, fmt.Errorf("tracking daemonset %s, %w", client.ObjectKeyFromObject(&snapshot.DaemonSets[i]), err)
+		}
+	}
+	s.provisioner = provisioning.NewProvisioner(kubeClient, s.recorder, s.cloudProvider, s.cluster, s.clk)
+	return s, nil
+}
+
+func provisioningReport(ctx context.Context, snapshot *debug.Snapshot) error {
+	s, err := newSimulation(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+	results, err := s.provisioner.Schedule(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Provisioning")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODEPOOL\tPODS\tINSTANCE TYPES\tCHEAPEST PRICE")
+	for _, nc := range results.NewNodeClaims {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", nc.NodePoolName, len(nc.Pods), instanceTypeNames(nc.InstanceTypeOptions), formatPrice(cheapestPrice(nc)))
+	}
+	for _, n := range results.ExistingNodes {
+		if len(n.Pods) > 0 {
+			fmt.Fprintf(w, "%s\t%d\t(existing node %s)\t\n", n.Labels()[karpv1.NodePoolLabelKey], len(n.Pods), n.Name())
+		}
+	}
+	_ = w.Flush()
+	for pod, err := range results.PodErrors {
+		fmt.Printf("pod %s can't be scheduled, %s\n", client.ObjectKeyFromObject(pod), err)
+	}
+	fmt.Println()
+	return nil
+}
+
+func consolidationReport(ctx context.Context, snapshot *debug.Snapshot) error {
+	s, err := newSimulation(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+	queue := orchestration.NewQueue(s.kubeClient, s.recorder, s.cluster, s.clk, s.provisioner)
+	candidates, err := disruption.GetCandidates(ctx, s.cluster, s.kubeClient, s.recorder, s.clk, s.cloudProvider,
+		func(context.Context, *disruption.Candidate) bool { return true }, disruption.GracefulDisruptionClass, queue)
+	if err != nil {
+		return fmt.Errorf("getting candidates, %w", err)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Name() < candidates[j].Name() })
+	fmt.Println("Single-node consolidation")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tNODEPOOL\tPRICE\tDECISION\tREPLACEMENT PRICE\tDETAILS")
+	for _, c := range candidates {
+		price := s.price(c)
+		results, err := disruption.SimulateScheduling(ctx, s.kubeClient, s.cluster, s.provisioner, c)
+		decision, replacementPrice, details := "none", "", ""
+		switch {
+		case err != nil:
+			details = err.Error()
+		case !results.AllNonPendingPodsScheduled():
+			details = results.NonPendingPodSchedulingErrors()
+		case len(results.NewNodeClaims) == 0:
+			decision = "delete"
+		case len(results.NewNodeClaims) == 1:
+			replacement := cheapestPrice(results.NewNodeClaims[0])
+			replacementPrice = formatPrice(replacement)
+			if replacement < price {
+				decision, details = "replace", instanceTypeNames(results.NewNodeClaims[0].InstanceTypeOptions)
+			} else {
+				details = "no cheaper replacement"
+			}
+		default:
+			details = fmt.Sprintf("pods would need %d new nodeclaims", len(results.NewNodeClaims))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", c.Name(), c.Labels()[karpv1.NodePoolLabelKey], formatPrice(price), decision, replacementPrice, details)
+	}
+	_ = w.Flush()
+	fmt.Println()
+	return nil
+}
+
+// price returns the price of the candidate's offering, or zero if the offering isn't in the snapshot
+func (s *simulation) price(c *disruption.Candidate) float64 {
+	it, ok := lo.Find(s.instanceTypes[c.Labels()[karpv1.NodePoolLabelKey]], func(it *cloudprovider.InstanceType) bool {
+		return it.Name == c.Labels()[corev1.LabelInstanceTypeStable]
+	})
+	if !ok {
+		return 0
+	}
+	offerings := it.Offerings.Compatible(scheduling.NewLabelRequirements(lo.PickByKeys(c.Labels(), []string{
+		corev1.LabelTopologyZone,
+		karpv1.CapacityTypeLabelKey,
+	})))
+	if len(offerings) == 0 {
+		return 0
+	}
+	return offerings.Cheapest().Price
+}
+
+func cheapestPrice(nc *pscheduling.NodeClaim) float64 {
+	prices := lo.FilterMap(nc.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) (float64, bool) {
+		offerings := it.Offerings.Available().Compatible(nc.Requirements)
+		if len(offerings) == 0 {
+			return 0, false
+		}
+		return offerings.Cheapest().Price, true
+	})
+	return lo.Min(prices)
+}
+
+func instanceTypeNames(instanceTypes []*cloudprovider.InstanceType) string {
+	names := lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) string { return it.Name })
+	if len(names) > 5 {
+		return fmt.Sprintf("%s and %d more", strings.Join(names[:5], ", "), len(names)-5)
+	}
+	return strings.Join(names, ", ")
+}
+
+func formatPrice(price float64) string {
+	return fmt.Sprintf("$%.4f/hr", price)
+}
+
+func toObjects[T any, PT interface {
+	*T
+	client.Object
+}](items []T) []client.Object {
+	return lo.Map(items, func(_ T, i int) client.Object { return PT(&items[i]) })
+}
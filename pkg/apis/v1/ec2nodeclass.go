@@ -19,8 +19,10 @@ import (
 	"log"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -46,6 +48,13 @@ type EC2NodeClassSpec struct {
 	// AssociatePublicIPAddress controls if public IP addresses are assigned to instances that are launched with the nodeclass.
 	// +optional
 	AssociatePublicIPAddress *bool `json:"associatePublicIPAddress,omitempty"`
+	// ElasticIPSelectorTerms is a list of or Elastic IP selector terms. The terms are ORed. When set, each instance launched
+	// with the nodeclass is associated with one of the unassociated Elastic IPs that the terms select once it's running.
+	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id']",rule="self.all(x, has(x.tags) || has(x.id))"
+	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in elasticIPSelectorTerms",rule="!self.all(x, has(x.id) && has(x.tags))"
+	// +kubebuilder:validation:MaxItems:=30
+	// +optional
+	ElasticIPSelectorTerms []ElasticIPSelectorTerm `json:"elasticIPSelectorTerms,omitempty"`
 	// AMISelectorTerms is a list of or ami selector terms. The terms are ORed.
 	// +kubebuilder:validation:XValidation:message="expected at least one, got none, ['tags', 'id', 'name', 'alias']",rule="self.all(x, has(x.tags) || has(x.id) || has(x.name) || has(x.alias))"
 	// +kubebuilder:validation:XValidation:message="'id' is mutually exclusive, cannot be set with a combination of other fields in amiSelectorTerms",rule="!self.exists(x, has(x.id) && (has(x.alias) || has(x.tags) || has(x.name) || has(x.owner)))"
@@ -55,6 +64,17 @@ type EC2NodeClassSpec struct {
 	// +kubebuilder:validation:MaxItems:=30
 	// +required
 	AMISelectorTerms []AMISelectorTerm `json:"amiSelectorTerms" hash:"ignore"`
+	// AMIRolloutPolicy controls how nodes are drifted when the AMIs resolved by amiSelectorTerms change. If not set, every
+	// node running a previous AMI is drifted as soon as the new AMIs are resolved.
+	// +optional
+	AMIRolloutPolicy *AMIRolloutPolicy `json:"amiRolloutPolicy,omitempty" hash:"ignore"`
+	// AMIMaxAge is the maximum age, measured from their creation date, of the AMIs that nodes are launched with. Older AMIs
+	// resolved by amiSelectorTerms are excluded, so nodes that are running them are drifted. If every resolved AMI is older,
+	// the EC2NodeClass isn't ready until a newer AMI is selected.
+	// +kubebuilder:validation:Pattern:="^([0-9]+(s|m|h))+$"
+	// +kubebuilder:validation:Type="string"
+	// +optional
+	AMIMaxAge *metav1.Duration `json:"amiMaxAge,omitempty" hash:"ignore"`
 	// AMIFamily dictates the UserData format and default BlockDeviceMappings used when generating launch templates.
 	// This field is optional when using an alias amiSelectorTerm, and the value will be inferred from the alias'
 	// family. When an alias is specified, this field may only be set to its corresponding family or 'Custom'. If no
@@ -69,20 +89,23 @@ type EC2NodeClassSpec struct {
 	// this UserData to ensure nodes are being provisioned with the correct configuration.
 	// +optional
 	UserData *string `json:"userData,omitempty"`
-	// Role is the AWS identity that nodes use. This field is immutable.
+	// Role is the AWS identity that nodes use, specified as a role name or an IAM role ARN in any partition. This field is immutable.
 	// This field is mutually exclusive from instanceProfile.
 	// Marking this field as immutable avoids concerns around terminating managed instance profiles from running instances.
 	// This field may be made mutable in the future, assuming the correct garbage collection and drift handling is implemented
 	// for the old instance profiles on an update.
 	// +kubebuilder:validation:XValidation:rule="self != ''",message="role cannot be empty"
+	// +kubebuilder:validation:XValidation:rule="!self.startsWith('arn:') || self.matches('^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+$')",message="expected a role name or IAM role ARN"
 	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="immutable field changed"
 	// +optional
 	Role string `json:"role,omitempty"`
-	// InstanceProfile is the AWS entity that instances use.
+	// InstanceProfile is the AWS entity that instances use, specified as an instance profile name or an IAM instance profile
+	// ARN in any partition.
 	// This field is mutually exclusive from role.
 	// The instance profile should already have a role assigned to it that Karpenter
 	//  has PassRole permission on for instance launch using this instanceProfile to succeed.
 	// +kubebuilder:validation:XValidation:rule="self != ''",message="instanceProfile cannot be empty"
+	// +kubebuilder:validation:XValidation:rule="!self.startsWith('arn:') || self.matches('^arn:aws[a-z-]*:iam::[0-9]{12}:instance-profile/.+$')",message="expected an instance profile name or IAM instance profile ARN"
 	// +optional
 	InstanceProfile *string `json:"instanceProfile,omitempty"`
 	// Tags to be applied on ec2 resources like instances and launch templates.
@@ -94,6 +117,9 @@ type EC2NodeClassSpec struct {
 	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.k8s.aws/ec2nodeclass",rule="self.all(k, k !='karpenter.k8s.aws/ec2nodeclass')"
 	// +optional
 	Tags map[string]string `json:"tags,omitempty"`
+	// Licensing attaches License Manager license configurations and compliance tags to the instances that are launched.
+	// +optional
+	Licensing *Licensing `json:"licensing,omitempty"`
 	// Kubelet defines args to be used when configuring kubelet on provisioned nodes.
 	// They are a subset of the upstream types, recognizing not all options may be supported.
 	// Wherever possible, the types and names should reflect the upstream kubelet types.
@@ -104,6 +130,7 @@ type EC2NodeClassSpec struct {
 	Kubelet *KubeletConfiguration `json:"kubelet,omitempty"`
 	// BlockDeviceMappings to be applied to provisioned nodes.
 	// +kubebuilder:validation:XValidation:message="must have only one blockDeviceMappings with rootVolume",rule="self.filter(x, has(x.rootVolume)?x.rootVolume==true:false).size() <= 1"
+	// +kubebuilder:validation:XValidation:message="maxVolumeSize can only be set on the rootVolume",rule="self.all(x, !has(x.ebs) || !has(x.ebs.maxVolumeSize) || (has(x.rootVolume) && x.rootVolume))"
 	// +kubebuilder:validation:MaxItems:=50
 	// +optional
 	BlockDeviceMappings []*BlockDeviceMapping `json:"blockDeviceMappings,omitempty"`
@@ -113,6 +140,67 @@ type EC2NodeClassSpec struct {
 	// DetailedMonitoring controls if detailed monitoring is enabled for instances that are launched
 	// +optional
 	DetailedMonitoring *bool `json:"detailedMonitoring,omitempty"`
+	// EnclaveOptions configures AWS Nitro Enclaves for launched instances. When enclaves are enabled, only instance types
+	// which support Nitro Enclaves are launched.
+	// +optional
+	EnclaveOptions *EnclaveOptions `json:"enclaveOptions,omitempty"`
+	// CPUOptions configures the processors of launched instances. Instance types which don't support the requested core
+	// count, threads per core or AMD SEV-SNP are not launched. The advertised CPU capacity of launched nodes reflects the
+	// customized core count and threads per core.
+	// +optional
+	CPUOptions *CPUOptions `json:"cpuOptions,omitempty"`
+	// CreditSpecification configures the CPU credit option of burstable performance (T family) instances and how their CPU
+	// capacity is advertised. It has no effect on instance types that aren't burstable.
+	// +optional
+	CreditSpecification *CreditSpecification `json:"creditSpecification,omitempty"`
+	// PrivateDNSNameOptions configures the hostnames of launched instances and the DNS records that resolve them. Nodes are
+	// named after the private DNS names of their instances, so "resource-name" hostnames name nodes after their instance ID.
+	// +optional
+	PrivateDNSNameOptions *PrivateDNSNameOptions `json:"privateDNSNameOptions,omitempty"`
+	// ZoneSpreadPolicy controls how Karpenter balances the zones of the capacity that it launches for a NodePool using this
+	// EC2NodeClass. When set, launches are steered towards the zones allowed by the NodeClaim that currently have the fewest
+	// nodes in the NodePool, rather than whichever zone is cheapest. "Strict" fails the launch if capacity can't be found
+	// in one of those zones, while "Preferred" falls back to the remaining zones.
+	// +optional
+	ZoneSpreadPolicy *ZoneSpreadPolicy `json:"zoneSpreadPolicy,omitempty" hash:"ignore"`
+	// PreTermination configures commands that Karpenter runs on instances through SSM Run Command once their node has been
+	// drained, before the instance is terminated. Instances must be managed by SSM for the commands to run.
+	// +optional
+	PreTermination *PreTermination `json:"preTermination,omitempty" hash:"ignore"`
+	// CapacityBlock launches instances into an EC2 Capacity Block for ML. Instances are only launched while the block is
+	// active and are restricted to the block's instance type and zone. Their NodeClaims are deleted ahead of the block's
+	// end time so that nodes are drained before EC2 reclaims the capacity.
+	// +optional
+	CapacityBlock *CapacityBlock `json:"capacityBlock,omitempty" hash:"ignore"`
+	// GPUPartitioning shares the NVIDIA GPUs of launched instances between pods, either by partitioning GPUs that support
+	// Multi-Instance GPU (MIG) or by time-slicing them. Instance types advertise the resources that the NVIDIA device plugin
+	// exposes once the GPUs have been partitioned, rather than whole GPUs.
+	// +kubebuilder:validation:XValidation:message="must specify exactly one of ['mig', 'timeSlicingReplicas']",rule="has(self.mig) != has(self.timeSlicingReplicas)"
+	// +optional
+	GPUPartitioning *GPUPartitioning `json:"gpuPartitioning,omitempty"`
+	// ExtendedResources are resources, such as those advertised by a device plugin, that every node launched with this
+	// EC2NodeClass provides in addition to the resources discovered from its instance type. Instance types advertise them
+	// as capacity so that pods requesting them trigger provisioning, replacing any discovered resource with the same name.
+	// Karpenter doesn't make the resources available on the node, which is left to the device plugin.
+	// +kubebuilder:validation:XValidation:message="extended resource names must be fully qualified and outside of the kubernetes.io domain",rule="self.all(k, k.matches('^[^/]+/[^/]+$') && !k.matches('^([^/]*[.])?kubernetes[.]io/') && !k.startsWith('requests.'))"
+	// +optional
+	ExtendedResources corev1.ResourceList `json:"extendedResources,omitempty" hash:"ignore"`
+	// Proxy configures the HTTPS proxy that kubelet and containerd connect through on launched instances, along with any
+	// additional certificate authorities that they trust. It is applied through the UserData generated for every AMI
+	// family other than Custom.
+	// +optional
+	Proxy *Proxy `json:"proxy,omitempty"`
+	// LaunchRole is an IAM role that Karpenter assumes to launch instances for this EC2NodeClass, so that launches are
+	// attributed in CloudTrail to a role session that is tagged with the NodePool and EC2NodeClass of the NodeClaim.
+	// Karpenter's own credentials are used for every other request.
+	// +optional
+	LaunchRole *LaunchRole `json:"launchRole,omitempty" hash:"ignore"`
+	// KeyName is the name of an EC2 key pair that is injected into launched instances for break-glass SSH access. Clusters
+	// may forbid key pairs entirely, in which case an EC2NodeClass that sets KeyName doesn't become ready.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=255
+	// +optional
+	KeyName *string `json:"keyName,omitempty"`
 	// MetadataOptions for the generated launch template of provisioned nodes.
 	//
 	// This specifies the exposure of the Instance Metadata Service to
@@ -136,6 +224,153 @@ type EC2NodeClassSpec struct {
 	Context *string `json:"context,omitempty"`
 }
 
+// PreTermination defines the commands which are run on an instance between the drain of its node and its termination
+type PreTermination struct {
+	// Commands are run in order with the AWS-RunShellScript SSM document, or AWS-RunPowerShellScript for Windows AMI families.
+	// +kubebuilder:validation:MinItems:=1
+	// +kubebuilder:validation:MaxItems:=20
+	// +required
+	Commands []string `json:"commands"`
+	// Timeout is how long the commands may run before SSM stops them. The instance is terminated once the commands complete,
+	// fail, or time out.
+	// +kubebuilder:validation:Pattern:="^([0-9]+(s|m|h))+$"
+	// +kubebuilder:validation:Type="string"
+	// +kubebuilder:default:="5m"
+	// +optional
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+}
+
+// CapacityBlock references the EC2 Capacity Block for ML that instances are launched into
+type CapacityBlock struct {
+	// ID of the Capacity Block's capacity reservation
+	// +kubebuilder:validation:Pattern:="^cr-[0-9a-z]+$"
+	// +required
+	ID string `json:"id"`
+	// DrainBefore is how long before EC2 reclaims the Capacity Block's instances that Karpenter begins terminating their
+	// NodeClaims. Nodes are drained until the instances are reclaimed, at which point any remaining pods are forcibly removed.
+	// +kubebuilder:validation:Pattern:="^([0-9]+(s|m|h))+$"
+	// +kubebuilder:validation:Type="string"
+	// +kubebuilder:default:="30m"
+	// +optional
+	DrainBefore metav1.Duration `json:"drainBefore,omitempty"`
+}
+
+// GPUPartitioning defines how NVIDIA GPUs are shared between pods
+type GPUPartitioning struct {
+	// MIG partitions every GPU that supports Multi-Instance GPU into GPU instances of a single profile. Karpenter labels
+	// nodes with nvidia.com/mig.config so that the NVIDIA GPU Operator's MIG manager applies the partitioning. GPUs that
+	// don't support the profile are advertised as whole GPUs.
+	// +optional
+	MIG *MIGPartitioning `json:"mig,omitempty"`
+	// TimeSlicingReplicas is the number of nvidia.com/gpu replicas that each GPU is advertised as. The NVIDIA device plugin
+	// must be configured with the same number of time-slicing replicas.
+	// +kubebuilder:validation:Minimum:=2
+	// +kubebuilder:validation:Maximum:=64
+	// +optional
+	TimeSlicingReplicas *int32 `json:"timeSlicingReplicas,omitempty"`
+}
+
+// MIGPartitioning defines the Multi-Instance GPU profile that GPUs are partitioned into
+type MIGPartitioning struct {
+	// Profile of the GPU instances that each GPU is partitioned into, e.g. 1g.10gb
+	// +kubebuilder:validation:Pattern:="^[1-7]g\\.[0-9]+gb$"
+	// +required
+	Profile string `json:"profile"`
+	// Strategy is the MIG strategy that the NVIDIA device plugin is configured with. The "single" strategy exposes GPU
+	// instances as nvidia.com/gpu, while the "mixed" strategy exposes them as nvidia.com/mig-<profile>.
+	// +kubebuilder:default:="single"
+	// +optional
+	Strategy MIGStrategy `json:"strategy,omitempty"`
+}
+
+// MIGStrategy enumerates the strategies that the NVIDIA device plugin uses to expose MIG devices
+// +kubebuilder:validation:Enum={single,mixed}
+type MIGStrategy string
+
+const (
+	MIGStrategySingle MIGStrategy = "single"
+	MIGStrategyMixed  MIGStrategy = "mixed"
+)
+
+// Proxy defines the proxy settings and trusted certificate authorities of launched instances
+type Proxy struct {
+	// HTTPSProxy is the URL of the proxy that HTTPS requests are sent through, e.g. http://proxy.example.com:3128
+	// +kubebuilder:validation:Pattern:="^https?://"
+	// +optional
+	HTTPSProxy *string `json:"httpsProxy,omitempty"`
+	// NoProxy lists the hosts, domains and CIDRs that are connected to directly rather than through the proxy. The instance
+	// metadata service and localhost are always connected to directly, as is the cluster's service CIDR when it is known.
+	// +kubebuilder:validation:MaxItems:=100
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+	// CABundle is a base64 encoded bundle of PEM certificate authorities that are trusted in addition to the instance's
+	// system certificate authorities, e.g. those of a TLS intercepting proxy
+	// +optional
+	CABundle *string `json:"caBundle,omitempty"`
+}
+
+// LaunchRole defines the role that Karpenter assumes to launch instances along with the attributes of its role sessions
+type LaunchRole struct {
+	// ARN of the role. Karpenter must be allowed to call sts:AssumeRole, sts:TagSession and, if a source identity is set,
+	// sts:SetSourceIdentity on the role.
+	// +kubebuilder:validation:Pattern:="^arn:aws[a-z-]*:iam::[0-9]{12}:role/.+$"
+	// +required
+	ARN string `json:"arn"`
+	// SessionTags are STS session tags that are passed when the role is assumed, e.g. to attribute launches to a team. The
+	// karpenter.sh/nodepool and karpenter.k8s.aws/ec2nodeclass session tags are always passed.
+	// +kubebuilder:validation:XValidation:message="session tag keys must be at most 128 characters and values at most 256 characters",rule="self.all(k, size(k) <= 128 && size(self[k]) <= 256)"
+	// +kubebuilder:validation:XValidation:message="session tag contains a restricted tag matching karpenter.sh/nodepool",rule="self.all(k, k != 'karpenter.sh/nodepool')"
+	// +kubebuilder:validation:XValidation:message="session tag contains a restricted tag matching karpenter.k8s.aws/ec2nodeclass",rule="self.all(k, k != 'karpenter.k8s.aws/ec2nodeclass')"
+	// +kubebuilder:validation:MaxProperties:=48
+	// +optional
+	SessionTags map[string]string `json:"sessionTags,omitempty"`
+	// SourceIdentity is set on the role sessions and is recorded in CloudTrail for every request made with them. Unlike a
+	// session tag, it can't be changed by roles that are assumed from the session.
+	// +kubebuilder:validation:Pattern:="^[A-Za-z0-9+=,.@-]{2,64}$"
+	// +optional
+	SourceIdentity *string `json:"sourceIdentity,omitempty"`
+}
+
+// AMIRolloutPolicy defines a canary rollout for AMI changes. When the resolved AMIs change, only a subset of the nodes using
+// the EC2NodeClass are drifted at first. The remaining nodes are drifted once the canary nodes running the new AMIs have
+// stayed Ready for the canary duration.
+type AMIRolloutPolicy struct {
+	// Canary is the number or percentage of the EC2NodeClass's nodes that are drifted first when the resolved AMIs change.
+	// Percentages are rounded up, so that at least one node is drifted.
+	// +kubebuilder:validation:Pattern:="^((100|[0-9]{1,2})%|[0-9]+)$"
+	// +kubebuilder:default:="10%"
+	// +optional
+	Canary string `json:"canary,omitempty"`
+	// CanaryDuration is how long the canary nodes running the new AMIs must remain Ready before the rest of the nodes are drifted.
+	// +kubebuilder:validation:Pattern:="^([0-9]+(s|m|h))+$"
+	// +kubebuilder:validation:Type="string"
+	// +kubebuilder:default:="10m"
+	// +optional
+	CanaryDuration metav1.Duration `json:"canaryDuration,omitempty"`
+}
+
+// Licensing configures how instances are tracked by AWS License Manager.
+type Licensing struct {
+	// LicenseConfigurationARNs are the ARNs of the License Manager license configurations that launched instances are
+	// associated with. If any of the license configurations enforces a hard limit that has been reached, the launch fails.
+	// +kubebuilder:validation:XValidation:message="expected a license configuration ARN",rule="self.all(arn, arn.matches('^arn:aws[a-z-]*:license-manager:[a-z0-9-]+:[0-9]{12}:license-configuration:lic-[0-9a-f]+$'))"
+	// +kubebuilder:validation:MinItems:=1
+	// +kubebuilder:validation:MaxItems:=10
+	// +required
+	LicenseConfigurationARNs []string `json:"licenseConfigurationARNs"`
+	// ComplianceTags are applied to every resource that is tagged with spec.tags. They take precedence over spec.tags so that
+	// tags required by license rules or tagging policies can't be overridden, and may not use any of the tag keys reserved by Karpenter.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching eks:eks-cluster-name",rule="self.all(k, k !='eks:eks-cluster-name')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching kubernetes.io/cluster/",rule="self.all(k, !k.startsWith('kubernetes.io/cluster') )"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodepool",rule="self.all(k, k != 'karpenter.sh/nodepool')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.sh/nodeclaim",rule="self.all(k, k !='karpenter.sh/nodeclaim')"
+	// +kubebuilder:validation:XValidation:message="tag contains a restricted tag matching karpenter.k8s.aws/ec2nodeclass",rule="self.all(k, k !='karpenter.k8s.aws/ec2nodeclass')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	ComplianceTags map[string]string `json:"complianceTags,omitempty"`
+}
+
 // SubnetSelectorTerm defines selection logic for a subnet used by Karpenter to launch nodes.
 // If multiple fields are used for selection, the requirements are ANDed.
 type SubnetSelectorTerm struct {
@@ -151,6 +386,21 @@ type SubnetSelectorTerm struct {
 	ID string `json:"id,omitempty"`
 }
 
+// ElasticIPSelectorTerm defines selection logic for the Elastic IPs that Karpenter associates with launched instances.
+// If multiple fields are used for selection, the requirements are ANDed.
+type ElasticIPSelectorTerm struct {
+	// Tags is a map of key/value tags used to select Elastic IPs
+	// Specifying '*' for a value selects all values for a given tag key.
+	// +kubebuilder:validation:XValidation:message="empty tag keys or values aren't supported",rule="self.all(k, k != '' && self[k] != '')"
+	// +kubebuilder:validation:MaxProperties:=20
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+	// ID is the allocation id of the Elastic IP in EC2
+	// +kubebuilder:validation:Pattern="eipalloc-[0-9a-z]+"
+	// +optional
+	ID string `json:"id,omitempty"`
+}
+
 // SecurityGroupSelectorTerm defines selection logic for a security group used by Karpenter to launch nodes.
 // If multiple fields are used for selection, the requirements are ANDed.
 type SecurityGroupSelectorTerm struct {
@@ -235,6 +485,12 @@ type KubeletConfiguration struct {
 	// +kubebuilder:validation:XValidation:message="kubeReserved value cannot be a negative resource quantity",rule="self.all(x, !self[x].startsWith('-'))"
 	// +optional
 	KubeReserved map[string]string `json:"kubeReserved,omitempty"`
+	// AutoSystemReserved computes systemReserved from the size of each instance type, and passes it to the kubelet
+	// along with the kubeReserved that Karpenter computes for the instance type, so that every AMI family reserves the
+	// same resources that Karpenter uses to compute allocatable. Values set in systemReserved or kubeReserved take
+	// precedence over the computed values.
+	// +optional
+	AutoSystemReserved *AutoSystemReserved `json:"autoSystemReserved,omitempty"`
 	// EvictionHard is the map of signal names to quantities that define hard eviction thresholds
 	// +kubebuilder:validation:XValidation:message="valid keys for evictionHard are ['memory.available','nodefs.available','nodefs.inodesFree','imagefs.available','imagefs.inodesFree','pid.available']",rule="self.all(x, x in ['memory.available','nodefs.available','nodefs.inodesFree','imagefs.available','imagefs.inodesFree','pid.available'])"
 	// +optional
@@ -273,6 +529,81 @@ type KubeletConfiguration struct {
 	CPUCFSQuota *bool `json:"cpuCFSQuota,omitempty"`
 }
 
+// AutoSystemReserved is the formula used to compute the resources reserved for OS system daemons on each instance type
+type AutoSystemReserved struct {
+	// CPUMillicoresPerVCPU is the CPU reserved for each vCPU of the instance type, in millicores. Defaults to 10.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=500
+	// +optional
+	CPUMillicoresPerVCPU *int32 `json:"cpuMillicoresPerVCPU,omitempty"`
+	// MemoryPercent is the percentage of the instance type's memory that is reserved in addition to a fixed 100Mi.
+	// Defaults to 2.
+	// +kubebuilder:validation:Minimum:=0
+	// +kubebuilder:validation:Maximum:=50
+	// +optional
+	MemoryPercent *int32 `json:"memoryPercent,omitempty"`
+}
+
+// EnclaveOptions configures AWS Nitro Enclaves for launched instances
+type EnclaveOptions struct {
+	// Enabled launches instances with Nitro Enclaves enabled, so that an isolated enclave can be carved out of each
+	// instance's CPU and memory.
+	// +required
+	Enabled bool `json:"enabled"`
+}
+
+// CPUOptions configures the processor features of launched instances
+type CPUOptions struct {
+	// CoreCount is the number of CPU cores that are enabled on launched instances, for example to reduce the number of
+	// cores that software is licensed for. Only instance types that support the core count are launched.
+	// +kubebuilder:validation:Minimum:=1
+	// +optional
+	CoreCount *int32 `json:"coreCount,omitempty"`
+	// ThreadsPerCore is the number of threads that run on each CPU core. Setting it to 1 disables simultaneous multithreading.
+	// Only instance types that support the number of threads per core are launched.
+	// +kubebuilder:validation:Minimum:=1
+	// +kubebuilder:validation:Maximum:=2
+	// +optional
+	ThreadsPerCore *int32 `json:"threadsPerCore,omitempty"`
+	// AMDSEVSNP controls whether AMD SEV-SNP is enabled for launched instances, encrypting and integrity protecting their
+	// memory. AMD SEV-SNP requires an AMI that boots with UEFI.
+	// +kubebuilder:validation:Enum:={enabled,disabled}
+	// +optional
+	AMDSEVSNP *string `json:"amdSevSnp,omitempty"`
+}
+
+// CreditSpecification configures burstable performance instances
+type CreditSpecification struct {
+	// CPUCredits is the credit option for CPU usage of burstable performance instances. "standard" instances are throttled
+	// to their baseline once their accrued credits are spent, while "unlimited" instances can burst indefinitely at an
+	// additional charge. If unset, the default credit option of the instance family is used.
+	// +kubebuilder:validation:Enum:={standard,unlimited}
+	// +optional
+	CPUCredits *string `json:"cpuCredits,omitempty"`
+	// BaselineCapacity advertises the CPU capacity of burstable instance types at their baseline performance rather than
+	// their vCPU count, so that pods requesting sustained CPU aren't packed onto instances that can only burst to it.
+	// +optional
+	BaselineCapacity *bool `json:"baselineCapacity,omitempty" hash:"ignore"`
+}
+
+// PrivateDNSNameOptions configures the hostnames of launched instances
+type PrivateDNSNameOptions struct {
+	// HostnameType is the type of hostname of launched instances. "ip-name" hostnames are derived from the instance's
+	// private IPv4 address, e.g. ip-10-0-0-1.ec2.internal, while "resource-name" hostnames are derived from its instance ID,
+	// e.g. i-0123456789abcdef0.ec2.internal. Instances launched into IPv6-only subnets require "resource-name". If unset,
+	// the hostname type of the subnet is used.
+	// +kubebuilder:validation:Enum:={ip-name,resource-name}
+	// +optional
+	HostnameType *string `json:"hostnameType,omitempty"`
+	// EnableResourceNameDNSARecord responds to DNS queries for "resource-name" hostnames with the instance's IPv4 address
+	// +optional
+	EnableResourceNameDNSARecord *bool `json:"enableResourceNameDNSARecord,omitempty"`
+	// EnableResourceNameDNSAAAARecord responds to DNS queries for "resource-name" hostnames with the instance's IPv6
+	// address. The instance must be launched into a subnet with an IPv6 CIDR block.
+	// +optional
+	EnableResourceNameDNSAAAARecord *bool `json:"enableResourceNameDNSAAAARecord,omitempty"`
+}
+
 // MetadataOptions contains parameters for specifying the exposure of the
 // Instance Metadata Service to provisioned EC2 nodes.
 type MetadataOptions struct {
@@ -329,6 +660,7 @@ type BlockDeviceMapping struct {
 	DeviceName *string `json:"deviceName,omitempty"`
 	// EBS contains parameters used to automatically set up EBS volumes when an instance is launched.
 	// +kubebuilder:validation:XValidation:message="snapshotID or volumeSize must be defined",rule="has(self.snapshotID) || has(self.volumeSize)"
+	// +kubebuilder:validation:XValidation:message="maxVolumeSize requires volumeSize to be defined",rule="!has(self.maxVolumeSize) || has(self.volumeSize)"
 	// +optional
 	EBS *BlockDevice `json:"ebs,omitempty"`
 	// RootVolume is a flag indicating if this device is mounted as kubelet root dir. You can
@@ -367,7 +699,8 @@ type BlockDevice struct {
 	// is not supported for gp2, st1, sc1, or standard volumes.
 	// +optional
 	IOPS *int64 `json:"iops,omitempty"`
-	// KMSKeyID (ARN) of the symmetric Key Management Service (KMS) CMK used for encryption.
+	// KMSKeyID (ARN) of the symmetric Key Management Service (KMS) CMK used for encryption. The key policy must permit the
+	// AWSServiceRoleForEC2Fleet service-linked role to use the key, otherwise the EC2NodeClass won't become ready.
 	// +optional
 	KMSKeyID *string `json:"kmsKeyID,omitempty"`
 	// SnapshotID is the ID of an EBS snapshot
@@ -395,6 +728,15 @@ type BlockDevice struct {
 	// +kubebuilder:validation:Type:=string
 	// +optional
 	VolumeSize *resource.Quantity `json:"volumeSize,omitempty" hash:"string"`
+	// MaxVolumeSize enables auto-sizing of the root volume. Instance types advertise MaxVolumeSize as their ephemeral-storage
+	// capacity, and each launch sizes the volume to fit the ephemeral-storage requests of the pods that it was launched for,
+	// between VolumeSize and MaxVolumeSize. The chosen size is recorded on the NodeClaim. MaxVolumeSize can only be set
+	// on the root volume, and requires VolumeSize to be set.
+	// +kubebuilder:validation:Pattern:="^((?:[1-9][0-9]{0,3}|[1-4][0-9]{4}|[5][0-8][0-9]{3}|59000)Gi|(?:[1-9][0-9]{0,3}|[1-5][0-9]{4}|[6][0-3][0-9]{3}|64000)G|([1-9]||[1-5][0-7]|58)Ti|([1-9]||[1-5][0-9]|6[0-3]|64)T)$"
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type:=string
+	// +optional
+	MaxVolumeSize *resource.Quantity `json:"maxVolumeSize,omitempty" hash:"string"`
 	// VolumeType of the block device.
 	// For more information, see Amazon EBS volume types (https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/EBSVolumeTypes.html)
 	// in the Amazon Elastic Compute Cloud User Guide.
@@ -404,7 +746,7 @@ type BlockDevice struct {
 }
 
 // InstanceStorePolicy enumerates options for configuring instance store disks.
-// +kubebuilder:validation:Enum={RAID0}
+// +kubebuilder:validation:Enum={RAID0,EphemeralOS}
 type InstanceStorePolicy string
 
 const (
@@ -413,6 +755,28 @@ const (
 	// ephemeral storage for more and faster node ephemeral-storage. The node's ephemeral storage can be shared among
 	// pods that request ephemeral storage and container images that are downloaded to the node.
 	InstanceStorePolicyRAID0 InstanceStorePolicy = "RAID0"
+	// InstanceStorePolicyEphemeralOS configures the instance storage disks in the same way as RAID0, so that container
+	// root filesystems and the kubelet's state are written to instance storage rather than EBS. Unlike RAID0, only instance
+	// types with NVMe instance storage are launched, so a node's writable state never falls back to its EBS volumes and they
+	// can be sized for the OS alone. This is only supported by the AL2, AL2023 and Bottlerocket AMI families.
+	InstanceStorePolicyEphemeralOS InstanceStorePolicy = "EphemeralOS"
+)
+
+// UsesRAID0 returns true if the instance storage disks are configured as a RAID-0 array for node ephemeral-storage
+func (p InstanceStorePolicy) UsesRAID0() bool {
+	return p == InstanceStorePolicyRAID0 || p == InstanceStorePolicyEphemeralOS
+}
+
+// ZoneSpreadPolicy enumerates options for balancing launched capacity across zones.
+// +kubebuilder:validation:Enum={Strict,Preferred}
+type ZoneSpreadPolicy string
+
+const (
+	// ZoneSpreadPolicyStrict only launches capacity into the least-populated zones of the NodePool
+	ZoneSpreadPolicyStrict ZoneSpreadPolicy = "Strict"
+	// ZoneSpreadPolicyPreferred launches capacity into the least-populated zones of the NodePool when it's available there,
+	// and otherwise falls back to any zone that is allowed by the NodeClaim
+	ZoneSpreadPolicyPreferred ZoneSpreadPolicy = "Preferred"
 )
 
 // EC2NodeClass is the Schema for the EC2NodeClass API
@@ -435,6 +799,7 @@ type EC2NodeClass struct {
 	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'Windows2019' or 'Custom' when using a Windows2019 alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'windows2019') ? (self.amiFamily == 'Custom' || self.amiFamily == 'Windows2019') : true)"
 	// +kubebuilder:validation:XValidation:message="if set, amiFamily must be 'Windows2022' or 'Custom' when using a Windows2022 alias",rule="!has(self.amiFamily) || (self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') == 'windows2022') ? (self.amiFamily == 'Custom' || self.amiFamily == 'Windows2022') : true)"
 	// +kubebuilder:validation:XValidation:message="must specify amiFamily if amiSelectorTerms does not contain an alias",rule="self.amiSelectorTerms.exists(x, has(x.alias)) ? true : has(self.amiFamily)"
+	// +kubebuilder:validation:XValidation:message="instanceStorePolicy 'EphemeralOS' is only supported by the AL2, AL2023 and Bottlerocket AMI families",rule="!has(self.instanceStorePolicy) || self.instanceStorePolicy != 'EphemeralOS' || (has(self.amiFamily) ? self.amiFamily in ['AL2', 'AL2023', 'Bottlerocket'] : self.amiSelectorTerms.exists(x, has(x.alias) && x.alias.find('^[^@]+') in ['al2', 'al2023', 'bottlerocket']))"
 	Spec   EC2NodeClassSpec   `json:"spec,omitempty"`
 	Status EC2NodeClassStatus `json:"status,omitempty"`
 }
@@ -463,8 +828,24 @@ func (in *EC2NodeClass) InstanceProfileName(clusterName, region string) string {
 	return fmt.Sprintf("%s_%d", clusterName, lo.Must(hashstructure.Hash(fmt.Sprintf("%s%s", region, in.Name), hashstructure.FormatV2, nil)))
 }
 
+// InstanceProfileRole returns the name of the role in spec.role, which may be specified as an ARN
 func (in *EC2NodeClass) InstanceProfileRole() string {
-	return in.Spec.Role
+	return iamResourceName(in.Spec.Role)
+}
+
+// SpecInstanceProfileName returns the name of the instance profile in spec.instanceProfile, which may be specified as an ARN
+func (in *EC2NodeClass) SpecInstanceProfileName() string {
+	return iamResourceName(lo.FromPtr(in.Spec.InstanceProfile))
+}
+
+// iamResourceName returns the name of an IAM resource from its ARN, e.g. arn:aws-us-gov:iam::111122223333:role/path/name
+// returns name. Values which aren't ARNs are already names, and are returned as is.
+func iamResourceName(nameOrARN string) string {
+	parsed, err := arn.Parse(nameOrARN)
+	if err != nil {
+		return nameOrARN
+	}
+	return parsed.Resource[strings.LastIndex(parsed.Resource, "/")+1:]
 }
 
 func (in *EC2NodeClass) InstanceProfileTags(clusterName string) map[string]string {
@@ -491,6 +872,21 @@ func (in *EC2NodeClass) AMIFamily() string {
 	return AMIFamilyCustom
 }
 
+// EnclavesEnabled returns true if instances are launched with Nitro Enclaves enabled
+func (in *EC2NodeClass) EnclavesEnabled() bool {
+	return in.Spec.EnclaveOptions != nil && in.Spec.EnclaveOptions.Enabled
+}
+
+// AMDSEVSNPEnabled returns true if instances are launched with AMD SEV-SNP enabled
+func (in *EC2NodeClass) AMDSEVSNPEnabled() bool {
+	return in.Spec.CPUOptions != nil && lo.FromPtr(in.Spec.CPUOptions.AMDSEVSNP) == "enabled"
+}
+
+// BaselineCapacityEnabled returns true if the CPU capacity of burstable instance types is advertised at their baseline performance
+func (in *EC2NodeClass) BaselineCapacityEnabled() bool {
+	return in.Spec.CreditSpecification != nil && lo.FromPtr(in.Spec.CreditSpecification.BaselineCapacity)
+}
+
 type Alias struct {
 	Family  string
 	Version string
@@ -138,6 +138,15 @@ var _ = Describe("Hash", func() {
 		Entry("DetailedMonitoring", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{DetailedMonitoring: aws.Bool(true)}}),
 		Entry("InstanceStorePolicy", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{InstanceStorePolicy: lo.ToPtr(v1.InstanceStorePolicyRAID0)}}),
 		Entry("AssociatePublicIPAddress", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{AssociatePublicIPAddress: lo.ToPtr(true)}}),
+		Entry("ElasticIPSelectorTerms", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{ElasticIPSelectorTerms: []v1.ElasticIPSelectorTerm{{Tags: map[string]string{"eip-pool": "egress"}}}}}),
+		Entry("Licensing", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Licensing: &v1.Licensing{LicenseConfigurationARNs: []string{"arn:aws:license-manager:us-west-2:111122223333:license-configuration:lic-0123456789abcdef"}}}}),
+		Entry("EnclaveOptions", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{EnclaveOptions: &v1.EnclaveOptions{Enabled: true}}}),
+		Entry("CPUOptions AMDSEVSNP", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{CPUOptions: &v1.CPUOptions{AMDSEVSNP: lo.ToPtr("enabled")}}}),
+		Entry("CreditSpecification CPUCredits", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{CreditSpecification: &v1.CreditSpecification{CPUCredits: lo.ToPtr("unlimited")}}}),
+		Entry("PrivateDNSNameOptions HostnameType", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{PrivateDNSNameOptions: &v1.PrivateDNSNameOptions{HostnameType: lo.ToPtr("resource-name")}}}),
+		Entry("PrivateDNSNameOptions EnableResourceNameDNSARecord", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{PrivateDNSNameOptions: &v1.PrivateDNSNameOptions{EnableResourceNameDNSARecord: lo.ToPtr(true)}}}),
+		Entry("CPUOptions CoreCount", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{CPUOptions: &v1.CPUOptions{CoreCount: lo.ToPtr[int32](2)}}}),
+		Entry("CPUOptions ThreadsPerCore", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{CPUOptions: &v1.CPUOptions{ThreadsPerCore: lo.ToPtr[int32](1)}}}),
 		Entry("MetadataOptions HTTPEndpoint", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{MetadataOptions: &v1.MetadataOptions{HTTPEndpoint: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPProtocolIPv6", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{MetadataOptions: &v1.MetadataOptions{HTTPProtocolIPv6: lo.ToPtr("enabled")}}}),
 		Entry("MetadataOptions HTTPPutResponseHopLimit", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{MetadataOptions: &v1.MetadataOptions{HTTPPutResponseHopLimit: lo.ToPtr(int64(10))}}}),
@@ -151,6 +160,12 @@ var _ = Describe("Hash", func() {
 		Entry("BlockDeviceMapping SnapshotID", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{BlockDeviceMappings: []*v1.BlockDeviceMapping{{EBS: &v1.BlockDevice{SnapshotID: lo.ToPtr("test")}}}}}),
 		Entry("BlockDeviceMapping Throughput", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{BlockDeviceMappings: []*v1.BlockDeviceMapping{{EBS: &v1.BlockDevice{Throughput: lo.ToPtr(int64(10))}}}}}),
 		Entry("BlockDeviceMapping VolumeType", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{BlockDeviceMappings: []*v1.BlockDeviceMapping{{EBS: &v1.BlockDevice{VolumeType: lo.ToPtr("io1")}}}}}),
+		Entry("Proxy HTTPSProxy", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Proxy: &v1.Proxy{HTTPSProxy: lo.ToPtr("http://proxy.example.com:3128")}}}),
+		Entry("Proxy NoProxy", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Proxy: &v1.Proxy{NoProxy: []string{".internal.example.com"}}}}),
+		Entry("GPUPartitioning MIG", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{GPUPartitioning: &v1.GPUPartitioning{MIG: &v1.MIGPartitioning{Profile: "1g.10gb"}}}}),
+		Entry("GPUPartitioning TimeSlicingReplicas", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{GPUPartitioning: &v1.GPUPartitioning{TimeSlicingReplicas: lo.ToPtr[int32](4)}}}),
+		Entry("Proxy CABundle", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Proxy: &v1.Proxy{CABundle: lo.ToPtr("Y2EtYnVuZGxl")}}}),
+		Entry("KeyName", v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{KeyName: lo.ToPtr("break-glass")}}),
 	)
 	// We create a separate test for updating blockDeviceMapping volumeSize, since resource.Quantity is a struct, and mergo.WithSliceDeepCopy
 	// doesn't work well with unexported fields, like the ones that are present in resource.Quantity
@@ -199,6 +214,15 @@ var _ = Describe("Hash", func() {
 				Tags: map[string]string{"ami-test-key": "ami-test-value"},
 			},
 		}
+		nodeClass.Spec.ZoneSpreadPolicy = lo.ToPtr(v1.ZoneSpreadPolicyStrict)
+		nodeClass.Spec.PreTermination = &v1.PreTermination{Commands: []string{"sync"}}
+		updatedHash := nodeClass.Hash()
+		Expect(hash).To(Equal(updatedHash))
+	})
+	It("should not change hash when creditSpecification baselineCapacity is updated", func() {
+		nodeClass.Spec.CreditSpecification = &v1.CreditSpecification{CPUCredits: lo.ToPtr("standard")}
+		hash := nodeClass.Hash()
+		nodeClass.Spec.CreditSpecification.BaselineCapacity = lo.ToPtr(true)
 		updatedHash := nodeClass.Hash()
 		Expect(hash).To(Equal(updatedHash))
 	})
@@ -17,13 +17,19 @@ package v1
 import (
 	"github.com/awslabs/operatorpkg/status"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
-	ConditionTypeSubnetsReady         = "SubnetsReady"
-	ConditionTypeSecurityGroupsReady  = "SecurityGroupsReady"
-	ConditionTypeAMIsReady            = "AMIsReady"
-	ConditionTypeInstanceProfileReady = "InstanceProfileReady"
+	ConditionTypeSubnetsReady          = "SubnetsReady"
+	ConditionTypeSecurityGroupsReady   = "SecurityGroupsReady"
+	ConditionTypeAMIsReady             = "AMIsReady"
+	ConditionTypeInstanceProfileReady  = "InstanceProfileReady"
+	ConditionTypeVolumeEncryptionReady = "VolumeEncryptionReady"
+	ConditionTypeCapacityBlockReady    = "CapacityBlockReady"
+	ConditionTypeVPCEndpointsReady     = "VPCEndpointsReady"
+	ConditionTypeNodeAccessReady       = "NodeAccessReady"
+	ConditionTypeKeyPairReady          = "KeyPairReady"
 )
 
 // Subnet contains resolved Subnet selector values utilized for node launch
@@ -37,6 +43,9 @@ type Subnet struct {
 	// The associated availability zone ID
 	// +optional
 	ZoneID string `json:"zoneID,omitempty"`
+	// The number of unused private IPv4 addresses in the subnet at the time it was resolved
+	// +optional
+	AvailableIPAddressCount int32 `json:"availableIPAddressCount,omitempty"`
 }
 
 // SecurityGroup contains resolved SecurityGroup selector values utilized for node launch
@@ -57,11 +66,36 @@ type AMI struct {
 	// Name of the AMI
 	// +optional
 	Name string `json:"name,omitempty"`
+	// CreationDate of the AMI
+	// +optional
+	CreationDate string `json:"creationDate,omitempty"`
 	// Requirements of the AMI to be utilized on an instance type
 	// +required
 	Requirements []corev1.NodeSelectorRequirement `json:"requirements"`
 }
 
+// CapacityBlockStatus contains the resolved Capacity Block that instances are launched into
+type CapacityBlockStatus struct {
+	// ID of the Capacity Block's capacity reservation
+	// +required
+	ID string `json:"id"`
+	// InstanceType that the Capacity Block reserves
+	// +required
+	InstanceType string `json:"instanceType"`
+	// Zone that the Capacity Block is reserved in
+	// +required
+	Zone string `json:"zone"`
+	// State of the capacity reservation at the time it was resolved
+	// +required
+	State string `json:"state"`
+	// StartTime is when the Capacity Block becomes active
+	// +required
+	StartTime metav1.Time `json:"startTime"`
+	// EndTime is when the Capacity Block expires
+	// +required
+	EndTime metav1.Time `json:"endTime"`
+}
+
 // EC2NodeClassStatus contains the resolved state of the EC2NodeClass
 type EC2NodeClassStatus struct {
 	// Subnets contains the current subnet values that are available to the
@@ -82,6 +116,17 @@ type EC2NodeClassStatus struct {
 	// Conditions contains signals for health and readiness
 	// +optional
 	Conditions []status.Condition `json:"conditions,omitempty"`
+	// ObservedGeneration is the generation of the EC2NodeClass spec that the resolved status values were last
+	// computed from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastResolvedTime is the last time that the resolved subnets, security groups, AMIs, or instance profile
+	// changed, or that a new generation of the EC2NodeClass was resolved
+	// +optional
+	LastResolvedTime *metav1.Time `json:"lastResolvedTime,omitempty"`
+	// CapacityBlock contains the resolved Capacity Block that instances are launched into
+	// +optional
+	CapacityBlock *CapacityBlockStatus `json:"capacityBlock,omitempty"`
 }
 
 func (in *EC2NodeClass) StatusConditions() status.ConditionSet {
@@ -90,6 +135,11 @@ func (in *EC2NodeClass) StatusConditions() status.ConditionSet {
 		ConditionTypeSubnetsReady,
 		ConditionTypeSecurityGroupsReady,
 		ConditionTypeInstanceProfileReady,
+		ConditionTypeVolumeEncryptionReady,
+		ConditionTypeCapacityBlockReady,
+		ConditionTypeVPCEndpointsReady,
+		ConditionTypeNodeAccessReady,
+		ConditionTypeKeyPairReady,
 	).For(in)
 }
 
@@ -81,6 +81,31 @@ var _ = Describe("CEL/Validation", func() {
 		nc.Spec.Role = ""
 		Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
 	})
+	DescribeTable("should succeed with role ARNs in any partition", func(role string) {
+		nc.Spec.Role = role
+		Expect(env.Client.Create(ctx, nc)).To(Succeed())
+	},
+		Entry("aws", "arn:aws:iam::111122223333:role/KarpenterNodeRole"),
+		Entry("aws-us-gov", "arn:aws-us-gov:iam::111122223333:role/KarpenterNodeRole"),
+		Entry("aws-cn", "arn:aws-cn:iam::111122223333:role/nodes/KarpenterNodeRole"),
+	)
+	It("should fail with an ARN that isn't a role", func() {
+		nc.Spec.Role = "arn:aws-us-gov:iam::111122223333:instance-profile/KarpenterNodeInstanceProfile"
+		Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+	})
+	DescribeTable("should succeed with instance profile ARNs in any partition", func(instanceProfile string) {
+		nc.Spec.Role = ""
+		nc.Spec.InstanceProfile = lo.ToPtr(instanceProfile)
+		Expect(env.Client.Create(ctx, nc)).To(Succeed())
+	},
+		Entry("aws", "arn:aws:iam::111122223333:instance-profile/KarpenterNodeInstanceProfile"),
+		Entry("aws-us-gov", "arn:aws-us-gov:iam::111122223333:instance-profile/KarpenterNodeInstanceProfile"),
+	)
+	It("should fail with an ARN that isn't an instance profile", func() {
+		nc.Spec.Role = ""
+		nc.Spec.InstanceProfile = lo.ToPtr("arn:aws-us-gov:iam::111122223333:role/KarpenterNodeRole")
+		Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+	})
 	Context("UserData", func() {
 		It("should succeed if user data is empty", func() {
 			Expect(env.Client.Create(ctx, nc)).To(Succeed())
@@ -211,6 +236,66 @@ var _ = Describe("CEL/Validation", func() {
 			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
 		})
 	})
+	Context("ExtendedResources", func() {
+		It("should succeed with fully qualified extended resource names", func() {
+			nc.Spec.ExtendedResources = corev1.ResourceList{
+				"example.com/fpga":             resource.MustParse("2"),
+				"smarter-devices/fuse":         resource.MustParse("20"),
+				"nvidia.com/gpu":               resource.MustParse("8"),
+				"devices.kubevirt.io/kvm":      resource.MustParse("110"),
+				"example.com/kubernetes.io-id": resource.MustParse("1"),
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		DescribeTable("should fail with an invalid extended resource name", func(name string) {
+			nc.Spec.ExtendedResources = corev1.ResourceList{corev1.ResourceName(name): resource.MustParse("1")}
+			Expect(env.Client.Create(ctx, nc)).To(Not(Succeed()))
+		},
+			Entry("unqualified", "fpga"),
+			Entry("standard resource", "memory"),
+			Entry("kubernetes.io domain", "kubernetes.io/fpga"),
+			Entry("kubernetes.io subdomain", "example.kubernetes.io/fpga"),
+			Entry("quota prefix", "requests.example.com/fpga"),
+		)
+	})
+	Context("Licensing", func() {
+		It("should succeed with license configuration ARNs and compliance tags", func() {
+			nc.Spec.Licensing = &v1.Licensing{
+				LicenseConfigurationARNs: []string{
+					"arn:aws:license-manager:us-west-2:111122223333:license-configuration:lic-0123456789abcdef",
+					"arn:aws-us-gov:license-manager:us-gov-west-1:111122223333:license-configuration:lic-fedcba9876543210",
+				},
+				ComplianceTags: map[string]string{"license-owner": "platform"},
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail without license configuration ARNs", func() {
+			nc.Spec.Licensing = &v1.Licensing{}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with an invalid license configuration ARN", func() {
+			nc.Spec.Licensing = &v1.Licensing{
+				LicenseConfigurationARNs: []string{"arn:aws:iam::111122223333:role/license"},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail with empty compliance tag values", func() {
+			nc.Spec.Licensing = &v1.Licensing{
+				LicenseConfigurationARNs: []string{"arn:aws:license-manager:us-west-2:111122223333:license-configuration:lic-0123456789abcdef"},
+				ComplianceTags:           map[string]string{"license-owner": ""},
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail if compliance tags contain a restricted domain key", func() {
+			for _, key := range []string{karpv1.NodePoolLabelKey, "kubernetes.io/cluster/test", v1.EKSClusterNameTagKey, v1.LabelNodeClass, "karpenter.sh/nodeclaim"} {
+				nc.Spec.Licensing = &v1.Licensing{
+					LicenseConfigurationARNs: []string{"arn:aws:license-manager:us-west-2:111122223333:license-configuration:lic-0123456789abcdef"},
+					ComplianceTags:           map[string]string{key: "test"},
+				}
+				Expect(env.Client.Create(ctx, nc)).ToNot(Succeed(), key)
+			}
+		})
+	})
 	Context("SubnetSelectorTerms", func() {
 		It("should succeed with a valid subnet selector on tags", func() {
 			nc.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{
@@ -309,6 +394,28 @@ var _ = Describe("CEL/Validation", func() {
 			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
 		})
 	})
+	Context("ElasticIPSelectorTerms", func() {
+		It("should succeed with a valid elastic ip selector on tags", func() {
+			nc.Spec.ElasticIPSelectorTerms = []v1.ElasticIPSelectorTerm{{Tags: map[string]string{"eip-pool": "egress"}}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should succeed with a valid elastic ip selector on id", func() {
+			nc.Spec.ElasticIPSelectorTerms = []v1.ElasticIPSelectorTerm{{ID: "eipalloc-12345749"}}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when an elastic ip selector term has no values", func() {
+			nc.Spec.ElasticIPSelectorTerms = []v1.ElasticIPSelectorTerm{{}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when an elastic ip selector term has a tag map value that is empty", func() {
+			nc.Spec.ElasticIPSelectorTerms = []v1.ElasticIPSelectorTerm{{Tags: map[string]string{"eip-pool": ""}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should fail when specifying id with tags", func() {
+			nc.Spec.ElasticIPSelectorTerms = []v1.ElasticIPSelectorTerm{{ID: "eipalloc-12345749", Tags: map[string]string{"eip-pool": "egress"}}}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
 	Context("SecurityGroupSelectorTerms", func() {
 		It("should succeed with a valid security group selector on tags", func() {
 			nc.Spec.SecurityGroupSelectorTerms = []v1.SecurityGroupSelectorTerm{
@@ -965,6 +1072,71 @@ var _ = Describe("CEL/Validation", func() {
 			}
 			Expect(env.Client.Create(ctx, nodeClass)).To(Not(Succeed()))
 		})
+		It("should succeed if maxVolumeSize is specified on the root volume", func() {
+			nodeClass := &v1.EC2NodeClass{
+				ObjectMeta: test.ObjectMeta(metav1.ObjectMeta{}),
+				Spec: v1.EC2NodeClassSpec{
+					AMISelectorTerms:           nc.Spec.AMISelectorTerms,
+					SubnetSelectorTerms:        nc.Spec.SubnetSelectorTerms,
+					SecurityGroupSelectorTerms: nc.Spec.SecurityGroupSelectorTerms,
+					Role:                       nc.Spec.Role,
+					BlockDeviceMappings: []*v1.BlockDeviceMapping{
+						{
+							DeviceName: aws.String("map-device-1"),
+							EBS: &v1.BlockDevice{
+								VolumeSize:    resource.NewScaledQuantity(50, resource.Giga),
+								MaxVolumeSize: resource.NewScaledQuantity(500, resource.Giga),
+							},
+							RootVolume: true,
+						},
+					},
+				},
+			}
+			Expect(env.Client.Create(ctx, nodeClass)).To(Succeed())
+		})
+		It("should fail if maxVolumeSize is specified on a volume that isn't the root volume", func() {
+			nodeClass := &v1.EC2NodeClass{
+				ObjectMeta: test.ObjectMeta(metav1.ObjectMeta{}),
+				Spec: v1.EC2NodeClassSpec{
+					AMISelectorTerms:           nc.Spec.AMISelectorTerms,
+					SubnetSelectorTerms:        nc.Spec.SubnetSelectorTerms,
+					SecurityGroupSelectorTerms: nc.Spec.SecurityGroupSelectorTerms,
+					Role:                       nc.Spec.Role,
+					BlockDeviceMappings: []*v1.BlockDeviceMapping{
+						{
+							DeviceName: aws.String("map-device-1"),
+							EBS: &v1.BlockDevice{
+								VolumeSize:    resource.NewScaledQuantity(50, resource.Giga),
+								MaxVolumeSize: resource.NewScaledQuantity(500, resource.Giga),
+							},
+						},
+					},
+				},
+			}
+			Expect(env.Client.Create(ctx, nodeClass)).To(Not(Succeed()))
+		})
+		It("should fail if maxVolumeSize is specified without volumeSize", func() {
+			nodeClass := &v1.EC2NodeClass{
+				ObjectMeta: test.ObjectMeta(metav1.ObjectMeta{}),
+				Spec: v1.EC2NodeClassSpec{
+					AMISelectorTerms:           nc.Spec.AMISelectorTerms,
+					SubnetSelectorTerms:        nc.Spec.SubnetSelectorTerms,
+					SecurityGroupSelectorTerms: nc.Spec.SecurityGroupSelectorTerms,
+					Role:                       nc.Spec.Role,
+					BlockDeviceMappings: []*v1.BlockDeviceMapping{
+						{
+							DeviceName: aws.String("map-device-1"),
+							EBS: &v1.BlockDevice{
+								SnapshotID:    aws.String("snap-0123456789"),
+								MaxVolumeSize: resource.NewScaledQuantity(500, resource.Giga),
+							},
+							RootVolume: true,
+						},
+					},
+				},
+			}
+			Expect(env.Client.Create(ctx, nodeClass)).To(Not(Succeed()))
+		})
 		It("should fail VolumeSize is less then 1Gi/1G", func() {
 			nodeClass := &v1.EC2NodeClass{
 				ObjectMeta: test.ObjectMeta(metav1.ObjectMeta{}),
@@ -1029,6 +1201,43 @@ var _ = Describe("CEL/Validation", func() {
 			Expect(env.Client.Create(ctx, nodeClass)).To(Not(Succeed()))
 		})
 	})
+	Context("InstanceStorePolicy", func() {
+		DescribeTable("should succeed with EphemeralOS for supported aliases", func(alias string) {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: alias}}
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyEphemeralOS)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		},
+			Entry("al2", "al2@latest"),
+			Entry("al2023", "al2023@latest"),
+			Entry("bottlerocket", "bottlerocket@latest"),
+		)
+		It("should succeed with EphemeralOS for a supported amiFamily", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-0123456789abcdef"}}
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyAL2023)
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyEphemeralOS)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		DescribeTable("should fail with EphemeralOS for unsupported amiFamilies", func(amiFamily string) {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-0123456789abcdef"}}
+			nc.Spec.AMIFamily = lo.ToPtr(amiFamily)
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyEphemeralOS)
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		},
+			Entry("Windows2022", v1.AMIFamilyWindows2022),
+			Entry("Custom", v1.AMIFamilyCustom),
+		)
+		It("should fail with EphemeralOS for a windows alias", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2022@latest"}}
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyEphemeralOS)
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		It("should succeed with RAID0 for a custom amiFamily", func() {
+			nc.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{ID: "ami-0123456789abcdef"}}
+			nc.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+			nc.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyRAID0)
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+	})
 	Context("Role Immutability", func() {
 		It("should fail if role is not defined", func() {
 			nc.Spec.Role = ""
@@ -1060,4 +1269,36 @@ var _ = Describe("CEL/Validation", func() {
 			Expect(env.Client.Update(ctx, nc)).ToNot(Succeed())
 		})
 	})
+	Context("LaunchRole", func() {
+		It("should succeed with a launch role", func() {
+			nc.Spec.LaunchRole = &v1.LaunchRole{
+				ARN:            "arn:aws-us-gov:iam::123456789012:role/team-a-launch",
+				SessionTags:    map[string]string{"team": "team-a"},
+				SourceIdentity: lo.ToPtr("team-a@example.com"),
+			}
+			Expect(env.Client.Create(ctx, nc)).To(Succeed())
+		})
+		It("should fail when the ARN isn't an IAM role ARN", func() {
+			nc.Spec.LaunchRole = &v1.LaunchRole{ARN: "arn:aws:iam::123456789012:instance-profile/team-a"}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+		DescribeTable("should fail with a restricted session tag",
+			func(key string) {
+				nc.Spec.LaunchRole = &v1.LaunchRole{
+					ARN:         "arn:aws:iam::123456789012:role/team-a-launch",
+					SessionTags: map[string]string{key: "value"},
+				}
+				Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+			},
+			Entry("karpenter.sh/nodepool", karpv1.NodePoolLabelKey),
+			Entry("karpenter.k8s.aws/ec2nodeclass", v1.LabelNodeClass),
+		)
+		It("should fail when a source identity has invalid characters", func() {
+			nc.Spec.LaunchRole = &v1.LaunchRole{
+				ARN:            "arn:aws:iam::123456789012:role/team-a-launch",
+				SourceIdentity: lo.ToPtr("team a"),
+			}
+			Expect(env.Client.Create(ctx, nc)).ToNot(Succeed())
+		})
+	})
 })
@@ -31,12 +31,15 @@ func init() {
 	karpv1.WellKnownLabels = karpv1.WellKnownLabels.Insert(
 		LabelInstanceHypervisor,
 		LabelInstanceEncryptionInTransitSupported,
+		LabelInstanceNitroEnclavesSupported,
+		LabelInstanceAMDSEVSNPSupported,
 		LabelInstanceCategory,
 		LabelInstanceFamily,
 		LabelInstanceGeneration,
 		LabelInstanceSize,
 		LabelInstanceLocalNVME,
 		LabelInstanceCPU,
+		LabelInstanceThreadsPerCore,
 		LabelInstanceCPUManufacturer,
 		LabelInstanceCPUSustainedClockSpeedMhz,
 		LabelInstanceMemory,
@@ -55,8 +58,9 @@ func init() {
 }
 
 var (
-	TerminationFinalizer   = apis.Group + "/termination"
-	AWSToKubeArchitectures = map[string]string{
+	TerminationFinalizer    = apis.Group + "/termination"
+	DeprovisioningFinalizer = apis.Group + "/deprovisioning-webhook"
+	AWSToKubeArchitectures  = map[string]string{
 		"x86_64":                 karpv1.ArchitectureAmd64,
 		karpv1.ArchitectureArm64: karpv1.ArchitectureArm64,
 	}
@@ -96,19 +100,50 @@ var (
 	ResourceAWSPodENI          corev1.ResourceName = "vpc.amazonaws.com/pod-eni"
 	ResourcePrivateIPv4Address corev1.ResourceName = "vpc.amazonaws.com/PrivateIPv4Address"
 	ResourceEFA                corev1.ResourceName = "vpc.amazonaws.com/efa"
+	// ResourceNVIDIAMIGPrefix prefixes the resources that MIG devices are exposed as by the NVIDIA device plugin's "mixed"
+	// strategy, e.g. nvidia.com/mig-1g.10gb
+	ResourceNVIDIAMIGPrefix = "nvidia.com/mig-"
+
+	// ResourceCostPerHour is a NodePool limit on the estimated hourly price (in USD) of all capacity in the NodePool.
+	// It is not advertised on instance types and is enforced by the provider at launch time.
+	ResourceCostPerHour corev1.ResourceName = "costPerHour"
 
 	LabelNodeClass = apis.Group + "/ec2nodeclass"
 
+	// LaunchValidationTaintKey is the key of a NodePool startup taint which holds its Nodes uninitialized until the launch
+	// validation webhook allows them, after which Karpenter removes the taint
+	LaunchValidationTaintKey = apis.Group + "/launch-validation"
+	// AMIProvenanceTaintKey is the key of a NodePool startup taint which holds its Nodes uninitialized until the provenance
+	// of their AMI has been verified, after which Karpenter removes the taint
+	AMIProvenanceTaintKey = apis.Group + "/ami-provenance"
+	// DaemonReadinessTaintKey is the key of a NodePool startup taint which holds its Nodes uninitialized until the pods of
+	// the readiness DaemonSets are ready on them, after which Karpenter removes the taint
+	DaemonReadinessTaintKey = apis.Group + "/daemon-readiness"
+	// SpotInterruptingTaintKey and RebalanceRecommendedTaintKey are the keys of the taints that Karpenter adds to Nodes on
+	// spot interruption warnings and rebalance recommendations when interruption taints are enabled
+	SpotInterruptingTaintKey     = apis.Group + "/spot-interrupting"
+	RebalanceRecommendedTaintKey = apis.Group + "/rebalance-recommended"
+
+	// LabelNVIDIAMIGConfig selects the MIG configuration that the NVIDIA GPU Operator's MIG manager applies to a node
+	LabelNVIDIAMIGConfig = "nvidia.com/mig.config"
+
 	LabelTopologyZoneID = "topology.k8s.aws/zone-id"
 
+	// LabelAdoptNodePool is set by users on Nodes that weren't launched by Karpenter, e.g. managed node group Nodes, to have
+	// Karpenter adopt their instances into the named NodePool
+	LabelAdoptNodePool = apis.Group + "/adopt-nodepool"
+
 	LabelInstanceHypervisor                   = apis.Group + "/instance-hypervisor"
 	LabelInstanceEncryptionInTransitSupported = apis.Group + "/instance-encryption-in-transit-supported"
+	LabelInstanceNitroEnclavesSupported       = apis.Group + "/instance-nitro-enclaves-supported"
+	LabelInstanceAMDSEVSNPSupported           = apis.Group + "/instance-amd-sev-snp-supported"
 	LabelInstanceCategory                     = apis.Group + "/instance-category"
 	LabelInstanceFamily                       = apis.Group + "/instance-family"
 	LabelInstanceGeneration                   = apis.Group + "/instance-generation"
 	LabelInstanceLocalNVME                    = apis.Group + "/instance-local-nvme"
 	LabelInstanceSize                         = apis.Group + "/instance-size"
 	LabelInstanceCPU                          = apis.Group + "/instance-cpu"
+	LabelInstanceThreadsPerCore               = apis.Group + "/instance-threads-per-core"
 	LabelInstanceCPUManufacturer              = apis.Group + "/instance-cpu-manufacturer"
 	LabelInstanceCPUSustainedClockSpeedMhz    = apis.Group + "/instance-cpu-sustained-clock-speed-mhz"
 	LabelInstanceMemory                       = apis.Group + "/instance-memory"
@@ -125,6 +160,42 @@ var (
 	AnnotationClusterNameTaggedCompatability  = apis.CompatibilityGroup + "/cluster-name-tagged"
 	AnnotationEC2NodeClassHashVersion         = apis.Group + "/ec2nodeclass-hash-version"
 	AnnotationInstanceTagged                  = apis.Group + "/tagged"
+	AnnotationPaused                          = coreapis.Group + "/paused"
+	AnnotationPausedDoNotDisrupt              = apis.Group + "/paused-do-not-disrupt"
+	AnnotationPreDrainWebhookSent             = apis.Group + "/pre-drain-webhook-sent"
+	AnnotationTerminationReason               = apis.Group + "/termination-reason"
+	AnnotationEvictionOrder                   = apis.Group + "/eviction-order"
+	AnnotationPreTerminationCommandID         = apis.Group + "/pre-termination-command-id"
+	AnnotationElasticIPAllocationID           = apis.Group + "/elastic-ip-allocation-id"
+	AnnotationApprovalRequired                = coreapis.Group + "/approval-required"
+	AnnotationDisruptionApprovedUntil         = apis.Group + "/disruption-approved-until"
+	AnnotationApprovalDoNotDisrupt            = apis.Group + "/approval-do-not-disrupt"
+	AnnotationPinNode                         = apis.Group + "/pin-node"
+	AnnotationPinnedUntil                     = apis.Group + "/pinned-until"
+	AnnotationBillingPeriod                   = apis.Group + "/billing-period"
+	AnnotationBillingDeferredUntil            = apis.Group + "/billing-deferred-until"
+	AnnotationAdoptedInstanceID               = apis.Group + "/adopted-instance-id"
+	AnnotationSyncedLabels                    = apis.Group + "/synced-labels"
+	AnnotationSyncedAnnotations               = apis.Group + "/synced-annotations"
+	AnnotationArm64PriceBias                  = apis.Group + "/arm64-price-bias"
+	AnnotationSustainability                  = apis.Group + "/sustainability"
+	AnnotationPackingPolicy                   = apis.Group + "/packing-policy"
+	AnnotationCapacityBlockID                 = apis.Group + "/capacity-block-id"
+	AnnotationCapacityBlockEndTime            = apis.Group + "/capacity-block-end-time"
+	AnnotationManagedNodeRoles                = apis.Group + "/managed-node-roles"
+	AnnotationScheduledMaintenanceTime        = apis.Group + "/scheduled-maintenance-time"
+	AnnotationSpotReclaimTime                 = apis.Group + "/spot-reclaim-time"
+	AnnotationExternalDrainDoNotDisrupt       = apis.Group + "/external-drain-do-not-disrupt"
+	AnnotationRootVolumeSize                  = apis.Group + "/root-volume-size"
+	AnnotationInstanceRunningTime             = apis.Group + "/instance-running-time"
+
+	// Event annotations are set on the Events that Karpenter publishes, describing the involved object when the event was
+	// published so that consumers don't need to parse the message
+	EventAnnotationReason     = apis.Group + "/event-reason"
+	EventAnnotationInstanceID = apis.Group + "/event-instance-id"
+	EventAnnotationPrice      = apis.Group + "/event-price"
+	EventAnnotationConditions = apis.Group + "/event-conditions"
+	EventAnnotationNode       = apis.Group + "/event-node"
 
 	NodeClaimTagKey          = coreapis.Group + "/nodeclaim"
 	NameTagKey               = "Name"
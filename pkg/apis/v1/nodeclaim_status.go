@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+const (
+	// ConditionTypeRegistrationRebooted is set on a NodeClaim once Karpenter has rebooted its instance after it failed to
+	// register within the configured registration reboot window. Instances are rebooted at most once.
+	ConditionTypeRegistrationRebooted = "RegistrationRebooted"
+	// ConditionTypeTerminationReason is set on a NodeClaim once it begins terminating. The condition's message holds the
+	// machine-readable TerminationReason, which is also written to the NodeClaim's Node as an annotation.
+	ConditionTypeTerminationReason = "TerminationReason"
+	// ConditionTypeDisruptionApprovalPending is set on a NodeClaim whose Node hosts pods with the approval-required
+	// annotation. It's true while voluntary disruption is blocked waiting on an operator's approval, and false while an
+	// unexpired approval is in place.
+	ConditionTypeDisruptionApprovalPending = "DisruptionApprovalPending"
+	// ConditionTypeLaunchValidated is set on a NodeClaim with the launch validation startup taint once the launch
+	// validation webhook has allowed or denied its Node, or validation has timed out. NodeClaims which fail validation
+	// are deleted.
+	ConditionTypeLaunchValidated = "LaunchValidated"
+	// ConditionTypeAMIProvenanceVerified is set on a NodeClaim with the AMI provenance startup taint once the provenance
+	// of its AMI has been checked against the trusted AMIs parameter and KMS key. NodeClaims whose AMI isn't trusted are
+	// left uninitialized.
+	ConditionTypeAMIProvenanceVerified = "AMIProvenanceVerified"
+	// ConditionTypeDaemonsReady is set on a NodeClaim with the daemon readiness startup taint. It's false, with the
+	// DaemonSets being waited on in its message, until the pods of the readiness DaemonSets are ready on its Node.
+	ConditionTypeDaemonsReady = "DaemonsReady"
+	// ConditionTypePodCapacityMatched is set on a NodeClaim once its Node registers. It's false, with both pod capacities
+	// in its message, if the Node's kubelet reports capacity for a different number of pods than Karpenter advertised for
+	// the NodeClaim's instance type when scheduling pods to it.
+	ConditionTypePodCapacityMatched = "PodCapacityMatched"
+	// ConditionTypeTerminationProtected is set on a terminating NodeClaim whose instance can't be terminated because
+	// termination protection was enabled on it out of band. Termination is retried until the protection is removed.
+	ConditionTypeTerminationProtected = "TerminationProtected"
+	// ConditionTypeInstanceStopped is set on a NodeClaim whose instance was stopped out of band, with a reason of Stopping
+	// or Stopped. The NodeClaim is then handled according to the --stopped-instance-policy.
+	ConditionTypeInstanceStopped = "InstanceStopped"
+	// ConditionTypeExternallyDrained is set on a NodeClaim whose Node was cordoned outside of Karpenter, e.g. with kubectl
+	// drain, to show that the drain is owned by an operator rather than by Karpenter's disruption controllers.
+	ConditionTypeExternallyDrained = "ExternallyDrained"
+)
+
+// TerminationReason describes why a NodeClaim was terminated
+type TerminationReason string
+
+const (
+	TerminationReasonSpotInterruption     TerminationReason = "spot-interruption"
+	TerminationReasonInterruption         TerminationReason = "interruption"
+	TerminationReasonConsolidationDelete  TerminationReason = "consolidation-delete"
+	TerminationReasonConsolidationReplace TerminationReason = "consolidation-replace"
+	TerminationReasonDrift                TerminationReason = "drift"
+	TerminationReasonExpiration           TerminationReason = "expiration"
+	TerminationReasonManualDelete         TerminationReason = "manual-delete"
+	TerminationReasonRepair               TerminationReason = "repair"
+	TerminationReasonCapacityBlockExpiry  TerminationReason = "capacity-block-expiry"
+	TerminationReasonLaunchValidation     TerminationReason = "launch-validation"
+	TerminationReasonInstanceStopped      TerminationReason = "instance-stopped"
+)
@@ -0,0 +1,27 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+const (
+	// ConditionTypePaused is set on a NodePool while provisioning and voluntary disruption are halted because the NodePool
+	// or its EC2NodeClass has the karpenter.sh/paused annotation
+	ConditionTypePaused = "Paused"
+	// ConditionTypeSatisfiable indicates whether the NodePool's requirements intersect with at least one instance type
+	// offering in the region for its EC2NodeClass
+	ConditionTypeSatisfiable = "Satisfiable"
+	// ConditionTypeProvisioningBlocked is set on a NodePool while it can't provision because its EC2NodeClass doesn't exist
+	// or isn't ready
+	ConditionTypeProvisioningBlocked = "ProvisioningBlocked"
+)
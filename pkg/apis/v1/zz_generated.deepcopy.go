@@ -47,6 +47,22 @@ func (in *AMI) DeepCopy() *AMI {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AMIRolloutPolicy) DeepCopyInto(out *AMIRolloutPolicy) {
+	*out = *in
+	out.CanaryDuration = in.CanaryDuration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AMIRolloutPolicy.
+func (in *AMIRolloutPolicy) DeepCopy() *AMIRolloutPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AMIRolloutPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AMISelectorTerm) DeepCopyInto(out *AMISelectorTerm) {
 	*out = *in
@@ -84,6 +100,31 @@ func (in *Alias) DeepCopy() *Alias {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoSystemReserved) DeepCopyInto(out *AutoSystemReserved) {
+	*out = *in
+	if in.CPUMillicoresPerVCPU != nil {
+		in, out := &in.CPUMillicoresPerVCPU, &out.CPUMillicoresPerVCPU
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MemoryPercent != nil {
+		in, out := &in.MemoryPercent, &out.MemoryPercent
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoSystemReserved.
+func (in *AutoSystemReserved) DeepCopy() *AutoSystemReserved {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoSystemReserved)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BlockDevice) DeepCopyInto(out *BlockDevice) {
 	*out = *in
@@ -122,6 +163,11 @@ func (in *BlockDevice) DeepCopyInto(out *BlockDevice) {
 		x := (*in).DeepCopy()
 		*out = &x
 	}
+	if in.MaxVolumeSize != nil {
+		in, out := &in.MaxVolumeSize, &out.MaxVolumeSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 	if in.VolumeType != nil {
 		in, out := &in.VolumeType, &out.VolumeType
 		*out = new(string)
@@ -164,6 +210,94 @@ func (in *BlockDeviceMapping) DeepCopy() *BlockDeviceMapping {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CPUOptions) DeepCopyInto(out *CPUOptions) {
+	*out = *in
+	if in.CoreCount != nil {
+		in, out := &in.CoreCount, &out.CoreCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ThreadsPerCore != nil {
+		in, out := &in.ThreadsPerCore, &out.ThreadsPerCore
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AMDSEVSNP != nil {
+		in, out := &in.AMDSEVSNP, &out.AMDSEVSNP
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CPUOptions.
+func (in *CPUOptions) DeepCopy() *CPUOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(CPUOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityBlock) DeepCopyInto(out *CapacityBlock) {
+	*out = *in
+	out.DrainBefore = in.DrainBefore
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityBlock.
+func (in *CapacityBlock) DeepCopy() *CapacityBlock {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityBlock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacityBlockStatus) DeepCopyInto(out *CapacityBlockStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.EndTime.DeepCopyInto(&out.EndTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacityBlockStatus.
+func (in *CapacityBlockStatus) DeepCopy() *CapacityBlockStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacityBlockStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CreditSpecification) DeepCopyInto(out *CreditSpecification) {
+	*out = *in
+	if in.CPUCredits != nil {
+		in, out := &in.CPUCredits, &out.CPUCredits
+		*out = new(string)
+		**out = **in
+	}
+	if in.BaselineCapacity != nil {
+		in, out := &in.BaselineCapacity, &out.BaselineCapacity
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CreditSpecification.
+func (in *CreditSpecification) DeepCopy() *CreditSpecification {
+	if in == nil {
+		return nil
+	}
+	out := new(CreditSpecification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EC2NodeClass) DeepCopyInto(out *EC2NodeClass) {
 	*out = *in
@@ -245,6 +379,13 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ElasticIPSelectorTerms != nil {
+		in, out := &in.ElasticIPSelectorTerms, &out.ElasticIPSelectorTerms
+		*out = make([]ElasticIPSelectorTerm, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.AMISelectorTerms != nil {
 		in, out := &in.AMISelectorTerms, &out.AMISelectorTerms
 		*out = make([]AMISelectorTerm, len(*in))
@@ -252,6 +393,16 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AMIRolloutPolicy != nil {
+		in, out := &in.AMIRolloutPolicy, &out.AMIRolloutPolicy
+		*out = new(AMIRolloutPolicy)
+		**out = **in
+	}
+	if in.AMIMaxAge != nil {
+		in, out := &in.AMIMaxAge, &out.AMIMaxAge
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	if in.AMIFamily != nil {
 		in, out := &in.AMIFamily, &out.AMIFamily
 		*out = new(string)
@@ -274,6 +425,11 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Licensing != nil {
+		in, out := &in.Licensing, &out.Licensing
+		*out = new(Licensing)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Kubelet != nil {
 		in, out := &in.Kubelet, &out.Kubelet
 		*out = new(KubeletConfiguration)
@@ -300,6 +456,68 @@ func (in *EC2NodeClassSpec) DeepCopyInto(out *EC2NodeClassSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.EnclaveOptions != nil {
+		in, out := &in.EnclaveOptions, &out.EnclaveOptions
+		*out = new(EnclaveOptions)
+		**out = **in
+	}
+	if in.CPUOptions != nil {
+		in, out := &in.CPUOptions, &out.CPUOptions
+		*out = new(CPUOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CreditSpecification != nil {
+		in, out := &in.CreditSpecification, &out.CreditSpecification
+		*out = new(CreditSpecification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrivateDNSNameOptions != nil {
+		in, out := &in.PrivateDNSNameOptions, &out.PrivateDNSNameOptions
+		*out = new(PrivateDNSNameOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ZoneSpreadPolicy != nil {
+		in, out := &in.ZoneSpreadPolicy, &out.ZoneSpreadPolicy
+		*out = new(ZoneSpreadPolicy)
+		**out = **in
+	}
+	if in.PreTermination != nil {
+		in, out := &in.PreTermination, &out.PreTermination
+		*out = new(PreTermination)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CapacityBlock != nil {
+		in, out := &in.CapacityBlock, &out.CapacityBlock
+		*out = new(CapacityBlock)
+		**out = **in
+	}
+	if in.GPUPartitioning != nil {
+		in, out := &in.GPUPartitioning, &out.GPUPartitioning
+		*out = new(GPUPartitioning)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExtendedResources != nil {
+		in, out := &in.ExtendedResources, &out.ExtendedResources
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(Proxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LaunchRole != nil {
+		in, out := &in.LaunchRole, &out.LaunchRole
+		*out = new(LaunchRole)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KeyName != nil {
+		in, out := &in.KeyName, &out.KeyName
+		*out = new(string)
+		**out = **in
+	}
 	if in.MetadataOptions != nil {
 		in, out := &in.MetadataOptions, &out.MetadataOptions
 		*out = new(MetadataOptions)
@@ -349,6 +567,15 @@ func (in *EC2NodeClassStatus) DeepCopyInto(out *EC2NodeClassStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LastResolvedTime != nil {
+		in, out := &in.LastResolvedTime, &out.LastResolvedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CapacityBlock != nil {
+		in, out := &in.CapacityBlock, &out.CapacityBlock
+		*out = new(CapacityBlockStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EC2NodeClassStatus.
@@ -361,6 +588,68 @@ func (in *EC2NodeClassStatus) DeepCopy() *EC2NodeClassStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ElasticIPSelectorTerm) DeepCopyInto(out *ElasticIPSelectorTerm) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ElasticIPSelectorTerm.
+func (in *ElasticIPSelectorTerm) DeepCopy() *ElasticIPSelectorTerm {
+	if in == nil {
+		return nil
+	}
+	out := new(ElasticIPSelectorTerm)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnclaveOptions) DeepCopyInto(out *EnclaveOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnclaveOptions.
+func (in *EnclaveOptions) DeepCopy() *EnclaveOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(EnclaveOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GPUPartitioning) DeepCopyInto(out *GPUPartitioning) {
+	*out = *in
+	if in.MIG != nil {
+		in, out := &in.MIG, &out.MIG
+		*out = new(MIGPartitioning)
+		**out = **in
+	}
+	if in.TimeSlicingReplicas != nil {
+		in, out := &in.TimeSlicingReplicas, &out.TimeSlicingReplicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPUPartitioning.
+func (in *GPUPartitioning) DeepCopy() *GPUPartitioning {
+	if in == nil {
+		return nil
+	}
+	out := new(GPUPartitioning)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KubeletConfiguration) DeepCopyInto(out *KubeletConfiguration) {
 	*out = *in
@@ -393,6 +682,11 @@ func (in *KubeletConfiguration) DeepCopyInto(out *KubeletConfiguration) {
 			(*out)[key] = val
 		}
 	}
+	if in.AutoSystemReserved != nil {
+		in, out := &in.AutoSystemReserved, &out.AutoSystemReserved
+		*out = new(AutoSystemReserved)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.EvictionHard != nil {
 		in, out := &in.EvictionHard, &out.EvictionHard
 		*out = make(map[string]string, len(*in))
@@ -446,6 +740,75 @@ func (in *KubeletConfiguration) DeepCopy() *KubeletConfiguration {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LaunchRole) DeepCopyInto(out *LaunchRole) {
+	*out = *in
+	if in.SessionTags != nil {
+		in, out := &in.SessionTags, &out.SessionTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SourceIdentity != nil {
+		in, out := &in.SourceIdentity, &out.SourceIdentity
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LaunchRole.
+func (in *LaunchRole) DeepCopy() *LaunchRole {
+	if in == nil {
+		return nil
+	}
+	out := new(LaunchRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Licensing) DeepCopyInto(out *Licensing) {
+	*out = *in
+	if in.LicenseConfigurationARNs != nil {
+		in, out := &in.LicenseConfigurationARNs, &out.LicenseConfigurationARNs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ComplianceTags != nil {
+		in, out := &in.ComplianceTags, &out.ComplianceTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Licensing.
+func (in *Licensing) DeepCopy() *Licensing {
+	if in == nil {
+		return nil
+	}
+	out := new(Licensing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MIGPartitioning) DeepCopyInto(out *MIGPartitioning) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MIGPartitioning.
+func (in *MIGPartitioning) DeepCopy() *MIGPartitioning {
+	if in == nil {
+		return nil
+	}
+	out := new(MIGPartitioning)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetadataOptions) DeepCopyInto(out *MetadataOptions) {
 	*out = *in
@@ -481,6 +844,87 @@ func (in *MetadataOptions) DeepCopy() *MetadataOptions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreTermination) DeepCopyInto(out *PreTermination) {
+	*out = *in
+	if in.Commands != nil {
+		in, out := &in.Commands, &out.Commands
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.Timeout = in.Timeout
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreTermination.
+func (in *PreTermination) DeepCopy() *PreTermination {
+	if in == nil {
+		return nil
+	}
+	out := new(PreTermination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrivateDNSNameOptions) DeepCopyInto(out *PrivateDNSNameOptions) {
+	*out = *in
+	if in.HostnameType != nil {
+		in, out := &in.HostnameType, &out.HostnameType
+		*out = new(string)
+		**out = **in
+	}
+	if in.EnableResourceNameDNSARecord != nil {
+		in, out := &in.EnableResourceNameDNSARecord, &out.EnableResourceNameDNSARecord
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EnableResourceNameDNSAAAARecord != nil {
+		in, out := &in.EnableResourceNameDNSAAAARecord, &out.EnableResourceNameDNSAAAARecord
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrivateDNSNameOptions.
+func (in *PrivateDNSNameOptions) DeepCopy() *PrivateDNSNameOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(PrivateDNSNameOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Proxy) DeepCopyInto(out *Proxy) {
+	*out = *in
+	if in.HTTPSProxy != nil {
+		in, out := &in.HTTPSProxy, &out.HTTPSProxy
+		*out = new(string)
+		**out = **in
+	}
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Proxy.
+func (in *Proxy) DeepCopy() *Proxy {
+	if in == nil {
+		return nil
+	}
+	out := new(Proxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityGroup) DeepCopyInto(out *SecurityGroup) {
 	*out = *in
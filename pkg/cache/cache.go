@@ -41,8 +41,26 @@ const (
 	// DiscoveredCapacityCacheTTL is the time to drop discovered resource capacity data per-instance type
 	// if it is not updated by a node creation event or refreshed during controller reconciliation
 	DiscoveredCapacityCacheTTL = 60 * 24 * time.Hour
+	// LaunchRoleSessionTTL is the time to drop the clients of unused launch role sessions. The credentials of sessions which
+	// are still in use are refreshed by the SDK before they expire.
+	LaunchRoleSessionTTL = 30 * time.Minute
+	// ImpairedZoneTTL is the time that launches are shifted away from a zone after failures are found to be concentrated
+	// in it. Failures are tracked again once the zone is available, so a zone that's still impaired is shifted away from again.
+	ImpairedZoneTTL = 15 * time.Minute
+	// ZoneFailureWindow is the window over which launch failures and interruptions are counted towards a zone's impairment
+	ZoneFailureWindow = 10 * time.Minute
+	// AMIProvenanceTTL is the time that the result of verifying an AMI's provenance is cached for, so that changes to the
+	// trusted AMIs parameter take effect for nodes launched after this interval
+	AMIProvenanceTTL = 5 * time.Minute
+	// InterruptionHandledTTL is the time that an interruption message is remembered after it's been acted on, so that
+	// duplicate deliveries of the same event for an instance aren't acted on again
+	InterruptionHandledTTL = time.Hour
 )
 
+// ZoneFailureThreshold is the number of launch failures and interruptions within the ZoneFailureWindow at which a zone
+// is considered impaired, as long as they make up the majority of failures across all zones
+const ZoneFailureThreshold = 5
+
 const (
 	// DefaultCleanupInterval triggers cache cleanup (lazy eviction) at this interval.
 	DefaultCleanupInterval = time.Minute
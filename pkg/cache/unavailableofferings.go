@@ -17,7 +17,10 @@ package cache
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
@@ -31,15 +34,20 @@ import (
 // attempting to launch the capacity. These offerings are ignored as long as they are in the cache on
 // GetInstanceTypes responses
 type UnavailableOfferings struct {
-	// key: <capacityType>:<instanceType>:<zone>, value: struct{}{}
+	// key: <capacityType>:<instanceType>:<zone> or zone:<zone> for impaired zones, value: struct{}{}
 	cache  *cache.Cache
 	SeqNum uint64
+
+	mu sync.Mutex
+	// zoneFailures holds the times of recent launch failures and interruptions in each zone
+	zoneFailures map[string][]time.Time
 }
 
 func NewUnavailableOfferings() *UnavailableOfferings {
 	uo := &UnavailableOfferings{
-		cache:  cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
-		SeqNum: 0,
+		cache:        cache.New(UnavailableOfferingsTTL, UnavailableOfferingsCleanupInterval),
+		SeqNum:       0,
+		zoneFailures: map[string][]time.Time{},
 	}
 	uo.cache.OnEvicted(func(_ string, _ interface{}) {
 		atomic.AddUint64(&uo.SeqNum, 1)
@@ -47,8 +55,11 @@ func NewUnavailableOfferings() *UnavailableOfferings {
 	return uo
 }
 
-// IsUnavailable returns true if the offering appears in the cache
+// IsUnavailable returns true if the offering or its zone appears in the cache
 func (u *UnavailableOfferings) IsUnavailable(instanceType ec2types.InstanceType, zone, capacityType string) bool {
+	if _, found := u.cache.Get(u.zoneKey(zone)); found {
+		return true
+	}
 	_, found := u.cache.Get(u.key(instanceType, zone, capacityType))
 	return found
 }
@@ -72,15 +83,97 @@ func (u *UnavailableOfferings) MarkUnavailableForFleetErr(ctx context.Context, f
 	u.MarkUnavailable(ctx, lo.FromPtr(fleetErr.ErrorCode), instanceType, zone, capacityType)
 }
 
+// RecordZoneFailure records a launch failure or interruption in the zone. If failures within the last ZoneFailureWindow
+// are concentrated in the zone, meaning it accounts for at least ZoneFailureThreshold failures and the majority of failures
+// across all zones, every offering in the zone is marked as unavailable for ImpairedZoneTTL. Only one zone is impaired at a
+// time, so launches always have somewhere else to go. It returns true if the zone was marked as impaired.
+func (u *UnavailableOfferings) RecordZoneFailure(ctx context.Context, reason string, zone string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	now := time.Now()
+	total := 0
+	for z, failures := range u.zoneFailures {
+		failures = lo.Filter(failures, func(t time.Time, _ int) bool { return now.Sub(t) < ZoneFailureWindow })
+		if len(failures) == 0 {
+			delete(u.zoneFailures, z)
+			continue
+		}
+		u.zoneFailures[z] = failures
+		total += len(failures)
+	}
+	u.zoneFailures[zone] = append(u.zoneFailures[zone], now)
+	total++
+	count := len(u.zoneFailures[zone])
+	if count < ZoneFailureThreshold || count*2 <= total || len(u.ImpairedZones()) > 0 {
+		return false
+	}
+	log.FromContext(ctx).WithValues(
+		"reason", reason,
+		"zone", zone,
+		"failures", count,
+		"window", ZoneFailureWindow,
+		"ttl", ImpairedZoneTTL).Info("shifting launches away from impaired zone")
+	u.cache.Set(u.zoneKey(zone), struct{}{}, ImpairedZoneTTL)
+	delete(u.zoneFailures, zone)
+	atomic.AddUint64(&u.SeqNum, 1)
+	return true
+}
+
+// ImpairedZones returns the zones that launches are currently shifted away from, along with the time at which each zone
+// becomes available again
+func (u *UnavailableOfferings) ImpairedZones() map[string]time.Time {
+	zones := map[string]time.Time{}
+	for key, item := range u.cache.Items() {
+		if zone, ok := strings.CutPrefix(key, "zone:"); ok {
+			zones[zone] = time.Unix(0, item.Expiration)
+		}
+	}
+	return zones
+}
+
 func (u *UnavailableOfferings) Delete(instanceType ec2types.InstanceType, zone string, capacityType string) {
 	u.cache.Delete(u.key(instanceType, zone, capacityType))
 }
 
+// UnavailableOffering is an offering in the cache along with the time at which it will become available again
+type UnavailableOffering struct {
+	CapacityType string    `json:"capacityType"`
+	InstanceType string    `json:"instanceType"`
+	Zone         string    `json:"zone"`
+	Expiration   time.Time `json:"expiration"`
+}
+
+// List returns every offering that is currently in the cache
+func (u *UnavailableOfferings) List() []UnavailableOffering {
+	var offerings []UnavailableOffering
+	for key, item := range u.cache.Items() {
+		parts := strings.SplitN(key, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		offerings = append(offerings, UnavailableOffering{
+			CapacityType: parts[0],
+			InstanceType: parts[1],
+			Zone:         parts[2],
+			Expiration:   time.Unix(0, item.Expiration),
+		})
+	}
+	return offerings
+}
+
 func (u *UnavailableOfferings) Flush() {
 	u.cache.Flush()
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.zoneFailures = map[string][]time.Time{}
 }
 
 // key returns the cache key for all offerings in the cache
 func (u *UnavailableOfferings) key(instanceType ec2types.InstanceType, zone string, capacityType string) string {
 	return fmt.Sprintf("%s:%s:%s", capacityType, instanceType, zone)
 }
+
+// zoneKey returns the cache key for an impaired zone
+func (u *UnavailableOfferings) zoneKey(zone string) string {
+	return fmt.Sprintf("zone:%s", zone)
+}
@@ -0,0 +1,59 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// adopt takes ownership of an existing instance for a NodeClaim created by the adoption controller, rather than launching
+// a new one. The instance is tagged the same way as instances that Karpenter launches, so that it's listed, garbage
+// collected and terminated like any other instance once its NodeClaim is deleted.
+func (c *CloudProvider) adopt(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass, instanceID string) (*karpv1.NodeClaim, error) {
+	instance, err := c.instanceProvider.Get(ctx, instanceID)
+	if err != nil {
+		if cloudprovider.IsNodeClaimNotFoundError(err) {
+			// There's nothing to adopt if the instance is gone, so the NodeClaim is deleted
+			return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("adopting instance %s, %w", instanceID, err))
+		}
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("adopting instance %s, %w", instanceID, err), "Error getting adopted instance")
+	}
+	if err = c.instanceProvider.CreateTags(ctx, instanceID, getTags(ctx, nodeClass, nodeClaim)); err != nil {
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("tagging adopted instance %s, %w", instanceID, err), "Error tagging adopted instance")
+	}
+	instanceTypes, err := c.instanceTypeProvider.List(ctx, nodeClass)
+	if err != nil {
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving instance types, %w", err), "Error resolving instance types")
+	}
+	instanceType, _ := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool {
+		return i.Name == string(instance.Type)
+	})
+	log.FromContext(ctx).WithValues("instance-id", instanceID).Info("adopted instance")
+	nc := c.instanceToNodeClaim(instance, instanceType, nodeClass)
+	nc.Annotations = lo.Assign(nc.Annotations, map[string]string{
+		v1.AnnotationEC2NodeClassHash:        nodeClass.Hash(),
+		v1.AnnotationEC2NodeClassHashVersion: v1.EC2NodeClassHashVersion,
+	})
+	return nc, nil
+}
@@ -0,0 +1,99 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// isAMIRolloutAllowed determines whether a NodeClaim running an outdated AMI may be marked as drifted under the EC2NodeClass's
+// AMI rollout policy. Until enough canary nodes running the new AMIs have stayed Ready for the canary duration, only the first
+// NodeClaims (ordered by name) needed to make up the canary count are allowed to drift.
+func (c *CloudProvider) isAMIRolloutAllowed(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass) (bool, error) {
+	policy := nodeClass.Spec.AMIRolloutPolicy
+	if policy == nil {
+		return true, nil
+	}
+	// A NodeClaim which was already selected shouldn't stop drifting as the rollout progresses
+	if nodeClaim.StatusConditions().Get(karpv1.ConditionTypeDrifted).IsTrue() {
+		return true, nil
+	}
+	nodeClaimList := &karpv1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList, nodeclaimutils.ForNodeClass(nodeClass)); err != nil {
+		return false, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	nodeClaims := lo.Filter(nodeClaimList.Items, func(nc karpv1.NodeClaim, _ int) bool { return nc.DeletionTimestamp.IsZero() })
+	amis := sets.New(lo.Map(nodeClass.Status.AMIs, func(ami v1.AMI, _ int) string { return ami.ID })...)
+	updated, outdated := lo.FilterReject(nodeClaims, func(nc karpv1.NodeClaim, _ int) bool { return amis.Has(nc.Status.ImageID) })
+
+	canaries, err := intstr.GetScaledValueFromIntOrPercent(lo.ToPtr(intstr.Parse(lo.Ternary(policy.Canary != "", policy.Canary, "10%"))), len(nodeClaims), true)
+	if err != nil {
+		return false, fmt.Errorf("resolving canary count, %w", err)
+	}
+	canaries = lo.Max([]int{canaries, 1})
+	healthy := 0
+	for i := range updated {
+		ready, err := c.isReadyFor(ctx, &updated[i], policy.CanaryDuration.Duration)
+		if err != nil {
+			return false, err
+		}
+		if ready {
+			healthy++
+		}
+	}
+	if healthy >= canaries {
+		return true, nil
+	}
+	pending, drifting := lo.FilterReject(outdated, func(nc karpv1.NodeClaim, _ int) bool {
+		return !nc.StatusConditions().Get(karpv1.ConditionTypeDrifted).IsTrue()
+	})
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Name < pending[j].Name })
+	remaining := canaries - len(updated) - len(drifting)
+	allowed := lo.ContainsBy(lo.Slice(pending, 0, lo.Max([]int{remaining, 0})), func(nc karpv1.NodeClaim) bool { return nc.Name == nodeClaim.Name })
+	if !allowed {
+		log.FromContext(ctx).WithValues("EC2NodeClass", nodeClass.Name, "canaries", canaries, "healthy", healthy).
+			V(1).Info("holding ami drift until canary nodes are healthy")
+	}
+	return allowed, nil
+}
+
+// isReadyFor returns true if the NodeClaim's Node has been Ready for at least the passed duration
+func (c *CloudProvider) isReadyFor(ctx context.Context, nodeClaim *karpv1.NodeClaim, duration time.Duration) (bool, error) {
+	if nodeClaim.Status.NodeName == "" {
+		return false, nil
+	}
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	ready, ok := lo.Find(node.Status.Conditions, func(cond corev1.NodeCondition) bool { return cond.Type == corev1.NodeReady })
+	return ok && ready.Status == corev1.ConditionTrue && time.Since(ready.LastTransitionTime.Time) >= duration, nil
+}
@@ -0,0 +1,102 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// arm64PriceBias returns the fraction by which amd64 offerings must be cheaper than arm64 offerings before the NodePool
+// launches them, parsed from a percentage such as "10%". Invalid values are ignored so that a typo doesn't block launches.
+func arm64PriceBias(ctx context.Context, nodePool *karpv1.NodePool) (float64, bool) {
+	value, ok := nodePool.Annotations[v1.AnnotationArm64PriceBias]
+	if !ok {
+		return 0, false
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err == nil && (percent < 0 || percent >= 100 || math.IsNaN(percent)) {
+		err = fmt.Errorf("must be at least 0%% and less than 100%%")
+	}
+	if err != nil {
+		log.FromContext(ctx).WithValues("NodePool", nodePool.Name).Error(err, fmt.Sprintf("ignoring invalid %s", v1.AnnotationArm64PriceBias))
+		return 0, false
+	}
+	return percent / 100, percent > 0
+}
+
+// biasedInstanceTypes returns copies of the amd64 instance types with their offering prices raised by the arm64 price bias,
+// so that scheduling and consolidation only choose amd64 capacity over arm64 capacity when it's cheaper by more than the bias.
+// Pods that can only run on one architecture constrain the instance types themselves, so they're unaffected.
+func biasedInstanceTypes(instanceTypes []*cloudprovider.InstanceType, bias float64) []*cloudprovider.InstanceType {
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		if !it.Requirements.Get(corev1.LabelArchStable).Has(karpv1.ArchitectureAmd64) {
+			return it
+		}
+		return withOfferings(it, lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) cloudprovider.Offering {
+			return cloudprovider.Offering{Requirements: o.Requirements, Price: o.Price / (1 - bias), Available: o.Available}
+		}))
+	})
+}
+
+// filterByArchitecturePreference drops the amd64 instance types from a launch that could run on either architecture, unless
+// the cheapest amd64 offering is cheaper than the cheapest arm64 offering by more than the NodePool's arm64 price bias. Fleet
+// launches with the lowest-price allocation strategy, so without this the real prices would override the biased prices that
+// the scheduler used to choose the NodeClaim.
+func filterByArchitecturePreference(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool,
+	instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
+	if nodePool == nil {
+		return instanceTypes
+	}
+	bias, ok := arm64PriceBias(ctx, nodePool)
+	if !ok {
+		return instanceTypes
+	}
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	cheapest := func(arch string) (float64, bool) {
+		prices := lo.FlatMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) []float64 {
+			if !it.Requirements.Get(corev1.LabelArchStable).Has(arch) {
+				return nil
+			}
+			return lo.Map(it.Offerings.Compatible(reqs).Available(), func(o cloudprovider.Offering, _ int) float64 { return o.Price })
+		})
+		return lo.Min(prices), len(prices) > 0
+	}
+	arm64Price, ok := cheapest(karpv1.ArchitectureArm64)
+	if !ok {
+		return instanceTypes
+	}
+	amd64Price, ok := cheapest(karpv1.ArchitectureAmd64)
+	if !ok || amd64Price < arm64Price*(1-bias) {
+		return instanceTypes
+	}
+	log.FromContext(ctx).WithValues("NodePool", nodePool.Name, "arm64-price", arm64Price, "amd64-price", amd64Price).V(1).Info("preferring arm64 instance types")
+	return lo.Reject(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool {
+		return !it.Requirements.Get(corev1.LabelArchStable).Has(karpv1.ArchitectureArm64)
+	})
+}
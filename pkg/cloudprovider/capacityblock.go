@@ -0,0 +1,61 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+)
+
+// capacityBlockInstanceTypes returns copies of the instance types where only the on-demand offering of the Capacity Block's
+// instance type in the Capacity Block's zone can be available, and only while the block is active. Launches stop once the
+// block is within its drain window, since those nodes would be drained as soon as they joined the cluster. As with paused
+// NodePools, the other instance types are returned without available offerings so that existing nodes aren't drifted.
+func capacityBlockInstanceTypes(instanceTypes []*cloudprovider.InstanceType, nodeClass *v1.EC2NodeClass, now time.Time) []*cloudprovider.InstanceType {
+	block := nodeClass.Status.CapacityBlock
+	launchable := block != nil && block.ID == nodeClass.Spec.CapacityBlock.ID && !now.Before(block.StartTime.Time) &&
+		now.Before(capacityreservation.ReclaimTime(block.EndTime.Time).Add(-nodeClass.Spec.CapacityBlock.DrainBefore.Duration))
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		return withOfferings(it, lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) cloudprovider.Offering {
+			return cloudprovider.Offering{
+				Requirements: o.Requirements,
+				Price:        o.Price,
+				Available: o.Available && launchable && it.Name == block.InstanceType &&
+					o.Requirements.Get(corev1.LabelTopologyZone).Any() == block.Zone &&
+					o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any() == karpv1.CapacityTypeOnDemand,
+			}
+		}))
+	})
+}
+
+// capacityBlockAnnotations records the Capacity Block that a NodeClaim was launched into, so that its NodeClaim can be
+// terminated before the block ends even if the EC2NodeClass is later pointed at a different block
+func capacityBlockAnnotations(nodeClass *v1.EC2NodeClass) map[string]string {
+	if nodeClass.Status.CapacityBlock == nil {
+		return nil
+	}
+	return map[string]string{
+		v1.AnnotationCapacityBlockID:      nodeClass.Status.CapacityBlock.ID,
+		v1.AnnotationCapacityBlockEndTime: nodeClass.Status.CapacityBlock.EndTime.UTC().Format(time.RFC3339),
+	}
+}
@@ -36,10 +36,12 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	"github.com/samber/lo"
+	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -47,9 +49,11 @@ import (
 
 	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/carbonintensity"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 )
@@ -60,21 +64,26 @@ type CloudProvider struct {
 	kubeClient client.Client
 	recorder   events.Recorder
 
-	instanceTypeProvider  instancetype.Provider
-	instanceProvider      instance.Provider
-	amiProvider           amifamily.Provider
-	securityGroupProvider securitygroup.Provider
+	instanceTypeProvider    instancetype.Provider
+	instanceProvider        instance.Provider
+	amiProvider             amifamily.Provider
+	securityGroupProvider   securitygroup.Provider
+	carbonIntensityProvider carbonintensity.Provider
+	versionProvider         version.Provider
 }
 
 func New(instanceTypeProvider instancetype.Provider, instanceProvider instance.Provider, recorder events.Recorder,
-	kubeClient client.Client, amiProvider amifamily.Provider, securityGroupProvider securitygroup.Provider) *CloudProvider {
+	kubeClient client.Client, amiProvider amifamily.Provider, securityGroupProvider securitygroup.Provider,
+	carbonIntensityProvider carbonintensity.Provider, versionProvider version.Provider) *CloudProvider {
 	return &CloudProvider{
-		instanceTypeProvider:  instanceTypeProvider,
-		instanceProvider:      instanceProvider,
-		kubeClient:            kubeClient,
-		amiProvider:           amiProvider,
-		securityGroupProvider: securityGroupProvider,
-		recorder:              recorder,
+		instanceTypeProvider:    instanceTypeProvider,
+		instanceProvider:        instanceProvider,
+		kubeClient:              kubeClient,
+		amiProvider:             amiProvider,
+		securityGroupProvider:   securityGroupProvider,
+		carbonIntensityProvider: carbonIntensityProvider,
+		versionProvider:         versionProvider,
+		recorder:                recorder,
 	}
 }
 
@@ -97,6 +106,26 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	if nodeClassReady.IsUnknown() {
 		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving NodeClass readiness, NodeClass is in Ready=Unknown, %s", nodeClassReady.Message), "NodeClass is in Ready=Unknown")
 	}
+	// Launch templates are rendered from both the spec and the resolved status, so launching before the status has caught up
+	// with an edit would render the new spec with the previous generation's subnets, security groups and AMIs. The launch is
+	// retried once the status controller, which is triggered by the edit, has resolved the new generation. A zero
+	// observed generation means the status was written before the generation was recorded, so it isn't treated as stale.
+	if nodeClass.Status.ObservedGeneration != 0 && nodeClass.Status.ObservedGeneration != nodeClass.Generation {
+		StaleNodeClassLaunches.Inc(map[string]string{nodeClassLabel: nodeClass.Name})
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving NodeClass status, status was resolved for generation %d but the NodeClass is at generation %d",
+			nodeClass.Status.ObservedGeneration, nodeClass.Generation), "NodeClass status is stale")
+	}
+	if instanceID, ok := nodeClaim.Annotations[v1.AnnotationAdoptedInstanceID]; ok {
+		return c.adopt(ctx, nodeClaim, nodeClass, instanceID)
+	}
+	nodePool, err := c.resolveNodePoolFromNodeClaim(ctx, nodeClaim)
+	if err != nil {
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving nodepool, %w", err), "Error resolving NodePool")
+	}
+	if isNodeClaimPaused(nodePool, nodeClass) {
+		// Paused NodePools don't advertise any available offerings, so we treat a launch that races with a pause as an ICE
+		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("nodepool or nodeclass is paused"))
+	}
 	instanceTypes, err := c.resolveInstanceTypes(ctx, nodeClaim, nodeClass)
 	if err != nil {
 		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving instance types, %w", err), "Error resolving instance types")
@@ -104,6 +133,19 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	if len(instanceTypes) == 0 {
 		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all requested instance types were unavailable during launch"))
 	}
+	if instanceTypes, err = c.filterByCostLimit(ctx, nodeClaim, nodePool, nodeClass, instanceTypes); err != nil {
+		return nil, err
+	}
+	if instanceTypes, err = c.filterBySubLimits(ctx, nodeClaim, nodePool, nodeClass, instanceTypes); err != nil {
+		return nil, err
+	}
+	if instanceTypes, err = c.filterByZoneSpread(ctx, nodeClaim, nodeClass, instanceTypes); err != nil {
+		return nil, err
+	}
+	instanceTypes = filterByArchitecturePreference(ctx, nodeClaim, nodePool, instanceTypes)
+	if instanceTypes, err = c.filterBySustainability(ctx, nodeClaim, nodePool, instanceTypes); err != nil {
+		return nil, err
+	}
 	instance, err := c.instanceProvider.Create(ctx, nodeClass, nodeClaim, getTags(ctx, nodeClass, nodeClaim), instanceTypes)
 	if err != nil {
 		conditionMessage := "Error creating instance"
@@ -120,10 +162,27 @@ func (c *CloudProvider) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 	nc.Annotations = lo.Assign(nc.Annotations, map[string]string{
 		v1.AnnotationEC2NodeClassHash:        nodeClass.Hash(),
 		v1.AnnotationEC2NodeClassHashVersion: v1.EC2NodeClassHashVersion,
-	})
+	}, capacityBlockAnnotations(nodeClass))
+	if instanceType != nil {
+		withRootVolumeSize(nc, amifamily.RootVolumeSize(nodeClass, nodeClaim, instanceType), instanceType)
+	}
 	return nc, nil
 }
 
+// withRootVolumeSize records the size that an auto-sized root volume was launched with on the NodeClaim. The instance type
+// advertises the maxVolumeSize of the volume, so the ephemeral-storage capacity of the NodeClaim is reduced to the launched
+// size to prevent the scheduler from binpacking pods onto storage that the node won't have.
+func withRootVolumeSize(nodeClaim *karpv1.NodeClaim, size *resource.Quantity, instanceType *cloudprovider.InstanceType) {
+	if size == nil {
+		return
+	}
+	nodeClaim.Annotations[v1.AnnotationRootVolumeSize] = size.String()
+	nodeClaim.Status.Capacity[corev1.ResourceEphemeralStorage] = *size
+	allocatable := size.DeepCopy()
+	allocatable.Sub(instanceType.Overhead.Total()[corev1.ResourceEphemeralStorage])
+	nodeClaim.Status.Allocatable[corev1.ResourceEphemeralStorage] = allocatable
+}
+
 func (c *CloudProvider) List(ctx context.Context) ([]*karpv1.NodeClaim, error) {
 	instances, err := c.instanceProvider.List(ctx)
 	if err != nil {
@@ -182,6 +241,24 @@ func (c *CloudProvider) GetInstanceTypes(ctx context.Context, nodePool *karpv1.N
 	if err != nil {
 		return nil, err
 	}
+	if utils.IsPaused(nodePool, nodeClass) {
+		return pausedInstanceTypes(instanceTypes), nil
+	}
+	if nodeClass.Spec.CapacityBlock != nil {
+		instanceTypes = capacityBlockInstanceTypes(instanceTypes, nodeClass, time.Now())
+	}
+	if multipliers := c.instanceTypeProvider.PerformanceMultipliers(); len(multipliers) != 0 {
+		instanceTypes = performanceAdjustedInstanceTypes(instanceTypes, multipliers)
+	}
+	if policy, ok := sustainabilityPolicy(ctx, nodePool); ok {
+		instanceTypes = sustainableInstanceTypes(ctx, instanceTypes, policy, c.zoneIntensities(instanceTypes))
+	}
+	if policy, ok := packingPolicy(ctx, nodePool); ok {
+		instanceTypes = packedInstanceTypes(instanceTypes, policy)
+	}
+	if bias, ok := arm64PriceBias(ctx, nodePool); ok {
+		return biasedInstanceTypes(instanceTypes, bias), nil
+	}
 	return instanceTypes, nil
 }
 
@@ -191,7 +268,38 @@ func (c *CloudProvider) Delete(ctx context.Context, nodeClaim *karpv1.NodeClaim)
 		return fmt.Errorf("getting instance ID, %w", err)
 	}
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("id", id))
-	return c.instanceProvider.Delete(ctx, id)
+	evicted, err := c.evictOrderedDaemons(ctx, nodeClaim)
+	if err != nil {
+		return fmt.Errorf("evicting daemonset pods, %w", err)
+	}
+	// Termination is held by returning without terminating the instance, and the nodeclaim.gracefultermination controller
+	// calls Delete again until it's released
+	if !evicted {
+		log.FromContext(ctx).V(1).Info("waiting on ordered daemonset pods to terminate")
+		return nil
+	}
+	completed, err := c.runPreTerminationCommands(ctx, nodeClaim, id)
+	if err != nil {
+		return fmt.Errorf("running pre-termination commands, %w", err)
+	}
+	if !completed {
+		log.FromContext(ctx).V(1).Info("waiting on pre-termination commands to complete")
+		return nil
+	}
+	if err = c.instanceProvider.Delete(ctx, id); err != nil {
+		if awserrors.IsTerminationProtected(err) {
+			return multierr.Append(err, c.surfaceTerminationProtection(ctx, nodeClaim, id))
+		}
+		return err
+	}
+	return nil
+}
+
+// terminationGracePeriodElapsed returns true once the NodeClaim's terminationGracePeriod has elapsed. The terminationGracePeriod
+// bounds the whole termination, so the instance's termination is no longer held once it has elapsed.
+func terminationGracePeriodElapsed(nodeClaim *karpv1.NodeClaim) bool {
+	tgp := nodeClaim.Spec.TerminationGracePeriod
+	return tgp != nil && !nodeClaim.DeletionTimestamp.Add(tgp.Duration).After(time.Now())
 }
 
 func (c *CloudProvider) DisruptionReasons() []karpv1.DisruptionReason {
@@ -218,6 +326,22 @@ func (c *CloudProvider) IsDrifted(ctx context.Context, nodeClaim *karpv1.NodeCla
 		}
 		return "", client.IgnoreNotFound(fmt.Errorf("resolving node class, %w", err))
 	}
+	// Drift is a voluntary disruption, so it's halted while the NodePool is paused
+	if utils.IsPaused(nodePool, nodeClass) {
+		return "", nil
+	}
+	if drifted := isScheduledMaintenanceDrifted(ctx, nodeClaim, time.Now()); drifted != "" {
+		return drifted, nil
+	}
+	if options.FromContext(ctx).AWSFeatureGates.Enabled(options.KubeletUpgradeRollout) {
+		drifted, held, err := c.isKubeletVersionDrifted(ctx, nodeClaim, nodePool)
+		if err != nil {
+			return "", fmt.Errorf("calculating kubelet version drift, %w", err)
+		}
+		if drifted != "" || held {
+			return drifted, nil
+		}
+	}
 	driftReason, err := c.isNodeClassDrifted(ctx, nodeClaim, nodePool, nodeClass)
 	if err != nil {
 		return "", err
@@ -243,7 +367,7 @@ func getTags(ctx context.Context, nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.
 	}
 	return lo.Assign(lo.OmitBy(nodeClass.Spec.Tags, func(key string, _ string) bool {
 		return strings.HasPrefix(key, "kubernetes.io/cluster/")
-	}), staticTags)
+	}), lo.FromPtr(nodeClass.Spec.Licensing).ComplianceTags, staticTags)
 }
 
 func (c *CloudProvider) RepairPolicies() []cloudprovider.RepairPolicy {
@@ -271,6 +395,20 @@ func (c *CloudProvider) resolveNodeClassFromNodeClaim(ctx context.Context, nodeC
 	return nodeClass, nil
 }
 
+// resolveNodePoolFromNodeClaim returns the NodePool of the NodeClaim, or nil if the NodeClaim doesn't belong to a NodePool
+// or its NodePool no longer exists. The NodePool is resolved once per launch and passed to every launch filter.
+func (c *CloudProvider) resolveNodePoolFromNodeClaim(ctx context.Context, nodeClaim *karpv1.NodeClaim) (*karpv1.NodePool, error) {
+	nodePoolName, ok := nodeClaim.Labels[karpv1.NodePoolLabelKey]
+	if !ok {
+		return nil, nil
+	}
+	nodePool := &karpv1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return nodePool, nil
+}
+
 func (c *CloudProvider) resolveNodeClassFromNodePool(ctx context.Context, nodePool *karpv1.NodePool) (*v1.EC2NodeClass, error) {
 	nodeClass := &v1.EC2NodeClass{}
 	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass); err != nil {
@@ -289,6 +427,9 @@ func (c *CloudProvider) resolveInstanceTypes(ctx context.Context, nodeClaim *kar
 	if err != nil {
 		return nil, fmt.Errorf("getting instance types, %w", err)
 	}
+	if nodeClass.Spec.CapacityBlock != nil {
+		instanceTypes = capacityBlockInstanceTypes(instanceTypes, nodeClass, time.Now())
+	}
 	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
 	return lo.Filter(instanceTypes, func(i *cloudprovider.InstanceType, _ int) bool {
 		return reqs.Compatible(i.Requirements, scheduling.AllowUndefinedWellKnownLabels) == nil &&
@@ -297,6 +438,19 @@ func (c *CloudProvider) resolveInstanceTypes(ctx context.Context, nodeClaim *kar
 	}), nil
 }
 
+// withOfferings returns a copy of the instance type with the passed offerings. Instance types are shared through the instance
+// type cache, so they're never modified in place. InstanceType can't be copied by value since it lazily computes its
+// allocatable resources, so every exported field is copied here and the allocatable resources are recomputed by the copy.
+func withOfferings(it *cloudprovider.InstanceType, offerings cloudprovider.Offerings) *cloudprovider.InstanceType {
+	return &cloudprovider.InstanceType{
+		Name:         it.Name,
+		Requirements: it.Requirements,
+		Offerings:    offerings,
+		Capacity:     it.Capacity,
+		Overhead:     it.Overhead,
+	}
+}
+
 func (c *CloudProvider) resolveInstanceTypeFromInstance(ctx context.Context, instance *instance.Instance) (*cloudprovider.InstanceType, error) {
 	nodePool, err := c.resolveNodePoolFromInstance(ctx, instance)
 	if err != nil {
@@ -391,6 +545,11 @@ func (c *CloudProvider) instanceToNodeClaim(i *instance.Instance, instanceType *
 	if i.State == ec2types.InstanceStateNameShuttingDown || i.State == ec2types.InstanceStateNameTerminated {
 		nodeClaim.DeletionTimestamp = &metav1.Time{Time: time.Now()}
 	}
+	// Stopped instances are still managed by their NodeClaims, so they're surfaced here rather than treated as terminating
+	if i.State == ec2types.InstanceStateNameStopping || i.State == ec2types.InstanceStateNameStopped {
+		nodeClaim.StatusConditions().SetTrueWithReason(v1.ConditionTypeInstanceStopped, lo.Ternary(i.State == ec2types.InstanceStateNameStopping, "Stopping", "Stopped"),
+			fmt.Sprintf("Instance is %s", i.State))
+	}
 	nodeClaim.Status.ProviderID = fmt.Sprintf("aws:///%s/%s", i.Zone, i.ID)
 	nodeClaim.Status.ImageID = i.ImageID
 	return nodeClaim
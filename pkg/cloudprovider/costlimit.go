@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+)
+
+// filterByCostLimit restricts the offerings of the passed instance types to those that can be launched without pushing the
+// NodeClaim's NodePool over its costPerHour limit.
+func (c *CloudProvider) filterByCostLimit(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool, nodeClass *v1.EC2NodeClass,
+	instanceTypes []*cloudprovider.InstanceType) ([]*cloudprovider.InstanceType, error) {
+	if nodePool == nil {
+		return instanceTypes, nil
+	}
+	limit, ok := nodePool.Spec.Limits[v1.ResourceCostPerHour]
+	if !ok {
+		return instanceTypes, nil
+	}
+	current, err := c.nodePoolCostPerHour(ctx, nodePool, nodeClaim, nodeClass)
+	if err != nil {
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving nodepool cost, %w", err), "Error resolving NodePool cost")
+	}
+	remaining := limit.AsApproximateFloat64() - current
+	filtered := lo.FilterMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) (*cloudprovider.InstanceType, bool) {
+		offerings := lo.Filter(it.Offerings, func(o cloudprovider.Offering, _ int) bool { return o.Price <= remaining })
+		if len(offerings) == 0 {
+			return nil, false
+		}
+		if len(offerings) == len(it.Offerings) {
+			return it, true
+		}
+		return withOfferings(it, offerings), true
+	})
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	if lo.ContainsBy(filtered, func(it *cloudprovider.InstanceType) bool { return len(it.Offerings.Compatible(reqs).Available()) > 0 }) {
+		return filtered, nil
+	}
+	log.FromContext(ctx).WithValues("NodePool", nodePool.Name, "limit", limit.String(), "current", fmt.Sprintf("%.4f", current)).
+		V(1).Info("throttling launch, nodepool cost limit exceeded")
+	c.recorder.Publish(cloudproviderevents.NodePoolCostLimitExceeded(nodePool, limit.String(), current))
+	CostLimitThrottledLaunches.Inc(map[string]string{nodePoolLabel: nodePool.Name})
+	return nil, cloudprovider.NewCreateError(
+		fmt.Errorf("launching nodeclaim would exceed nodepool %q costPerHour limit of %s (current %.4f)", nodePool.Name, limit.String(), current),
+		"NodePool costPerHour limit exceeded",
+	)
+}
+
+// nodePoolCostPerHour estimates the aggregate hourly cost of all launched NodeClaims in the NodePool, excluding the passed NodeClaim.
+// Prices are looked up from the instance type offerings matching the zone and capacity type that each NodeClaim was launched with.
+func (c *CloudProvider) nodePoolCostPerHour(ctx context.Context, nodePool *karpv1.NodePool, nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass) (float64, error) {
+	nodeClaimList := &karpv1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList, client.MatchingLabels{karpv1.NodePoolLabelKey: nodePool.Name}); err != nil {
+		return 0, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	instanceTypes, err := c.instanceTypeProvider.List(ctx, nodeClass)
+	if err != nil {
+		return 0, fmt.Errorf("getting instance types, %w", err)
+	}
+	instanceTypeMap := lo.SliceToMap(instanceTypes, func(it *cloudprovider.InstanceType) (string, *cloudprovider.InstanceType) {
+		return it.Name, it
+	})
+	cost := 0.0
+	for i := range nodeClaimList.Items {
+		nc := &nodeClaimList.Items[i]
+		if nc.Name == nodeClaim.Name {
+			continue
+		}
+		it, ok := instanceTypeMap[nc.Labels[corev1.LabelInstanceTypeStable]]
+		if !ok {
+			continue
+		}
+		offerings := it.Offerings.Compatible(scheduling.NewLabelRequirements(lo.PickByKeys(nc.Labels, []string{
+			corev1.LabelTopologyZone,
+			karpv1.CapacityTypeLabelKey,
+		})))
+		if len(offerings) == 0 {
+			continue
+		}
+		cost += offerings.Cheapest().Price
+	}
+	return cost, nil
+}
@@ -0,0 +1,92 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	nodeutils "sigs.k8s.io/karpenter/pkg/utils/node"
+	podutils "sigs.k8s.io/karpenter/pkg/utils/pod"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// evictOrderedDaemons evicts the DaemonSet pods on the NodeClaim's Node which opt in to ordered eviction with the
+// eviction-order annotation, one order at a time from lowest to highest. Karpenter's drain leaves DaemonSet pods which
+// tolerate the disrupted taint running, so without this they're killed along with the instance. It returns true once none of
+// the opted-in pods are left running and the instance can be terminated.
+func (c *CloudProvider) evictOrderedDaemons(ctx context.Context, nodeClaim *karpv1.NodeClaim) (bool, error) {
+	if nodeClaim.Status.NodeName == "" || nodeClaim.DeletionTimestamp.IsZero() {
+		return true, nil
+	}
+	if terminationGracePeriodElapsed(nodeClaim) {
+		return true, nil
+	}
+	pods, err := nodeutils.GetPods(ctx, c.kubeClient, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeClaim.Status.NodeName}})
+	if err != nil {
+		return false, fmt.Errorf("listing pods, %w", err)
+	}
+	orders := map[*corev1.Pod]int{}
+	for _, pod := range pods {
+		// DaemonSet pods which tolerate the disrupted taint are recreated once evicted, so we only evict the pods which were
+		// running when the NodeClaim began terminating
+		if !podutils.IsOwnedByDaemonSet(pod) || podutils.IsTerminal(pod) || podutils.IsStuckTerminating(pod, clock.RealClock{}) ||
+			pod.CreationTimestamp.After(nodeClaim.DeletionTimestamp.Time) {
+			continue
+		}
+		value, ok := pod.Annotations[v1.AnnotationEvictionOrder]
+		if !ok {
+			continue
+		}
+		order, err := strconv.Atoi(value)
+		if err != nil {
+			log.FromContext(ctx).WithValues("Pod", klog.KObj(pod)).Error(err, "ignoring invalid eviction order")
+			continue
+		}
+		orders[pod] = order
+	}
+	if len(orders) == 0 {
+		return true, nil
+	}
+	next := lo.Min(lo.Values(orders))
+	for pod, order := range orders {
+		if order != next || podutils.IsTerminating(pod) {
+			continue
+		}
+		if err = c.kubeClient.SubResource("eviction").Create(ctx, pod, &policyv1.Eviction{
+			DeleteOptions: &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: lo.ToPtr(pod.UID)}},
+		}); err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			// 429s are returned while a PDB blocks the eviction, so we retry alongside the pods that are terminating
+			if !apierrors.IsTooManyRequests(err) {
+				return false, fmt.Errorf("evicting pod %s, %w", klog.KObj(pod), err)
+			}
+			continue
+		}
+		log.FromContext(ctx).WithValues("Pod", klog.KObj(pod), "order", order).V(1).Info("evicted daemonset pod")
+	}
+	return false, nil
+}
@@ -17,6 +17,7 @@ package cloudprovider
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
@@ -26,6 +27,7 @@ import (
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
@@ -36,6 +38,9 @@ const (
 	SubnetDrift        cloudprovider.DriftReason = "SubnetDrift"
 	SecurityGroupDrift cloudprovider.DriftReason = "SecurityGroupDrift"
 	NodeClassDrift     cloudprovider.DriftReason = "NodeClassDrift"
+	// ScheduledMaintenanceDrift is reported for NodeClaims whose instance is affected by an upcoming AWS Health scheduled
+	// change, e.g. an instance retirement, so they're replaced before EC2 performs the change
+	ScheduledMaintenanceDrift cloudprovider.DriftReason = "ScheduledMaintenanceDrift"
 )
 
 func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool, nodeClass *v1.EC2NodeClass) (cloudprovider.DriftReason, error) {
@@ -65,6 +70,20 @@ func (c *CloudProvider) isNodeClassDrifted(ctx context.Context, nodeClaim *karpv
 	return drifted, nil
 }
 
+// isScheduledMaintenanceDrifted returns true once the scheduled change recorded on the NodeClaim is within the scheduled
+// change lead time. The annotation is only set when a lead time is configured.
+func isScheduledMaintenanceDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, now time.Time) cloudprovider.DriftReason {
+	raw, ok := nodeClaim.Annotations[v1.AnnotationScheduledMaintenanceTime]
+	if !ok {
+		return ""
+	}
+	scheduledTime, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return ""
+	}
+	return lo.Ternary(!now.Before(scheduledTime.Add(-options.FromContext(ctx).ScheduledChangeLeadTime)), ScheduledMaintenanceDrift, "")
+}
+
 func (c *CloudProvider) isAMIDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool,
 	instance *instance.Instance, nodeClass *v1.EC2NodeClass) (cloudprovider.DriftReason, error) {
 	instanceTypes, err := c.GetInstanceTypes(ctx, nodePool)
@@ -82,7 +101,11 @@ func (c *CloudProvider) isAMIDrifted(ctx context.Context, nodeClaim *karpv1.Node
 	}
 	mappedAMIs := amifamily.MapToInstanceTypes([]*cloudprovider.InstanceType{nodeInstanceType}, nodeClass.Status.AMIs)
 	if !lo.Contains(lo.Keys(mappedAMIs), instance.ImageID) {
-		return AMIDrift, nil
+		allowed, err := c.isAMIRolloutAllowed(ctx, nodeClaim, nodeClass)
+		if err != nil {
+			return "", fmt.Errorf("evaluating ami rollout policy, %w", err)
+		}
+		return lo.Ternary(allowed, AMIDrift, ""), nil
 	}
 	return "", nil
 }
@@ -15,6 +15,10 @@ limitations under the License.
 package events
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 
 	v1 "sigs.k8s.io/karpenter/pkg/apis/v1"
@@ -38,3 +42,53 @@ func NodeClaimFailedToResolveNodeClass(nodeClaim *v1.NodeClaim) events.Event {
 		DedupeValues:   []string{string(nodeClaim.UID)},
 	}
 }
+
+func NodePoolCostLimitExceeded(nodePool *v1.NodePool, limit string, current float64) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "CostLimitExceeded",
+		Message:        fmt.Sprintf("Provisioning throttled, launching would exceed costPerHour limit of %s (current %.4f)", limit, current),
+		DedupeValues:   []string{string(nodePool.UID)},
+	}
+}
+
+func NodePoolSubLimitExceeded(nodePool *v1.NodePool, limits []string) events.Event {
+	return events.Event{
+		InvolvedObject: nodePool,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "SubLimitExceeded",
+		Message:        fmt.Sprintf("Provisioning throttled, launching would exceed limits %s", strings.Join(limits, ", ")),
+		DedupeValues:   []string{string(nodePool.UID)},
+	}
+}
+
+func NodeClaimPreTerminationCommandFailedToSend(nodeClaim *v1.NodeClaim, err error) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "PreTerminationCommandFailed",
+		Message:        fmt.Sprintf("Failed sending pre-termination commands, terminating instance, %s", err),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodeClaimPreTerminationCommandCompleted(nodeClaim *v1.NodeClaim, succeeded bool, status string, output string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           lo.Ternary(succeeded, corev1.EventTypeNormal, corev1.EventTypeWarning),
+		Reason:         lo.Ternary(succeeded, "PreTerminationCommandSucceeded", "PreTerminationCommandFailed"),
+		Message:        fmt.Sprintf("Pre-termination commands completed with status %s, output: %s", status, output),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
+
+func NodeClaimTerminationProtected(nodeClaim *v1.NodeClaim, instanceID string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "TerminationProtected",
+		Message:        fmt.Sprintf("Instance %s can't be terminated until its disableApiTermination attribute is disabled", instanceID),
+		DedupeValues:   []string{string(nodeClaim.UID)},
+	}
+}
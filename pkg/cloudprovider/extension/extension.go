@@ -0,0 +1,247 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extension
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mitchellh/hashstructure/v2"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+	"sigs.k8s.io/karpenter/pkg/utils/resources"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
+)
+
+type decorator struct {
+	cloudprovider.CloudProvider
+	kubeClient client.Client
+	clk        clock.Clock
+	adjuster   webhook.OfferingsAdjuster
+	cache      *gocache.Cache
+	cm         *pretty.ChangeMonitor
+}
+
+type offeringKey struct {
+	instanceType string
+	zone         string
+	capacityType string
+}
+
+func newOfferingKey(it *cloudprovider.InstanceType, o cloudprovider.Offering) offeringKey {
+	return offeringKey{
+		instanceType: it.Name,
+		zone:         o.Requirements.Get(corev1.LabelTopologyZone).Any(),
+		capacityType: o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any(),
+	}
+}
+
+// Decorate returns a CloudProvider that delegates to the passed CloudProvider, and passes the available offerings of the
+// instance types that it resolves through the offerings webhook, so that offerings can be filtered and their prices adjusted
+// out of process without forking the provider. Responses are cached for each NodePool until its offerings change, or at
+// most for the default cache TTL. Launches are limited to the offerings that the webhook allows as well, since NodeClaims
+// may have been scheduled with instance types that were resolved before the webhook's response changed. If the webhook
+// fails, the offerings that the provider resolved are used unchanged.
+func Decorate(cloudProvider cloudprovider.CloudProvider, kubeClient client.Client, clk clock.Clock, adjuster webhook.OfferingsAdjuster) cloudprovider.CloudProvider {
+	return &decorator{
+		CloudProvider: cloudProvider,
+		kubeClient:    kubeClient,
+		clk:           clk,
+		adjuster:      adjuster,
+		cache:         gocache.New(cache.DefaultTTL, cache.DefaultCleanupInterval),
+		cm:            pretty.NewChangeMonitor(),
+	}
+}
+
+func (d *decorator) Create(ctx context.Context, nodeClaim *karpv1.NodeClaim) (*karpv1.NodeClaim, error) {
+	// Adopted instances are already running, so there's no offering to choose
+	nodePoolName, ok := nodeClaim.Labels[karpv1.NodePoolLabelKey]
+	if _, adopted := nodeClaim.Annotations[v1.AnnotationAdoptedInstanceID]; !ok || adopted {
+		return d.CloudProvider.Create(ctx, nodeClaim)
+	}
+	nodePool := &karpv1.NodePool{}
+	if err := d.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving nodepool, %w", err), "Error resolving NodePool")
+	}
+	instanceTypes, err := d.CloudProvider.GetInstanceTypes(ctx, nodePool)
+	if err != nil {
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving instance types, %w", err), "Error resolving instance types")
+	}
+	prices, ok := d.resolvePrices(ctx, nodePool, instanceTypes)
+	if !ok {
+		return d.CloudProvider.Create(ctx, nodeClaim)
+	}
+	requirements, ok := allowedRequirements(nodeClaim, instanceTypes, prices)
+	if !ok {
+		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("all requested offerings were rejected by the offerings webhook"))
+	}
+	nodeClaim = nodeClaim.DeepCopy()
+	nodeClaim.Spec.Requirements = requirements.NodeSelectorRequirements()
+	return d.CloudProvider.Create(ctx, nodeClaim)
+}
+
+func (d *decorator) GetInstanceTypes(ctx context.Context, nodePool *karpv1.NodePool) ([]*cloudprovider.InstanceType, error) {
+	instanceTypes, err := d.CloudProvider.GetInstanceTypes(ctx, nodePool)
+	if err != nil {
+		return nil, err
+	}
+	prices, ok := d.resolvePrices(ctx, nodePool, instanceTypes)
+	if !ok {
+		return instanceTypes, nil
+	}
+	return adjustedInstanceTypes(instanceTypes, prices), nil
+}
+
+// resolvePrices sends the available offerings of the NodePool's instance types to the webhook, and returns false if it
+// fails so that the offerings are used unchanged
+func (d *decorator) resolvePrices(ctx context.Context, nodePool *karpv1.NodePool, instanceTypes []*cloudprovider.InstanceType) (map[offeringKey]float64, bool) {
+	request := webhook.OfferingsRequest{
+		Type:     webhook.EventTypeOfferings,
+		Time:     d.clk.Now().UTC(),
+		NodePool: nodePool.Name,
+		Offerings: lo.FlatMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) []webhook.Offering {
+			return lo.Map(it.Offerings.Available(), func(o cloudprovider.Offering, _ int) webhook.Offering {
+				return webhook.Offering{
+					InstanceType: it.Name,
+					Zone:         o.Requirements.Get(corev1.LabelTopologyZone).Any(),
+					CapacityType: o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any(),
+					Price:        o.Price,
+				}
+			})
+		}),
+	}
+	if nodePool.Spec.Template.Spec.NodeClassRef != nil {
+		request.NodeClass = nodePool.Spec.Template.Spec.NodeClassRef.Name
+	}
+	prices, err := d.prices(ctx, request)
+	if err != nil {
+		if d.cm.HasChanged(nodePool.Name, err.Error()) {
+			log.FromContext(ctx).WithValues("NodePool", nodePool.Name).Error(err, "failed adjusting offerings, using unadjusted offerings")
+		}
+		return nil, false
+	}
+	d.cm.HasChanged(nodePool.Name, nil)
+	return prices, true
+}
+
+// prices returns the prices of the offerings that the webhook allowed, keyed by instance type, zone and capacity type
+func (d *decorator) prices(ctx context.Context, request webhook.OfferingsRequest) (map[offeringKey]float64, error) {
+	hash := lo.Must(hashstructure.Hash(request.Offerings, hashstructure.FormatV2, nil))
+	key := fmt.Sprintf("%s/%d", request.NodePool, hash)
+	if prices, ok := d.cache.Get(key); ok {
+		return prices.(map[offeringKey]float64), nil
+	}
+	response, err := d.adjuster.AdjustOfferings(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	prices := lo.SliceToMap(response.Offerings, func(o webhook.Offering) (offeringKey, float64) {
+		return offeringKey{instanceType: o.InstanceType, zone: o.Zone, capacityType: o.CapacityType}, o.Price
+	})
+	d.cache.SetDefault(key, prices)
+	return prices, nil
+}
+
+// allowedRequirements narrows the NodeClaim's requirements so that each available offering that they're compatible with
+// is one that the webhook allowed. Requirements can't express combinations of instance types, zones and capacity types,
+// so the zones and capacity types without an allowed offering are removed first, and then the instance types with an
+// offering that the webhook didn't allow. It returns false if none of the NodeClaim's offerings are left.
+func allowedRequirements(nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, prices map[offeringKey]float64) (scheduling.Requirements, bool) {
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	allowed := func(it *cloudprovider.InstanceType, o cloudprovider.Offering) bool {
+		_, ok := prices[newOfferingKey(it, o)]
+		return ok
+	}
+	instanceTypes = lo.Filter(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool {
+		return requirements.IsCompatible(it.Requirements, scheduling.AllowUndefinedWellKnownLabels) &&
+			resources.Fits(nodeClaim.Spec.Resources.Requests, it.Allocatable())
+	})
+	zones, capacityTypes := sets.New[string](), sets.New[string]()
+	for _, it := range instanceTypes {
+		for _, o := range it.Offerings.Available().Compatible(requirements) {
+			if allowed(it, o) {
+				zones.Insert(o.Requirements.Get(corev1.LabelTopologyZone).Any())
+				capacityTypes.Insert(o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any())
+			}
+		}
+	}
+	if zones.Len() == 0 {
+		return nil, false
+	}
+	requirements.Add(
+		scheduling.NewRequirement(corev1.LabelTopologyZone, corev1.NodeSelectorOpIn, sets.List(zones)...),
+		scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, sets.List(capacityTypes)...),
+	)
+	names := lo.FilterMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) (string, bool) {
+		offerings := it.Offerings.Available().Compatible(requirements)
+		return it.Name, len(offerings) > 0 && lo.EveryBy(offerings, func(o cloudprovider.Offering) bool { return allowed(it, o) })
+	})
+	if len(names) == 0 {
+		return nil, false
+	}
+	requirements.Add(scheduling.NewRequirement(corev1.LabelInstanceTypeStable, corev1.NodeSelectorOpIn, names...))
+	return requirements, true
+}
+
+// adjustedInstanceTypes removes the available offerings that the webhook didn't return and replaces the prices of those
+// that it did. Unavailable offerings aren't sent to the webhook and are left unchanged. Instance types are shared through
+// the instance type cache, so any instance type with modified offerings is returned as a copy.
+func adjustedInstanceTypes(instanceTypes []*cloudprovider.InstanceType, prices map[offeringKey]float64) []*cloudprovider.InstanceType {
+	return lo.FilterMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) (*cloudprovider.InstanceType, bool) {
+		modified := false
+		offerings := lo.FilterMap(it.Offerings, func(o cloudprovider.Offering, _ int) (cloudprovider.Offering, bool) {
+			if !o.Available {
+				return o, true
+			}
+			price, ok := prices[newOfferingKey(it, o)]
+			if !ok {
+				modified = true
+				return o, false
+			}
+			if price != o.Price {
+				modified = true
+				o.Price = price
+			}
+			return o, true
+		})
+		if !modified {
+			return it, true
+		}
+		if len(offerings) == 0 {
+			return nil, false
+		}
+		return &cloudprovider.InstanceType{
+			Name:         it.Name,
+			Requirements: it.Requirements,
+			Offerings:    offerings,
+			Capacity:     it.Capacity,
+			Overhead:     it.Overhead,
+		}, true
+	})
+}
@@ -0,0 +1,260 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package extension_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakecr "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider/fake"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider/extension"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var fakeClock *clock.FakeClock
+var fakeCloudProvider *fake.CloudProvider
+var kubeClient client.Client
+var cloudProvider corecloudprovider.CloudProvider
+var server *httptest.Server
+var handler func(webhook.OfferingsRequest) (webhook.OfferingsResponse, int)
+var requests atomic.Int64
+var nodePool *karpv1.NodePool
+
+func TestExtension(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CloudProvider/Extension")
+}
+
+var _ = BeforeSuite(func() {
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		request := webhook.OfferingsRequest{}
+		Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+		response, status := handler(request)
+		w.WriteHeader(status)
+		Expect(json.NewEncoder(w).Encode(response)).To(Succeed())
+	}))
+})
+
+var _ = AfterSuite(func() {
+	server.Close()
+})
+
+var _ = BeforeEach(func() {
+	fakeClock = clock.NewFakeClock(time.Now())
+	fakeCloudProvider = fake.NewCloudProvider()
+	fakeCloudProvider.InstanceTypes = []*corecloudprovider.InstanceType{
+		fake.NewInstanceType(fake.InstanceTypeOptions{Name: "small-instance-type"}),
+		fake.NewInstanceType(fake.InstanceTypeOptions{Name: "large-instance-type"}),
+	}
+	kubeClient = fakecr.NewFakeClient()
+	cloudProvider = extension.Decorate(fakeCloudProvider, kubeClient, fakeClock, webhook.NewDefaultProvider(server.URL, time.Second))
+	nodePool = coretest.NodePool()
+	Expect(kubeClient.Create(ctx, nodePool)).To(Succeed())
+	requests.Store(0)
+	// By default, the webhook allows every offering that it's sent without changing its price
+	handler = func(request webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+		return webhook.OfferingsResponse{Offerings: request.Offerings}, http.StatusOK
+	}
+})
+
+func offering(instanceTypes []*corecloudprovider.InstanceType, name, zone, capacityType string) (corecloudprovider.Offering, bool) {
+	it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == name })
+	if !ok {
+		return corecloudprovider.Offering{}, false
+	}
+	offerings := it.Offerings.Compatible(scheduling.NewLabelRequirements(map[string]string{
+		corev1.LabelTopologyZone:    zone,
+		karpv1.CapacityTypeLabelKey: capacityType,
+	}))
+	if len(offerings) == 0 {
+		return corecloudprovider.Offering{}, false
+	}
+	return offerings[0], true
+}
+
+var _ = Describe("Extension", func() {
+	It("should send the available offerings of the nodepool's instance types", func() {
+		var received webhook.OfferingsRequest
+		handler = func(request webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+			received = request
+			return webhook.OfferingsResponse{Offerings: request.Offerings}, http.StatusOK
+		}
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instanceTypes).To(HaveLen(2))
+		Expect(received.Type).To(Equal(webhook.EventTypeOfferings))
+		Expect(received.NodePool).To(Equal(nodePool.Name))
+		Expect(received.NodeClass).To(Equal(nodePool.Spec.Template.Spec.NodeClassRef.Name))
+		Expect(received.Offerings).To(HaveLen(10))
+		Expect(received.Offerings).To(ContainElement(webhook.Offering{
+			InstanceType: "small-instance-type",
+			Zone:         "test-zone-1",
+			CapacityType: karpv1.CapacityTypeSpot,
+			Price:        fakeCloudProvider.InstanceTypes[0].Offerings[0].Price,
+		}))
+	})
+	It("should not send unavailable offerings and should leave them unchanged", func() {
+		fakeCloudProvider.InstanceTypes[0].Offerings[0].Available = false
+		handler = func(request webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+			Expect(request.Offerings).To(HaveLen(9))
+			return webhook.OfferingsResponse{}, http.StatusOK
+		}
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instanceTypes).To(HaveLen(1))
+		Expect(instanceTypes[0].Offerings).To(HaveLen(1))
+		Expect(instanceTypes[0].Offerings[0].Available).To(BeFalse())
+	})
+	It("should remove offerings that the webhook doesn't return", func() {
+		handler = func(request webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+			return webhook.OfferingsResponse{Offerings: lo.Filter(request.Offerings, func(o webhook.Offering, _ int) bool {
+				return o.CapacityType == karpv1.CapacityTypeOnDemand && o.InstanceType == "small-instance-type"
+			})}, http.StatusOK
+		}
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instanceTypes).To(HaveLen(1))
+		Expect(instanceTypes[0].Name).To(Equal("small-instance-type"))
+		Expect(instanceTypes[0].Offerings).To(HaveLen(3))
+		_, ok := offering(instanceTypes, "small-instance-type", "test-zone-1", karpv1.CapacityTypeSpot)
+		Expect(ok).To(BeFalse())
+		// The shared instance type shouldn't be modified
+		Expect(fakeCloudProvider.InstanceTypes[0].Offerings).To(HaveLen(5))
+	})
+	It("should replace the prices of offerings with those that the webhook returns", func() {
+		handler = func(request webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+			return webhook.OfferingsResponse{Offerings: lo.Map(request.Offerings, func(o webhook.Offering, _ int) webhook.Offering {
+				if o.Zone == "test-zone-2" {
+					o.Price = 0.01
+				}
+				return o
+			})}, http.StatusOK
+		}
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		o, ok := offering(instanceTypes, "large-instance-type", "test-zone-2", karpv1.CapacityTypeOnDemand)
+		Expect(ok).To(BeTrue())
+		Expect(o.Price).To(BeNumerically("==", 0.01))
+		o, ok = offering(instanceTypes, "large-instance-type", "test-zone-1", karpv1.CapacityTypeOnDemand)
+		Expect(ok).To(BeTrue())
+		Expect(o.Price).To(Equal(fakeCloudProvider.InstanceTypes[1].Offerings[0].Price))
+		Expect(fakeCloudProvider.InstanceTypes[1].Offerings[1].Price).ToNot(BeNumerically("==", 0.01))
+	})
+	It("should use the unadjusted offerings when the webhook fails", func() {
+		handler = func(webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+			return webhook.OfferingsResponse{}, http.StatusInternalServerError
+		}
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(instanceTypes).To(Equal(fakeCloudProvider.InstanceTypes))
+	})
+	It("should cache responses until the offerings change", func() {
+		_, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests.Load()).To(BeNumerically("==", 1))
+
+		fakeCloudProvider.InstanceTypes[0].Offerings[0].Price = 100
+		_, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests.Load()).To(BeNumerically("==", 2))
+	})
+	It("should not cache failed responses", func() {
+		handler = func(webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+			return webhook.OfferingsResponse{}, http.StatusInternalServerError
+		}
+		_, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests.Load()).To(BeNumerically("==", 2))
+	})
+	Context("Create", func() {
+		var nodeClaim *karpv1.NodeClaim
+		BeforeEach(func() {
+			nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{karpv1.NodePoolLabelKey: nodePool.Name}}})
+		})
+		It("should launch from the offerings that the webhook allows", func() {
+			handler = func(request webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+				return webhook.OfferingsResponse{Offerings: lo.Reject(request.Offerings, func(o webhook.Offering, _ int) bool {
+					return o.InstanceType == "small-instance-type"
+				})}, http.StatusOK
+			}
+			created, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(created.Labels).To(HaveKeyWithValue(corev1.LabelInstanceTypeStable, "large-instance-type"))
+			Expect(fakeCloudProvider.CreateCalls).To(HaveLen(1))
+			requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(fakeCloudProvider.CreateCalls[0].Spec.Requirements...)
+			Expect(requirements.Get(corev1.LabelInstanceTypeStable).Values()).To(ConsistOf("large-instance-type"))
+			// The NodeClaim that was passed in shouldn't be modified
+			Expect(nodeClaim.Spec.Requirements).ToNot(ContainElement(HaveField("Key", corev1.LabelInstanceTypeStable)))
+		})
+		It("should exclude the zones and capacity types without an allowed offering", func() {
+			handler = func(request webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+				return webhook.OfferingsResponse{Offerings: lo.Filter(request.Offerings, func(o webhook.Offering, _ int) bool {
+					return o.Zone != "test-zone-1" && o.CapacityType == karpv1.CapacityTypeOnDemand
+				})}, http.StatusOK
+			}
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(fakeCloudProvider.CreateCalls[0].Spec.Requirements...)
+			Expect(requirements.Get(corev1.LabelTopologyZone).Has("test-zone-1")).To(BeFalse())
+			Expect(requirements.Get(karpv1.CapacityTypeLabelKey).Values()).To(ConsistOf(karpv1.CapacityTypeOnDemand))
+			Expect(requirements.Get(corev1.LabelInstanceTypeStable).Values()).To(ConsistOf("small-instance-type", "large-instance-type"))
+		})
+		It("should return an insufficient capacity error when the webhook rejects every offering", func() {
+			handler = func(webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+				return webhook.OfferingsResponse{}, http.StatusOK
+			}
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(fakeCloudProvider.CreateCalls).To(BeEmpty())
+		})
+		It("should launch with the NodeClaim's requirements when the webhook fails", func() {
+			handler = func(webhook.OfferingsRequest) (webhook.OfferingsResponse, int) {
+				return webhook.OfferingsResponse{}, http.StatusInternalServerError
+			}
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeCloudProvider.CreateCalls).To(HaveLen(1))
+			Expect(fakeCloudProvider.CreateCalls[0]).To(Equal(nodeClaim))
+		})
+	})
+})
@@ -0,0 +1,62 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	nodePoolLabel          = "nodepool"
+	nodeClassLabel         = "nodeclass"
+)
+
+var (
+	CostLimitThrottledLaunches = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "nodepool_cost_limit_throttled_launches_total",
+			Help:      "Number of launches refused because they would exceed the NodePool's costPerHour limit, broken down by NodePool.",
+		},
+		[]string{nodePoolLabel},
+	)
+	SubLimitThrottledLaunches = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "nodepool_sub_limit_throttled_launches_total",
+			Help:      "Number of launches refused because they would exceed one of the NodePool's zone or capacity type sub-limits, broken down by NodePool.",
+		},
+		[]string{nodePoolLabel},
+	)
+	StaleNodeClassLaunches = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "nodeclass_stale_status_launches_total",
+			Help:      "Number of launches deferred because the EC2NodeClass had been edited but its status hadn't yet been resolved for the new generation, broken down by EC2NodeClass.",
+		},
+		[]string{nodeClassLabel},
+	)
+)
@@ -0,0 +1,83 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+const (
+	packingConsolidate = "consolidate"
+	packingSpread      = "spread"
+	packingBalanced    = "balanced"
+)
+
+// packingPriceExponent is how strongly the packing policy biases prices by instance size. Each doubling of vCPUs makes an
+// offering about 16% cheaper for "consolidate", or 19% more expensive for "spread", relative to its raw price.
+const packingPriceExponent = 0.25
+
+// packingPolicy returns the NodePool's packing policy, parsed from its packing policy annotation. Invalid values are
+// ignored, as with the arm64 price bias.
+func packingPolicy(ctx context.Context, nodePool *karpv1.NodePool) (string, bool) {
+	value, ok := nodePool.Annotations[v1.AnnotationPackingPolicy]
+	if !ok {
+		return "", false
+	}
+	switch value {
+	case packingConsolidate, packingSpread:
+		return value, true
+	case packingBalanced:
+		return "", false
+	}
+	log.FromContext(ctx).WithValues("NodePool", nodePool.Name).Error(fmt.Errorf("must be one of %q, %q or %q", packingConsolidate, packingSpread, packingBalanced),
+		fmt.Sprintf("ignoring invalid %s", v1.AnnotationPackingPolicy))
+	return "", false
+}
+
+// packedInstanceTypes returns copies of the instance types with their offering prices scaled by their size relative to the
+// smallest instance type, so that scheduling and consolidation favor fewer, larger nodes for "consolidate" and more, smaller
+// nodes for "spread" beyond what their raw prices would choose. Consolidation only replaces nodes with cheaper ones, so the
+// bias decides whether several small nodes are merged onto a large node, or a large node is replaced by smaller ones.
+func packedInstanceTypes(instanceTypes []*cloudprovider.InstanceType, policy string) []*cloudprovider.InstanceType {
+	cpus := lo.FilterMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) (float64, bool) {
+		cpu := it.Capacity.Cpu().AsApproximateFloat64()
+		return cpu, cpu > 0
+	})
+	if len(cpus) == 0 {
+		return instanceTypes
+	}
+	smallest := lo.Min(cpus)
+	exponent := lo.Ternary(policy == packingSpread, packingPriceExponent, -packingPriceExponent)
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		cpu := it.Capacity.Cpu().AsApproximateFloat64()
+		if cpu <= 0 {
+			return it
+		}
+		multiplier := math.Pow(cpu/smallest, exponent)
+		return withOfferings(it, lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) cloudprovider.Offering {
+			return cloudprovider.Offering{Requirements: o.Requirements, Price: o.Price * multiplier, Available: o.Available}
+		}))
+	})
+}
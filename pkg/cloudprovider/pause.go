@@ -0,0 +1,42 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"github.com/samber/lo"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// isNodeClaimPaused returns true if the NodeClaim's NodePool or EC2NodeClass has been paused. The NodePool is nil if the
+// NodeClaim doesn't belong to one.
+func isNodeClaimPaused(nodePool *karpv1.NodePool, nodeClass *v1.EC2NodeClass) bool {
+	return utils.IsPaused(nodeClass) || (nodePool != nil && utils.IsPaused(nodePool))
+}
+
+// pausedInstanceTypes returns copies of the instance types with all offerings marked as unavailable. Returning the instance
+// types rather than an empty list keeps existing nodes from being considered drifted due to their instance type not being found,
+// while preventing the scheduler from launching any new capacity for a paused NodePool.
+func pausedInstanceTypes(instanceTypes []*cloudprovider.InstanceType) []*cloudprovider.InstanceType {
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		return withOfferings(it, lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) cloudprovider.Offering {
+			return cloudprovider.Offering{Requirements: o.Requirements, Price: o.Price, Available: false}
+		}))
+	})
+}
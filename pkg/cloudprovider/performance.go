@@ -0,0 +1,47 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"strings"
+
+	"github.com/samber/lo"
+
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// performanceMultiplier returns the multiplier for the instance type, falling back to the multiplier for its family
+func performanceMultiplier(multipliers map[string]float64, instanceTypeName string) (float64, bool) {
+	if multiplier, ok := multipliers[instanceTypeName]; ok {
+		return multiplier, true
+	}
+	multiplier, ok := multipliers[strings.Split(instanceTypeName, ".")[0]]
+	return multiplier, ok
+}
+
+// performanceAdjustedInstanceTypes returns copies of the instance types with their offering prices divided by their
+// performance multiplier, so that scheduling and consolidation rank instance types by price per unit of effective
+// performance rather than by raw price. Instance types without a multiplier are unchanged.
+func performanceAdjustedInstanceTypes(instanceTypes []*cloudprovider.InstanceType, multipliers map[string]float64) []*cloudprovider.InstanceType {
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		multiplier, ok := performanceMultiplier(multipliers, it.Name)
+		if !ok || multiplier == 1 {
+			return it
+		}
+		return withOfferings(it, lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) cloudprovider.Offering {
+			return cloudprovider.Offering{Requirements: o.Requirements, Price: o.Price / multiplier, Available: o.Available}
+		}))
+	})
+}
@@ -0,0 +1,98 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+)
+
+// maxCommandOutputLength bounds the command output that's surfaced in events
+const maxCommandOutputLength = 512
+
+// runPreTerminationCommands runs the EC2NodeClass's pre-termination commands on the NodeClaim's instance through SSM Run
+// Command. The instance is only drained by the time it's deleted, so this runs as the final step before the instance is
+// terminated. The command ID is stored on the NodeClaim so that the commands are sent once and polled on later calls. It
+// returns true once the commands have completed, or if they can't be run, and the instance can be terminated.
+func (c *CloudProvider) runPreTerminationCommands(ctx context.Context, nodeClaim *karpv1.NodeClaim, id string) (bool, error) {
+	if nodeClaim.DeletionTimestamp.IsZero() || nodeClaim.Spec.NodeClassRef == nil {
+		return true, nil
+	}
+	if terminationGracePeriodElapsed(nodeClaim) {
+		return true, nil
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		// A deleted EC2NodeClass has no commands to run, so we don't hold the instance's termination on it
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("resolving node class, %w", err)
+	}
+	if nodeClass.Spec.PreTermination == nil {
+		return true, nil
+	}
+	commandID, ok := nodeClaim.Annotations[v1.AnnotationPreTerminationCommandID]
+	if !ok {
+		document := lo.Ternary(lo.Contains([]string{v1.AMIFamilyWindows2019, v1.AMIFamilyWindows2022}, nodeClass.AMIFamily()),
+			"AWS-RunPowerShellScript", "AWS-RunShellScript")
+		sent, err := c.instanceProvider.SendCommand(ctx, id, document, nodeClass.Spec.PreTermination.Commands, nodeClass.Spec.PreTermination.Timeout.Duration)
+		if err != nil {
+			// Instances which aren't managed by SSM can't run the commands, so we don't block their termination
+			log.FromContext(ctx).Error(err, "failed sending pre-termination commands")
+			c.recorder.Publish(cloudproviderevents.NodeClaimPreTerminationCommandFailedToSend(nodeClaim, err))
+			return true, nil
+		}
+		stored := nodeClaim.DeepCopy()
+		nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationPreTerminationCommandID: sent})
+		if err = c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+			return false, client.IgnoreNotFound(fmt.Errorf("patching nodeclaim, %w", err))
+		}
+		log.FromContext(ctx).WithValues("command-id", sent).V(1).Info("sent pre-termination commands")
+		return false, nil
+	}
+	invocation, err := c.instanceProvider.GetCommandInvocation(ctx, commandID, id)
+	if err != nil {
+		// Invocations aren't visible immediately after the command is sent
+		if awserrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !invocation.IsComplete() {
+		return false, nil
+	}
+	output := lo.Ternary(invocation.Error != "", invocation.Error, invocation.Output)
+	if len(output) > maxCommandOutputLength {
+		output = "..." + output[len(output)-maxCommandOutputLength:]
+	}
+	log.FromContext(ctx).WithValues("command-id", commandID, "status", invocation.Status, "details", invocation.StatusDetails).
+		V(1).Info("pre-termination commands completed")
+	c.recorder.Publish(cloudproviderevents.NodeClaimPreTerminationCommandCompleted(nodeClaim, invocation.Succeeded(),
+		lo.Ternary(invocation.StatusDetails != "", invocation.StatusDetails, string(invocation.Status)), output))
+	return true, nil
+}
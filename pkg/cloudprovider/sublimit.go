@@ -0,0 +1,187 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/controllers/nodepool/counter"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+)
+
+// subLimit is a NodePool limit that only applies to the capacity in a zone and/or of a capacity type, e.g. "nodes@spot,us-east-1a"
+type subLimit struct {
+	key          corev1.ResourceName
+	resource     corev1.ResourceName
+	zone         string
+	capacityType string
+	limit        float64
+}
+
+func (s subLimit) matches(zone, capacityType string) bool {
+	return (s.zone == "" || s.zone == zone) && (s.capacityType == "" || s.capacityType == capacityType)
+}
+
+// parseSubLimits returns the sub-limits of a NodePool. Sub-limits are specified alongside the NodePool's other limits with a
+// key of the form <resource>@<scope>, where the scope is a comma separated zone and/or capacity type. Core Karpenter doesn't
+// track usage for these keys, so they are ignored by its own limit checks.
+func parseSubLimits(ctx context.Context, nodePool *karpv1.NodePool) []subLimit {
+	var subLimits []subLimit
+	for key, quantity := range nodePool.Spec.Limits {
+		resource, scope, ok := strings.Cut(string(key), "@")
+		if !ok {
+			continue
+		}
+		s := subLimit{key: key, resource: corev1.ResourceName(resource), limit: quantity.AsApproximateFloat64()}
+		valid := resource != "" && scope != ""
+		for _, value := range strings.Split(scope, ",") {
+			switch {
+			case value == "":
+				valid = false
+			case lo.Contains([]string{karpv1.CapacityTypeSpot, karpv1.CapacityTypeOnDemand}, value):
+				valid = valid && s.capacityType == ""
+				s.capacityType = value
+			default:
+				valid = valid && s.zone == ""
+				s.zone = value
+			}
+		}
+		if !valid {
+			log.FromContext(ctx).WithValues("NodePool", nodePool.Name, "limit", key).Error(fmt.Errorf("scope must be a zone and/or capacity type"), "ignoring invalid nodepool sub-limit")
+			continue
+		}
+		subLimits = append(subLimits, s)
+	}
+	return subLimits
+}
+
+// filterBySubLimits restricts the offerings of the passed instance types to those that can be launched without pushing the
+// capacity of the NodeClaim's NodePool in their zone or of their capacity type over one of the NodePool's sub-limits.
+// Instance types are shared through the instance type cache, so any instance type with a reduced set of offerings is
+// returned as a copy.
+func (c *CloudProvider) filterBySubLimits(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool, nodeClass *v1.EC2NodeClass,
+	instanceTypes []*cloudprovider.InstanceType) ([]*cloudprovider.InstanceType, error) {
+	if nodePool == nil {
+		return instanceTypes, nil
+	}
+	subLimits := parseSubLimits(ctx, nodePool)
+	if len(subLimits) == 0 {
+		return instanceTypes, nil
+	}
+	usage, err := c.nodePoolSubLimitUsage(ctx, nodePool, nodeClaim, nodeClass, subLimits)
+	if err != nil {
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving nodepool usage, %w", err), "Error resolving NodePool usage")
+	}
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	exceeded := map[corev1.ResourceName]subLimit{}
+	filtered := lo.FilterMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) (*cloudprovider.InstanceType, bool) {
+		offerings := lo.Filter(it.Offerings, func(o cloudprovider.Offering, _ int) bool {
+			zone := o.Requirements.Get(corev1.LabelTopologyZone).Any()
+			capacityType := o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any()
+			for i, s := range subLimits {
+				if s.matches(zone, capacityType) && usage[i]+subLimitRequest(it, s.resource) > s.limit {
+					if o.Available && reqs.IsCompatible(o.Requirements, scheduling.AllowUndefinedWellKnownLabels) {
+						exceeded[s.key] = s
+					}
+					return false
+				}
+			}
+			return true
+		})
+		if len(offerings) == 0 {
+			return nil, false
+		}
+		if len(offerings) == len(it.Offerings) {
+			return it, true
+		}
+		return withOfferings(it, offerings), true
+	})
+	if lo.ContainsBy(filtered, func(it *cloudprovider.InstanceType) bool { return len(it.Offerings.Compatible(reqs).Available()) > 0 }) {
+		return filtered, nil
+	}
+	keys := lo.Map(lo.Keys(exceeded), func(k corev1.ResourceName, _ int) string { return string(k) })
+	sort.Strings(keys)
+	log.FromContext(ctx).WithValues("NodePool", nodePool.Name, "limits", keys).V(1).Info("throttling launch, nodepool sub-limits exceeded")
+	c.recorder.Publish(cloudproviderevents.NodePoolSubLimitExceeded(nodePool, keys))
+	SubLimitThrottledLaunches.Inc(map[string]string{nodePoolLabel: nodePool.Name})
+	return nil, cloudprovider.NewCreateError(
+		fmt.Errorf("launching nodeclaim would exceed nodepool %q limits %s", nodePool.Name, strings.Join(keys, ", ")),
+		"NodePool sub-limit exceeded",
+	)
+}
+
+// nodePoolSubLimitUsage sums the capacity of all NodeClaims in the NodePool, excluding the passed NodeClaim, that falls within the
+// scope of each sub-limit. NodeClaims that haven't resolved their capacity yet are counted with the capacity of their
+// instance type.
+func (c *CloudProvider) nodePoolSubLimitUsage(ctx context.Context, nodePool *karpv1.NodePool, nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass,
+	subLimits []subLimit) ([]float64, error) {
+	nodeClaimList := &karpv1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList, client.MatchingLabels{karpv1.NodePoolLabelKey: nodePool.Name}); err != nil {
+		return nil, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	instanceTypes, err := c.instanceTypeProvider.List(ctx, nodeClass)
+	if err != nil {
+		return nil, fmt.Errorf("getting instance types, %w", err)
+	}
+	instanceTypeMap := lo.SliceToMap(instanceTypes, func(it *cloudprovider.InstanceType) (string, *cloudprovider.InstanceType) {
+		return it.Name, it
+	})
+	usage := make([]float64, len(subLimits))
+	for i := range nodeClaimList.Items {
+		nc := &nodeClaimList.Items[i]
+		if nc.Name == nodeClaim.Name {
+			continue
+		}
+		capacity := nc.Status.Capacity
+		if len(capacity) == 0 {
+			if it, ok := instanceTypeMap[nc.Labels[corev1.LabelInstanceTypeStable]]; ok {
+				capacity = it.Capacity
+			}
+		}
+		for j, s := range subLimits {
+			if !s.matches(nc.Labels[corev1.LabelTopologyZone], nc.Labels[karpv1.CapacityTypeLabelKey]) {
+				continue
+			}
+			if s.resource == counter.ResourceNode {
+				usage[j]++
+			} else if quantity, ok := capacity[s.resource]; ok {
+				usage[j] += quantity.AsApproximateFloat64()
+			}
+		}
+	}
+	return usage, nil
+}
+
+// subLimitRequest is the amount of a sub-limited resource that launching the instance type consumes
+func subLimitRequest(it *cloudprovider.InstanceType, resource corev1.ResourceName) float64 {
+	if resource == counter.ResourceNode {
+		return 1
+	}
+	quantity := it.Capacity[resource]
+	return quantity.AsApproximateFloat64()
+}
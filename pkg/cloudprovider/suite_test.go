@@ -16,8 +16,11 @@ package cloudprovider_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -29,12 +32,16 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/client-go/tools/record"
 	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
 
 	opstatus "github.com/awslabs/operatorpkg/status"
 	"github.com/imdario/mergo"
@@ -44,6 +51,7 @@ import (
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/status"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
@@ -88,7 +96,7 @@ var _ = BeforeSuite(func() {
 	fakeClock = clock.NewFakeClock(time.Now())
 	recorder = events.NewRecorder(&record.FakeRecorder{})
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, recorder,
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, recorder, cloudProvider, cluster, fakeClock)
 })
@@ -213,6 +221,44 @@ var _ = Describe("CloudProvider", func() {
 		Expect(err).To(HaveOccurred())
 		Expect(corecloudprovider.IsNodeClassNotReadyError(err)).To(BeTrue())
 	})
+	It("should not launch while the NodeClass status hasn't been resolved for the latest generation", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		nodeClass.Status.ObservedGeneration = nodeClass.Generation
+		nodeClass.Spec.Tags = map[string]string{"edited": "true"}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.ObservedGeneration).To(BeNumerically("<", nodeClass.Generation))
+
+		_, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).To(HaveOccurred())
+		Expect(corecloudprovider.IsNodeClassNotReadyError(err)).To(BeFalse())
+		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeFalse())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		ExpectMetricCounterValue(cloudprovider.StaleNodeClassLaunches, 1, map[string]string{"nodeclass": nodeClass.Name})
+
+		nodeClass.Status.ObservedGeneration = nodeClass.Generation
+		ExpectApplied(ctx, env.Client, nodeClass)
+		_, err = cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+	})
+	It("should launch after an edit when a status reconciler that doesn't resolve launch values is failing", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IsolatedVPC: lo.ToPtr(true)}))
+		awsEnv.EC2API.DescribeVpcEndpointsBehavior.Error.Set(fmt.Errorf("failed"), fake.MaxCalls(1000))
+		controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, awsEnv.CapacityReservationProvider, awsEnv.VPCEndpointProvider, awsEnv.AccessEntryProvider)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		nodeClass.Spec.Tags = map[string]string{"edited": "true"}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		_ = ExpectObjectReconcileFailed(ctx, env.Client, controller, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.ObservedGeneration).To(Equal(nodeClass.Generation))
+
+		_, err := cloudProvider.Create(ctx, nodeClaim)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+	})
 	It("should return an ICE error when there are no instance types to launch", func() {
 		// Specify no instance types and expect to receive a capacity error
 		nodeClaim.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
@@ -276,6 +322,817 @@ var _ = Describe("CloudProvider", func() {
 		Expect(ok).To(BeTrue())
 		Expect(v).To(Equal(v1.EC2NodeClassHashVersion))
 	})
+	Context("Cost Limit", func() {
+		var prices map[string]float64
+		BeforeEach(func() {
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			prices = map[string]float64{}
+			for _, it := range instanceTypes {
+				for _, of := range it.Offerings.Available() {
+					if of.Requirements.Get(karpv1.CapacityTypeLabelKey).Any() == karpv1.CapacityTypeOnDemand {
+						prices[it.Name+"/"+of.Requirements.Get(corev1.LabelTopologyZone).Any()] = of.Price
+					}
+				}
+			}
+			Expect(prices).ToNot(BeEmpty())
+		})
+		It("should launch without restriction when the NodePool has no costPerHour limit", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim).ToNot(BeNil())
+		})
+		It("should only launch offerings that fit within the remaining costPerHour budget", func() {
+			sorted := lo.Values(prices)
+			slices.Sort(sorted)
+			budget := sorted[len(sorted)/2]
+			nodePool.Spec.Limits = karpv1.Limits{v1.ResourceCostPerHour: resource.MustParse(fmt.Sprintf("%f", budget))}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(prices[string(override.InstanceType)+"/"+aws.ToString(override.AvailabilityZone)]).To(BeNumerically("<=", budget))
+				}
+			}
+		})
+		It("should refuse to launch when existing NodeClaims consume the costPerHour budget", func() {
+			key := lo.Keys(prices)[0]
+			instanceType, zone, _ := strings.Cut(key, "/")
+			existing := coretest.NodeClaim(karpv1.NodeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						karpv1.NodePoolLabelKey:        nodePool.Name,
+						corev1.LabelInstanceTypeStable: instanceType,
+						corev1.LabelTopologyZone:       zone,
+						karpv1.CapacityTypeLabelKey:    karpv1.CapacityTypeOnDemand,
+					},
+				},
+			})
+			nodePool.Spec.Limits = karpv1.Limits{v1.ResourceCostPerHour: resource.MustParse(fmt.Sprintf("%f", prices[key]))}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, existing, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeFalse())
+			var createError *corecloudprovider.CreateError
+			Expect(errors.As(err, &createError)).To(BeTrue())
+			Expect(createError.ConditionMessage).To(Equal("NodePool costPerHour limit exceeded"))
+			Expect(cloudProviderNodeClaim).To(BeNil())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+	})
+	Context("Sub-Limits", func() {
+		launchedZones := func() sets.Set[string] {
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			zones := sets.New[string]()
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					zones.Insert(aws.ToString(override.AvailabilityZone))
+				}
+			}
+			return zones
+		}
+		It("should not launch into a zone whose sub-limit is exhausted", func() {
+			nodePool.Spec.Limits = karpv1.Limits{"nodes@test-zone-1a": resource.MustParse("0")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			zones := launchedZones()
+			Expect(zones).ToNot(BeEmpty())
+			Expect(zones.Has("test-zone-1a")).To(BeFalse())
+		})
+		It("should count the capacity of existing NodeClaims in the scope of the sub-limit", func() {
+			existing := coretest.NodeClaim(karpv1.NodeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						karpv1.NodePoolLabelKey:     nodePool.Name,
+						corev1.LabelTopologyZone:    "test-zone-1b",
+						karpv1.CapacityTypeLabelKey: karpv1.CapacityTypeOnDemand,
+					},
+				},
+			})
+			nodePool.Spec.Limits = karpv1.Limits{"nodes@on-demand,test-zone-1b": resource.MustParse("1")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, existing, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			zones := launchedZones()
+			Expect(zones).ToNot(BeEmpty())
+			Expect(zones.Has("test-zone-1b")).To(BeFalse())
+		})
+		It("should not restrict capacity types outside of the sub-limit's scope", func() {
+			nodePool.Spec.Limits = karpv1.Limits{"nodes@spot": resource.MustParse("0")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(launchedZones()).To(HaveLen(3))
+		})
+		It("should refuse to launch when every offering would exceed a sub-limit", func() {
+			nodePool.Spec.Limits = karpv1.Limits{"cpu@on-demand": resource.MustParse("1")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).To(HaveOccurred())
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeFalse())
+			var createError *corecloudprovider.CreateError
+			Expect(errors.As(err, &createError)).To(BeTrue())
+			Expect(createError.ConditionMessage).To(Equal("NodePool sub-limit exceeded"))
+			Expect(err.Error()).To(ContainSubstring("cpu@on-demand"))
+			Expect(cloudProviderNodeClaim).To(BeNil())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should ignore sub-limits with an invalid scope", func() {
+			nodePool.Spec.Limits = karpv1.Limits{"nodes@spot,on-demand": resource.MustParse("0")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+	Context("Zone Spread", func() {
+		zonalNodeClaim := func(zone string) *karpv1.NodeClaim {
+			return coretest.NodeClaim(karpv1.NodeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						karpv1.NodePoolLabelKey:  nodePool.Name,
+						corev1.LabelTopologyZone: zone,
+					},
+				},
+			})
+		}
+		launchedZones := func() sets.Set[string] {
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			zones := sets.New[string]()
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					zones.Insert(aws.ToString(override.AvailabilityZone))
+				}
+			}
+			return zones
+		}
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelTopologyZone,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"test-zone-1a", "test-zone-1b", "test-zone-1c"},
+				},
+			})
+		})
+		It("should launch into any zone when no zoneSpreadPolicy is set", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, zonalNodeClaim("test-zone-1a"), zonalNodeClaim("test-zone-1b"), nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedZones())).To(ConsistOf("test-zone-1a", "test-zone-1b", "test-zone-1c"))
+		})
+		It("should launch into the least-populated zone of the NodePool", func() {
+			nodeClass.Spec.ZoneSpreadPolicy = lo.ToPtr(v1.ZoneSpreadPolicyStrict)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, zonalNodeClaim("test-zone-1a"), zonalNodeClaim("test-zone-1b"), nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedZones())).To(ConsistOf("test-zone-1c"))
+		})
+		It("should not count drifted NodeClaims towards the zone distribution", func() {
+			nodeClass.Spec.ZoneSpreadPolicy = lo.ToPtr(v1.ZoneSpreadPolicyStrict)
+			drifted := zonalNodeClaim("test-zone-1c")
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, zonalNodeClaim("test-zone-1a"), zonalNodeClaim("test-zone-1b"), drifted, nodeClaim)
+			drifted.StatusConditions().SetTrue(karpv1.ConditionTypeDrifted)
+			ExpectApplied(ctx, env.Client, drifted)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedZones())).To(ConsistOf("test-zone-1c"))
+		})
+		Context("Unavailable Capacity", func() {
+			BeforeEach(func() {
+				nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+					NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+						Key:      corev1.LabelInstanceTypeStable,
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{"m5.large"},
+					},
+				})
+				awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "m5.large", "test-zone-1c", karpv1.CapacityTypeOnDemand)
+			})
+			It("should fail to launch with a strict policy when the least-populated zone has no capacity", func() {
+				nodeClass.Spec.ZoneSpreadPolicy = lo.ToPtr(v1.ZoneSpreadPolicyStrict)
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass, zonalNodeClaim("test-zone-1a"), zonalNodeClaim("test-zone-1b"), nodeClaim)
+				_, err := cloudProvider.Create(ctx, nodeClaim)
+				Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+				Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+			})
+			It("should fall back to the remaining zones with a preferred policy", func() {
+				nodeClass.Spec.ZoneSpreadPolicy = lo.ToPtr(v1.ZoneSpreadPolicyPreferred)
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass, zonalNodeClaim("test-zone-1a"), zonalNodeClaim("test-zone-1b"), zonalNodeClaim("test-zone-1b"), nodeClaim)
+				_, err := cloudProvider.Create(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sets.List(launchedZones())).To(ConsistOf("test-zone-1a"))
+			})
+		})
+	})
+	Context("Arm64 Price Bias", func() {
+		launchedInstanceTypes := func() sets.Set[string] {
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			instanceTypes := sets.New[string]()
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					instanceTypes.Insert(string(override.InstanceType))
+				}
+			}
+			return instanceTypes
+		}
+		requireInstanceTypes := func(instanceTypes ...string) {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelInstanceTypeStable,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   instanceTypes,
+				},
+			})
+		}
+		BeforeEach(func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelTopologyZone,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"test-zone-1a"},
+				},
+			})
+		})
+		It("should launch either architecture without a bias", func() {
+			requireInstanceTypes("c6g.large", "t3.large", "m5.large")
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedInstanceTypes())).To(ConsistOf("c6g.large", "t3.large", "m5.large"))
+		})
+		It("should only launch arm64 instance types when amd64 isn't cheaper by more than the bias", func() {
+			requireInstanceTypes("c6g.large", "t3.large", "m5.large")
+			nodePool.Annotations = map[string]string{v1.AnnotationArm64PriceBias: "10%"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedInstanceTypes())).To(ConsistOf("c6g.large"))
+		})
+		It("should launch amd64 instance types when they're cheaper by more than the bias", func() {
+			awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+				InstanceTypeOfferings: []ec2types.InstanceTypeOffering{
+					{InstanceType: "t4g.xlarge", Location: aws.String("test-zone-1a")},
+					{InstanceType: "m5.large", Location: aws.String("test-zone-1a")},
+				},
+			})
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+			requireInstanceTypes("t4g.xlarge", "m5.large")
+			nodePool.Annotations = map[string]string{v1.AnnotationArm64PriceBias: "10%"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedInstanceTypes())).To(ConsistOf("t4g.xlarge", "m5.large"))
+		})
+		It("should not restrict NodeClaims that require amd64", func() {
+			requireInstanceTypes("c6g.large", "t3.large", "m5.large")
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelArchStable,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{karpv1.ArchitectureAmd64},
+				},
+			})
+			nodePool.Annotations = map[string]string{v1.AnnotationArm64PriceBias: "10%"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedInstanceTypes())).To(ConsistOf("t3.large", "m5.large"))
+		})
+		It("should ignore an invalid bias", func() {
+			requireInstanceTypes("c6g.large", "t3.large", "m5.large")
+			nodePool.Annotations = map[string]string{v1.AnnotationArm64PriceBias: "150%"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedInstanceTypes())).To(ConsistOf("c6g.large", "t3.large", "m5.large"))
+		})
+		It("should raise the prices of amd64 offerings by the bias", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			unbiased, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			nodePool.Annotations = map[string]string{v1.AnnotationArm64PriceBias: "20%"}
+			ExpectApplied(ctx, env.Client, nodePool)
+			biased, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(biased).To(HaveLen(len(unbiased)))
+			for i := range biased {
+				expected := lo.Ternary(biased[i].Requirements.Get(corev1.LabelArchStable).Has(karpv1.ArchitectureAmd64), 1/0.8, 1.0)
+				for j := range biased[i].Offerings {
+					Expect(biased[i].Offerings[j].Price).To(BeNumerically("~", unbiased[i].Offerings[j].Price*expected, 1e-9))
+				}
+			}
+		})
+	})
+	Context("Performance Multipliers", func() {
+		It("should divide offering prices by the performance multiplier of the instance type or its family", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			unadjusted, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			awsEnv.InstanceTypesProvider.SetPerformanceMultipliers(map[string]float64{"m5": 1.25, "m5.large": 2, "c6g.large": 0.5})
+			adjusted, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(adjusted).To(HaveLen(len(unadjusted)))
+			for i := range adjusted {
+				expected := 1.0
+				switch {
+				case adjusted[i].Name == "m5.large":
+					expected = 1 / 2.0
+				case adjusted[i].Name == "c6g.large":
+					expected = 1 / 0.5
+				case strings.HasPrefix(adjusted[i].Name, "m5."):
+					expected = 1 / 1.25
+				}
+				for j := range adjusted[i].Offerings {
+					Expect(adjusted[i].Offerings[j].Price).To(BeNumerically("~", unadjusted[i].Offerings[j].Price*expected, 1e-9))
+				}
+			}
+		})
+	})
+	Context("Sustainability", func() {
+		launchedZones := func() sets.Set[string] {
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			zones := sets.New[string]()
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					zones.Insert(lo.FromPtr(override.AvailabilityZone))
+				}
+			}
+			return zones
+		}
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{CarbonIntensityParameter: lo.ToPtr("/karpenter/carbon-intensity")}))
+			awsEnv.SSMAPI.Parameters = map[string]string{
+				"/karpenter/carbon-intensity": `{"test-zone-1a": 100, "test-zone-1b": 500}`,
+			}
+			Expect(awsEnv.CarbonIntensityProvider.UpdateIntensities(ctx)).To(Succeed())
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelInstanceTypeStable,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"m5.large"},
+				},
+			})
+		})
+		It("should launch in any zone without a sustainability policy", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedZones())).To(ConsistOf("test-zone-1a", "test-zone-1b", "test-zone-1c"))
+		})
+		It("should only launch in the lowest carbon zone when sustainability is required", func() {
+			nodePool.Annotations = map[string]string{v1.AnnotationSustainability: "require"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedZones())).To(ConsistOf("test-zone-1a"))
+		})
+		It("should fail to launch when sustainability is required and the lowest carbon zone isn't allowed", func() {
+			nodeClaim.Spec.Requirements = append(nodeClaim.Spec.Requirements, karpv1.NodeSelectorRequirementWithMinValues{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelTopologyZone,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"test-zone-1b", "test-zone-1c"},
+				},
+			})
+			nodePool.Annotations = map[string]string{v1.AnnotationSustainability: "require"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+		})
+		It("should prefer the lowest carbon zone when prices are equal", func() {
+			nodePool.Annotations = map[string]string{v1.AnnotationSustainability: "prefer"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedZones())).To(ConsistOf("test-zone-1a"))
+		})
+		It("should launch in any zone when carbon intensities aren't known", func() {
+			awsEnv.CarbonIntensityProvider.Reset()
+			nodePool.Annotations = map[string]string{v1.AnnotationSustainability: "require"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sets.List(launchedZones())).To(ConsistOf("test-zone-1a", "test-zone-1b", "test-zone-1c"))
+		})
+		DescribeTable("should ignore a sustainability policy that doesn't apply",
+			func(policy string) {
+				nodePool.Annotations = map[string]string{v1.AnnotationSustainability: policy}
+				ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+				_, err := cloudProvider.Create(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sets.List(launchedZones())).To(ConsistOf("test-zone-1a", "test-zone-1b", "test-zone-1c"))
+			},
+			Entry("off", "off"),
+			Entry("invalid", "always"),
+		)
+		It("should weight offering prices by carbon intensity when sustainability is preferred", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			unweighted, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			nodePool.Annotations = map[string]string{v1.AnnotationSustainability: "prefer"}
+			ExpectApplied(ctx, env.Client, nodePool)
+			weighted, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(weighted).To(HaveLen(len(unweighted)))
+			for i := range weighted {
+				for j := range weighted[i].Offerings {
+					// The weight defaults to 0.5, and zones without a known carbon intensity are treated as the highest
+					expected := lo.Ternary(weighted[i].Offerings[j].Requirements.Get(corev1.LabelTopologyZone).Any() == "test-zone-1a", 1.0, 1.5)
+					Expect(weighted[i].Offerings[j].Price).To(BeNumerically("~", unweighted[i].Offerings[j].Price*expected, 1e-9))
+				}
+			}
+		})
+		It("should mark offerings outside of the lowest carbon zone unavailable when sustainability is required", func() {
+			nodePool.Annotations = map[string]string{v1.AnnotationSustainability: "require"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceTypes).ToNot(BeEmpty())
+			for _, it := range instanceTypes {
+				for _, o := range it.Offerings.Available() {
+					Expect(o.Requirements.Get(corev1.LabelTopologyZone).Any()).To(Equal("test-zone-1a"))
+				}
+			}
+		})
+	})
+	Context("Capacity Block", func() {
+		const id = "cr-0123456789abcdef0"
+		BeforeEach(func() {
+			nodeClass.Spec.CapacityBlock = &v1.CapacityBlock{ID: id, DrainBefore: metav1.Duration{Duration: time.Hour}}
+			nodeClass.Status.CapacityBlock = &v1.CapacityBlockStatus{
+				ID:           id,
+				InstanceType: "m5.large",
+				Zone:         "test-zone-1b",
+				State:        "active",
+				StartTime:    metav1.NewTime(time.Now().Add(-time.Hour)),
+				EndTime:      metav1.NewTime(time.Now().Add(24 * time.Hour)),
+			}
+		})
+		It("should only launch the Capacity Block's instance type and zone into the Capacity Block", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationCapacityBlockID, id))
+			Expect(cloudProviderNodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationCapacityBlockEndTime, nodeClass.Status.CapacityBlock.EndTime.UTC().Format(time.RFC3339)))
+
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			Expect(createFleetInput.TargetCapacitySpecification.DefaultTargetCapacityType).To(Equal(ec2types.DefaultTargetCapacityTypeCapacityBlock))
+			for _, ltc := range createFleetInput.LaunchTemplateConfigs {
+				for _, override := range ltc.Overrides {
+					Expect(string(override.InstanceType)).To(Equal("m5.large"))
+					Expect(aws.ToString(override.AvailabilityZone)).To(Equal("test-zone-1b"))
+				}
+			}
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(input *ec2.CreateLaunchTemplateInput) {
+				Expect(input.LaunchTemplateData.InstanceMarketOptions.MarketType).To(Equal(ec2types.MarketTypeCapacityBlock))
+				Expect(aws.ToString(input.LaunchTemplateData.CapacityReservationSpecification.CapacityReservationTarget.CapacityReservationId)).To(Equal(id))
+			})
+		})
+		It("should not launch before the Capacity Block starts", func() {
+			nodeClass.Status.CapacityBlock.StartTime = metav1.NewTime(time.Now().Add(time.Hour))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should not launch once the Capacity Block is within its drain window", func() {
+			nodeClass.Status.CapacityBlock.EndTime = metav1.NewTime(time.Now().Add(time.Hour))
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should not launch until the Capacity Block has been resolved", func() {
+			nodeClass.Status.CapacityBlock.ID = "cr-fedcba9876543210f"
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should only return the Capacity Block's offering as available", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(len(instanceTypes)).To(BeNumerically(">", 1))
+			for _, it := range instanceTypes {
+				for _, o := range it.Offerings.Available() {
+					Expect(it.Name).To(Equal("m5.large"))
+					Expect(o.Requirements.Get(corev1.LabelTopologyZone).Any()).To(Equal("test-zone-1b"))
+					Expect(o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any()).To(Equal(karpv1.CapacityTypeOnDemand))
+				}
+			}
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "m5.large" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Offerings.Available()).To(HaveLen(1))
+		})
+	})
+	Context("Packing Policy", func() {
+		packedPrices := func(policy string) ([]*corecloudprovider.InstanceType, []*corecloudprovider.InstanceType) {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			unpacked, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			nodePool.Annotations = map[string]string{v1.AnnotationPackingPolicy: policy}
+			ExpectApplied(ctx, env.Client, nodePool)
+			packed, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packed).To(HaveLen(len(unpacked)))
+			return unpacked, packed
+		}
+		expectPricesScaled := func(unpacked, packed []*corecloudprovider.InstanceType, exponent float64) {
+			smallest := lo.Min(lo.Map(unpacked, func(it *corecloudprovider.InstanceType, _ int) float64 {
+				return it.Capacity.Cpu().AsApproximateFloat64()
+			}))
+			for i := range packed {
+				expected := math.Pow(packed[i].Capacity.Cpu().AsApproximateFloat64()/smallest, exponent)
+				for j := range packed[i].Offerings {
+					Expect(packed[i].Offerings[j].Price).To(BeNumerically("~", unpacked[i].Offerings[j].Price*expected, 1e-9), packed[i].Name)
+				}
+			}
+		}
+		It("should lower the prices of larger instance types to consolidate", func() {
+			unpacked, packed := packedPrices("consolidate")
+			expectPricesScaled(unpacked, packed, -0.25)
+		})
+		It("should raise the prices of larger instance types to spread", func() {
+			unpacked, packed := packedPrices("spread")
+			expectPricesScaled(unpacked, packed, 0.25)
+		})
+		DescribeTable("should not change prices",
+			func(policy string) {
+				unpacked, packed := packedPrices(policy)
+				expectPricesScaled(unpacked, packed, 0)
+			},
+			Entry("when balanced", "balanced"),
+			Entry("when the policy is invalid", "pack-tightly"),
+		)
+	})
+	Context("Paused", func() {
+		It("should not launch capacity for a paused NodePool", func() {
+			nodePool.Annotations = map[string]string{v1.AnnotationPaused: "true"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should not launch capacity for a NodePool with a paused EC2NodeClass", func() {
+			nodeClass.Annotations = lo.Assign(nodeClass.Annotations, map[string]string{v1.AnnotationPaused: "true"})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should return instance types without available offerings for a paused NodePool", func() {
+			nodePool.Annotations = map[string]string{v1.AnnotationPaused: "true"}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceTypes).ToNot(BeEmpty())
+			for _, it := range instanceTypes {
+				Expect(it.Offerings.Available()).To(BeEmpty())
+			}
+		})
+	})
+	Context("Adoption", func() {
+		It("should resolve an adopted instance instead of launching one", func() {
+			instance := ec2types.Instance{
+				InstanceId:   aws.String(fake.InstanceID()),
+				InstanceType: "m5.large",
+				ImageId:      aws.String(fake.ImageID()),
+				SubnetId:     aws.String("subnet-test1"),
+				State:        &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+				Placement:    &ec2types.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+				LaunchTime:   aws.Time(time.Now()),
+			}
+			awsEnv.EC2API.Instances.Store(lo.FromPtr(instance.InstanceId), instance)
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationAdoptedInstanceID: lo.FromPtr(instance.InstanceId)})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			cloudProviderNodeClaim, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cloudProviderNodeClaim.Status.ProviderID).To(Equal(fake.ProviderID(lo.FromPtr(instance.InstanceId))))
+			Expect(cloudProviderNodeClaim.Labels).To(HaveKeyWithValue(corev1.LabelInstanceTypeStable, "m5.large"))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+			Expect(awsEnv.EC2API.CreateTagsBehavior.CalledWithInput.Len()).To(Equal(1))
+			input := awsEnv.EC2API.CreateTagsBehavior.CalledWithInput.Pop()
+			Expect(input.Resources).To(ConsistOf(lo.FromPtr(instance.InstanceId)))
+			Expect(input.Tags).To(ContainElement(ec2types.Tag{Key: aws.String(karpv1.NodePoolLabelKey), Value: aws.String(nodePool.Name)}))
+		})
+		It("should return an ICE error when the adopted instance doesn't exist", func() {
+			nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationAdoptedInstanceID: fake.InstanceID()})
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass, nodeClaim)
+			_, err := cloudProvider.Create(ctx, nodeClaim)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+	})
+	Context("Ordered Daemon Eviction", func() {
+		var node *corev1.Node
+		daemonPod := func(order string) *corev1.Pod {
+			return coretest.Pod(coretest.PodOptions{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: lo.Ternary(order != "", map[string]string{v1.AnnotationEvictionOrder: order}, nil),
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "apps/v1",
+						Kind:       "DaemonSet",
+						Name:       "daemonset",
+						UID:        "daemonset-uid",
+						Controller: lo.ToPtr(true),
+					}},
+				},
+				NodeName: node.Name,
+			})
+		}
+		BeforeEach(func() {
+			node = coretest.Node()
+			nodeClaim.Finalizers = []string{karpv1.TerminationFinalizer}
+			nodeClaim.Status.ProviderID = fake.ProviderID(fake.InstanceID())
+			nodeClaim.Status.NodeName = node.Name
+		})
+		expectTerminating := func(pod *corev1.Pod, terminating bool) {
+			GinkgoHelper()
+			Expect(ExpectExists(ctx, env.Client, pod).DeletionTimestamp.IsZero()).To(Equal(!terminating))
+		}
+		It("should evict opted-in daemonset pods in order before terminating the instance", func() {
+			first, second, other := daemonPod("1"), daemonPod("2"), daemonPod("")
+			ExpectApplied(ctx, env.Client, nodeClaim, node, first, second, other)
+			ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+
+			err := cloudProvider.Delete(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			expectTerminating(first, true)
+			expectTerminating(second, false)
+
+			Expect(env.Client.Delete(ctx, first, client.GracePeriodSeconds(0))).To(Succeed())
+			err = cloudProvider.Delete(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			expectTerminating(second, true)
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(0))
+
+			Expect(env.Client.Delete(ctx, second, client.GracePeriodSeconds(0))).To(Succeed())
+			err = cloudProvider.Delete(ctx, nodeClaim)
+			// The instance doesn't exist, but we've moved on to terminating it
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			expectTerminating(other, false)
+		})
+		It("should not wait on daemonset pods once the terminationGracePeriod has elapsed", func() {
+			nodeClaim.Spec.TerminationGracePeriod = &metav1.Duration{Duration: time.Second}
+			pod := daemonPod("1")
+			ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+			ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+			nodeClaim.DeletionTimestamp = lo.ToPtr(metav1.NewTime(time.Now().Add(-time.Minute)))
+
+			err := cloudProvider.Delete(ctx, nodeClaim)
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			expectTerminating(pod, false)
+		})
+		It("should not evict daemonset pods created after the NodeClaim began terminating", func() {
+			pod := daemonPod("1")
+			ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+			ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+			nodeClaim.DeletionTimestamp = lo.ToPtr(metav1.NewTime(time.Now().Add(-time.Minute)))
+
+			err := cloudProvider.Delete(ctx, nodeClaim)
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			expectTerminating(pod, false)
+		})
+	})
+	Context("Pre-Termination Commands", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.PreTermination = &v1.PreTermination{
+				Commands: []string{"nvidia-smi -pm 0"},
+				Timeout:  metav1.Duration{Duration: 2 * time.Minute},
+			}
+			nodeClaim.Finalizers = []string{karpv1.TerminationFinalizer}
+			nodeClaim.Status.ProviderID = fake.ProviderID(fake.InstanceID())
+		})
+		It("should run the commands and wait for them to complete before terminating the instance", func() {
+			awsEnv.SSMAPI.GetCommandInvocationBehavior.Output.Set(&ssm.GetCommandInvocationOutput{Status: ssmtypes.CommandInvocationStatusInProgress})
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+
+			err := cloudProvider.Delete(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.SSMAPI.SendCommandBehavior.CalledWithInput.Len()).To(Equal(1))
+			input := awsEnv.SSMAPI.SendCommandBehavior.CalledWithInput.Pop()
+			Expect(aws.ToString(input.DocumentName)).To(Equal("AWS-RunShellScript"))
+			Expect(input.Parameters).To(HaveKeyWithValue("commands", []string{"nvidia-smi -pm 0"}))
+			Expect(input.Parameters).To(HaveKeyWithValue("executionTimeout", []string{"120"}))
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Annotations).To(HaveKey(v1.AnnotationPreTerminationCommandID))
+
+			err = cloudProvider.Delete(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(awsEnv.SSMAPI.SendCommandBehavior.CalledWithInput.Len()).To(Equal(0))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(0))
+
+			awsEnv.SSMAPI.GetCommandInvocationBehavior.Output.Set(&ssm.GetCommandInvocationOutput{Status: ssmtypes.CommandInvocationStatusSuccess})
+			err = cloudProvider.Delete(ctx, nodeClaim)
+			// The instance doesn't exist, but we've moved on to terminating it
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+		})
+		It("should terminate the instance once the commands have failed", func() {
+			awsEnv.SSMAPI.GetCommandInvocationBehavior.Output.Set(&ssm.GetCommandInvocationOutput{Status: ssmtypes.CommandInvocationStatusTimedOut})
+			nodeClaim.Annotations = map[string]string{v1.AnnotationPreTerminationCommandID: "command-id"}
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+
+			err := cloudProvider.Delete(ctx, nodeClaim)
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			Expect(awsEnv.SSMAPI.SendCommandBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+		It("should terminate the instance if the commands can't be sent", func() {
+			awsEnv.SSMAPI.SendCommandBehavior.Error.Set(fmt.Errorf("InvalidInstanceId"))
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+
+			err := cloudProvider.Delete(ctx, nodeClaim)
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			Expect(ExpectExists(ctx, env.Client, nodeClaim).Annotations).ToNot(HaveKey(v1.AnnotationPreTerminationCommandID))
+		})
+		It("should terminate the instance if the EC2NodeClass has been deleted", func() {
+			instanceID := fake.InstanceID()
+			awsEnv.EC2API.Instances.Store(instanceID, ec2types.Instance{
+				InstanceId:   aws.String(instanceID),
+				InstanceType: "m5.large",
+				State:        &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+				Placement:    &ec2types.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+				LaunchTime:   aws.Time(time.Now()),
+			})
+			nodeClaim.Status.ProviderID = fake.ProviderID(instanceID)
+			ExpectApplied(ctx, env.Client, nodeClaim)
+			ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.SSMAPI.SendCommandBehavior.CalledWithInput.Len()).To(Equal(0))
+			Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+			_, ok := awsEnv.EC2API.Instances.Load(instanceID)
+			Expect(ok).To(BeFalse())
+		})
+		It("should use the PowerShell document for Windows AMI families", func() {
+			nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyWindows2022)
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2022@latest"}}
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+
+			_ = cloudProvider.Delete(ctx, nodeClaim)
+			Expect(awsEnv.SSMAPI.SendCommandBehavior.CalledWithInput.Len()).To(Equal(1))
+			Expect(aws.ToString(awsEnv.SSMAPI.SendCommandBehavior.CalledWithInput.Pop().DocumentName)).To(Equal("AWS-RunPowerShellScript"))
+		})
+		It("should not run commands for NodeClaims that aren't terminating", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			err := cloudProvider.Delete(ctx, nodeClaim)
+			Expect(corecloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+			Expect(awsEnv.SSMAPI.SendCommandBehavior.CalledWithInput.Len()).To(Equal(0))
+		})
+	})
+	Context("Termination Protection", func() {
+		var instanceID string
+		BeforeEach(func() {
+			instanceID = fake.InstanceID()
+			awsEnv.EC2API.Instances.Store(instanceID, ec2types.Instance{
+				InstanceId:   aws.String(instanceID),
+				InstanceType: "m5.large",
+				State:        &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+				Placement:    &ec2types.Placement{AvailabilityZone: aws.String("test-zone-1a")},
+				LaunchTime:   aws.Time(time.Now()),
+			})
+			awsEnv.EC2API.TerminationProtectedInstances.Store(instanceID, true)
+			nodeClaim.Finalizers = []string{karpv1.TerminationFinalizer}
+			nodeClaim.Status.ProviderID = fake.ProviderID(instanceID)
+			ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+			ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+		})
+		It("should surface a condition when the instance has termination protection enabled", func() {
+			err := cloudProvider.Delete(ctx, nodeClaim)
+			Expect(awserrors.IsTerminationProtected(err)).To(BeTrue())
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeTerminationProtected).IsTrue()).To(BeTrue())
+			Expect(awsEnv.EC2API.ModifyInstanceAttributeBehavior.CalledWithInput.Len()).To(Equal(0))
+			_, ok := awsEnv.EC2API.Instances.Load(instanceID)
+			Expect(ok).To(BeTrue())
+		})
+		It("should remove termination protection before terminating the instance when enabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AWSFeatureGates: options.FeatureGates{options.RemoveTerminationProtection: true}}))
+			Expect(cloudProvider.Delete(ctx, nodeClaim)).To(Succeed())
+			Expect(awsEnv.EC2API.ModifyInstanceAttributeBehavior.CalledWithInput.Len()).To(Equal(1))
+			input := awsEnv.EC2API.ModifyInstanceAttributeBehavior.CalledWithInput.Pop()
+			Expect(aws.ToString(input.InstanceId)).To(Equal(instanceID))
+			Expect(aws.ToBool(input.DisableApiTermination.Value)).To(BeFalse())
+			_, ok := awsEnv.EC2API.Instances.Load(instanceID)
+			Expect(ok).To(BeFalse())
+			Expect(ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeTerminationProtected)).To(BeNil())
+		})
+	})
 	Context("EC2 Context", func() {
 		contextID := "context-1234"
 		It("should set context on the CreateFleet request if specified on the NodePool", func() {
@@ -778,6 +1635,31 @@ var _ = Describe("CloudProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
 		})
+		It("should not return drifted if the NodePool is paused", func() {
+			instance.ImageId = aws.String(fake.ImageID())
+			awsEnv.EC2API.DescribeInstancesBehavior.Output.Set(&ec2.DescribeInstancesOutput{
+				Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{instance}}},
+			})
+			nodePool.Annotations = map[string]string{v1.AnnotationPaused: "true"}
+			ExpectApplied(ctx, env.Client, nodePool)
+			isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(BeEmpty())
+		})
+		It("should return drifted once a scheduled change is within the lead time", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ScheduledChangeLeadTime: lo.ToPtr(24 * time.Hour)}))
+			nodeClaim.Annotations[v1.AnnotationScheduledMaintenanceTime] = time.Now().Add(12 * time.Hour).UTC().Format(time.RFC3339)
+			isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(Equal(cloudprovider.ScheduledMaintenanceDrift))
+		})
+		It("should not return drifted if a scheduled change is outside of the lead time", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ScheduledChangeLeadTime: lo.ToPtr(24 * time.Hour)}))
+			nodeClaim.Annotations[v1.AnnotationScheduledMaintenanceTime] = time.Now().Add(72 * time.Hour).UTC().Format(time.RFC3339)
+			isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isDrifted).To(BeEmpty())
+		})
 		It("should return drifted if there are multiple drift reasons", func() {
 			// Instance is a reference to what we return in the GetInstances call
 			instance.ImageId = aws.String(fake.ImageID())
@@ -903,6 +1785,117 @@ var _ = Describe("CloudProvider", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
 		})
+		Context("AMI Rollout Policy", func() {
+			var outdatedAMIID string
+			var other *karpv1.NodeClaim
+			BeforeEach(func() {
+				outdatedAMIID = fake.ImageID()
+				instance.ImageId = aws.String(outdatedAMIID)
+				awsEnv.EC2API.DescribeInstancesBehavior.Output.Set(&ec2.DescribeInstancesOutput{
+					Reservations: []ec2types.Reservation{{Instances: []ec2types.Instance{instance}}},
+				})
+				nodeClass.Spec.AMIRolloutPolicy = &v1.AMIRolloutPolicy{Canary: "1", CanaryDuration: metav1.Duration{Duration: 10 * time.Minute}}
+				nodeClaim.Name = "nodeclaim-a"
+				nodeClaim.Status.ImageID = outdatedAMIID
+				other = nodeClaim.DeepCopy()
+				other.Name = "nodeclaim-b"
+				ExpectApplied(ctx, env.Client, nodeClass, nodeClaim, other)
+			})
+			canary := func(readySince time.Time) {
+				node := coretest.Node(coretest.NodeOptions{
+					ReadyStatus: corev1.ConditionTrue,
+				})
+				ExpectApplied(ctx, env.Client, node)
+				node.Status.Conditions = lo.Map(node.Status.Conditions, func(cond corev1.NodeCondition, _ int) corev1.NodeCondition {
+					if cond.Type == corev1.NodeReady {
+						cond.LastTransitionTime = metav1.NewTime(readySince)
+					}
+					return cond
+				})
+				ExpectApplied(ctx, env.Client, node)
+				updated := coretest.NodeClaim(karpv1.NodeClaim{
+					Spec: karpv1.NodeClaimSpec{NodeClassRef: nodeClaim.Spec.NodeClassRef},
+					Status: karpv1.NodeClaimStatus{
+						ImageID:  amdAMIID,
+						NodeName: node.Name,
+					},
+				})
+				ExpectApplied(ctx, env.Client, updated)
+			}
+			It("should only drift the canary NodeClaims", func() {
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
+				isDrifted, err = cloudProvider.IsDrifted(ctx, other)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(BeEmpty())
+			})
+			It("should hold drift while the canary nodes haven't been ready for the canary duration", func() {
+				canary(time.Now().Add(-time.Minute))
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(BeEmpty())
+				isDrifted, err = cloudProvider.IsDrifted(ctx, other)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(BeEmpty())
+			})
+			It("should drift the remaining NodeClaims once the canary nodes are healthy", func() {
+				canary(time.Now().Add(-20 * time.Minute))
+				isDrifted, err := cloudProvider.IsDrifted(ctx, other)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
+			})
+			It("should continue to drift NodeClaims that are already drifted", func() {
+				canary(time.Now().Add(-time.Minute))
+				other.StatusConditions().SetTrue(karpv1.ConditionTypeDrifted)
+				ExpectApplied(ctx, env.Client, other)
+				isDrifted, err := cloudProvider.IsDrifted(ctx, other)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(Equal(cloudprovider.AMIDrift))
+			})
+		})
+		Context("Kubelet Upgrade Rollout", func() {
+			var outdatedKubeletVersion string
+			node := func(nodePoolName string, kubeletVersion string) *corev1.Node {
+				n := coretest.Node(coretest.NodeOptions{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{karpv1.NodePoolLabelKey: nodePoolName}}})
+				n.Status.NodeInfo.KubeletVersion = kubeletVersion
+				ExpectApplied(ctx, env.Client, n)
+				ExpectApplied(ctx, env.Client, n)
+				return n
+			}
+			BeforeEach(func() {
+				ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AWSFeatureGates: options.FeatureGates{options.KubeletUpgradeRollout: true}}))
+				minor := k8sversion.MustParseGeneric(awsEnv.VersionProvider.Get(ctx)).Minor()
+				outdatedKubeletVersion = fmt.Sprintf("v1.%d.5-eks-5e0fdde", minor-1)
+			})
+			It("should drift NodeClaims whose kubelet is older than the control plane", func() {
+				nodeClaim.Status.NodeName = node(nodePool.Name, outdatedKubeletVersion).Name
+				ExpectApplied(ctx, env.Client, nodeClaim)
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(Equal(cloudprovider.KubeletVersionDrift))
+			})
+			It("should not drift NodeClaims whose kubelet matches the control plane", func() {
+				nodeClaim.Status.NodeName = node(nodePool.Name, fmt.Sprintf("v%s.5-eks-5e0fdde", awsEnv.VersionProvider.Get(ctx))).Name
+				ExpectApplied(ctx, env.Client, nodeClaim)
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(BeEmpty())
+			})
+			It("should hold drift until the NodePools before it have been replaced", func() {
+				nodeClaim.Status.NodeName = node(nodePool.Name, outdatedKubeletVersion).Name
+				ExpectApplied(ctx, env.Client, nodeClaim)
+				first := node("0-first", outdatedKubeletVersion)
+				isDrifted, err := cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(BeEmpty())
+
+				ExpectDeleted(ctx, env.Client, first)
+				isDrifted, err = cloudProvider.IsDrifted(ctx, nodeClaim)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(isDrifted).To(Equal(cloudprovider.KubeletVersionDrift))
+			})
+		})
 		Context("Static Drift Detection", func() {
 			BeforeEach(func() {
 				armRequirements := []corev1.NodeSelectorRequirement{
@@ -1148,7 +2141,7 @@ var _ = Describe("CloudProvider", func() {
 				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(100),
 					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
 			}})
-			controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider)
+			controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, awsEnv.CapacityReservationProvider, awsEnv.VPCEndpointProvider, awsEnv.AccessEntryProvider)
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
 			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{corev1.LabelTopologyZone: "test-zone-1a"}})
@@ -1165,7 +2158,7 @@ var _ = Describe("CloudProvider", func() {
 				{SubnetId: aws.String("test-subnet-2"), AvailabilityZone: aws.String("test-zone-1a"), AvailabilityZoneId: aws.String("tstz1-1a"), AvailableIpAddressCount: aws.Int32(11),
 					Tags: []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("test-subnet-2")}}},
 			}})
-			controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider)
+			controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, awsEnv.CapacityReservationProvider, awsEnv.VPCEndpointProvider, awsEnv.AccessEntryProvider)
 			nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{
 				MaxPods: aws.Int32(1),
 			}
@@ -1206,7 +2199,7 @@ var _ = Describe("CloudProvider", func() {
 			}})
 			nodeClass.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{Tags: map[string]string{"Name": "test-subnet-1"}}}
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
-			controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider)
+			controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, awsEnv.CapacityReservationProvider, awsEnv.VPCEndpointProvider, awsEnv.AccessEntryProvider)
 			ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
 			podSubnet1 := coretest.UnschedulablePod()
 			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, podSubnet1)
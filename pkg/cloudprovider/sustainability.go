@@ -0,0 +1,150 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+const (
+	sustainabilityPrefer  = "prefer"
+	sustainabilityRequire = "require"
+	sustainabilityOff     = "off"
+)
+
+// sustainabilityPolicy returns the NodePool's sustainability policy, parsed from its sustainability annotation. Invalid
+// values are ignored, as with the arm64 price bias.
+func sustainabilityPolicy(ctx context.Context, nodePool *karpv1.NodePool) (string, bool) {
+	value, ok := nodePool.Annotations[v1.AnnotationSustainability]
+	if !ok {
+		return "", false
+	}
+	switch value {
+	case sustainabilityPrefer, sustainabilityRequire:
+		return value, true
+	case sustainabilityOff:
+		return "", false
+	}
+	log.FromContext(ctx).WithValues("NodePool", nodePool.Name).Error(fmt.Errorf("must be one of %q, %q or %q", sustainabilityPrefer, sustainabilityRequire, sustainabilityOff),
+		fmt.Sprintf("ignoring invalid %s", v1.AnnotationSustainability))
+	return "", false
+}
+
+// sustainableInstanceTypes returns copies of the instance types with their offerings adjusted by the sustainability policy.
+// "prefer" raises the price of each offering by up to the carbon-intensity-weight, in proportion to the carbon intensity of
+// its zone relative to the least and most carbon intensive zones, so that scheduling and consolidation only choose a more
+// carbon intensive zone when it's cheaper by more than the difference. Zones without a known carbon intensity are treated as
+// the most carbon intensive. "require" marks the offerings outside of the least carbon intensive zones as unavailable. The
+// instance types are returned unchanged if no carbon intensities are known, so that a missing data source doesn't block
+// launches.
+func sustainableInstanceTypes(ctx context.Context, instanceTypes []*cloudprovider.InstanceType, policy string, intensities map[string]float64) []*cloudprovider.InstanceType {
+	if len(intensities) == 0 {
+		return instanceTypes
+	}
+	lowest, highest := lo.Min(lo.Values(intensities)), lo.Max(lo.Values(intensities))
+	weight := options.FromContext(ctx).CarbonIntensityWeight
+	return lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) *cloudprovider.InstanceType {
+		return withOfferings(it, lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) cloudprovider.Offering {
+			intensity, ok := intensities[o.Requirements.Get(corev1.LabelTopologyZone).Any()]
+			if policy == sustainabilityRequire {
+				return cloudprovider.Offering{Requirements: o.Requirements, Price: o.Price, Available: o.Available && ok && intensity == lowest}
+			}
+			if !ok {
+				intensity = highest
+			}
+			multiplier := 1.0
+			if highest > lowest {
+				multiplier += weight * (intensity - lowest) / (highest - lowest)
+			}
+			return cloudprovider.Offering{Requirements: o.Requirements, Price: o.Price * multiplier, Available: o.Available}
+		}))
+	})
+}
+
+// zoneIntensities returns the carbon intensity of each zone that the instance types are offered in, omitting the zones
+// whose carbon intensity isn't known
+func (c *CloudProvider) zoneIntensities(instanceTypes []*cloudprovider.InstanceType) map[string]float64 {
+	intensities := map[string]float64{}
+	for _, it := range instanceTypes {
+		for _, o := range it.Offerings {
+			zone := o.Requirements.Get(corev1.LabelTopologyZone).Any()
+			if intensity, ok := c.carbonIntensityProvider.Intensity(zone); ok {
+				intensities[zone] = intensity
+			}
+		}
+	}
+	return intensities
+}
+
+// filterBySustainability restricts a launch to the zones chosen by the NodePool's sustainability policy. Fleet launches with
+// the lowest-price allocation strategy, so without this the real prices would override the carbon weighted prices that the
+// scheduler used to choose the NodeClaim. "prefer" restricts the launch to the zones of the offerings with the lowest carbon
+// weighted price, while "require" restricts it to the least carbon intensive zones and fails the launch if they don't have
+// capacity available.
+func (c *CloudProvider) filterBySustainability(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool,
+	instanceTypes []*cloudprovider.InstanceType) ([]*cloudprovider.InstanceType, error) {
+	if nodePool == nil {
+		return instanceTypes, nil
+	}
+	policy, ok := sustainabilityPolicy(ctx, nodePool)
+	if !ok {
+		return instanceTypes, nil
+	}
+	intensities := c.zoneIntensities(instanceTypes)
+	if len(intensities) == 0 {
+		return instanceTypes, nil
+	}
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	offerings := lo.FlatMap(sustainableInstanceTypes(ctx, instanceTypes, policy, intensities), func(it *cloudprovider.InstanceType, _ int) []cloudprovider.Offering {
+		return it.Offerings.Compatible(reqs).Available()
+	})
+	if len(offerings) == 0 {
+		return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("no capacity available in the least carbon intensive zones of nodepool %q", nodePool.Name))
+	}
+	targetZones := sets.New[string]()
+	if policy == sustainabilityRequire {
+		targetZones.Insert(lo.Map(offerings, func(o cloudprovider.Offering, _ int) string {
+			return o.Requirements.Get(corev1.LabelTopologyZone).Any()
+		})...)
+	} else {
+		cheapest := lo.MinBy(offerings, func(a, b cloudprovider.Offering) bool { return a.Price < b.Price }).Price
+		targetZones.Insert(lo.FilterMap(offerings, func(o cloudprovider.Offering, _ int) (string, bool) {
+			return o.Requirements.Get(corev1.LabelTopologyZone).Any(), o.Price == cheapest
+		})...)
+	}
+	log.FromContext(ctx).WithValues("NodePool", nodePool.Name, "zones", sets.List(targetZones)).V(1).Info("restricting launch to sustainable zones")
+	return lo.FilterMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) (*cloudprovider.InstanceType, bool) {
+		offerings := lo.Filter(it.Offerings, func(o cloudprovider.Offering, _ int) bool {
+			return targetZones.Has(o.Requirements.Get(corev1.LabelTopologyZone).Any())
+		})
+		if len(offerings) == 0 {
+			return nil, false
+		}
+		return withOfferings(it, offerings), true
+	}), nil
+}
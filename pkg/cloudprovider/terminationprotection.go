@@ -0,0 +1,48 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	cloudproviderevents "github.com/aws/karpenter-provider-aws/pkg/cloudprovider/events"
+)
+
+// surfaceTerminationProtection sets the TerminationProtected condition on a NodeClaim whose instance couldn't be terminated
+// because termination protection was enabled on it out of band, so that the cause is visible rather than buried in the
+// termination controller's retries.
+func (c *CloudProvider) surfaceTerminationProtection(ctx context.Context, nodeClaim *karpv1.NodeClaim, id string) error {
+	if nodeClaim.StatusConditions().Get(v1.ConditionTypeTerminationProtected).IsTrue() {
+		return nil
+	}
+	log.FromContext(ctx).Info("instance has termination protection enabled, retrying termination until it's removed")
+	c.recorder.Publish(cloudproviderevents.NodeClaimTerminationProtected(nodeClaim, id))
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.StatusConditions().SetTrueWithReason(v1.ConditionTypeTerminationProtected, "DisableApiTermination",
+		fmt.Sprintf("Instance %s has the disableApiTermination attribute enabled", id))
+	// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+	// can cause races due to the fact that it fully replaces the list on a change
+	if err := c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
+)
+
+// KubeletVersionDrift is reported for NodeClaims whose kubelet is older than the control plane during a kubelet upgrade rollout
+const KubeletVersionDrift cloudprovider.DriftReason = "KubeletVersionDrift"
+
+// isKubeletVersionDrifted returns whether the NodeClaim is drifted by a kubelet upgrade rollout. NodeClaims whose kubelet is
+// older than the control plane are drifted one NodePool at a time, in the order of the NodePools' names. Drift of the
+// NodeClaims of the other NodePools is held, including drift for other reasons, so that they're replaced pool-by-pool.
+// held is true if the NodeClaim is outdated but its NodePool's turn hasn't come yet.
+func (c *CloudProvider) isKubeletVersionDrifted(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodePool *karpv1.NodePool) (drifted cloudprovider.DriftReason, held bool, err error) {
+	// A NodeClaim which was already selected shouldn't stop drifting as the rollout progresses
+	if nodeClaim.StatusConditions().Get(karpv1.ConditionTypeDrifted).IsTrue() || nodeClaim.Status.NodeName == "" {
+		return "", false, nil
+	}
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return "", false, client.IgnoreNotFound(err)
+	}
+	kubernetesVersion := c.versionProvider.Get(ctx)
+	if !version.IsOlderMinor(node.Status.NodeInfo.KubeletVersion, kubernetesVersion) {
+		return "", false, nil
+	}
+	nodeList := &corev1.NodeList{}
+	if err := c.kubeClient.List(ctx, nodeList, client.HasLabels{karpv1.NodePoolLabelKey}); err != nil {
+		return "", false, fmt.Errorf("listing nodes, %w", err)
+	}
+	// The rollout is at the first NodePool, by name, which still has Nodes with an outdated kubelet
+	current := lo.Min(lo.FilterMap(nodeList.Items, func(n corev1.Node, _ int) (string, bool) {
+		return n.Labels[karpv1.NodePoolLabelKey], version.IsOlderMinor(n.Status.NodeInfo.KubeletVersion, kubernetesVersion)
+	}))
+	if current != "" && current < nodePool.Name {
+		log.FromContext(ctx).WithValues("NodePool", nodePool.Name, "rollout-nodepool", current, "kubernetes-version", kubernetesVersion).
+			V(1).Info("holding drift until the kubelet upgrade rollout reaches the nodepool")
+		return "", true, nil
+	}
+	return KubeletVersionDrift, false, nil
+}
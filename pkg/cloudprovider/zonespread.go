@@ -0,0 +1,103 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudprovider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// filterByZoneSpread restricts the offerings of the passed instance types to the zones that currently have the fewest
+// NodeClaims in the NodeClaim's NodePool, according to the EC2NodeClass zoneSpreadPolicy. This keeps replacement capacity
+// for drifted or expired nodes from collapsing into whichever zone happens to be the cheapest at the time of launch.
+func (c *CloudProvider) filterByZoneSpread(ctx context.Context, nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass,
+	instanceTypes []*cloudprovider.InstanceType) ([]*cloudprovider.InstanceType, error) {
+	policy := lo.FromPtr(nodeClass.Spec.ZoneSpreadPolicy)
+	nodePoolName, ok := nodeClaim.Labels[karpv1.NodePoolLabelKey]
+	if policy == "" || !ok {
+		return instanceTypes, nil
+	}
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	// Strict considers every zone that the instance types are offered in, so that a least-populated zone without capacity
+	// fails the launch. Preferred only considers zones which currently have available capacity.
+	zones := sets.New[string]()
+	for _, it := range instanceTypes {
+		offerings := it.Offerings.Compatible(reqs)
+		if policy == v1.ZoneSpreadPolicyPreferred {
+			offerings = offerings.Available()
+		}
+		for _, o := range offerings {
+			zones.Insert(o.Requirements.Get(corev1.LabelTopologyZone).Any())
+		}
+	}
+	if zones.Len() <= 1 {
+		return instanceTypes, nil
+	}
+	counts, err := c.nodePoolZoneCounts(ctx, nodePoolName, nodeClaim)
+	if err != nil {
+		return nil, cloudprovider.NewCreateError(fmt.Errorf("resolving nodepool zone distribution, %w", err), "Error resolving NodePool zone distribution")
+	}
+	minCount := lo.Min(lo.Map(sets.List(zones), func(zone string, _ int) int { return counts[zone] }))
+	targetZones := sets.New(lo.Filter(sets.List(zones), func(zone string, _ int) bool { return counts[zone] == minCount })...)
+	if targetZones.Len() == zones.Len() {
+		return instanceTypes, nil
+	}
+	filtered := lo.FilterMap(instanceTypes, func(it *cloudprovider.InstanceType, _ int) (*cloudprovider.InstanceType, bool) {
+		offerings := lo.Filter(it.Offerings, func(o cloudprovider.Offering, _ int) bool {
+			return targetZones.Has(o.Requirements.Get(corev1.LabelTopologyZone).Any())
+		})
+		if len(offerings) == 0 {
+			return nil, false
+		}
+		return withOfferings(it, offerings), true
+	})
+	if lo.ContainsBy(filtered, func(it *cloudprovider.InstanceType) bool { return len(it.Offerings.Compatible(reqs).Available()) > 0 }) {
+		log.FromContext(ctx).WithValues("NodePool", nodePoolName, "zones", sets.List(targetZones)).V(1).Info("restricting launch to least-populated zones")
+		return filtered, nil
+	}
+	return nil, cloudprovider.NewInsufficientCapacityError(fmt.Errorf("no capacity available in least-populated zones %v of nodepool %q", sets.List(targetZones), nodePoolName))
+}
+
+// nodePoolZoneCounts returns the number of NodeClaims in each zone for the NodePool, excluding the passed NodeClaim. NodeClaims
+// that are being deleted or have drifted aren't counted, since they are expected to be replaced.
+func (c *CloudProvider) nodePoolZoneCounts(ctx context.Context, nodePoolName string, nodeClaim *karpv1.NodeClaim) (map[string]int, error) {
+	nodeClaimList := &karpv1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList, client.MatchingLabels{karpv1.NodePoolLabelKey: nodePoolName}); err != nil {
+		return nil, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	counts := map[string]int{}
+	for i := range nodeClaimList.Items {
+		nc := &nodeClaimList.Items[i]
+		if nc.Name == nodeClaim.Name || !nc.DeletionTimestamp.IsZero() || nc.StatusConditions().Get(karpv1.ConditionTypeDrifted).IsTrue() {
+			continue
+		}
+		if zone, ok := nc.Labels[corev1.LabelTopologyZone]; ok {
+			counts[zone]++
+		}
+	}
+	return counts, nil
+}
@@ -30,35 +30,82 @@ import (
 	nodeclasshash "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/hash"
 	nodeclassstatus "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/status"
 	nodeclasstermination "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/termination"
+	controllerscarbonintensity "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/carbonintensity"
 	controllersinstancetype "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype"
 	controllersinstancetypecapacity "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype/capacity"
+	controllersinstancetypememoryoverhead "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype/memoryoverhead"
+	controllersinstancetypeperformance "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype/performance"
+	controllerslaunchtemplate "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/launchtemplate"
+	controllersleakedresource "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/leakedresource"
 	controllerspricing "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/pricing"
+	controllersquota "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/quota"
+	controllersquotaledger "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/quota/ledger"
 	ssminvalidation "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/ssm/invalidation"
 	controllersversion "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/version"
+	controllerszonalshift "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/zonalshift"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	servicesqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/utils/clock"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/utils/env"
 
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	diagnosticscontroller "github.com/aws/karpenter-provider-aws/pkg/controllers/diagnostics"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption"
+	interruptionredrive "github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/redrive"
+	nodeclaimadoption "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/adoption"
+	nodeclaimamiprovenance "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/amiprovenance"
+	nodeclaimbillingboundary "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/billingboundary"
+	nodeclaimcapacityblock "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/capacityblock"
+	nodeclaimdaemonreadiness "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/daemonreadiness"
+	nodeclaimdeprovisioningwebhook "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/deprovisioningwebhook"
+	nodeclaimdisruptionapproval "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/disruptionapproval"
+	nodeclaimelasticip "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/elasticip"
+	nodeclaimexternaldrain "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/externaldrain"
 	nodeclaimgarbagecollection "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/garbagecollection"
+	nodeclaimgracefultermination "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/gracefultermination"
+	nodeclaimlaunchvalidation "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/launchvalidation"
+	nodeclaimmetadatasync "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/metadatasync"
+	nodeclaimpdboverride "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/pdboverride"
+	nodeclaimpinning "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/pinning"
+	nodeclaimpodcapacity "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/podcapacity"
+	nodeclaimregistrationreboot "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/registrationreboot"
+	nodeclaimstoppedinstance "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/stoppedinstance"
+	nodeclaimstuckpod "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/stuckpod"
 	nodeclaimtagging "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/tagging"
+	nodeclaimterminationreason "github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/terminationreason"
+	nodepoolpause "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/pause"
+	nodepoolprovisioningblocked "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/provisioningblocked"
+	nodepoolsatisfiability "github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/satisfiability"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accessentry"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amiprovenance"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/carbonintensity"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/diagnostics"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/elasticip"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/leakedresource"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
 )
 
 func NewControllers(
@@ -67,6 +114,7 @@ func NewControllers(
 	cfg aws.Config,
 	clk clock.Clock,
 	kubeClient client.Client,
+	kubernetesInterface kubernetes.Interface,
 	recorder events.Recorder,
 	unavailableOfferings *awscache.UnavailableOfferings,
 	ssmCache *cache.Cache,
@@ -76,28 +124,126 @@ func NewControllers(
 	instanceProfileProvider instanceprofile.Provider,
 	instanceProvider instance.Provider,
 	pricingProvider pricing.Provider,
+	quotaProvider quota.Provider,
+	carbonIntensityProvider carbonintensity.Provider,
+	elasticIPProvider elasticip.Provider,
+	leakedResourceProvider leakedresource.Provider,
+	kmsProvider kms.Provider,
+	capacityReservationProvider capacityreservation.Provider,
+	vpcEndpointProvider vpcendpoint.Provider,
+	accessEntryProvider accessentry.Provider,
+	diagnosticsProvider diagnostics.Provider,
 	amiProvider amifamily.Provider,
+	amiProvenanceProvider amiprovenance.Provider,
 	launchTemplateProvider launchtemplate.Provider,
 	versionProvider *version.DefaultProvider,
 	instanceTypeProvider *instancetype.DefaultProvider) []controller.Controller {
 	controllers := []controller.Controller{
 		nodeclasshash.NewController(kubeClient),
-		nodeclassstatus.NewController(kubeClient, subnetProvider, securityGroupProvider, amiProvider, instanceProfileProvider, launchTemplateProvider),
-		nodeclasstermination.NewController(kubeClient, recorder, instanceProfileProvider, launchTemplateProvider),
+		nodeclassstatus.NewController(kubeClient, subnetProvider, securityGroupProvider, amiProvider, instanceProfileProvider, launchTemplateProvider, kmsProvider, capacityReservationProvider, vpcEndpointProvider, accessEntryProvider),
+		nodeclasstermination.NewController(kubeClient, recorder, instanceProfileProvider, launchTemplateProvider, accessEntryProvider),
 		nodeclaimgarbagecollection.NewController(kubeClient, cloudProvider),
 		nodeclaimtagging.NewController(kubeClient, cloudProvider, instanceProvider),
+		nodeclaimelasticip.NewController(kubeClient, recorder, cloudProvider, instanceProvider, elasticIPProvider),
+		nodeclaimterminationreason.NewController(clk, kubeClient, cloudProvider),
+		nodeclaimdaemonreadiness.NewController(kubeClient, cloudProvider),
+		nodeclaimbillingboundary.NewController(clk, kubeClient, recorder, cloudProvider),
+		nodeclaimcapacityblock.NewController(clk, kubeClient, recorder, cloudProvider),
+		nodeclaimpdboverride.NewController(clk, kubeClient, recorder, cloudProvider),
+		nodeclaimpodcapacity.NewController(kubeClient, recorder, cloudProvider),
+		nodeclaimgracefultermination.NewController(cloudProvider),
+		nodeclaimstuckpod.NewController(clk, kubeClient, recorder, cloudProvider),
+		nodeclaimexternaldrain.NewController(kubeClient, recorder, cloudProvider),
+		nodeclaimdeprovisioningwebhook.NewController(clk, kubeClient, cloudProvider,
+			webhook.NewDefaultProvider(options.FromContext(ctx).DeprovisioningWebhookURL, options.FromContext(ctx).DeprovisioningWebhookTimeout)),
+		nodepoolpause.NewController(kubeClient, cloudProvider),
+		nodepoolprovisioningblocked.NewController(kubeClient, recorder, cloudProvider),
+		nodepoolsatisfiability.NewController(kubeClient, cloudProvider, instanceTypeProvider),
 		controllerspricing.NewController(pricingProvider),
 		controllersinstancetype.NewController(instanceTypeProvider),
 		controllersinstancetypecapacity.NewController(kubeClient, cloudProvider, instanceTypeProvider),
+		controllerslaunchtemplate.NewController(launchTemplateProvider),
 		ssminvalidation.NewController(ssmCache, amiProvider),
 		status.NewController[*v1.EC2NodeClass](kubeClient, mgr.GetEventRecorderFor("karpenter"), status.EmitDeprecatedMetrics),
 		opevents.NewController[*corev1.Node](kubeClient, clk),
 		controllersversion.NewController(versionProvider),
+		diagnosticscontroller.NewController(clk, kubernetesInterface, env.WithDefaultString("SYSTEM_NAMESPACE", "kube-system"), diagnosticsProvider),
+		controllersinstancetypeperformance.NewController(kubernetesInterface, env.WithDefaultString("SYSTEM_NAMESPACE", "kube-system"), instanceTypeProvider),
+	}
+	if options.FromContext(ctx).StoppedInstancePolicy != string(options.StoppedInstancePolicyIgnore) {
+		controllers = append(controllers, nodeclaimstoppedinstance.NewController(kubeClient, recorder, cloudProvider, instanceProvider))
+	}
+	if options.FromContext(ctx).AWSFeatureGates.Enabled(options.NodeMetadataSync) {
+		controllers = append(controllers, nodeclaimmetadatasync.NewController(kubeClient, cloudProvider))
+	}
+	if options.FromContext(ctx).AWSFeatureGates.Enabled(options.NodeAdoption) {
+		controllers = append(controllers, nodeclaimadoption.NewController(kubeClient))
+	}
+	if options.FromContext(ctx).AWSFeatureGates.Enabled(options.NodePinning) {
+		controllers = append(controllers, nodeclaimpinning.NewController(clk, kubeClient, cloudProvider))
+	}
+	if options.FromContext(ctx).AWSFeatureGates.Enabled(options.DisruptionApproval) {
+		controllers = append(controllers, nodeclaimdisruptionapproval.NewController(clk, kubeClient, cloudProvider))
+	}
+	if options.FromContext(ctx).AWSFeatureGates.Enabled(options.MemoryOverheadCalibration) {
+		controllers = append(controllers, controllersinstancetypememoryoverhead.NewController(kubernetesInterface,
+			env.WithDefaultString("SYSTEM_NAMESPACE", "kube-system"), instanceTypeProvider))
+	}
+	if options.FromContext(ctx).VCPUQuotaAwareness {
+		controllers = append(controllers, controllersquota.NewController(quotaProvider))
+		if options.FromContext(ctx).CapacityLedgerKubeconfig != "" {
+			controllers = append(controllers, controllersquotaledger.NewController(quotaProvider))
+		}
+	}
+	if options.FromContext(ctx).CarbonIntensityParameter != "" {
+		controllers = append(controllers, controllerscarbonintensity.NewController(carbonIntensityProvider))
+	}
+	if options.FromContext(ctx).ZonalShift {
+		controllers = append(controllers, controllerszonalshift.NewController(kubeClient, recorder, unavailableOfferings))
+	}
+	if options.FromContext(ctx).LaunchValidationWebhookURL != "" {
+		controllers = append(controllers, nodeclaimlaunchvalidation.NewController(clk, kubeClient, cloudProvider,
+			webhook.NewDefaultProvider(options.FromContext(ctx).LaunchValidationWebhookURL, nodeclaimlaunchvalidation.RequestTimeout)))
+	}
+	if options.FromContext(ctx).TrustedAMIsParameter != "" || options.FromContext(ctx).TrustedAMIKMSKeyARN != "" {
+		controllers = append(controllers, nodeclaimamiprovenance.NewController(kubeClient, recorder, cloudProvider, amiProvenanceProvider))
+	}
+	if options.FromContext(ctx).LeakedResourceGCTTL > 0 {
+		controllers = append(controllers, controllersleakedresource.NewController(leakedResourceProvider))
+	}
+	if options.FromContext(ctx).RegistrationRebootAfter > 0 {
+		controllers = append(controllers, nodeclaimregistrationreboot.NewController(clk, kubeClient, cloudProvider, instanceProvider))
 	}
 	if options.FromContext(ctx).InterruptionQueue != "" {
 		sqsapi := servicesqs.NewFromConfig(cfg)
 		out := lo.Must(sqsapi.GetQueueUrl(ctx, &servicesqs.GetQueueUrlInput{QueueName: lo.ToPtr(options.FromContext(ctx).InterruptionQueue)}))
-		controllers = append(controllers, interruption.NewController(kubeClient, cloudProvider, clk, recorder, lo.Must(sqs.NewDefaultProvider(sqsapi, lo.FromPtr(out.QueueUrl))), unavailableOfferings))
+		sqsProvider := lo.Must(sqs.NewDefaultProvider(sqsapi, lo.FromPtr(out.QueueUrl)))
+		additionalSQSProviders := lo.Map(options.FromContext(ctx).AdditionalInterruptionQueueConfigs(), func(queue options.InterruptionQueueConfig, _ int) sqs.Provider {
+			return lo.Must(sqs.NewDefaultProvider(newInterruptionQueueSQSAPI(cfg, queue), queue.URL))
+		})
+		var notifier webhook.InterruptionNotifier
+		if options.FromContext(ctx).InterruptionWebhookURL != "" {
+			notifier = webhook.NewDefaultProvider(options.FromContext(ctx).InterruptionWebhookURL, interruption.NotificationTimeout)
+		}
+		controllers = append(controllers, interruption.NewController(kubeClient, cloudProvider, clk, recorder,
+			sqs.NewMultiProvider(sqsProvider, additionalSQSProviders...), unavailableOfferings, notifier))
+		if options.FromContext(ctx).InterruptionDLQ != "" {
+			dlqOut := lo.Must(sqsapi.GetQueueUrl(ctx, &servicesqs.GetQueueUrlInput{QueueName: lo.ToPtr(options.FromContext(ctx).InterruptionDLQ)}))
+			controllers = append(controllers, interruptionredrive.NewController(lo.Must(sqs.NewDefaultProvider(sqsapi, lo.FromPtr(dlqOut.QueueUrl))), sqsProvider))
+		}
 	}
 	return controllers
 }
+
+// newInterruptionQueueSQSAPI returns an SQS client for an additional interruption queue, in the queue's region and with the
+// credentials of the queue's role if it has one
+func newInterruptionQueueSQSAPI(cfg aws.Config, queue options.InterruptionQueueConfig) *servicesqs.Client {
+	queueCfg := cfg.Copy()
+	queueCfg.Region = queue.Region
+	if queue.RoleARN != "" {
+		queueCfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), queue.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "karpenter-interruption"
+		}))
+	}
+	return servicesqs.NewFromConfig(queueCfg)
+}
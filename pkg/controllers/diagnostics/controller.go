@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/diagnostics"
+)
+
+const (
+	// ConfigMapName is the ConfigMap in the controller's namespace that the results of the last diagnostics run are
+	// published to. Deleting the ConfigMap reruns the diagnostics.
+	ConfigMapName = "karpenter-diagnostics"
+	// LastRunTimeKey is the ConfigMap key holding the time that the diagnostics last ran
+	LastRunTimeKey = "lastRunTime"
+	// rerunInterval is how often the diagnostics are rerun if they haven't been requested
+	rerunInterval = time.Hour
+)
+
+type Controller struct {
+	clk                 clock.Clock
+	kubernetesInterface kubernetes.Interface
+	namespace           string
+	diagnosticsProvider diagnostics.Provider
+	lastRun             time.Time
+}
+
+func NewController(clk clock.Clock, kubernetesInterface kubernetes.Interface, namespace string, diagnosticsProvider diagnostics.Provider) *Controller {
+	return &Controller{
+		clk:                 clk,
+		kubernetesInterface: kubernetesInterface,
+		namespace:           namespace,
+		diagnosticsProvider: diagnosticsProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "diagnostics")
+
+	configMap, err := c.kubernetesInterface.CoreV1().ConfigMaps(c.namespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, fmt.Errorf("getting configmap, %w", err)
+	}
+	found := err == nil
+	if found && !c.lastRun.IsZero() && c.clk.Since(c.lastRun) < rerunInterval {
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	results := c.diagnosticsProvider.Run(ctx)
+	c.lastRun = c.clk.Now()
+	for _, result := range results {
+		if result.Status == diagnostics.StatusFailed || result.Status == diagnostics.StatusWarning {
+			log.FromContext(ctx).WithValues("check", result.Name, "status", result.Status, "message", result.Message).Info("diagnostic check did not pass")
+		}
+	}
+	data := lo.SliceToMap(results, func(r diagnostics.Result) (string, string) { return r.Name, r.String() })
+	data[LastRunTimeKey] = c.lastRun.UTC().Format(time.RFC3339)
+	if !found {
+		if _, err = c.kubernetesInterface.CoreV1().ConfigMaps(c.namespace).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: c.namespace},
+			Data:       data,
+		}, metav1.CreateOptions{}); err != nil {
+			return reconcile.Result{}, fmt.Errorf("creating configmap, %w", err)
+		}
+	} else {
+		configMap.Data = data
+		if _, err = c.kubernetesInterface.CoreV1().ConfigMaps(c.namespace).Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+			return reconcile.Result{}, fmt.Errorf("updating configmap, %w", err)
+		}
+	}
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	// Only the leader runs the diagnostics, so the other replicas have no results and are always ready
+	if err := m.AddReadyzCheck("diagnostics", func(_ *http.Request) error { return c.diagnosticsProvider.Ready() }); err != nil {
+		return err
+	}
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("diagnostics").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	controllersdiagnostics "github.com/aws/karpenter-provider-aws/pkg/controllers/diagnostics"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/diagnostics"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+const namespace = "kube-system"
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var sqsapi *fake.SQSAPI
+var listener net.Listener
+var diagnosticsProvider *diagnostics.DefaultProvider
+var controller *controllersdiagnostics.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Diagnostics")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+	sqsapi = &fake.SQSAPI{}
+	// The cluster endpoint check only dials the endpoint, so a local listener is enough for it to pass
+	listener = lo.Must(net.Listen("tcp", "127.0.0.1:0"))
+	diagnosticsProvider = diagnostics.NewDefaultProvider(awsEnv.EC2API, sqsapi, fmt.Sprintf("https://%s", listener.Addr().String()))
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(listener.Close()).To(Succeed())
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueue: lo.ToPtr("test-cluster")}))
+
+	awsEnv.Reset()
+	sqsapi.Reset()
+	diagnosticsProvider.Reset()
+	controller = controllersdiagnostics.NewController(awsEnv.Clock, env.KubernetesInterface, namespace, diagnosticsProvider)
+})
+
+var _ = AfterEach(func() {
+	Expect(env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Delete(ctx, controllersdiagnostics.ConfigMapName, metav1.DeleteOptions{})).To(Or(Succeed(), MatchError(ContainSubstring("not found"))))
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func expectConfigMap() *corev1.ConfigMap {
+	GinkgoHelper()
+	configMap, err := env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Get(ctx, controllersdiagnostics.ConfigMapName, metav1.GetOptions{})
+	Expect(err).ToNot(HaveOccurred())
+	return configMap
+}
+
+var _ = Describe("Diagnostics", func() {
+	It("should publish passing results to the diagnostics configmap", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		configMap := expectConfigMap()
+		Expect(configMap.Data).To(HaveKeyWithValue(diagnostics.CheckCreateFleet, string(diagnostics.StatusPassed)))
+		Expect(configMap.Data).To(HaveKeyWithValue(diagnostics.CheckDescribeInstanceTypes, string(diagnostics.StatusPassed)))
+		Expect(configMap.Data).To(HaveKeyWithValue(diagnostics.CheckClusterEndpoint, string(diagnostics.StatusPassed)))
+		Expect(configMap.Data).To(HaveKeyWithValue(diagnostics.CheckInterruptionQueue, string(diagnostics.StatusPassed)))
+		Expect(configMap.Data).To(HaveKeyWithValue(controllersdiagnostics.LastRunTimeKey, awsEnv.Clock.Now().UTC().Format(time.RFC3339)))
+		Expect(diagnosticsProvider.Ready()).To(Succeed())
+	})
+	It("should fail readiness when the controller role isn't authorized to launch instances", func() {
+		awsEnv.EC2API.CreateFleetBehavior.Error.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"}, fake.MaxCalls(1))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data[diagnostics.CheckCreateFleet]).To(HavePrefix(string(diagnostics.StatusFailed)))
+		Expect(diagnosticsProvider.Ready()).To(MatchError(ContainSubstring(diagnostics.CheckCreateFleet)))
+	})
+	It("should only warn when permissions can't be verified", func() {
+		awsEnv.EC2API.CreateFleetBehavior.Error.Set(&smithy.GenericAPIError{Code: "InvalidLaunchTemplateName.NotFoundException"}, fake.MaxCalls(1))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data[diagnostics.CheckCreateFleet]).To(HavePrefix(string(diagnostics.StatusWarning)))
+		Expect(diagnosticsProvider.Ready()).To(Succeed())
+	})
+	It("should fail readiness when the cluster endpoint can't be reached", func() {
+		unreachable := diagnostics.NewDefaultProvider(awsEnv.EC2API, sqsapi, "https://127.0.0.1:1")
+		controller = controllersdiagnostics.NewController(awsEnv.Clock, env.KubernetesInterface, namespace, unreachable)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data[diagnostics.CheckClusterEndpoint]).To(HavePrefix(string(diagnostics.StatusFailed)))
+		Expect(unreachable.Ready()).To(MatchError(ContainSubstring(diagnostics.CheckClusterEndpoint)))
+	})
+	It("should skip the interruption queue check when interruption handling is disabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueue: lo.ToPtr("")}))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data[diagnostics.CheckInterruptionQueue]).To(HavePrefix(string(diagnostics.StatusSkipped)))
+	})
+	It("should fail readiness when the interruption queue doesn't exist", func() {
+		sqsapi.GetQueueURLBehavior.Error.Set(&sqstypes.QueueDoesNotExist{}, fake.MaxCalls(1))
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data[diagnostics.CheckInterruptionQueue]).To(HavePrefix(string(diagnostics.StatusFailed)))
+		Expect(diagnosticsProvider.Ready()).To(MatchError(ContainSubstring(diagnostics.CheckInterruptionQueue)))
+	})
+	It("should warn when the interruption queue has a backlog", func() {
+		sqsapi.GetQueueAttributesBehavior.Output.Set(&sqs.GetQueueAttributesOutput{
+			Attributes: map[string]string{string(sqstypes.QueueAttributeNameApproximateNumberOfMessages): "5000"},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data[diagnostics.CheckInterruptionQueue]).To(Equal("Warning: 5000 messages are waiting to be processed"))
+		Expect(diagnosticsProvider.Ready()).To(Succeed())
+	})
+	It("should only rerun the diagnostics once an hour", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		awsEnv.EC2API.CreateFleetBehavior.Error.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data[diagnostics.CheckCreateFleet]).To(Equal(string(diagnostics.StatusPassed)))
+
+		awsEnv.Clock.Step(time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data[diagnostics.CheckCreateFleet]).To(HavePrefix(string(diagnostics.StatusFailed)))
+	})
+	It("should rerun the diagnostics when the configmap is deleted", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		awsEnv.EC2API.CreateFleetBehavior.Error.Set(&smithy.GenericAPIError{Code: "UnauthorizedOperation"})
+		Expect(env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Delete(ctx, controllersdiagnostics.ConfigMapName, metav1.DeleteOptions{})).To(Succeed())
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data[diagnostics.CheckCreateFleet]).To(HavePrefix(string(diagnostics.StatusFailed)))
+	})
+})
@@ -17,6 +17,7 @@ package interruption
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -25,6 +26,8 @@ import (
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	"github.com/awslabs/operatorpkg/singleton"
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/workqueue"
@@ -41,10 +44,15 @@ import (
 	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
 	"sigs.k8s.io/karpenter/pkg/utils/pretty"
 
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	"github.com/aws/karpenter-provider-aws/pkg/cache"
 	interruptionevents "github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/events"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/scheduledchange"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/spotinterruption"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
 	"sigs.k8s.io/karpenter/pkg/events"
@@ -57,6 +65,18 @@ const (
 	NoAction       Action = "NoAction"
 )
 
+// NotificationTimeout is the maximum duration that Karpenter waits for the interruption webhook to respond
+const NotificationTimeout = 5 * time.Second
+
+// notificationKinds are the names of the message kinds in the events sent to the interruption webhook
+var notificationKinds = map[messages.Kind]string{
+	messages.SpotInterruptionKind:        "SpotInterruption",
+	messages.RebalanceRecommendationKind: "RebalanceRecommendation",
+	messages.ScheduledChangeKind:         "ScheduledChange",
+	messages.InstanceStoppedKind:         "InstanceStopping",
+	messages.InstanceTerminatedKind:      "InstanceTerminating",
+}
+
 // Controller is an AWS interruption controller.
 // It continually polls an SQS queue for events from aws.ec2 and aws.health that
 // trigger node health events or node spot interruption/rebalance events.
@@ -69,6 +89,11 @@ type Controller struct {
 	unavailableOfferingsCache *cache.UnavailableOfferings
 	parser                    *EventParser
 	cm                        *pretty.ChangeMonitor
+	// handled records the instance IDs and kinds of the messages that have already been acted on. SQS and EventBridge
+	// both deliver at least once, so the same event may be received more than once.
+	handled *gocache.Cache
+	// notifier re-publishes interruption messages to the interruption webhook, and is nil if the webhook isn't configured
+	notifier webhook.InterruptionNotifier
 }
 
 func NewController(
@@ -78,6 +103,7 @@ func NewController(
 	recorder events.Recorder,
 	sqsProvider sqs.Provider,
 	unavailableOfferingsCache *cache.UnavailableOfferings,
+	notifier webhook.InterruptionNotifier,
 ) *Controller {
 	return &Controller{
 		kubeClient:                kubeClient,
@@ -88,6 +114,8 @@ func NewController(
 		unavailableOfferingsCache: unavailableOfferingsCache,
 		parser:                    NewEventParser(DefaultParsers...),
 		cm:                        pretty.NewChangeMonitor(),
+		handled:                   gocache.New(cache.InterruptionHandledTTL, cache.DefaultCleanupInterval),
+		notifier:                  notifier,
 	}
 }
 
@@ -112,12 +140,20 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("making node instance id map, %w", err)
 	}
+	// Processing a burst of messages can outlast the visibility timeout, so the visibility of the messages that are
+	// still being processed is extended until they're deleted. Messages that fail are left to become visible again.
+	inFlight := newMessageSet(sqsMessages)
+	extendCtx, stopExtending := context.WithCancel(ctx)
+	defer stopExtending()
+	go c.extendVisibility(extendCtx, inFlight)
+
 	errs := make([]error, len(sqsMessages))
 	workqueue.ParallelizeUntil(ctx, 10, len(sqsMessages), func(i int) {
+		defer inFlight.remove(sqsMessages[i])
 		msg, e := c.parseMessage(sqsMessages[i])
 		if e != nil {
 			// If we fail to parse, then we should delete the message but still log the error
-			log.FromContext(ctx).Error(err, "failed parsing interruption message")
+			log.FromContext(ctx).Error(e, "failed parsing interruption message")
 			errs[i] = c.deleteMessage(ctx, sqsMessages[i])
 			return
 		}
@@ -133,6 +169,27 @@ func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
 	return reconcile.Result{RequeueAfter: singleton.RequeueImmediately}, nil
 }
 
+// extendVisibility periodically extends the visibility timeout of the messages that are still in flight until the context
+// is cancelled. Failing to extend the visibility only risks a duplicate delivery, which is safe, so errors are only logged.
+func (c *Controller) extendVisibility(ctx context.Context, inFlight *messageSet) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clk.After(sqs.VisibilityTimeout / 2):
+			msgs := inFlight.list()
+			if len(msgs) == 0 {
+				continue
+			}
+			if err := c.sqsProvider.ChangeSQSMessageVisibility(ctx, msgs, sqs.VisibilityTimeout); err != nil && ctx.Err() == nil {
+				log.FromContext(ctx).Error(err, "failed extending interruption message visibility")
+				continue
+			}
+			VisibilityExtensions.Inc(nil)
+		}
+	}
+}
+
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("interruption").
@@ -168,8 +225,16 @@ func (c *Controller) handleMessage(ctx context.Context, nodeClaimInstanceIDMap m
 		if !ok {
 			continue
 		}
+		// Add fails if the key is present, so only one of any concurrent duplicates claims the message
+		key := fmt.Sprintf("%s/%s", instanceID, msg.Kind())
+		if c.handled.Add(key, struct{}{}, gocache.DefaultExpiration) != nil {
+			DuplicateMessages.Inc(map[string]string{messageTypeLabel: string(msg.Kind())})
+			continue
+		}
 		node := nodeInstanceIDMap[instanceID]
 		if e := c.handleNodeClaim(ctx, msg, nodeClaim, node); e != nil {
+			// Forget the message so that it's acted on when it's redelivered
+			c.handled.Delete(key)
 			err = multierr.Append(err, e)
 		}
 	}
@@ -197,8 +262,21 @@ func (c *Controller) handleNodeClaim(ctx context.Context, msg messages.Message,
 		ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Node", klog.KRef("", node.Name)))
 	}
 
+	// Running instances aren't interrupted. The time that the instance started running is recorded so that controllers
+	// waiting on a launch are triggered by the NodeClaim update rather than polling EC2.
+	if msg.Kind() == messages.InstanceRunningKind {
+		return c.markInstanceRunning(ctx, nodeClaim, msg.StartTime())
+	}
+
 	// Record metric and event for this action
 	c.notifyForMessage(msg, nodeClaim, node)
+	c.notifyWebhook(ctx, msg, action, nodeClaim, node)
+
+	if node != nil && options.FromContext(ctx).InterruptionTaints {
+		if err := c.taintNode(ctx, msg, node); err != nil {
+			return err
+		}
+	}
 
 	// Mark the offering as unavailable in the ICE cache since we got a spot interruption warning
 	if msg.Kind() == messages.SpotInterruptionKind {
@@ -207,6 +285,23 @@ func (c *Controller) handleNodeClaim(ctx context.Context, msg messages.Message,
 		if zone != "" && instanceType != "" {
 			c.unavailableOfferingsCache.MarkUnavailable(ctx, string(msg.Kind()), ec2types.InstanceType(instanceType), zone, karpv1.CapacityTypeSpot)
 		}
+		if zone != "" && options.FromContext(ctx).ZonalShift {
+			c.unavailableOfferingsCache.RecordZoneFailure(ctx, string(msg.Kind()), zone)
+		}
+	}
+	// Scheduled changes are usually announced days in advance, so when a lead time is configured the NodeClaim is drifted
+	// ahead of the change instead of being deleted, which replaces it within the NodePool's disruption budgets
+	if scheduledChange, ok := msg.(scheduledchange.Message); ok && options.FromContext(ctx).ScheduledChangeLeadTime > 0 {
+		scheduledTime, err := scheduledChange.ScheduledTime()
+		if err == nil {
+			return c.markScheduledMaintenance(ctx, nodeClaim, scheduledTime)
+		}
+		log.FromContext(ctx).Error(err, "failed parsing scheduled change, deleting nodeclaim")
+	}
+	// Stopped instances are only replaced under the Replace policy. They're left stopped under the Ignore policy, and started
+	// again by the nodeclaim.stoppedinstance controller under the Start policy.
+	if msg.Kind() == messages.InstanceStoppedKind && options.FromContext(ctx).StoppedInstancePolicy != string(options.StoppedInstancePolicyReplace) {
+		return nil
 	}
 	if action != NoAction {
 		return c.deleteNodeClaim(ctx, msg, nodeClaim, node)
@@ -214,11 +309,81 @@ func (c *Controller) handleNodeClaim(ctx context.Context, msg messages.Message,
 	return nil
 }
 
+// taintNode distinguishes hard and soft capacity signals with separate taints, so that workloads can tolerate each
+// differently. Spot interruptions are tainted NoExecute since the instance is reclaimed within two minutes, while rebalance
+// recommendations are tainted PreferNoSchedule since the instance may keep running for much longer.
+func (c *Controller) taintNode(ctx context.Context, msg messages.Message, node *corev1.Node) error {
+	var taint corev1.Taint
+	switch msg.Kind() {
+	case messages.SpotInterruptionKind:
+		taint = corev1.Taint{Key: v1.SpotInterruptingTaintKey, Value: "true", Effect: corev1.TaintEffectNoExecute}
+	case messages.RebalanceRecommendationKind:
+		taint = corev1.Taint{Key: v1.RebalanceRecommendedTaintKey, Value: "true", Effect: corev1.TaintEffectPreferNoSchedule}
+	default:
+		return nil
+	}
+	if lo.ContainsBy(node.Spec.Taints, func(t corev1.Taint) bool { return t.MatchTaint(&taint) }) {
+		return nil
+	}
+	stored := node.DeepCopy()
+	node.Spec.Taints = append(node.Spec.Taints, taint)
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("tainting node, %w", err))
+	}
+	log.FromContext(ctx).WithValues("taint", taint.ToString()).Info("tainted node from interruption message")
+	return nil
+}
+
+// markScheduledMaintenance records the time of the scheduled change on the NodeClaim. The NodeClaim is drifted once the
+// change is within the scheduled change lead time.
+func (c *Controller) markScheduledMaintenance(ctx context.Context, nodeClaim *karpv1.NodeClaim, scheduledTime time.Time) error {
+	if !nodeClaim.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1.AnnotationScheduledMaintenanceTime: scheduledTime.UTC().Format(time.RFC3339),
+	})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("patching nodeclaim scheduled maintenance time, %w", err))
+	}
+	log.FromContext(ctx).WithValues("scheduled-time", scheduledTime.UTC().Format(time.RFC3339)).Info("marked nodeclaim for replacement ahead of scheduled change")
+	return nil
+}
+
+// markInstanceRunning records the time of the instance's running state-change notification on the NodeClaim
+func (c *Controller) markInstanceRunning(ctx context.Context, nodeClaim *karpv1.NodeClaim, runningTime time.Time) error {
+	if _, ok := nodeClaim.Annotations[v1.AnnotationInstanceRunningTime]; ok || !nodeClaim.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1.AnnotationInstanceRunningTime: runningTime.UTC().Format(time.RFC3339),
+	})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("patching nodeclaim instance running time, %w", err))
+	}
+	log.FromContext(ctx).V(1).Info("marked nodeclaim instance as running from state-change notification")
+	return nil
+}
+
 // deleteNodeClaim removes the NodeClaim from the api-server
 func (c *Controller) deleteNodeClaim(ctx context.Context, msg messages.Message, nodeClaim *karpv1.NodeClaim, node *corev1.Node) error {
 	if !nodeClaim.DeletionTimestamp.IsZero() {
 		return nil
 	}
+	// Record the termination reason before deleting since it can't be inferred from the NodeClaim once it's terminating
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1.AnnotationTerminationReason: string(lo.Ternary(msg.Kind() == messages.SpotInterruptionKind, v1.TerminationReasonSpotInterruption, v1.TerminationReasonInterruption)),
+	})
+	// Pods which are still blocked by PDBs shortly before the instance is reclaimed are deleted relative to this time
+	if spotInterruption, ok := msg.(spotinterruption.Message); ok && options.FromContext(ctx).InterruptionPDBOverride {
+		nodeClaim.Annotations[v1.AnnotationSpotReclaimTime] = spotInterruption.ReclaimTime().UTC().Format(time.RFC3339)
+	}
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("patching nodeclaim termination reason, %w", err))
+	}
 	if err := c.kubeClient.Delete(ctx, nodeClaim); err != nil {
 		return client.IgnoreNotFound(fmt.Errorf("deleting the node on interruption message, %w", err))
 	}
@@ -254,6 +419,43 @@ func (c *Controller) notifyForMessage(msg messages.Message, nodeClaim *karpv1.No
 	}
 }
 
+// notifyWebhook sends the message to the interruption webhook in a normalized form. Failing to notify the webhook shouldn't
+// hold up acting on the interruption, so errors are only logged.
+func (c *Controller) notifyWebhook(ctx context.Context, msg messages.Message, action Action, nodeClaim *karpv1.NodeClaim, node *corev1.Node) {
+	if c.notifier == nil {
+		return
+	}
+	kind, ok := notificationKinds[msg.Kind()]
+	if !ok {
+		return
+	}
+	event := webhook.InterruptionEvent{
+		Event:  webhook.NewEvent(webhook.EventTypeInterruption, nodeClaim, c.clk.Now()),
+		Kind:   kind,
+		Action: string(action),
+	}
+	if node != nil {
+		event.Node = node.Name
+	}
+	event.InstanceID, _ = utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	switch m := msg.(type) {
+	case spotinterruption.Message:
+		event.Deadline = lo.ToPtr(m.ReclaimTime().UTC())
+	case scheduledchange.Message:
+		if scheduledTime, err := m.ScheduledTime(); err == nil {
+			event.Deadline = lo.ToPtr(scheduledTime.UTC())
+		}
+	}
+	notifyCtx, cancel := context.WithTimeout(ctx, NotificationTimeout)
+	defer cancel()
+	if err := c.notifier.NotifyInterruption(notifyCtx, event); err != nil {
+		log.FromContext(ctx).Error(err, "failed notifying interruption webhook")
+		NotificationErrors.Inc(map[string]string{messageTypeLabel: string(msg.Kind())})
+		return
+	}
+	Notifications.Inc(map[string]string{messageTypeLabel: string(msg.Kind())})
+}
+
 // makeNodeClaimInstanceIDMap builds a map between the instance id that is stored in the
 // NodeClaim .status.providerID and the NodeClaim
 func (c *Controller) makeNodeClaimInstanceIDMap(ctx context.Context) (map[string]*karpv1.NodeClaim, error) {
@@ -304,3 +506,25 @@ func actionForMessage(msg messages.Message) Action {
 		return NoAction
 	}
 }
+
+// messageSet is the set of messages from a receive that haven't finished processing
+type messageSet struct {
+	mu   sync.Mutex
+	msgs map[*sqstypes.Message]struct{}
+}
+
+func newMessageSet(msgs []*sqstypes.Message) *messageSet {
+	return &messageSet{msgs: lo.SliceToMap(msgs, func(m *sqstypes.Message) (*sqstypes.Message, struct{}) { return m, struct{}{} })}
+}
+
+func (s *messageSet) remove(msg *sqstypes.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.msgs, msg)
+}
+
+func (s *messageSet) list() []*sqstypes.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return lo.Keys(s.msgs)
+}
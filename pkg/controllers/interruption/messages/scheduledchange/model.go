@@ -15,6 +15,9 @@ limitations under the License.
 package scheduledchange
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
 )
 
@@ -38,6 +41,17 @@ func (Message) Kind() messages.Kind {
 	return messages.ScheduledChangeKind
 }
 
+// ScheduledTime returns the time that the scheduled change begins. AWS Health formats event times as RFC 1123 dates,
+// e.g. "Sat, 05 Jun 2021 05:00:00 GMT".
+func (m Message) ScheduledTime() (time.Time, error) {
+	for _, layout := range []string{time.RFC1123, time.RFC3339} {
+		if t, err := time.Parse(layout, m.Detail.StartTime); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("parsing scheduled change start time %q", m.Detail.StartTime)
+}
+
 type Detail struct {
 	EventARN          string             `json:"eventArn"`
 	EventTypeCode     string             `json:"eventTypeCode"`
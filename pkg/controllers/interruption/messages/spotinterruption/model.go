@@ -15,9 +15,14 @@ limitations under the License.
 package spotinterruption
 
 import (
+	"time"
+
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
 )
 
+// ReclaimDelay is how long after a spot interruption warning EC2 begins reclaiming the instance
+const ReclaimDelay = 2 * time.Minute
+
 // Message contains the properties defined in AWS EventBridge schema
 // aws.ec2@EC2SpotInstanceInterruptionWarning v0.
 type Message struct {
@@ -38,3 +43,8 @@ func (m Message) EC2InstanceIDs() []string {
 func (Message) Kind() messages.Kind {
 	return messages.SpotInterruptionKind
 }
+
+// ReclaimTime returns the time that EC2 begins reclaiming the interrupted instance
+func (m Message) ReclaimTime() time.Time {
+	return m.Time.Add(ReclaimDelay)
+}
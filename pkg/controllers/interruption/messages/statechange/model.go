@@ -38,6 +38,9 @@ func (m Message) EC2InstanceIDs() []string {
 }
 
 func (m Message) Kind() messages.Kind {
+	if m.Detail.State == "running" {
+		return messages.InstanceRunningKind
+	}
 	if lo.Contains([]string{"stopping", "stopped"}, m.Detail.State) {
 		return messages.InstanceStoppedKind
 	}
@@ -24,7 +24,7 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
 )
 
-var acceptedStates = sets.NewString("stopping", "stopped", "shutting-down", "terminated")
+var acceptedStates = sets.NewString("running", "stopping", "stopped", "shutting-down", "terminated")
 
 type Parser struct{}
 
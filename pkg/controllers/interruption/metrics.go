@@ -48,6 +48,46 @@ var (
 		},
 		[]string{},
 	)
+	DuplicateMessages = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "duplicate_messages_total",
+			Help:      "Count of messages received for an instance that a message of the same type was already acted on for. Broken down by message type.",
+		},
+		[]string{messageTypeLabel},
+	)
+	VisibilityExtensions = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "message_visibility_extensions_total",
+			Help:      "Count of times the visibility timeout of in-flight messages was extended because processing outlasted it.",
+		},
+		[]string{},
+	)
+	Notifications = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "webhook_notifications_total",
+			Help:      "Count of interruption messages that were sent to the interruption webhook. Broken down by message type.",
+		},
+		[]string{messageTypeLabel},
+	)
+	NotificationErrors = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "webhook_notification_errors_total",
+			Help:      "Count of interruption messages that failed to be sent to the interruption webhook. Broken down by message type.",
+		},
+		[]string{messageTypeLabel},
+	)
 	MessageLatency = opmetrics.NewPrometheusHistogram(
 		crmetrics.Registry,
 		prometheus.HistogramOpts{
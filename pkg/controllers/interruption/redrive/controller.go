@@ -0,0 +1,123 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redrive
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+)
+
+const (
+	// RedriveCountAttribute is the message attribute that records how many times a message has been moved from the
+	// dead-letter queue back to the interruption queue
+	RedriveCountAttribute = "karpenter-redrive-count"
+	// MaxRedrives is the number of times a message is re-driven before it's dropped. Messages that repeatedly fail are
+	// usually for instances that no longer exist, so they're dropped rather than retried indefinitely.
+	MaxRedrives = 3
+)
+
+// Controller moves messages from the interruption dead-letter queue back to the interruption queue so that messages
+// which failed processing, e.g. while the API server was unavailable, are retried rather than lost
+type Controller struct {
+	dlqProvider   sqs.Provider
+	queueProvider sqs.Provider
+}
+
+func NewController(dlqProvider sqs.Provider, queueProvider sqs.Provider) *Controller {
+	return &Controller{
+		dlqProvider:   dlqProvider,
+		queueProvider: queueProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "interruption.redrive")
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("queue", c.dlqProvider.Name()))
+
+	sqsMessages, err := c.dlqProvider.GetSQSMessages(ctx)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting messages from dead-letter queue, %w", err)
+	}
+	if len(sqsMessages) == 0 {
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	var errs error
+	for _, msg := range sqsMessages {
+		errs = multierr.Append(errs, c.redrive(ctx, msg))
+	}
+	if errs != nil {
+		return reconcile.Result{}, errs
+	}
+	return reconcile.Result{RequeueAfter: singleton.RequeueImmediately}, nil
+}
+
+// redrive sends a copy of the message to the interruption queue before deleting it from the dead-letter queue, so a
+// failure between the two results in a duplicate rather than a lost message
+func (c *Controller) redrive(ctx context.Context, msg *sqstypes.Message) error {
+	count := redriveCount(msg)
+	if count >= MaxRedrives {
+		log.FromContext(ctx).WithValues("messageID", aws.ToString(msg.MessageId), "redrives", count).Error(nil, "dropping interruption message, exceeded maximum redrives")
+		if err := c.dlqProvider.DeleteSQSMessage(ctx, msg); err != nil {
+			return err
+		}
+		DroppedMessages.Inc(nil)
+		return nil
+	}
+	msg.MessageAttributes = lo.Assign(msg.MessageAttributes, map[string]sqstypes.MessageAttributeValue{
+		RedriveCountAttribute: {DataType: aws.String("Number"), StringValue: aws.String(strconv.Itoa(count + 1))},
+	})
+	if _, err := c.queueProvider.SendSQSMessage(ctx, msg); err != nil {
+		return err
+	}
+	if err := c.dlqProvider.DeleteSQSMessage(ctx, msg); err != nil {
+		return err
+	}
+	RedrivenMessages.Inc(nil)
+	return nil
+}
+
+func redriveCount(msg *sqstypes.Message) int {
+	attr, ok := msg.MessageAttributes[RedriveCountAttribute]
+	if !ok {
+		return 0
+	}
+	count, err := strconv.Atoi(aws.ToString(attr.StringValue))
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("interruption.redrive").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,123 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redrive_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	servicesqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/redrive"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var dlqapi *fake.SQSAPI
+var queueapi *fake.SQSAPI
+var controller *redrive.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "InterruptionRedrive")
+}
+
+var _ = BeforeSuite(func() {
+	dlqapi = &fake.SQSAPI{}
+	queueapi = &fake.SQSAPI{}
+	controller = redrive.NewController(
+		lo.Must(sqs.NewDefaultProvider(dlqapi, fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/test-cluster-dlq", fake.DefaultRegion, fake.DefaultAccount))),
+		lo.Must(sqs.NewDefaultProvider(queueapi, fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/test-cluster", fake.DefaultRegion, fake.DefaultAccount))),
+	)
+})
+
+var _ = BeforeEach(func() {
+	dlqapi.Reset()
+	queueapi.Reset()
+	redrive.RedrivenMessages.Reset()
+	redrive.DroppedMessages.Reset()
+})
+
+var _ = Describe("Redrive", func() {
+	It("should move messages from the dead-letter queue back to the interruption queue", func() {
+		ExpectDeadLetterMessages(deadLetterMessage("first", nil), deadLetterMessage("second", nil))
+		ExpectSingletonReconciled(ctx, controller)
+
+		Expect(queueapi.SendMessageBehavior.SuccessfulCalls()).To(Equal(2))
+		Expect(dlqapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(2))
+		var bodies []string
+		queueapi.SendMessageBehavior.CalledWithInput.ForEach(func(in *servicesqs.SendMessageInput) { bodies = append(bodies, aws.ToString(in.MessageBody)) })
+		Expect(bodies).To(ConsistOf("first", "second"))
+		ExpectMetricCounterValue(redrive.RedrivenMessages, 2, nil)
+	})
+	It("should record the number of times a message has been re-driven", func() {
+		ExpectDeadLetterMessages(deadLetterMessage("body", lo.ToPtr("1")))
+		ExpectSingletonReconciled(ctx, controller)
+
+		input := queueapi.SendMessageBehavior.CalledWithInput.Pop()
+		Expect(aws.ToString(input.MessageAttributes[redrive.RedriveCountAttribute].StringValue)).To(Equal("2"))
+	})
+	It("should drop messages that have exceeded the maximum number of redrives", func() {
+		ExpectDeadLetterMessages(deadLetterMessage("body", lo.ToPtr(fmt.Sprint(redrive.MaxRedrives))))
+		ExpectSingletonReconciled(ctx, controller)
+
+		Expect(queueapi.SendMessageBehavior.Calls()).To(Equal(0))
+		Expect(dlqapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+		ExpectMetricCounterValue(redrive.DroppedMessages, 1, nil)
+	})
+	It("should leave messages on the dead-letter queue when they can't be sent to the interruption queue", func() {
+		ExpectDeadLetterMessages(deadLetterMessage("body", nil))
+		queueapi.SendMessageBehavior.Error.Set(fmt.Errorf("failed"))
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+
+		Expect(dlqapi.DeleteMessageBehavior.Calls()).To(Equal(0))
+	})
+	It("should requeue when the dead-letter queue is empty", func() {
+		ExpectDeadLetterMessages()
+		result := ExpectSingletonReconciled(ctx, controller)
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(queueapi.SendMessageBehavior.Calls()).To(Equal(0))
+	})
+})
+
+func ExpectDeadLetterMessages(messages ...sqstypes.Message) {
+	dlqapi.ReceiveMessageBehavior.Output.Set(&servicesqs.ReceiveMessageOutput{Messages: messages})
+}
+
+func deadLetterMessage(body string, redriveCount *string) sqstypes.Message {
+	msg := sqstypes.Message{
+		Body:          aws.String(body),
+		MessageId:     aws.String(string(uuid.NewUUID())),
+		ReceiptHandle: aws.String(string(uuid.NewUUID())),
+	}
+	if redriveCount != nil {
+		msg.MessageAttributes = map[string]sqstypes.MessageAttributeValue{
+			redrive.RedriveCountAttribute: {DataType: aws.String("Number"), StringValue: redriveCount},
+		}
+	}
+	return msg
+}
@@ -18,6 +18,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -41,16 +44,19 @@ import (
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/rebalancerecommendation"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/scheduledchange"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/spotinterruption"
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/interruption/messages/statechange"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/sqs"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
@@ -74,6 +80,7 @@ var sqsapi *fake.SQSAPI
 var sqsProvider *sqs.DefaultProvider
 var unavailableOfferingsCache *awscache.UnavailableOfferings
 var fakeClock *clock.FakeClock
+var cloudProvider *cloudprovider.CloudProvider
 var controller *interruption.Controller
 
 func TestAPIs(t *testing.T) {
@@ -90,9 +97,9 @@ var _ = BeforeSuite(func() {
 	unavailableOfferingsCache = awscache.NewUnavailableOfferings()
 	sqsapi = &fake.SQSAPI{}
 	sqsProvider = lo.Must(sqs.NewDefaultProvider(sqsapi, fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/test-cluster", fake.DefaultRegion, fake.DefaultAccount)))
-	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
-	controller = interruption.NewController(env.Client, cloudProvider, fakeClock, events.NewRecorder(&record.FakeRecorder{}), sqsProvider, unavailableOfferingsCache)
+	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	controller = interruption.NewController(env.Client, cloudProvider, fakeClock, events.NewRecorder(&record.FakeRecorder{}), sqsProvider, unavailableOfferingsCache, nil)
 })
 
 var _ = AfterSuite(func() {
@@ -101,6 +108,7 @@ var _ = AfterSuite(func() {
 
 var _ = BeforeEach(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
 	unavailableOfferingsCache.Flush()
 	sqsapi.Reset()
 })
@@ -139,6 +147,27 @@ var _ = Describe("InterruptionHandling", func() {
 			ExpectNotFound(ctx, env.Client, nodeClaim)
 			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
 		})
+		It("should record the termination reason on the NodeClaim before deleting it", func() {
+			nodeClaim.Finalizers = append(nodeClaim.Finalizers, karpv1.TerminationFinalizer)
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.DeletionTimestamp.IsZero()).To(BeFalse())
+			Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationTerminationReason, string(v1.TerminationReasonSpotInterruption)))
+		})
+		It("should record the spot reclaim time on the NodeClaim when PDB override is enabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionPDBOverride: lo.ToPtr(true)}))
+			nodeClaim.Finalizers = append(nodeClaim.Finalizers, karpv1.TerminationFinalizer)
+			msg := spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)))
+			ExpectMessagesCreated(msg)
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationSpotReclaimTime, msg.Time.Add(2*time.Minute).UTC().Format(time.RFC3339)))
+		})
 		It("should delete the NodeClaim when receiving a scheduled change message", func() {
 			ExpectMessagesCreated(scheduledChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
 			ExpectApplied(ctx, env.Client, nodeClaim, node)
@@ -152,7 +181,32 @@ var _ = Describe("InterruptionHandling", func() {
 			ExpectNotFound(ctx, env.Client, nodeClaim)
 			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
 		})
+		It("should mark the NodeClaim for replacement when receiving a scheduled change message with a lead time", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ScheduledChangeLeadTime: lo.ToPtr(48 * time.Hour)}))
+			scheduledTime := time.Now().Add(7 * 24 * time.Hour).UTC().Truncate(time.Second)
+			msg := scheduledChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)))
+			msg.Detail.StartTime = scheduledTime.Format(time.RFC1123)
+			ExpectMessagesCreated(msg)
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.DeletionTimestamp.IsZero()).To(BeTrue())
+			Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationScheduledMaintenanceTime, scheduledTime.Format(time.RFC3339)))
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+		})
+		It("should delete the NodeClaim when the scheduled change time can't be parsed", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ScheduledChangeLeadTime: lo.ToPtr(48 * time.Hour)}))
+			msg := scheduledChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)))
+			msg.Detail.StartTime = "tomorrow"
+			ExpectMessagesCreated(msg)
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
 		It("should delete the NodeClaim when receiving a state change message", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{StoppedInstancePolicy: lo.ToPtr(string(options.StoppedInstancePolicyReplace))}))
 			var nodeClaims []*karpv1.NodeClaim
 			var messages []interface{}
 			for _, state := range []string{"terminated", "stopped", "stopping", "shutting-down"} {
@@ -185,6 +239,34 @@ var _ = Describe("InterruptionHandling", func() {
 			ExpectNotFound(ctx, env.Client, lo.Map(nodeClaims, func(nc *karpv1.NodeClaim, _ int) client.Object { return nc })...)
 			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(4))
 		})
+		It("should not delete the NodeClaim of a stopped instance with the Ignore stopped instance policy", func() {
+			ExpectMessagesCreated(stateChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)), "stopped"))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+		})
+		It("should not delete the NodeClaim of a stopped instance with the Start stopped instance policy", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{StoppedInstancePolicy: lo.ToPtr(string(options.StoppedInstancePolicyStart))}))
+			ExpectMessagesCreated(stateChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)), "stopped"))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+		})
+		It("should record the running time on the NodeClaim when receiving a running state change message", func() {
+			msg := stateChangeMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)), "running")
+			ExpectMessagesCreated(msg)
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(nodeClaim.DeletionTimestamp.IsZero()).To(BeTrue())
+			Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationInstanceRunningTime, msg.Time.UTC().Format(time.RFC3339)))
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+		})
 		It("should handle multiple messages that cause nodeClaim deletion", func() {
 			var nodeClaims []*karpv1.NodeClaim
 			var instanceIDs []string
@@ -256,6 +338,195 @@ var _ = Describe("InterruptionHandling", func() {
 			// Expect a t3.large in coretest-zone-1a to be added to the ICE cache
 			Expect(unavailableOfferingsCache.IsUnavailable("t3.large", "coretest-zone-1a", karpv1.CapacityTypeSpot)).To(BeTrue())
 		})
+		It("should shift launches away from a zone when spot interruptions are concentrated in it", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ZonalShift: lo.ToPtr(true)}))
+			var messages []interface{}
+			for i := 0; i < awscache.ZoneFailureThreshold; i++ {
+				instanceID := fake.InstanceID()
+				nc, n := coretest.NodeClaimAndNode(karpv1.NodeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							karpv1.NodePoolLabelKey:        "default",
+							corev1.LabelTopologyZone:       "coretest-zone-1a",
+							corev1.LabelInstanceTypeStable: "t3.large",
+						},
+					},
+					Status: karpv1.NodeClaimStatus{
+						ProviderID: fake.ProviderID(instanceID),
+					},
+				})
+				ExpectApplied(ctx, env.Client, nc, n)
+				messages = append(messages, spotInterruptionMessage(instanceID))
+			}
+			ExpectMessagesCreated(messages...)
+			ExpectSingletonReconciled(ctx, controller)
+
+			Expect(unavailableOfferingsCache.ImpairedZones()).To(HaveKey("coretest-zone-1a"))
+			Expect(unavailableOfferingsCache.IsUnavailable("m5.large", "coretest-zone-1a", karpv1.CapacityTypeOnDemand)).To(BeTrue())
+		})
+		It("should taint the Node NoExecute when receiving a spot interruption warning with interruption taints", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionTaints: lo.ToPtr(true)}))
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(ExpectExists(ctx, env.Client, node).Spec.Taints).To(ContainElement(corev1.Taint{
+				Key:    v1.SpotInterruptingTaintKey,
+				Value:  "true",
+				Effect: corev1.TaintEffectNoExecute,
+			}))
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should taint the Node PreferNoSchedule when receiving a rebalance recommendation with interruption taints", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionTaints: lo.ToPtr(true)}))
+			ExpectMessagesCreated(rebalanceRecommendationMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(ExpectExists(ctx, env.Client, node).Spec.Taints).To(ContainElement(corev1.Taint{
+				Key:    v1.RebalanceRecommendedTaintKey,
+				Value:  "true",
+				Effect: corev1.TaintEffectPreferNoSchedule,
+			}))
+			ExpectExists(ctx, env.Client, nodeClaim)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+		})
+		It("should not taint the Node when interruption taints are disabled", func() {
+			ExpectMessagesCreated(rebalanceRecommendationMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(ExpectExists(ctx, env.Client, node).Spec.Taints).To(BeEmpty())
+		})
+		It("should only act once on duplicate messages for an instance", func() {
+			nodeClaim.Finalizers = append(nodeClaim.Finalizers, karpv1.TerminationFinalizer)
+			instanceID := lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))
+			ExpectMessagesCreated(spotInterruptionMessage(instanceID), spotInterruptionMessage(instanceID))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, controller)
+			ExpectMetricCounterValue(metrics.NodeClaimsDisruptedTotal, 1, map[string]string{
+				metrics.ReasonLabel: "spot_interrupted",
+				"nodepool":          "default",
+			})
+			ExpectMetricCounterValue(interruption.DuplicateMessages, 1, map[string]string{"message_type": "spot_interrupted"})
+			// Duplicates are still deleted from the queue
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(2))
+		})
+	})
+	Context("Interruption Webhook", func() {
+		var server *httptest.Server
+		var webhookController *interruption.Controller
+		var mu sync.Mutex
+		var received []webhook.InterruptionEvent
+		var statusCode int
+		BeforeEach(func() {
+			received = nil
+			statusCode = http.StatusOK
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				event := webhook.InterruptionEvent{}
+				Expect(json.NewDecoder(r.Body).Decode(&event)).To(Succeed())
+				mu.Lock()
+				defer mu.Unlock()
+				received = append(received, event)
+				w.WriteHeader(statusCode)
+			}))
+			webhookController = interruption.NewController(env.Client, cloudProvider, fakeClock, events.NewRecorder(&record.FakeRecorder{}),
+				sqsProvider, unavailableOfferingsCache, webhook.NewDefaultProvider(server.URL, time.Second))
+		})
+		AfterEach(func() {
+			server.Close()
+		})
+		It("should send a normalized event with the reclaim time for spot interruptions", func() {
+			msg := spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID)))
+			ExpectMessagesCreated(msg)
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, webhookController)
+			mu.Lock()
+			defer mu.Unlock()
+			Expect(received).To(HaveLen(1))
+			Expect(received[0].Type).To(Equal(webhook.EventTypeInterruption))
+			Expect(received[0].Kind).To(Equal("SpotInterruption"))
+			Expect(received[0].Action).To(Equal(string(interruption.CordonAndDrain)))
+			Expect(received[0].InstanceID).To(Equal(msg.Detail.InstanceID))
+			Expect(received[0].NodeClaim).To(Equal(nodeClaim.Name))
+			Expect(received[0].Node).To(Equal(node.Name))
+			Expect(received[0].NodePool).To(Equal("default"))
+			Expect(received[0].Deadline).ToNot(BeNil())
+			Expect(received[0].Deadline.Equal(msg.ReclaimTime())).To(BeTrue())
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+		})
+		It("should send a normalized event without a deadline for rebalance recommendations", func() {
+			ExpectMessagesCreated(rebalanceRecommendationMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, webhookController)
+			mu.Lock()
+			defer mu.Unlock()
+			Expect(received).To(HaveLen(1))
+			Expect(received[0].Kind).To(Equal("RebalanceRecommendation"))
+			Expect(received[0].Action).To(Equal(string(interruption.NoAction)))
+			Expect(received[0].Deadline).To(BeNil())
+			ExpectExists(ctx, env.Client, nodeClaim)
+		})
+		It("should still act on the message when the webhook fails", func() {
+			statusCode = http.StatusInternalServerError
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, webhookController)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			ExpectMetricCounterValue(interruption.NotificationErrors, 1, map[string]string{"message_type": "spot_interrupted"})
+		})
+	})
+	Context("Multiple Queues", func() {
+		var otherSQSAPI *fake.SQSAPI
+		var multiController *interruption.Controller
+		BeforeEach(func() {
+			otherSQSAPI = &fake.SQSAPI{}
+			otherSQSProvider := lo.Must(sqs.NewDefaultProvider(otherSQSAPI, "https://sqs.eu-west-1.amazonaws.com/111122223333/other-cluster"))
+			multiController = interruption.NewController(env.Client, cloudProvider, fakeClock, events.NewRecorder(&record.FakeRecorder{}),
+				sqs.NewMultiProvider(sqsProvider, otherSQSProvider), unavailableOfferingsCache, nil)
+			sqs.QueueReceiveErrors.Reset()
+		})
+		It("should handle messages from every queue and delete them from the queue they were received from", func() {
+			otherNodeClaim, otherNode := coretest.NodeClaimAndNode(karpv1.NodeClaim{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{karpv1.NodePoolLabelKey: "default"}},
+				Status:     karpv1.NodeClaimStatus{ProviderID: fake.RandomProviderID()},
+			})
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			otherSQSAPI.ReceiveMessageBehavior.Output.Set(&servicesqs.ReceiveMessageOutput{
+				Messages: []sqstypes.Message{{
+					Body:      aws.String(string(lo.Must(json.Marshal(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(otherNodeClaim.Status.ProviderID))))))),
+					MessageId: aws.String(string(uuid.NewUUID())),
+				}},
+			})
+			ExpectApplied(ctx, env.Client, nodeClaim, node, otherNodeClaim, otherNode)
+
+			ExpectSingletonReconciled(ctx, multiController)
+			ExpectNotFound(ctx, env.Client, nodeClaim, otherNodeClaim)
+			Expect(sqsapi.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			Expect(otherSQSAPI.DeleteMessageBehavior.SuccessfulCalls()).To(Equal(1))
+			ExpectMetricGaugeValue(sqs.QueueHealthy, 1, map[string]string{"queue": "other-cluster"})
+		})
+		It("should handle messages from healthy queues when another queue fails", func() {
+			ExpectMessagesCreated(spotInterruptionMessage(lo.Must(utils.ParseInstanceID(nodeClaim.Status.ProviderID))))
+			otherSQSAPI.ReceiveMessageBehavior.Error.Set(smithyErrWithCode("AccessDenied"), fake.MaxCalls(0))
+			ExpectApplied(ctx, env.Client, nodeClaim, node)
+
+			ExpectSingletonReconciled(ctx, multiController)
+			ExpectNotFound(ctx, env.Client, nodeClaim)
+			ExpectMetricGaugeValue(sqs.QueueHealthy, 0, map[string]string{"queue": "other-cluster"})
+			ExpectMetricCounterValue(sqs.QueueReceiveErrors, 1, map[string]string{"queue": "other-cluster"})
+		})
+		It("should fail when every queue fails", func() {
+			sqsapi.ReceiveMessageBehavior.Error.Set(smithyErrWithCode("AccessDenied"), fake.MaxCalls(0))
+			otherSQSAPI.ReceiveMessageBehavior.Error.Set(smithyErrWithCode("AccessDenied"), fake.MaxCalls(0))
+			_ = ExpectSingletonReconcileFailed(ctx, multiController)
+		})
 	})
 })
 
@@ -315,6 +586,26 @@ func spotInterruptionMessage(involvedInstanceID string) spotinterruption.Message
 	}
 }
 
+func rebalanceRecommendationMessage(involvedInstanceID string) rebalancerecommendation.Message {
+	return rebalancerecommendation.Message{
+		Metadata: messages.Metadata{
+			Version:    "0",
+			Account:    defaultAccountID,
+			DetailType: "EC2 Instance Rebalance Recommendation",
+			ID:         string(uuid.NewUUID()),
+			Region:     fake.DefaultRegion,
+			Resources: []string{
+				fmt.Sprintf("arn:aws:ec2:%s:instance/%s", fake.DefaultRegion, involvedInstanceID),
+			},
+			Source: ec2Source,
+			Time:   time.Now(),
+		},
+		Detail: rebalancerecommendation.Detail{
+			InstanceID: involvedInstanceID,
+		},
+	}
+}
+
 func stateChangeMessage(involvedInstanceID, state string) statechange.Message {
 	return statechange.Message{
 		Metadata: messages.Metadata{
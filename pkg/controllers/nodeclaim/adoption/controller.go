@@ -0,0 +1,166 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adoption
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// adoptedLabelKeys are the labels of an adopted Node that its NodeClaim is pinned to
+var adoptedLabelKeys = []string{corev1.LabelInstanceTypeStable, corev1.LabelTopologyZone, corev1.LabelArchStable}
+
+// Controller adopts the instances of Nodes that weren't launched by Karpenter, e.g. managed node group or manually
+// launched Nodes, into the NodePool named by their karpenter.k8s.aws/adopt-nodepool label. A NodeClaim is created for
+// each Node with the instance ID in its karpenter.k8s.aws/adopted-instance-id annotation, which the cloud provider
+// resolves to the existing instance instead of launching a new one. From then on the Node is consolidated, drifted and
+// expired like any other Node in the NodePool, without first having to be replaced.
+type Controller struct {
+	kubeClient client.Client
+}
+
+func NewController(kubeClient client.Client) *Controller {
+	return &Controller{
+		kubeClient: kubeClient,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, node *corev1.Node) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.adoption")
+
+	nodePoolName, ok := node.Labels[v1.LabelAdoptNodePool]
+	if !ok || !node.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	// Nodes that are already owned by a NodeClaim have either been adopted or were launched by Karpenter
+	if _, ok = node.Labels[karpv1.NodePoolLabelKey]; ok {
+		return reconcile.Result{}, nil
+	}
+	instanceID, err := utils.ParseInstanceID(node.Spec.ProviderID)
+	if err != nil || instanceID == "" {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Node", klog.KRef("", node.Name), "NodePool", klog.KRef("", nodePoolName), "instance-id", instanceID))
+	adopted, err := c.isAdopted(ctx, node, instanceID)
+	if err != nil || adopted {
+		return reconcile.Result{}, err
+	}
+	nodePool := &karpv1.NodePool{}
+	if err = c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
+		if errors.IsNotFound(err) {
+			log.FromContext(ctx).Error(err, "failed adopting node, nodepool not found")
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("getting nodepool, %w", err)
+	}
+	if err = scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...).
+		Intersects(scheduling.NewLabelRequirements(lo.PickByKeys(node.Labels, adoptedLabelKeys))); err != nil {
+		log.FromContext(ctx).Error(err, "failed adopting node, node is incompatible with nodepool requirements")
+		return reconcile.Result{}, nil
+	}
+	// Upstream requires a Node to either have the unregistered taint or the registered label before it's registered. The
+	// taint would evict the Node's pods, so the label is added instead.
+	stored := node.DeepCopy()
+	node.Labels = lo.Assign(node.Labels, map[string]string{karpv1.NodeRegisteredLabelKey: "true"})
+	if err = c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching node, %w", err))
+	}
+	nodeClaim := adoptedNodeClaim(nodePool, node, instanceID)
+	if err = c.kubeClient.Create(ctx, nodeClaim); err != nil {
+		return reconcile.Result{}, fmt.Errorf("creating nodeclaim, %w", err)
+	}
+	log.FromContext(ctx).WithValues("NodeClaim", klog.KObj(nodeClaim)).Info("adopting node")
+	return reconcile.Result{}, nil
+}
+
+// isAdopted returns whether a NodeClaim already exists for the instance. The NodeClaim's provider ID isn't set until the
+// cloud provider has resolved the instance, so NodeClaims that are still being adopted are matched by their annotation.
+func (c *Controller) isAdopted(ctx context.Context, node *corev1.Node, instanceID string) (bool, error) {
+	nodeClaimList := &karpv1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList); err != nil {
+		return false, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	return lo.ContainsBy(nodeClaimList.Items, func(nc karpv1.NodeClaim) bool {
+		return nc.Status.ProviderID == node.Spec.ProviderID || nc.Annotations[v1.AnnotationAdoptedInstanceID] == instanceID
+	}), nil
+}
+
+// adoptedNodeClaim builds the NodeClaim for an adopted Node from its NodePool's template, the same way that upstream does
+// for provisioned NodeClaims, but pinned to the Node's instance type and zone. Startup taints are dropped since the Node
+// is already running.
+func adoptedNodeClaim(nodePool *karpv1.NodePool, node *corev1.Node, instanceID string) *karpv1.NodeClaim {
+	nodeClaim := nodePool.Spec.Template.ToNodeClaim()
+	nodeClaim.GenerateName = fmt.Sprintf("%s-", nodePool.Name)
+	nodeClaim.Labels = lo.Assign(nodeClaim.Labels, map[string]string{
+		karpv1.NodePoolLabelKey: nodePool.Name,
+		karpv1.NodeClassLabelKey(nodePool.Spec.Template.Spec.NodeClassRef.GroupKind()): nodePool.Spec.Template.Spec.NodeClassRef.Name,
+	})
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		karpv1.NodePoolHashAnnotationKey:        nodePool.Hash(),
+		karpv1.NodePoolHashVersionAnnotationKey: karpv1.NodePoolHashVersion,
+		v1.AnnotationAdoptedInstanceID:          instanceID,
+	})
+	nodeClaim.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion:         object.GVK(nodePool).GroupVersion().String(),
+		Kind:               object.GVK(nodePool).Kind,
+		Name:               nodePool.Name,
+		UID:                nodePool.UID,
+		BlockOwnerDeletion: lo.ToPtr(true),
+	}}
+	nodeClaim.Spec.StartupTaints = nil
+	requirements := scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...)
+	requirements.Add(scheduling.NewLabelRequirements(lo.PickByKeys(node.Labels, adoptedLabelKeys)).Values()...)
+	nodeClaim.Spec.Requirements = requirements.NodeSelectorRequirements()
+	return nodeClaim
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.adoption").
+		For(&corev1.Node{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			_, ok := o.GetLabels()[v1.LabelAdoptNodePool]
+			return ok
+		}))).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 1,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
@@ -0,0 +1,146 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adoption_test
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/adoption"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var controller *adoption.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Adoption")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	controller = adoption.NewController(env.Client)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("Adoption", func() {
+	var nodePool *karpv1.NodePool
+	var node *corev1.Node
+	var instanceID string
+
+	BeforeEach(func() {
+		nodePool = coretest.NodePool(karpv1.NodePool{
+			Spec: karpv1.NodePoolSpec{
+				Template: karpv1.NodeClaimTemplate{
+					Spec: karpv1.NodeClaimTemplateSpec{
+						StartupTaints: []corev1.Taint{{Key: "example.com/startup", Effect: corev1.TaintEffectNoSchedule}},
+					},
+				},
+			},
+		})
+		instanceID = fake.InstanceID()
+		node = coretest.Node(coretest.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					v1.LabelAdoptNodePool:          nodePool.Name,
+					corev1.LabelInstanceTypeStable: "m5.large",
+					corev1.LabelTopologyZone:       "test-zone-1a",
+					corev1.LabelArchStable:         karpv1.ArchitectureAmd64,
+				},
+			},
+			ProviderID: fake.ProviderID(instanceID),
+		})
+	})
+	It("should create a NodeClaim for a labeled Node", func() {
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+
+		nodeClaims := ExpectNodeClaims(ctx, env.Client)
+		Expect(nodeClaims).To(HaveLen(1))
+		Expect(nodeClaims[0].Annotations).To(HaveKeyWithValue(v1.AnnotationAdoptedInstanceID, instanceID))
+		Expect(nodeClaims[0].Labels).To(HaveKeyWithValue(karpv1.NodePoolLabelKey, nodePool.Name))
+		Expect(nodeClaims[0].Spec.StartupTaints).To(BeEmpty())
+		Expect(nodeClaims[0].Spec.Requirements).To(ContainElement(HaveField("NodeSelectorRequirement", corev1.NodeSelectorRequirement{
+			Key:      corev1.LabelInstanceTypeStable,
+			Operator: corev1.NodeSelectorOpIn,
+			Values:   []string{"m5.large"},
+		})))
+		Expect(ExpectExists(ctx, env.Client, node).Labels).To(HaveKeyWithValue(karpv1.NodeRegisteredLabelKey, "true"))
+	})
+	It("should not create a NodeClaim for a Node without the adopt label", func() {
+		delete(node.Labels, v1.LabelAdoptNodePool)
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		Expect(ExpectNodeClaims(ctx, env.Client)).To(BeEmpty())
+	})
+	It("should not create a NodeClaim for a Node that has already been adopted", func() {
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		Expect(ExpectNodeClaims(ctx, env.Client)).To(HaveLen(1))
+	})
+	It("should not create a NodeClaim for a Node that's owned by a NodeClaim", func() {
+		node.Labels[karpv1.NodePoolLabelKey] = nodePool.Name
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		Expect(ExpectNodeClaims(ctx, env.Client)).To(BeEmpty())
+	})
+	It("should not create a NodeClaim when the NodePool doesn't exist", func() {
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		Expect(ExpectNodeClaims(ctx, env.Client)).To(BeEmpty())
+		Expect(ExpectExists(ctx, env.Client, node).Labels).ToNot(HaveKey(karpv1.NodeRegisteredLabelKey))
+	})
+	It("should not create a NodeClaim when the Node is incompatible with the NodePool", func() {
+		nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+				Key:      corev1.LabelTopologyZone,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{"test-zone-1b"},
+			},
+		}}
+		ExpectApplied(ctx, env.Client, nodePool, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, node)
+		Expect(ExpectNodeClaims(ctx, env.Client)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,144 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amiprovenance
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/klog/v2"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amiprovenance"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// Controller verifies the provenance of the AMI that a NodeClaim with the AMI provenance startup taint was launched with,
+// once its Node has registered. Upstream doesn't initialize a NodeClaim until its startup taints are removed, so the taint
+// holds the NodeClaim uninitialized until its AMI is trusted. NodeClaims whose AMI isn't trusted are left uninitialized,
+// rather than deleted, so that the instance can be inspected and released if the AMI is later added to the trusted AMIs.
+type Controller struct {
+	kubeClient    client.Client
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+	provenance    amiprovenance.Provider
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider, provenance amiprovenance.Provider) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+		provenance:    provenance,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.amiprovenance")
+
+	if !isVerifiable(nodeClaim) || !nodeClaim.StatusConditions().Get(karpv1.ConditionTypeRegistered).IsTrue() {
+		return reconcile.Result{}, nil
+	}
+	node, err := nodeclaim.NodeForNodeClaim(ctx, c.kubeClient, nodeClaim)
+	if err != nil {
+		return reconcile.Result{}, nodeclaim.IgnoreDuplicateNodeError(nodeclaim.IgnoreNodeNotFoundError(err))
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Node", klog.KRef("", node.Name), "image-id", nodeClaim.Status.ImageID))
+	// The taint is removed after the condition is set, so that a NodeClaim whose AMI is trusted is never verified again
+	if nodeClaim.StatusConditions().Get(v1.ConditionTypeAMIProvenanceVerified).IsTrue() {
+		return reconcile.Result{}, c.removeTaint(ctx, node)
+	}
+	verified, message, err := c.provenance.Verify(ctx, nodeClaim.Status.ImageID)
+	if err != nil {
+		VerificationsTotal.Inc(map[string]string{outcomeLabel: "error"})
+		return reconcile.Result{}, fmt.Errorf("verifying ami provenance, %w", err)
+	}
+	stored := nodeClaim.DeepCopy()
+	if !verified {
+		VerificationsTotal.Inc(map[string]string{outcomeLabel: "untrusted"})
+		nodeClaim.StatusConditions().SetFalse(v1.ConditionTypeAMIProvenanceVerified, "Untrusted", message)
+		if !equality.Semantic.DeepEqual(stored.Status, nodeClaim.Status) {
+			if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+				return reconcile.Result{}, client.IgnoreNotFound(err)
+			}
+			log.FromContext(ctx).WithValues("message", message).Info("ami provenance couldn't be verified, holding node uninitialized")
+		}
+		c.recorder.Publish(UntrustedAMIEvent(nodeClaim, message))
+		// The trusted AMIs parameter may be updated to include the AMI, so it's verified again once the cached result expires
+		return reconcile.Result{RequeueAfter: awscache.AMIProvenanceTTL}, nil
+	}
+	VerificationsTotal.Inc(map[string]string{outcomeLabel: "verified"})
+	nodeClaim.StatusConditions().SetTrue(v1.ConditionTypeAMIProvenanceVerified)
+	if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).Info("verified ami provenance")
+	return reconcile.Result{}, c.removeTaint(ctx, node)
+}
+
+func (c *Controller) removeTaint(ctx context.Context, node *corev1.Node) error {
+	stored := node.DeepCopy()
+	node.Spec.Taints = lo.Reject(node.Spec.Taints, func(t corev1.Taint, _ int) bool { return t.Key == v1.AMIProvenanceTaintKey })
+	if len(node.Spec.Taints) == len(stored.Spec.Taints) {
+		return nil
+	}
+	// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+	// can cause races due to the fact that it fully replaces the list on a change
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("removing ami provenance taint, %w", err))
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.amiprovenance").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isVerifiable(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isVerifiable(nc *karpv1.NodeClaim) bool {
+	// NodeClaim is currently terminating
+	if !nc.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return lo.ContainsBy(nc.Spec.StartupTaints, func(t corev1.Taint) bool { return t.Key == v1.AMIProvenanceTaintKey })
+}
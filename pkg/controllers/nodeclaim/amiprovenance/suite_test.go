@@ -0,0 +1,226 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amiprovenance_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/amiprovenance"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+const (
+	trustedAMIsParameter = "/karpenter/trusted-amis"
+	keyARN               = "arn:aws:kms:us-west-2:111122223333:key/ami-signing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var recorder *record.FakeRecorder
+var provenanceController *amiprovenance.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AMIProvenanceController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	recorder = record.NewFakeRecorder(10)
+	provenanceController = amiprovenance.NewController(env.Client, events.NewRecorder(recorder), cloudProvider, awsEnv.AMIProvenanceProvider)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+		TrustedAMIsParameter: lo.ToPtr(trustedAMIsParameter),
+		TrustedAMIKMSKeyARN:  lo.ToPtr(keyARN),
+	}))
+	awsEnv.Reset()
+	amiprovenance.VerificationsTotal.Reset()
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+	awsEnv.SSMAPI.Parameters = map[string]string{trustedAMIsParameter: "ami-trusted1, ami-trusted2"}
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+// signedImage stores an EBS backed image whose snapshots are encrypted with the passed key
+func signedImage(imageID, key string) {
+	snapshotID := fmt.Sprintf("snap-%s", imageID)
+	awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: []ec2types.Image{{
+		ImageId: aws.String(imageID),
+		Name:    aws.String(imageID),
+		BlockDeviceMappings: []ec2types.BlockDeviceMapping{{
+			DeviceName: aws.String("/dev/xvda"),
+			Ebs:        &ec2types.EbsBlockDevice{SnapshotId: aws.String(snapshotID)},
+		}},
+	}}})
+	awsEnv.EC2API.Snapshots.Store(snapshotID, ec2types.Snapshot{
+		SnapshotId: aws.String(snapshotID),
+		Encrypted:  aws.Bool(key != ""),
+		KmsKeyId:   lo.Ternary(key != "", aws.String(key), nil),
+	})
+}
+
+var _ = Describe("AMIProvenanceController", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+	taint := corev1.Taint{Key: v1.AMIProvenanceTaintKey, Effect: corev1.TaintEffectNoSchedule}
+
+	withImage := func(imageID string) {
+		nodeClaim, node = coretest.NodeClaimAndNode(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: "default"},
+			},
+			Spec: karpv1.NodeClaimSpec{
+				StartupTaints: []corev1.Taint{taint},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+				ImageID:    imageID,
+			},
+		})
+		node.Spec.Taints = []corev1.Taint{taint}
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+	}
+	expectTainted := func(tainted bool) {
+		node = ExpectExists(ctx, env.Client, node)
+		if tainted {
+			Expect(node.Spec.Taints).To(ContainElement(HaveField("Key", v1.AMIProvenanceTaintKey)))
+		} else {
+			Expect(node.Spec.Taints).ToNot(ContainElement(HaveField("Key", v1.AMIProvenanceTaintKey)))
+		}
+	}
+
+	It("should remove the taint when the AMI is listed in the trusted AMIs parameter", func() {
+		withImage("ami-trusted2")
+		ExpectObjectReconciled(ctx, env.Client, provenanceController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeAMIProvenanceVerified).IsTrue()).To(BeTrue())
+		expectTainted(false)
+		ExpectMetricCounterValue(amiprovenance.VerificationsTotal, 1, map[string]string{"outcome": "verified"})
+	})
+	It("should remove the taint when the AMI's snapshots are encrypted with the trusted KMS key", func() {
+		withImage("ami-signed")
+		signedImage("ami-signed", keyARN)
+		ExpectObjectReconciled(ctx, env.Client, provenanceController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeAMIProvenanceVerified).IsTrue()).To(BeTrue())
+		expectTainted(false)
+	})
+	It("should keep the taint when the AMI's snapshots are encrypted with another key", func() {
+		withImage("ami-signed")
+		signedImage("ami-signed", "arn:aws:kms:us-west-2:111122223333:key/other")
+		result := ExpectObjectReconciled(ctx, env.Client, provenanceController, nodeClaim)
+		Expect(result.RequeueAfter).To(Equal(awscache.AMIProvenanceTTL))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		cond := nodeClaim.StatusConditions().Get(v1.ConditionTypeAMIProvenanceVerified)
+		Expect(cond.IsFalse()).To(BeTrue())
+		Expect(cond.Reason).To(Equal("Untrusted"))
+		Expect(cond.Message).To(ContainSubstring("isn't listed in the trusted AMIs parameter"))
+		Expect(cond.Message).To(ContainSubstring("isn't encrypted with the trusted KMS key"))
+		expectTainted(true)
+		Expect(recorder.Events).To(Receive(ContainSubstring("UntrustedAMI")))
+		ExpectMetricCounterValue(amiprovenance.VerificationsTotal, 1, map[string]string{"outcome": "untrusted"})
+	})
+	It("should keep the taint when the AMI's snapshots aren't encrypted", func() {
+		withImage("ami-unsigned")
+		signedImage("ami-unsigned", "")
+		ExpectObjectReconciled(ctx, env.Client, provenanceController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeAMIProvenanceVerified).IsFalse()).To(BeTrue())
+		expectTainted(true)
+	})
+	It("should only check the trusted AMIs parameter when no KMS key is configured", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{TrustedAMIsParameter: lo.ToPtr(trustedAMIsParameter)}))
+		withImage("ami-signed")
+		signedImage("ami-signed", keyARN)
+		ExpectObjectReconciled(ctx, env.Client, provenanceController, nodeClaim)
+		Expect(awsEnv.EC2API.CalledWithDescribeImagesInput.Len()).To(BeZero())
+		expectTainted(true)
+	})
+	It("should verify the AMI again once it's added to the trusted AMIs parameter", func() {
+		withImage("ami-new")
+		ExpectObjectReconciled(ctx, env.Client, provenanceController, nodeClaim)
+		expectTainted(true)
+
+		awsEnv.SSMAPI.Parameters = map[string]string{trustedAMIsParameter: "ami-trusted1,ami-new"}
+		awsEnv.AMIProvenanceCache.Flush()
+		ExpectObjectReconciled(ctx, env.Client, provenanceController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeAMIProvenanceVerified).IsTrue()).To(BeTrue())
+		expectTainted(false)
+	})
+	It("should fail to reconcile when the trusted AMIs parameter can't be read", func() {
+		withImage("ami-trusted1")
+		awsEnv.SSMAPI.WantErr = fmt.Errorf("access denied")
+		_ = ExpectObjectReconcileFailed(ctx, env.Client, provenanceController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeAMIProvenanceVerified)).To(BeNil())
+		expectTainted(true)
+		ExpectMetricCounterValue(amiprovenance.VerificationsTotal, 1, map[string]string{"outcome": "error"})
+	})
+	It("should not verify a nodeclaim before it's registered", func() {
+		withImage("ami-trusted1")
+		nodeClaim.StatusConditions().SetUnknown(karpv1.ConditionTypeRegistered)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, provenanceController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeAMIProvenanceVerified)).To(BeNil())
+		expectTainted(true)
+	})
+})
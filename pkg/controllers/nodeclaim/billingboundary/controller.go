@@ -0,0 +1,213 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package billingboundary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// Controller defers voluntary disruption of Nodes in NodePools with the karpenter.k8s.aws/billing-period annotation until
+// their current billing period is nearly used, for instances and software licenses that are billed by the hour rather than
+// by the second. Billing periods are measured from when the instance was launched, and disruption is allowed for the
+// --billing-boundary-window before each period ends. Disruption is deferred the same way as for paused NodePools, by adding
+// the do-not-disrupt annotation to the Node, so interruption handling, expiration and manual deletion are unaffected.
+type Controller struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.billingboundary")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() || nodeClaim.Status.NodeName == "" {
+		return reconcile.Result{}, nil
+	}
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("getting node, %w", err))
+	}
+	nodePool := &karpv1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Labels[karpv1.NodePoolLabelKey]}, nodePool); err != nil {
+		if !errors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("getting nodepool, %w", err)
+		}
+		nodePool = nil
+	}
+	period := billingPeriod(ctx, nodePool)
+	now := c.clk.Now()
+	boundary := nextBoundary(launchTime(nodeClaim), period, now)
+	window := options.FromContext(ctx).BillingBoundaryWindow
+	// Nodes that are already being disrupted are left alone, so that a disruption that started within the window isn't
+	// blocked once the next billing period starts
+	disrupting := lo.ContainsBy(node.Spec.Taints, func(t corev1.Taint) bool { return t.MatchTaint(&karpv1.DisruptedNoScheduleTaint) })
+	deferred := period > 0 && !disrupting && boundary.Sub(now) > window
+
+	released, err := c.reconcileNode(ctx, node, deferred, boundary.Add(-window))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if released && period > 0 {
+		c.recorder.Publish(BillingBoundaryReachedEvent(nodeClaim, boundary, c.price(ctx, nodePool, nodeClaim)*(period-window).Hours()))
+	}
+	switch {
+	case deferred:
+		// Allow disruption as soon as the window opens
+		return reconcile.Result{RequeueAfter: boundary.Add(-window).Sub(now)}, nil
+	case period > 0 && !disrupting:
+		// Defer disruption again once the next billing period starts
+		return reconcile.Result{RequeueAfter: boundary.Sub(now)}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// billingPeriod returns the billing period from the NodePool's annotation, or zero if disruption isn't deferred for the
+// NodePool
+func billingPeriod(ctx context.Context, nodePool *karpv1.NodePool) time.Duration {
+	if nodePool == nil {
+		return 0
+	}
+	value, ok := nodePool.Annotations[v1.AnnotationBillingPeriod]
+	if !ok {
+		return 0
+	}
+	period, err := time.ParseDuration(value)
+	if err != nil || period <= 0 {
+		log.FromContext(ctx).WithValues("NodePool", nodePool.Name, "value", value).
+			Info(fmt.Sprintf("ignoring %s annotation, expected a positive duration", v1.AnnotationBillingPeriod))
+		return 0
+	}
+	return period
+}
+
+// launchTime returns when the NodeClaim's instance was launched, which is when its first billing period started
+func launchTime(nodeClaim *karpv1.NodeClaim) time.Time {
+	if launched := nodeClaim.StatusConditions().Get(karpv1.ConditionTypeLaunched); launched.IsTrue() {
+		return launched.LastTransitionTime.Time
+	}
+	return nodeClaim.CreationTimestamp.Time
+}
+
+// nextBoundary returns the end of the billing period that now falls in
+func nextBoundary(launched time.Time, period time.Duration, now time.Time) time.Time {
+	if period <= 0 {
+		return time.Time{}
+	}
+	if now.Before(launched) {
+		return launched.Add(period)
+	}
+	return launched.Add((now.Sub(launched)/period + 1) * period)
+}
+
+// reconcileNode adds the do-not-disrupt annotation to the Node while disruption is deferred, and removes it once the billing
+// boundary window opens. Nodes that already had the annotation are left untouched, so that we never remove an annotation
+// that we didn't add. It returns whether a deferral was released.
+func (c *Controller) reconcileNode(ctx context.Context, node *corev1.Node, deferred bool, deferredUntil time.Time) (bool, error) {
+	stored := node.DeepCopy()
+	_, doNotDisrupt := node.Annotations[karpv1.DoNotDisruptAnnotationKey]
+	_, managed := node.Annotations[v1.AnnotationBillingDeferredUntil]
+	switch {
+	case deferred && (!doNotDisrupt || managed):
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{
+			karpv1.DoNotDisruptAnnotationKey:  "true",
+			v1.AnnotationBillingDeferredUntil: deferredUntil.UTC().Format(time.RFC3339),
+		})
+	case !deferred && managed:
+		node.Annotations = lo.OmitByKeys(node.Annotations, []string{karpv1.DoNotDisruptAnnotationKey, v1.AnnotationBillingDeferredUntil})
+	default:
+		return false, nil
+	}
+	if equality.Semantic.DeepEqual(stored.Annotations, node.Annotations) {
+		return false, nil
+	}
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); err != nil {
+		return false, client.IgnoreNotFound(fmt.Errorf("patching node, %w", err))
+	}
+	log.FromContext(ctx).WithValues("Node", node.Name, "deferred", deferred).V(1).Info("updated billing boundary deferral")
+	return !deferred, nil
+}
+
+// price returns the hourly price of the NodeClaim's offering, or zero if it can't be resolved
+func (c *Controller) price(ctx context.Context, nodePool *karpv1.NodePool, nodeClaim *karpv1.NodeClaim) float64 {
+	instanceTypes, err := c.cloudProvider.GetInstanceTypes(ctx, nodePool)
+	if err != nil {
+		return 0
+	}
+	it, ok := lo.Find(instanceTypes, func(it *cloudprovider.InstanceType) bool {
+		return it.Name == nodeClaim.Labels[corev1.LabelInstanceTypeStable]
+	})
+	if !ok {
+		return 0
+	}
+	offerings := it.Offerings.Compatible(scheduling.NewLabelRequirements(lo.PickByKeys(nodeClaim.Labels, []string{
+		corev1.LabelTopologyZone,
+		karpv1.CapacityTypeLabelKey,
+	})))
+	if len(offerings) == 0 {
+		return 0
+	}
+	return offerings.Cheapest().Price
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.billingboundary").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		Watches(&corev1.Node{}, nodeclaimutils.NodeEventHandler(c.kubeClient, c.cloudProvider)).
+		Watches(&karpv1.NodePool{}, nodeclaimutils.NodePoolEventHandler(c.kubeClient, c.cloudProvider)).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
@@ -0,0 +1,36 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package billingboundary
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func BillingBoundaryReachedEvent(nodeClaim *karpv1.NodeClaim, boundary time.Time, saved float64) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClaim,
+		Type:           corev1.EventTypeNormal,
+		Reason:         "BillingBoundaryReached",
+		Message: fmt.Sprintf("Allowing voluntary disruption until the billing period ends at %s, deferring disruption to the end of the billing period avoids up to $%.4f of paid, unused time",
+			boundary.Format(time.RFC3339), saved),
+		DedupeValues: []string{string(nodeClaim.UID), boundary.String()},
+	}
+}
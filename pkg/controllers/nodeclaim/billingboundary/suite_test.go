@@ -0,0 +1,187 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package billingboundary_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/billingboundary"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var recorder *record.FakeRecorder
+var controller *billingboundary.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "BillingBoundary")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{BillingBoundaryWindow: lo.ToPtr(5 * time.Minute)}))
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	fakeClock = clock.NewFakeClock(time.Now())
+	recorder = record.NewFakeRecorder(10)
+	controller = billingboundary.NewController(fakeClock, env.Client, events.NewRecorder(recorder), cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	fakeClock.SetTime(time.Now().Truncate(time.Second))
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("BillingBoundary", func() {
+	var nodePool *karpv1.NodePool
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+	launchedAgo := func(d time.Duration) {
+		nodeClaim.Status.Conditions = []status.Condition{{
+			Type:               karpv1.ConditionTypeLaunched,
+			Status:             metav1.ConditionTrue,
+			Reason:             karpv1.ConditionTypeLaunched,
+			LastTransitionTime: metav1.NewTime(fakeClock.Now().Add(-d)),
+		}}
+	}
+
+	BeforeEach(func() {
+		nodePool = coretest.NodePool(karpv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1.AnnotationBillingPeriod: "1h"},
+			},
+		})
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: nodePool.Name},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		node = coretest.Node(coretest.NodeOptions{ProviderID: nodeClaim.Status.ProviderID})
+		nodeClaim.Status.NodeName = node.Name
+	})
+
+	It("should defer disruption until the billing boundary window", func() {
+		launchedAgo(10 * time.Minute)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", 45*time.Minute, time.Second))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(karpv1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).To(HaveKeyWithValue(v1.AnnotationBillingDeferredUntil, fakeClock.Now().Add(45*time.Minute).UTC().Format(time.RFC3339)))
+	})
+	It("should measure billing periods from launch", func() {
+		launchedAgo(70 * time.Minute)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", 45*time.Minute, time.Second))
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should allow disruption within the billing boundary window", func() {
+		launchedAgo(57 * time.Minute)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", 3*time.Minute, time.Second))
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should release deferred Nodes once the window opens", func() {
+		launchedAgo(50 * time.Minute)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+
+		fakeClock.Step(6 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+		Expect(node.Annotations).ToNot(HaveKey(v1.AnnotationBillingDeferredUntil))
+		Expect(recorder.Events).To(Receive(ContainSubstring("BillingBoundaryReached")))
+	})
+	It("should not defer disruption for NodePools without a billing period", func() {
+		nodePool.Annotations = nil
+		launchedAgo(10 * time.Minute)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should ignore billing periods that can't be parsed", func() {
+		nodePool.Annotations = map[string]string{v1.AnnotationBillingPeriod: "hourly"}
+		launchedAgo(10 * time.Minute)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should not defer Nodes that are already being disrupted", func() {
+		launchedAgo(10 * time.Minute)
+		node.Spec.Taints = []corev1.Taint{karpv1.DisruptedNoScheduleTaint}
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should not remove a do-not-disrupt annotation that it didn't add", func() {
+		node.Annotations = map[string]string{karpv1.DoNotDisruptAnnotationKey: "true"}
+		launchedAgo(57 * time.Minute)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(karpv1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).ToNot(HaveKey(v1.AnnotationBillingDeferredUntil))
+	})
+})
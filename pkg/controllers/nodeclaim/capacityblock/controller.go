@@ -0,0 +1,144 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityblock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// Controller terminates NodeClaims that were launched into a Capacity Block ahead of the block's end time. NodeClaims are
+// deleted once the block is within its EC2NodeClass's drainBefore window, with a termination deadline of the time that EC2
+// begins reclaiming the block's instances. Karpenter drains the node until that deadline and then forcibly removes any
+// remaining pods, so that workloads are shut down by Karpenter rather than by EC2 terminating the instance underneath them.
+type Controller struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.capacityblock")
+
+	if !inCapacityBlock(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	id := nodeClaim.Annotations[v1.AnnotationCapacityBlockID]
+	endTime, err := time.Parse(time.RFC3339, nodeClaim.Annotations[v1.AnnotationCapacityBlockEndTime])
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the annotation has been updated.
+		log.FromContext(ctx).Error(err, fmt.Sprintf("failed parsing %s", v1.AnnotationCapacityBlockEndTime))
+		return reconcile.Result{}, nil
+	}
+	drainBefore, err := c.drainBefore(ctx, nodeClaim, id)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	reclaimTime := capacityreservation.ReclaimTime(endTime)
+	if ttl := reclaimTime.Add(-drainBefore).Sub(c.clk.Now()); ttl > 0 {
+		return reconcile.Result{RequeueAfter: ttl}, nil
+	}
+	// Record the termination reason and deadline before deleting, since the deadline is only derived from the NodeClaim's
+	// terminationGracePeriod if it hasn't already been set
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{
+		v1.AnnotationTerminationReason: string(v1.TerminationReasonCapacityBlockExpiry),
+	})
+	if _, ok := nodeClaim.Annotations[karpv1.NodeClaimTerminationTimestampAnnotationKey]; !ok {
+		nodeClaim.Annotations[karpv1.NodeClaimTerminationTimestampAnnotationKey] = reclaimTime.Format(time.RFC3339)
+	}
+	if err = c.kubeClient.Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		if errors.IsConflict(err) {
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching nodeclaim termination deadline, %w", err))
+	}
+	if err = c.kubeClient.Delete(ctx, nodeClaim); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("deleting nodeclaim, %w", err))
+	}
+	log.FromContext(ctx).WithValues("capacity-block", id, "reclaim-time", reclaimTime.Format(time.RFC3339)).Info("terminating nodeclaim before capacity block expires")
+	c.recorder.Publish(TerminatingOnCapacityBlockExpiryEvent(nodeClaim, id, reclaimTime))
+	return reconcile.Result{}, nil
+}
+
+// drainBefore returns the drain window of the Capacity Block that the NodeClaim was launched into. If the EC2NodeClass no
+// longer references that block, the NodeClaim isn't deleted until EC2 begins reclaiming the block.
+func (c *Controller) drainBefore(ctx context.Context, nodeClaim *karpv1.NodeClaim, id string) (time.Duration, error) {
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		if errors.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("getting nodeclass, %w", err)
+	}
+	if nodeClass.Spec.CapacityBlock == nil || nodeClass.Spec.CapacityBlock.ID != id {
+		return 0, nil
+	}
+	return nodeClass.Spec.CapacityBlock.DrainBefore.Duration, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.capacityblock").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return inCapacityBlock(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func inCapacityBlock(nc *karpv1.NodeClaim) bool {
+	_, ok := nc.Annotations[v1.AnnotationCapacityBlockEndTime]
+	return ok && nc.DeletionTimestamp.IsZero() && nc.Spec.NodeClassRef != nil
+}
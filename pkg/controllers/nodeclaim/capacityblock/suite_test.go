@@ -0,0 +1,162 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityblock_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/awslabs/operatorpkg/object"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/capacityblock"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var controller *capacityblock.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CapacityBlock")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...), coretest.WithFieldIndexers(coretest.NodeClaimNodeClassRefFieldIndexer(ctx)))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	fakeClock = clock.NewFakeClock(time.Now())
+	controller = capacityblock.NewController(fakeClock, env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	fakeClock.SetTime(time.Now())
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("CapacityBlock", func() {
+	const id = "cr-0123456789abcdef0"
+	var nodeClass *v1.EC2NodeClass
+	var nodeClaim *karpv1.NodeClaim
+	var endTime time.Time
+
+	BeforeEach(func() {
+		endTime = fakeClock.Now().Add(24 * time.Hour).Truncate(time.Second)
+		nodeClass = test.EC2NodeClass(v1.EC2NodeClass{
+			Spec: v1.EC2NodeClassSpec{
+				CapacityBlock: &v1.CapacityBlock{ID: id, DrainBefore: metav1.Duration{Duration: time.Hour}},
+			},
+		})
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1.AnnotationCapacityBlockID:      id,
+					v1.AnnotationCapacityBlockEndTime: endTime.Format(time.RFC3339),
+				},
+				Finalizers: []string{karpv1.TerminationFinalizer},
+			},
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+	})
+
+	It("should requeue until the capacity block's drain window begins", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(result.RequeueAfter).To(Equal(endTime.Add(-30 * time.Minute).Add(-time.Hour).Sub(fakeClock.Now())))
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).DeletionTimestamp.IsZero()).To(BeTrue())
+	})
+	It("should delete the nodeclaim with a termination deadline of the capacity block's reclaim time", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		fakeClock.SetTime(endTime.Add(-30 * time.Minute).Add(-time.Hour))
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.DeletionTimestamp.IsZero()).To(BeFalse())
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationTerminationReason, string(v1.TerminationReasonCapacityBlockExpiry)))
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(karpv1.NodeClaimTerminationTimestampAnnotationKey, endTime.Add(-30*time.Minute).Format(time.RFC3339)))
+	})
+	It("should not override an existing termination deadline", func() {
+		deadline := fakeClock.Now().Add(time.Minute).Format(time.RFC3339)
+		nodeClaim.Annotations[karpv1.NodeClaimTerminationTimestampAnnotationKey] = deadline
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		fakeClock.SetTime(endTime.Add(-30 * time.Minute).Add(-time.Hour))
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.DeletionTimestamp.IsZero()).To(BeFalse())
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(karpv1.NodeClaimTerminationTimestampAnnotationKey, deadline))
+	})
+	It("should ignore the drain window when the nodeclass references a different capacity block", func() {
+		nodeClass.Spec.CapacityBlock.ID = "cr-fedcba9876543210f"
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		fakeClock.SetTime(endTime.Add(-30 * time.Minute).Add(-time.Hour))
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(result.RequeueAfter).To(Equal(time.Hour))
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).DeletionTimestamp.IsZero()).To(BeTrue())
+	})
+	It("should ignore nodeclaims that weren't launched into a capacity block", func() {
+		nodeClaim.Annotations = nil
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		fakeClock.SetTime(endTime)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).DeletionTimestamp.IsZero()).To(BeTrue())
+	})
+})
@@ -0,0 +1,193 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemonreadiness
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	nodeutils "sigs.k8s.io/karpenter/pkg/utils/node"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// Controller holds the Nodes of NodeClaims with the daemon readiness startup taint uninitialized until the pods of the
+// readiness DaemonSets, e.g. the VPC CNI and EBS CSI node plugins, are ready on them. Upstream considers a Node initialized
+// once it's ready and its extended resources are registered, which can be before its networking is functional, so pods
+// scheduled to it early fail to start. Upstream doesn't initialize a NodeClaim until its startup taints are removed, so the
+// taint holds the NodeClaim uninitialized until then.
+type Controller struct {
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.daemonreadiness")
+
+	if !isGated(nodeClaim) || !nodeClaim.StatusConditions().Get(karpv1.ConditionTypeRegistered).IsTrue() {
+		return reconcile.Result{}, nil
+	}
+	node, err := nodeclaim.NodeForNodeClaim(ctx, c.kubeClient, nodeClaim)
+	if err != nil {
+		return reconcile.Result{}, nodeclaim.IgnoreDuplicateNodeError(nodeclaim.IgnoreNodeNotFoundError(err))
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Node", klog.KRef("", node.Name)))
+	// The taint is removed after the condition is set, so that a NodeClaim is never held again once its daemons were ready
+	if nodeClaim.StatusConditions().Get(v1.ConditionTypeDaemonsReady).IsTrue() {
+		return reconcile.Result{}, c.removeTaint(ctx, node)
+	}
+	pending, err := c.pendingDaemonSets(ctx, nodeClaim, node)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	stored := nodeClaim.DeepCopy()
+	if len(pending) > 0 {
+		nodeClaim.StatusConditions().SetFalse(v1.ConditionTypeDaemonsReady, "DaemonPodsNotReady",
+			fmt.Sprintf("Waiting for the pods of DaemonSets %s to be ready", pretty.Slice(pending, 5)))
+		if !equality.Semantic.DeepEqual(stored.Status, nodeClaim.Status) {
+			if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+				return reconcile.Result{}, client.IgnoreNotFound(err)
+			}
+			log.FromContext(ctx).WithValues("daemonsets", pending).V(1).Info("waiting for daemon pods to be ready")
+		}
+		// Daemon pod readiness changes are watched, so there's no need to requeue
+		return reconcile.Result{}, nil
+	}
+	nodeClaim.StatusConditions().SetTrue(v1.ConditionTypeDaemonsReady)
+	if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).Info("daemon pods are ready")
+	return reconcile.Result{}, c.removeTaint(ctx, node)
+}
+
+// pendingDaemonSets returns the readiness DaemonSets that should run a pod on the Node, but whose pod isn't ready yet.
+// DaemonSets that don't exist, or whose pods wouldn't be scheduled to the Node, aren't waited for.
+func (c *Controller) pendingDaemonSets(ctx context.Context, nodeClaim *karpv1.NodeClaim, node *corev1.Node) ([]string, error) {
+	pods, err := nodeutils.GetPods(ctx, c.kubeClient, node)
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, key := range options.FromContext(ctx).ReadinessDaemonSetKeys() {
+		daemonSet := &appsv1.DaemonSet{}
+		if err = c.kubeClient.Get(ctx, key, daemonSet); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("getting daemonset, %w", err)
+		}
+		if !schedulesTo(daemonSet, nodeClaim, node) {
+			continue
+		}
+		if !lo.ContainsBy(pods, func(p *corev1.Pod) bool { return isOwnedBy(p, key) && isReady(p) }) {
+			pending = append(pending, key.String())
+		}
+	}
+	sort.Strings(pending)
+	return pending, nil
+}
+
+// schedulesTo returns whether the DaemonSet's pods would be scheduled to the Node. Startup taints are ignored since
+// they're removed once the Node is initialized, so only the NodeClaim's taints need to be tolerated.
+func schedulesTo(daemonSet *appsv1.DaemonSet, nodeClaim *karpv1.NodeClaim, node *corev1.Node) bool {
+	pod := &corev1.Pod{Spec: daemonSet.Spec.Template.Spec}
+	if err := scheduling.Taints(nodeClaim.Spec.Taints).Tolerates(pod); err != nil {
+		return false
+	}
+	return scheduling.NewLabelRequirements(node.Labels).Compatible(scheduling.NewStrictPodRequirements(pod)) == nil
+}
+
+func isOwnedBy(pod *corev1.Pod, daemonSet types.NamespacedName) bool {
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind == "DaemonSet" && owner.Name == daemonSet.Name && pod.Namespace == daemonSet.Namespace
+}
+
+func isReady(pod *corev1.Pod) bool {
+	return lo.ContainsBy(pod.Status.Conditions, func(c corev1.PodCondition) bool {
+		return c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue
+	})
+}
+
+func (c *Controller) removeTaint(ctx context.Context, node *corev1.Node) error {
+	stored := node.DeepCopy()
+	node.Spec.Taints = lo.Reject(node.Spec.Taints, func(t corev1.Taint, _ int) bool { return t.Key == v1.DaemonReadinessTaintKey })
+	if len(node.Spec.Taints) == len(stored.Spec.Taints) {
+		return nil
+	}
+	// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+	// can cause races due to the fact that it fully replaces the list on a change
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("removing daemon readiness taint, %w", err))
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.daemonreadiness").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider), predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isGated(o.(*karpv1.NodeClaim))
+		}))).
+		Watches(&corev1.Pod{}, nodeclaim.PodEventHandler(c.kubeClient, c.cloudProvider)).
+		Watches(&corev1.Node{}, nodeclaim.NodeEventHandler(c.kubeClient, c.cloudProvider)).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isGated(nc *karpv1.NodeClaim) bool {
+	// NodeClaim is currently terminating
+	if !nc.DeletionTimestamp.IsZero() {
+		return false
+	}
+	return lo.ContainsBy(nc.Spec.StartupTaints, func(t corev1.Taint) bool { return t.Key == v1.DaemonReadinessTaintKey })
+}
@@ -0,0 +1,187 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package daemonreadiness_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/daemonreadiness"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var controller *daemonreadiness.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DaemonReadiness")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	controller = daemonreadiness.NewController(env.Client, cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+		ReadinessDaemonSets: lo.ToPtr("kube-system/aws-node,kube-system/ebs-csi-node"),
+	}))
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("DaemonReadiness", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+	var daemonSet *appsv1.DaemonSet
+	taint := corev1.Taint{Key: v1.DaemonReadinessTaintKey, Effect: corev1.TaintEffectNoSchedule}
+
+	BeforeEach(func() {
+		nodeClaim, node = coretest.NodeClaimAndNode(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: "default"},
+			},
+			Spec: karpv1.NodeClaimSpec{
+				StartupTaints: []corev1.Taint{taint},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		node.Spec.Taints = []corev1.Taint{taint}
+		daemonSet = coretest.DaemonSet(coretest.DaemonSetOptions{
+			ObjectMeta: metav1.ObjectMeta{Name: "aws-node", Namespace: "kube-system"},
+		})
+	})
+	apply := func(objects ...client.Object) {
+		ExpectApplied(ctx, env.Client, append([]client.Object{nodeClaim, node}, objects...)...)
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+	}
+	daemonPod := func(ready corev1.ConditionStatus) *corev1.Pod {
+		return coretest.Pod(coretest.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: daemonSet.Namespace,
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "DaemonSet",
+					Name:       daemonSet.Name,
+					UID:        daemonSet.UID,
+					Controller: lo.ToPtr(true),
+				}},
+			},
+			NodeName:   node.Name,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+		})
+	}
+	expectTainted := func(tainted bool) {
+		node = ExpectExists(ctx, env.Client, node)
+		if tainted {
+			Expect(node.Spec.Taints).To(ContainElement(HaveField("Key", v1.DaemonReadinessTaintKey)))
+		} else {
+			Expect(node.Spec.Taints).ToNot(ContainElement(HaveField("Key", v1.DaemonReadinessTaintKey)))
+		}
+	}
+
+	It("should hold the Node until the daemon pod is ready", func() {
+		ExpectApplied(ctx, env.Client, daemonSet)
+		pod := daemonPod(corev1.ConditionFalse)
+		apply(pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		condition := ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDaemonsReady)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("DaemonPodsNotReady"))
+		Expect(condition.Message).To(ContainSubstring("kube-system/aws-node"))
+		expectTainted(true)
+
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		ExpectApplied(ctx, env.Client, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDaemonsReady).IsTrue()).To(BeTrue())
+		expectTainted(false)
+	})
+	It("should hold the Node while the daemon pod hasn't been created", func() {
+		ExpectApplied(ctx, env.Client, daemonSet)
+		apply()
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDaemonsReady).IsFalse()).To(BeTrue())
+		expectTainted(true)
+	})
+	It("should not wait for DaemonSets that don't exist", func() {
+		apply()
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDaemonsReady).IsTrue()).To(BeTrue())
+		expectTainted(false)
+	})
+	It("should not wait for DaemonSets that don't schedule to the Node", func() {
+		daemonSet.Spec.Template.Spec.NodeSelector = map[string]string{corev1.LabelArchStable: "s390x"}
+		ExpectApplied(ctx, env.Client, daemonSet)
+		apply()
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		expectTainted(false)
+	})
+	It("should not wait for DaemonSets that don't tolerate the NodeClaim's taints", func() {
+		nodeClaim.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+		ExpectApplied(ctx, env.Client, daemonSet)
+		apply()
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		expectTainted(false)
+	})
+	It("should ignore NodeClaims without the daemon readiness startup taint", func() {
+		nodeClaim.Spec.StartupTaints = nil
+		ExpectApplied(ctx, env.Client, daemonSet)
+		apply()
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDaemonsReady)).To(BeNil())
+		expectTainted(true)
+	})
+})
@@ -0,0 +1,151 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioningwebhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// Controller notifies the deprovisioning webhook when a NodeClaim begins terminating and again once its instance has been
+// terminated. A finalizer is added to every NodeClaim so that the NodeClaim is kept around until the PostTermination event has
+// been sent, after the upstream termination finalizer has been removed.
+type Controller struct {
+	clk             clock.Clock
+	kubeClient      client.Client
+	cloudProvider   cloudprovider.CloudProvider
+	webhookProvider webhook.Provider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, webhookProvider webhook.Provider) *Controller {
+	return &Controller{
+		clk:             clk,
+		kubeClient:      kubeClient,
+		cloudProvider:   cloudProvider,
+		webhookProvider: webhookProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.deprovisioningwebhook")
+
+	// The webhook may have been disabled after NodeClaims were launched with the finalizer, so we still need to release them
+	if options.FromContext(ctx).DeprovisioningWebhookURL == "" {
+		return c.removeFinalizer(ctx, nodeClaim)
+	}
+	if nodeClaim.DeletionTimestamp.IsZero() {
+		return c.addFinalizer(ctx, nodeClaim)
+	}
+	if !controllerutil.ContainsFinalizer(nodeClaim, v1.DeprovisioningFinalizer) {
+		return reconcile.Result{}, nil
+	}
+	// The PreDrain event is only meaningful while the upstream termination finalizer is still draining the node and terminating the instance
+	if nodeClaim.Annotations[v1.AnnotationPreDrainWebhookSent] != "true" && controllerutil.ContainsFinalizer(nodeClaim, karpv1.TerminationFinalizer) {
+		if err := c.send(ctx, nodeClaim, webhook.EventTypePreDrain); err != nil {
+			return reconcile.Result{}, err
+		}
+		stored := nodeClaim.DeepCopy()
+		nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationPreDrainWebhookSent: "true"})
+		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching nodeclaim, %w", err))
+		}
+	}
+	// We'll be re-triggered once the upstream termination finalizer is removed
+	if controllerutil.ContainsFinalizer(nodeClaim, karpv1.TerminationFinalizer) {
+		return reconcile.Result{}, nil
+	}
+	if err := c.send(ctx, nodeClaim, webhook.EventTypePostTermination); err != nil {
+		return reconcile.Result{}, err
+	}
+	return c.removeFinalizer(ctx, nodeClaim)
+}
+
+// send delivers the event to the webhook. Failures only fail the reconcile, and so are retried, under the Fail policy.
+func (c *Controller) send(ctx context.Context, nodeClaim *karpv1.NodeClaim, eventType webhook.EventType) error {
+	err := c.webhookProvider.Send(ctx, webhook.NewEvent(eventType, nodeClaim, c.clk.Now()))
+	if err == nil {
+		log.FromContext(ctx).WithValues("event", eventType).V(1).Info("sent deprovisioning webhook event")
+		return nil
+	}
+	if options.FromContext(ctx).DeprovisioningWebhookFailurePolicy == string(options.DeprovisioningWebhookFailurePolicyFail) {
+		return fmt.Errorf("sending deprovisioning webhook event, %w", err)
+	}
+	log.FromContext(ctx).WithValues("event", eventType).Error(err, "failed sending deprovisioning webhook event, ignoring")
+	return nil
+}
+
+func (c *Controller) addFinalizer(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	if controllerutil.ContainsFinalizer(nodeClaim, v1.DeprovisioningFinalizer) {
+		return reconcile.Result{}, nil
+	}
+	stored := nodeClaim.DeepCopy()
+	controllerutil.AddFinalizer(nodeClaim, v1.DeprovisioningFinalizer)
+	return c.patchFinalizers(ctx, stored, nodeClaim)
+}
+
+func (c *Controller) removeFinalizer(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	if !controllerutil.ContainsFinalizer(nodeClaim, v1.DeprovisioningFinalizer) {
+		return reconcile.Result{}, nil
+	}
+	stored := nodeClaim.DeepCopy()
+	controllerutil.RemoveFinalizer(nodeClaim, v1.DeprovisioningFinalizer)
+	return c.patchFinalizers(ctx, stored, nodeClaim)
+}
+
+func (c *Controller) patchFinalizers(ctx context.Context, stored, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+	// can cause races due to the fact that it fully replaces the list on a change
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		if errors.IsConflict(err) {
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching nodeclaim finalizers, %w", err))
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.deprovisioningwebhook").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
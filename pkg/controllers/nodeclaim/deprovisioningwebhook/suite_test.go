@@ -0,0 +1,225 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprovisioningwebhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/deprovisioningwebhook"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var cloudProvider *cloudprovider.CloudProvider
+var server *httptest.Server
+var webhookController *deprovisioningwebhook.Controller
+
+var mu sync.Mutex
+var received []webhook.Event
+var statusCode int
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DeprovisioningWebhookController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		event := webhook.Event{}
+		Expect(json.NewDecoder(r.Body).Decode(&event)).To(Succeed())
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, event)
+		w.WriteHeader(statusCode)
+	}))
+	webhookController = deprovisioningwebhook.NewController(fakeClock, env.Client, cloudProvider, webhook.NewDefaultProvider(server.URL, time.Second))
+})
+var _ = AfterSuite(func() {
+	server.Close()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{DeprovisioningWebhookURL: lo.ToPtr(server.URL)}))
+	fakeClock.SetTime(time.Now())
+	awsEnv.Reset()
+	mu.Lock()
+	defer mu.Unlock()
+	received = nil
+	statusCode = http.StatusOK
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func receivedEvents() []webhook.Event {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]webhook.Event{}, received...)
+}
+
+// expectTerminated simulates the upstream termination controller finishing by removing its finalizer from the NodeClaim
+func expectTerminated(nodeClaim *karpv1.NodeClaim) {
+	GinkgoHelper()
+	nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+	stored := nodeClaim.DeepCopy()
+	controllerutil.RemoveFinalizer(nodeClaim, karpv1.TerminationFinalizer)
+	Expect(env.Client.Patch(ctx, nodeClaim, client.MergeFrom(stored))).To(Succeed())
+}
+
+var _ = Describe("DeprovisioningWebhookController", func() {
+	var nodeClaim *karpv1.NodeClaim
+
+	BeforeEach(func() {
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{karpv1.TerminationFinalizer},
+				Labels: map[string]string{
+					karpv1.NodePoolLabelKey:        "default",
+					corev1.LabelInstanceTypeStable: "m5.large",
+					corev1.LabelTopologyZone:       "test-zone-1a",
+					karpv1.CapacityTypeLabelKey:    karpv1.CapacityTypeOnDemand,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+				NodeName:   "test-node",
+			},
+		})
+	})
+	It("should add the finalizer to NodeClaims", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Finalizers).To(ContainElement(v1.DeprovisioningFinalizer))
+		Expect(receivedEvents()).To(BeEmpty())
+	})
+	It("should remove the finalizer when the webhook is disabled", func() {
+		nodeClaim.Finalizers = append(nodeClaim.Finalizers, v1.DeprovisioningFinalizer)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ctx = options.ToContext(ctx, test.Options())
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Finalizers).ToNot(ContainElement(v1.DeprovisioningFinalizer))
+	})
+	It("should send PreDrain and PostTermination events as the NodeClaim terminates", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		events := receivedEvents()
+		Expect(events).To(HaveLen(1))
+		Expect(events[0].Type).To(Equal(webhook.EventTypePreDrain))
+		Expect(events[0].NodeClaim).To(Equal(nodeClaim.Name))
+		Expect(events[0].Node).To(Equal("test-node"))
+		Expect(events[0].NodePool).To(Equal("default"))
+		Expect(events[0].ProviderID).To(Equal(nodeClaim.Status.ProviderID))
+		Expect(events[0].InstanceType).To(Equal("m5.large"))
+		Expect(events[0].Zone).To(Equal("test-zone-1a"))
+		Expect(events[0].CapacityType).To(Equal(karpv1.CapacityTypeOnDemand))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationPreDrainWebhookSent, "true"))
+
+		// The PreDrain event isn't resent while the instance is terminating
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		Expect(receivedEvents()).To(HaveLen(1))
+
+		expectTerminated(nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		events = receivedEvents()
+		Expect(events).To(HaveLen(2))
+		Expect(events[1].Type).To(Equal(webhook.EventTypePostTermination))
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+	})
+	It("should not hold the NodeClaim when the webhook fails with the Ignore policy", func() {
+		statusCode = http.StatusInternalServerError
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		expectTerminated(nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		Expect(receivedEvents()).To(HaveLen(2))
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+	})
+	It("should hold the NodeClaim until the PostTermination event is delivered with the Fail policy", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			DeprovisioningWebhookURL:           lo.ToPtr(server.URL),
+			DeprovisioningWebhookFailurePolicy: lo.ToPtr(string(options.DeprovisioningWebhookFailurePolicyFail)),
+		}))
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+		expectTerminated(nodeClaim)
+
+		statusCode = http.StatusServiceUnavailable
+		_ = ExpectObjectReconcileFailed(ctx, env.Client, webhookController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Finalizers).To(ContainElement(v1.DeprovisioningFinalizer))
+
+		statusCode = http.StatusOK
+		ExpectObjectReconciled(ctx, env.Client, webhookController, nodeClaim)
+		// The PreDrain event is skipped since the instance had already been terminated
+		Expect(lo.Map(receivedEvents(), func(e webhook.Event, _ int) webhook.EventType { return e.Type })).To(Equal([]webhook.EventType{
+			webhook.EventTypePostTermination, webhook.EventTypePostTermination,
+		}))
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+	})
+})
@@ -0,0 +1,179 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disruptionapproval
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeutils "sigs.k8s.io/karpenter/pkg/utils/node"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+	podutils "sigs.k8s.io/karpenter/pkg/utils/pod"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// Controller blocks voluntary disruption of Nodes hosting pods with the karpenter.sh/approval-required annotation until an
+// operator approves it. Approval is given by annotating the NodeClaim with karpenter.k8s.aws/disruption-approved-until, set
+// to the RFC3339 time at which the approval expires. Disruption is blocked the same way as for paused NodePools, by adding
+// the do-not-disrupt annotation to the Node, so interruption handling and manual deletion are unaffected.
+type Controller struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.disruptionapproval")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() || nodeClaim.Status.NodeName == "" {
+		return reconcile.Result{}, nil
+	}
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("getting node, %w", err))
+	}
+	pods, err := nodeutils.GetPods(ctx, c.kubeClient, node)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	gated := lo.FilterMap(pods, func(p *corev1.Pod, _ int) (string, bool) {
+		return client.ObjectKeyFromObject(p).String(), !podutils.IsTerminal(p) && p.Annotations[v1.AnnotationApprovalRequired] == "true"
+	})
+	sort.Strings(gated)
+	approvedUntil, approvalErr := parseApproval(nodeClaim)
+	approved := approvalErr == nil && approvedUntil.After(c.clk.Now())
+	blocked := len(gated) > 0 && !approved
+
+	if err = c.reconcileNode(ctx, node, blocked); err != nil {
+		return reconcile.Result{}, err
+	}
+	stored := nodeClaim.DeepCopy()
+	switch {
+	case len(gated) == 0:
+		_ = nodeClaim.StatusConditions().Clear(v1.ConditionTypeDisruptionApprovalPending)
+	case approved:
+		nodeClaim.StatusConditions().SetFalse(v1.ConditionTypeDisruptionApprovalPending, "Approved",
+			fmt.Sprintf("Disruption approved until %s", approvedUntil.Format(time.RFC3339)))
+	default:
+		nodeClaim.StatusConditions().SetTrueWithReason(v1.ConditionTypeDisruptionApprovalPending, "AwaitingApproval",
+			awaitingApprovalMessage(gated, approvedUntil, approvalErr))
+	}
+	if !equality.Semantic.DeepEqual(stored, nodeClaim) {
+		if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			if errors.IsConflict(err) {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching nodeclaim, %w", err))
+		}
+		log.FromContext(ctx).WithValues("pods", len(gated), "blocked", blocked).V(1).Info("updated disruption approval state")
+	}
+	// Re-block disruption as soon as the approval expires
+	if approved && len(gated) > 0 {
+		return reconcile.Result{RequeueAfter: approvedUntil.Sub(c.clk.Now())}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// parseApproval parses the approval expiry from the NodeClaim. A zero time is returned if the NodeClaim isn't approved.
+func parseApproval(nodeClaim *karpv1.NodeClaim) (time.Time, error) {
+	value, ok := nodeClaim.Annotations[v1.AnnotationDisruptionApprovedUntil]
+	if !ok {
+		return time.Time{}, nil
+	}
+	until, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing %s, %w", v1.AnnotationDisruptionApprovedUntil, err)
+	}
+	return until, nil
+}
+
+func awaitingApprovalMessage(gated []string, approvedUntil time.Time, approvalErr error) string {
+	msg := fmt.Sprintf("Pods %s require approval before voluntary disruption", pretty.Slice(gated, 5))
+	switch {
+	case approvalErr != nil:
+		return fmt.Sprintf("%s, %s", msg, approvalErr)
+	case !approvedUntil.IsZero():
+		return fmt.Sprintf("%s, approval expired at %s", msg, approvedUntil.Format(time.RFC3339))
+	}
+	return msg
+}
+
+// reconcileNode adds the do-not-disrupt annotation to the Node while disruption is blocked, and removes it once approved or
+// once no pod requires approval. Nodes that already had the annotation are left untouched, so that we never remove an
+// annotation that we didn't add.
+func (c *Controller) reconcileNode(ctx context.Context, node *corev1.Node, blocked bool) error {
+	stored := node.DeepCopy()
+	_, doNotDisrupt := node.Annotations[karpv1.DoNotDisruptAnnotationKey]
+	_, managed := node.Annotations[v1.AnnotationApprovalDoNotDisrupt]
+	switch {
+	case blocked && !doNotDisrupt:
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{
+			karpv1.DoNotDisruptAnnotationKey:  "true",
+			v1.AnnotationApprovalDoNotDisrupt: "true",
+		})
+	case !blocked && managed:
+		node.Annotations = lo.OmitByKeys(node.Annotations, []string{karpv1.DoNotDisruptAnnotationKey, v1.AnnotationApprovalDoNotDisrupt})
+	default:
+		return nil
+	}
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("patching node, %w", err)
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.disruptionapproval").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		Watches(&corev1.Pod{}, nodeclaimutils.PodEventHandler(c.kubeClient, c.cloudProvider)).
+		Watches(&corev1.Node{}, nodeclaimutils.NodeEventHandler(c.kubeClient, c.cloudProvider)).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
@@ -0,0 +1,180 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disruptionapproval_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/disruptionapproval"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var controller *disruptionapproval.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "DisruptionApproval")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...), coretest.WithFieldIndexers(coretest.NodeClaimNodeClassRefFieldIndexer(ctx)))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	fakeClock = clock.NewFakeClock(time.Now())
+	controller = disruptionapproval.NewController(fakeClock, env.Client, cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	fakeClock.SetTime(time.Now())
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("DisruptionApproval", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: "default"},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		node = coretest.Node(coretest.NodeOptions{ProviderID: nodeClaim.Status.ProviderID})
+		nodeClaim.Status.NodeName = node.Name
+		pod = coretest.Pod(coretest.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1.AnnotationApprovalRequired: "true"},
+			},
+			NodeName: node.Name,
+		})
+	})
+	approveUntil := func(until time.Time) {
+		nodeClaim.Annotations = map[string]string{v1.AnnotationDisruptionApprovedUntil: until.Format(time.RFC3339)}
+	}
+
+	It("should not block disruption of Nodes without pods requiring approval", func() {
+		pod.Annotations = nil
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDisruptionApprovalPending)).To(BeNil())
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should block disruption until approved", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(karpv1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).To(HaveKeyWithValue(v1.AnnotationApprovalDoNotDisrupt, "true"))
+		condition := ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDisruptionApprovalPending)
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("AwaitingApproval"))
+		Expect(condition.Message).To(ContainSubstring(pod.Name))
+	})
+	It("should unblock disruption once approved and re-block when the approval expires", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		approveUntil(fakeClock.Now().Add(time.Hour))
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+		condition := ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDisruptionApprovalPending)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("Approved"))
+
+		fakeClock.Step(time.Hour + time.Second)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKeyWithValue(karpv1.DoNotDisruptAnnotationKey, "true"))
+		condition = ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDisruptionApprovalPending)
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Message).To(ContainSubstring("approval expired"))
+	})
+	It("should keep blocking disruption when the approval can't be parsed", func() {
+		nodeClaim.Annotations = map[string]string{v1.AnnotationDisruptionApprovedUntil: "tomorrow"}
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKeyWithValue(karpv1.DoNotDisruptAnnotationKey, "true"))
+		condition := ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDisruptionApprovalPending)
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Message).To(ContainSubstring(v1.AnnotationDisruptionApprovedUntil))
+	})
+	It("should unblock disruption once the pods requiring approval are gone", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+
+		ExpectDeleted(ctx, env.Client, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).StatusConditions().Get(v1.ConditionTypeDisruptionApprovalPending)).To(BeNil())
+	})
+	It("should ignore terminal pods", func() {
+		pod.Status.Phase = corev1.PodSucceeded
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should not remove a do-not-disrupt annotation that it didn't add", func() {
+		node.Annotations = map[string]string{karpv1.DoNotDisruptAnnotationKey: "true"}
+		approveUntil(fakeClock.Now().Add(time.Hour))
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKeyWithValue(karpv1.DoNotDisruptAnnotationKey, "true"))
+	})
+})
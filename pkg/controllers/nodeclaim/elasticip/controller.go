@@ -0,0 +1,156 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticip
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/elasticip"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+const (
+	// pollInterval is how often the instance is checked while it's pending, when there's no interruption queue
+	pollInterval = 5 * time.Second
+	// fallbackPollInterval is how often the instance is checked while it's pending when its running state-change
+	// notification is expected from the interruption queue
+	fallbackPollInterval = 30 * time.Second
+)
+
+// Controller associates the Elastic IPs selected by an EC2NodeClass's elasticIPSelectorTerms with the instances launched
+// for it. Addresses can only be associated once the instance is running, so this happens shortly after launch rather than
+// as part of the CreateFleet request. The association is released by EC2 when the instance is terminated.
+type Controller struct {
+	kubeClient        client.Client
+	recorder          events.Recorder
+	cloudProvider     cloudprovider.CloudProvider
+	instanceProvider  instance.Provider
+	elasticIPProvider elasticip.Provider
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider, instanceProvider instance.Provider,
+	elasticIPProvider elasticip.Provider) *Controller {
+	return &Controller{
+		kubeClient:        kubeClient,
+		recorder:          recorder,
+		cloudProvider:     cloudProvider,
+		instanceProvider:  instanceProvider,
+		elasticIPProvider: elasticIPProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.elasticip")
+
+	if !isAssignable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if len(nodeClass.Spec.ElasticIPSelectorTerms) == 0 {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	// The interruption controller records when the instance started running from its state-change notification, which
+	// triggers a reconcile. EC2 is only polled when that hasn't happened yet, in case the notification is delayed or missed.
+	if _, ok := nodeClaim.Annotations[v1.AnnotationInstanceRunningTime]; !ok {
+		inst, err := c.instanceProvider.Get(ctx, id)
+		if err != nil {
+			return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(fmt.Errorf("getting instance, %w", err))
+		}
+		if inst.State != ec2types.InstanceStateNameRunning {
+			return reconcile.Result{RequeueAfter: lo.Ternary(options.FromContext(ctx).InterruptionQueue != "", fallbackPollInterval, pollInterval)}, nil
+		}
+	}
+	addresses, err := c.elasticIPProvider.List(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing elastic ips, %w", err)
+	}
+	// The address may already be associated if we failed to record the association on a previous reconcile
+	address, ok := lo.Find(addresses, func(a ec2types.Address) bool { return aws.ToString(a.InstanceId) == id })
+	if !ok {
+		if address, ok = lo.Find(addresses, func(a ec2types.Address) bool { return a.AssociationId == nil }); !ok {
+			c.recorder.Publish(ElasticIPPoolExhaustedEvent(nodeClaim, nodeClass))
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+		// Associations aren't reassigned, so this fails and is retried if the address was claimed after we listed it
+		if err = c.elasticIPProvider.Associate(ctx, aws.ToString(address.AllocationId), id); err != nil {
+			return reconcile.Result{}, err
+		}
+		log.FromContext(ctx).WithValues("allocation-id", aws.ToString(address.AllocationId), "public-ip", aws.ToString(address.PublicIp)).
+			Info("associated elastic ip")
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationElasticIPAllocationID: aws.ToString(address.AllocationId)})
+	if err = c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.elasticip").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isAssignable(o.(*karpv1.NodeClaim))
+		})).
+		// Associations are made one at a time so that concurrent reconciles don't race for the same unassociated address
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isAssignable(nc *karpv1.NodeClaim) bool {
+	if _, ok := nc.Annotations[v1.AnnotationElasticIPAllocationID]; ok {
+		return false
+	}
+	return nc.Status.ProviderID != "" && nc.DeletionTimestamp.IsZero() && nc.Spec.NodeClassRef != nil
+}
@@ -0,0 +1,204 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticip_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/elasticip"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var elasticIPController *elasticip.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ElasticIPController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	recorder := events.NewRecorder(&record.FakeRecorder{})
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, recorder,
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	elasticIPController = elasticip.NewController(env.Client, recorder, cloudProvider, awsEnv.InstanceProvider, awsEnv.ElasticIPProvider)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("ElasticIPController", func() {
+	var nodeClass *v1.EC2NodeClass
+	var nodeClaim *karpv1.NodeClaim
+	var ec2Instance ec2types.Instance
+	storeAddress := func(allocationID string, tags map[string]string, instanceID string) {
+		awsEnv.EC2API.Addresses.Store(allocationID, ec2types.Address{
+			AllocationId:  aws.String(allocationID),
+			PublicIp:      aws.String("203.0.113.10"),
+			AssociationId: lo.Ternary(instanceID != "", aws.String("eipassoc-"+allocationID), nil),
+			InstanceId:    lo.Ternary(instanceID != "", aws.String(instanceID), nil),
+			Tags: lo.MapToSlice(tags, func(k, v string) ec2types.Tag {
+				return ec2types.Tag{Key: aws.String(k), Value: aws.String(v)}
+			}),
+		})
+	}
+	addressFor := func(allocationID string) ec2types.Address {
+		address, ok := awsEnv.EC2API.Addresses.Load(allocationID)
+		Expect(ok).To(BeTrue())
+		return address.(ec2types.Address)
+	}
+
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass(v1.EC2NodeClass{
+			Spec: v1.EC2NodeClassSpec{
+				ElasticIPSelectorTerms: []v1.ElasticIPSelectorTerm{{Tags: map[string]string{"eip-pool": "egress"}}},
+			},
+		})
+		ec2Instance = ec2types.Instance{
+			State:        &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			Placement:    &ec2types.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+			InstanceId:   aws.String(fake.InstanceID()),
+			InstanceType: "m5.large",
+		}
+		awsEnv.EC2API.Instances.Store(aws.ToString(ec2Instance.InstanceId), ec2Instance)
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(aws.ToString(ec2Instance.InstanceId)),
+			},
+		})
+	})
+
+	It("should associate an unassociated elastic ip selected by the nodeclass", func() {
+		storeAddress("eipalloc-1", map[string]string{"eip-pool": "egress"}, "i-other")
+		storeAddress("eipalloc-2", map[string]string{"eip-pool": "egress"}, "")
+		storeAddress("eipalloc-3", map[string]string{"eip-pool": "other"}, "")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(aws.ToString(addressFor("eipalloc-2").InstanceId)).To(Equal(aws.ToString(ec2Instance.InstanceId)))
+		Expect(addressFor("eipalloc-3").AssociationId).To(BeNil())
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationElasticIPAllocationID, "eipalloc-2"))
+	})
+	It("should record an existing association without associating another elastic ip", func() {
+		storeAddress("eipalloc-1", map[string]string{"eip-pool": "egress"}, aws.ToString(ec2Instance.InstanceId))
+		storeAddress("eipalloc-2", map[string]string{"eip-pool": "egress"}, "")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(awsEnv.EC2API.AssociateAddressBehavior.Calls()).To(Equal(0))
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).Annotations).To(HaveKeyWithValue(v1.AnnotationElasticIPAllocationID, "eipalloc-1"))
+	})
+	It("should requeue when every selected elastic ip is associated", func() {
+		storeAddress("eipalloc-1", map[string]string{"eip-pool": "egress"}, "i-other")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).Annotations).ToNot(HaveKey(v1.AnnotationElasticIPAllocationID))
+	})
+	It("should wait for the instance to be running", func() {
+		ec2Instance.State = &ec2types.InstanceState{Name: ec2types.InstanceStateNamePending}
+		awsEnv.EC2API.Instances.Store(aws.ToString(ec2Instance.InstanceId), ec2Instance)
+		storeAddress("eipalloc-1", map[string]string{"eip-pool": "egress"}, "")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(result.RequeueAfter).ToNot(BeZero())
+		Expect(addressFor("eipalloc-1").AssociationId).To(BeNil())
+	})
+	It("should poll less often for the instance to be running when there's an interruption queue", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionQueue: lo.ToPtr("test-queue")}))
+		ec2Instance.State = &ec2types.InstanceState{Name: ec2types.InstanceStateNamePending}
+		awsEnv.EC2API.Instances.Store(aws.ToString(ec2Instance.InstanceId), ec2Instance)
+		storeAddress("eipalloc-1", map[string]string{"eip-pool": "egress"}, "")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(result.RequeueAfter).To(Equal(30 * time.Second))
+		Expect(addressFor("eipalloc-1").AssociationId).To(BeNil())
+	})
+	It("should not poll for the instance state once it's recorded as running", func() {
+		nodeClaim.Annotations = map[string]string{v1.AnnotationInstanceRunningTime: time.Now().UTC().Format(time.RFC3339)}
+		storeAddress("eipalloc-1", map[string]string{"eip-pool": "egress"}, "")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(awsEnv.EC2API.DescribeInstancesBehavior.Calls()).To(Equal(0))
+		Expect(aws.ToString(addressFor("eipalloc-1").InstanceId)).To(Equal(aws.ToString(ec2Instance.InstanceId)))
+	})
+	It("should not associate elastic ips when the nodeclass doesn't select any", func() {
+		nodeClass.Spec.ElasticIPSelectorTerms = nil
+		storeAddress("eipalloc-1", map[string]string{"eip-pool": "egress"}, "")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(addressFor("eipalloc-1").AssociationId).To(BeNil())
+	})
+	It("should not associate elastic ips with terminating nodeclaims", func() {
+		storeAddress("eipalloc-1", map[string]string{"eip-pool": "egress"}, "")
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, elasticIPController, nodeClaim)
+
+		Expect(addressFor("eipalloc-1").AssociationId).To(BeNil())
+	})
+})
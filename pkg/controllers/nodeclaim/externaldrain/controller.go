@@ -0,0 +1,142 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaldrain
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// Controller detects Nodes that were cordoned outside of Karpenter, e.g. by an operator running kubectl drain. Karpenter
+// never cordons Nodes itself, it taints them with karpenter.sh/disrupted instead, so a cordoned Node without that taint is
+// being drained by someone else. The NodeClaim is marked with the ExternallyDrained condition while its Node is cordoned.
+// With the RespectExternalDrains AWS feature gate, the Node is also excluded from consolidation and drift with the
+// do-not-disrupt annotation, so that Karpenter doesn't start evicting pods from it while the operator's drain is in
+// progress.
+type Controller struct {
+	kubeClient    client.Client
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.externaldrain")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() || nodeClaim.Status.NodeName == "" {
+		return reconcile.Result{}, nil
+	}
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("getting node, %w", err))
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Node", klog.KRef("", node.Name)))
+	drained := IsExternallyDrained(node)
+	if err := c.reconcileNode(ctx, node, drained && options.FromContext(ctx).AWSFeatureGates.Enabled(options.RespectExternalDrains)); err != nil {
+		return reconcile.Result{}, err
+	}
+	stored := nodeClaim.DeepCopy()
+	if drained {
+		nodeClaim.StatusConditions().SetTrueWithReason(v1.ConditionTypeExternallyDrained, "Cordoned", "Node was cordoned outside of Karpenter")
+	} else if err := nodeClaim.StatusConditions().Clear(v1.ConditionTypeExternallyDrained); err != nil {
+		return reconcile.Result{}, err
+	}
+	if equality.Semantic.DeepEqual(stored.Status, nodeClaim.Status) {
+		return reconcile.Result{}, nil
+	}
+	if err := c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if drained {
+		log.FromContext(ctx).Info("node was cordoned outside of karpenter")
+		c.recorder.Publish(ExternallyDrainedEvent(nodeClaim, node))
+	}
+	return reconcile.Result{}, nil
+}
+
+// reconcileNode adds the do-not-disrupt annotation to the Node while it's being drained externally, and removes it once
+// it's uncordoned. Nodes that already had the annotation are left untouched, so that we never remove an annotation that
+// we didn't add.
+func (c *Controller) reconcileNode(ctx context.Context, node *corev1.Node, protect bool) error {
+	stored := node.DeepCopy()
+	_, doNotDisrupt := node.Annotations[karpv1.DoNotDisruptAnnotationKey]
+	_, managed := node.Annotations[v1.AnnotationExternalDrainDoNotDisrupt]
+	switch {
+	case protect && !doNotDisrupt:
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{
+			karpv1.DoNotDisruptAnnotationKey:       "true",
+			v1.AnnotationExternalDrainDoNotDisrupt: "true",
+		})
+	case !protect && managed:
+		node.Annotations = lo.OmitByKeys(node.Annotations, []string{karpv1.DoNotDisruptAnnotationKey, v1.AnnotationExternalDrainDoNotDisrupt})
+	default:
+		return nil
+	}
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("patching node, %w", err)
+	}
+	log.FromContext(ctx).WithValues("protected", protect).V(1).Info("updated externally drained node")
+	return nil
+}
+
+// IsExternallyDrained returns true if the Node is cordoned and wasn't disrupted by Karpenter
+func IsExternallyDrained(node *corev1.Node) bool {
+	return node.Spec.Unschedulable && !lo.ContainsBy(node.Spec.Taints, func(t corev1.Taint) bool {
+		return t.MatchTaint(&karpv1.DisruptedNoScheduleTaint)
+	})
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.externaldrain").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		Watches(&corev1.Node{}, nodeclaimutils.NodeEventHandler(c.kubeClient, c.cloudProvider)).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
@@ -36,6 +36,8 @@ import (
 	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 )
 
 type Controller struct {
@@ -95,7 +97,14 @@ func (c *Controller) garbageCollect(ctx context.Context, nodeClaim *karpv1.NodeC
 	if err := c.cloudProvider.Delete(ctx, nodeClaim); err != nil {
 		return cloudprovider.IgnoreNodeClaimNotFoundError(err)
 	}
-	log.FromContext(ctx).V(1).Info("garbage collected cloudprovider instance")
+	// Stopped instances that still have a NodeClaim are handled by the nodeclaim.stoppedinstance controller, so a stopped
+	// instance only gets here once its NodeClaim is gone. These are logged separately so they can be told apart from orphans
+	// that were left running.
+	if nodeClaim.StatusConditions().Get(v1.ConditionTypeInstanceStopped).IsTrue() {
+		log.FromContext(ctx).V(1).Info("garbage collected stopped cloudprovider instance")
+	} else {
+		log.FromContext(ctx).V(1).Info("garbage collected cloudprovider instance")
+	}
 
 	// Go ahead and cleanup the node if we know that it exists to make scheduling go quicker
 	if node, ok := lo.Find(nodeList.Items, func(n corev1.Node) bool {
@@ -65,7 +65,7 @@ var _ = BeforeSuite(func() {
 	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
 	awsEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
 	garbageCollectionController = garbagecollection.NewController(env.Client, cloudProvider)
 })
 
@@ -280,6 +280,40 @@ var _ = Describe("GarbageCollection", func() {
 			ExpectExists(ctx, env.Client, nodeClaim)
 		}
 	})
+	It("should delete a stopped instance if there is no NodeClaim owner", func() {
+		instance.State = &ec2types.InstanceState{Name: ec2types.InstanceStateNameStopped}
+		instance.LaunchTime = aws.Time(time.Now().Add(-time.Minute))
+		awsEnv.EC2API.Instances.Store(aws.ToString(instance.InstanceId), *instance)
+
+		ExpectSingletonReconciled(ctx, garbageCollectionController)
+		_, err := cloudProvider.Get(ctx, providerID)
+		Expect(err).To(HaveOccurred())
+		Expect(karpcloudprovider.IsNodeClaimNotFoundError(err)).To(BeTrue())
+	})
+	It("should not delete a stopped instance if it has a NodeClaim that matches it", func() {
+		instance.State = &ec2types.InstanceState{Name: ec2types.InstanceStateNameStopped}
+		instance.LaunchTime = aws.Time(time.Now().Add(-time.Minute))
+		awsEnv.EC2API.Instances.Store(aws.ToString(instance.InstanceId), *instance)
+
+		nodeClaim := coretest.NodeClaim(karpv1.NodeClaim{
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: providerID,
+			},
+		})
+		ExpectApplied(ctx, env.Client, nodeClaim)
+
+		ExpectSingletonReconciled(ctx, garbageCollectionController)
+		cloudNodeClaim, err := cloudProvider.Get(ctx, providerID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cloudNodeClaim.StatusConditions().Get(v1.ConditionTypeInstanceStopped).IsTrue()).To(BeTrue())
+	})
 	It("should not delete an instance if it is within the NodeClaim resolution window (1m)", func() {
 		// Launch time just happened
 		instance.LaunchTime = aws.Time(time.Now())
@@ -0,0 +1,94 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gracefultermination
+
+import (
+	"context"
+	"time"
+
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// pollInterval is how often a NodeClaim whose instance termination is being held is driven
+const pollInterval = 5 * time.Second
+
+// Controller drives the termination of instances that the cloud provider holds, e.g. while DaemonSet pods are evicted in
+// order or pre-termination commands run. Delete returns without terminating the instance while it's held, so that upstream doesn't treat the wait as a
+// failed termination and back off. Upstream then marks the NodeClaim as InstanceTerminating and only polls the instance,
+// so this calls Delete again at a fixed interval until the instance is terminated.
+type Controller struct {
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.gracefultermination")
+
+	if !isTerminating(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	retrieved, err := c.cloudProvider.Get(ctx, nodeClaim.Status.ProviderID)
+	if err != nil {
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(err)
+	}
+	// The instance is already shutting down
+	if !retrieved.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	if err = c.cloudProvider.Delete(ctx, nodeClaim); err != nil {
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(err)
+	}
+	return reconcile.Result{RequeueAfter: pollInterval}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.gracefultermination").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider), predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isTerminating(o.(*karpv1.NodeClaim))
+		}))).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+// isTerminating returns true once upstream has called Delete for the NodeClaim's instance, until the NodeClaim is released
+func isTerminating(nc *karpv1.NodeClaim) bool {
+	return !nc.DeletionTimestamp.IsZero() && nc.Status.ProviderID != "" && controllerutil.ContainsFinalizer(nc, karpv1.TerminationFinalizer) &&
+		nc.StatusConditions().Get(karpv1.ConditionTypeInstanceTerminating).IsTrue()
+}
@@ -0,0 +1,144 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gracefultermination_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/gracefultermination"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var gracefulTerminationController *gracefultermination.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GracefulTerminationController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	gracefulTerminationController = gracefultermination.NewController(cloudProvider)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("GracefulTerminationController", func() {
+	var node *corev1.Node
+	var nodeClaim *karpv1.NodeClaim
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		instanceID := fake.InstanceID()
+		awsEnv.EC2API.Instances.Store(instanceID, ec2types.Instance{
+			State:        &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			Placement:    &ec2types.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+			InstanceId:   aws.String(instanceID),
+			InstanceType: "m5.large",
+		})
+		node = coretest.Node()
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{Finalizers: []string{karpv1.TerminationFinalizer}},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(instanceID),
+				NodeName:   node.Name,
+			},
+		})
+		pod = coretest.Pod(coretest.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1.AnnotationEvictionOrder: "1"},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "DaemonSet",
+					Name:       "daemonset",
+					UID:        "daemonset-uid",
+					Controller: lo.ToPtr(true),
+				}},
+			},
+			NodeName: node.Name,
+		})
+	})
+
+	It("should terminate the instance once the ordered daemonset pods have terminated", func() {
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeInstanceTerminating)
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+
+		result := ExpectObjectReconciled(ctx, env.Client, gracefulTerminationController, nodeClaim)
+		Expect(result.RequeueAfter).To(Equal(5 * time.Second))
+		Expect(ExpectExists(ctx, env.Client, pod).DeletionTimestamp.IsZero()).To(BeFalse())
+		Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(0))
+
+		Expect(env.Client.Delete(ctx, pod, client.GracePeriodSeconds(0))).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, gracefulTerminationController, nodeClaim)
+		Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(1))
+
+		result = ExpectObjectReconciled(ctx, env.Client, gracefulTerminationController, nodeClaim)
+		Expect(result.RequeueAfter).To(BeZero())
+	})
+	It("should not terminate instances that upstream hasn't started terminating", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+
+		result := ExpectObjectReconciled(ctx, env.Client, gracefulTerminationController, nodeClaim)
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(ExpectExists(ctx, env.Client, pod).DeletionTimestamp.IsZero()).To(BeTrue())
+		Expect(awsEnv.EC2API.TerminateInstancesBehavior.CalledWithInput.Len()).To(Equal(0))
+	})
+})
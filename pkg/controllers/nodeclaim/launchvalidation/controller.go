@@ -0,0 +1,176 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchvalidation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// RequestTimeout is the maximum duration that Karpenter waits for a single response from the launch validation webhook
+const RequestTimeout = 10 * time.Second
+
+// retryInterval is the interval at which the launch validation webhook is retried after failing to respond
+const retryInterval = 10 * time.Second
+
+// Controller calls the launch validation webhook once the Node of a NodeClaim with the launch validation startup taint has
+// registered. Upstream doesn't initialize a NodeClaim until its startup taints are removed, so the taint holds the NodeClaim
+// uninitialized until the webhook allows it. NodeClaims which are denied, or aren't allowed within the launch validation
+// timeout, are deleted so that they're replaced.
+type Controller struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+	validator     webhook.Validator
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, validator webhook.Validator) *Controller {
+	return &Controller{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+		validator:     validator,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.launchvalidation")
+
+	if !isValidatable(nodeClaim) || !nodeClaim.StatusConditions().Get(karpv1.ConditionTypeRegistered).IsTrue() {
+		return reconcile.Result{}, nil
+	}
+	node, err := nodeclaim.NodeForNodeClaim(ctx, c.kubeClient, nodeClaim)
+	if err != nil {
+		return reconcile.Result{}, nodeclaim.IgnoreDuplicateNodeError(nodeclaim.IgnoreNodeNotFoundError(err))
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Node", klog.KRef("", node.Name)))
+	// The taint is removed after the condition is set, so that a NodeClaim that's allowed is never validated again
+	if nodeClaim.StatusConditions().Get(v1.ConditionTypeLaunchValidated).IsTrue() {
+		return reconcile.Result{}, c.removeTaint(ctx, node)
+	}
+	// The timeout is measured from the last transition of the Registered condition, which is when the taint was applied
+	// NOTE: remaining has to be stored and checked in the same place since c.clk can advance after the check causing a race
+	registered := nodeClaim.StatusConditions().Get(karpv1.ConditionTypeRegistered)
+	remaining := options.FromContext(ctx).LaunchValidationTimeout - c.clk.Since(registered.LastTransitionTime.Time)
+	if remaining <= 0 {
+		ValidationsTotal.Inc(map[string]string{outcomeLabel: "timeout"})
+		return reconcile.Result{}, c.fail(ctx, nodeClaim, "Timeout", fmt.Sprintf("Launch validation webhook didn't allow the node within %s", options.FromContext(ctx).LaunchValidationTimeout))
+	}
+	event := webhook.NewEvent(webhook.EventTypeLaunchValidation, nodeClaim, c.clk.Now())
+	event.Node = node.Name
+	response, err := c.validator.Validate(ctx, event)
+	if err != nil {
+		ValidationsTotal.Inc(map[string]string{outcomeLabel: "error"})
+		log.FromContext(ctx).Error(err, "failed calling launch validation webhook, retrying")
+		return reconcile.Result{RequeueAfter: lo.Min([]time.Duration{retryInterval, remaining})}, nil
+	}
+	if !response.Allowed {
+		ValidationsTotal.Inc(map[string]string{outcomeLabel: "denied"})
+		return reconcile.Result{}, c.fail(ctx, nodeClaim, "Denied", lo.Ternary(response.Reason != "", response.Reason, "Launch validation webhook denied the node"))
+	}
+	ValidationsTotal.Inc(map[string]string{outcomeLabel: "allowed"})
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.StatusConditions().SetTrue(v1.ConditionTypeLaunchValidated)
+	if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).Info("launch validation webhook allowed node")
+	return reconcile.Result{}, c.removeTaint(ctx, node)
+}
+
+// fail records the failed validation on the NodeClaim and deletes it. The termination reason is annotated before the
+// NodeClaim is deleted so that it's recorded as failing validation rather than as a manual deletion.
+func (c *Controller) fail(ctx context.Context, nodeClaim *karpv1.NodeClaim, reason, message string) error {
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.StatusConditions().SetFalse(v1.ConditionTypeLaunchValidated, reason, message)
+	if err := c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	stored = nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationTerminationReason: string(v1.TerminationReasonLaunchValidation)})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if err := c.kubeClient.Delete(ctx, nodeClaim); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).WithValues("reason", reason, "message", message).Info("deleting nodeclaim that failed launch validation")
+	return nil
+}
+
+func (c *Controller) removeTaint(ctx context.Context, node *corev1.Node) error {
+	stored := node.DeepCopy()
+	node.Spec.Taints = lo.Reject(node.Spec.Taints, func(t corev1.Taint, _ int) bool { return t.Key == v1.LaunchValidationTaintKey })
+	if len(node.Spec.Taints) == len(stored.Spec.Taints) {
+		return nil
+	}
+	// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+	// can cause races due to the fact that it fully replaces the list on a change
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return client.IgnoreNotFound(fmt.Errorf("removing launch validation taint, %w", err))
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.launchvalidation").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isValidatable(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isValidatable(nc *karpv1.NodeClaim) bool {
+	// NodeClaim is currently terminating
+	if !nc.DeletionTimestamp.IsZero() {
+		return false
+	}
+	// NodeClaim has already failed validation
+	if nc.StatusConditions().Get(v1.ConditionTypeLaunchValidated).IsFalse() {
+		return false
+	}
+	return lo.ContainsBy(nc.Spec.StartupTaints, func(t corev1.Taint) bool { return t.Key == v1.LaunchValidationTaintKey })
+}
@@ -0,0 +1,237 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchvalidation_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/launchvalidation"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/webhook"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var server *httptest.Server
+var validationController *launchvalidation.Controller
+
+var mu sync.Mutex
+var received []webhook.Event
+var statusCode int
+var response webhook.ValidationResponse
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LaunchValidationController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		event := webhook.Event{}
+		Expect(json.NewDecoder(r.Body).Decode(&event)).To(Succeed())
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, event)
+		w.WriteHeader(statusCode)
+		Expect(json.NewEncoder(w).Encode(response)).To(Succeed())
+	}))
+	validationController = launchvalidation.NewController(fakeClock, env.Client, cloudProvider, webhook.NewDefaultProvider(server.URL, time.Second))
+})
+var _ = AfterSuite(func() {
+	server.Close()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+		LaunchValidationWebhookURL: lo.ToPtr(server.URL),
+		LaunchValidationTimeout:    lo.ToPtr(5 * time.Minute),
+	}))
+	fakeClock.SetTime(time.Now())
+	awsEnv.Reset()
+	launchvalidation.ValidationsTotal.Reset()
+	mu.Lock()
+	defer mu.Unlock()
+	received = nil
+	statusCode = http.StatusOK
+	response = webhook.ValidationResponse{Allowed: true}
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func receivedEvents() []webhook.Event {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]webhook.Event{}, received...)
+}
+
+func respond(code int, resp webhook.ValidationResponse) {
+	mu.Lock()
+	defer mu.Unlock()
+	statusCode = code
+	response = resp
+}
+
+var _ = Describe("LaunchValidationController", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+	taint := corev1.Taint{Key: v1.LaunchValidationTaintKey, Effect: corev1.TaintEffectNoSchedule}
+
+	BeforeEach(func() {
+		nodeClaim, node = coretest.NodeClaimAndNode(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					karpv1.NodePoolLabelKey:        "default",
+					corev1.LabelInstanceTypeStable: "m5.large",
+					corev1.LabelTopologyZone:       "test-zone-1a",
+				},
+			},
+			Spec: karpv1.NodeClaimSpec{
+				StartupTaints: []corev1.Taint{taint},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		node.Spec.Taints = []corev1.Taint{taint}
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+	})
+
+	It("should remove the taint when the webhook allows the node", func() {
+		ExpectObjectReconciled(ctx, env.Client, validationController, nodeClaim)
+		Expect(receivedEvents()).To(HaveLen(1))
+		event := receivedEvents()[0]
+		Expect(event.Type).To(Equal(webhook.EventTypeLaunchValidation))
+		Expect(event.NodeClaim).To(Equal(nodeClaim.Name))
+		Expect(event.Node).To(Equal(node.Name))
+		Expect(event.ProviderID).To(Equal(nodeClaim.Status.ProviderID))
+		Expect(event.InstanceType).To(Equal("m5.large"))
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeLaunchValidated).IsTrue()).To(BeTrue())
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Spec.Taints).ToNot(ContainElement(HaveField("Key", v1.LaunchValidationTaintKey)))
+		ExpectMetricCounterValue(launchvalidation.ValidationsTotal, 1, map[string]string{"outcome": "allowed"})
+	})
+	It("should only call the webhook once for a node that's been allowed", func() {
+		ExpectObjectReconciled(ctx, env.Client, validationController, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, validationController, nodeClaim)
+		Expect(receivedEvents()).To(HaveLen(1))
+	})
+	It("should delete the nodeclaim when the webhook denies the node", func() {
+		respond(http.StatusOK, webhook.ValidationResponse{Allowed: false, Reason: "security agent not enrolled"})
+		ExpectObjectReconciled(ctx, env.Client, validationController, nodeClaim)
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+		ExpectMetricCounterValue(launchvalidation.ValidationsTotal, 1, map[string]string{"outcome": "denied"})
+	})
+	It("should record the failed validation on the nodeclaim before it's deleted", func() {
+		nodeClaim.Finalizers = []string{karpv1.TerminationFinalizer}
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		respond(http.StatusOK, webhook.ValidationResponse{Allowed: false, Reason: "security agent not enrolled"})
+		ExpectObjectReconciled(ctx, env.Client, validationController, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.DeletionTimestamp.IsZero()).To(BeFalse())
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationTerminationReason, string(v1.TerminationReasonLaunchValidation)))
+		cond := nodeClaim.StatusConditions().Get(v1.ConditionTypeLaunchValidated)
+		Expect(cond.IsFalse()).To(BeTrue())
+		Expect(cond.Reason).To(Equal("Denied"))
+		Expect(cond.Message).To(Equal("security agent not enrolled"))
+		ExpectFinalizersRemoved(ctx, env.Client, nodeClaim)
+	})
+	It("should retry the webhook when it fails to respond, keeping the taint", func() {
+		respond(http.StatusServiceUnavailable, webhook.ValidationResponse{})
+		result := ExpectObjectReconciled(ctx, env.Client, validationController, nodeClaim)
+		Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeLaunchValidated)).To(BeNil())
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Spec.Taints).To(ContainElement(HaveField("Key", v1.LaunchValidationTaintKey)))
+		ExpectMetricCounterValue(launchvalidation.ValidationsTotal, 1, map[string]string{"outcome": "error"})
+
+		respond(http.StatusOK, webhook.ValidationResponse{Allowed: true})
+		fakeClock.Step(10 * time.Second)
+		ExpectObjectReconciled(ctx, env.Client, validationController, nodeClaim)
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Spec.Taints).ToNot(ContainElement(HaveField("Key", v1.LaunchValidationTaintKey)))
+	})
+	It("should delete the nodeclaim when the node isn't allowed within the timeout", func() {
+		respond(http.StatusServiceUnavailable, webhook.ValidationResponse{})
+		fakeClock.Step(6 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, validationController, nodeClaim)
+		Expect(receivedEvents()).To(BeEmpty())
+		ExpectNotFound(ctx, env.Client, nodeClaim)
+		ExpectMetricCounterValue(launchvalidation.ValidationsTotal, 1, map[string]string{"outcome": "timeout"})
+	})
+	It("should not validate a nodeclaim before it's registered", func() {
+		nodeClaim.StatusConditions().SetUnknown(karpv1.ConditionTypeRegistered)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, validationController, nodeClaim)
+		Expect(receivedEvents()).To(BeEmpty())
+	})
+	It("should not validate a nodeclaim without the launch validation startup taint", func() {
+		other, otherNode := coretest.NodeClaimAndNode(karpv1.NodeClaim{
+			Status: karpv1.NodeClaimStatus{ProviderID: fake.ProviderID(fake.InstanceID())},
+		})
+		ExpectApplied(ctx, env.Client, other, otherNode)
+		other.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+		ExpectApplied(ctx, env.Client, other)
+		ExpectObjectReconciled(ctx, env.Client, validationController, other)
+		Expect(receivedEvents()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,141 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadatasync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// Controller keeps the labels and annotations that a NodePool lists in its synced-labels and synced-annotations annotations
+// in sync from the NodePool's template onto its NodeClaims and their Nodes. Upstream, template metadata is only copied when a
+// NodeClaim is created and when its Node registers, so later changes never reach running Nodes. For synced keys, the NodePool
+// template is the source of truth: keys removed from the template are removed from the NodeClaim and Node, and changes made
+// directly to the Node are reverted.
+type Controller struct {
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.metadatasync")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	nodePoolName, ok := nodeClaim.Labels[karpv1.NodePoolLabelKey]
+	if !ok {
+		return reconcile.Result{}, nil
+	}
+	nodePool := &karpv1.NodePool{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePoolName}, nodePool); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("getting nodepool, %w", err))
+	}
+	labelKeys := syncedKeys(nodePool, v1.AnnotationSyncedLabels)
+	annotationKeys := syncedKeys(nodePool, v1.AnnotationSyncedAnnotations)
+	if len(labelKeys) == 0 && len(annotationKeys) == 0 {
+		return reconcile.Result{}, nil
+	}
+	labels := lo.PickByKeys(nodePool.Spec.Template.Labels, labelKeys)
+	annotations := lo.PickByKeys(nodePool.Spec.Template.Annotations, annotationKeys)
+
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Labels = syncKeys(nodeClaim.Labels, labels, labelKeys)
+	nodeClaim.Annotations = syncKeys(nodeClaim.Annotations, annotations, annotationKeys)
+	if !equality.Semantic.DeepEqual(stored, nodeClaim) {
+		if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching nodeclaim, %w", err))
+		}
+		log.FromContext(ctx).V(1).Info("synced nodepool metadata to nodeclaim")
+	}
+	// NodeClaims that haven't registered yet have their metadata copied onto the Node at registration
+	if nodeClaim.Status.NodeName == "" {
+		return reconcile.Result{}, nil
+	}
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("getting node, %w", err))
+	}
+	storedNode := node.DeepCopy()
+	node.Labels = syncKeys(node.Labels, labels, labelKeys)
+	node.Annotations = syncKeys(node.Annotations, annotations, annotationKeys)
+	if !equality.Semantic.DeepEqual(storedNode, node) {
+		if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(storedNode)); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching node, %w", err))
+		}
+		log.FromContext(ctx).WithValues("Node", node.Name).V(1).Info("synced nodepool metadata to node")
+	}
+	return reconcile.Result{}, nil
+}
+
+// syncedKeys parses the comma-separated keys from the NodePool annotation. Keys in domains that Karpenter or the
+// CloudProvider manage are dropped, since syncing them could overwrite or remove metadata that other controllers own.
+func syncedKeys(nodePool *karpv1.NodePool, annotation string) []string {
+	value, ok := nodePool.Annotations[annotation]
+	if !ok {
+		return nil
+	}
+	return lo.Uniq(lo.FilterMap(strings.Split(value, ","), func(key string, _ int) (string, bool) {
+		key = strings.TrimSpace(key)
+		return key, key != "" && !karpv1.IsRestrictedNodeLabel(key)
+	}))
+}
+
+// syncKeys sets each of the keys in current to its value in desired, removing keys that desired doesn't have
+func syncKeys(current, desired map[string]string, keys []string) map[string]string {
+	return lo.Assign(lo.OmitByKeys(current, keys), desired)
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.metadatasync").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		Watches(&karpv1.NodePool{}, nodeclaimutils.NodePoolEventHandler(c.kubeClient, c.cloudProvider)).
+		Watches(&corev1.Node{}, nodeclaimutils.NodeEventHandler(c.kubeClient, c.cloudProvider)).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
@@ -0,0 +1,176 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metadatasync_test
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/metadatasync"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var controller *metadatasync.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MetadataSync")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	controller = metadatasync.NewController(env.Client, cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("MetadataSync", func() {
+	var nodePool *karpv1.NodePool
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+
+	BeforeEach(func() {
+		nodePool = coretest.NodePool(karpv1.NodePool{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1.AnnotationSyncedLabels:      "team, cost-center",
+					v1.AnnotationSyncedAnnotations: "example.com/owner",
+				},
+			},
+			Spec: karpv1.NodePoolSpec{
+				Template: karpv1.NodeClaimTemplate{
+					ObjectMeta: karpv1.ObjectMeta{
+						Labels:      map[string]string{"team": "a", "cost-center": "1234", "unsynced": "true"},
+						Annotations: map[string]string{"example.com/owner": "alice"},
+					},
+				},
+			},
+		})
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: nodePool.Name, "team": "a"},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		node = coretest.Node(coretest.NodeOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: nodePool.Name, "team": "a"},
+			},
+			ProviderID: nodeClaim.Status.ProviderID,
+		})
+		nodeClaim.Status.NodeName = node.Name
+	})
+
+	It("should sync synced keys from the NodePool template to the NodeClaim and Node", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		for _, o := range []metav1.Object{ExpectExists(ctx, env.Client, nodeClaim), ExpectExists(ctx, env.Client, node)} {
+			Expect(o.GetLabels()).To(HaveKeyWithValue("team", "a"))
+			Expect(o.GetLabels()).To(HaveKeyWithValue("cost-center", "1234"))
+			Expect(o.GetLabels()).ToNot(HaveKey("unsynced"))
+			Expect(o.GetAnnotations()).To(HaveKeyWithValue("example.com/owner", "alice"))
+		}
+	})
+	It("should propagate updates to NodePool template labels", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodePool.Spec.Template.Labels["team"] = "b"
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).Labels).To(HaveKeyWithValue("team", "b"))
+		Expect(ExpectExists(ctx, env.Client, node).Labels).To(HaveKeyWithValue("team", "b"))
+	})
+	It("should remove synced keys that are removed from the NodePool template", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		delete(nodePool.Spec.Template.Labels, "cost-center")
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).Labels).ToNot(HaveKey("cost-center"))
+		Expect(ExpectExists(ctx, env.Client, node).Labels).ToNot(HaveKey("cost-center"))
+	})
+	It("should revert changes made directly to the Node", func() {
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		node = ExpectExists(ctx, env.Client, node)
+		node.Labels["team"] = "c"
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Labels).To(HaveKeyWithValue("team", "a"))
+	})
+	It("should not sync any keys without the NodePool annotations", func() {
+		nodePool.Annotations = nil
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Labels).ToNot(HaveKey("cost-center"))
+	})
+	It("should not sync restricted keys", func() {
+		nodePool.Annotations[v1.AnnotationSyncedLabels] = "team," + corev1.LabelInstanceTypeStable + "," + karpv1.NodePoolLabelKey
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).Labels).To(HaveKeyWithValue(karpv1.NodePoolLabelKey, nodePool.Name))
+		Expect(ExpectExists(ctx, env.Client, node).Labels).To(HaveKeyWithValue(karpv1.NodePoolLabelKey, nodePool.Name))
+	})
+	It("should only sync the NodeClaim before its Node registers", func() {
+		nodeClaim.Status.NodeName = ""
+		ExpectApplied(ctx, env.Client, nodePool, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, nodeClaim).Labels).To(HaveKeyWithValue("cost-center", "1234"))
+		Expect(ExpectExists(ctx, env.Client, node).Labels).ToNot(HaveKey("cost-center"))
+	})
+})
@@ -0,0 +1,126 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdboverride
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeutils "sigs.k8s.io/karpenter/pkg/utils/node"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+	"sigs.k8s.io/karpenter/pkg/utils/pdb"
+	podutils "sigs.k8s.io/karpenter/pkg/utils/pod"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// LeadTime is how long before a spot interruption reclaims the instance that pods blocked by PDBs are deleted
+const LeadTime = 30 * time.Second
+
+// Controller deletes the pods on spot interrupted Nodes that are still blocked from eviction by a PDB shortly before
+// EC2 reclaims the instance. Evictions of these pods can't succeed before the instance is terminated, and deleting them
+// lets their controllers observe a clean deletion and replace them, rather than waiting for the Node to disappear. Pods are
+// deleted rather than evicted since deletion isn't subject to PDBs. The interruption controller only records the reclaim
+// time on the NodeClaim when --interruption-pdb-override is enabled.
+type Controller struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.pdboverride")
+
+	if nodeClaim.Status.NodeName == "" {
+		return reconcile.Result{}, nil
+	}
+	reclaimTime, err := time.Parse(time.RFC3339, nodeClaim.Annotations[v1.AnnotationSpotReclaimTime])
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the annotation has been updated.
+		log.FromContext(ctx).Error(err, fmt.Sprintf("failed parsing %s", v1.AnnotationSpotReclaimTime))
+		return reconcile.Result{}, nil
+	}
+	if ttl := reclaimTime.Add(-LeadTime).Sub(c.clk.Now()); ttl > 0 {
+		return reconcile.Result{RequeueAfter: ttl}, nil
+	}
+	pods, err := nodeutils.GetPods(ctx, c.kubeClient, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeClaim.Status.NodeName}})
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing pods, %w", err)
+	}
+	limits, err := pdb.NewLimits(ctx, c.clk, c.kubeClient)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("tracking poddisruptionbudgets, %w", err)
+	}
+	for _, pod := range pods {
+		if podutils.IsTerminating(pod) {
+			continue
+		}
+		key, evictable := limits.CanEvictPods([]*corev1.Pod{pod})
+		if evictable {
+			continue
+		}
+		if err = c.kubeClient.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, fmt.Errorf("deleting pod, %w", err)
+		}
+		log.FromContext(ctx).WithValues("Pod", client.ObjectKeyFromObject(pod), "PodDisruptionBudget", key,
+			"reclaim-time", reclaimTime.Format(time.RFC3339)).Info("deleting pod blocked by pdb ahead of spot interruption")
+		c.recorder.Publish(PDBOverriddenEvent(pod, key, reclaimTime))
+	}
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.pdboverride").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			_, ok := o.GetAnnotations()[v1.AnnotationSpotReclaimTime]
+			return ok
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
@@ -0,0 +1,36 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdboverride
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func PDBOverriddenEvent(pod *corev1.Pod, pdb client.ObjectKey, reclaimTime time.Time) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "PDBOverridden",
+		Message: fmt.Sprintf("Deleting pod blocked by PodDisruptionBudget %s, since its node is reclaimed by a spot interruption at %s",
+			pdb.String(), reclaimTime.Format(time.RFC3339)),
+		DedupeValues: []string{string(pod.UID)},
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdboverride_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/pdboverride"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var recorder *record.FakeRecorder
+var controller *pdboverride.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PDBOverride")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InterruptionPDBOverride: lo.ToPtr(true)}))
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	fakeClock = clock.NewFakeClock(time.Now())
+	recorder = record.NewFakeRecorder(10)
+	controller = pdboverride.NewController(fakeClock, env.Client, events.NewRecorder(recorder), cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	fakeClock.SetTime(time.Now().Truncate(time.Second))
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("PDBOverride", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+	var pod *corev1.Pod
+	var pdb *policyv1.PodDisruptionBudget
+	labels := map[string]string{"app": "test"}
+
+	BeforeEach(func() {
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					v1.AnnotationSpotReclaimTime: fakeClock.Now().Add(2 * time.Minute).UTC().Format(time.RFC3339),
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		node = coretest.Node(coretest.NodeOptions{ProviderID: nodeClaim.Status.ProviderID})
+		nodeClaim.Status.NodeName = node.Name
+		pod = coretest.Pod(coretest.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			NodeName:   node.Name,
+		})
+		pdb = coretest.PodDisruptionBudget(coretest.PDBOptions{
+			Labels:         labels,
+			MaxUnavailable: lo.ToPtr(intstr.FromInt32(0)),
+		})
+	})
+
+	It("should not delete pods before the lead time", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod, pdb)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", 90*time.Second, time.Second))
+		Expect(ExpectExists(ctx, env.Client, pod).DeletionTimestamp.IsZero()).To(BeTrue())
+	})
+	It("should delete pods blocked by a PDB within the lead time", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod, pdb)
+		fakeClock.Step(90 * time.Second)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		EventuallyExpectTerminating(ctx, env.Client, pod)
+		Expect(recorder.Events).To(Receive(ContainSubstring("PDBOverridden")))
+	})
+	It("should not delete pods that aren't blocked by a PDB", func() {
+		pdb.Spec.Selector.MatchLabels = map[string]string{"app": "other"}
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod, pdb)
+		fakeClock.Step(90 * time.Second)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, pod).DeletionTimestamp.IsZero()).To(BeTrue())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+	It("should not delete pods on other nodes", func() {
+		otherNode := coretest.Node()
+		pod.Spec.NodeName = otherNode.Name
+		ExpectApplied(ctx, env.Client, nodeClaim, node, otherNode, pod, pdb)
+		fakeClock.Step(90 * time.Second)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, pod).DeletionTimestamp.IsZero()).To(BeTrue())
+	})
+	It("should ignore reclaim times that can't be parsed", func() {
+		nodeClaim.Annotations[v1.AnnotationSpotReclaimTime] = "soon"
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod, pdb)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(ExpectExists(ctx, env.Client, pod).DeletionTimestamp.IsZero()).To(BeTrue())
+	})
+})
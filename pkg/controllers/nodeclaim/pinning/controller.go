@@ -0,0 +1,178 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pinning
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeutils "sigs.k8s.io/karpenter/pkg/utils/node"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// Controller pins Nodes against voluntary disruption while they're running pods with the karpenter.k8s.aws/pin-node
+// annotation, e.g. long running training jobs that can't be checkpointed. The annotation value is the duration to pin the
+// Node for, measured from when the pod started, or "true" to pin it for the --max-node-pin-duration. Pinned Nodes are
+// excluded from consolidation and drift the same way as for paused NodePools, by adding the do-not-disrupt annotation to
+// the Node, so interruption handling and manual deletion are unaffected.
+type Controller struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+
+	mu     sync.Mutex
+	pinned map[string]string // pinned NodeClaim name -> NodePool name
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+		pinned:        map[string]string{},
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.pinning")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() || nodeClaim.Status.NodeName == "" {
+		c.setPinned(nodeClaim, false)
+		return reconcile.Result{}, nil
+	}
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("getting node, %w", err))
+	}
+	pods, err := nodeutils.GetPods(ctx, c.kubeClient, node)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	pinnedUntil := c.pinnedUntil(ctx, pods)
+	pinned := pinnedUntil.After(c.clk.Now())
+	if err = c.reconcileNode(ctx, node, pinned, pinnedUntil); err != nil {
+		return reconcile.Result{}, err
+	}
+	c.setPinned(nodeClaim, pinned)
+	// Unpin the Node as soon as the pin expires
+	if pinned {
+		return reconcile.Result{RequeueAfter: pinnedUntil.Sub(c.clk.Now())}, nil
+	}
+	return reconcile.Result{}, nil
+}
+
+// pinnedUntil returns the latest time that any of the running pods pins the Node until. Pins are capped at the
+// --max-node-pin-duration, and pods with an annotation that can't be parsed don't pin the Node.
+func (c *Controller) pinnedUntil(ctx context.Context, pods []*corev1.Pod) time.Time {
+	maxDuration := options.FromContext(ctx).MaxNodePinDuration
+	var until time.Time
+	for _, pod := range pods {
+		value, ok := pod.Annotations[v1.AnnotationPinNode]
+		if !ok || pod.Status.Phase != corev1.PodRunning || pod.Status.StartTime == nil {
+			continue
+		}
+		duration := maxDuration
+		if value != "true" {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				log.FromContext(ctx).WithValues("Pod", client.ObjectKeyFromObject(pod), "value", value).
+					Info(fmt.Sprintf("ignoring %s annotation, expected a positive duration or \"true\"", v1.AnnotationPinNode))
+				continue
+			}
+			duration = lo.Min([]time.Duration{d, maxDuration})
+		}
+		if podUntil := pod.Status.StartTime.Add(duration); podUntil.After(until) {
+			until = podUntil
+		}
+	}
+	return until
+}
+
+// reconcileNode adds the do-not-disrupt annotation to the Node while it's pinned, and removes it once the pin expires or
+// once no running pod pins the Node. Nodes that already had the annotation are left untouched, so that we never remove
+// an annotation that we didn't add.
+func (c *Controller) reconcileNode(ctx context.Context, node *corev1.Node, pinned bool, pinnedUntil time.Time) error {
+	stored := node.DeepCopy()
+	_, doNotDisrupt := node.Annotations[karpv1.DoNotDisruptAnnotationKey]
+	_, managed := node.Annotations[v1.AnnotationPinnedUntil]
+	switch {
+	case pinned && (!doNotDisrupt || managed):
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{
+			karpv1.DoNotDisruptAnnotationKey: "true",
+			v1.AnnotationPinnedUntil:         pinnedUntil.UTC().Format(time.RFC3339),
+		})
+	case !pinned && managed:
+		node.Annotations = lo.OmitByKeys(node.Annotations, []string{karpv1.DoNotDisruptAnnotationKey, v1.AnnotationPinnedUntil})
+	default:
+		return nil
+	}
+	if equality.Semantic.DeepEqual(stored.Annotations, node.Annotations) {
+		return nil
+	}
+	if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("patching node, %w", err)
+	}
+	log.FromContext(ctx).WithValues("Node", node.Name, "pinned", pinned).V(1).Info("updated node pin")
+	return nil
+}
+
+// setPinned tracks which NodeClaims are pinned so that the pinned count can be reported per NodePool
+func (c *Controller) setPinned(nodeClaim *karpv1.NodeClaim, pinned bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	nodePool := nodeClaim.Labels[karpv1.NodePoolLabelKey]
+	if pinned {
+		c.pinned[nodeClaim.Name] = nodePool
+	} else {
+		delete(c.pinned, nodeClaim.Name)
+	}
+	NodeClaimsPinned.Set(float64(len(lo.PickByValues(c.pinned, []string{nodePool}))), map[string]string{nodePoolLabel: nodePool})
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.pinning").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		Watches(&corev1.Pod{}, nodeclaimutils.PodEventHandler(c.kubeClient, c.cloudProvider)).
+		Watches(&corev1.Node{}, nodeclaimutils.NodeEventHandler(c.kubeClient, c.cloudProvider)).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
@@ -0,0 +1,185 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pinning_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/pinning"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var controller *pinning.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pinning")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...), coretest.WithFieldIndexers(coretest.NodeClaimNodeClassRefFieldIndexer(ctx)))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{MaxNodePinDuration: lo.ToPtr(24 * time.Hour)}))
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	fakeClock = clock.NewFakeClock(time.Now())
+	controller = pinning.NewController(fakeClock, env.Client, cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	fakeClock.SetTime(time.Now().Truncate(time.Second))
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("Pinning", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: "default"},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		node = coretest.Node(coretest.NodeOptions{ProviderID: nodeClaim.Status.ProviderID})
+		nodeClaim.Status.NodeName = node.Name
+		pod = coretest.Pod(coretest.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{v1.AnnotationPinNode: "12h"},
+			},
+			NodeName: node.Name,
+			Phase:    corev1.PodRunning,
+		})
+		pod.Status.StartTime = lo.ToPtr(metav1.NewTime(fakeClock.Now()))
+	})
+
+	It("should pin Nodes running pods with the pin-node annotation", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", 12*time.Hour, time.Second))
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).To(HaveKeyWithValue(karpv1.DoNotDisruptAnnotationKey, "true"))
+		Expect(node.Annotations).To(HaveKeyWithValue(v1.AnnotationPinnedUntil, fakeClock.Now().Add(12*time.Hour).UTC().Format(time.RFC3339)))
+	})
+	It("should not pin Nodes without pods with the pin-node annotation", func() {
+		pod.Annotations = nil
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should not pin Nodes for pods that aren't running", func() {
+		pod.Status.Phase = corev1.PodPending
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should ignore pin-node annotations that can't be parsed", func() {
+		pod.Annotations = map[string]string{v1.AnnotationPinNode: "forever"}
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should cap pins at the max node pin duration", func() {
+		pod.Annotations = map[string]string{v1.AnnotationPinNode: "72h"}
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", 24*time.Hour, time.Second))
+	})
+	It("should pin Nodes for the max node pin duration when the annotation is true", func() {
+		pod.Annotations = map[string]string{v1.AnnotationPinNode: "true"}
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", 24*time.Hour, time.Second))
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKeyWithValue(karpv1.DoNotDisruptAnnotationKey, "true"))
+	})
+	It("should unpin Nodes once the pin expires", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+
+		fakeClock.Step(12*time.Hour + time.Second)
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeZero())
+		node = ExpectExists(ctx, env.Client, node)
+		Expect(node.Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+		Expect(node.Annotations).ToNot(HaveKey(v1.AnnotationPinnedUntil))
+	})
+	It("should unpin Nodes once the pinning pods are gone", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+
+		ExpectDeleted(ctx, env.Client, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(karpv1.DoNotDisruptAnnotationKey))
+	})
+	It("should not remove a do-not-disrupt annotation that it didn't add", func() {
+		node.Annotations = map[string]string{karpv1.DoNotDisruptAnnotationKey: "true"}
+		pod.Annotations = nil
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKeyWithValue(karpv1.DoNotDisruptAnnotationKey, "true"))
+	})
+	It("should report the number of pinned NodeClaims per NodePool", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectMetricGaugeValue(pinning.NodeClaimsPinned, 1, map[string]string{"nodepool": "default"})
+
+		ExpectDeleted(ctx, env.Client, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectMetricGaugeValue(pinning.NodeClaimsPinned, 0, map[string]string{"nodepool": "default"})
+	})
+})
@@ -0,0 +1,145 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podcapacity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeutils "sigs.k8s.io/karpenter/pkg/utils/node"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// OutOfPodsReason is the status reason of pods that the kubelet rejects because the Node has no pod capacity left
+const OutOfPodsReason = "OutOfpods"
+
+// Controller compares the pod capacity that the kubelet of a registered Node reports with the pod capacity that was
+// advertised for the NodeClaim's instance type when pods were scheduled to it. Custom CNI setups, or a maxPods that
+// the kubelet doesn't honor, can leave a Node with capacity for fewer pods than Karpenter packed onto it, and the pods
+// which don't fit are rejected by the kubelet rather than scheduled elsewhere. Mismatches are surfaced through the
+// PodCapacityMatched condition, an event and a metric. With the RescheduleOutOfPods AWS feature gate, the pods that
+// the kubelet rejected are also deleted so that their owners recreate them and they're scheduled to other Nodes.
+type Controller struct {
+	kubeClient    client.Client
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.podcapacity")
+
+	if !nodeClaim.DeletionTimestamp.IsZero() || !nodeClaim.StatusConditions().Get(karpv1.ConditionTypeRegistered).IsTrue() {
+		return reconcile.Result{}, nil
+	}
+	advertised, ok := nodeClaim.Status.Capacity[corev1.ResourcePods]
+	if !ok || advertised.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	node, err := nodeclaimutils.NodeForNodeClaim(ctx, c.kubeClient, nodeClaim)
+	if err != nil {
+		return reconcile.Result{}, nodeclaimutils.IgnoreDuplicateNodeError(nodeclaimutils.IgnoreNodeNotFoundError(err))
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Node", klog.KRef("", node.Name)))
+	actual, ok := node.Status.Capacity[corev1.ResourcePods]
+	if !ok {
+		// The kubelet hasn't reported its capacity yet
+		return reconcile.Result{}, nil
+	}
+	stored := nodeClaim.DeepCopy()
+	if actual.Value() == advertised.Value() {
+		nodeClaim.StatusConditions().SetTrue(v1.ConditionTypePodCapacityMatched)
+	} else {
+		nodeClaim.StatusConditions().SetFalse(v1.ConditionTypePodCapacityMatched, "PodCapacityMismatch",
+			fmt.Sprintf("Node reports capacity for %d pods, but %d were advertised", actual.Value(), advertised.Value()))
+	}
+	if !equality.Semantic.DeepEqual(stored.Status, nodeClaim.Status) {
+		if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+		if actual.Value() != advertised.Value() {
+			log.FromContext(ctx).WithValues("advertised", advertised.Value(), "actual", actual.Value()).Info("node pod capacity doesn't match the advertised capacity")
+			c.recorder.Publish(PodCapacityMismatchEvent(nodeClaim, advertised.Value(), actual.Value()))
+			PodCapacityMismatchesTotal.Inc(map[string]string{
+				nodePoolLabel:     nodeClaim.Labels[karpv1.NodePoolLabelKey],
+				instanceTypeLabel: nodeClaim.Labels[corev1.LabelInstanceTypeStable],
+			})
+		}
+	}
+	if actual.Value() < advertised.Value() && options.FromContext(ctx).AWSFeatureGates.Enabled(options.RescheduleOutOfPods) {
+		return reconcile.Result{}, c.deleteOutOfPods(ctx, node)
+	}
+	return reconcile.Result{}, nil
+}
+
+// deleteOutOfPods deletes the pods on the Node that the kubelet rejected for lack of pod capacity. Pods without a
+// controller wouldn't be recreated, so they're left for their creator to handle.
+func (c *Controller) deleteOutOfPods(ctx context.Context, node *corev1.Node) error {
+	pods, err := nodeutils.GetPods(ctx, c.kubeClient, node)
+	if err != nil {
+		return fmt.Errorf("listing pods, %w", err)
+	}
+	for _, pod := range lo.Filter(pods, func(p *corev1.Pod, _ int) bool {
+		return p.Status.Phase == corev1.PodFailed && p.Status.Reason == OutOfPodsReason && p.DeletionTimestamp.IsZero() && metav1.GetControllerOf(p) != nil
+	}) {
+		if err = c.kubeClient.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting pod, %w", err)
+		}
+		log.FromContext(ctx).WithValues("Pod", klog.KObj(pod)).Info("deleted pod rejected for lack of pod capacity")
+	}
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.podcapacity").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		Watches(&corev1.Pod{}, nodeclaimutils.PodEventHandler(c.kubeClient, c.cloudProvider)).
+		Watches(&corev1.Node{}, nodeclaimutils.NodeEventHandler(c.kubeClient, c.cloudProvider)).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
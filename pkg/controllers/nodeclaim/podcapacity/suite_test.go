@@ -0,0 +1,178 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podcapacity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/podcapacity"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var recorder *record.FakeRecorder
+var controller *podcapacity.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PodCapacity")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	recorder = record.NewFakeRecorder(10)
+	controller = podcapacity.NewController(env.Client, events.NewRecorder(recorder), cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("PodCapacity", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+	var outOfPods *corev1.Pod
+
+	BeforeEach(func() {
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+				Capacity:   corev1.ResourceList{corev1.ResourcePods: resource.MustParse("29")},
+			},
+		})
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+		node = coretest.Node(coretest.NodeOptions{
+			ProviderID: nodeClaim.Status.ProviderID,
+			Capacity:   corev1.ResourceList{corev1.ResourcePods: resource.MustParse("29")},
+		})
+		outOfPods = coretest.Pod(coretest.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion:         "apps/v1",
+					Kind:               "ReplicaSet",
+					Name:               "test",
+					UID:                "test-uid",
+					Controller:         lo.ToPtr(true),
+					BlockOwnerDeletion: lo.ToPtr(true),
+				}},
+			},
+			NodeName: node.Name,
+			Phase:    corev1.PodFailed,
+		})
+		outOfPods.Status.Reason = podcapacity.OutOfPodsReason
+	})
+
+	It("should mark the pod capacity as matched when the node reports the advertised capacity", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypePodCapacityMatched).IsTrue()).To(BeTrue())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+	It("should mark the pod capacity as mismatched when the node reports less than the advertised capacity", func() {
+		node.Status.Capacity[corev1.ResourcePods] = resource.MustParse("17")
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		condition := nodeClaim.StatusConditions().Get(v1.ConditionTypePodCapacityMatched)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("PodCapacityMismatch"))
+		Expect(condition.Message).To(ContainSubstring("17 pods, but 29"))
+		Expect(recorder.Events).To(Receive(ContainSubstring("PodCapacityMismatch")))
+	})
+	It("should only publish an event once for a mismatch", func() {
+		node.Status.Capacity[corev1.ResourcePods] = resource.MustParse("17")
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(recorder.Events).To(HaveLen(1))
+	})
+	It("should ignore nodeclaims that haven't registered", func() {
+		nodeClaim.StatusConditions().SetFalse(karpv1.ConditionTypeRegistered, "NotRegistered", "NotRegistered")
+		node.Status.Capacity[corev1.ResourcePods] = resource.MustParse("17")
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypePodCapacityMatched)).To(BeNil())
+	})
+	It("should not delete pods rejected by the kubelet when rescheduling is disabled", func() {
+		node.Status.Capacity[corev1.ResourcePods] = resource.MustParse("17")
+		ExpectApplied(ctx, env.Client, nodeClaim, node, outOfPods)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectExists(ctx, env.Client, outOfPods)
+	})
+	It("should delete pods rejected by the kubelet when rescheduling is enabled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AWSFeatureGates: options.FeatureGates{options.RescheduleOutOfPods: true}}))
+		node.Status.Capacity[corev1.ResourcePods] = resource.MustParse("17")
+		running := coretest.Pod(coretest.PodOptions{NodeName: node.Name, Phase: corev1.PodRunning})
+		ExpectApplied(ctx, env.Client, nodeClaim, node, outOfPods, running)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectNotFound(ctx, env.Client, outOfPods)
+		ExpectExists(ctx, env.Client, running)
+	})
+	It("should not delete rejected pods without a controller", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AWSFeatureGates: options.FeatureGates{options.RescheduleOutOfPods: true}}))
+		node.Status.Capacity[corev1.ResourcePods] = resource.MustParse("17")
+		outOfPods.OwnerReferences = nil
+		ExpectApplied(ctx, env.Client, nodeClaim, node, outOfPods)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectExists(ctx, env.Client, outOfPods)
+	})
+})
@@ -0,0 +1,126 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registrationreboot
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// Controller reboots instances which haven't registered with the cluster after the configured registration reboot window.
+// Transient bootstrap failures (e.g. a dependency that wasn't reachable on first boot) are often resolved by a reboot, which
+// is much faster than waiting for the registration TTL to terminate the NodeClaim and launching a new instance.
+type Controller struct {
+	clk              clock.Clock
+	kubeClient       client.Client
+	cloudProvider    cloudprovider.CloudProvider
+	instanceProvider instance.Provider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		clk:              clk,
+		kubeClient:       kubeClient,
+		cloudProvider:    cloudProvider,
+		instanceProvider: instanceProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.registrationreboot")
+
+	if !isRebootable(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	// The registration TTL is measured from the last transition of the Registered condition, so we measure from the same point
+	// NOTE: ttl has to be stored and checked in the same place since c.clk can advance after the check causing a race
+	registered := nodeClaim.StatusConditions().Get(karpv1.ConditionTypeRegistered)
+	rebootAfter := options.FromContext(ctx).RegistrationRebootAfter
+	if ttl := rebootAfter - c.clk.Since(registered.LastTransitionTime.Time); ttl > 0 {
+		return reconcile.Result{RequeueAfter: ttl}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	// Record the attempt before rebooting so that an instance is never rebooted more than once, even if the reboot call
+	// fails or the controller restarts part way through
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.StatusConditions().SetTrueWithReason(v1.ConditionTypeRegistrationRebooted, "RegistrationTimeout",
+		fmt.Sprintf("Rebooted instance after it failed to register within %s", rebootAfter))
+	if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		return reconcile.Result{}, client.IgnoreNotFound(err)
+	}
+	if err = c.instanceProvider.Reboot(ctx, id); err != nil {
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(err)
+	}
+	log.FromContext(ctx).WithValues("after", rebootAfter).Info("rebooted instance that failed to register")
+	return reconcile.Result{}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.registrationreboot").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isRebootable(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter: reasonable.RateLimiter(),
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isRebootable(nc *karpv1.NodeClaim) bool {
+	// NodeClaim is currently terminating
+	if !nc.DeletionTimestamp.IsZero() {
+		return false
+	}
+	// Instance hasn't been launched yet
+	if nc.Status.ProviderID == "" {
+		return false
+	}
+	// Instance has already been rebooted
+	if nc.StatusConditions().Get(v1.ConditionTypeRegistrationRebooted) != nil {
+		return false
+	}
+	registered := nc.StatusConditions().Get(karpv1.ConditionTypeRegistered)
+	return registered != nil && !registered.IsTrue()
+}
@@ -0,0 +1,163 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registrationreboot_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/registrationreboot"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var rebootController *registrationreboot.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RegistrationRebootController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RegistrationRebootAfter: lo.ToPtr(5 * time.Minute)}))
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	rebootController = registrationreboot.NewController(fakeClock, env.Client, cloudProvider, awsEnv.InstanceProvider)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	fakeClock.SetTime(time.Now())
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("RegistrationRebootController", func() {
+	var ec2Instance ec2types.Instance
+	var nodeClaim *karpv1.NodeClaim
+
+	BeforeEach(func() {
+		ec2Instance = ec2types.Instance{
+			State: &ec2types.InstanceState{
+				Name: ec2types.InstanceStateNameRunning,
+			},
+			Placement: &ec2types.Placement{
+				AvailabilityZone: aws.String(fake.DefaultRegion),
+			},
+			InstanceId:   aws.String(fake.InstanceID()),
+			InstanceType: "m5.large",
+		}
+		awsEnv.EC2API.Instances.Store(aws.ToString(ec2Instance.InstanceId), ec2Instance)
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(*ec2Instance.InstanceId),
+			},
+		})
+	})
+	// setRegistered applies the NodeClaim with the Registered condition last transitioning at the passed time
+	setRegistered := func(registered bool, since time.Time) {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		if registered {
+			nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+		} else {
+			nodeClaim.StatusConditions().SetUnknown(karpv1.ConditionTypeRegistered)
+		}
+		cond := nodeClaim.StatusConditions().Get(karpv1.ConditionTypeRegistered)
+		cond.LastTransitionTime.Time = since
+		nodeClaim.StatusConditions().Set(*cond)
+		ExpectApplied(ctx, env.Client, nodeClaim)
+	}
+
+	It("should not reboot an instance before the reboot window has passed", func() {
+		setRegistered(false, fakeClock.Now())
+		fakeClock.Step(4 * time.Minute)
+		result := ExpectObjectReconciled(ctx, env.Client, rebootController, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(0))
+	})
+	It("should reboot an instance that hasn't registered within the reboot window", func() {
+		setRegistered(false, fakeClock.Now())
+		fakeClock.Step(6 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, rebootController, nodeClaim)
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(1))
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.CalledWithInput.Pop().InstanceIds).To(ConsistOf(*ec2Instance.InstanceId))
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeRegistrationRebooted).IsTrue()).To(BeTrue())
+	})
+	It("should only reboot an instance once", func() {
+		setRegistered(false, fakeClock.Now())
+		fakeClock.Step(6 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, rebootController, nodeClaim)
+		fakeClock.Step(time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, rebootController, nodeClaim)
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(1))
+	})
+	It("should not reboot an instance that has registered", func() {
+		setRegistered(true, fakeClock.Now())
+		fakeClock.Step(6 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, rebootController, nodeClaim)
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(0))
+	})
+	It("should not fail when the instance no longer exists", func() {
+		setRegistered(false, fakeClock.Now())
+		awsEnv.EC2API.Instances.Delete(*ec2Instance.InstanceId)
+		fakeClock.Step(6 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, rebootController, nodeClaim)
+	})
+	It("should not reboot an instance with a malformed providerID", func() {
+		nodeClaim.Status.ProviderID = "Bad providerID"
+		setRegistered(false, fakeClock.Now())
+		fakeClock.Step(6 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, rebootController, nodeClaim)
+		Expect(awsEnv.EC2API.RebootInstancesBehavior.Calls()).To(Equal(0))
+	})
+})
@@ -0,0 +1,164 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stoppedinstance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/equality"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+const (
+	// pollInterval is how often the instance of a NodeClaim is checked. Stop events from the interruption queue are handled
+	// by the interruption controller as they arrive, so this only bounds how long a stop goes unnoticed without a queue.
+	pollInterval = 2 * time.Minute
+	// stoppingInterval is how often an instance that was stopped is checked while it's still stopping or starting
+	stoppingInterval = 15 * time.Second
+)
+
+// Controller handles instances that are stopped out of band, e.g. from the EC2 console or by an automation in the account.
+// A stopped instance keeps its NodeClaim, but its Node goes NotReady and its pods can't run, so the NodeClaim is marked
+// with the InstanceStopped condition while the instance is stopping or stopped. What happens next is controlled by the
+// --stopped-instance-policy: the instance is started again, or its NodeClaim is deleted so that it's replaced. The controller
+// isn't registered under the Ignore policy, since instances are left stopped and nothing needs to be checked.
+type Controller struct {
+	kubeClient       client.Client
+	recorder         events.Recorder
+	cloudProvider    cloudprovider.CloudProvider
+	instanceProvider instance.Provider
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider, instanceProvider instance.Provider) *Controller {
+	return &Controller{
+		kubeClient:       kubeClient,
+		recorder:         recorder,
+		cloudProvider:    cloudProvider,
+		instanceProvider: instanceProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.stoppedinstance")
+
+	if !isLaunched(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("provider-id", nodeClaim.Status.ProviderID))
+	id, err := utils.ParseInstanceID(nodeClaim.Status.ProviderID)
+	if err != nil {
+		// We don't throw an error here since we don't want to retry until the ProviderID has been updated.
+		log.FromContext(ctx).Error(err, "failed parsing instance id")
+		return reconcile.Result{}, nil
+	}
+	inst, err := c.instanceProvider.Get(ctx, id)
+	if err != nil {
+		return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(fmt.Errorf("getting instance, %w", err))
+	}
+	stopped := inst.State == ec2types.InstanceStateNameStopping || inst.State == ec2types.InstanceStateNameStopped
+	stored := nodeClaim.DeepCopy()
+	if stopped {
+		nodeClaim.StatusConditions().SetTrueWithReason(v1.ConditionTypeInstanceStopped, lo.Ternary(inst.State == ec2types.InstanceStateNameStopping, "Stopping", "Stopped"),
+			fmt.Sprintf("Instance is %s", inst.State))
+	} else if err = nodeClaim.StatusConditions().Clear(v1.ConditionTypeInstanceStopped); err != nil {
+		return reconcile.Result{}, err
+	}
+	if !equality.Semantic.DeepEqual(stored.Status, nodeClaim.Status) {
+		if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+		if stopped && !stored.StatusConditions().Get(v1.ConditionTypeInstanceStopped).IsTrue() {
+			log.FromContext(ctx).WithValues("state", inst.State).Info("instance was stopped out of band")
+			c.recorder.Publish(InstanceStoppedEvent(nodeClaim, string(inst.State)))
+		}
+	}
+	if !stopped {
+		return reconcile.Result{RequeueAfter: pollInterval}, nil
+	}
+	switch options.StoppedInstancePolicy(options.FromContext(ctx).StoppedInstancePolicy) {
+	case options.StoppedInstancePolicyStart:
+		// EC2 rejects starting an instance until it has finished stopping
+		if inst.State == ec2types.InstanceStateNameStopping {
+			return reconcile.Result{RequeueAfter: stoppingInterval}, nil
+		}
+		if err = c.instanceProvider.Start(ctx, id); err != nil {
+			return reconcile.Result{}, cloudprovider.IgnoreNodeClaimNotFoundError(err)
+		}
+		log.FromContext(ctx).Info("started stopped instance")
+		c.recorder.Publish(InstanceStartedEvent(nodeClaim))
+		return reconcile.Result{RequeueAfter: stoppingInterval}, nil
+	case options.StoppedInstancePolicyReplace:
+		return reconcile.Result{}, c.replace(ctx, nodeClaim)
+	default:
+		return reconcile.Result{RequeueAfter: pollInterval}, nil
+	}
+}
+
+// replace deletes the NodeClaim of a stopped instance. The termination reason is annotated before the NodeClaim is deleted
+// so that it's recorded as a stopped instance rather than as a manual deletion.
+func (c *Controller) replace(ctx context.Context, nodeClaim *karpv1.NodeClaim) error {
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationTerminationReason: string(v1.TerminationReasonInstanceStopped)})
+	if err := c.kubeClient.Patch(ctx, nodeClaim, client.MergeFrom(stored)); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if err := c.kubeClient.Delete(ctx, nodeClaim); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	log.FromContext(ctx).Info("deleting nodeclaim of stopped instance")
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.stoppedinstance").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isLaunched(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isLaunched(nc *karpv1.NodeClaim) bool {
+	return nc.Status.ProviderID != "" && nc.DeletionTimestamp.IsZero()
+}
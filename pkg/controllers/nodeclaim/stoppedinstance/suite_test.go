@@ -0,0 +1,188 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stoppedinstance_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/object"
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/stoppedinstance"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var stoppedInstanceController *stoppedinstance.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "StoppedInstanceController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	recorder := events.NewRecorder(&record.FakeRecorder{})
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, recorder,
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	stoppedInstanceController = stoppedinstance.NewController(env.Client, recorder, cloudProvider, awsEnv.InstanceProvider)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("StoppedInstanceController", func() {
+	var nodeClass *v1.EC2NodeClass
+	var nodeClaim *karpv1.NodeClaim
+	var ec2Instance ec2types.Instance
+	setState := func(state ec2types.InstanceStateName) {
+		ec2Instance.State = &ec2types.InstanceState{Name: state}
+		awsEnv.EC2API.Instances.Store(aws.ToString(ec2Instance.InstanceId), ec2Instance)
+	}
+	stateOf := func() ec2types.InstanceStateName {
+		raw, ok := awsEnv.EC2API.Instances.Load(aws.ToString(ec2Instance.InstanceId))
+		Expect(ok).To(BeTrue())
+		return raw.(ec2types.Instance).State.Name
+	}
+
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass()
+		ec2Instance = ec2types.Instance{
+			State:        &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			Placement:    &ec2types.Placement{AvailabilityZone: aws.String(fake.DefaultRegion)},
+			InstanceId:   aws.String(fake.InstanceID()),
+			InstanceType: "m5.large",
+		}
+		awsEnv.EC2API.Instances.Store(aws.ToString(ec2Instance.InstanceId), ec2Instance)
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			Spec: karpv1.NodeClaimSpec{
+				NodeClassRef: &karpv1.NodeClassReference{
+					Group: object.GVK(nodeClass).Group,
+					Kind:  object.GVK(nodeClass).Kind,
+					Name:  nodeClass.Name,
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(aws.ToString(ec2Instance.InstanceId)),
+			},
+		})
+	})
+
+	It("should not mark the nodeclaim of a running instance", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, stoppedInstanceController, nodeClaim)
+		Expect(result.RequeueAfter).To(Equal(2 * time.Minute))
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeInstanceStopped)).To(BeNil())
+	})
+	It("should mark the nodeclaim of a stopped instance and leave it stopped with the Ignore policy", func() {
+		setState(ec2types.InstanceStateNameStopped)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, stoppedInstanceController, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		cond := nodeClaim.StatusConditions().Get(v1.ConditionTypeInstanceStopped)
+		Expect(cond.IsTrue()).To(BeTrue())
+		Expect(cond.Reason).To(Equal("Stopped"))
+		Expect(stateOf()).To(Equal(ec2types.InstanceStateNameStopped))
+		Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(BeZero())
+	})
+	It("should clear the condition once the instance is running again", func() {
+		setState(ec2types.InstanceStateNameStopping)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, stoppedInstanceController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeInstanceStopped).Reason).To(Equal("Stopping"))
+
+		setState(ec2types.InstanceStateNameRunning)
+		ExpectObjectReconciled(ctx, env.Client, stoppedInstanceController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeInstanceStopped)).To(BeNil())
+	})
+	It("should start a stopped instance with the Start policy", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{StoppedInstancePolicy: lo.ToPtr(string(options.StoppedInstancePolicyStart))}))
+		setState(ec2types.InstanceStateNameStopped)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, stoppedInstanceController, nodeClaim)
+
+		Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(Equal(1))
+		Expect(stateOf()).To(Equal(ec2types.InstanceStateNamePending))
+		ExpectExists(ctx, env.Client, nodeClaim)
+	})
+	It("should wait for a stopping instance to stop before starting it with the Start policy", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{StoppedInstancePolicy: lo.ToPtr(string(options.StoppedInstancePolicyStart))}))
+		setState(ec2types.InstanceStateNameStopping)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		result := ExpectObjectReconciled(ctx, env.Client, stoppedInstanceController, nodeClaim)
+
+		Expect(result.RequeueAfter).To(Equal(15 * time.Second))
+		Expect(awsEnv.EC2API.StartInstancesBehavior.Calls()).To(BeZero())
+	})
+	It("should delete the nodeclaim of a stopped instance with the Replace policy", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{StoppedInstancePolicy: lo.ToPtr(string(options.StoppedInstancePolicyReplace))}))
+		nodeClaim.Finalizers = []string{karpv1.TerminationFinalizer}
+		setState(ec2types.InstanceStateNameStopped)
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, stoppedInstanceController, nodeClaim)
+
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.DeletionTimestamp.IsZero()).To(BeFalse())
+		Expect(nodeClaim.Annotations).To(HaveKeyWithValue(v1.AnnotationTerminationReason, string(v1.TerminationReasonInstanceStopped)))
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeInstanceStopped).IsTrue()).To(BeTrue())
+		ExpectFinalizersRemoved(ctx, env.Client, nodeClaim)
+	})
+	It("should ignore nodeclaims whose instance no longer exists", func() {
+		awsEnv.EC2API.Instances.Delete(aws.ToString(ec2Instance.InstanceId))
+		ExpectApplied(ctx, env.Client, nodeClass, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, stoppedInstanceController, nodeClaim)
+		ExpectExists(ctx, env.Client, nodeClaim)
+	})
+})
@@ -0,0 +1,160 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stuckpod
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodeutils "sigs.k8s.io/karpenter/pkg/utils/node"
+	nodeclaimutils "sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+	podutils "sigs.k8s.io/karpenter/pkg/utils/pod"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+// requeueInterval is how often the pods of a deleting Node are checked again once its drain deadline has passed, since
+// pods becoming stuck doesn't trigger a reconcile of the NodeClaim
+const requeueInterval = time.Minute
+
+// Controller cleans up the pods that block the termination of a deleting Node because they're stuck terminating, most often
+// because of a finalizer left behind by a controller that was uninstalled or is failing. Once the Node's drain deadline has
+// passed, pods that are still terminating past their grace period are handled by --stuck-pod-policy: either the allowlisted
+// finalizers are removed from them, or every finalizer is removed and they're deleted without a grace period.
+type Controller struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.stuckpod")
+
+	policy := options.StuckPodPolicy(options.FromContext(ctx).StuckPodPolicy)
+	if policy == options.StuckPodPolicyIgnore || nodeClaim.DeletionTimestamp.IsZero() || nodeClaim.Status.NodeName == "" {
+		return reconcile.Result{}, nil
+	}
+	deadline := c.drainDeadline(ctx, nodeClaim)
+	if ttl := deadline.Sub(c.clk.Now()); ttl > 0 {
+		return reconcile.Result{RequeueAfter: ttl}, nil
+	}
+	pods, err := nodeutils.GetPods(ctx, c.kubeClient, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeClaim.Status.NodeName}})
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing pods, %w", err)
+	}
+	stuck := lo.Filter(pods, func(pod *corev1.Pod, _ int) bool { return podutils.IsStuckTerminating(pod, c.clk) })
+	for _, pod := range stuck {
+		if err := c.cleanup(ctx, nodeClaim, pod, policy); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	// Pods may still be stuck because of finalizers that aren't allowlisted, or may become stuck later
+	return reconcile.Result{RequeueAfter: requeueInterval}, nil
+}
+
+// drainDeadline returns the time after which pods that are still terminating on the NodeClaim's Node are considered stuck.
+// This is the earlier of --stuck-pod-timeout after the NodeClaim started deleting, and the expiry of its
+// terminationGracePeriod.
+func (c *Controller) drainDeadline(ctx context.Context, nodeClaim *karpv1.NodeClaim) time.Time {
+	deadline := nodeClaim.DeletionTimestamp.Add(options.FromContext(ctx).StuckPodTimeout)
+	if value, ok := nodeClaim.Annotations[karpv1.NodeClaimTerminationTimestampAnnotationKey]; ok {
+		terminationTime, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			log.FromContext(ctx).Error(err, fmt.Sprintf("failed parsing %s", karpv1.NodeClaimTerminationTimestampAnnotationKey))
+		} else if terminationTime.Before(deadline) {
+			deadline = terminationTime
+		}
+	}
+	return deadline
+}
+
+func (c *Controller) cleanup(ctx context.Context, nodeClaim *karpv1.NodeClaim, pod *corev1.Pod, policy options.StuckPodPolicy) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("Pod", client.ObjectKeyFromObject(pod), "policy", policy))
+
+	removed := pod.Finalizers
+	if policy == options.StuckPodPolicyRemoveFinalizers {
+		removed = lo.Intersect(pod.Finalizers, options.FromContext(ctx).StuckPodFinalizerNames())
+	}
+	if len(removed) > 0 {
+		stored := pod.DeepCopy()
+		pod.Finalizers = lo.Without(pod.Finalizers, removed...)
+		// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+		// can cause races due to the fact that it fully replaces the list on a change
+		// Here, we are updating the finalizer list
+		if err := c.kubeClient.Patch(ctx, pod, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		log.FromContext(ctx).WithValues("finalizers", removed).Info("removed finalizers from stuck pod")
+		c.recorder.Publish(FinalizersRemovedEvent(pod, nodeClaim, removed))
+	}
+	if policy == options.StuckPodPolicyForceDelete {
+		if err := c.kubeClient.Delete(ctx, pod, client.GracePeriodSeconds(0)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting pod, %w", err)
+		}
+		log.FromContext(ctx).Info("force deleted stuck pod")
+		c.recorder.Publish(ForceDeletedEvent(pod, nodeClaim))
+	} else if len(removed) == 0 {
+		return nil
+	}
+	StuckPodsCleanedUpTotal.Inc(map[string]string{
+		policyLabel:   string(policy),
+		nodePoolLabel: nodeClaim.Labels[karpv1.NodePoolLabelKey],
+	})
+	return nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.stuckpod").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaimutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return !o.GetDeletionTimestamp().IsZero()
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
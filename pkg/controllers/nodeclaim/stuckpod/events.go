@@ -0,0 +1,46 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stuckpod
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+)
+
+func FinalizersRemovedEvent(pod *corev1.Pod, nodeClaim *karpv1.NodeClaim, finalizers []string) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "StuckPodFinalizersRemoved",
+		Message: fmt.Sprintf("Removed finalizers %s from pod stuck terminating past the drain deadline of NodeClaim %s",
+			strings.Join(finalizers, ", "), nodeClaim.Name),
+		DedupeValues: []string{string(pod.UID)},
+	}
+}
+
+func ForceDeletedEvent(pod *corev1.Pod, nodeClaim *karpv1.NodeClaim) events.Event {
+	return events.Event{
+		InvolvedObject: pod,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "StuckPodForceDeleted",
+		Message:        fmt.Sprintf("Force deleted pod stuck terminating past the drain deadline of NodeClaim %s", nodeClaim.Name),
+		DedupeValues:   []string{string(pod.UID)},
+	}
+}
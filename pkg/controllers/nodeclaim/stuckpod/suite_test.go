@@ -0,0 +1,176 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stuckpod_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/stuckpod"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var recorder *record.FakeRecorder
+var controller *stuckpod.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "StuckPod")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	fakeClock = clock.NewFakeClock(time.Now())
+	recorder = record.NewFakeRecorder(10)
+	controller = stuckpod.NewController(fakeClock, env.Client, events.NewRecorder(recorder), cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+		StuckPodPolicy:     lo.ToPtr(string(options.StuckPodPolicyRemoveFinalizers)),
+		StuckPodFinalizers: lo.ToPtr("example.com/orphaned"),
+	}))
+	fakeClock.SetTime(time.Now().Truncate(time.Second))
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("StuckPod", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Finalizers: []string{karpv1.TerminationFinalizer},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		node = coretest.Node(coretest.NodeOptions{ProviderID: nodeClaim.Status.ProviderID})
+		nodeClaim.Status.NodeName = node.Name
+		pod = coretest.Pod(coretest.PodOptions{
+			ObjectMeta: metav1.ObjectMeta{Finalizers: []string{"example.com/orphaned", "example.com/other"}},
+			NodeName:   node.Name,
+		})
+	})
+	// deleting deletes the NodeClaim and the pod, so that the pod is terminating on the deleting Node
+	deleting := func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node, pod)
+		Expect(env.Client.Delete(ctx, nodeClaim)).To(Succeed())
+		Expect(env.Client.Delete(ctx, pod)).To(Succeed())
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+	}
+
+	It("should not clean up pods when the policy is Ignore", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{StuckPodPolicy: lo.ToPtr(string(options.StuckPodPolicyIgnore))}))
+		deleting()
+		fakeClock.Step(time.Hour)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, pod).Finalizers).To(HaveLen(2))
+		Expect(recorder.Events).To(BeEmpty())
+	})
+	It("should not clean up pods before the drain deadline", func() {
+		deleting()
+		result := ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(result.RequeueAfter).To(BeNumerically("~", 10*time.Minute, 5*time.Second))
+		Expect(ExpectExists(ctx, env.Client, pod).Finalizers).To(HaveLen(2))
+	})
+	It("should remove the allowlisted finalizers from stuck pods after the drain deadline", func() {
+		deleting()
+		fakeClock.Step(11 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, pod).Finalizers).To(ConsistOf("example.com/other"))
+		Expect(recorder.Events).To(Receive(ContainSubstring("StuckPodFinalizersRemoved")))
+		ExpectMetricCounterValue(stuckpod.StuckPodsCleanedUpTotal, 1, map[string]string{
+			"policy":   string(options.StuckPodPolicyRemoveFinalizers),
+			"nodepool": nodeClaim.Labels[karpv1.NodePoolLabelKey],
+		})
+	})
+	It("should clean up stuck pods once the terminationGracePeriod expires before the stuck pod timeout", func() {
+		nodeClaim.Annotations = map[string]string{
+			karpv1.NodeClaimTerminationTimestampAnnotationKey: fakeClock.Now().Add(2 * time.Minute).UTC().Format(time.RFC3339),
+		}
+		deleting()
+		fakeClock.Step(3 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, pod).Finalizers).To(ConsistOf("example.com/other"))
+	})
+	It("should not clean up pods that are still within their grace period", func() {
+		deleting()
+		fakeClock.Step(11 * time.Minute)
+		other := coretest.Pod(coretest.PodOptions{
+			ObjectMeta:                    metav1.ObjectMeta{Finalizers: []string{"example.com/orphaned"}},
+			NodeName:                      node.Name,
+			TerminationGracePeriodSeconds: lo.ToPtr[int64](3600),
+		})
+		ExpectApplied(ctx, env.Client, other)
+		Expect(env.Client.Delete(ctx, other)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, other).Finalizers).To(ConsistOf("example.com/orphaned"))
+	})
+	It("should remove every finalizer and force delete stuck pods when the policy is ForceDelete", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{StuckPodPolicy: lo.ToPtr(string(options.StuckPodPolicyForceDelete))}))
+		deleting()
+		fakeClock.Step(11 * time.Minute)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodeClaim)
+		ExpectNotFound(ctx, env.Client, pod)
+		Expect(recorder.Events).To(Receive(ContainSubstring("StuckPodFinalizersRemoved")))
+		Expect(recorder.Events).To(Receive(ContainSubstring("StuckPodForceDeleted")))
+	})
+})
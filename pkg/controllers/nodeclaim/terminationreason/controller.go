@@ -0,0 +1,183 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminationreason
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/utils/nodeclaim"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+)
+
+// Controller records why a NodeClaim is being terminated, as soon as it begins terminating, on both the NodeClaim (as the
+// TerminationReason condition) and its Node (as the termination-reason annotation). Reasons that Karpenter's interruption
+// handling is responsible for are annotated on the NodeClaim before it's deleted. All other reasons are inferred from the
+// state of the NodeClaim and Node at deletion, since the upstream disruption controllers don't record why they deleted a NodeClaim.
+type Controller struct {
+	clk           clock.Clock
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		clk:           clk,
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodeClaim *karpv1.NodeClaim) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodeclaim.terminationreason")
+
+	if !isUnrecorded(nodeClaim) {
+		return reconcile.Result{}, nil
+	}
+	node, err := c.nodeFor(ctx, nodeClaim)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	reason, err := c.reasonFor(ctx, nodeClaim, node)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	// The Node is annotated first since the condition marks the reason as recorded
+	if node != nil && node.Annotations[v1.AnnotationTerminationReason] != string(reason) {
+		stored := node.DeepCopy()
+		node.Annotations = lo.Assign(node.Annotations, map[string]string{v1.AnnotationTerminationReason: string(reason)})
+		if err = c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); client.IgnoreNotFound(err) != nil {
+			return reconcile.Result{}, fmt.Errorf("patching node termination reason, %w", err)
+		}
+	}
+	stored := nodeClaim.DeepCopy()
+	nodeClaim.StatusConditions().SetTrueWithReason(v1.ConditionTypeTerminationReason, lo.PascalCase(string(reason)), string(reason))
+	if err = c.kubeClient.Status().Patch(ctx, nodeClaim, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+		if errors.IsConflict(err) {
+			return reconcile.Result{Requeue: true}, nil
+		}
+		return reconcile.Result{}, client.IgnoreNotFound(fmt.Errorf("patching nodeclaim termination reason, %w", err))
+	}
+	log.FromContext(ctx).WithValues("reason", reason).V(1).Info("recorded termination reason")
+	return reconcile.Result{}, nil
+}
+
+// reasonFor infers the termination reason from the NodeClaim and Node. This is reconciled as soon as the NodeClaim starts
+// terminating, so their current state reflects the state they were deleted in. Reasons are checked from most to least
+// specific since, for example, a drifted NodeClaim may also be expired.
+func (c *Controller) reasonFor(ctx context.Context, nodeClaim *karpv1.NodeClaim, node *corev1.Node) (v1.TerminationReason, error) {
+	if reason, ok := nodeClaim.Annotations[v1.AnnotationTerminationReason]; ok {
+		return v1.TerminationReason(reason), nil
+	}
+	if node != nil && c.isUnhealthy(node) {
+		return v1.TerminationReasonRepair, nil
+	}
+	if nodeClaim.StatusConditions().Get(karpv1.ConditionTypeDrifted).IsTrue() {
+		// NodeClaims affected by a scheduled change are drifted so they're replaced ahead of the change
+		if _, ok := nodeClaim.Annotations[v1.AnnotationScheduledMaintenanceTime]; ok {
+			return v1.TerminationReasonInterruption, nil
+		}
+		return v1.TerminationReasonDrift, nil
+	}
+	if expireAfter := nodeClaim.Spec.ExpireAfter.Duration; expireAfter != nil &&
+		!nodeClaim.CreationTimestamp.Add(*expireAfter).After(c.clk.Now()) {
+		return v1.TerminationReasonExpiration, nil
+	}
+	if consolidatable := nodeClaim.StatusConditions().Get(karpv1.ConditionTypeConsolidatable); consolidatable.IsTrue() {
+		replaced, err := c.hasReplacement(ctx, nodeClaim, consolidatable.LastTransitionTime.Time)
+		if err != nil {
+			return "", err
+		}
+		return lo.Ternary(replaced, v1.TerminationReasonConsolidationReplace, v1.TerminationReasonConsolidationDelete), nil
+	}
+	return v1.TerminationReasonManualDelete, nil
+}
+
+// isUnhealthy returns true if the Node has been failing one of the CloudProvider's repair policies for longer than the
+// policy's toleration duration, in which case the NodeClaim was deleted by node repair
+func (c *Controller) isUnhealthy(node *corev1.Node) bool {
+	return lo.ContainsBy(c.cloudProvider.RepairPolicies(), func(policy cloudprovider.RepairPolicy) bool {
+		condition, ok := lo.Find(node.Status.Conditions, func(cond corev1.NodeCondition) bool { return cond.Type == policy.ConditionType })
+		return ok && condition.Status == policy.ConditionStatus &&
+			!condition.LastTransitionTime.Add(policy.TolerationDuration).After(c.clk.Now())
+	})
+}
+
+// hasReplacement returns true if a NodeClaim was launched into the same NodePool after the passed NodeClaim became
+// consolidatable. Consolidation launches replacements before deleting the NodeClaims they replace, but it doesn't link them,
+// so this is a best-effort signal that the NodeClaim was replaced rather than deleted.
+func (c *Controller) hasReplacement(ctx context.Context, nodeClaim *karpv1.NodeClaim, consolidatableAt time.Time) (bool, error) {
+	nodeClaimList := &karpv1.NodeClaimList{}
+	if err := c.kubeClient.List(ctx, nodeClaimList, client.MatchingLabels{karpv1.NodePoolLabelKey: nodeClaim.Labels[karpv1.NodePoolLabelKey]}); err != nil {
+		return false, fmt.Errorf("listing nodeclaims, %w", err)
+	}
+	return lo.ContainsBy(nodeClaimList.Items, func(nc karpv1.NodeClaim) bool {
+		return nc.Name != nodeClaim.Name && nc.DeletionTimestamp.IsZero() &&
+			nc.CreationTimestamp.After(consolidatableAt) && !nc.CreationTimestamp.After(nodeClaim.DeletionTimestamp.Time)
+	}), nil
+}
+
+func (c *Controller) nodeFor(ctx context.Context, nodeClaim *karpv1.NodeClaim) (*corev1.Node, error) {
+	if nodeClaim.Status.NodeName == "" {
+		return nil, nil
+	}
+	node := &corev1.Node{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodeClaim.Status.NodeName}, node); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting node, %w", err)
+	}
+	return node, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodeclaim.terminationreason").
+		For(&karpv1.NodeClaim{}, builder.WithPredicates(nodeclaim.IsManagedPredicateFuncs(c.cloudProvider))).
+		WithEventFilter(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return isUnrecorded(o.(*karpv1.NodeClaim))
+		})).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
+
+func isUnrecorded(nc *karpv1.NodeClaim) bool {
+	return !nc.DeletionTimestamp.IsZero() && nc.StatusConditions().Get(v1.ConditionTypeTerminationReason) == nil
+}
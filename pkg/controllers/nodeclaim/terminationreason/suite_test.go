@@ -0,0 +1,189 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terminationreason_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclaim/terminationreason"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var awsEnv *test.Environment
+var env *coretest.Environment
+var fakeClock *clock.FakeClock
+var reasonController *terminationreason.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "TerminationReasonController")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	fakeClock = clock.NewFakeClock(time.Now())
+	reasonController = terminationreason.NewController(fakeClock, env.Client, cloudProvider)
+})
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	fakeClock.SetTime(time.Now())
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("TerminationReasonController", func() {
+	var nodeClaim *karpv1.NodeClaim
+	var node *corev1.Node
+
+	BeforeEach(func() {
+		nodeClaim = coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: "default"},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: fake.ProviderID(fake.InstanceID()),
+			},
+		})
+		node = coretest.Node(coretest.NodeOptions{ProviderID: nodeClaim.Status.ProviderID})
+		nodeClaim.Status.NodeName = node.Name
+	})
+
+	expectReason := func(expected v1.TerminationReason) {
+		GinkgoHelper()
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, reasonController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		condition := nodeClaim.StatusConditions().Get(v1.ConditionTypeTerminationReason)
+		Expect(condition).ToNot(BeNil())
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Message).To(Equal(string(expected)))
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKeyWithValue(v1.AnnotationTerminationReason, string(expected)))
+	}
+
+	It("should not record a reason for NodeClaims that aren't terminating", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, node)
+		ExpectObjectReconciled(ctx, env.Client, reasonController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeTerminationReason)).To(BeNil())
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).ToNot(HaveKey(v1.AnnotationTerminationReason))
+	})
+	It("should use the reason annotated on the NodeClaim", func() {
+		nodeClaim.Annotations = map[string]string{v1.AnnotationTerminationReason: string(v1.TerminationReasonSpotInterruption)}
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeDrifted)
+		expectReason(v1.TerminationReasonSpotInterruption)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeTerminationReason).Reason).To(Equal("SpotInterruption"))
+	})
+	It("should record repair when the node has been unhealthy past the repair toleration", func() {
+		node.Status.Conditions = []corev1.NodeCondition{{
+			Type:               corev1.NodeReady,
+			Status:             corev1.ConditionFalse,
+			LastTransitionTime: metav1.NewTime(fakeClock.Now().Add(-time.Hour)),
+		}}
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeDrifted)
+		expectReason(v1.TerminationReasonRepair)
+	})
+	It("should record drift for drifted NodeClaims", func() {
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeDrifted)
+		expectReason(v1.TerminationReasonDrift)
+	})
+	It("should record interruption for NodeClaims drifted ahead of a scheduled change", func() {
+		nodeClaim.Annotations = lo.Assign(nodeClaim.Annotations, map[string]string{v1.AnnotationScheduledMaintenanceTime: "2024-01-01T00:00:00Z"})
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeDrifted)
+		expectReason(v1.TerminationReasonInterruption)
+	})
+	It("should record expiration for expired NodeClaims", func() {
+		nodeClaim.Spec.ExpireAfter = karpv1.MustParseNillableDuration("1h")
+		fakeClock.Step(time.Hour * 2)
+		expectReason(v1.TerminationReasonExpiration)
+	})
+	It("should record consolidation-delete for consolidatable NodeClaims without a replacement", func() {
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeConsolidatable)
+		expectReason(v1.TerminationReasonConsolidationDelete)
+	})
+	It("should record consolidation-replace for consolidatable NodeClaims with a replacement", func() {
+		nodeClaim.Status.Conditions = append(nodeClaim.Status.Conditions, status.Condition{
+			Type:               karpv1.ConditionTypeConsolidatable,
+			Status:             metav1.ConditionTrue,
+			Reason:             karpv1.ConditionTypeConsolidatable,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Minute)),
+		})
+		replacement := coretest.NodeClaim(karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{karpv1.NodePoolLabelKey: "default"},
+			},
+		})
+		ExpectApplied(ctx, env.Client, replacement)
+		expectReason(v1.TerminationReasonConsolidationReplace)
+	})
+	It("should record manual-delete when no other reason applies", func() {
+		expectReason(v1.TerminationReasonManualDelete)
+	})
+	It("should record the reason on the NodeClaim when its Node doesn't exist", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim)
+		ExpectDeletionTimestampSet(ctx, env.Client, nodeClaim)
+		ExpectObjectReconciled(ctx, env.Client, reasonController, nodeClaim)
+		nodeClaim = ExpectExists(ctx, env.Client, nodeClaim)
+		Expect(nodeClaim.StatusConditions().Get(v1.ConditionTypeTerminationReason).Message).To(Equal(string(v1.TerminationReasonManualDelete)))
+	})
+	It("should not change a recorded reason", func() {
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeDrifted)
+		expectReason(v1.TerminationReasonDrift)
+		node = ExpectExists(ctx, env.Client, node)
+		node.Annotations[v1.AnnotationTerminationReason] = "other"
+		ExpectApplied(ctx, env.Client, node)
+		ExpectObjectReconciled(ctx, env.Client, reasonController, nodeClaim)
+		Expect(ExpectExists(ctx, env.Client, node).Annotations).To(HaveKeyWithValue(v1.AnnotationTerminationReason, "other"))
+	})
+})
@@ -0,0 +1,58 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accessentry"
+)
+
+type AccessEntry struct {
+	accessEntryProvider accessentry.Provider
+}
+
+func (a *AccessEntry) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	// When access isn't managed by Karpenter, we assume that the node role has been mapped by the cluster administrator
+	if !options.FromContext(ctx).ManageNodeAccessEntries {
+		nodeClass.StatusConditions().SetTrue(v1.ConditionTypeNodeAccessReady)
+		return reconcile.Result{}, nil
+	}
+	// The termination controller removes access once the EC2NodeClass is deleted, so we shouldn't grant it again
+	if !nodeClass.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	principalARN, err := a.accessEntryProvider.PrincipalARN(ctx, nodeClass)
+	if err != nil {
+		if awserrors.IsNotFound(err) {
+			nodeClass.StatusConditions().SetFalse(v1.ConditionTypeNodeAccessReady, "NodeRoleNotFound", fmt.Sprintf("Failed to resolve node role, %s", err))
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("resolving node role, %w", err)
+	}
+	if err = a.accessEntryProvider.Ensure(ctx, principalARN, accessentry.AccessEntryType(nodeClass)); err != nil {
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeNodeAccessReady, "NodeAccessFailed", fmt.Sprintf("Failed to grant node role %s access to the cluster, %s", principalARN, err))
+		return reconcile.Result{}, fmt.Errorf("granting node access, %w", err)
+	}
+	nodeClass.StatusConditions().SetTrue(v1.ConditionTypeNodeAccessReady)
+	return reconcile.Result{}, nil
+}
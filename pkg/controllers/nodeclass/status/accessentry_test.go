@@ -0,0 +1,170 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accessentry"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Access Entry Status Controller", func() {
+	var awsAuth *corev1.ConfigMap
+	setAuthenticationMode := func(mode ekstypes.AuthenticationMode) {
+		awsEnv.EKSAPI.DescribeClusterBehavior.Output.Set(&eks.DescribeClusterOutput{
+			Cluster: &ekstypes.Cluster{AccessConfig: &ekstypes.AccessConfigResponse{AuthenticationMode: mode}},
+		})
+	}
+	BeforeEach(func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ManageNodeAccessEntries: lo.ToPtr(true)}))
+		awsAuth = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: accessentry.AWSAuthConfigMapName}}
+	})
+	AfterEach(func() {
+		ctx = options.ToContext(ctx, test.Options())
+		ExpectDeleted(ctx, env.Client, awsAuth)
+	})
+	It("should be ready without granting access when node access entries aren't managed", func() {
+		ctx = options.ToContext(ctx, test.Options())
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeNodeAccessReady)).To(BeTrue())
+		Expect(awsEnv.IAMAPI.GetRoleBehavior.Calls()).To(BeZero())
+		Expect(awsEnv.EKSAPI.CreateAccessEntryBehavior.Calls()).To(BeZero())
+	})
+	Context("API Authentication Mode", func() {
+		BeforeEach(func() {
+			setAuthenticationMode(ekstypes.AuthenticationModeApiAndConfigMap)
+		})
+		It("should create an access entry for the node role", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+			entry, ok := awsEnv.EKSAPI.AccessEntries.Load(fake.RoleARN("test-role"))
+			Expect(ok).To(BeTrue())
+			Expect(lo.FromPtr(entry.(*ekstypes.AccessEntry).Type)).To(Equal(accessentry.AccessEntryTypeLinux))
+			Expect(entry.(*ekstypes.AccessEntry).Tags).To(HaveKeyWithValue(fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName), "owned"))
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeNodeAccessReady)).To(BeTrue())
+		})
+		It("should not create an access entry when one already exists for the node role", func() {
+			awsEnv.EKSAPI.AccessEntries.Store(fake.RoleARN("test-role"), &ekstypes.AccessEntry{
+				PrincipalArn: aws.String(fake.RoleARN("test-role")),
+				Type:         aws.String(accessentry.AccessEntryTypeLinux),
+			})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+			Expect(awsEnv.EKSAPI.CreateAccessEntryBehavior.Calls()).To(BeZero())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeNodeAccessReady)).To(BeTrue())
+		})
+		It("should create an access entry for the role of an unmanaged instance profile without the role's path", func() {
+			awsEnv.IAMAPI.InstanceProfiles = map[string]*iamtypes.InstanceProfile{
+				"test-instance-profile": {
+					InstanceProfileName: aws.String("test-instance-profile"),
+					Roles: []iamtypes.Role{{
+						RoleName: aws.String("test-role"),
+						Arn:      aws.String("arn:aws:iam::123456789012:role/karpenter/test-role"),
+					}},
+				},
+			}
+			nodeClass.Spec.Role = ""
+			nodeClass.Spec.InstanceProfile = aws.String("test-instance-profile")
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+			_, ok := awsEnv.EKSAPI.AccessEntries.Load(fake.RoleARN("test-role"))
+			Expect(ok).To(BeTrue())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeNodeAccessReady)).To(BeTrue())
+		})
+		It("should not be ready when the node role doesn't exist", func() {
+			awsEnv.IAMAPI.GetRoleBehavior.Error.Set(&smithy.GenericAPIError{Code: "NoSuchEntity", Message: "The role with name test-role cannot be found"}, fake.MaxCalls(1))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().Get(v1.ConditionTypeNodeAccessReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("NodeRoleNotFound"))
+			Expect(awsEnv.EKSAPI.CreateAccessEntryBehavior.Calls()).To(BeZero())
+		})
+	})
+	Context("CONFIG_MAP Authentication Mode", func() {
+		BeforeEach(func() {
+			setAuthenticationMode(ekstypes.AuthenticationModeConfigMap)
+		})
+		It("should create the aws-auth ConfigMap with a mapping for the node role", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+			awsAuth = ExpectExists(ctx, env.Client, awsAuth)
+			var roles []map[string]any
+			Expect(yaml.Unmarshal([]byte(awsAuth.Data["mapRoles"]), &roles)).To(Succeed())
+			Expect(roles).To(ConsistOf(map[string]any{
+				"rolearn":  fake.RoleARN("test-role"),
+				"username": "system:node:{{EC2PrivateDNSName}}",
+				"groups":   []any{"system:bootstrappers", "system:nodes"},
+			}))
+			Expect(awsAuth.Annotations).To(HaveKeyWithValue(v1.AnnotationManagedNodeRoles, fake.RoleARN("test-role")))
+			Expect(awsEnv.EKSAPI.CreateAccessEntryBehavior.Calls()).To(BeZero())
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeNodeAccessReady)).To(BeTrue())
+		})
+		It("should preserve existing role mappings in the aws-auth ConfigMap", func() {
+			awsAuth.Data = map[string]string{"mapRoles": "- rolearn: arn:aws:iam::123456789012:role/managed-node-group\n  username: system:node:{{EC2PrivateDNSName}}\n  groups:\n  - system:bootstrappers\n  - system:nodes\n  custom: value\n"}
+			ExpectApplied(ctx, env.Client, awsAuth, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+			awsAuth = ExpectExists(ctx, env.Client, awsAuth)
+			var roles []map[string]any
+			Expect(yaml.Unmarshal([]byte(awsAuth.Data["mapRoles"]), &roles)).To(Succeed())
+			Expect(roles).To(HaveLen(2))
+			Expect(roles[0]).To(HaveKeyWithValue("custom", "value"))
+			Expect(roles[1]).To(HaveKeyWithValue("rolearn", fake.RoleARN("test-role")))
+			Expect(awsAuth.Annotations).To(HaveKeyWithValue(v1.AnnotationManagedNodeRoles, fake.RoleARN("test-role")))
+		})
+	})
+	DescribeTable("should resolve the access entry type from the AMI family",
+		func(alias string, expected string) {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: alias}}
+			Expect(accessentry.AccessEntryType(nodeClass)).To(Equal(expected))
+		},
+		Entry("AL2", "al2@latest", accessentry.AccessEntryTypeLinux),
+		Entry("AL2023", "al2023@latest", accessentry.AccessEntryTypeLinux),
+		Entry("Bottlerocket", "bottlerocket@latest", accessentry.AccessEntryTypeLinux),
+		Entry("Windows2019", "windows2019@latest", accessentry.AccessEntryTypeWindows),
+		Entry("Windows2022", "windows2022@latest", accessentry.AccessEntryTypeWindows),
+	)
+})
@@ -27,6 +27,7 @@ import (
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 )
 
@@ -41,9 +42,23 @@ func (a *AMI) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconc
 	}
 	if len(amis) == 0 {
 		nodeClass.Status.AMIs = nil
-		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeAMIsReady, "AMINotFound", "AMISelector did not match any AMIs")
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeAMIsReady, "AMINotFound", lo.Ternary(options.FromContext(ctx).AWSFeatureGates.Enabled(options.KubeletVersionSkew),
+			"AMISelector did not match any AMIs within the kubelet version skew of the control plane", "AMISelector did not match any AMIs"))
 		return reconcile.Result{}, nil
 	}
+	if maxAge := nodeClass.Spec.AMIMaxAge; maxAge != nil {
+		// AMIs without a parseable creation date are kept, rather than leaving the EC2NodeClass without AMIs
+		fresh := lo.Filter(amis, func(ami amifamily.AMI, _ int) bool {
+			created, err := time.Parse(time.RFC3339, ami.CreationDate)
+			return err != nil || time.Since(created) <= maxAge.Duration
+		})
+		if len(fresh) == 0 {
+			nodeClass.Status.AMIs = nil
+			nodeClass.StatusConditions().SetFalse(v1.ConditionTypeAMIsReady, "AMIsExpired", fmt.Sprintf("AMISelector only matched AMIs older than amiMaxAge (%s)", maxAge.Duration))
+			return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+		}
+		amis = fresh
+	}
 	nodeClass.Status.AMIs = lo.Map(amis, func(ami amifamily.AMI, _ int) v1.AMI {
 		reqs := lo.Map(ami.Requirements.NodeSelectorRequirements(), func(item karpv1.NodeSelectorRequirementWithMinValues, _ int) corev1.NodeSelectorRequirement {
 			return item.NodeSelectorRequirement
@@ -58,6 +73,7 @@ func (a *AMI) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconc
 		return v1.AMI{
 			Name:         ami.Name,
 			ID:           ami.AmiID,
+			CreationDate: ami.CreationDate,
 			Requirements: reqs,
 		}
 	})
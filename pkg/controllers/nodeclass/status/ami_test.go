@@ -23,6 +23,7 @@ import (
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
@@ -35,8 +36,11 @@ import (
 
 var _ = Describe("NodeClass AMI Status Controller", func() {
 	var k8sVersion string
+	var creationDate, newCreationDate string
 	BeforeEach(func() {
 		k8sVersion = awsEnv.VersionProvider.Get(ctx)
+		creationDate = time.Now().Format(time.RFC3339)
+		newCreationDate = time.Now().Add(time.Minute).Format(time.RFC3339)
 		nodeClass = test.EC2NodeClass(v1.EC2NodeClass{
 			Spec: v1.EC2NodeClassSpec{
 				SubnetSelectorTerms: []v1.SubnetSelectorTerm{
@@ -62,7 +66,7 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				{
 					Name:         aws.String("amd64-standard"),
 					ImageId:      aws.String("ami-amd64-standard"),
-					CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+					CreationDate: aws.String(creationDate),
 					Architecture: "x86_64",
 					Tags: []ec2types.Tag{
 						{Key: aws.String("Name"), Value: aws.String("amd64-standard")},
@@ -72,7 +76,7 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				{
 					Name:         aws.String("amd64-standard-new"),
 					ImageId:      aws.String("ami-amd64-standard-new"),
-					CreationDate: aws.String(time.Now().Add(time.Minute).Format(time.RFC3339)),
+					CreationDate: aws.String(newCreationDate),
 					Architecture: "x86_64",
 					Tags: []ec2types.Tag{
 						{Key: aws.String("Name"), Value: aws.String("amd64-standard")},
@@ -82,7 +86,7 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				{
 					Name:         aws.String("amd64-nvidia"),
 					ImageId:      aws.String("ami-amd64-nvidia"),
-					CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+					CreationDate: aws.String(creationDate),
 					Architecture: "x86_64",
 					Tags: []ec2types.Tag{
 						{Key: aws.String("Name"), Value: aws.String("amd64-nvidia")},
@@ -92,7 +96,7 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				{
 					Name:         aws.String("amd64-neuron"),
 					ImageId:      aws.String("ami-amd64-neuron"),
-					CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+					CreationDate: aws.String(creationDate),
 					Architecture: "x86_64",
 					Tags: []ec2types.Tag{
 						{Key: aws.String("Name"), Value: aws.String("amd64-neuron")},
@@ -102,7 +106,7 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				{
 					Name:         aws.String("arm64-standard"),
 					ImageId:      aws.String("ami-arm64-standard"),
-					CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+					CreationDate: aws.String(creationDate),
 					Architecture: "arm64",
 					Tags: []ec2types.Tag{
 						{Key: aws.String("Name"), Value: aws.String("arm64-standard")},
@@ -112,7 +116,7 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				{
 					Name:         aws.String("arm64-nvidia"),
 					ImageId:      aws.String("ami-arm64-nvidia"),
-					CreationDate: aws.String(time.Now().Format(time.RFC3339)),
+					CreationDate: aws.String(creationDate),
 					Architecture: "arm64",
 					Tags: []ec2types.Tag{
 						{Key: aws.String("Name"), Value: aws.String("arm64-nvidia")},
@@ -138,8 +142,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 			Expect(len(nodeClass.Status.AMIs)).To(Equal(4))
 			Expect(nodeClass.Status.AMIs).To(ContainElements([]v1.AMI{
 				{
-					Name: "amd64-standard",
-					ID:   "ami-amd64-standard",
+					Name:         "amd64-standard",
+					ID:           "ami-amd64-standard",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -157,8 +162,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 					},
 				},
 				{
-					Name: "amd64-nvidia",
-					ID:   "ami-amd64-nvidia",
+					Name:         "amd64-nvidia",
+					ID:           "ami-amd64-nvidia",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -172,8 +178,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 					},
 				},
 				{
-					Name: "amd64-neuron",
-					ID:   "ami-amd64-neuron",
+					Name:         "amd64-neuron",
+					ID:           "ami-amd64-neuron",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -187,8 +194,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 					},
 				},
 				{
-					Name: "arm64-standard",
-					ID:   "ami-arm64-standard",
+					Name:         "arm64-standard",
+					ID:           "ami-arm64-standard",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -222,8 +230,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 			Expect(len(nodeClass.Status.AMIs)).To(Equal(4))
 			Expect(nodeClass.Status.AMIs).To(ContainElements([]v1.AMI{
 				{
-					Name: "amd64-standard",
-					ID:   "ami-amd64-standard",
+					Name:         "amd64-standard",
+					ID:           "ami-amd64-standard",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -241,8 +250,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 					},
 				},
 				{
-					Name: "amd64-nvidia",
-					ID:   "ami-amd64-nvidia",
+					Name:         "amd64-nvidia",
+					ID:           "ami-amd64-nvidia",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -257,8 +267,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				},
 				// Note: AL2 uses the same AMI for nvidia and neuron, we use the nvidia AMI here
 				{
-					Name: "amd64-nvidia",
-					ID:   "ami-amd64-nvidia",
+					Name:         "amd64-nvidia",
+					ID:           "ami-amd64-nvidia",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -272,8 +283,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 					},
 				},
 				{
-					Name: "arm64-standard",
-					ID:   "ami-arm64-standard",
+					Name:         "arm64-standard",
+					ID:           "ami-arm64-standard",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -308,8 +320,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 			Expect(len(nodeClass.Status.AMIs)).To(Equal(4))
 			Expect(nodeClass.Status.AMIs).To(ContainElements([]v1.AMI{
 				{
-					Name: "amd64-standard",
-					ID:   "ami-amd64-standard",
+					Name:         "amd64-standard",
+					ID:           "ami-amd64-standard",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -327,8 +340,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 					},
 				},
 				{
-					Name: "arm64-standard",
-					ID:   "ami-arm64-standard",
+					Name:         "arm64-standard",
+					ID:           "ami-arm64-standard",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -346,8 +360,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 					},
 				},
 				{
-					Name: "amd64-nvidia",
-					ID:   "ami-amd64-nvidia",
+					Name:         "amd64-nvidia",
+					ID:           "ami-amd64-nvidia",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -361,8 +376,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 					},
 				},
 				{
-					Name: "arm64-nvidia",
-					ID:   "ami-arm64-nvidia",
+					Name:         "arm64-nvidia",
+					ID:           "ami-arm64-nvidia",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelArchStable,
@@ -390,8 +406,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 			Expect(len(nodeClass.Status.AMIs)).To(Equal(1))
 			Expect(nodeClass.Status.AMIs).To(ContainElements([]v1.AMI{
 				{
-					Name: "amd64-standard",
-					ID:   "ami-amd64-standard",
+					Name:         "amd64-standard",
+					ID:           "ami-amd64-standard",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelOSStable,
@@ -425,8 +442,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 			Expect(len(nodeClass.Status.AMIs)).To(Equal(1))
 			Expect(nodeClass.Status.AMIs).To(ContainElements([]v1.AMI{
 				{
-					Name: "amd64-standard",
-					ID:   "ami-amd64-standard",
+					Name:         "amd64-standard",
+					ID:           "ami-amd64-standard",
+					CreationDate: creationDate,
 					Requirements: []corev1.NodeSelectorRequirement{
 						{
 							Key:      corev1.LabelOSStable,
@@ -465,8 +483,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 		Expect(len(nodeClass.Status.AMIs)).To(Equal(2))
 		Expect(nodeClass.Status.AMIs).To(ContainElements([]v1.AMI{
 			{
-				Name: "arm64-standard",
-				ID:   "ami-arm64-standard",
+				Name:         "arm64-standard",
+				ID:           "ami-arm64-standard",
+				CreationDate: creationDate,
 				Requirements: []corev1.NodeSelectorRequirement{
 					{
 						Key:      corev1.LabelArchStable,
@@ -484,8 +503,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 				},
 			},
 			{
-				Name: "amd64-standard",
-				ID:   "ami-amd64-standard",
+				Name:         "amd64-standard",
+				ID:           "ami-amd64-standard",
+				CreationDate: creationDate,
 				Requirements: []corev1.NodeSelectorRequirement{
 					{
 						Key:      corev1.LabelArchStable,
@@ -515,8 +535,9 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 		Expect(nodeClass.Status.AMIs).To(Equal(
 			[]v1.AMI{
 				{
-					Name: "amd64-standard-new",
-					ID:   "ami-amd64-standard-new",
+					Name:         "amd64-standard-new",
+					ID:           "ami-amd64-standard-new",
+					CreationDate: newCreationDate,
 					Requirements: []corev1.NodeSelectorRequirement{{
 						Key:      corev1.LabelArchStable,
 						Operator: corev1.NodeSelectorOpIn,
@@ -527,6 +548,52 @@ var _ = Describe("NodeClass AMI Status Controller", func() {
 		))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsReady)).To(BeTrue())
 	})
+	It("should exclude AMIs older than amiMaxAge", func() {
+		awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+			Images: []ec2types.Image{
+				{
+					Name:         aws.String("amd64-standard"),
+					ImageId:      aws.String("ami-amd64-standard"),
+					CreationDate: aws.String(time.Now().Add(-48 * time.Hour).Format(time.RFC3339)),
+					Architecture: "x86_64",
+					Tags:         []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("amd64-standard")}},
+				},
+				{
+					Name:         aws.String("arm64-standard"),
+					ImageId:      aws.String("ami-arm64-standard"),
+					CreationDate: aws.String(creationDate),
+					Architecture: "arm64",
+					Tags:         []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("arm64-standard")}},
+				},
+			},
+		})
+		nodeClass.Spec.AMIMaxAge = &metav1.Duration{Duration: 24 * time.Hour}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(lo.Map(nodeClass.Status.AMIs, func(ami v1.AMI, _ int) string { return ami.ID })).To(ConsistOf("ami-arm64-standard"))
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeAMIsReady)).To(BeTrue())
+	})
+	It("should not be ready when every AMI is older than amiMaxAge", func() {
+		awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{
+			Images: []ec2types.Image{
+				{
+					Name:         aws.String("amd64-standard"),
+					ImageId:      aws.String("ami-amd64-standard"),
+					CreationDate: aws.String(time.Now().Add(-48 * time.Hour).Format(time.RFC3339)),
+					Architecture: "x86_64",
+					Tags:         []ec2types.Tag{{Key: aws.String("Name"), Value: aws.String("amd64-standard")}},
+				},
+			},
+		})
+		nodeClass.Spec.AMIMaxAge = &metav1.Duration{Duration: 24 * time.Hour}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.AMIs).To(BeEmpty())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeAMIsReady).IsFalse()).To(BeTrue())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeAMIsReady).Reason).To(Equal("AMIsExpired"))
+	})
 	It("should get error when resolving AMIs and have status condition set to false", func() {
 		awsEnv.EC2API.NextError.Set(fmt.Errorf("unable to resolve AMI"))
 		ExpectApplied(ctx, env.Client, nodeClass)
@@ -0,0 +1,73 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+)
+
+type CapacityBlock struct {
+	capacityReservationProvider capacityreservation.Provider
+}
+
+func (c *CapacityBlock) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	if nodeClass.Spec.CapacityBlock == nil {
+		nodeClass.Status.CapacityBlock = nil
+		nodeClass.StatusConditions().SetTrue(v1.ConditionTypeCapacityBlockReady)
+		return reconcile.Result{}, nil
+	}
+	id := nodeClass.Spec.CapacityBlock.ID
+	reservation, err := c.capacityReservationProvider.Get(ctx, id)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting capacity block, %w", err)
+	}
+	if reservation == nil {
+		nodeClass.Status.CapacityBlock = nil
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeCapacityBlockReady, "CapacityBlockNotFound", fmt.Sprintf("Capacity reservation %q not found", id))
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	if reservation.ReservationType != ec2types.CapacityReservationTypeCapacityBlock {
+		nodeClass.Status.CapacityBlock = nil
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeCapacityBlockReady, "NotCapacityBlock", fmt.Sprintf("Capacity reservation %q isn't a Capacity Block", id))
+		return reconcile.Result{}, nil
+	}
+	nodeClass.Status.CapacityBlock = &v1.CapacityBlockStatus{
+		ID:           id,
+		InstanceType: aws.ToString(reservation.InstanceType),
+		Zone:         aws.ToString(reservation.AvailabilityZone),
+		State:        string(reservation.State),
+		StartTime:    metav1.NewTime(aws.ToTime(reservation.StartDate)),
+		EndTime:      metav1.NewTime(aws.ToTime(reservation.EndDate)),
+	}
+	switch reservation.State {
+	case ec2types.CapacityReservationStateExpired, ec2types.CapacityReservationStateCancelled, ec2types.CapacityReservationStateFailed,
+		ec2types.CapacityReservationStatePaymentFailed:
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeCapacityBlockReady, "CapacityBlockUnavailable",
+			fmt.Sprintf("Capacity Block %q is %s", id, reservation.State))
+		return reconcile.Result{}, nil
+	}
+	nodeClass.StatusConditions().SetTrue(v1.ConditionTypeCapacityBlockReady)
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
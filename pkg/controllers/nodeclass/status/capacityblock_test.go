@@ -0,0 +1,119 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/status"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Capacity Block Status Controller", func() {
+	const id = "cr-0123456789abcdef0"
+	var start, end time.Time
+	capacityBlock := func(state ec2types.CapacityReservationState) ec2types.CapacityReservation {
+		return ec2types.CapacityReservation{
+			CapacityReservationId: aws.String(id),
+			ReservationType:       ec2types.CapacityReservationTypeCapacityBlock,
+			InstanceType:          aws.String("p5.48xlarge"),
+			AvailabilityZone:      aws.String("test-zone-1a"),
+			State:                 state,
+			StartDate:             aws.Time(start),
+			EndDate:               aws.Time(end),
+		}
+	}
+	BeforeEach(func() {
+		start = time.Now().Add(-time.Hour).Truncate(time.Second)
+		end = time.Now().Add(24 * time.Hour).Truncate(time.Second)
+		nodeClass.Spec.CapacityBlock = &v1.CapacityBlock{ID: id}
+	})
+	It("should be ready without a Capacity Block", func() {
+		nodeClass.Spec.CapacityBlock = nil
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityBlock).To(BeNil())
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeCapacityBlockReady)).To(BeTrue())
+	})
+	It("should resolve the Capacity Block", func() {
+		awsEnv.EC2API.CapacityReservations.Store(id, capacityBlock(ec2types.CapacityReservationStateActive))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityBlock).ToNot(BeNil())
+		Expect(nodeClass.Status.CapacityBlock.ID).To(Equal(id))
+		Expect(nodeClass.Status.CapacityBlock.InstanceType).To(Equal("p5.48xlarge"))
+		Expect(nodeClass.Status.CapacityBlock.Zone).To(Equal("test-zone-1a"))
+		Expect(nodeClass.Status.CapacityBlock.State).To(Equal("active"))
+		Expect(nodeClass.Status.CapacityBlock.StartTime.Time).To(BeTemporally("==", start))
+		Expect(nodeClass.Status.CapacityBlock.EndTime.Time).To(BeTemporally("==", end))
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeCapacityBlockReady)).To(BeTrue())
+		Expect(nodeClass.StatusConditions().IsTrue(status.ConditionReady)).To(BeTrue())
+	})
+	It("should be ready for a Capacity Block that hasn't started yet", func() {
+		start = time.Now().Add(time.Hour).Truncate(time.Second)
+		awsEnv.EC2API.CapacityReservations.Store(id, capacityBlock(ec2types.CapacityReservationStateScheduled))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityBlock.State).To(Equal("scheduled"))
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeCapacityBlockReady)).To(BeTrue())
+	})
+	It("should not be ready when the Capacity Block doesn't exist", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityBlock).To(BeNil())
+		condition := nodeClass.StatusConditions().Get(v1.ConditionTypeCapacityBlockReady)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("CapacityBlockNotFound"))
+		Expect(nodeClass.StatusConditions().IsTrue(status.ConditionReady)).To(BeFalse())
+	})
+	It("should not be ready when the capacity reservation isn't a Capacity Block", func() {
+		reservation := capacityBlock(ec2types.CapacityReservationStateActive)
+		reservation.ReservationType = ec2types.CapacityReservationTypeDefault
+		awsEnv.EC2API.CapacityReservations.Store(id, reservation)
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.CapacityBlock).To(BeNil())
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeCapacityBlockReady).Reason).To(Equal("NotCapacityBlock"))
+	})
+	DescribeTable("should not be ready when the Capacity Block can no longer be used",
+		func(state ec2types.CapacityReservationState) {
+			awsEnv.EC2API.CapacityReservations.Store(id, capacityBlock(state))
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.Status.CapacityBlock.State).To(Equal(string(state)))
+			condition := nodeClass.StatusConditions().Get(v1.ConditionTypeCapacityBlockReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("CapacityBlockUnavailable"))
+			Expect(condition.Message).To(Equal(fmt.Sprintf("Capacity Block %q is %s", id, state)))
+		},
+		Entry("expired", ec2types.CapacityReservationStateExpired),
+		Entry("cancelled", ec2types.CapacityReservationStateCancelled),
+		Entry("payment-failed", ec2types.CapacityReservationStatePaymentFailed),
+	)
+})
@@ -17,9 +17,11 @@ package status
 import (
 	"context"
 
+	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -33,11 +35,15 @@ import (
 	"github.com/awslabs/operatorpkg/reasonable"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accessentry"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
 )
 
 type nodeClassStatusReconciler interface {
@@ -51,11 +57,18 @@ type Controller struct {
 	instanceprofile *InstanceProfile
 	subnet          *Subnet
 	securitygroup   *SecurityGroup
+	encryption      *Encryption
+	capacityblock   *CapacityBlock
+	vpcendpoint     *VPCEndpoint
+	accessentry     *AccessEntry
+	keypair         *KeyPair
 	readiness       *Readiness //TODO : Remove this when we have sub status conditions
 }
 
 func NewController(kubeClient client.Client, subnetProvider subnet.Provider, securityGroupProvider securitygroup.Provider,
-	amiProvider amifamily.Provider, instanceProfileProvider instanceprofile.Provider, launchTemplateProvider launchtemplate.Provider) *Controller {
+	amiProvider amifamily.Provider, instanceProfileProvider instanceprofile.Provider, launchTemplateProvider launchtemplate.Provider,
+	kmsProvider kms.Provider, capacityReservationProvider capacityreservation.Provider, vpcEndpointProvider vpcendpoint.Provider,
+	accessEntryProvider accessentry.Provider) *Controller {
 	return &Controller{
 		kubeClient: kubeClient,
 
@@ -63,6 +76,11 @@ func NewController(kubeClient client.Client, subnetProvider subnet.Provider, sec
 		subnet:          &Subnet{subnetProvider: subnetProvider},
 		securitygroup:   &SecurityGroup{securityGroupProvider: securityGroupProvider},
 		instanceprofile: &InstanceProfile{instanceProfileProvider: instanceProfileProvider},
+		encryption:      &Encryption{kmsProvider: kmsProvider},
+		capacityblock:   &CapacityBlock{capacityReservationProvider: capacityReservationProvider},
+		vpcendpoint:     &VPCEndpoint{subnetProvider: subnetProvider, vpcEndpointProvider: vpcEndpointProvider},
+		accessentry:     &AccessEntry{accessEntryProvider: accessEntryProvider},
+		keypair:         &KeyPair{},
 		readiness:       &Readiness{launchTemplateProvider: launchTemplateProvider},
 	}
 }
@@ -87,18 +105,31 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass)
 	stored := nodeClass.DeepCopy()
 
 	var results []reconcile.Result
-	var errs error
+	var errs, resolverErrs error
 	for _, reconciler := range []nodeClassStatusReconciler{
 		c.ami,
 		c.subnet,
 		c.securitygroup,
 		c.instanceprofile,
+		c.accessentry,
+		c.encryption,
+		c.capacityblock,
+		c.vpcendpoint,
+		c.keypair,
 		c.readiness,
 	} {
 		res, err := reconciler.Reconcile(ctx, nodeClass)
 		errs = multierr.Append(errs, err)
+		if c.isResolver(reconciler) {
+			resolverErrs = multierr.Append(resolverErrs, err)
+		}
 		results = append(results, res)
 	}
+	// Launches wait for the observed generation, so it's recorded once the values that launch templates are rendered from
+	// have been resolved, even if an unrelated reconciler is failing
+	if resolverErrs == nil {
+		stampResolution(stored, nodeClass)
+	}
 
 	if !equality.Semantic.DeepEqual(stored, nodeClass) {
 		// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
@@ -117,6 +148,27 @@ func (c *Controller) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass)
 	return result.Min(results...), nil
 }
 
+// isResolver returns true for the reconcilers that resolve the subnets, security groups, AMIs and instance profile that
+// launch templates are rendered from
+func (c *Controller) isResolver(reconciler nodeClassStatusReconciler) bool {
+	return lo.Contains([]nodeClassStatusReconciler{c.ami, c.subnet, c.securitygroup, c.instanceprofile}, reconciler)
+}
+
+// stampResolution records the generation that the resolved status values were computed from. The resolution time is only
+// bumped when the resolved values or the generation change so that periodic requeues don't patch an unchanged status.
+func stampResolution(stored, nodeClass *v1.EC2NodeClass) {
+	if nodeClass.Status.ObservedGeneration == nodeClass.Generation && nodeClass.Status.LastResolvedTime != nil &&
+		equality.Semantic.DeepEqual(resolved(stored), resolved(nodeClass)) {
+		return
+	}
+	nodeClass.Status.ObservedGeneration = nodeClass.Generation
+	nodeClass.Status.LastResolvedTime = lo.ToPtr(metav1.Now())
+}
+
+func resolved(nodeClass *v1.EC2NodeClass) []any {
+	return []any{nodeClass.Status.Subnets, nodeClass.Status.SecurityGroups, nodeClass.Status.AMIs, nodeClass.Status.InstanceProfile, nodeClass.Status.CapacityBlock}
+}
+
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("nodeclass.status").
@@ -0,0 +1,78 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
+)
+
+type Encryption struct {
+	kmsProvider kms.Provider
+}
+
+func (e *Encryption) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	if options.FromContext(ctx).RequireEncryptedRootVolumes && !rootVolumeEncrypted(nodeClass) {
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeVolumeEncryptionReady, "RootVolumeNotEncrypted", "Encryption is required for root volumes, but the root volume isn't configured to be encrypted")
+		return reconcile.Result{}, nil
+	}
+	keyIDs := lo.Uniq(lo.FilterMap(nodeClass.Spec.BlockDeviceMappings, func(bdm *v1.BlockDeviceMapping, _ int) (string, bool) {
+		return lo.FromPtr(lo.FromPtr(bdm.EBS).KMSKeyID), bdm.EBS != nil && bdm.EBS.KMSKeyID != nil
+	}))
+	for _, keyID := range keyIDs {
+		if e.kmsProvider.PermitsServiceLinkedRole(ctx, keyID) {
+			continue
+		}
+		message := fmt.Sprintf("Key policy for KMS key %q doesn't permit %s to use the key", keyID, kms.ServiceLinkedRoleName)
+		// The key policy check doesn't see grants or every policy construct, so a key that it rejects may still work. It only
+		// blocks launches when encryption is enforced, and is otherwise surfaced as a warning on the condition.
+		if !options.FromContext(ctx).RequireEncryptedRootVolumes {
+			nodeClass.StatusConditions().SetTrueWithReason(v1.ConditionTypeVolumeEncryptionReady, "KMSKeyPolicyUnverified", message)
+			return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+		}
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeVolumeEncryptionReady, "KMSKeyPolicyInvalid", message)
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	nodeClass.StatusConditions().SetTrue(v1.ConditionTypeVolumeEncryptionReady)
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+// rootVolumeEncrypted returns true if the volume that backs the kubelet root dir is encrypted. The default block device
+// mappings of every AMI family are encrypted, but when mappings are specified the root volume has to be found among them,
+// either through rootVolume or by the device name that the AMI family uses for it.
+func rootVolumeEncrypted(nodeClass *v1.EC2NodeClass) bool {
+	if len(nodeClass.Spec.BlockDeviceMappings) == 0 {
+		return true
+	}
+	root, ok := lo.Find(nodeClass.Spec.BlockDeviceMappings, func(bdm *v1.BlockDeviceMapping) bool { return bdm.RootVolume })
+	if !ok {
+		device := amifamily.GetAMIFamily(nodeClass.AMIFamily(), nil).EphemeralBlockDevice()
+		if root, ok = lo.Find(nodeClass.Spec.BlockDeviceMappings, func(bdm *v1.BlockDeviceMapping) bool {
+			return device != nil && lo.FromPtr(bdm.DeviceName) == *device
+		}); !ok {
+			return false
+		}
+	}
+	return root.EBS != nil && lo.FromPtr(root.EBS.Encrypted)
+}
@@ -0,0 +1,189 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Volume Encryption Status Controller", func() {
+	const keyID = "0123abcd-12ab-34cd-56ef-1234567890ab"
+	BeforeEach(func() {
+		ctx = options.ToContext(ctx, test.Options())
+		nodeClass.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{
+			{
+				DeviceName: aws.String("/dev/xvda"),
+				EBS: &v1.BlockDevice{
+					VolumeSize: lo.ToPtr(resource.MustParse("20Gi")),
+					Encrypted:  aws.Bool(true),
+					KMSKeyID:   aws.String(keyID),
+				},
+				RootVolume: true,
+			},
+		}
+	})
+	It("should be ready when the key policy permits the service-linked role", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeVolumeEncryptionReady)).To(BeTrue())
+		Expect(nodeClass.StatusConditions().IsTrue(status.ConditionReady)).To(BeTrue())
+	})
+	It("should be ready when the key policy permits any principal", func() {
+		awsEnv.KMSAPI.KeyPolicies.Store(keyID, `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "kms:*", "Resource": "*"}]}`)
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeVolumeEncryptionReady)).To(BeTrue())
+	})
+	It("should stay ready with a warning when the key policy doesn't permit the service-linked role", func() {
+		awsEnv.KMSAPI.KeyPolicies.Store(keyID, `{
+			"Statement": [
+				{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::123456789:root"}, "Action": "kms:*", "Resource": "*"}
+			]
+		}`)
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		condition := nodeClass.StatusConditions().Get(v1.ConditionTypeVolumeEncryptionReady)
+		Expect(condition.IsTrue()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("KMSKeyPolicyUnverified"))
+		Expect(nodeClass.StatusConditions().IsTrue(status.ConditionReady)).To(BeTrue())
+	})
+	DescribeTable("should skip validation when the key can't be read",
+		func(behavior func()) {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RequireEncryptedRootVolumes: lo.ToPtr(true)}))
+			behavior()
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeVolumeEncryptionReady)).To(BeTrue())
+		},
+		Entry("when the key can't be described", func() {
+			awsEnv.KMSAPI.DescribeKeyBehavior.Error.Set(fmt.Errorf("throttled"))
+		}),
+		Entry("when the key policy can't be read", func() {
+			awsEnv.KMSAPI.GetKeyPolicyBehavior.Error.Set(fmt.Errorf("cross-account access isn't supported"))
+		}),
+		Entry("when the key policy isn't valid JSON", func() {
+			awsEnv.KMSAPI.KeyPolicies.Store(keyID, `{"Statement":`)
+		}),
+		Entry("when the key policy uses NotPrincipal", func() {
+			awsEnv.KMSAPI.KeyPolicies.Store(keyID, `{"Statement": [{"Effect": "Deny", "NotPrincipal": {"AWS": "arn:aws:iam::123456789:root"}, "Action": "kms:ScheduleKeyDeletion", "Resource": "*"}]}`)
+		}),
+	)
+	It("should not read the key policy of AWS managed keys", func() {
+		awsEnv.KMSAPI.DescribeKeyBehavior.Output.Set(&kms.DescribeKeyOutput{
+			KeyMetadata: &kmstypes.KeyMetadata{KeyId: aws.String(keyID), KeyManager: kmstypes.KeyManagerTypeAws},
+		})
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeVolumeEncryptionReady)).To(BeTrue())
+		Expect(awsEnv.KMSAPI.GetKeyPolicyBehavior.Calls()).To(Equal(0))
+	})
+	Context("Required Root Volume Encryption", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{RequireEncryptedRootVolumes: lo.ToPtr(true)}))
+		})
+		It("should not be ready when the key policy doesn't permit the service-linked role", func() {
+			awsEnv.KMSAPI.KeyPolicies.Store(keyID, `{
+				"Statement": [
+					{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::123456789:root"}, "Action": "kms:*", "Resource": "*"}
+				]
+			}`)
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().Get(v1.ConditionTypeVolumeEncryptionReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("KMSKeyPolicyInvalid"))
+			Expect(nodeClass.StatusConditions().IsTrue(status.ConditionReady)).To(BeFalse())
+		})
+		It("should not be ready when the key policy only permits some of the required actions", func() {
+			awsEnv.KMSAPI.KeyPolicies.Store(keyID, `{
+				"Statement": [
+					{
+						"Effect": "Allow",
+						"Principal": {"AWS": ["arn:aws:iam::123456789:role/aws-service-role/ec2fleet.amazonaws.com/AWSServiceRoleForEC2Fleet"]},
+						"Action": ["kms:Decrypt", "kms:GenerateDataKey*", "kms:DescribeKey"],
+						"Resource": "*"
+					}
+				]
+			}`)
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeVolumeEncryptionReady).IsFalse()).To(BeTrue())
+		})
+		It("should be ready when the root volume is encrypted", func() {
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeVolumeEncryptionReady)).To(BeTrue())
+		})
+		It("should be ready when the default block device mappings are used", func() {
+			nodeClass.Spec.BlockDeviceMappings = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeVolumeEncryptionReady)).To(BeTrue())
+		})
+		It("should not be ready when the root volume isn't encrypted", func() {
+			nodeClass.Spec.BlockDeviceMappings[0].EBS.Encrypted = aws.Bool(false)
+			nodeClass.Spec.BlockDeviceMappings[0].EBS.KMSKeyID = nil
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			condition := nodeClass.StatusConditions().Get(v1.ConditionTypeVolumeEncryptionReady)
+			Expect(condition.IsFalse()).To(BeTrue())
+			Expect(condition.Reason).To(Equal("RootVolumeNotEncrypted"))
+		})
+		It("should find the root volume by the AMI family's device name", func() {
+			nodeClass.Spec.BlockDeviceMappings[0].RootVolume = false
+			nodeClass.Spec.BlockDeviceMappings = append(nodeClass.Spec.BlockDeviceMappings, &v1.BlockDeviceMapping{
+				DeviceName: aws.String("/dev/xvdb"),
+				EBS:        &v1.BlockDevice{VolumeSize: lo.ToPtr(resource.MustParse("100Gi"))},
+			})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeVolumeEncryptionReady)).To(BeTrue())
+		})
+		It("should not be ready when the root volume can't be found in the block device mappings", func() {
+			nodeClass.Spec.BlockDeviceMappings[0].RootVolume = false
+			nodeClass.Spec.BlockDeviceMappings[0].DeviceName = aws.String("/dev/xvdc")
+			ExpectApplied(ctx, env.Client, nodeClass)
+			ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeVolumeEncryptionReady).IsFalse()).To(BeTrue())
+		})
+	})
+})
@@ -18,7 +18,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/samber/lo"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
@@ -36,8 +35,18 @@ func (ip *InstanceProfile) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeC
 			return reconcile.Result{}, fmt.Errorf("creating instance profile, %w", err)
 		}
 		nodeClass.Status.InstanceProfile = name
+		// The EC2NodeClass isn't launched from until the instance profile has propagated, since EC2 may reject it until then
+		remaining, err := ip.instanceProfileProvider.Propagating(ctx, nodeClass)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("verifying instance profile propagation, %w", err)
+		}
+		if remaining > 0 {
+			nodeClass.StatusConditions().SetUnknownWithReason(v1.ConditionTypeInstanceProfileReady, "InstanceProfilePropagating",
+				fmt.Sprintf("Waiting for instance profile %q to propagate", name))
+			return reconcile.Result{RequeueAfter: remaining}, nil
+		}
 	} else {
-		nodeClass.Status.InstanceProfile = lo.FromPtr(nodeClass.Spec.InstanceProfile)
+		nodeClass.Status.InstanceProfile = nodeClass.SpecInstanceProfileName()
 	}
 	nodeClass.StatusConditions().SetTrue(v1.ConditionTypeInstanceProfileReady)
 	return reconcile.Result{}, nil
@@ -16,6 +16,7 @@ package status_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -54,6 +56,24 @@ var _ = Describe("NodeClass InstanceProfile Status Controller", func() {
 		Expect(nodeClass.Status.InstanceProfile).To(Equal(profileName))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeInstanceProfileReady)).To(BeTrue())
 	})
+	It("should wait for a created instance profile to propagate before it's ready", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceProfilePropagationDelay: lo.ToPtr(10 * time.Second)}))
+		nodeClass.Spec.Role = "test-role"
+		ExpectApplied(ctx, env.Client, nodeClass)
+		result := ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+		Expect(result.RequeueAfter).To(Equal(10 * time.Second))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.InstanceProfile).To(Equal(profileName))
+		cond := nodeClass.StatusConditions().Get(v1.ConditionTypeInstanceProfileReady)
+		Expect(cond.IsUnknown()).To(BeTrue())
+		Expect(cond.Reason).To(Equal("InstanceProfilePropagating"))
+
+		awsEnv.Clock.Step(10 * time.Second)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeInstanceProfileReady)).To(BeTrue())
+	})
 	It("should add the role to the instance profile when it exists without a role", func() {
 		awsEnv.IAMAPI.InstanceProfiles = map[string]*iamtypes.InstanceProfile{
 			profileName: {
@@ -146,4 +166,35 @@ var _ = Describe("NodeClass InstanceProfile Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeInstanceProfileReady)).To(BeTrue())
 	})
+	It("should add the role to the instance profile by name when the role is specified as an ARN", func() {
+		nodeClass.Spec.Role = "arn:aws-us-gov:iam::111122223333:role/nodes/test-role"
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+		Expect(awsEnv.IAMAPI.InstanceProfiles[profileName].Roles).To(HaveLen(1))
+		Expect(*awsEnv.IAMAPI.InstanceProfiles[profileName].Roles[0].RoleName).To(Equal("test-role"))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeInstanceProfileReady)).To(BeTrue())
+	})
+	It("should resolve the instance profile name into the status when the instance profile is specified as an ARN", func() {
+		nodeClass.Spec.Role = ""
+		nodeClass.Spec.InstanceProfile = lo.ToPtr("arn:aws-us-gov:iam::111122223333:instance-profile/test-instance-profile")
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.InstanceProfile).To(Equal("test-instance-profile"))
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeInstanceProfileReady)).To(BeTrue())
+	})
+	It("should prefix the instance profile name with the resource name prefix", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ResourceNamePrefix: lo.ToPtr("acme-")}))
+		nodeClass.Spec.Role = "test-role"
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+
+		Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveLen(1))
+		Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveKey("acme-" + profileName))
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.InstanceProfile).To(Equal("acme-" + profileName))
+	})
 })
@@ -0,0 +1,35 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+type KeyPair struct{}
+
+func (k *KeyPair) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	if options.FromContext(ctx).ForbidKeyPairs && nodeClass.Spec.KeyName != nil {
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeKeyPairReady, "KeyPairsForbidden", "Key pairs are forbidden in this cluster, but keyName is set")
+		return reconcile.Result{}, nil
+	}
+	nodeClass.StatusConditions().SetTrue(v1.ConditionTypeKeyPairReady)
+	return reconcile.Result{}, nil
+}
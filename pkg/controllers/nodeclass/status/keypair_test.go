@@ -0,0 +1,62 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass Key Pair Status Controller", func() {
+	BeforeEach(func() {
+		nodeClass.Spec.KeyName = lo.ToPtr("break-glass")
+	})
+	AfterEach(func() {
+		ctx = options.ToContext(ctx, test.Options())
+	})
+	It("should be ready when key pairs aren't forbidden", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeKeyPairReady)).To(BeTrue())
+		Expect(nodeClass.StatusConditions().IsTrue(status.ConditionReady)).To(BeTrue())
+	})
+	It("should be ready when key pairs are forbidden and keyName isn't set", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ForbidKeyPairs: lo.ToPtr(true)}))
+		nodeClass.Spec.KeyName = nil
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeKeyPairReady)).To(BeTrue())
+	})
+	It("should not be ready when key pairs are forbidden and keyName is set", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ForbidKeyPairs: lo.ToPtr(true)}))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		condition := nodeClass.StatusConditions().Get(v1.ConditionTypeKeyPairReady)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("KeyPairsForbidden"))
+		Expect(nodeClass.StatusConditions().IsTrue(status.ConditionReady)).To(BeFalse())
+	})
+})
@@ -15,10 +15,14 @@ limitations under the License.
 package status_test
 
 import (
+	"fmt"
+
 	"github.com/awslabs/operatorpkg/status"
 	"github.com/samber/lo"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -53,7 +57,7 @@ var _ = Describe("NodeClass Status Condition Controller", func() {
 		ExpectApplied(ctx, env.Client, nodeClass)
 		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
-		Expect(nodeClass.Status.Conditions).To(HaveLen(5))
+		Expect(nodeClass.Status.Conditions).To(HaveLen(7))
 		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsTrue()).To(BeTrue())
 	})
 	It("should update status condition as Not Ready", func() {
@@ -69,4 +73,57 @@ var _ = Describe("NodeClass Status Condition Controller", func() {
 		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).IsFalse()).To(BeTrue())
 		Expect(nodeClass.StatusConditions().Get(status.ConditionReady).Message).To(Equal("SecurityGroupsReady=False"))
 	})
+	It("should stamp the observed generation and resolution time", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.ObservedGeneration).To(Equal(nodeClass.Generation))
+		Expect(nodeClass.Status.LastResolvedTime).ToNot(BeNil())
+	})
+	It("should not bump the resolution time when the resolved values are unchanged", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		lastResolvedTime := nodeClass.Status.LastResolvedTime
+
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.LastResolvedTime).To(Equal(lastResolvedTime))
+	})
+	It("should update the observed generation when the spec changes", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		generation := nodeClass.Generation
+
+		nodeClass.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{ID: "subnet-test1"}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Generation).To(BeNumerically(">", generation))
+		Expect(nodeClass.Status.ObservedGeneration).To(Equal(nodeClass.Generation))
+	})
+	It("should update the observed generation when a reconciler that doesn't resolve launch values fails", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IsolatedVPC: lo.ToPtr(true)}))
+		awsEnv.EC2API.DescribeVpcEndpointsBehavior.Error.Set(fmt.Errorf("failed"), fake.MaxCalls(1000))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		_ = ExpectObjectReconcileFailed(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		generation := nodeClass.Generation
+
+		nodeClass.Spec.SubnetSelectorTerms = []v1.SubnetSelectorTerm{{ID: "subnet-test1"}}
+		ExpectApplied(ctx, env.Client, nodeClass)
+		_ = ExpectObjectReconcileFailed(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Generation).To(BeNumerically(">", generation))
+		Expect(nodeClass.Status.ObservedGeneration).To(Equal(nodeClass.Generation))
+	})
+	It("should not update the observed generation when resolution fails", func() {
+		ExpectApplied(ctx, env.Client, nodeClass)
+		awsEnv.EC2API.NextError.Set(fmt.Errorf("failed"))
+		_ = ExpectObjectReconcileFailed(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.Status.ObservedGeneration).To(BeZero())
+		Expect(nodeClass.Status.LastResolvedTime).To(BeNil())
+	})
 })
@@ -57,24 +57,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -90,19 +94,22 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: 50,
 			},
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 20,
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -121,14 +128,16 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -144,9 +153,10 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -157,24 +167,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 
@@ -195,14 +209,16 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -213,24 +229,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 
@@ -244,9 +264,10 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeSubnetsReady)).To(BeTrue())
@@ -269,24 +290,28 @@ var _ = Describe("NodeClass Subnet Status Controller", func() {
 		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
 		Expect(nodeClass.Status.Subnets).To(Equal([]v1.Subnet{
 			{
-				ID:     "subnet-test1",
-				Zone:   "test-zone-1a",
-				ZoneID: "tstz1-1a",
+				ID:                      "subnet-test1",
+				Zone:                    "test-zone-1a",
+				ZoneID:                  "tstz1-1a",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test2",
-				Zone:   "test-zone-1b",
-				ZoneID: "tstz1-1b",
+				ID:                      "subnet-test2",
+				Zone:                    "test-zone-1b",
+				ZoneID:                  "tstz1-1b",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test3",
-				Zone:   "test-zone-1c",
-				ZoneID: "tstz1-1c",
+				ID:                      "subnet-test3",
+				Zone:                    "test-zone-1c",
+				ZoneID:                  "tstz1-1c",
+				AvailableIPAddressCount: 100,
 			},
 			{
-				ID:     "subnet-test4",
-				Zone:   "test-zone-1a-local",
-				ZoneID: "tstz1-1alocal",
+				ID:                      "subnet-test4",
+				Zone:                    "test-zone-1a-local",
+				ZoneID:                  "tstz1-1alocal",
+				AvailableIPAddressCount: 100,
 			},
 		}))
 
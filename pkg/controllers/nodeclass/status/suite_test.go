@@ -60,6 +60,10 @@ var _ = BeforeSuite(func() {
 		awsEnv.AMIProvider,
 		awsEnv.InstanceProfileProvider,
 		awsEnv.LaunchTemplateProvider,
+		awsEnv.KMSProvider,
+		awsEnv.CapacityReservationProvider,
+		awsEnv.VPCEndpointProvider,
+		awsEnv.AccessEntryProvider,
 	)
 })
 
@@ -69,6 +73,7 @@ var _ = AfterSuite(func() {
 
 var _ = BeforeEach(func() {
 	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
 	nodeClass = test.EC2NodeClass()
 	awsEnv.Reset()
 })
@@ -0,0 +1,67 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
+)
+
+type VPCEndpoint struct {
+	subnetProvider      subnet.Provider
+	vpcEndpointProvider vpcendpoint.Provider
+}
+
+func (v *VPCEndpoint) Reconcile(ctx context.Context, nodeClass *v1.EC2NodeClass) (reconcile.Result, error) {
+	// Without an isolated VPC, nodes can reach AWS services through their public endpoints
+	if !options.FromContext(ctx).IsolatedVPC {
+		nodeClass.StatusConditions().SetTrue(v1.ConditionTypeVPCEndpointsReady)
+		return reconcile.Result{}, nil
+	}
+	subnets, err := v.subnetProvider.List(ctx, nodeClass)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("getting subnets, %w", err)
+	}
+	vpcIDs := lo.Uniq(lo.FilterMap(subnets, func(s ec2types.Subnet, _ int) (string, bool) { return lo.FromPtr(s.VpcId), s.VpcId != nil }))
+	var messages []string
+	for _, vpcID := range vpcIDs {
+		missing, err := v.vpcEndpointProvider.MissingServices(ctx, vpcID)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("validating vpc endpoints, %w", err)
+		}
+		if len(missing) != 0 {
+			messages = append(messages, fmt.Sprintf("VPC %s doesn't have VPC endpoints for %s", vpcID, strings.Join(missing, ", ")))
+		}
+	}
+	if len(messages) != 0 {
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeVPCEndpointsReady, "VPCEndpointsNotFound",
+			fmt.Sprintf("Nodes in an isolated VPC can't bootstrap without VPC endpoints, %s", strings.Join(messages, "; ")))
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+	nodeClass.StatusConditions().SetTrue(v1.ConditionTypeVPCEndpointsReady)
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
@@ -0,0 +1,98 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status_test
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+)
+
+var _ = Describe("NodeClass VPC Endpoint Status Controller", func() {
+	storeVPCEndpoints := func(services ...string) {
+		for _, service := range services {
+			awsEnv.EC2API.VPCEndpoints.Store(service, ec2types.VpcEndpoint{
+				VpcEndpointId: aws.String(fmt.Sprintf("vpce-%s", service)),
+				VpcId:         aws.String("vpc-test1"),
+				ServiceName:   aws.String(fmt.Sprintf("com.amazonaws.%s.%s", fake.DefaultRegion, service)),
+				State:         ec2types.StateAvailable,
+			})
+		}
+	}
+	BeforeEach(func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{IsolatedVPC: lo.ToPtr(true)}))
+	})
+	AfterEach(func() {
+		ctx = options.ToContext(ctx, test.Options())
+	})
+	It("should be ready without checking VPC endpoints when the VPC isn't isolated", func() {
+		ctx = options.ToContext(ctx, test.Options())
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeVPCEndpointsReady)).To(BeTrue())
+		Expect(awsEnv.EC2API.DescribeVpcEndpointsBehavior.Calls()).To(BeZero())
+	})
+	It("should be ready when the VPC has endpoints for every required service", func() {
+		storeVPCEndpoints(vpcendpoint.RequiredServices...)
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().IsTrue(v1.ConditionTypeVPCEndpointsReady)).To(BeTrue())
+		Expect(nodeClass.StatusConditions().IsTrue(status.ConditionReady)).To(BeTrue())
+	})
+	It("should not be ready when the VPC is missing endpoints for required services", func() {
+		storeVPCEndpoints("ec2", "ecr.api", "s3")
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		condition := nodeClass.StatusConditions().Get(v1.ConditionTypeVPCEndpointsReady)
+		Expect(condition.IsFalse()).To(BeTrue())
+		Expect(condition.Reason).To(Equal("VPCEndpointsNotFound"))
+		Expect(condition.Message).To(ContainSubstring("VPC vpc-test1 doesn't have VPC endpoints for ecr.dkr, sts"))
+		Expect(nodeClass.StatusConditions().IsTrue(status.ConditionReady)).To(BeFalse())
+	})
+	It("should not count VPC endpoints in other VPCs", func() {
+		storeVPCEndpoints(vpcendpoint.RequiredServices...)
+		awsEnv.EC2API.VPCEndpoints.Range(func(k, v any) bool {
+			endpoint := v.(ec2types.VpcEndpoint)
+			endpoint.VpcId = aws.String("vpc-test2")
+			awsEnv.EC2API.VPCEndpoints.Store(k, endpoint)
+			return true
+		})
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, statusController, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		Expect(nodeClass.StatusConditions().Get(v1.ConditionTypeVPCEndpointsReady).IsFalse()).To(BeTrue())
+	})
+	It("should fail to reconcile when VPC endpoints can't be described", func() {
+		awsEnv.EC2API.DescribeVpcEndpointsBehavior.Error.Set(fmt.Errorf("failed"))
+		ExpectApplied(ctx, env.Client, nodeClass)
+		_ = ExpectObjectReconcileFailed(ctx, env.Client, statusController, nodeClass)
+	})
+})
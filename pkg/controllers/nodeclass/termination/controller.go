@@ -44,6 +44,9 @@ import (
 	"sigs.k8s.io/karpenter/pkg/events"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accessentry"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 )
 
@@ -52,16 +55,19 @@ type Controller struct {
 	recorder                events.Recorder
 	instanceProfileProvider instanceprofile.Provider
 	launchTemplateProvider  launchtemplate.Provider
+	accessEntryProvider     accessentry.Provider
 }
 
 func NewController(kubeClient client.Client, recorder events.Recorder,
-	instanceProfileProvider instanceprofile.Provider, launchTemplateProvider launchtemplate.Provider) *Controller {
+	instanceProfileProvider instanceprofile.Provider, launchTemplateProvider launchtemplate.Provider,
+	accessEntryProvider accessentry.Provider) *Controller {
 
 	return &Controller{
 		kubeClient:              kubeClient,
 		recorder:                recorder,
 		instanceProfileProvider: instanceProfileProvider,
 		launchTemplateProvider:  launchTemplateProvider,
+		accessEntryProvider:     accessEntryProvider,
 	}
 }
 
@@ -87,6 +93,11 @@ func (c *Controller) finalize(ctx context.Context, nodeClass *v1.EC2NodeClass) (
 		c.recorder.Publish(WaitingOnNodeClaimTerminationEvent(nodeClass, lo.Map(nodeClaims.Items, func(nc karpv1.NodeClaim, _ int) string { return nc.Name })))
 		return reconcile.Result{RequeueAfter: time.Minute * 10}, nil // periodically fire the event
 	}
+	if options.FromContext(ctx).ManageNodeAccessEntries {
+		if err := c.deleteAccess(ctx, nodeClass); err != nil {
+			return reconcile.Result{}, fmt.Errorf("deleting node access, %w", err)
+		}
+	}
 	if nodeClass.Spec.Role != "" {
 		if err := c.instanceProfileProvider.Delete(ctx, nodeClass); err != nil {
 			return reconcile.Result{}, fmt.Errorf("deleting instance profile, %w", err)
@@ -111,6 +122,34 @@ func (c *Controller) finalize(ctx context.Context, nodeClass *v1.EC2NodeClass) (
 	return reconcile.Result{}, nil
 }
 
+// deleteAccess removes the access granted to the EC2NodeClass' node role unless another EC2NodeClass uses the same role
+func (c *Controller) deleteAccess(ctx context.Context, nodeClass *v1.EC2NodeClass) error {
+	principalARN, err := c.accessEntryProvider.PrincipalARN(ctx, nodeClass)
+	if err != nil {
+		return awserrors.IgnoreNotFound(err)
+	}
+	nodeClasses := &v1.EC2NodeClassList{}
+	if err = c.kubeClient.List(ctx, nodeClasses); err != nil {
+		return fmt.Errorf("listing nodeclasses, %w", err)
+	}
+	for i := range nodeClasses.Items {
+		if nodeClasses.Items[i].Name == nodeClass.Name || !nodeClasses.Items[i].DeletionTimestamp.IsZero() {
+			continue
+		}
+		arn, err := c.accessEntryProvider.PrincipalARN(ctx, &nodeClasses.Items[i])
+		if err != nil {
+			if awserrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if arn == principalARN {
+			return nil
+		}
+	}
+	return c.accessEntryProvider.Delete(ctx, principalARN)
+}
+
 func (c *Controller) Register(_ context.Context, m manager.Manager) error {
 	return controllerruntime.NewControllerManagedBy(m).
 		Named("nodeclass.termination").
@@ -24,10 +24,14 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
 
 	"github.com/awslabs/operatorpkg/object"
 	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
@@ -40,6 +44,7 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodeclass/termination"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accessentry"
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -65,7 +70,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	awsEnv = test.NewEnvironment(ctx, env)
 
-	terminationController = termination.NewController(env.Client, events.NewRecorder(&record.FakeRecorder{}), awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider)
+	terminationController = termination.NewController(env.Client, events.NewRecorder(&record.FakeRecorder{}), awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.AccessEntryProvider)
 })
 
 var _ = AfterSuite(func() {
@@ -179,6 +184,24 @@ var _ = Describe("NodeClass Termination", func() {
 		Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveLen(0))
 		ExpectNotFound(ctx, env.Client, nodeClass)
 	})
+	It("should delete both the prefixed and unprefixed instance profiles when the resource name prefix is set", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ResourceNamePrefix: lo.ToPtr("acme-")}))
+		awsEnv.IAMAPI.InstanceProfiles = map[string]*iamtypes.InstanceProfile{
+			profileName: {
+				InstanceProfileName: aws.String(profileName),
+			},
+			"acme-" + profileName: {
+				InstanceProfileName: aws.String("acme-" + profileName),
+			},
+		}
+		controllerutil.AddFinalizer(nodeClass, v1.TerminationFinalizer)
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, terminationController, nodeClass)
+		Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+		ExpectObjectReconciled(ctx, env.Client, terminationController, nodeClass)
+		Expect(awsEnv.IAMAPI.InstanceProfiles).To(HaveLen(0))
+		ExpectNotFound(ctx, env.Client, nodeClass)
+	})
 	It("should succeed to delete the instance profile when no roles exist with no NodeClaims", func() {
 		awsEnv.IAMAPI.InstanceProfiles = map[string]*iamtypes.InstanceProfile{
 			profileName: {
@@ -283,4 +306,75 @@ var _ = Describe("NodeClass Termination", func() {
 		Expect(awsEnv.IAMAPI.DeleteInstanceProfileBehavior.Calls()).To(BeZero())
 		Expect(awsEnv.IAMAPI.RemoveRoleFromInstanceProfileBehavior.Calls()).To(BeZero())
 	})
+	Context("Node Access", func() {
+		ownedTags := func() map[string]string {
+			return map[string]string{fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName): "owned"}
+		}
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ManageNodeAccessEntries: lo.ToPtr(true)}))
+			awsEnv.EKSAPI.DescribeClusterBehavior.Output.Set(&eks.DescribeClusterOutput{
+				Cluster: &ekstypes.Cluster{AccessConfig: &ekstypes.AccessConfigResponse{AuthenticationMode: ekstypes.AuthenticationModeApi}},
+			})
+			controllerutil.AddFinalizer(nodeClass, v1.TerminationFinalizer)
+		})
+		AfterEach(func() {
+			ctx = options.ToContext(ctx, test.Options())
+		})
+		It("should delete the access entry created by Karpenter for the node role", func() {
+			awsEnv.EKSAPI.AccessEntries.Store(fake.RoleARN("test-role"), &ekstypes.AccessEntry{PrincipalArn: aws.String(fake.RoleARN("test-role")), Tags: ownedTags()})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, terminationController, nodeClass)
+
+			_, ok := awsEnv.EKSAPI.AccessEntries.Load(fake.RoleARN("test-role"))
+			Expect(ok).To(BeFalse())
+			ExpectNotFound(ctx, env.Client, nodeClass)
+		})
+		It("should not delete an access entry that wasn't created by Karpenter", func() {
+			awsEnv.EKSAPI.AccessEntries.Store(fake.RoleARN("test-role"), &ekstypes.AccessEntry{PrincipalArn: aws.String(fake.RoleARN("test-role"))})
+			ExpectApplied(ctx, env.Client, nodeClass)
+			Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, terminationController, nodeClass)
+
+			_, ok := awsEnv.EKSAPI.AccessEntries.Load(fake.RoleARN("test-role"))
+			Expect(ok).To(BeTrue())
+			Expect(awsEnv.EKSAPI.DeleteAccessEntryBehavior.Calls()).To(BeZero())
+			ExpectNotFound(ctx, env.Client, nodeClass)
+		})
+		It("should not delete the access entry while another EC2NodeClass uses the node role", func() {
+			awsEnv.EKSAPI.AccessEntries.Store(fake.RoleARN("test-role"), &ekstypes.AccessEntry{PrincipalArn: aws.String(fake.RoleARN("test-role")), Tags: ownedTags()})
+			other := test.EC2NodeClass(v1.EC2NodeClass{Spec: v1.EC2NodeClassSpec{Role: "test-role"}})
+			ExpectApplied(ctx, env.Client, nodeClass, other)
+			Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, terminationController, nodeClass)
+
+			_, ok := awsEnv.EKSAPI.AccessEntries.Load(fake.RoleARN("test-role"))
+			Expect(ok).To(BeTrue())
+			ExpectNotFound(ctx, env.Client, nodeClass)
+		})
+		It("should only remove the role mapping added by Karpenter from the aws-auth ConfigMap", func() {
+			awsEnv.EKSAPI.DescribeClusterBehavior.Output.Set(&eks.DescribeClusterOutput{
+				Cluster: &ekstypes.Cluster{AccessConfig: &ekstypes.AccessConfigResponse{AuthenticationMode: ekstypes.AuthenticationModeConfigMap}},
+			})
+			awsAuth := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   metav1.NamespaceSystem,
+					Name:        accessentry.AWSAuthConfigMapName,
+					Annotations: map[string]string{v1.AnnotationManagedNodeRoles: fake.RoleARN("test-role")},
+				},
+				Data: map[string]string{"mapRoles": fmt.Sprintf("- rolearn: %s\n  username: system:node:{{EC2PrivateDNSName}}\n- rolearn: %s\n  username: system:node:{{EC2PrivateDNSName}}\n",
+					fake.RoleARN("managed-node-group"), fake.RoleARN("test-role"))},
+			}
+			ExpectApplied(ctx, env.Client, awsAuth, nodeClass)
+			Expect(env.Client.Delete(ctx, nodeClass)).To(Succeed())
+			ExpectObjectReconciled(ctx, env.Client, terminationController, nodeClass)
+
+			awsAuth = ExpectExists(ctx, env.Client, awsAuth)
+			Expect(awsAuth.Data["mapRoles"]).To(ContainSubstring(fake.RoleARN("managed-node-group")))
+			Expect(awsAuth.Data["mapRoles"]).ToNot(ContainSubstring(fake.RoleARN("test-role")))
+			Expect(awsAuth.Annotations[v1.AnnotationManagedNodeRoles]).To(BeEmpty())
+			ExpectNotFound(ctx, env.Client, nodeClass)
+			ExpectDeleted(ctx, env.Client, awsAuth)
+		})
+	})
 })
@@ -0,0 +1,147 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pause
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	nodepoolutils "sigs.k8s.io/karpenter/pkg/utils/nodepool"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// Controller reflects the paused state of a NodePool, which is paused when either the NodePool or its EC2NodeClass has the
+// karpenter.sh/paused annotation. The CloudProvider stops launching capacity and drifting nodes for paused NodePools, while
+// this controller halts consolidation by adding the do-not-disrupt annotation to the NodePool's Nodes. Interruption handling
+// is unaffected, since interrupted instances are going away regardless.
+type Controller struct {
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodePool *karpv1.NodePool) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.pause")
+
+	if !nodePool.DeletionTimestamp.IsZero() {
+		NodePoolPaused.Delete(map[string]string{nodePoolLabel: nodePool.Name})
+		return reconcile.Result{}, nil
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if nodePool.Spec.Template.Spec.NodeClassRef != nil {
+		if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+			if !errors.IsNotFound(err) {
+				return reconcile.Result{}, fmt.Errorf("getting nodeclass, %w", err)
+			}
+		}
+	}
+	paused := utils.IsPaused(nodePool, nodeClass)
+	NodePoolPaused.Set(lo.Ternary[float64](paused, 1, 0), map[string]string{nodePoolLabel: nodePool.Name})
+
+	if err := c.reconcileNodes(ctx, nodePool, paused); err != nil {
+		return reconcile.Result{}, err
+	}
+	stored := nodePool.DeepCopy()
+	if paused {
+		nodePool.StatusConditions().SetTrueWithReason(v1.ConditionTypePaused, "Paused",
+			lo.Ternary(utils.IsPaused(nodePool), "NodePool is paused", fmt.Sprintf("EC2NodeClass %q is paused", nodeClass.Name)))
+	} else {
+		_ = nodePool.StatusConditions().Clear(v1.ConditionTypePaused)
+	}
+	if !equality.Semantic.DeepEqual(stored, nodePool) {
+		// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+		// can cause races due to the fact that it fully replaces the list on a change
+		// Here, we are updating the status condition list
+		if err := c.kubeClient.Status().Patch(ctx, nodePool, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			if errors.IsConflict(err) {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+		log.FromContext(ctx).WithValues("paused", paused).Info("updated nodepool paused state")
+	}
+	return reconcile.Result{}, nil
+}
+
+// reconcileNodes adds the do-not-disrupt annotation to the NodePool's Nodes while it's paused, and removes it once unpaused.
+// Nodes that already had the annotation are left untouched, so that we never remove an annotation that we didn't add.
+func (c *Controller) reconcileNodes(ctx context.Context, nodePool *karpv1.NodePool, paused bool) error {
+	nodeList := &corev1.NodeList{}
+	if err := c.kubeClient.List(ctx, nodeList, client.MatchingLabels{karpv1.NodePoolLabelKey: nodePool.Name}); err != nil {
+		return fmt.Errorf("listing nodes, %w", err)
+	}
+	var errs error
+	for i := range nodeList.Items {
+		node := &nodeList.Items[i]
+		stored := node.DeepCopy()
+		_, doNotDisrupt := node.Annotations[karpv1.DoNotDisruptAnnotationKey]
+		_, managed := node.Annotations[v1.AnnotationPausedDoNotDisrupt]
+		switch {
+		case paused && !doNotDisrupt:
+			node.Annotations = lo.Assign(node.Annotations, map[string]string{
+				karpv1.DoNotDisruptAnnotationKey: "true",
+				v1.AnnotationPausedDoNotDisrupt:  "true",
+			})
+		case !paused && managed:
+			node.Annotations = lo.OmitByKeys(node.Annotations, []string{karpv1.DoNotDisruptAnnotationKey, v1.AnnotationPausedDoNotDisrupt})
+		default:
+			continue
+		}
+		if err := c.kubeClient.Patch(ctx, node, client.MergeFrom(stored)); client.IgnoreNotFound(err) != nil {
+			errs = multierr.Append(errs, fmt.Errorf("patching node %s, %w", node.Name, err))
+		}
+	}
+	return errs
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.pause").
+		For(&karpv1.NodePool{}, builder.WithPredicates(nodepoolutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		Watches(&v1.EC2NodeClass{}, nodepoolutils.NodeClassEventHandler(c.kubeClient)).
+		Watches(&corev1.Node{}, nodepoolutils.NodeEventHandler()).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
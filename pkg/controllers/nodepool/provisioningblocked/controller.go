@@ -0,0 +1,180 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioningblocked
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+	"github.com/awslabs/operatorpkg/status"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	nodepoolutils "sigs.k8s.io/karpenter/pkg/utils/nodepool"
+	podutils "sigs.k8s.io/karpenter/pkg/utils/pod"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+// Controller surfaces NodePools that can't provision because their EC2NodeClass doesn't exist or isn't ready, e.g. because
+// its subnet or AMI selectors don't match anything. Pending pods would otherwise sit without any indication of the cause,
+// since the scheduler only considers NodePools whose EC2NodeClass is ready. The NodePool has the ProvisioningBlocked
+// condition, and pending pods which no other NodePool can provision for get an event naming the EC2NodeClass.
+type Controller struct {
+	kubeClient    client.Client
+	recorder      events.Recorder
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, cloudProvider cloudprovider.CloudProvider) *Controller {
+	return &Controller{
+		kubeClient:    kubeClient,
+		recorder:      recorder,
+		cloudProvider: cloudProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodePool *karpv1.NodePool) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.provisioningblocked")
+
+	if !nodePool.DeletionTimestamp.IsZero() || nodePool.Spec.Template.Spec.NodeClassRef == nil {
+		return reconcile.Result{}, nil
+	}
+	reason, message, err := c.blocked(ctx, nodePool)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	stored := nodePool.DeepCopy()
+	if reason != "" {
+		if cond := nodePool.StatusConditions().Get(v1.ConditionTypeProvisioningBlocked); cond == nil || cond.Reason != reason {
+			ProvisioningBlockedTotal.Inc(map[string]string{reasonLabel: reason, nodeClassLabel: nodePool.Spec.Template.Spec.NodeClassRef.Name})
+		}
+		nodePool.StatusConditions().SetTrueWithReason(v1.ConditionTypeProvisioningBlocked, reason, message)
+	} else {
+		_ = nodePool.StatusConditions().Clear(v1.ConditionTypeProvisioningBlocked)
+	}
+	if !equality.Semantic.DeepEqual(stored, nodePool) {
+		// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+		// can cause races due to the fact that it fully replaces the list on a change
+		// Here, we are updating the status condition list
+		if err := c.kubeClient.Status().Patch(ctx, nodePool, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			if errors.IsConflict(err) {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+		log.FromContext(ctx).WithValues("blocked", reason != "", "reason", reason).Info("updated nodepool provisioning blocked state")
+	}
+	if reason == "" {
+		return reconcile.Result{}, nil
+	}
+	if err := c.publishPodEvents(ctx, nodePool, message); err != nil {
+		return reconcile.Result{}, err
+	}
+	// Pods become pending without the NodePool or its EC2NodeClass changing
+	return reconcile.Result{RequeueAfter: time.Minute}, nil
+}
+
+// blocked returns the reason and message that the NodePool can't provision for, or an empty reason if it isn't blocked. The
+// reason is that of the first false condition of the EC2NodeClass, e.g. SubnetsNotFound. An EC2NodeClass whose conditions
+// are still being resolved isn't considered to block provisioning.
+func (c *Controller) blocked(ctx context.Context, nodePool *karpv1.NodePool) (string, string, error) {
+	nodeClass := &v1.EC2NodeClass{}
+	if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+		if errors.IsNotFound(err) {
+			return "NodeClassNotFound", fmt.Sprintf("EC2NodeClass %q not found", nodePool.Spec.Template.Spec.NodeClassRef.Name), nil
+		}
+		return "", "", fmt.Errorf("getting nodeclass, %w", err)
+	}
+	if !nodeClass.StatusConditions().Root().IsFalse() {
+		return "", "", nil
+	}
+	cond, ok := lo.Find(nodeClass.StatusConditions().List(), func(cond status.Condition) bool {
+		return cond.Type != status.ConditionReady && cond.IsFalse()
+	})
+	if !ok {
+		return "NodeClassNotReady", fmt.Sprintf("EC2NodeClass %q is not ready", nodeClass.Name), nil
+	}
+	return cond.Reason, fmt.Sprintf("EC2NodeClass %q is not ready, %s", nodeClass.Name, cond.Message), nil
+}
+
+// publishPodEvents publishes an event onto the pending pods that the NodePool could provision for, unless another NodePool
+// that isn't blocked could provision for them too
+func (c *Controller) publishPodEvents(ctx context.Context, nodePool *karpv1.NodePool, message string) error {
+	nodePoolList := &karpv1.NodePoolList{}
+	if err := c.kubeClient.List(ctx, nodePoolList); err != nil {
+		return fmt.Errorf("listing nodepools, %w", err)
+	}
+	unblocked := lo.Filter(nodePoolList.Items, func(np karpv1.NodePool, _ int) bool {
+		return np.Name != nodePool.Name && np.DeletionTimestamp.IsZero() && nodepoolutils.IsManaged(&np, c.cloudProvider) &&
+			!np.StatusConditions().Get(v1.ConditionTypeProvisioningBlocked).IsTrue()
+	})
+	podList := &corev1.PodList{}
+	if err := c.kubeClient.List(ctx, podList, client.MatchingFields{"spec.nodeName": ""}); err != nil {
+		return fmt.Errorf("listing pods, %w", err)
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !podutils.IsProvisionable(pod) || !compatible(nodePool, pod) {
+			continue
+		}
+		if lo.ContainsBy(unblocked, func(np karpv1.NodePool) bool { return compatible(&np, pod) }) {
+			continue
+		}
+		c.recorder.Publish(ProvisioningBlockedEvent(pod, nodePool, message))
+	}
+	return nil
+}
+
+// compatible returns true if the pod tolerates the NodePool's taints and its node requirements are compatible with the
+// NodePool's requirements
+func compatible(nodePool *karpv1.NodePool, pod *corev1.Pod) bool {
+	if scheduling.Taints(nodePool.Spec.Template.Spec.Taints).Tolerates(pod) != nil {
+		return false
+	}
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+	reqs.Add(lo.Values(scheduling.NewLabelRequirements(nodePool.Spec.Template.Labels))...)
+	return reqs.Compatible(scheduling.NewStrictPodRequirements(pod), scheduling.AllowUndefinedWellKnownLabels) == nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.provisioningblocked").
+		For(&karpv1.NodePool{}, builder.WithPredicates(nodepoolutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		Watches(&v1.EC2NodeClass{}, nodepoolutils.NodeClassEventHandler(c.kubeClient)).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
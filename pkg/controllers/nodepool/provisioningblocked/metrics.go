@@ -0,0 +1,40 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioningblocked
+
+import (
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	provisioningSubsystem = "provisioning"
+	reasonLabel           = "reason"
+	nodeClassLabel        = "nodeclass"
+)
+
+var ProvisioningBlockedTotal = opmetrics.NewPrometheusCounter(
+	crmetrics.Registry,
+	prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: provisioningSubsystem,
+		Name:      "blocked_total",
+		Help:      "Number of times that a NodePool was blocked from provisioning because its EC2NodeClass doesn't exist or isn't ready. Labeled by the reason, e.g. a failing EC2NodeClass condition's reason, and nodeclass.",
+	},
+	[]string{reasonLabel, nodeClassLabel},
+)
@@ -0,0 +1,187 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioningblocked_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/awslabs/operatorpkg/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/provisioningblocked"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var recorder *record.FakeRecorder
+var controller *provisioningblocked.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodePoolProvisioningBlocked")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	recorder = record.NewFakeRecorder(10)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	controller = provisioningblocked.NewController(env.Client, events.NewRecorder(recorder), cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("NodePoolProvisioningBlocked", func() {
+	var nodeClass *v1.EC2NodeClass
+	var nodePool *karpv1.NodePool
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass()
+		nodePool = coretest.NodePool(karpv1.NodePool{
+			Spec: karpv1.NodePoolSpec{
+				Template: karpv1.NodeClaimTemplate{
+					Spec: karpv1.NodeClaimTemplateSpec{
+						NodeClassRef: &karpv1.NodeClassReference{
+							Group: "karpenter.k8s.aws",
+							Kind:  "EC2NodeClass",
+							Name:  nodeClass.Name,
+						},
+					},
+				},
+			},
+		})
+	})
+	notReady := func() {
+		nodeClass.StatusConditions().SetFalse(v1.ConditionTypeSubnetsReady, "SubnetsNotFound", "SubnetSelector did not match any Subnets")
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectApplied(ctx, env.Client, nodePool)
+	}
+	It("should not block a NodePool whose EC2NodeClass is ready", func() {
+		nodeClass.StatusConditions().SetTrue(status.ConditionReady)
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.StatusConditions().Get(v1.ConditionTypeProvisioningBlocked)).To(BeNil())
+	})
+	It("should not block a NodePool whose EC2NodeClass is still being resolved", func() {
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.StatusConditions().Get(v1.ConditionTypeProvisioningBlocked)).To(BeNil())
+	})
+	It("should block a NodePool whose EC2NodeClass doesn't exist", func() {
+		ExpectApplied(ctx, env.Client, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		cond := nodePool.StatusConditions().Get(v1.ConditionTypeProvisioningBlocked)
+		Expect(cond.IsTrue()).To(BeTrue())
+		Expect(cond.Reason).To(Equal("NodeClassNotFound"))
+	})
+	It("should block a NodePool with the reason of the failing EC2NodeClass condition", func() {
+		notReady()
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		cond := nodePool.StatusConditions().Get(v1.ConditionTypeProvisioningBlocked)
+		Expect(cond.IsTrue()).To(BeTrue())
+		Expect(cond.Reason).To(Equal("SubnetsNotFound"))
+		Expect(cond.Message).To(ContainSubstring(nodeClass.Name))
+		Expect(cond.Message).To(ContainSubstring("SubnetSelector did not match any Subnets"))
+	})
+	It("should unblock a NodePool once its EC2NodeClass is ready", func() {
+		notReady()
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		nodeClass.StatusConditions().SetTrue(v1.ConditionTypeSubnetsReady)
+		nodeClass.StatusConditions().SetTrue(status.ConditionReady)
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.StatusConditions().Get(v1.ConditionTypeProvisioningBlocked)).To(BeNil())
+	})
+	It("should count the NodePool as blocked once per reason", func() {
+		notReady()
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		ExpectMetricCounterValue(provisioningblocked.ProvisioningBlockedTotal, 1, map[string]string{
+			"reason":    "SubnetsNotFound",
+			"nodeclass": nodeClass.Name,
+		})
+	})
+	It("should publish an event onto pending pods that the NodePool could provision for", func() {
+		pod := coretest.UnschedulablePod()
+		notReady()
+		ExpectApplied(ctx, env.Client, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(recorder.Events).To(Receive(And(ContainSubstring("ProvisioningBlocked"), ContainSubstring(nodeClass.Name))))
+	})
+	It("should not publish an event onto pending pods that don't tolerate the NodePool's taints", func() {
+		nodePool.Spec.Template.Spec.Taints = []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}}
+		pod := coretest.UnschedulablePod()
+		notReady()
+		ExpectApplied(ctx, env.Client, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(recorder.Events).ToNot(Receive())
+	})
+	It("should not publish an event onto pending pods that another NodePool could provision for", func() {
+		other := coretest.NodePool(karpv1.NodePool{
+			Spec: karpv1.NodePoolSpec{
+				Template: karpv1.NodeClaimTemplate{
+					Spec: karpv1.NodeClaimTemplateSpec{
+						NodeClassRef: nodePool.Spec.Template.Spec.NodeClassRef,
+					},
+				},
+			},
+		})
+		pod := coretest.UnschedulablePod()
+		notReady()
+		ExpectApplied(ctx, env.Client, other, pod)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		Expect(recorder.Events).ToNot(Receive())
+	})
+})
@@ -0,0 +1,155 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package satisfiability
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/awslabs/operatorpkg/reasonable"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	nodepoolutils "sigs.k8s.io/karpenter/pkg/utils/nodepool"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+)
+
+// Controller evaluates whether a NodePool's requirements can be met by at least one instance type offering for its
+// EC2NodeClass. A NodePool whose requirements conflict with every offering would otherwise never provision, with nothing
+// pointing at the cause. Availability is ignored so that transient capacity errors don't flip the condition.
+type Controller struct {
+	kubeClient           client.Client
+	cloudProvider        cloudprovider.CloudProvider
+	instanceTypeProvider instancetype.Provider
+}
+
+func NewController(kubeClient client.Client, cloudProvider cloudprovider.CloudProvider, instanceTypeProvider instancetype.Provider) *Controller {
+	return &Controller{
+		kubeClient:           kubeClient,
+		cloudProvider:        cloudProvider,
+		instanceTypeProvider: instanceTypeProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context, nodePool *karpv1.NodePool) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "nodepool.satisfiability")
+
+	if !nodePool.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+	nodeClass := &v1.EC2NodeClass{}
+	if nodePool.Spec.Template.Spec.NodeClassRef != nil {
+		if err := c.kubeClient.Get(ctx, types.NamespacedName{Name: nodePool.Spec.Template.Spec.NodeClassRef.Name}, nodeClass); err != nil {
+			if !errors.IsNotFound(err) {
+				return reconcile.Result{}, fmt.Errorf("getting nodeclass, %w", err)
+			}
+		}
+	}
+	stored := nodePool.DeepCopy()
+	// Offerings can't be resolved until the EC2NodeClass is ready, which is already surfaced through the NodeClassReady condition
+	if nodeClass.StatusConditions().Root().IsTrue() {
+		instanceTypes, err := c.instanceTypeProvider.List(ctx, nodeClass)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("listing instance types, %w", err)
+		}
+		reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+		reqs.Add(lo.Values(scheduling.NewLabelRequirements(nodePool.Spec.Template.Labels))...)
+		if err := unsatisfiable(instanceTypes, reqs); err != nil {
+			nodePool.StatusConditions().SetFalse(v1.ConditionTypeSatisfiable, "RequirementsNotSatisfiable", err.Error())
+		} else {
+			nodePool.StatusConditions().SetTrue(v1.ConditionTypeSatisfiable)
+		}
+	} else {
+		_ = nodePool.StatusConditions().Clear(v1.ConditionTypeSatisfiable)
+	}
+	if !equality.Semantic.DeepEqual(stored, nodePool) {
+		// We use client.MergeFromWithOptimisticLock because patching a list with a JSON merge patch
+		// can cause races due to the fact that it fully replaces the list on a change
+		// Here, we are updating the status condition list
+		if err := c.kubeClient.Status().Patch(ctx, nodePool, client.MergeFromWithOptions(stored, client.MergeFromWithOptimisticLock{})); err != nil {
+			if errors.IsConflict(err) {
+				return reconcile.Result{Requeue: true}, nil
+			}
+			return reconcile.Result{}, client.IgnoreNotFound(err)
+		}
+		if cond := nodePool.StatusConditions().Get(v1.ConditionTypeSatisfiable); cond.IsFalse() {
+			log.FromContext(ctx).WithValues("reason", cond.Message).Info("nodepool requirements are not satisfiable")
+		}
+	}
+	// Offerings change as instance types and zones are discovered, even when neither the NodePool nor its EC2NodeClass change
+	return reconcile.Result{RequeueAfter: 5 * time.Minute}, nil
+}
+
+// unsatisfiable returns an error naming the conflicting requirement keys if no instance type offering is compatible with
+// the requirements. Keys which can't be satisfied on their own are reported; if every key is satisfiable on its own, the
+// conflict is between keys and all of them are reported.
+func unsatisfiable(instanceTypes []*cloudprovider.InstanceType, reqs scheduling.Requirements) error {
+	compatible := lo.Filter(instanceTypes, func(it *cloudprovider.InstanceType, _ int) bool { return isCompatible(it, reqs) })
+	if len(compatible) > 0 {
+		if _, err := cloudprovider.InstanceTypes(compatible).SatisfiesMinValues(reqs); err != nil {
+			return fmt.Errorf("minValues can't be satisfied by the %d compatible instance types, %w", len(compatible), err)
+		}
+		return nil
+	}
+	keys := lo.Filter(sortedKeys(reqs), func(key string, _ int) bool {
+		single := scheduling.NewRequirements(reqs.Get(key))
+		return !lo.ContainsBy(instanceTypes, func(it *cloudprovider.InstanceType) bool { return isCompatible(it, single) })
+	})
+	if len(keys) == 0 {
+		keys = sortedKeys(reqs)
+	}
+	return fmt.Errorf("no instance type offerings satisfy requirements on %s", strings.Join(keys, ", "))
+}
+
+func isCompatible(it *cloudprovider.InstanceType, reqs scheduling.Requirements) bool {
+	return it.Requirements.Intersects(reqs) == nil && len(it.Offerings.Compatible(reqs)) > 0
+}
+
+func sortedKeys(reqs scheduling.Requirements) []string {
+	keys := reqs.Keys().UnsortedList()
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("nodepool.satisfiability").
+		For(&karpv1.NodePool{}, builder.WithPredicates(nodepoolutils.IsManagedPredicateFuncs(c.cloudProvider))).
+		Watches(&v1.EC2NodeClass{}, nodepoolutils.NodeClassEventHandler(c.kubeClient)).
+		WithOptions(controller.Options{
+			RateLimiter:             reasonable.RateLimiter(),
+			MaxConcurrentReconciles: 10,
+		}).
+		Complete(reconcile.AsReconciler(m.GetClient(), c))
+}
@@ -0,0 +1,197 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package satisfiability_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/nodepool/satisfiability"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *satisfiability.Controller
+
+func TestAPIs(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodePoolSatisfiability")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	awsEnv = test.NewEnvironment(ctx, env)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	controller = satisfiability.NewController(env.Client, cloudProvider, awsEnv.InstanceTypesProvider)
+})
+
+var _ = AfterSuite(func() {
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+	Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+var _ = Describe("NodePoolSatisfiability", func() {
+	var nodeClass *v1.EC2NodeClass
+	var nodePool *karpv1.NodePool
+	BeforeEach(func() {
+		nodeClass = test.EC2NodeClass()
+		nodeClass.StatusConditions().SetTrue(status.ConditionReady)
+		nodePool = coretest.NodePool(karpv1.NodePool{
+			Spec: karpv1.NodePoolSpec{
+				Template: karpv1.NodeClaimTemplate{
+					Spec: karpv1.NodeClaimTemplateSpec{
+						NodeClassRef: &karpv1.NodeClassReference{
+							Group: "karpenter.k8s.aws",
+							Kind:  "EC2NodeClass",
+							Name:  nodeClass.Name,
+						},
+					},
+				},
+			},
+		})
+	})
+	requirement := func(key string, values ...string) karpv1.NodeSelectorRequirementWithMinValues {
+		return karpv1.NodeSelectorRequirementWithMinValues{
+			NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: key, Operator: corev1.NodeSelectorOpIn, Values: values},
+		}
+	}
+	It("should mark a NodePool with compatible offerings as satisfiable", func() {
+		nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+			requirement(corev1.LabelInstanceTypeStable, "m5.large"),
+			requirement(karpv1.CapacityTypeLabelKey, karpv1.CapacityTypeSpot),
+		}
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.StatusConditions().Get(v1.ConditionTypeSatisfiable).IsTrue()).To(BeTrue())
+	})
+	It("should report a requirement that matches no instance types", func() {
+		nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+			requirement(corev1.LabelInstanceTypeStable, "m99.large"),
+			requirement(karpv1.CapacityTypeLabelKey, karpv1.CapacityTypeSpot),
+		}
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		cond := nodePool.StatusConditions().Get(v1.ConditionTypeSatisfiable)
+		Expect(cond.IsFalse()).To(BeTrue())
+		Expect(cond.Reason).To(Equal("RequirementsNotSatisfiable"))
+		Expect(cond.Message).To(ContainSubstring(corev1.LabelInstanceTypeStable))
+		Expect(cond.Message).ToNot(ContainSubstring(karpv1.CapacityTypeLabelKey))
+	})
+	It("should report a zone that the EC2NodeClass has no subnets in", func() {
+		nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+			requirement(corev1.LabelTopologyZone, "test-zone-1z"),
+		}
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		cond := nodePool.StatusConditions().Get(v1.ConditionTypeSatisfiable)
+		Expect(cond.IsFalse()).To(BeTrue())
+		Expect(cond.Message).To(ContainSubstring(corev1.LabelTopologyZone))
+	})
+	It("should report every key when the conflict is between requirements", func() {
+		nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+			requirement(corev1.LabelInstanceTypeStable, "m5.large"),
+			requirement(corev1.LabelArchStable, karpv1.ArchitectureArm64),
+		}
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		cond := nodePool.StatusConditions().Get(v1.ConditionTypeSatisfiable)
+		Expect(cond.IsFalse()).To(BeTrue())
+		Expect(cond.Message).To(ContainSubstring(corev1.LabelInstanceTypeStable))
+		Expect(cond.Message).To(ContainSubstring(corev1.LabelArchStable))
+	})
+	It("should mark a NodePool as unsatisfiable when minValues can't be met", func() {
+		nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+			{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{
+					Key:      corev1.LabelInstanceTypeStable,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{"m5.large", "m5.xlarge"},
+				},
+				MinValues: lo.ToPtr(3),
+			},
+		}
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		cond := nodePool.StatusConditions().Get(v1.ConditionTypeSatisfiable)
+		Expect(cond.IsFalse()).To(BeTrue())
+		Expect(cond.Message).To(ContainSubstring("minValues"))
+	})
+	It("should clear the condition while the EC2NodeClass isn't ready", func() {
+		nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+			requirement(corev1.LabelInstanceTypeStable, "m99.large"),
+		}
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.StatusConditions().Get(v1.ConditionTypeSatisfiable).IsFalse()).To(BeTrue())
+
+		nodeClass.StatusConditions().SetFalse(status.ConditionReady, "NotReady", "NotReady")
+		ExpectApplied(ctx, env.Client, nodeClass)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.StatusConditions().Get(v1.ConditionTypeSatisfiable)).To(BeNil())
+	})
+	It("should ignore offering availability", func() {
+		nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
+			requirement(corev1.LabelInstanceTypeStable, "m5.large"),
+			requirement(karpv1.CapacityTypeLabelKey, karpv1.CapacityTypeSpot),
+			requirement(corev1.LabelTopologyZone, "test-zone-1a"),
+		}
+		awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "m5.large", "test-zone-1a", karpv1.CapacityTypeSpot)
+		ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+		ExpectObjectReconciled(ctx, env.Client, controller, nodePool)
+		nodePool = ExpectExists(ctx, env.Client, nodePool)
+		Expect(nodePool.StatusConditions().Get(v1.ConditionTypeSatisfiable).IsTrue()).To(BeTrue())
+	})
+})
@@ -0,0 +1,55 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package carbonintensity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/carbonintensity"
+)
+
+type Controller struct {
+	carbonIntensityProvider carbonintensity.Provider
+}
+
+func NewController(carbonIntensityProvider carbonintensity.Provider) *Controller {
+	return &Controller{
+		carbonIntensityProvider: carbonIntensityProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.carbonintensity")
+
+	if err := c.carbonIntensityProvider.UpdateIntensities(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("updating carbon intensities, %w", err)
+	}
+	return reconcile.Result{RequeueAfter: 15 * time.Minute}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.carbonintensity").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -80,7 +80,7 @@ var _ = BeforeSuite(func() {
 	nodeClaim = coretest.NodeClaim()
 	node = coretest.Node()
 	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
 	controller = controllersinstancetypecapacity.NewController(env.Client, cloudProvider, awsEnv.InstanceTypesProvider)
 })
 
@@ -174,4 +174,44 @@ var _ = Describe("CapacityCache", func() {
 		mem.Sub(resource.MustParse(fmt.Sprintf("%dMi", int64(math.Ceil(float64(mem.Value())*options.FromContext(ctx).VMMemoryOverheadPercent/1024/1024)))))
 		Expect(i.Capacity.Memory().Value()).To(Equal(mem.Value()), "Expected capacity to match VMMemoryOverheadPercent calculation")
 	})
+	Context("Memory Overhead Calibration", func() {
+		BeforeEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				VMMemoryOverheadPercent: lo.ToPtr[float64](0.075),
+				AWSFeatureGates:         options.FeatureGates{options.MemoryOverheadCalibration: true},
+			}))
+		})
+		AfterEach(func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				VMMemoryOverheadPercent: lo.ToPtr[float64](0.075),
+			}))
+		})
+		It("should use the calibrated overhead after AMI update", func() {
+			ExpectObjectReconciled(ctx, env.Client, controller, node)
+			Expect(awsEnv.InstanceTypesProvider.MemoryOverheads()).To(HaveKeyWithValue("t3.medium", BeNumerically("~", 1-3840.0/8192.0)))
+
+			nodeClass.Status.AMIs[0].ID = "ami-new-test-id"
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).To(BeNil())
+			i, ok := lo.Find(instanceTypes, func(i *karpcloudprovider.InstanceType) bool {
+				return i.Name == "t3.medium"
+			})
+			Expect(ok).To(BeTrue())
+			Expect(i.Capacity.Memory().Value()).To(Equal(node.Status.Capacity.Memory().Value()), "Expected capacity to match calibrated overhead")
+		})
+		It("should keep the largest overhead observed", func() {
+			ExpectObjectReconciled(ctx, env.Client, controller, node)
+			node.Status.Capacity[corev1.ResourceMemory] = resource.MustParse("7680Mi")
+			ExpectApplied(ctx, env.Client, node)
+			ExpectObjectReconciled(ctx, env.Client, controller, node)
+			Expect(awsEnv.InstanceTypesProvider.MemoryOverheads()).To(HaveKeyWithValue("t3.medium", BeNumerically("~", 1-3840.0/8192.0)))
+		})
+		It("should not calibrate the overhead when the feature gate is disabled", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+				VMMemoryOverheadPercent: lo.ToPtr[float64](0.075),
+			}))
+			ExpectObjectReconciled(ctx, env.Client, controller, node)
+			Expect(awsEnv.InstanceTypesProvider.MemoryOverheads()).To(BeEmpty())
+		})
+	})
 })
@@ -0,0 +1,114 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memoryoverhead
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+)
+
+const (
+	// ConfigMapName is the ConfigMap in the controller's namespace that the calibrated VM memory overheads are persisted
+	// to, keyed by instance type, so that they survive controller restarts
+	ConfigMapName = "karpenter-memory-overhead"
+	// syncInterval is how often the calibrated overheads are persisted
+	syncInterval = 5 * time.Minute
+)
+
+// Controller persists the VM memory overheads that the instance type provider calibrates from registered nodes. The
+// persisted overheads are loaded into the provider on the first reconcile, before any are written.
+type Controller struct {
+	kubernetesInterface  kubernetes.Interface
+	namespace            string
+	instanceTypeProvider *instancetype.DefaultProvider
+	loaded               bool
+}
+
+func NewController(kubernetesInterface kubernetes.Interface, namespace string, instanceTypeProvider *instancetype.DefaultProvider) *Controller {
+	return &Controller{
+		kubernetesInterface:  kubernetesInterface,
+		namespace:            namespace,
+		instanceTypeProvider: instanceTypeProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.instancetype.memoryoverhead")
+
+	configMap, err := c.kubernetesInterface.CoreV1().ConfigMaps(c.namespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, fmt.Errorf("getting configmap, %w", err)
+	}
+	found := err == nil
+	if found && !c.loaded {
+		overheads := map[string]float64{}
+		for instanceTypeName, value := range configMap.Data {
+			overhead, err := strconv.ParseFloat(value, 64)
+			if err != nil || overhead < 0 || overhead >= 1 {
+				log.FromContext(ctx).WithValues("instance-type", instanceTypeName, "value", value).Info("ignoring invalid memory overhead")
+				continue
+			}
+			overheads[instanceTypeName] = overhead
+		}
+		c.instanceTypeProvider.SetMemoryOverheads(overheads)
+		log.FromContext(ctx).WithValues("count", len(overheads)).V(1).Info("loaded calibrated vm memory overheads")
+	}
+	c.loaded = true
+
+	data := map[string]string{}
+	for instanceTypeName, overhead := range c.instanceTypeProvider.MemoryOverheads() {
+		data[instanceTypeName] = strconv.FormatFloat(overhead, 'f', -1, 64)
+	}
+	if !found {
+		if len(data) == 0 {
+			return reconcile.Result{RequeueAfter: syncInterval}, nil
+		}
+		if _, err = c.kubernetesInterface.CoreV1().ConfigMaps(c.namespace).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: c.namespace},
+			Data:       data,
+		}, metav1.CreateOptions{}); err != nil {
+			return reconcile.Result{}, fmt.Errorf("creating configmap, %w", err)
+		}
+	} else if !equality.Semantic.DeepEqual(configMap.Data, data) {
+		configMap.Data = data
+		if _, err = c.kubernetesInterface.CoreV1().ConfigMaps(c.namespace).Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+			return reconcile.Result{}, fmt.Errorf("updating configmap, %w", err)
+		}
+	}
+	return reconcile.Result{RequeueAfter: syncInterval}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.instancetype.memoryoverhead").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,121 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package memoryoverhead_test
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	controllersmemoryoverhead "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype/memoryoverhead"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+const namespace = "kube-system"
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *controllersmemoryoverhead.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MemoryOverhead")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+		AWSFeatureGates: options.FeatureGates{options.MemoryOverheadCalibration: true},
+	}))
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	controller = controllersmemoryoverhead.NewController(env.KubernetesInterface, namespace, awsEnv.InstanceTypesProvider)
+})
+
+var _ = AfterEach(func() {
+	Expect(env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Delete(ctx, controllersmemoryoverhead.ConfigMapName, metav1.DeleteOptions{})).To(Or(Succeed(), MatchError(ContainSubstring("not found"))))
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func expectConfigMap() *corev1.ConfigMap {
+	GinkgoHelper()
+	configMap, err := env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Get(ctx, controllersmemoryoverhead.ConfigMapName, metav1.GetOptions{})
+	Expect(err).ToNot(HaveOccurred())
+	return configMap
+}
+
+func createConfigMap(data map[string]string) {
+	GinkgoHelper()
+	_, err := env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: controllersmemoryoverhead.ConfigMapName, Namespace: namespace},
+		Data:       data,
+	}, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred())
+}
+
+var _ = Describe("MemoryOverhead", func() {
+	It("should not create the configmap before any overheads are calibrated", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		_, err := env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Get(ctx, controllersmemoryoverhead.ConfigMapName, metav1.GetOptions{})
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+	})
+	It("should persist calibrated overheads to the configmap", func() {
+		awsEnv.InstanceTypesProvider.SetMemoryOverheads(map[string]float64{"m5.large": 0.05, "r5.24xlarge": 0.03})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data).To(Equal(map[string]string{"m5.large": "0.05", "r5.24xlarge": "0.03"}))
+
+		awsEnv.InstanceTypesProvider.SetMemoryOverheads(map[string]float64{"m5.large": 0.06})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(expectConfigMap().Data).To(HaveKeyWithValue("m5.large", "0.06"))
+	})
+	It("should load persisted overheads before writing", func() {
+		createConfigMap(map[string]string{"m5.large": "0.05", "r5.24xlarge": "0.03"})
+		awsEnv.InstanceTypesProvider.SetMemoryOverheads(map[string]float64{"m5.large": 0.04, "c5.large": 0.06})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.InstanceTypesProvider.MemoryOverheads()).To(Equal(map[string]float64{"m5.large": 0.05, "r5.24xlarge": 0.03, "c5.large": 0.06}))
+		Expect(expectConfigMap().Data).To(Equal(map[string]string{"m5.large": "0.05", "r5.24xlarge": "0.03", "c5.large": "0.06"}))
+	})
+	It("should ignore invalid persisted overheads", func() {
+		createConfigMap(map[string]string{"m5.large": "invalid", "c5.large": "1.5", "r5.24xlarge": "0.03"})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.InstanceTypesProvider.MemoryOverheads()).To(Equal(map[string]float64{"r5.24xlarge": 0.03}))
+		Expect(expectConfigMap().Data).To(Equal(map[string]string{"r5.24xlarge": "0.03"}))
+	})
+})
@@ -0,0 +1,90 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package performance
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+)
+
+const (
+	// ConfigMapName is the ConfigMap in the controller's namespace that operators provide performance multipliers in, keyed
+	// by instance type or instance family
+	ConfigMapName = "karpenter-performance-multipliers"
+	// pollInterval is how often the ConfigMap is read
+	pollInterval = time.Minute
+)
+
+// Controller loads the performance multipliers from the ConfigMap into the instance type provider. The multipliers are
+// cleared if the ConfigMap is deleted.
+type Controller struct {
+	kubernetesInterface  kubernetes.Interface
+	namespace            string
+	instanceTypeProvider *instancetype.DefaultProvider
+}
+
+func NewController(kubernetesInterface kubernetes.Interface, namespace string, instanceTypeProvider *instancetype.DefaultProvider) *Controller {
+	return &Controller{
+		kubernetesInterface:  kubernetesInterface,
+		namespace:            namespace,
+		instanceTypeProvider: instanceTypeProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.instancetype.performance")
+
+	configMap, err := c.kubernetesInterface.CoreV1().ConfigMaps(c.namespace).Get(ctx, ConfigMapName, metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return reconcile.Result{}, fmt.Errorf("getting configmap, %w", err)
+	}
+	multipliers := map[string]float64{}
+	if err == nil {
+		for key, value := range configMap.Data {
+			multiplier, err := strconv.ParseFloat(value, 64)
+			if err != nil || multiplier <= 0 || math.IsInf(multiplier, 0) || math.IsNaN(multiplier) {
+				log.FromContext(ctx).WithValues("instance-type", key, "value", value).Info("ignoring invalid performance multiplier")
+				continue
+			}
+			multipliers[key] = multiplier
+		}
+	}
+	if c.instanceTypeProvider.SetPerformanceMultipliers(multipliers) {
+		log.FromContext(ctx).WithValues("count", len(multipliers)).V(1).Info("updated instance type performance multipliers")
+	}
+	return reconcile.Result{RequeueAfter: pollInterval}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.instancetype.performance").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
@@ -0,0 +1,107 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package performance_test
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	controllersperformance "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/instancetype/performance"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+const namespace = "kube-system"
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *controllersperformance.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Performance")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+	controller = controllersperformance.NewController(env.KubernetesInterface, namespace, awsEnv.InstanceTypesProvider)
+})
+
+var _ = AfterEach(func() {
+	Expect(env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Delete(ctx, controllersperformance.ConfigMapName, metav1.DeleteOptions{})).To(Or(Succeed(), MatchError(ContainSubstring("not found"))))
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func createConfigMap(data map[string]string) {
+	GinkgoHelper()
+	_, err := env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: controllersperformance.ConfigMapName, Namespace: namespace},
+		Data:       data,
+	}, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred())
+}
+
+var _ = Describe("Performance", func() {
+	It("should not set multipliers when the configmap doesn't exist", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.InstanceTypesProvider.PerformanceMultipliers()).To(BeEmpty())
+	})
+	It("should load multipliers from the configmap", func() {
+		createConfigMap(map[string]string{"m7g": "1.25", "c5.large": "0.9"})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.InstanceTypesProvider.PerformanceMultipliers()).To(Equal(map[string]float64{"m7g": 1.25, "c5.large": 0.9}))
+	})
+	It("should ignore invalid multipliers", func() {
+		createConfigMap(map[string]string{"m7g": "invalid", "c5.large": "0", "r5.large": "-1", "m5": "1.1"})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.InstanceTypesProvider.PerformanceMultipliers()).To(Equal(map[string]float64{"m5": 1.1}))
+	})
+	It("should clear multipliers when the configmap is deleted", func() {
+		createConfigMap(map[string]string{"m7g": "1.25"})
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.InstanceTypesProvider.PerformanceMultipliers()).To(HaveLen(1))
+		Expect(env.KubernetesInterface.CoreV1().ConfigMaps(namespace).Delete(ctx, controllersperformance.ConfigMapName, metav1.DeleteOptions{})).To(Succeed())
+		ExpectSingletonReconciled(ctx, controller)
+		Expect(awsEnv.InstanceTypesProvider.PerformanceMultipliers()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,186 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	controllerslaunchtemplate "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *controllerslaunchtemplate.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LaunchTemplate")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = controllerslaunchtemplate.NewController(awsEnv.LaunchTemplateProvider)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{LaunchTemplateGCTTL: lo.ToPtr(24 * time.Hour)}))
+
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func storeLaunchTemplate(clusterName string, age time.Duration) ec2types.LaunchTemplate {
+	tags := map[string]string{}
+	if clusterName != "" {
+		tags = map[string]string{v1.EKSClusterNameTagKey: clusterName, v1.NodeClassTagKey: "default"}
+	}
+	return storeNamedLaunchTemplate(fmt.Sprintf("%s/%s", v1.LaunchTemplateNamePrefix, coretest.RandomName()), tags, age)
+}
+
+func storeNamedLaunchTemplate(name string, tags map[string]string, age time.Duration) ec2types.LaunchTemplate {
+	lt := ec2types.LaunchTemplate{
+		LaunchTemplateName: aws.String(name),
+		LaunchTemplateId:   aws.String(fake.LaunchTemplateID()),
+		CreateTime:         aws.Time(awsEnv.Clock.Now().Add(-age)),
+		Tags: lo.MapToSlice(tags, func(k, v string) ec2types.Tag {
+			return ec2types.Tag{Key: aws.String(k), Value: aws.String(v)}
+		}),
+	}
+	awsEnv.EC2API.LaunchTemplates.Store(lt.LaunchTemplateName, lt)
+	return lt
+}
+
+func ExpectLaunchTemplateExists(lt ec2types.LaunchTemplate, exists bool) {
+	GinkgoHelper()
+	_, ok := awsEnv.EC2API.LaunchTemplates.Load(lt.LaunchTemplateName)
+	Expect(ok).To(Equal(exists), aws.ToString(lt.LaunchTemplateName))
+}
+
+var _ = Describe("LaunchTemplate Garbage Collection", func() {
+	It("should delete launch templates for the cluster which are older than the ttl", func() {
+		lt := storeLaunchTemplate("test-cluster", 25*time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(lt, false)
+		ExpectMetricCounterValue(launchtemplate.LaunchTemplatesGarbageCollected, 1, map[string]string{})
+	})
+	It("should not delete launch templates which are newer than the ttl", func() {
+		lt := storeLaunchTemplate("test-cluster", time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(lt, true)
+	})
+	It("should delete launch templates once they become older than the ttl", func() {
+		lt := storeLaunchTemplate("test-cluster", 23*time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(lt, true)
+
+		awsEnv.Clock.Step(2 * time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(lt, false)
+	})
+	It("should not delete launch templates for other clusters", func() {
+		other := storeLaunchTemplate("other-cluster", 25*time.Hour)
+		untagged := storeLaunchTemplate("", 25*time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(other, true)
+		ExpectLaunchTemplateExists(untagged, true)
+	})
+	It("should not delete launch templates for the cluster which weren't created by Karpenter", func() {
+		untagged := storeNamedLaunchTemplate(fmt.Sprintf("%s/%s", v1.LaunchTemplateNamePrefix, coretest.RandomName()),
+			map[string]string{v1.EKSClusterNameTagKey: "test-cluster"}, 25*time.Hour)
+		unprefixed := storeNamedLaunchTemplate(coretest.RandomName(),
+			map[string]string{v1.EKSClusterNameTagKey: "test-cluster", v1.NodeClassTagKey: "default"}, 25*time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(untagged, true)
+		ExpectLaunchTemplateExists(unprefixed, true)
+	})
+	It("should delete launch templates named with the resource name prefix", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{LaunchTemplateGCTTL: lo.ToPtr(24 * time.Hour), ResourceNamePrefix: lo.ToPtr("acme-")}))
+		lt := storeNamedLaunchTemplate(fmt.Sprintf("acme-%s/%s", v1.LaunchTemplateNamePrefix, coretest.RandomName()),
+			map[string]string{v1.EKSClusterNameTagKey: "test-cluster", v1.NodeClassTagKey: "default"}, 25*time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(lt, false)
+	})
+	It("should not delete launch templates which are in the launch template cache", func() {
+		lt := storeLaunchTemplate("test-cluster", 25*time.Hour)
+		awsEnv.LaunchTemplateCache.SetDefault(aws.ToString(lt.LaunchTemplateName), lt)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(lt, true)
+	})
+	It("should not delete launch templates which running instances were launched from", func() {
+		lt := storeLaunchTemplate("test-cluster", 25*time.Hour)
+		awsEnv.EC2API.Instances.Store("i-1", ec2types.Instance{
+			InstanceId: aws.String("i-1"),
+			State:      &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			Tags: []ec2types.Tag{
+				{Key: aws.String(v1.EKSClusterNameTagKey), Value: aws.String("test-cluster")},
+				{Key: aws.String("aws:ec2launchtemplate:id"), Value: lt.LaunchTemplateId},
+			},
+		})
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(lt, true)
+	})
+	It("should not delete launch templates when garbage collection is disabled", func() {
+		ctx = options.ToContext(ctx, test.Options())
+		lt := storeLaunchTemplate("test-cluster", 25*time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectLaunchTemplateExists(lt, true)
+	})
+	It("should report the launch templates in the region against the quota", func() {
+		storeLaunchTemplate("test-cluster", time.Hour)
+		storeLaunchTemplate("other-cluster", time.Hour)
+		storeLaunchTemplate("", time.Hour)
+		storeLaunchTemplate("", time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectMetricGaugeValue(launchtemplate.LaunchTemplateQuotaUsage, 4, map[string]string{})
+		ExpectMetricGaugeValue(launchtemplate.LaunchTemplateQuotaUtilization, 4.0/launchtemplate.LaunchTemplateQuota, map[string]string{})
+	})
+})
@@ -0,0 +1,183 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leakedresource_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	controllersleakedresource "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/leakedresource"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/leakedresource"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *controllersleakedresource.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LeakedResource")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = controllersleakedresource.NewController(awsEnv.LeakedResourceProvider)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{LeakedResourceGCTTL: lo.ToPtr(time.Hour)}))
+
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func tags(kvs ...string) []ec2types.Tag {
+	return lo.Map(lo.Chunk(kvs, 2), func(kv []string, _ int) ec2types.Tag {
+		return ec2types.Tag{Key: aws.String(kv[0]), Value: aws.String(kv[1])}
+	})
+}
+
+func storeNetworkInterface(status ec2types.NetworkInterfaceStatus, tags []ec2types.Tag) string {
+	id := "eni-" + coretest.RandomName()
+	awsEnv.EC2API.NetworkInterfaces.Store(id, ec2types.NetworkInterface{NetworkInterfaceId: aws.String(id), Status: status, TagSet: tags})
+	return id
+}
+
+func storeVolume(state ec2types.VolumeState, tags []ec2types.Tag) string {
+	id := "vol-" + coretest.RandomName()
+	awsEnv.EC2API.Volumes.Store(id, ec2types.Volume{VolumeId: aws.String(id), State: state, Tags: tags})
+	return id
+}
+
+func ExpectNetworkInterfaceExists(id string, exists bool) {
+	GinkgoHelper()
+	_, ok := awsEnv.EC2API.NetworkInterfaces.Load(id)
+	Expect(ok).To(Equal(exists), id)
+}
+
+func ExpectVolumeExists(id string, exists bool) {
+	GinkgoHelper()
+	_, ok := awsEnv.EC2API.Volumes.Load(id)
+	Expect(ok).To(Equal(exists), id)
+}
+
+// ExpectReconciledAfterTTL reconciles once to observe the detached resources, then again once they've been detached for the TTL
+func ExpectReconciledAfterTTL() {
+	GinkgoHelper()
+	ExpectSingletonReconciled(ctx, controller)
+	awsEnv.Clock.Step(time.Hour)
+	ExpectSingletonReconciled(ctx, controller)
+}
+
+var _ = Describe("Leaked Resource Garbage Collection", func() {
+	It("should delete detached network interfaces for the cluster once they've been detached for the ttl", func() {
+		cni := storeNetworkInterface(ec2types.NetworkInterfaceStatusAvailable, tags(leakedresource.VPCCNIClusterNameTagKey, "test-cluster"))
+		karpenter := storeNetworkInterface(ec2types.NetworkInterfaceStatusAvailable, tags(v1.EKSClusterNameTagKey, "test-cluster"))
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectNetworkInterfaceExists(cni, true)
+		ExpectNetworkInterfaceExists(karpenter, true)
+		ExpectMetricGaugeValue(leakedresource.LeakedResources, 2, map[string]string{"resource_type": "network-interface"})
+
+		awsEnv.Clock.Step(time.Hour)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectNetworkInterfaceExists(cni, false)
+		ExpectNetworkInterfaceExists(karpenter, false)
+		ExpectMetricCounterValue(leakedresource.LeakedResourcesGarbageCollected, 2, map[string]string{"resource_type": "network-interface"})
+	})
+	It("should not delete network interfaces which are attached or belong to other clusters", func() {
+		attached := storeNetworkInterface(ec2types.NetworkInterfaceStatusInUse, tags(leakedresource.VPCCNIClusterNameTagKey, "test-cluster"))
+		other := storeNetworkInterface(ec2types.NetworkInterfaceStatusAvailable, tags(leakedresource.VPCCNIClusterNameTagKey, "other-cluster"))
+		untagged := storeNetworkInterface(ec2types.NetworkInterfaceStatusAvailable, nil)
+		ExpectReconciledAfterTTL()
+		ExpectNetworkInterfaceExists(attached, true)
+		ExpectNetworkInterfaceExists(other, true)
+		ExpectNetworkInterfaceExists(untagged, true)
+	})
+	It("should measure the ttl from when a resource was last seen detached", func() {
+		id := storeNetworkInterface(ec2types.NetworkInterfaceStatusAvailable, tags(leakedresource.VPCCNIClusterNameTagKey, "test-cluster"))
+		ExpectSingletonReconciled(ctx, controller)
+		awsEnv.Clock.Step(30 * time.Minute)
+		// The network interface is reattached, and then detached again
+		awsEnv.EC2API.NetworkInterfaces.Store(id, ec2types.NetworkInterface{NetworkInterfaceId: aws.String(id), Status: ec2types.NetworkInterfaceStatusInUse})
+		ExpectSingletonReconciled(ctx, controller)
+		awsEnv.EC2API.NetworkInterfaces.Store(id, ec2types.NetworkInterface{NetworkInterfaceId: aws.String(id),
+			Status: ec2types.NetworkInterfaceStatusAvailable, TagSet: tags(leakedresource.VPCCNIClusterNameTagKey, "test-cluster")})
+		ExpectSingletonReconciled(ctx, controller)
+		awsEnv.Clock.Step(30 * time.Minute)
+		ExpectSingletonReconciled(ctx, controller)
+		ExpectNetworkInterfaceExists(id, true)
+	})
+	It("should delete detached volumes launched by Karpenter for the cluster", func() {
+		id := storeVolume(ec2types.VolumeStateAvailable, tags(v1.EKSClusterNameTagKey, "test-cluster", karpv1.NodePoolLabelKey, "default"))
+		ExpectReconciledAfterTTL()
+		ExpectVolumeExists(id, false)
+		ExpectMetricCounterValue(leakedresource.LeakedResourcesGarbageCollected, 1, map[string]string{"resource_type": "volume"})
+	})
+	It("should not delete volumes which are attached, weren't launched by Karpenter or back a PersistentVolumeClaim", func() {
+		attached := storeVolume(ec2types.VolumeStateInUse, tags(v1.EKSClusterNameTagKey, "test-cluster", karpv1.NodePoolLabelKey, "default"))
+		other := storeVolume(ec2types.VolumeStateAvailable, tags(v1.EKSClusterNameTagKey, "other-cluster", karpv1.NodePoolLabelKey, "default"))
+		unmanaged := storeVolume(ec2types.VolumeStateAvailable, tags(v1.EKSClusterNameTagKey, "test-cluster"))
+		pvc := storeVolume(ec2types.VolumeStateAvailable, tags(v1.EKSClusterNameTagKey, "test-cluster", karpv1.NodePoolLabelKey, "default",
+			"kubernetes.io/created-for/pvc/name", "data"))
+		ExpectReconciledAfterTTL()
+		ExpectVolumeExists(attached, true)
+		ExpectVolumeExists(other, true)
+		ExpectVolumeExists(unmanaged, true)
+		ExpectVolumeExists(pvc, true)
+	})
+	It("should not delete leaked resources in dry run", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{LeakedResourceGCTTL: lo.ToPtr(time.Hour), LeakedResourceGCDryRun: lo.ToPtr(true)}))
+		eni := storeNetworkInterface(ec2types.NetworkInterfaceStatusAvailable, tags(leakedresource.VPCCNIClusterNameTagKey, "test-cluster"))
+		volume := storeVolume(ec2types.VolumeStateAvailable, tags(v1.EKSClusterNameTagKey, "test-cluster", karpv1.NodePoolLabelKey, "default"))
+		ExpectReconciledAfterTTL()
+		ExpectNetworkInterfaceExists(eni, true)
+		ExpectVolumeExists(volume, true)
+		ExpectMetricGaugeValue(leakedresource.LeakedResources, 1, map[string]string{"resource_type": "volume"})
+	})
+})
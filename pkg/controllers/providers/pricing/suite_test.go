@@ -330,4 +330,23 @@ var _ = Describe("Pricing", func() {
 		Expect(ok).To(BeTrue())
 		Expect(price).To(BeNumerically("==", 1.23))
 	})
+	It("should return static on-demand data when in the GovCloud (US) partition", func() {
+		tmpPricingProvider := pricing.NewDefaultProvider(ctx, awsEnv.PricingAPI, awsEnv.EC2API, "us-gov-west-1")
+		tmpController := controllerspricing.NewController(tmpPricingProvider)
+
+		awsEnv.PricingAPI.GetProductsOutput.Set(&awspricing.GetProductsOutput{
+			// these are incorrect prices which are here to ensure that
+			// results from only static pricing are used
+			PriceList: []string{
+				fake.NewOnDemandPrice("c5.large", 1.20),
+			},
+		})
+		ExpectSingletonReconciled(ctx, tmpController)
+
+		price, ok := tmpPricingProvider.OnDemandPrice("c5.large")
+		Expect(ok).To(BeTrue())
+		Expect(price).To(BeNumerically("==", pricing.InitialOnDemandPricesUSGov["us-gov-west-1"]["c5.large"]))
+		onDemandUpdated, _ := tmpPricingProvider.LastUpdated()
+		Expect(onDemandUpdated.IsZero()).To(BeTrue())
+	})
 })
@@ -0,0 +1,61 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
+)
+
+// syncInterval is how often the launches of the other clusters are read from the capacity ledger. It's much shorter than
+// the interval that running instances are described at, since it's how quickly one cluster sees another's launches.
+const syncInterval = 15 * time.Second
+
+// Controller refreshes the launches that the other clusters in the account have recorded in the capacity ledger, so that
+// they're counted against the vCPU quotas before they're seen among the account's running instances
+type Controller struct {
+	quotaProvider quota.Provider
+}
+
+func NewController(quotaProvider quota.Provider) *Controller {
+	return &Controller{
+		quotaProvider: quotaProvider,
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.quota.ledger")
+
+	if err := c.quotaProvider.SyncLedger(ctx); err != nil {
+		return reconcile.Result{}, fmt.Errorf("syncing capacity ledger, %w", err)
+	}
+	return reconcile.Result{RequeueAfter: syncInterval}, nil
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.quota.ledger").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
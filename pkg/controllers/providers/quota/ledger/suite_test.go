@@ -0,0 +1,136 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ledger_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clock "k8s.io/utils/clock/testing"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	controllersledger "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/quota/ledger"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+const namespace = "karpenter"
+
+var ctx context.Context
+var fakeClock *clock.FakeClock
+var hub *kubefake.Clientset
+var serviceQuotasAPI *fake.ServiceQuotasAPI
+var providerA, providerB *quota.DefaultProvider
+var controller *controllersledger.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "CapacityLedger")
+}
+
+var _ = BeforeEach(func() {
+	fakeClock = clock.NewFakeClock(time.Now())
+	hub = kubefake.NewSimpleClientset()
+	serviceQuotasAPI = &fake.ServiceQuotasAPI{}
+	serviceQuotasAPI.Quotas.Store("L-1216C47A", 64.0)
+	ec2api := fake.NewEC2API()
+	providerA = quota.NewDefaultProvider(fakeClock, ec2api, serviceQuotasAPI, quota.NewConfigMapLedger(hub, namespace, "cluster-a", fakeClock))
+	providerB = quota.NewDefaultProvider(fakeClock, ec2api, serviceQuotasAPI, quota.NewConfigMapLedger(hub, namespace, "cluster-b", fakeClock))
+	Expect(providerA.UpdateQuotas(ctx)).To(Succeed())
+	Expect(providerB.UpdateQuotas(ctx)).To(Succeed())
+	controller = controllersledger.NewController(providerB)
+})
+
+func expectRemaining(provider *quota.DefaultProvider, expected float64) {
+	GinkgoHelper()
+	remaining, ok := provider.Remaining("m5.large", karpv1.CapacityTypeOnDemand)
+	Expect(ok).To(BeTrue())
+	Expect(remaining).To(BeNumerically("==", expected))
+}
+
+var _ = Describe("CapacityLedger", func() {
+	It("should count the launches of other clusters once the ledger is synced", func() {
+		providerA.RecordLaunch(ctx, "m5.xlarge", karpv1.CapacityTypeOnDemand, 4)
+		expectRemaining(providerA, 60)
+		expectRemaining(providerB, 64)
+
+		seqNum := providerB.SeqNum()
+		ExpectSingletonReconciled(ctx, controller)
+		expectRemaining(providerB, 60)
+		Expect(providerB.SeqNum()).To(BeNumerically(">", seqNum))
+	})
+	It("should return the launches of every cluster when recording a launch", func() {
+		providerA.RecordLaunch(ctx, "m5.xlarge", karpv1.CapacityTypeOnDemand, 4)
+		providerB.RecordLaunch(ctx, "m5.2xlarge", karpv1.CapacityTypeOnDemand, 8)
+		expectRemaining(providerB, 52)
+
+		configMap, err := hub.CoreV1().ConfigMaps(namespace).Get(ctx, quota.LedgerConfigMapName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(configMap.Data).To(HaveKey("cluster-a"))
+		Expect(configMap.Data).To(HaveKey("cluster-b"))
+	})
+	It("should only count launches against their own quota", func() {
+		providerA.RecordLaunch(ctx, "m5.xlarge", karpv1.CapacityTypeSpot, 4)
+		providerA.RecordLaunch(ctx, "p3.8xlarge", karpv1.CapacityTypeOnDemand, 32)
+		ExpectSingletonReconciled(ctx, controller)
+		expectRemaining(providerB, 64)
+	})
+	It("should stop counting launches once running instances have been described again", func() {
+		providerA.RecordLaunch(ctx, "m5.xlarge", karpv1.CapacityTypeOnDemand, 4)
+		ExpectSingletonReconciled(ctx, controller)
+		expectRemaining(providerB, 60)
+
+		fakeClock.Step(time.Minute)
+		Expect(providerB.UpdateQuotas(ctx)).To(Succeed())
+		expectRemaining(providerB, 64)
+	})
+	It("should drop launches from the ledger once they've expired", func() {
+		providerA.RecordLaunch(ctx, "m5.xlarge", karpv1.CapacityTypeOnDemand, 4)
+		fakeClock.Step(quota.LedgerTTL)
+		providerA.RecordLaunch(ctx, "m5.2xlarge", karpv1.CapacityTypeOnDemand, 8)
+
+		configMap, err := hub.CoreV1().ConfigMaps(namespace).Get(ctx, quota.LedgerConfigMapName, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		var launches []quota.Launch
+		Expect(json.Unmarshal([]byte(configMap.Data["cluster-a"]), &launches)).To(Succeed())
+		Expect(launches).To(HaveLen(1))
+		Expect(launches[0].VCPUs).To(BeNumerically("==", 8))
+	})
+	It("should ignore clusters with invalid ledger entries", func() {
+		_, err := hub.CoreV1().ConfigMaps(namespace).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: quota.LedgerConfigMapName, Namespace: namespace},
+			Data:       map[string]string{"cluster-c": "not-json"},
+		}, metav1.CreateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		providerA.RecordLaunch(ctx, "m5.xlarge", karpv1.CapacityTypeOnDemand, 4)
+		ExpectSingletonReconciled(ctx, controller)
+		expectRemaining(providerB, 60)
+	})
+	It("should not fail to sync before any launches have been recorded", func() {
+		ExpectSingletonReconciled(ctx, controller)
+		expectRemaining(providerB, 64)
+	})
+})
@@ -0,0 +1,177 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	controllersquota "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/quota"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var controller *controllersquota.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Quota")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+	controller = controllersquota.NewController(awsEnv.QuotaProvider)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{VCPUQuotaAwareness: lo.ToPtr(true)}))
+
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func runningInstance(id string, instanceType ec2types.InstanceType, lifecycle ec2types.InstanceLifecycleType, vcpus int32) ec2types.Instance {
+	return ec2types.Instance{
+		InstanceId:        lo.ToPtr(id),
+		InstanceType:      instanceType,
+		InstanceLifecycle: lifecycle,
+		State:             &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+		CpuOptions:        &ec2types.CpuOptions{CoreCount: lo.ToPtr(vcpus / 2), ThreadsPerCore: lo.ToPtr[int32](2)},
+	}
+}
+
+var _ = Describe("Quota", func() {
+	It("should not restrict launches before quotas are known", func() {
+		_, ok := awsEnv.QuotaProvider.Remaining("m5.large", karpv1.CapacityTypeOnDemand)
+		Expect(ok).To(BeFalse())
+	})
+	It("should compute remaining vCPUs from the quota and running instances", func() {
+		awsEnv.ServiceQuotasAPI.Quotas.Store("L-1216C47A", 64.0)
+		awsEnv.ServiceQuotasAPI.Quotas.Store("L-34B43A08", 32.0)
+		awsEnv.EC2API.Instances.Store("i-1", runningInstance("i-1", "m5.xlarge", "", 4))
+		awsEnv.EC2API.Instances.Store("i-2", runningInstance("i-2", "c6g.large", "", 2))
+		awsEnv.EC2API.Instances.Store("i-3", runningInstance("i-3", "t3.large", ec2types.InstanceLifecycleTypeSpot, 2))
+		awsEnv.EC2API.Instances.Store("i-4", runningInstance("i-4", "p3.8xlarge", "", 32))
+		ExpectSingletonReconciled(ctx, controller)
+
+		remaining, ok := awsEnv.QuotaProvider.Remaining("m5.large", karpv1.CapacityTypeOnDemand)
+		Expect(ok).To(BeTrue())
+		Expect(remaining).To(BeNumerically("==", 58))
+		remaining, ok = awsEnv.QuotaProvider.Remaining("m5.large", karpv1.CapacityTypeSpot)
+		Expect(ok).To(BeTrue())
+		Expect(remaining).To(BeNumerically("==", 30))
+		// No P quota is set, so the P instance shouldn't be restricted
+		_, ok = awsEnv.QuotaProvider.Remaining("p3.8xlarge", karpv1.CapacityTypeOnDemand)
+		Expect(ok).To(BeFalse())
+	})
+	It("should count launches against the quota until running instances are described again", func() {
+		awsEnv.ServiceQuotasAPI.Quotas.Store("L-1216C47A", 64.0)
+		ExpectSingletonReconciled(ctx, controller)
+
+		awsEnv.QuotaProvider.RecordLaunch(ctx, "m5.xlarge", karpv1.CapacityTypeOnDemand, 4)
+		remaining, ok := awsEnv.QuotaProvider.Remaining("m5.large", karpv1.CapacityTypeOnDemand)
+		Expect(ok).To(BeTrue())
+		Expect(remaining).To(BeNumerically("==", 60))
+
+		awsEnv.Clock.Step(time.Minute)
+		awsEnv.EC2API.Instances.Store("i-1", runningInstance("i-1", "m5.xlarge", "", 4))
+		ExpectSingletonReconciled(ctx, controller)
+		remaining, ok = awsEnv.QuotaProvider.Remaining("m5.large", karpv1.CapacityTypeOnDemand)
+		Expect(ok).To(BeTrue())
+		Expect(remaining).To(BeNumerically("==", 60))
+	})
+	It("should fail to reconcile when the Service Quotas API fails", func() {
+		awsEnv.ServiceQuotasAPI.GetServiceQuotaBehavior.Error.Set(fmt.Errorf("failed"))
+		_ = ExpectSingletonReconcileFailed(ctx, controller)
+		_, ok := awsEnv.QuotaProvider.Remaining("m5.large", karpv1.CapacityTypeOnDemand)
+		Expect(ok).To(BeFalse())
+	})
+	It("should mark offerings unavailable when launching would exceed the quota", func() {
+		awsEnv.ServiceQuotasAPI.Quotas.Store("L-1216C47A", 6.0)
+		awsEnv.EC2API.Instances.Store("i-1", runningInstance("i-1", "m5.large", "", 2))
+		ExpectSingletonReconciled(ctx, controller)
+
+		nodeClass := test.EC2NodeClass()
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		for name, expected := range map[string]bool{"m5.large": true, "m5.xlarge": true, "t3.large": true, "m5.metal": false} {
+			it, ok := lo.Find(instanceTypes, func(it *cloudprovider.InstanceType) bool { return it.Name == name })
+			Expect(ok).To(BeTrue())
+			onDemand := it.Offerings.Available().Compatible(scheduling.NewRequirements(
+				scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, karpv1.CapacityTypeOnDemand),
+			))
+			Expect(len(onDemand) > 0).To(Equal(expected), name)
+		}
+	})
+	DescribeTable("should resolve the quota class for an instance type",
+		func(instanceType string, expected quota.Class, expectedOK bool) {
+			class, ok := quota.ClassFor(ec2types.InstanceType(instanceType))
+			Expect(ok).To(Equal(expectedOK))
+			Expect(class).To(Equal(expected))
+		},
+		Entry("m5.large", "m5.large", quota.ClassStandard, true),
+		Entry("im4gn.large", "im4gn.large", quota.ClassStandard, true),
+		Entry("g4dn.8xlarge", "g4dn.8xlarge", quota.ClassG, true),
+		Entry("vt1.3xlarge", "vt1.3xlarge", quota.ClassG, true),
+		Entry("p3.8xlarge", "p3.8xlarge", quota.ClassP, true),
+		Entry("inf2.xlarge", "inf2.xlarge", quota.ClassInf, true),
+		Entry("trn1.2xlarge", "trn1.2xlarge", quota.ClassTrn, true),
+		Entry("dl1.24xlarge", "dl1.24xlarge", quota.ClassDL, true),
+		Entry("hpc7g.4xlarge", "hpc7g.4xlarge", quota.ClassHPC, true),
+		Entry("x2idn.16xlarge", "x2idn.16xlarge", quota.ClassX, true),
+		Entry("u-6tb1.metal", "u-6tb1.metal", quota.Class(""), false),
+		Entry("mac1.metal", "mac1.metal", quota.Class(""), false),
+	)
+})
@@ -0,0 +1,98 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonalshift
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/awslabs/operatorpkg/singleton"
+	"github.com/samber/lo"
+	controllerruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cache"
+)
+
+// Controller publishes events on the EC2NodeClasses that use an availability zone when launches are shifted away from
+// the zone, and again when the zone recovers. Zones are impaired and recovered by the unavailable offerings cache, so the
+// controller only reports on the shift.
+type Controller struct {
+	kubeClient           client.Client
+	recorder             events.Recorder
+	unavailableOfferings *cache.UnavailableOfferings
+
+	impairedZones map[string]time.Time
+}
+
+func NewController(kubeClient client.Client, recorder events.Recorder, unavailableOfferings *cache.UnavailableOfferings) *Controller {
+	return &Controller{
+		kubeClient:           kubeClient,
+		recorder:             recorder,
+		unavailableOfferings: unavailableOfferings,
+		impairedZones:        map[string]time.Time{},
+	}
+}
+
+func (c *Controller) Reconcile(ctx context.Context) (reconcile.Result, error) {
+	ctx = injection.WithControllerName(ctx, "providers.zonalshift")
+
+	impairedZones := c.unavailableOfferings.ImpairedZones()
+	shifted := lo.OmitByKeys(impairedZones, lo.Keys(c.impairedZones))
+	recovered := lo.OmitByKeys(c.impairedZones, lo.Keys(impairedZones))
+	if len(shifted) == 0 && len(recovered) == 0 {
+		return reconcile.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+	nodeClassList := &v1.EC2NodeClassList{}
+	if err := c.kubeClient.List(ctx, nodeClassList); err != nil {
+		return reconcile.Result{}, fmt.Errorf("listing nodeclasses, %w", err)
+	}
+	for zone, until := range shifted {
+		for i := range nodeClassList.Items {
+			if usesZone(&nodeClassList.Items[i], zone) {
+				c.recorder.Publish(ZonalShiftStartedEvent(&nodeClassList.Items[i], zone, until))
+			}
+		}
+	}
+	for zone := range recovered {
+		log.FromContext(ctx).WithValues("zone", zone).Info("zone recovered, launching into zone")
+		for i := range nodeClassList.Items {
+			if usesZone(&nodeClassList.Items[i], zone) {
+				c.recorder.Publish(ZonalShiftEndedEvent(&nodeClassList.Items[i], zone))
+			}
+		}
+	}
+	c.impairedZones = impairedZones
+	return reconcile.Result{RequeueAfter: 30 * time.Second}, nil
+}
+
+func usesZone(nodeClass *v1.EC2NodeClass, zone string) bool {
+	return lo.ContainsBy(nodeClass.Status.Subnets, func(s v1.Subnet) bool { return s.Zone == zone })
+}
+
+func (c *Controller) Register(_ context.Context, m manager.Manager) error {
+	return controllerruntime.NewControllerManagedBy(m).
+		Named("providers.zonalshift").
+		WatchesRawSource(singleton.Source()).
+		Complete(singleton.AsReconciler(c))
+}
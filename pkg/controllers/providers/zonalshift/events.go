@@ -0,0 +1,46 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonalshift
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"sigs.k8s.io/karpenter/pkg/events"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+func ZonalShiftStartedEvent(nodeClass *v1.EC2NodeClass, zone string, until time.Time) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           corev1.EventTypeWarning,
+		Reason:         "ZonalShiftStarted",
+		Message:        fmt.Sprintf("Shifting launches away from zone %s until %s, launch failures and interruptions are concentrated in the zone", zone, until.Format(time.RFC3339)),
+		DedupeValues:   []string{string(nodeClass.UID), zone},
+	}
+}
+
+func ZonalShiftEndedEvent(nodeClass *v1.EC2NodeClass, zone string) events.Event {
+	return events.Event{
+		InvolvedObject: nodeClass,
+		Type:           corev1.EventTypeNormal,
+		Reason:         "ZonalShiftEnded",
+		Message:        fmt.Sprintf("Launching into zone %s again", zone),
+		DedupeValues:   []string{string(nodeClass.UID), zone},
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package zonalshift_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/controllers/providers/zonalshift"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var recorder *record.FakeRecorder
+var controller *zonalshift.Controller
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ZonalShift")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...), coretest.WithCRDs(v1alpha1.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ZonalShift: lo.ToPtr(true)}))
+	awsEnv.Reset()
+	recorder = record.NewFakeRecorder(10)
+	controller = zonalshift.NewController(env.Client, events.NewRecorder(recorder), awsEnv.UnavailableOfferingsCache)
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func recordFailures(zone string, count int) {
+	for i := 0; i < count; i++ {
+		awsEnv.UnavailableOfferingsCache.RecordZoneFailure(ctx, "InsufficientCapacity", zone)
+	}
+}
+
+var _ = Describe("ZonalShift", func() {
+	Context("Impairment", func() {
+		It("should not impair a zone below the failure threshold", func() {
+			recordFailures("test-zone-1a", awscache.ZoneFailureThreshold-1)
+			Expect(awsEnv.UnavailableOfferingsCache.ImpairedZones()).To(BeEmpty())
+			Expect(awsEnv.UnavailableOfferingsCache.IsUnavailable("m5.large", "test-zone-1a", karpv1.CapacityTypeOnDemand)).To(BeFalse())
+		})
+		It("should make every offering in a zone unavailable once failures are concentrated in it", func() {
+			recordFailures("test-zone-1b", 1)
+			recordFailures("test-zone-1a", awscache.ZoneFailureThreshold)
+			Expect(awsEnv.UnavailableOfferingsCache.ImpairedZones()).To(HaveKey("test-zone-1a"))
+			for _, capacityType := range []string{karpv1.CapacityTypeOnDemand, karpv1.CapacityTypeSpot} {
+				Expect(awsEnv.UnavailableOfferingsCache.IsUnavailable("m5.large", "test-zone-1a", capacityType)).To(BeTrue())
+				Expect(awsEnv.UnavailableOfferingsCache.IsUnavailable("c5.xlarge", "test-zone-1a", capacityType)).To(BeTrue())
+				Expect(awsEnv.UnavailableOfferingsCache.IsUnavailable("m5.large", "test-zone-1b", capacityType)).To(BeFalse())
+			}
+			// Impaired zones aren't individual offerings, so they shouldn't be listed as such
+			Expect(awsEnv.UnavailableOfferingsCache.List()).To(BeEmpty())
+		})
+		It("should not impair a zone when failures are spread across zones", func() {
+			for i := 0; i < awscache.ZoneFailureThreshold; i++ {
+				recordFailures("test-zone-1a", 1)
+				recordFailures("test-zone-1b", 1)
+				recordFailures("test-zone-1c", 1)
+			}
+			Expect(awsEnv.UnavailableOfferingsCache.ImpairedZones()).To(BeEmpty())
+		})
+		It("should only impair one zone at a time", func() {
+			recordFailures("test-zone-1a", awscache.ZoneFailureThreshold)
+			recordFailures("test-zone-1b", awscache.ZoneFailureThreshold*2)
+			Expect(awsEnv.UnavailableOfferingsCache.ImpairedZones()).To(HaveLen(1))
+			Expect(awsEnv.UnavailableOfferingsCache.ImpairedZones()).To(HaveKey("test-zone-1a"))
+		})
+		It("should recover the zone once it's removed from the cache", func() {
+			recordFailures("test-zone-1a", awscache.ZoneFailureThreshold)
+			Expect(awsEnv.UnavailableOfferingsCache.ImpairedZones()).To(HaveKey("test-zone-1a"))
+			awsEnv.UnavailableOfferingsCache.Flush()
+			Expect(awsEnv.UnavailableOfferingsCache.IsUnavailable("m5.large", "test-zone-1a", karpv1.CapacityTypeOnDemand)).To(BeFalse())
+		})
+	})
+	Context("Events", func() {
+		var nodeClassA, nodeClassB *v1.EC2NodeClass
+		BeforeEach(func() {
+			nodeClassA = test.EC2NodeClass()
+			nodeClassB = test.EC2NodeClass()
+			ExpectApplied(ctx, env.Client, nodeClassA, nodeClassB)
+			nodeClassA.Status.Subnets = []v1.Subnet{{ID: "subnet-a", Zone: "test-zone-1a"}, {ID: "subnet-b", Zone: "test-zone-1b"}}
+			nodeClassB.Status.Subnets = []v1.Subnet{{ID: "subnet-b", Zone: "test-zone-1b"}}
+			ExpectApplied(ctx, env.Client, nodeClassA, nodeClassB)
+		})
+		It("should not publish events when no zones are impaired", func() {
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(recorder.Events).To(BeEmpty())
+		})
+		It("should publish events on the EC2NodeClasses using the zone when launches are shifted away from it and when it recovers", func() {
+			recordFailures("test-zone-1a", awscache.ZoneFailureThreshold)
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(recorder.Events).To(HaveLen(1))
+			Expect(<-recorder.Events).To(And(ContainSubstring("ZonalShiftStarted"), ContainSubstring("test-zone-1a")))
+
+			// The shift is only reported once
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(recorder.Events).To(BeEmpty())
+
+			awsEnv.UnavailableOfferingsCache.Flush()
+			ExpectSingletonReconciled(ctx, controller)
+			Expect(recorder.Events).To(HaveLen(1))
+			Expect(<-recorder.Events).To(And(ContainSubstring("ZonalShiftEnded"), ContainSubstring("test-zone-1a")))
+		})
+	})
+})
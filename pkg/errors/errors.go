@@ -16,6 +16,7 @@ package errors
 
 import (
 	"errors"
+	"strings"
 
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/smithy-go"
@@ -24,6 +25,7 @@ import (
 
 const (
 	launchTemplateNameNotFoundCode = "InvalidLaunchTemplateName.NotFoundException"
+	operationNotPermittedCode      = "OperationNotPermitted"
 )
 
 var (
@@ -34,9 +36,19 @@ var (
 		"InvalidLaunchTemplateId.NotFound",
 		"QueueDoesNotExist",
 		"NoSuchEntity",
+		"InvocationDoesNotExist",
+		"InvalidCapacityReservationId.NotFound",
+		"InvalidNetworkInterfaceID.NotFound",
+		"InvalidVolume.NotFound",
+		"ResourceNotFoundException",
 	)
 	alreadyExistsErrorCodes = sets.New[string](
 		"EntityAlreadyExists",
+		"ResourceInUseException",
+		"InvalidLaunchTemplateName.AlreadyExistsException",
+	)
+	accessDeniedErrorCodes = sets.New[string](
+		"AccessDeniedException",
 	)
 
 	// unfulfillableCapacityErrorCodes signify that capacity is temporarily unable to be launched
@@ -48,6 +60,11 @@ var (
 		"Unsupported",
 		"InsufficientFreeAddressesInSubnet",
 	)
+
+	// licenseLimitExceededErrorCodes signify that a License Manager license configuration with a hard limit has no licenses left
+	licenseLimitExceededErrorCodes = sets.New[string](
+		"LicenseUsageException",
+	)
 )
 
 // IsNotFound returns true if the err is an AWS error (even if it's
@@ -89,6 +106,19 @@ func IgnoreAlreadyExists(err error) error {
 	return err
 }
 
+// IsAccessDenied returns true if the err is an AWS error (even if it's
+// wrapped) and means that the caller isn't authorized to make the request
+func IsAccessDenied(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return accessDeniedErrorCodes.Has(apiErr.ErrorCode())
+	}
+	return false
+}
+
 // IsUnfulfillableCapacity returns true if the Fleet err means
 // capacity is temporarily unavailable for launching.
 // This could be due to account limits, insufficient ec2 capacity, etc.
@@ -96,6 +126,12 @@ func IsUnfulfillableCapacity(err ec2types.CreateFleetError) bool {
 	return unfulfillableCapacityErrorCodes.Has(*err.ErrorCode)
 }
 
+// IsLicenseLimitExceeded returns true if the Fleet err means that launching
+// would exceed the hard limit of a License Manager license configuration.
+func IsLicenseLimitExceeded(err ec2types.CreateFleetError) bool {
+	return licenseLimitExceededErrorCodes.Has(*err.ErrorCode)
+}
+
 func IsLaunchTemplateNotFound(err error) bool {
 	if err == nil {
 		return false
@@ -106,3 +142,16 @@ func IsLaunchTemplateNotFound(err error) bool {
 	}
 	return false
 }
+
+// IsTerminationProtected returns true if the err is from terminating an instance which has termination protection, i.e. the
+// disableApiTermination attribute, enabled
+func IsTerminationProtected(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode() == operationNotPermittedCode && strings.Contains(apiErr.ErrorMessage(), "disableApiTermination")
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+// labels are copied from the involved object onto the event's annotations under the same keys
+var labels = []string{
+	karpv1.NodePoolLabelKey,
+	corev1.LabelInstanceTypeStable,
+	karpv1.CapacityTypeLabelKey,
+	corev1.LabelTopologyZone,
+}
+
+// EventRecorder publishes every event with structured annotations describing the involved object, e.g. the instance
+// type, capacity type, zone and price of a NodeClaim, along with the event's reason. This covers the events that upstream
+// controllers publish as well, so consumers can rely on the annotations rather than parsing messages.
+type EventRecorder struct {
+	record.EventRecorder
+	pricingProvider pricing.Provider
+}
+
+func NewEventRecorder(eventRecorder record.EventRecorder, pricingProvider pricing.Provider) *EventRecorder {
+	return &EventRecorder{
+		EventRecorder:   eventRecorder,
+		pricingProvider: pricingProvider,
+	}
+}
+
+func (r *EventRecorder) Event(object runtime.Object, eventType, reason, message string) {
+	r.EventRecorder.AnnotatedEventf(object, r.Annotations(object, reason), eventType, reason, "%s", message)
+}
+
+func (r *EventRecorder) Eventf(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, r.Annotations(object, reason), eventType, reason, "%s", fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *EventRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventType, reason, messageFmt string, args ...interface{}) {
+	r.EventRecorder.AnnotatedEventf(object, lo.Assign(r.Annotations(object, reason), annotations), eventType, reason, "%s", fmt.Sprintf(messageFmt, args...))
+}
+
+// Annotations returns the structured annotations of an event with the passed reason involving the object
+func (r *EventRecorder) Annotations(object runtime.Object, reason string) map[string]string {
+	annotations := map[string]string{v1.EventAnnotationReason: reason}
+	if o, ok := object.(metav1.Object); ok {
+		for _, key := range labels {
+			if value, ok := o.GetLabels()[key]; ok {
+				annotations[key] = value
+			}
+		}
+	}
+	var providerID string
+	switch o := object.(type) {
+	case *karpv1.NodeClaim:
+		providerID = o.Status.ProviderID
+		if o.Status.NodeName != "" {
+			annotations[v1.EventAnnotationNode] = o.Status.NodeName
+		}
+		if conditions := lo.FilterMap(o.GetConditions(), func(c status.Condition, _ int) (string, bool) {
+			return c.Type, c.IsTrue()
+		}); len(conditions) > 0 {
+			sort.Strings(conditions)
+			annotations[v1.EventAnnotationConditions] = strings.Join(conditions, ",")
+		}
+	case *corev1.Node:
+		providerID = o.Spec.ProviderID
+		annotations[v1.EventAnnotationNode] = o.Name
+	case *corev1.Pod:
+		if o.Spec.NodeName != "" {
+			annotations[v1.EventAnnotationNode] = o.Spec.NodeName
+		}
+	}
+	if id, err := utils.ParseInstanceID(providerID); err == nil {
+		annotations[v1.EventAnnotationInstanceID] = id
+	}
+	if price, ok := r.price(annotations); ok {
+		annotations[v1.EventAnnotationPrice] = strconv.FormatFloat(price, 'f', -1, 64)
+	}
+	return annotations
+}
+
+// price returns the hourly price of the instance type, capacity type and zone in the annotations
+func (r *EventRecorder) price(annotations map[string]string) (float64, bool) {
+	instanceType, ok := annotations[corev1.LabelInstanceTypeStable]
+	if !ok {
+		return 0, false
+	}
+	switch annotations[karpv1.CapacityTypeLabelKey] {
+	case karpv1.CapacityTypeOnDemand:
+		return r.pricingProvider.OnDemandPrice(ec2types.InstanceType(instanceType))
+	case karpv1.CapacityTypeSpot:
+		return r.pricingProvider.SpotPrice(ec2types.InstanceType(instanceType), annotations[corev1.LabelTopologyZone])
+	}
+	return 0, false
+}
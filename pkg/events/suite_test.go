@@ -0,0 +1,151 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/awslabs/operatorpkg/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	coreevents "sigs.k8s.io/karpenter/pkg/events"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/events"
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var pricingProvider *pricing.DefaultProvider
+var eventRecorder *fakeEventRecorder
+var recorder coreevents.Recorder
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Events")
+}
+
+// fakeEventRecorder keeps the annotations of the last event that was published
+type fakeEventRecorder struct {
+	record.EventRecorder
+	reason      string
+	message     string
+	annotations map[string]string
+}
+
+func (f *fakeEventRecorder) AnnotatedEventf(_ runtime.Object, annotations map[string]string, _, reason, messageFmt string, args ...interface{}) {
+	f.reason, f.annotations = reason, annotations
+	f.message = messageFmt
+	if len(args) == 1 {
+		f.message = args[0].(string)
+	}
+}
+
+var _ = BeforeSuite(func() {
+	pricingProvider = pricing.NewDefaultProvider(ctx, &fake.PricingAPI{}, fake.NewEC2API(), fake.DefaultRegion)
+})
+
+var _ = BeforeEach(func() {
+	eventRecorder = &fakeEventRecorder{}
+	recorder = coreevents.NewRecorder(events.NewEventRecorder(eventRecorder, pricingProvider))
+})
+
+var _ = Describe("Events", func() {
+	It("should annotate NodeClaim events with its instance, price and conditions", func() {
+		nodeClaim := &karpv1.NodeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "default-abcde",
+				Labels: map[string]string{
+					karpv1.NodePoolLabelKey:        "default",
+					corev1.LabelInstanceTypeStable: "m5.large",
+					karpv1.CapacityTypeLabelKey:    karpv1.CapacityTypeOnDemand,
+					corev1.LabelTopologyZone:       "test-zone-1a",
+					"team":                         "a",
+				},
+			},
+			Status: karpv1.NodeClaimStatus{
+				ProviderID: "aws:///test-zone-1a/i-01234567890abcdef",
+				NodeName:   "ip-10-0-0-1.ec2.internal",
+			},
+		}
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeLaunched)
+		nodeClaim.StatusConditions().SetTrue(karpv1.ConditionTypeDrifted)
+		nodeClaim.StatusConditions().SetFalse(karpv1.ConditionTypeConsolidatable, "NotConsolidatable", "")
+		recorder.Publish(coreevents.Event{InvolvedObject: nodeClaim, Type: corev1.EventTypeNormal, Reason: "DisruptionLaunching", Message: "Launching NodeClaim: Drifted"})
+
+		price, ok := pricingProvider.OnDemandPrice("m5.large")
+		Expect(ok).To(BeTrue())
+		Expect(eventRecorder.message).To(Equal("Launching NodeClaim: Drifted"))
+		Expect(eventRecorder.annotations).To(Equal(map[string]string{
+			v1.EventAnnotationReason:       "DisruptionLaunching",
+			karpv1.NodePoolLabelKey:        "default",
+			corev1.LabelInstanceTypeStable: "m5.large",
+			karpv1.CapacityTypeLabelKey:    karpv1.CapacityTypeOnDemand,
+			corev1.LabelTopologyZone:       "test-zone-1a",
+			v1.EventAnnotationInstanceID:   "i-01234567890abcdef",
+			v1.EventAnnotationNode:         "ip-10-0-0-1.ec2.internal",
+			v1.EventAnnotationPrice:        strconv.FormatFloat(price, 'f', -1, 64),
+			v1.EventAnnotationConditions:   "Drifted,Launched",
+		}))
+	})
+	It("should annotate Node events with its instance", func() {
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "ip-10-0-0-1.ec2.internal", Labels: map[string]string{karpv1.NodePoolLabelKey: "default"}},
+			Spec:       corev1.NodeSpec{ProviderID: "aws:///test-zone-1a/i-01234567890abcdef"},
+		}
+		recorder.Publish(coreevents.Event{InvolvedObject: node, Type: corev1.EventTypeWarning, Reason: "FailedDraining", Message: "Failed to drain node"})
+		Expect(eventRecorder.annotations).To(Equal(map[string]string{
+			v1.EventAnnotationReason:     "FailedDraining",
+			karpv1.NodePoolLabelKey:      "default",
+			v1.EventAnnotationInstanceID: "i-01234567890abcdef",
+			v1.EventAnnotationNode:       "ip-10-0-0-1.ec2.internal",
+		}))
+	})
+	It("should annotate Pod events with the node it's bound to", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod"}, Spec: corev1.PodSpec{NodeName: "ip-10-0-0-1.ec2.internal"}}
+		recorder.Publish(coreevents.Event{InvolvedObject: pod, Type: corev1.EventTypeNormal, Reason: "Evicted", Message: "Evicted pod"})
+		Expect(eventRecorder.annotations).To(Equal(map[string]string{
+			v1.EventAnnotationReason: "Evicted",
+			v1.EventAnnotationNode:   "ip-10-0-0-1.ec2.internal",
+		}))
+	})
+	It("should only annotate the reason of events for other objects", func() {
+		nodePool := &karpv1.NodePool{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+		recorder.Publish(coreevents.Event{InvolvedObject: nodePool, Type: corev1.EventTypeWarning, Reason: "NoCompatibleInstanceTypes", Message: "NodePool requirements filtered out all compatible available instance types"})
+		Expect(eventRecorder.reason).To(Equal("NoCompatibleInstanceTypes"))
+		Expect(eventRecorder.annotations).To(Equal(map[string]string{v1.EventAnnotationReason: "NoCompatibleInstanceTypes"}))
+	})
+	It("should keep annotations that are passed with the event", func() {
+		events.NewEventRecorder(eventRecorder, pricingProvider).AnnotatedEventf(&corev1.Pod{}, map[string]string{"custom": "value"}, corev1.EventTypeNormal, "Custom", "%s", "message")
+		Expect(eventRecorder.annotations).To(Equal(map[string]string{v1.EventAnnotationReason: "Custom", "custom": "value"}))
+	})
+	It("should not annotate conditions that aren't true", func() {
+		nodeClaim := &karpv1.NodeClaim{}
+		nodeClaim.StatusConditions().SetUnknown(status.ConditionReady)
+		recorder.Publish(coreevents.Event{InvolvedObject: nodeClaim, Type: corev1.EventTypeNormal, Reason: "Unknown", Message: "message"})
+		Expect(eventRecorder.annotations).ToNot(HaveKey(v1.EventAnnotationConditions))
+	})
+})
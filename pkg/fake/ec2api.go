@@ -46,25 +46,44 @@ type CapacityPool struct {
 // EC2Behavior must be reset between tests otherwise tests will
 // pollute each other.
 type EC2Behavior struct {
-	DescribeImagesOutput                AtomicPtr[ec2.DescribeImagesOutput]
-	DescribeLaunchTemplatesOutput       AtomicPtr[ec2.DescribeLaunchTemplatesOutput]
-	DescribeSubnetsOutput               AtomicPtr[ec2.DescribeSubnetsOutput]
-	DescribeSecurityGroupsOutput        AtomicPtr[ec2.DescribeSecurityGroupsOutput]
-	DescribeInstanceTypesOutput         AtomicPtr[ec2.DescribeInstanceTypesOutput]
-	DescribeInstanceTypeOfferingsOutput AtomicPtr[ec2.DescribeInstanceTypeOfferingsOutput]
-	DescribeAvailabilityZonesOutput     AtomicPtr[ec2.DescribeAvailabilityZonesOutput]
-	DescribeSpotPriceHistoryInput       AtomicPtr[ec2.DescribeSpotPriceHistoryInput]
-	DescribeSpotPriceHistoryOutput      AtomicPtr[ec2.DescribeSpotPriceHistoryOutput]
-	CreateFleetBehavior                 MockedFunction[ec2.CreateFleetInput, ec2.CreateFleetOutput]
-	TerminateInstancesBehavior          MockedFunction[ec2.TerminateInstancesInput, ec2.TerminateInstancesOutput]
-	DescribeInstancesBehavior           MockedFunction[ec2.DescribeInstancesInput, ec2.DescribeInstancesOutput]
-	CreateTagsBehavior                  MockedFunction[ec2.CreateTagsInput, ec2.CreateTagsOutput]
-	CalledWithCreateLaunchTemplateInput AtomicPtrSlice[ec2.CreateLaunchTemplateInput]
-	CalledWithDescribeImagesInput       AtomicPtrSlice[ec2.DescribeImagesInput]
-	Instances                           sync.Map
-	LaunchTemplates                     sync.Map
-	InsufficientCapacityPools           atomic.Slice[CapacityPool]
-	NextError                           AtomicError
+	DescribeImagesOutput                 AtomicPtr[ec2.DescribeImagesOutput]
+	DescribeLaunchTemplatesOutput        AtomicPtr[ec2.DescribeLaunchTemplatesOutput]
+	DescribeSubnetsOutput                AtomicPtr[ec2.DescribeSubnetsOutput]
+	DescribeSecurityGroupsOutput         AtomicPtr[ec2.DescribeSecurityGroupsOutput]
+	DescribeInstanceTypesOutput          AtomicPtr[ec2.DescribeInstanceTypesOutput]
+	DescribeInstanceTypeOfferingsOutput  AtomicPtr[ec2.DescribeInstanceTypeOfferingsOutput]
+	DescribeAvailabilityZonesOutput      AtomicPtr[ec2.DescribeAvailabilityZonesOutput]
+	DescribeSpotPriceHistoryInput        AtomicPtr[ec2.DescribeSpotPriceHistoryInput]
+	DescribeSpotPriceHistoryOutput       AtomicPtr[ec2.DescribeSpotPriceHistoryOutput]
+	CreateFleetBehavior                  MockedFunction[ec2.CreateFleetInput, ec2.CreateFleetOutput]
+	TerminateInstancesBehavior           MockedFunction[ec2.TerminateInstancesInput, ec2.TerminateInstancesOutput]
+	DescribeInstancesBehavior            MockedFunction[ec2.DescribeInstancesInput, ec2.DescribeInstancesOutput]
+	CreateTagsBehavior                   MockedFunction[ec2.CreateTagsInput, ec2.CreateTagsOutput]
+	RebootInstancesBehavior              MockedFunction[ec2.RebootInstancesInput, ec2.RebootInstancesOutput]
+	StartInstancesBehavior               MockedFunction[ec2.StartInstancesInput, ec2.StartInstancesOutput]
+	ModifyInstanceAttributeBehavior      MockedFunction[ec2.ModifyInstanceAttributeInput, ec2.ModifyInstanceAttributeOutput]
+	DescribeAddressesBehavior            MockedFunction[ec2.DescribeAddressesInput, ec2.DescribeAddressesOutput]
+	AssociateAddressBehavior             MockedFunction[ec2.AssociateAddressInput, ec2.AssociateAddressOutput]
+	DescribeCapacityReservationsBehavior MockedFunction[ec2.DescribeCapacityReservationsInput, ec2.DescribeCapacityReservationsOutput]
+	DescribeVpcEndpointsBehavior         MockedFunction[ec2.DescribeVpcEndpointsInput, ec2.DescribeVpcEndpointsOutput]
+	DescribeSnapshotsBehavior            MockedFunction[ec2.DescribeSnapshotsInput, ec2.DescribeSnapshotsOutput]
+	DeleteNetworkInterfaceBehavior       MockedFunction[ec2.DeleteNetworkInterfaceInput, ec2.DeleteNetworkInterfaceOutput]
+	DeleteVolumeBehavior                 MockedFunction[ec2.DeleteVolumeInput, ec2.DeleteVolumeOutput]
+	CalledWithCreateLaunchTemplateInput  AtomicPtrSlice[ec2.CreateLaunchTemplateInput]
+	CalledWithDescribeImagesInput        AtomicPtrSlice[ec2.DescribeImagesInput]
+	Instances                            sync.Map
+	Addresses                            sync.Map
+	CapacityReservations                 sync.Map
+	VPCEndpoints                         sync.Map
+	Snapshots                            sync.Map
+	NetworkInterfaces                    sync.Map
+	Volumes                              sync.Map
+	LaunchTemplates                      sync.Map
+	InsufficientCapacityPools            atomic.Slice[CapacityPool]
+	NextError                            AtomicError
+
+	// TerminationProtectedInstances are the IDs of instances with the disableApiTermination attribute enabled
+	TerminationProtectedInstances sync.Map
 }
 
 type EC2API struct {
@@ -92,6 +111,16 @@ func (e *EC2API) Reset() {
 	e.CreateFleetBehavior.Reset()
 	e.TerminateInstancesBehavior.Reset()
 	e.DescribeInstancesBehavior.Reset()
+	e.RebootInstancesBehavior.Reset()
+	e.StartInstancesBehavior.Reset()
+	e.ModifyInstanceAttributeBehavior.Reset()
+	e.DescribeAddressesBehavior.Reset()
+	e.AssociateAddressBehavior.Reset()
+	e.DescribeCapacityReservationsBehavior.Reset()
+	e.DescribeVpcEndpointsBehavior.Reset()
+	e.DescribeSnapshotsBehavior.Reset()
+	e.DeleteNetworkInterfaceBehavior.Reset()
+	e.DeleteVolumeBehavior.Reset()
 	e.CalledWithCreateLaunchTemplateInput.Reset()
 	e.CalledWithDescribeImagesInput.Reset()
 	e.DescribeSpotPriceHistoryInput.Reset()
@@ -100,10 +129,38 @@ func (e *EC2API) Reset() {
 		e.Instances.Delete(k)
 		return true
 	})
+	e.TerminationProtectedInstances.Range(func(k, v any) bool {
+		e.TerminationProtectedInstances.Delete(k)
+		return true
+	})
+	e.Addresses.Range(func(k, v any) bool {
+		e.Addresses.Delete(k)
+		return true
+	})
 	e.LaunchTemplates.Range(func(k, v any) bool {
 		e.LaunchTemplates.Delete(k)
 		return true
 	})
+	e.CapacityReservations.Range(func(k, v any) bool {
+		e.CapacityReservations.Delete(k)
+		return true
+	})
+	e.VPCEndpoints.Range(func(k, v any) bool {
+		e.VPCEndpoints.Delete(k)
+		return true
+	})
+	e.Snapshots.Range(func(k, v any) bool {
+		e.Snapshots.Delete(k)
+		return true
+	})
+	e.NetworkInterfaces.Range(func(k, v any) bool {
+		e.NetworkInterfaces.Delete(k)
+		return true
+	})
+	e.Volumes.Range(func(k, v any) bool {
+		e.Volumes.Delete(k)
+		return true
+	})
 	e.InsufficientCapacityPools.Reset()
 	e.NextError.Reset()
 }
@@ -111,6 +168,9 @@ func (e *EC2API) Reset() {
 // nolint: gocyclo
 func (e *EC2API) CreateFleet(_ context.Context, input *ec2.CreateFleetInput, _ ...func(*ec2.Options)) (*ec2.CreateFleetOutput, error) {
 	return e.CreateFleetBehavior.Invoke(input, func(input *ec2.CreateFleetInput) (*ec2.CreateFleetOutput, error) {
+		if aws.ToBool(input.DryRun) {
+			return nil, &smithy.GenericAPIError{Code: "DryRunOperation", Message: "Request would have succeeded, but DryRun flag is set."}
+		}
 		if input.LaunchTemplateConfigs[0].LaunchTemplateSpecification.LaunchTemplateName == nil {
 			return nil, fmt.Errorf("missing launch template name")
 		}
@@ -199,6 +259,11 @@ func (e *EC2API) CreateFleet(_ context.Context, input *ec2.CreateFleetInput, _ .
 func (e *EC2API) TerminateInstances(_ context.Context, input *ec2.TerminateInstancesInput, _ ...func(*ec2.Options)) (*ec2.TerminateInstancesOutput, error) {
 	return e.TerminateInstancesBehavior.Invoke(input, func(input *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
 		var instanceStateChanges []ec2types.InstanceStateChange
+		for _, id := range input.InstanceIds {
+			if _, ok := e.TerminationProtectedInstances.Load(id); ok {
+				return nil, &smithy.GenericAPIError{Code: "OperationNotPermitted", Message: fmt.Sprintf("The instance '%s' may not be terminated. Modify its 'disableApiTermination' instance attribute and try again.", id)}
+			}
+		}
 		for _, id := range input.InstanceIds {
 			if _, ok := e.Instances.LoadAndDelete(id); ok {
 				instanceStateChanges = append(instanceStateChanges, ec2types.InstanceStateChange{
@@ -218,11 +283,213 @@ func (e *EC2API) CreateLaunchTemplate(_ context.Context, input *ec2.CreateLaunch
 		return nil, e.NextError.Get()
 	}
 	e.CalledWithCreateLaunchTemplateInput.Add(input)
-	launchTemplate := ec2types.LaunchTemplate{LaunchTemplateName: input.LaunchTemplateName}
+	var tags []ec2types.Tag
+	for _, tagSpecification := range input.TagSpecifications {
+		if tagSpecification.ResourceType == ec2types.ResourceTypeLaunchTemplate {
+			tags = tagSpecification.Tags
+		}
+	}
+	launchTemplate := ec2types.LaunchTemplate{
+		LaunchTemplateName: input.LaunchTemplateName,
+		LaunchTemplateId:   aws.String(LaunchTemplateID()),
+		CreateTime:         aws.Time(time.Now()),
+		Tags:               tags,
+	}
 	e.LaunchTemplates.Store(input.LaunchTemplateName, launchTemplate)
 	return &ec2.CreateLaunchTemplateOutput{LaunchTemplate: lo.ToPtr(launchTemplate)}, nil
 }
 
+func (e *EC2API) RebootInstances(_ context.Context, input *ec2.RebootInstancesInput, _ ...func(*ec2.Options)) (*ec2.RebootInstancesOutput, error) {
+	return e.RebootInstancesBehavior.Invoke(input, func(input *ec2.RebootInstancesInput) (*ec2.RebootInstancesOutput, error) {
+		for _, id := range input.InstanceIds {
+			if _, ok := e.Instances.Load(id); !ok {
+				return nil, &smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound", Message: fmt.Sprintf("instance with id '%s' does not exist", id)}
+			}
+		}
+		return &ec2.RebootInstancesOutput{}, nil
+	})
+}
+
+func (e *EC2API) StartInstances(_ context.Context, input *ec2.StartInstancesInput, _ ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	return e.StartInstancesBehavior.Invoke(input, func(input *ec2.StartInstancesInput) (*ec2.StartInstancesOutput, error) {
+		for _, id := range input.InstanceIds {
+			raw, ok := e.Instances.Load(id)
+			if !ok {
+				return nil, &smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound", Message: fmt.Sprintf("instance with id '%s' does not exist", id)}
+			}
+			instance := raw.(ec2types.Instance)
+			instance.State = &ec2types.InstanceState{Name: ec2types.InstanceStateNamePending, Code: aws.Int32(0)}
+			e.Instances.Store(id, instance)
+		}
+		return &ec2.StartInstancesOutput{}, nil
+	})
+}
+
+func (e *EC2API) ModifyInstanceAttribute(_ context.Context, input *ec2.ModifyInstanceAttributeInput, _ ...func(*ec2.Options)) (*ec2.ModifyInstanceAttributeOutput, error) {
+	return e.ModifyInstanceAttributeBehavior.Invoke(input, func(input *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+		if _, ok := e.Instances.Load(lo.FromPtr(input.InstanceId)); !ok {
+			return nil, &smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound", Message: fmt.Sprintf("instance with id '%s' does not exist", lo.FromPtr(input.InstanceId))}
+		}
+		if input.DisableApiTermination != nil {
+			if lo.FromPtr(input.DisableApiTermination.Value) {
+				e.TerminationProtectedInstances.Store(lo.FromPtr(input.InstanceId), true)
+			} else {
+				e.TerminationProtectedInstances.Delete(lo.FromPtr(input.InstanceId))
+			}
+		}
+		return &ec2.ModifyInstanceAttributeOutput{}, nil
+	})
+}
+
+// DescribeAddresses returns the addresses stored in Addresses that match the filters
+func (e *EC2API) DescribeAddresses(_ context.Context, input *ec2.DescribeAddressesInput, _ ...func(*ec2.Options)) (*ec2.DescribeAddressesOutput, error) {
+	return e.DescribeAddressesBehavior.Invoke(input, func(input *ec2.DescribeAddressesInput) (*ec2.DescribeAddressesOutput, error) {
+		var addresses []ec2types.Address
+		e.Addresses.Range(func(_, v any) bool {
+			address := v.(ec2types.Address)
+			if Filter(input.Filters, aws.ToString(address.AllocationId), "", address.Tags) {
+				addresses = append(addresses, address)
+			}
+			return true
+		})
+		return &ec2.DescribeAddressesOutput{Addresses: addresses}, nil
+	})
+}
+
+// AssociateAddress associates the address stored in Addresses with the instance, failing if it's already associated
+func (e *EC2API) AssociateAddress(_ context.Context, input *ec2.AssociateAddressInput, _ ...func(*ec2.Options)) (*ec2.AssociateAddressOutput, error) {
+	return e.AssociateAddressBehavior.Invoke(input, func(input *ec2.AssociateAddressInput) (*ec2.AssociateAddressOutput, error) {
+		v, ok := e.Addresses.Load(aws.ToString(input.AllocationId))
+		if !ok {
+			return nil, &smithy.GenericAPIError{Code: "InvalidAllocationID.NotFound", Message: fmt.Sprintf("allocation id '%s' does not exist", aws.ToString(input.AllocationId))}
+		}
+		address := v.(ec2types.Address)
+		if address.AssociationId != nil && !aws.ToBool(input.AllowReassociation) {
+			return nil, &smithy.GenericAPIError{Code: "Resource.AlreadyAssociated", Message: fmt.Sprintf("resource %s is already associated", aws.ToString(input.AllocationId))}
+		}
+		address.AssociationId = aws.String(fmt.Sprintf("eipassoc-%s", randomdata.Alphanumeric(17)))
+		address.InstanceId = input.InstanceId
+		e.Addresses.Store(aws.ToString(input.AllocationId), address)
+		return &ec2.AssociateAddressOutput{AssociationId: address.AssociationId}, nil
+	})
+}
+
+// DescribeCapacityReservations returns the capacity reservations stored in CapacityReservations with the requested ids
+func (e *EC2API) DescribeCapacityReservations(_ context.Context, input *ec2.DescribeCapacityReservationsInput, _ ...func(*ec2.Options)) (*ec2.DescribeCapacityReservationsOutput, error) {
+	return e.DescribeCapacityReservationsBehavior.Invoke(input, func(input *ec2.DescribeCapacityReservationsInput) (*ec2.DescribeCapacityReservationsOutput, error) {
+		var reservations []ec2types.CapacityReservation
+		for _, id := range input.CapacityReservationIds {
+			v, ok := e.CapacityReservations.Load(id)
+			if !ok {
+				return nil, &smithy.GenericAPIError{Code: "InvalidCapacityReservationId.NotFound", Message: fmt.Sprintf("capacity reservation '%s' does not exist", id)}
+			}
+			reservations = append(reservations, v.(ec2types.CapacityReservation))
+		}
+		return &ec2.DescribeCapacityReservationsOutput{CapacityReservations: reservations}, nil
+	})
+}
+
+// DescribeVpcEndpoints returns the VPC endpoints stored in VPCEndpoints which are in the VPC of the vpc-id filter
+func (e *EC2API) DescribeVpcEndpoints(_ context.Context, input *ec2.DescribeVpcEndpointsInput, _ ...func(*ec2.Options)) (*ec2.DescribeVpcEndpointsOutput, error) {
+	return e.DescribeVpcEndpointsBehavior.Invoke(input, func(input *ec2.DescribeVpcEndpointsInput) (*ec2.DescribeVpcEndpointsOutput, error) {
+		vpcFilter, _ := lo.Find(input.Filters, func(f ec2types.Filter) bool { return lo.FromPtr(f.Name) == "vpc-id" })
+		var endpoints []ec2types.VpcEndpoint
+		e.VPCEndpoints.Range(func(_, v any) bool {
+			endpoint := v.(ec2types.VpcEndpoint)
+			if len(vpcFilter.Values) == 0 || lo.Contains(vpcFilter.Values, lo.FromPtr(endpoint.VpcId)) {
+				endpoints = append(endpoints, endpoint)
+			}
+			return true
+		})
+		return &ec2.DescribeVpcEndpointsOutput{VpcEndpoints: endpoints}, nil
+	})
+}
+
+// DescribeNetworkInterfaces returns the network interfaces stored in NetworkInterfaces that match the status and tag filters
+func (e *EC2API) DescribeNetworkInterfaces(_ context.Context, input *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	if !e.NextError.IsNil() {
+		defer e.NextError.Reset()
+		return nil, e.NextError.Get()
+	}
+	var networkInterfaces []ec2types.NetworkInterface
+	e.NetworkInterfaces.Range(func(_, v any) bool {
+		ni := v.(ec2types.NetworkInterface)
+		if filterStatus(input.Filters, string(ni.Status), ni.TagSet) {
+			networkInterfaces = append(networkInterfaces, ni)
+		}
+		return true
+	})
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: networkInterfaces}, nil
+}
+
+// DeleteNetworkInterface deletes the network interface stored in NetworkInterfaces, failing if it's attached
+func (e *EC2API) DeleteNetworkInterface(_ context.Context, input *ec2.DeleteNetworkInterfaceInput, _ ...func(*ec2.Options)) (*ec2.DeleteNetworkInterfaceOutput, error) {
+	return e.DeleteNetworkInterfaceBehavior.Invoke(input, func(input *ec2.DeleteNetworkInterfaceInput) (*ec2.DeleteNetworkInterfaceOutput, error) {
+		v, ok := e.NetworkInterfaces.Load(aws.ToString(input.NetworkInterfaceId))
+		if !ok {
+			return nil, &smithy.GenericAPIError{Code: "InvalidNetworkInterfaceID.NotFound", Message: fmt.Sprintf("the networkInterface ID '%s' does not exist", aws.ToString(input.NetworkInterfaceId))}
+		}
+		if v.(ec2types.NetworkInterface).Status != ec2types.NetworkInterfaceStatusAvailable {
+			return nil, &smithy.GenericAPIError{Code: "InvalidNetworkInterface.InUse", Message: fmt.Sprintf("interface '%s' is currently in use", aws.ToString(input.NetworkInterfaceId))}
+		}
+		e.NetworkInterfaces.Delete(aws.ToString(input.NetworkInterfaceId))
+		return &ec2.DeleteNetworkInterfaceOutput{}, nil
+	})
+}
+
+// DescribeVolumes returns the volumes stored in Volumes that match the status and tag filters
+func (e *EC2API) DescribeVolumes(_ context.Context, input *ec2.DescribeVolumesInput, _ ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	if !e.NextError.IsNil() {
+		defer e.NextError.Reset()
+		return nil, e.NextError.Get()
+	}
+	var volumes []ec2types.Volume
+	e.Volumes.Range(func(_, v any) bool {
+		volume := v.(ec2types.Volume)
+		if filterStatus(input.Filters, string(volume.State), volume.Tags) {
+			volumes = append(volumes, volume)
+		}
+		return true
+	})
+	return &ec2.DescribeVolumesOutput{Volumes: volumes}, nil
+}
+
+// DeleteVolume deletes the volume stored in Volumes, failing if it's attached
+func (e *EC2API) DeleteVolume(_ context.Context, input *ec2.DeleteVolumeInput, _ ...func(*ec2.Options)) (*ec2.DeleteVolumeOutput, error) {
+	return e.DeleteVolumeBehavior.Invoke(input, func(input *ec2.DeleteVolumeInput) (*ec2.DeleteVolumeOutput, error) {
+		v, ok := e.Volumes.Load(aws.ToString(input.VolumeId))
+		if !ok {
+			return nil, &smithy.GenericAPIError{Code: "InvalidVolume.NotFound", Message: fmt.Sprintf("the volume '%s' does not exist", aws.ToString(input.VolumeId))}
+		}
+		if v.(ec2types.Volume).State != ec2types.VolumeStateAvailable {
+			return nil, &smithy.GenericAPIError{Code: "VolumeInUse", Message: fmt.Sprintf("volume %s is currently attached", aws.ToString(input.VolumeId))}
+		}
+		e.Volumes.Delete(aws.ToString(input.VolumeId))
+		return &ec2.DeleteVolumeOutput{}, nil
+	})
+}
+
+// filterStatus matches the "status" filter against the passed status and the remaining filters against the tags
+func filterStatus(filters []ec2types.Filter, status string, tags []ec2types.Tag) bool {
+	statusFilters, otherFilters := lo.FilterReject(filters, func(f ec2types.Filter, _ int) bool { return aws.ToString(f.Name) == "status" })
+	return lo.EveryBy(statusFilters, func(f ec2types.Filter) bool { return lo.Contains(f.Values, status) }) && Filter(otherFilters, "", "", tags)
+}
+
+// DescribeSnapshots returns the snapshots stored in Snapshots with the requested ids
+func (e *EC2API) DescribeSnapshots(_ context.Context, input *ec2.DescribeSnapshotsInput, _ ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error) {
+	return e.DescribeSnapshotsBehavior.Invoke(input, func(input *ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error) {
+		var snapshots []ec2types.Snapshot
+		for _, id := range input.SnapshotIds {
+			v, ok := e.Snapshots.Load(id)
+			if !ok {
+				return nil, &smithy.GenericAPIError{Code: "InvalidSnapshot.NotFound", Message: fmt.Sprintf("the snapshot '%s' does not exist", id)}
+			}
+			snapshots = append(snapshots, v.(ec2types.Snapshot))
+		}
+		return &ec2.DescribeSnapshotsOutput{Snapshots: snapshots}, nil
+	})
+}
+
 func (e *EC2API) CreateTags(_ context.Context, input *ec2.CreateTagsInput, _ ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
 	return e.CreateTagsBehavior.Invoke(input, func(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
 		// Update passed in instances with the passed tags
@@ -352,14 +619,15 @@ func (e *EC2API) DescribeLaunchTemplates(_ context.Context, input *ec2.DescribeL
 		return e.DescribeLaunchTemplatesOutput.Clone(), nil
 	}
 	output := &ec2.DescribeLaunchTemplatesOutput{}
+	listAll := len(input.LaunchTemplateNames) == 0 && len(input.Filters) == 0
 	e.LaunchTemplates.Range(func(key, value interface{}) bool {
 		launchTemplate := value.(ec2types.LaunchTemplate)
-		if lo.Contains(aws.StringSlice(input.LaunchTemplateNames), launchTemplate.LaunchTemplateName) || len(input.Filters) != 0 && Filter(input.Filters, aws.ToString(launchTemplate.LaunchTemplateId), aws.ToString(launchTemplate.LaunchTemplateName), launchTemplate.Tags) {
+		if listAll || lo.Contains(aws.StringSlice(input.LaunchTemplateNames), launchTemplate.LaunchTemplateName) || len(input.Filters) != 0 && Filter(input.Filters, aws.ToString(launchTemplate.LaunchTemplateId), aws.ToString(launchTemplate.LaunchTemplateName), launchTemplate.Tags) {
 			output.LaunchTemplates = append(output.LaunchTemplates, launchTemplate)
 		}
 		return true
 	})
-	if len(input.Filters) != 0 {
+	if listAll || len(input.Filters) != 0 {
 		return output, nil
 	}
 	if len(output.LaunchTemplates) == 0 {
@@ -377,6 +645,14 @@ func (e *EC2API) DeleteLaunchTemplate(_ context.Context, input *ec2.DeleteLaunch
 		return nil, e.NextError.Get()
 	}
 	e.LaunchTemplates.Delete(input.LaunchTemplateName)
+	if input.LaunchTemplateId != nil {
+		e.LaunchTemplates.Range(func(k, v any) bool {
+			if aws.ToString(v.(ec2types.LaunchTemplate).LaunchTemplateId) == aws.ToString(input.LaunchTemplateId) {
+				e.LaunchTemplates.Delete(k)
+			}
+			return true
+		})
+	}
 	return nil, nil
 }
 
@@ -396,6 +672,7 @@ func (e *EC2API) DescribeSubnets(_ context.Context, input *ec2.DescribeSubnetsIn
 			AvailabilityZone:        aws.String("test-zone-1a"),
 			AvailabilityZoneId:      aws.String("tstz1-1a"),
 			AvailableIpAddressCount: aws.Int32(100),
+			VpcId:                   aws.String("vpc-test1"),
 			MapPublicIpOnLaunch:     aws.Bool(false),
 			Tags: []ec2types.Tag{
 				{Key: aws.String("Name"), Value: aws.String("test-subnet-1")},
@@ -407,6 +684,7 @@ func (e *EC2API) DescribeSubnets(_ context.Context, input *ec2.DescribeSubnetsIn
 			AvailabilityZone:        aws.String("test-zone-1b"),
 			AvailabilityZoneId:      aws.String("tstz1-1b"),
 			AvailableIpAddressCount: aws.Int32(100),
+			VpcId:                   aws.String("vpc-test1"),
 			MapPublicIpOnLaunch:     aws.Bool(true),
 			Tags: []ec2types.Tag{
 				{Key: aws.String("Name"), Value: aws.String("test-subnet-2")},
@@ -418,6 +696,7 @@ func (e *EC2API) DescribeSubnets(_ context.Context, input *ec2.DescribeSubnetsIn
 			AvailabilityZone:        aws.String("test-zone-1c"),
 			AvailabilityZoneId:      aws.String("tstz1-1c"),
 			AvailableIpAddressCount: aws.Int32(100),
+			VpcId:                   aws.String("vpc-test1"),
 			Tags: []ec2types.Tag{
 				{Key: aws.String("Name"), Value: aws.String("test-subnet-3")},
 				{Key: aws.String("TestTag")},
@@ -429,6 +708,7 @@ func (e *EC2API) DescribeSubnets(_ context.Context, input *ec2.DescribeSubnetsIn
 			AvailabilityZone:        aws.String("test-zone-1a-local"),
 			AvailabilityZoneId:      aws.String("tstz1-1alocal"),
 			AvailableIpAddressCount: aws.Int32(100),
+			VpcId:                   aws.String("vpc-test1"),
 			MapPublicIpOnLaunch:     aws.Bool(true),
 			Tags: []ec2types.Tag{
 				{Key: aws.String("Name"), Value: aws.String("test-subnet-4")},
@@ -500,11 +780,14 @@ func (e *EC2API) DescribeAvailabilityZones(context.Context, *ec2.DescribeAvailab
 	}}, nil
 }
 
-func (e *EC2API) DescribeInstanceTypes(_ context.Context, _ *ec2.DescribeInstanceTypesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+func (e *EC2API) DescribeInstanceTypes(_ context.Context, input *ec2.DescribeInstanceTypesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
 	if !e.NextError.IsNil() {
 		defer e.NextError.Reset()
 		return nil, e.NextError.Get()
 	}
+	if aws.ToBool(input.DryRun) {
+		return nil, &smithy.GenericAPIError{Code: "DryRunOperation", Message: "Request would have succeeded, but DryRun flag is set."}
+	}
 	if !e.DescribeInstanceTypesOutput.IsNil() {
 		return e.DescribeInstanceTypesOutput.Clone(), nil
 	}
@@ -16,9 +16,13 @@ package fake
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/smithy-go"
 	"github.com/samber/lo"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
@@ -29,12 +33,17 @@ const ()
 // EKSAPIBehavior must be reset between tests otherwise tests will
 // pollute each other.
 type EKSAPIBehavior struct {
-	DescribeClusterBehavior MockedFunction[eks.DescribeClusterInput, eks.DescribeClusterOutput]
+	DescribeClusterBehavior     MockedFunction[eks.DescribeClusterInput, eks.DescribeClusterOutput]
+	DescribeAccessEntryBehavior MockedFunction[eks.DescribeAccessEntryInput, eks.DescribeAccessEntryOutput]
+	CreateAccessEntryBehavior   MockedFunction[eks.CreateAccessEntryInput, eks.CreateAccessEntryOutput]
+	DeleteAccessEntryBehavior   MockedFunction[eks.DeleteAccessEntryInput, eks.DeleteAccessEntryOutput]
 }
 
 type EKSAPI struct {
 	sdk.EKSAPI
 	EKSAPIBehavior
+
+	AccessEntries sync.Map
 }
 
 func NewEKSAPI() *EKSAPI {
@@ -45,6 +54,13 @@ func NewEKSAPI() *EKSAPI {
 // each other.
 func (s *EKSAPI) Reset() {
 	s.DescribeClusterBehavior.Reset()
+	s.DescribeAccessEntryBehavior.Reset()
+	s.CreateAccessEntryBehavior.Reset()
+	s.DeleteAccessEntryBehavior.Reset()
+	s.AccessEntries.Range(func(k, _ any) bool {
+		s.AccessEntries.Delete(k)
+		return true
+	})
 }
 
 func (s *EKSAPI) DescribeCluster(_ context.Context, input *eks.DescribeClusterInput, _ ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
@@ -59,3 +75,45 @@ func (s *EKSAPI) DescribeCluster(_ context.Context, input *eks.DescribeClusterIn
 		}, nil
 	})
 }
+
+func (s *EKSAPI) DescribeAccessEntry(_ context.Context, input *eks.DescribeAccessEntryInput, _ ...func(*eks.Options)) (*eks.DescribeAccessEntryOutput, error) {
+	return s.DescribeAccessEntryBehavior.Invoke(input, func(*eks.DescribeAccessEntryInput) (*eks.DescribeAccessEntryOutput, error) {
+		if entry, ok := s.AccessEntries.Load(aws.ToString(input.PrincipalArn)); ok {
+			return &eks.DescribeAccessEntryOutput{AccessEntry: entry.(*ekstypes.AccessEntry)}, nil
+		}
+		return nil, &smithy.GenericAPIError{
+			Code:    "ResourceNotFoundException",
+			Message: fmt.Sprintf("The specified access entry resource %s could not be found", aws.ToString(input.PrincipalArn)),
+		}
+	})
+}
+
+func (s *EKSAPI) CreateAccessEntry(_ context.Context, input *eks.CreateAccessEntryInput, _ ...func(*eks.Options)) (*eks.CreateAccessEntryOutput, error) {
+	return s.CreateAccessEntryBehavior.Invoke(input, func(*eks.CreateAccessEntryInput) (*eks.CreateAccessEntryOutput, error) {
+		entry := &ekstypes.AccessEntry{
+			ClusterName:  input.ClusterName,
+			PrincipalArn: input.PrincipalArn,
+			Type:         input.Type,
+			Tags:         input.Tags,
+		}
+		if _, loaded := s.AccessEntries.LoadOrStore(aws.ToString(input.PrincipalArn), entry); loaded {
+			return nil, &smithy.GenericAPIError{
+				Code:    "ResourceInUseException",
+				Message: fmt.Sprintf("The specified access entry resource %s is already in use", aws.ToString(input.PrincipalArn)),
+			}
+		}
+		return &eks.CreateAccessEntryOutput{AccessEntry: entry}, nil
+	})
+}
+
+func (s *EKSAPI) DeleteAccessEntry(_ context.Context, input *eks.DeleteAccessEntryInput, _ ...func(*eks.Options)) (*eks.DeleteAccessEntryOutput, error) {
+	return s.DeleteAccessEntryBehavior.Invoke(input, func(*eks.DeleteAccessEntryInput) (*eks.DeleteAccessEntryOutput, error) {
+		if _, loaded := s.AccessEntries.LoadAndDelete(aws.ToString(input.PrincipalArn)); !loaded {
+			return nil, &smithy.GenericAPIError{
+				Code:    "ResourceNotFoundException",
+				Message: fmt.Sprintf("The specified access entry resource %s could not be found", aws.ToString(input.PrincipalArn)),
+			}
+		}
+		return &eks.DeleteAccessEntryOutput{}, nil
+	})
+}
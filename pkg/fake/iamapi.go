@@ -40,6 +40,7 @@ type IAMAPIBehavior struct {
 	AddRoleToInstanceProfileBehavior      MockedFunction[iam.AddRoleToInstanceProfileInput, iam.AddRoleToInstanceProfileOutput]
 	TagInstanceProfileBehavior            MockedFunction[iam.TagInstanceProfileInput, iam.TagInstanceProfileOutput]
 	RemoveRoleFromInstanceProfileBehavior MockedFunction[iam.RemoveRoleFromInstanceProfileInput, iam.RemoveRoleFromInstanceProfileOutput]
+	GetRoleBehavior                       MockedFunction[iam.GetRoleInput, iam.GetRoleOutput]
 }
 
 type IAMAPI struct {
@@ -61,6 +62,7 @@ func (s *IAMAPI) Reset() {
 	s.DeleteInstanceProfileBehavior.Reset()
 	s.AddRoleToInstanceProfileBehavior.Reset()
 	s.RemoveRoleFromInstanceProfileBehavior.Reset()
+	s.GetRoleBehavior.Reset()
 	s.InstanceProfiles = map[string]*iamtypes.InstanceProfile{}
 }
 
@@ -160,7 +162,7 @@ func (s *IAMAPI) AddRoleToInstanceProfile(_ context.Context, input *iam.AddRoleT
 						aws.ToString(input.InstanceProfileName)),
 				}
 			}
-			i.Roles = append(i.Roles, iamtypes.Role{RoleId: aws.String(RoleID()), RoleName: input.RoleName})
+			i.Roles = append(i.Roles, iamtypes.Role{RoleId: aws.String(RoleID()), RoleName: input.RoleName, Arn: aws.String(RoleARN(aws.ToString(input.RoleName)))})
 			return nil, nil
 		}
 		return nil, &smithy.GenericAPIError{
@@ -197,3 +199,14 @@ func (s *IAMAPI) RemoveRoleFromInstanceProfile(_ context.Context, input *iam.Rem
 		}
 	})
 }
+
+// GetRole returns a role for any role name unless a behavior is set, since roles are created outside of Karpenter
+func (s *IAMAPI) GetRole(_ context.Context, input *iam.GetRoleInput, _ ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	return s.GetRoleBehavior.Invoke(input, func(*iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+		return &iam.GetRoleOutput{Role: &iamtypes.Role{
+			RoleId:   aws.String(RoleID()),
+			RoleName: input.RoleName,
+			Arn:      aws.String(RoleARN(aws.ToString(input.RoleName))),
+		}}, nil
+	})
+}
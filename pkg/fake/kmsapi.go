@@ -0,0 +1,94 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/samber/lo"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+// DefaultKeyPolicy permits the EC2 Fleet service-linked role to use the key for EBS encryption
+const DefaultKeyPolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Principal": {"AWS": "arn:aws:iam::` + DefaultAccount + `:role/aws-service-role/ec2fleet.amazonaws.com/AWSServiceRoleForEC2Fleet"},
+      "Action": ["kms:Encrypt", "kms:Decrypt", "kms:ReEncrypt*", "kms:GenerateDataKey*", "kms:DescribeKey"],
+      "Resource": "*"
+    },
+    {
+      "Effect": "Allow",
+      "Principal": {"AWS": "arn:aws:iam::` + DefaultAccount + `:role/aws-service-role/ec2fleet.amazonaws.com/AWSServiceRoleForEC2Fleet"},
+      "Action": "kms:CreateGrant",
+      "Resource": "*",
+      "Condition": {"Bool": {"kms:GrantIsForAWSResource": true}}
+    }
+  ]
+}`
+
+type KMSBehavior struct {
+	DescribeKeyBehavior  MockedFunction[kms.DescribeKeyInput, kms.DescribeKeyOutput]
+	GetKeyPolicyBehavior MockedFunction[kms.GetKeyPolicyInput, kms.GetKeyPolicyOutput]
+	// KeyPolicies is keyed by key ID. Keys which aren't present return the DefaultKeyPolicy.
+	KeyPolicies sync.Map
+}
+
+type KMSAPI struct {
+	sdk.KMSAPI
+	KMSBehavior
+}
+
+func NewKMSAPI() *KMSAPI {
+	return &KMSAPI{}
+}
+
+func (k *KMSAPI) Reset() {
+	k.DescribeKeyBehavior.Reset()
+	k.GetKeyPolicyBehavior.Reset()
+	k.KeyPolicies.Range(func(key, _ any) bool {
+		k.KeyPolicies.Delete(key)
+		return true
+	})
+}
+
+func (k *KMSAPI) DescribeKey(_ context.Context, input *kms.DescribeKeyInput, _ ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	return k.DescribeKeyBehavior.Invoke(input, func(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+		return &kms.DescribeKeyOutput{
+			KeyMetadata: &kmstypes.KeyMetadata{
+				KeyId:      input.KeyId,
+				Arn:        lo.ToPtr(fmt.Sprintf("arn:aws:kms:%s:%s:key/%s", DefaultRegion, DefaultAccount, lo.FromPtr(input.KeyId))),
+				KeyManager: kmstypes.KeyManagerTypeCustomer,
+			},
+		}, nil
+	})
+}
+
+func (k *KMSAPI) GetKeyPolicy(_ context.Context, input *kms.GetKeyPolicyInput, _ ...func(*kms.Options)) (*kms.GetKeyPolicyOutput, error) {
+	return k.GetKeyPolicyBehavior.Invoke(input, func(input *kms.GetKeyPolicyInput) (*kms.GetKeyPolicyOutput, error) {
+		policy, ok := k.KeyPolicies.Load(lo.FromPtr(input.KeyId))
+		if !ok {
+			return &kms.GetKeyPolicyOutput{Policy: lo.ToPtr(DefaultKeyPolicy), PolicyName: input.PolicyName}, nil
+		}
+		return &kms.GetKeyPolicyOutput{Policy: lo.ToPtr(policy.(string)), PolicyName: input.PolicyName}, nil
+	})
+}
@@ -0,0 +1,65 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fake
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	servicequotastypes "github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+	"github.com/samber/lo"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+type ServiceQuotasBehavior struct {
+	GetServiceQuotaBehavior MockedFunction[servicequotas.GetServiceQuotaInput, servicequotas.GetServiceQuotaOutput]
+	// Quotas is keyed by quota code. Quota codes which aren't present return a NoSuchResourceException.
+	Quotas sync.Map
+}
+
+type ServiceQuotasAPI struct {
+	sdk.ServiceQuotasAPI
+	ServiceQuotasBehavior
+}
+
+func NewServiceQuotasAPI() *ServiceQuotasAPI {
+	return &ServiceQuotasAPI{}
+}
+
+func (s *ServiceQuotasAPI) Reset() {
+	s.GetServiceQuotaBehavior.Reset()
+	s.Quotas.Range(func(k, _ any) bool {
+		s.Quotas.Delete(k)
+		return true
+	})
+}
+
+func (s *ServiceQuotasAPI) GetServiceQuota(_ context.Context, input *servicequotas.GetServiceQuotaInput, _ ...func(*servicequotas.Options)) (*servicequotas.GetServiceQuotaOutput, error) {
+	return s.GetServiceQuotaBehavior.Invoke(input, func(input *servicequotas.GetServiceQuotaInput) (*servicequotas.GetServiceQuotaOutput, error) {
+		value, ok := s.Quotas.Load(lo.FromPtr(input.QuotaCode))
+		if !ok {
+			return nil, &servicequotastypes.NoSuchResourceException{Message: lo.ToPtr("quota not found")}
+		}
+		return &servicequotas.GetServiceQuotaOutput{
+			Quota: &servicequotastypes.ServiceQuota{
+				ServiceCode: input.ServiceCode,
+				QuotaCode:   input.QuotaCode,
+				Value:       lo.ToPtr(value.(float64)),
+			},
+		}, nil
+	})
+}
@@ -16,9 +16,11 @@ package fake
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 )
@@ -30,9 +32,12 @@ const (
 // SQSBehavior must be reset between tests otherwise tests will
 // pollute each other.
 type SQSBehavior struct {
-	GetQueueURLBehavior    MockedFunction[sqs.GetQueueUrlInput, sqs.GetQueueUrlOutput]
-	ReceiveMessageBehavior MockedFunction[sqs.ReceiveMessageInput, sqs.ReceiveMessageOutput]
-	DeleteMessageBehavior  MockedFunction[sqs.DeleteMessageInput, sqs.DeleteMessageOutput]
+	GetQueueURLBehavior        MockedFunction[sqs.GetQueueUrlInput, sqs.GetQueueUrlOutput]
+	GetQueueAttributesBehavior MockedFunction[sqs.GetQueueAttributesInput, sqs.GetQueueAttributesOutput]
+	ReceiveMessageBehavior     MockedFunction[sqs.ReceiveMessageInput, sqs.ReceiveMessageOutput]
+	DeleteMessageBehavior      MockedFunction[sqs.DeleteMessageInput, sqs.DeleteMessageOutput]
+	SendMessageBehavior        MockedFunction[sqs.SendMessageInput, sqs.SendMessageOutput]
+	ChangeVisibilityBehavior   MockedFunction[sqs.ChangeMessageVisibilityBatchInput, sqs.ChangeMessageVisibilityBatchOutput]
 }
 
 type SQSAPI struct {
@@ -44,8 +49,11 @@ type SQSAPI struct {
 // each other.
 func (s *SQSAPI) Reset() {
 	s.GetQueueURLBehavior.Reset()
+	s.GetQueueAttributesBehavior.Reset()
 	s.ReceiveMessageBehavior.Reset()
 	s.DeleteMessageBehavior.Reset()
+	s.SendMessageBehavior.Reset()
+	s.ChangeVisibilityBehavior.Reset()
 }
 
 //nolint:revive,stylecheck
@@ -57,6 +65,14 @@ func (s *SQSAPI) GetQueueUrl(_ context.Context, input *sqs.GetQueueUrlInput, _ .
 	})
 }
 
+func (s *SQSAPI) GetQueueAttributes(_ context.Context, input *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	return s.GetQueueAttributesBehavior.Invoke(input, func(_ *sqs.GetQueueAttributesInput) (*sqs.GetQueueAttributesOutput, error) {
+		return &sqs.GetQueueAttributesOutput{
+			Attributes: map[string]string{string(sqstypes.QueueAttributeNameApproximateNumberOfMessages): "0"},
+		}, nil
+	})
+}
+
 func (s *SQSAPI) ReceiveMessage(_ context.Context, input *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
 	return s.ReceiveMessageBehavior.Invoke(input, func(_ *sqs.ReceiveMessageInput) (*sqs.ReceiveMessageOutput, error) {
 		return nil, nil
@@ -68,3 +84,15 @@ func (s *SQSAPI) DeleteMessage(_ context.Context, input *sqs.DeleteMessageInput,
 		return nil, nil
 	})
 }
+
+func (s *SQSAPI) SendMessage(_ context.Context, input *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	return s.SendMessageBehavior.Invoke(input, func(_ *sqs.SendMessageInput) (*sqs.SendMessageOutput, error) {
+		return &sqs.SendMessageOutput{MessageId: aws.String(fmt.Sprint(s.SendMessageBehavior.Calls()))}, nil
+	})
+}
+
+func (s *SQSAPI) ChangeMessageVisibilityBatch(_ context.Context, input *sqs.ChangeMessageVisibilityBatchInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	return s.ChangeVisibilityBehavior.Invoke(input, func(_ *sqs.ChangeMessageVisibilityBatchInput) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+		return &sqs.ChangeMessageVisibilityBatchOutput{}, nil
+	})
+}
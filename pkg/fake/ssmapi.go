@@ -33,6 +33,9 @@ type SSMAPI struct {
 	GetParameterOutput *ssm.GetParameterOutput
 	WantErr            error
 
+	SendCommandBehavior          MockedFunction[ssm.SendCommandInput, ssm.SendCommandOutput]
+	GetCommandInvocationBehavior MockedFunction[ssm.GetCommandInvocationInput, ssm.GetCommandInvocationOutput]
+
 	defaultParameters map[string]string
 }
 
@@ -42,7 +45,7 @@ func NewSSMAPI() *SSMAPI {
 	}
 }
 
-func (a SSMAPI) GetParameter(_ context.Context, input *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
+func (a *SSMAPI) GetParameter(_ context.Context, input *ssm.GetParameterInput, _ ...func(*ssm.Options)) (*ssm.GetParameterOutput, error) {
 	parameter := lo.FromPtr(input.Name)
 	if a.WantErr != nil {
 		return &ssm.GetParameterOutput{}, a.WantErr
@@ -77,9 +80,36 @@ func (a SSMAPI) GetParameter(_ context.Context, input *ssm.GetParameterInput, _
 	}, nil
 }
 
+// SendCommand returns a random command ID, unless overridden by the SendCommandBehavior
+func (a *SSMAPI) SendCommand(_ context.Context, input *ssm.SendCommandInput, _ ...func(*ssm.Options)) (*ssm.SendCommandOutput, error) {
+	return a.SendCommandBehavior.Invoke(input, func(input *ssm.SendCommandInput) (*ssm.SendCommandOutput, error) {
+		return &ssm.SendCommandOutput{
+			Command: &ssmtypes.Command{
+				CommandId:    lo.ToPtr(randomdata.Alphanumeric(36)),
+				DocumentName: input.DocumentName,
+				InstanceIds:  input.InstanceIds,
+				Status:       ssmtypes.CommandStatusPending,
+			},
+		}, nil
+	})
+}
+
+// GetCommandInvocation reports that the command succeeded, unless overridden by the GetCommandInvocationBehavior
+func (a *SSMAPI) GetCommandInvocation(_ context.Context, input *ssm.GetCommandInvocationInput, _ ...func(*ssm.Options)) (*ssm.GetCommandInvocationOutput, error) {
+	return a.GetCommandInvocationBehavior.Invoke(input, func(input *ssm.GetCommandInvocationInput) (*ssm.GetCommandInvocationOutput, error) {
+		return &ssm.GetCommandInvocationOutput{
+			CommandId:  input.CommandId,
+			InstanceId: input.InstanceId,
+			Status:     ssmtypes.CommandInvocationStatusSuccess,
+		}, nil
+	})
+}
+
 func (a *SSMAPI) Reset() {
 	a.Parameters = nil
 	a.GetParameterOutput = nil
 	a.WantErr = nil
+	a.SendCommandBehavior.Reset()
+	a.GetCommandInvocationBehavior.Reset()
 	a.defaultParameters = map[string]string{}
 }
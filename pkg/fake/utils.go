@@ -61,6 +61,10 @@ func RoleID() string {
 	return fmt.Sprintf("role-%s", randomdata.Alphanumeric(17))
 }
 
+func RoleARN(roleName string) string {
+	return fmt.Sprintf("arn:aws:iam::123456789012:role/%s", roleName)
+}
+
 func LaunchTemplateName() string {
 	return fmt.Sprintf("karpenter.k8s.aws/%s", randomdata.Alphanumeric(17))
 }
@@ -112,7 +116,7 @@ func FilterDescribeImages(images []ec2types.Image, filters []ec2types.Filter) []
 func Filter(filters []ec2types.Filter, id, name string, tags []ec2types.Tag) bool {
 	return lo.EveryBy(filters, func(filter ec2types.Filter) bool {
 		switch filterName := aws.ToString(filter.Name); {
-		case filterName == "subnet-id" || filterName == "group-id" || filterName == "image-id":
+		case filterName == "subnet-id" || filterName == "group-id" || filterName == "image-id" || filterName == "allocation-id":
 			for _, val := range filter.Values {
 				if id == val {
 					return true
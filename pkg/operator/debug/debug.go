@@ -0,0 +1,161 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+)
+
+// Path is the path that the state handler is served on from the metrics server
+const Path = "/debug/karpenter/state"
+
+// State is a point-in-time snapshot of Karpenter's internal state, intended for diagnosing launch stalls
+type State struct {
+	Time                 time.Time                      `json:"time"`
+	UnavailableOfferings []awscache.UnavailableOffering `json:"unavailableOfferings"`
+	Pricing              PricingState                   `json:"pricing"`
+	NodePools            map[string]NodePoolState       `json:"nodePools"`
+}
+
+// PricingState reports when pricing data was last refreshed. Zero times mean the static initial pricing data is still in use.
+type PricingState struct {
+	OnDemandLastUpdated time.Time `json:"onDemandLastUpdated,omitempty"`
+	OnDemandAge         string    `json:"onDemandAge,omitempty"`
+	SpotLastUpdated     time.Time `json:"spotLastUpdated,omitempty"`
+	SpotAge             string    `json:"spotAge,omitempty"`
+}
+
+// NodePoolState counts the NodeClaims in a NodePool that are in flight
+type NodePoolState struct {
+	// Launching is the number of NodeClaims whose instance hasn't been launched yet
+	Launching int `json:"launching"`
+	// Registering is the number of NodeClaims whose instance has launched but hasn't registered
+	Registering int `json:"registering"`
+}
+
+type Handler struct {
+	token                string
+	clk                  clock.Clock
+	kubeClient           client.Client
+	unavailableOfferings *awscache.UnavailableOfferings
+	pricingProvider      pricing.Provider
+}
+
+func NewHandler(token string, clk clock.Clock, kubeClient client.Client, unavailableOfferings *awscache.UnavailableOfferings,
+	pricingProvider pricing.Provider) *Handler {
+	return &Handler{
+		token:                token,
+		clk:                  clk,
+		kubeClient:           kubeClient,
+		unavailableOfferings: unavailableOfferings,
+		pricingProvider:      pricingProvider,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r, h.token, http.MethodGet) {
+		return
+	}
+	state, err := h.State(r)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "failed building debug state")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, state)
+}
+
+// authorized rejects requests that don't use the method or bearer token, writing the error response
+func authorized(w http.ResponseWriter, r *http.Request, expected, method string) bool {
+	if r.Method != method {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func (h *Handler) State(r *http.Request) (State, error) {
+	now := h.clk.Now()
+	offerings := h.unavailableOfferings.List()
+	sort.Slice(offerings, func(i, j int) bool {
+		return strings.Join([]string{offerings[i].CapacityType, offerings[i].InstanceType, offerings[i].Zone}, ":") <
+			strings.Join([]string{offerings[j].CapacityType, offerings[j].InstanceType, offerings[j].Zone}, ":")
+	})
+	onDemandUpdated, spotUpdated := h.pricingProvider.LastUpdated()
+	nodeClaimList := &karpv1.NodeClaimList{}
+	if err := h.kubeClient.List(r.Context(), nodeClaimList); err != nil {
+		return State{}, err
+	}
+	nodePools := map[string]NodePoolState{}
+	for i := range nodeClaimList.Items {
+		nc := &nodeClaimList.Items[i]
+		nodePool, ok := nc.Labels[karpv1.NodePoolLabelKey]
+		if !ok || !nc.DeletionTimestamp.IsZero() {
+			continue
+		}
+		state := nodePools[nodePool]
+		if !nc.StatusConditions().Get(karpv1.ConditionTypeLaunched).IsTrue() {
+			state.Launching++
+		} else if !nc.StatusConditions().Get(karpv1.ConditionTypeRegistered).IsTrue() {
+			state.Registering++
+		}
+		nodePools[nodePool] = state
+	}
+	return State{
+		Time:                 now,
+		UnavailableOfferings: lo.Ternary(offerings == nil, []awscache.UnavailableOffering{}, offerings),
+		Pricing: PricingState{
+			OnDemandLastUpdated: onDemandUpdated,
+			OnDemandAge:         age(now, onDemandUpdated),
+			SpotLastUpdated:     spotUpdated,
+			SpotAge:             age(now, spotUpdated),
+		},
+		NodePools: nodePools,
+	}, nil
+}
+
+func age(now, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return now.Sub(t).Truncate(time.Second).String()
+}
@@ -0,0 +1,131 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	nodepoolutils "sigs.k8s.io/karpenter/pkg/utils/nodepool"
+)
+
+// InstanceTypesPath is the path that the instance types handler is served on from the metrics server
+const InstanceTypesPath = "/debug/karpenter/instancetypes"
+
+// NodePoolInstanceTypes lists the instance types that the scheduler considers for a NodePool. Instance types that the
+// NodePool's EC2NodeClass can't launch at all, e.g. because they don't support its CPU options, aren't listed.
+type NodePoolInstanceTypes struct {
+	InstanceTypes []InstanceType `json:"instanceTypes"`
+	// Error is set if the NodePool's instance types couldn't be resolved, e.g. because its EC2NodeClass isn't ready
+	Error string `json:"error,omitempty"`
+}
+
+// InstanceType is an instance type as the scheduler sees it, after the NodePool's price and availability filters
+type InstanceType struct {
+	Name        string              `json:"name"`
+	Allocatable corev1.ResourceList `json:"allocatable"`
+	Offerings   []Offering          `json:"offerings"`
+	// Eligible is false if the scheduler can't choose the instance type for the NodePool, for the reason in IneligibleReason
+	Eligible         bool   `json:"eligible"`
+	IneligibleReason string `json:"ineligibleReason,omitempty"`
+}
+
+type Offering struct {
+	Zone         string  `json:"zone"`
+	CapacityType string  `json:"capacityType"`
+	Price        float64 `json:"price"`
+	Available    bool    `json:"available"`
+}
+
+type InstanceTypesHandler struct {
+	token         string
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewInstanceTypesHandler(token string, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *InstanceTypesHandler {
+	return &InstanceTypesHandler{
+		token:         token,
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+	}
+}
+
+// ServeHTTP lists the instance types of every NodePool, or of the NodePool named by the nodepool query parameter
+func (h *InstanceTypesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r, h.token, http.MethodGet) {
+		return
+	}
+	nodePools, err := nodepoolutils.ListManaged(r.Context(), h.kubeClient, h.cloudProvider)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "failed listing nodepools")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if name := r.URL.Query().Get("nodepool"); name != "" {
+		nodePools = lo.Filter(nodePools, func(np *karpv1.NodePool, _ int) bool { return np.Name == name })
+		if len(nodePools) == 0 {
+			http.Error(w, fmt.Sprintf("nodepool %q not found", name), http.StatusNotFound)
+			return
+		}
+	}
+	writeJSON(w, lo.SliceToMap(nodePools, func(np *karpv1.NodePool) (string, NodePoolInstanceTypes) {
+		return np.Name, h.InstanceTypes(r, np)
+	}))
+}
+
+func (h *InstanceTypesHandler) InstanceTypes(r *http.Request, nodePool *karpv1.NodePool) NodePoolInstanceTypes {
+	instanceTypes, err := h.cloudProvider.GetInstanceTypes(r.Context(), nodePool)
+	if err != nil {
+		return NodePoolInstanceTypes{InstanceTypes: []InstanceType{}, Error: err.Error()}
+	}
+	// The scheduler requires instance types to be compatible with both the requirements and the labels of the NodePool's template
+	reqs := scheduling.NewNodeSelectorRequirementsWithMinValues(nodePool.Spec.Template.Spec.Requirements...)
+	reqs.Add(scheduling.NewLabelRequirements(nodePool.Spec.Template.Labels).Values()...)
+	result := lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) InstanceType {
+		offerings := lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) Offering {
+			return Offering{
+				Zone:         o.Requirements.Get(corev1.LabelTopologyZone).Any(),
+				CapacityType: o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any(),
+				Price:        o.Price,
+				Available:    o.Available,
+			}
+		})
+		sort.Slice(offerings, func(i, j int) bool {
+			if offerings[i].Zone != offerings[j].Zone {
+				return offerings[i].Zone < offerings[j].Zone
+			}
+			return offerings[i].CapacityType < offerings[j].CapacityType
+		})
+		result := InstanceType{Name: it.Name, Allocatable: it.Allocatable(), Offerings: offerings, Eligible: true}
+		if err := reqs.Compatible(it.Requirements, scheduling.AllowUndefinedWellKnownLabels); err != nil {
+			result.Eligible, result.IneligibleReason = false, fmt.Sprintf("incompatible with nodepool requirements, %s", err)
+		} else if len(it.Offerings.Available().Compatible(reqs)) == 0 {
+			result.Eligible, result.IneligibleReason = false, "no available offerings are compatible with nodepool requirements"
+		}
+		return result
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return NodePoolInstanceTypes{InstanceTypes: result}
+}
@@ -0,0 +1,274 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/controllers/provisioning"
+	"sigs.k8s.io/karpenter/pkg/controllers/provisioning/scheduling"
+	"sigs.k8s.io/karpenter/pkg/controllers/state"
+	"sigs.k8s.io/karpenter/pkg/events"
+	"sigs.k8s.io/karpenter/pkg/operator/injection"
+)
+
+// SimulatePath is the path that the simulate handler is served on from the metrics server
+const SimulatePath = "/debug/karpenter/simulate"
+
+const (
+	maxSimulateBodyBytes = 10 << 20
+	maxSimulateReplicas  = 1000
+)
+
+// Simulation is the result of scheduling a set of pods against the current cluster state without launching anything
+type Simulation struct {
+	NodeClaims    []SimulatedNodeClaim    `json:"nodeClaims,omitempty"`
+	ExistingNodes []SimulatedExistingNode `json:"existingNodes,omitempty"`
+	// PodErrors is keyed by "<namespace>/<name>" of each pod that couldn't be scheduled
+	PodErrors map[string]string `json:"podErrors,omitempty"`
+}
+
+// SimulatedNodeClaim is a NodeClaim that Karpenter would launch for the simulated pods
+type SimulatedNodeClaim struct {
+	NodePool      string                           `json:"nodePool"`
+	Pods          []string                         `json:"pods"`
+	InstanceTypes []string                         `json:"instanceTypes"`
+	Requirements  []corev1.NodeSelectorRequirement `json:"requirements"`
+	CheapestPrice float64                          `json:"cheapestPrice"`
+}
+
+// SimulatedExistingNode is an existing node that the simulated pods would schedule to
+type SimulatedExistingNode struct {
+	Name     string   `json:"name"`
+	NodePool string   `json:"nodePool,omitempty"`
+	Pods     []string `json:"pods"`
+}
+
+type SimulateHandler struct {
+	token         string
+	clk           clock.Clock
+	kubeClient    client.Client
+	cloudProvider cloudprovider.CloudProvider
+}
+
+func NewSimulateHandler(token string, clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider) *SimulateHandler {
+	return &SimulateHandler{
+		token:         token,
+		clk:           clk,
+		kubeClient:    kubeClient,
+		cloudProvider: cloudProvider,
+	}
+}
+
+// ServeHTTP schedules the Pod or PodList in the request body against the cluster's NodePools and existing nodes. The
+// optional "replicas" query parameter schedules that many copies of each pod, e.g. to check a Deployment's pod template.
+func (h *SimulateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r, h.token, http.MethodPost) {
+		return
+	}
+	replicas := 1
+	if v := r.URL.Query().Get("replicas"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSimulateReplicas {
+			http.Error(w, fmt.Sprintf("replicas must be an integer between 1 and %d", maxSimulateReplicas), http.StatusBadRequest)
+			return
+		}
+		replicas = n
+	}
+	pods, err := decodePods(http.MaxBytesReader(w, r.Body, maxSimulateBodyBytes), replicas)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	simulation, err := h.Simulate(injection.WithControllerName(r.Context(), "debug.simulate"), pods)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "failed simulating scheduling")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, simulation)
+}
+
+// Simulate schedules the pods against an in-memory copy of the cluster state, so that nothing is launched and the
+// controller's own cluster state isn't affected
+func (h *SimulateHandler) Simulate(ctx context.Context, pods []*corev1.Pod) (Simulation, error) {
+	cluster, err := h.cluster(ctx)
+	if err != nil {
+		return Simulation{}, err
+	}
+	provisioner := provisioning.NewProvisioner(h.kubeClient, events.NewRecorder(&record.FakeRecorder{}), h.cloudProvider, cluster, h.clk)
+	simulation := Simulation{PodErrors: map[string]string{}}
+	pods = lo.Filter(pods, func(p *corev1.Pod, _ int) bool {
+		if err := provisioner.Validate(ctx, p); err != nil {
+			simulation.PodErrors[podKey(p)] = err.Error()
+			return false
+		}
+		return true
+	})
+	if len(pods) == 0 {
+		return simulation, nil
+	}
+	s, err := provisioner.NewScheduler(ctx, pods, cluster.Nodes().Active())
+	if err != nil {
+		if errors.Is(err, provisioning.ErrNodePoolsNotFound) {
+			for _, p := range pods {
+				simulation.PodErrors[podKey(p)] = "no ready nodepools found"
+			}
+			return simulation, nil
+		}
+		return Simulation{}, fmt.Errorf("creating scheduler, %w", err)
+	}
+	results := s.Solve(ctx, pods).TruncateInstanceTypes(scheduling.MaxInstanceTypes)
+	for _, nc := range results.NewNodeClaims {
+		simulation.NodeClaims = append(simulation.NodeClaims, SimulatedNodeClaim{
+			NodePool:      nc.NodePoolName,
+			Pods:          lo.Map(nc.Pods, func(p *corev1.Pod, _ int) string { return podKey(p) }),
+			InstanceTypes: lo.Map(nc.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) string { return it.Name }),
+			Requirements:  nodeSelectorRequirements(nc.Requirements),
+			CheapestPrice: cheapestPrice(nc),
+		})
+	}
+	for _, n := range results.ExistingNodes {
+		if len(n.Pods) == 0 {
+			continue
+		}
+		simulation.ExistingNodes = append(simulation.ExistingNodes, SimulatedExistingNode{
+			Name:     n.Name(),
+			NodePool: n.Labels()[karpv1.NodePoolLabelKey],
+			Pods:     lo.Map(n.Pods, func(p *corev1.Pod, _ int) string { return podKey(p) }),
+		})
+	}
+	sort.Slice(simulation.ExistingNodes, func(i, j int) bool { return simulation.ExistingNodes[i].Name < simulation.ExistingNodes[j].Name })
+	for p, err := range results.PodErrors {
+		simulation.PodErrors[podKey(p)] = err.Error()
+	}
+	return simulation, nil
+}
+
+// cluster builds cluster state from the live NodeClaims, nodes, pods and daemonsets, in the same way that
+// hack/tools/snapshot_replay does from a snapshot
+func (h *SimulateHandler) cluster(ctx context.Context) (*state.Cluster, error) {
+	nodeClaims := &karpv1.NodeClaimList{}
+	nodes := &corev1.NodeList{}
+	pods := &corev1.PodList{}
+	daemonSets := &appsv1.DaemonSetList{}
+	for _, l := range []client.ObjectList{nodeClaims, nodes, pods, daemonSets} {
+		if err := h.kubeClient.List(ctx, l); err != nil {
+			return nil, fmt.Errorf("listing %T, %w", l, err)
+		}
+	}
+	cluster := state.NewCluster(h.clk, h.kubeClient, h.cloudProvider)
+	for i := range nodeClaims.Items {
+		cluster.UpdateNodeClaim(&nodeClaims.Items[i])
+	}
+	for i := range nodes.Items {
+		if err := cluster.UpdateNode(ctx, &nodes.Items[i]); err != nil {
+			return nil, fmt.Errorf("tracking node %s, %w", nodes.Items[i].Name, err)
+		}
+	}
+	for i := range pods.Items {
+		if err := cluster.UpdatePod(ctx, &pods.Items[i]); err != nil {
+			return nil, fmt.Errorf("tracking pod %s, %w", client.ObjectKeyFromObject(&pods.Items[i]), err)
+		}
+	}
+	for i := range daemonSets.Items {
+		if err := cluster.UpdateDaemonSet(ctx, &daemonSets.Items[i]); err != nil {
+			return nil, fmt.Errorf("tracking daemonset %s, %w", client.ObjectKeyFromObject(&daemonSets.Items[i]), err)
+		}
+	}
+	return cluster, nil
+}
+
+// decodePods reads a Pod or PodList, returning pending copies of each pod. Pods are given a namespace, name and UID
+// where they're missing, since the scheduler tracks pods by these.
+func decodePods(r io.Reader, replicas int) ([]*corev1.Pod, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading request body, %w", err)
+	}
+	var meta struct {
+		Kind string `json:"kind"`
+	}
+	if err = json.Unmarshal(body, &meta); err != nil {
+		return nil, fmt.Errorf("decoding request body, %w", err)
+	}
+	var items []corev1.Pod
+	switch meta.Kind {
+	case "PodList", "List":
+		list := &corev1.PodList{}
+		if err = json.Unmarshal(body, list); err != nil {
+			return nil, fmt.Errorf("decoding pod list, %w", err)
+		}
+		items = list.Items
+	case "Pod", "":
+		pod := corev1.Pod{}
+		if err = json.Unmarshal(body, &pod); err != nil {
+			return nil, fmt.Errorf("decoding pod, %w", err)
+		}
+		items = []corev1.Pod{pod}
+	default:
+		return nil, fmt.Errorf("expected a Pod or PodList, got %s", meta.Kind)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no pods to simulate")
+	}
+	var pods []*corev1.Pod
+	for i := range items {
+		for j := 0; j < replicas; j++ {
+			pod := items[i].DeepCopy()
+			pod.Namespace = lo.Ternary(pod.Namespace == "", "default", pod.Namespace)
+			base := lo.Ternary(pod.Name == "", fmt.Sprintf("simulated-%d", i), pod.Name)
+			pod.Name = lo.Ternary(replicas > 1, fmt.Sprintf("%s-%d", base, j), base)
+			pod.UID = uuid.NewUUID()
+			pod.Spec.NodeName = ""
+			pod.Status = corev1.PodStatus{Phase: corev1.PodPending}
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func cheapestPrice(nc *scheduling.NodeClaim) float64 {
+	prices := lo.FilterMap(nc.InstanceTypeOptions, func(it *cloudprovider.InstanceType, _ int) (float64, bool) {
+		offerings := it.Offerings.Available().Compatible(nc.Requirements)
+		if len(offerings) == 0 {
+			return 0, false
+		}
+		return offerings.Cheapest().Price, true
+	})
+	return lo.Min(prices)
+}
+
+func podKey(p *corev1.Pod) string {
+	return client.ObjectKeyFromObject(p).String()
+}
@@ -0,0 +1,233 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/scheduling"
+	nodepoolutils "sigs.k8s.io/karpenter/pkg/utils/nodepool"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+)
+
+// SnapshotPath is the path that the snapshot handler is served on from the metrics server
+const SnapshotPath = "/debug/karpenter/snapshot"
+
+// Snapshot is a point-in-time copy of the cluster objects and cached cloud provider data that Karpenter schedules and
+// consolidates against. Snapshots are replayed offline by hack/tools/snapshot_replay.
+type Snapshot struct {
+	Time                   time.Time                         `json:"time"`
+	NodePools              []karpv1.NodePool                 `json:"nodePools"`
+	EC2NodeClasses         []v1.EC2NodeClass                 `json:"ec2NodeClasses"`
+	NodeClaims             []karpv1.NodeClaim                `json:"nodeClaims"`
+	Nodes                  []corev1.Node                     `json:"nodes"`
+	Pods                   []corev1.Pod                      `json:"pods"`
+	DaemonSets             []appsv1.DaemonSet                `json:"daemonSets"`
+	PodDisruptionBudgets   []policyv1.PodDisruptionBudget    `json:"podDisruptionBudgets"`
+	PersistentVolumeClaims []corev1.PersistentVolumeClaim    `json:"persistentVolumeClaims"`
+	PersistentVolumes      []corev1.PersistentVolume         `json:"persistentVolumes"`
+	StorageClasses         []storagev1.StorageClass          `json:"storageClasses"`
+	InstanceTypes          map[string][]SnapshotInstanceType `json:"instanceTypes"`
+	UnavailableOfferings   []awscache.UnavailableOffering    `json:"unavailableOfferings"`
+}
+
+// SnapshotInstanceType is the serialized form of an instance type, as returned by the cloud provider for a NodePool
+type SnapshotInstanceType struct {
+	Name              string                           `json:"name"`
+	Requirements      []corev1.NodeSelectorRequirement `json:"requirements"`
+	Capacity          corev1.ResourceList              `json:"capacity"`
+	KubeReserved      corev1.ResourceList              `json:"kubeReserved,omitempty"`
+	SystemReserved    corev1.ResourceList              `json:"systemReserved,omitempty"`
+	EvictionThreshold corev1.ResourceList              `json:"evictionThreshold,omitempty"`
+	Offerings         []SnapshotOffering               `json:"offerings"`
+}
+
+type SnapshotOffering struct {
+	Requirements []corev1.NodeSelectorRequirement `json:"requirements"`
+	Price        float64                          `json:"price"`
+	Available    bool                             `json:"available"`
+}
+
+type SnapshotHandler struct {
+	token                string
+	clk                  clock.Clock
+	kubeClient           client.Client
+	cloudProvider        cloudprovider.CloudProvider
+	unavailableOfferings *awscache.UnavailableOfferings
+}
+
+func NewSnapshotHandler(token string, clk clock.Clock, kubeClient client.Client, cloudProvider cloudprovider.CloudProvider,
+	unavailableOfferings *awscache.UnavailableOfferings) *SnapshotHandler {
+	return &SnapshotHandler{
+		token:                token,
+		clk:                  clk,
+		kubeClient:           kubeClient,
+		cloudProvider:        cloudProvider,
+		unavailableOfferings: unavailableOfferings,
+	}
+}
+
+// ServeHTTP writes the snapshot as gzipped JSON. The snapshot is built in full before anything is written, so that a
+// failure is reported with an error status rather than a truncated archive.
+func (h *SnapshotHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorized(w, r, h.token, http.MethodGet) {
+		return
+	}
+	snapshot, err := h.Snapshot(r.Context())
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "failed building snapshot")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=karpenter-snapshot-%d.json.gz", snapshot.Time.Unix()))
+	gw := gzip.NewWriter(w)
+	if err = json.NewEncoder(gw).Encode(snapshot); err != nil {
+		log.FromContext(r.Context()).Error(err, "failed writing snapshot")
+		return
+	}
+	_ = gw.Close()
+}
+
+func (h *SnapshotHandler) Snapshot(ctx context.Context) (*Snapshot, error) {
+	snapshot := &Snapshot{Time: h.clk.Now(), InstanceTypes: map[string][]SnapshotInstanceType{}}
+	for _, l := range []struct {
+		list  client.ObjectList
+		items func(client.ObjectList)
+	}{
+		{&karpv1.NodePoolList{}, func(l client.ObjectList) { snapshot.NodePools = l.(*karpv1.NodePoolList).Items }},
+		{&v1.EC2NodeClassList{}, func(l client.ObjectList) { snapshot.EC2NodeClasses = l.(*v1.EC2NodeClassList).Items }},
+		{&karpv1.NodeClaimList{}, func(l client.ObjectList) { snapshot.NodeClaims = l.(*karpv1.NodeClaimList).Items }},
+		{&corev1.NodeList{}, func(l client.ObjectList) { snapshot.Nodes = l.(*corev1.NodeList).Items }},
+		{&corev1.PodList{}, func(l client.ObjectList) { snapshot.Pods = l.(*corev1.PodList).Items }},
+		{&appsv1.DaemonSetList{}, func(l client.ObjectList) { snapshot.DaemonSets = l.(*appsv1.DaemonSetList).Items }},
+		{&policyv1.PodDisruptionBudgetList{}, func(l client.ObjectList) {
+			snapshot.PodDisruptionBudgets = l.(*policyv1.PodDisruptionBudgetList).Items
+		}},
+		{&corev1.PersistentVolumeClaimList{}, func(l client.ObjectList) {
+			snapshot.PersistentVolumeClaims = l.(*corev1.PersistentVolumeClaimList).Items
+		}},
+		{&corev1.PersistentVolumeList{}, func(l client.ObjectList) { snapshot.PersistentVolumes = l.(*corev1.PersistentVolumeList).Items }},
+		{&storagev1.StorageClassList{}, func(l client.ObjectList) { snapshot.StorageClasses = l.(*storagev1.StorageClassList).Items }},
+	} {
+		if err := h.kubeClient.List(ctx, l.list); err != nil {
+			return nil, fmt.Errorf("listing %T, %w", l.list, err)
+		}
+		l.items(l.list)
+	}
+	// Container environments aren't needed to simulate scheduling and may hold credentials, so they're left out
+	for i := range snapshot.Pods {
+		for j := range snapshot.Pods[i].Spec.Containers {
+			snapshot.Pods[i].Spec.Containers[j].Env = nil
+		}
+		for j := range snapshot.Pods[i].Spec.InitContainers {
+			snapshot.Pods[i].Spec.InitContainers[j].Env = nil
+		}
+	}
+	nodePools, err := nodepoolutils.ListManaged(ctx, h.kubeClient, h.cloudProvider)
+	if err != nil {
+		return nil, fmt.Errorf("listing nodepools, %w", err)
+	}
+	for _, np := range nodePools {
+		instanceTypes, err := h.cloudProvider.GetInstanceTypes(ctx, np)
+		if err != nil {
+			// The NodePool's instance types are left out, as they would be when scheduling
+			log.FromContext(ctx).WithValues("NodePool", np.Name).Error(err, "failed resolving instance types for snapshot")
+			continue
+		}
+		snapshot.InstanceTypes[np.Name] = lo.Map(instanceTypes, func(it *cloudprovider.InstanceType, _ int) SnapshotInstanceType {
+			return NewSnapshotInstanceType(it)
+		})
+	}
+	snapshot.UnavailableOfferings = h.unavailableOfferings.List()
+	sort.Slice(snapshot.UnavailableOfferings, func(i, j int) bool {
+		return snapshot.UnavailableOfferings[i].InstanceType < snapshot.UnavailableOfferings[j].InstanceType
+	})
+	return snapshot, nil
+}
+
+func NewSnapshotInstanceType(it *cloudprovider.InstanceType) SnapshotInstanceType {
+	result := SnapshotInstanceType{
+		Name:         it.Name,
+		Requirements: nodeSelectorRequirements(it.Requirements),
+		Capacity:     it.Capacity,
+		Offerings: lo.Map(it.Offerings, func(o cloudprovider.Offering, _ int) SnapshotOffering {
+			return SnapshotOffering{Requirements: nodeSelectorRequirements(o.Requirements), Price: o.Price, Available: o.Available}
+		}),
+	}
+	if it.Overhead != nil {
+		result.KubeReserved, result.SystemReserved, result.EvictionThreshold = it.Overhead.KubeReserved, it.Overhead.SystemReserved, it.Overhead.EvictionThreshold
+	}
+	return result
+}
+
+// InstanceType converts the instance type back to the form the scheduler uses
+func (s SnapshotInstanceType) InstanceType() *cloudprovider.InstanceType {
+	return &cloudprovider.InstanceType{
+		Name:         s.Name,
+		Requirements: scheduling.NewNodeSelectorRequirements(s.Requirements...),
+		Capacity:     s.Capacity,
+		Overhead: &cloudprovider.InstanceTypeOverhead{
+			KubeReserved:      s.KubeReserved,
+			SystemReserved:    s.SystemReserved,
+			EvictionThreshold: s.EvictionThreshold,
+		},
+		Offerings: lo.Map(s.Offerings, func(o SnapshotOffering, _ int) cloudprovider.Offering {
+			return cloudprovider.Offering{Requirements: scheduling.NewNodeSelectorRequirements(o.Requirements...), Price: o.Price, Available: o.Available}
+		}),
+	}
+}
+
+func nodeSelectorRequirements(requirements scheduling.Requirements) []corev1.NodeSelectorRequirement {
+	result := lo.Map(requirements.NodeSelectorRequirements(), func(r karpv1.NodeSelectorRequirementWithMinValues, _ int) corev1.NodeSelectorRequirement {
+		return r.NodeSelectorRequirement
+	})
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// ReadSnapshot reads a snapshot in the gzipped JSON form that it's served in
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing snapshot, %w", err)
+	}
+	defer gr.Close()
+	snapshot := &Snapshot{}
+	if err = json.NewDecoder(gr).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("decoding snapshot, %w", err)
+	}
+	return snapshot, nil
+}
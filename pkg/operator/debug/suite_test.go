@@ -0,0 +1,389 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/awslabs/operatorpkg/status"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	clock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	corecloudprovider "sigs.k8s.io/karpenter/pkg/cloudprovider"
+	"sigs.k8s.io/karpenter/pkg/events"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/apis"
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/debug"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/test/expectations"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var stop context.CancelFunc
+var env *coretest.Environment
+var awsEnv *test.Environment
+var fakeClock *clock.FakeClock
+var handler *debug.Handler
+var instanceTypesHandler *debug.InstanceTypesHandler
+var snapshotHandler *debug.SnapshotHandler
+var simulateHandler *debug.SimulateHandler
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Debug")
+}
+
+var _ = BeforeSuite(func() {
+	env = coretest.NewEnvironment(coretest.WithCRDs(apis.CRDs...))
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	ctx = options.ToContext(ctx, test.Options())
+	ctx, stop = context.WithCancel(ctx)
+	awsEnv = test.NewEnvironment(ctx, env)
+	fakeClock = clock.NewFakeClock(time.Now())
+	handler = debug.NewHandler("test-token", fakeClock, env.Client, awsEnv.UnavailableOfferingsCache, awsEnv.PricingProvider)
+	cloudProvider := cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
+	instanceTypesHandler = debug.NewInstanceTypesHandler("test-token", env.Client, cloudProvider)
+	snapshotHandler = debug.NewSnapshotHandler("test-token", fakeClock, env.Client, cloudProvider, awsEnv.UnavailableOfferingsCache)
+	simulateHandler = debug.NewSimulateHandler("test-token", fakeClock, env.Client, cloudProvider)
+})
+
+var _ = AfterSuite(func() {
+	stop()
+	Expect(env.Stop()).To(Succeed(), "Failed to stop environment")
+})
+
+var _ = BeforeEach(func() {
+	awsEnv.Reset()
+})
+
+var _ = AfterEach(func() {
+	ExpectCleanedUp(ctx, env.Client)
+})
+
+func serve(method, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, debug.Path, nil).WithContext(ctx)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+var _ = Describe("Debug", func() {
+	It("should reject requests without a valid token", func() {
+		Expect(serve(http.MethodGet, "").Code).To(Equal(http.StatusUnauthorized))
+		Expect(serve(http.MethodGet, "wrong-token").Code).To(Equal(http.StatusUnauthorized))
+	})
+	It("should reject requests that aren't a GET", func() {
+		Expect(serve(http.MethodPost, "test-token").Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+	It("should report unavailable offerings", func() {
+		awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "m5.large", "test-zone-1a", karpv1.CapacityTypeSpot)
+		rec := serve(http.MethodGet, "test-token")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		state := debug.State{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &state)).To(Succeed())
+		Expect(state.UnavailableOfferings).To(HaveLen(1))
+		Expect(state.UnavailableOfferings[0].InstanceType).To(Equal("m5.large"))
+		Expect(state.UnavailableOfferings[0].Zone).To(Equal("test-zone-1a"))
+		Expect(state.UnavailableOfferings[0].CapacityType).To(Equal(karpv1.CapacityTypeSpot))
+	})
+	It("should report in-flight NodeClaims per NodePool", func() {
+		nodePool := coretest.NodePool()
+		launching := coretest.NodeClaim(karpv1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{karpv1.NodePoolLabelKey: nodePool.Name}}})
+		registering := coretest.NodeClaim(karpv1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{karpv1.NodePoolLabelKey: nodePool.Name}}})
+		registered := coretest.NodeClaim(karpv1.NodeClaim{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{karpv1.NodePoolLabelKey: nodePool.Name}}})
+		ExpectApplied(ctx, env.Client, nodePool, launching, registering, registered)
+		registering.StatusConditions().SetTrue(karpv1.ConditionTypeLaunched)
+		registered.StatusConditions().SetTrue(karpv1.ConditionTypeLaunched)
+		registered.StatusConditions().SetTrue(karpv1.ConditionTypeRegistered)
+		for _, nc := range []client.Object{registering, registered} {
+			Expect(env.Client.Status().Update(ctx, nc)).To(Succeed())
+		}
+
+		rec := serve(http.MethodGet, "test-token")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		state := debug.State{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &state)).To(Succeed())
+		Expect(state.NodePools).To(HaveKeyWithValue(nodePool.Name, debug.NodePoolState{Launching: 1, Registering: 1}))
+	})
+	It("should omit pricing ages when pricing hasn't been refreshed", func() {
+		rec := serve(http.MethodGet, "test-token")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		state := debug.State{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &state)).To(Succeed())
+		Expect(state.Pricing.OnDemandLastUpdated.IsZero()).To(BeTrue())
+		Expect(state.Pricing.OnDemandAge).To(BeEmpty())
+		Expect(state.Pricing.SpotLastUpdated.IsZero()).To(BeTrue())
+		Expect(state.Pricing.SpotAge).To(BeEmpty())
+	})
+	Context("Instance Types", func() {
+		var nodeClass *v1.EC2NodeClass
+		var nodePool *karpv1.NodePool
+		serveInstanceTypes := func(token, query string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, debug.InstanceTypesPath+query, nil).WithContext(ctx)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			instanceTypesHandler.ServeHTTP(rec, req)
+			return rec
+		}
+		instanceTypes := func(query string) map[string]debug.NodePoolInstanceTypes {
+			rec := serveInstanceTypes("test-token", query)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			result := map[string]debug.NodePoolInstanceTypes{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+			return result
+		}
+		BeforeEach(func() {
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+			nodeClass = test.EC2NodeClass()
+			nodeClass.StatusConditions().SetTrue(status.ConditionReady)
+			nodePool = coretest.NodePool(karpv1.NodePool{
+				Spec: karpv1.NodePoolSpec{
+					Template: karpv1.NodeClaimTemplate{
+						Spec: karpv1.NodeClaimTemplateSpec{
+							NodeClassRef: &karpv1.NodeClassReference{
+								Group: "karpenter.k8s.aws",
+								Kind:  "EC2NodeClass",
+								Name:  nodeClass.Name,
+							},
+						},
+					},
+				},
+			})
+		})
+		It("should reject requests without a valid token", func() {
+			Expect(serveInstanceTypes("wrong-token", "").Code).To(Equal(http.StatusUnauthorized))
+		})
+		It("should list the instance types of each NodePool with their allocatable and offerings", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			result := instanceTypes("")
+			Expect(result).To(HaveKey(nodePool.Name))
+			Expect(result[nodePool.Name].Error).To(BeEmpty())
+			it, ok := lo.Find(result[nodePool.Name].InstanceTypes, func(it debug.InstanceType) bool { return it.Name == "m5.large" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Eligible).To(BeTrue())
+			Expect(it.Allocatable).To(HaveKey(corev1.ResourceCPU))
+			Expect(it.Offerings).ToNot(BeEmpty())
+			for _, o := range it.Offerings {
+				Expect(o.Zone).ToNot(BeEmpty())
+				Expect(o.CapacityType).To(BeElementOf(karpv1.CapacityTypeSpot, karpv1.CapacityTypeOnDemand))
+				Expect(o.Price).To(BeNumerically(">", 0))
+			}
+		})
+		It("should explain why instance types are ineligible", func() {
+			nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{
+				NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelInstanceTypeStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"m5.large"}},
+			}}
+			awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "m5.large", "test-zone-1a", karpv1.CapacityTypeSpot)
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			result := instanceTypes("?nodepool=" + nodePool.Name)
+			for _, it := range result[nodePool.Name].InstanceTypes {
+				if it.Name == "m5.large" {
+					Expect(it.Eligible).To(BeTrue())
+					offering, ok := lo.Find(it.Offerings, func(o debug.Offering) bool {
+						return o.Zone == "test-zone-1a" && o.CapacityType == karpv1.CapacityTypeSpot
+					})
+					Expect(ok).To(BeTrue())
+					Expect(offering.Available).To(BeFalse())
+					continue
+				}
+				Expect(it.Eligible).To(BeFalse())
+				Expect(it.IneligibleReason).To(ContainSubstring(corev1.LabelInstanceTypeStable))
+			}
+		})
+		It("should report NodePools whose instance types can't be resolved", func() {
+			// The NodePool's EC2NodeClass doesn't exist
+			ExpectApplied(ctx, env.Client, nodePool)
+			result := instanceTypes("")
+			Expect(result[nodePool.Name].Error).ToNot(BeEmpty())
+			Expect(result[nodePool.Name].InstanceTypes).To(BeEmpty())
+		})
+		It("should return not found for an unknown NodePool", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			Expect(serveInstanceTypes("test-token", "?nodepool=unknown").Code).To(Equal(http.StatusNotFound))
+		})
+	})
+	Context("Snapshot", func() {
+		var nodeClass *v1.EC2NodeClass
+		var nodePool *karpv1.NodePool
+		serveSnapshot := func(token string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, debug.SnapshotPath, nil).WithContext(ctx)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rec := httptest.NewRecorder()
+			snapshotHandler.ServeHTTP(rec, req)
+			return rec
+		}
+		BeforeEach(func() {
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+			nodeClass = test.EC2NodeClass()
+			nodeClass.StatusConditions().SetTrue(status.ConditionReady)
+			nodePool = coretest.NodePool(karpv1.NodePool{
+				Spec: karpv1.NodePoolSpec{
+					Template: karpv1.NodeClaimTemplate{
+						Spec: karpv1.NodeClaimTemplateSpec{
+							NodeClassRef: &karpv1.NodeClassReference{
+								Group: "karpenter.k8s.aws",
+								Kind:  "EC2NodeClass",
+								Name:  nodeClass.Name,
+							},
+						},
+					},
+				},
+			})
+		})
+		It("should reject requests without a valid token", func() {
+			Expect(serveSnapshot("wrong-token").Code).To(Equal(http.StatusUnauthorized))
+		})
+		It("should snapshot cluster objects and the instance types of each NodePool", func() {
+			pod := coretest.UnschedulablePod()
+			pod.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "PASSWORD", Value: "secret"}}
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool, pod)
+			awsEnv.UnavailableOfferingsCache.MarkUnavailable(ctx, "test", "m5.large", "test-zone-1a", karpv1.CapacityTypeSpot)
+
+			rec := serveSnapshot("test-token")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Header().Get("Content-Type")).To(Equal("application/gzip"))
+			snapshot, err := debug.ReadSnapshot(rec.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshot.NodePools).To(HaveLen(1))
+			Expect(snapshot.EC2NodeClasses).To(HaveLen(1))
+			Expect(snapshot.Pods).To(HaveLen(1))
+			Expect(snapshot.Pods[0].Spec.Containers[0].Env).To(BeEmpty())
+			Expect(snapshot.UnavailableOfferings).To(HaveLen(1))
+
+			it, ok := lo.Find(snapshot.InstanceTypes[nodePool.Name], func(it debug.SnapshotInstanceType) bool { return it.Name == "m5.large" })
+			Expect(ok).To(BeTrue())
+			instanceType := it.InstanceType()
+			Expect(instanceType.Requirements.Get(corev1.LabelInstanceTypeStable).Has("m5.large")).To(BeTrue())
+			Expect(instanceType.Allocatable()).To(HaveKey(corev1.ResourceCPU))
+			offering, ok := lo.Find(instanceType.Offerings, func(o corecloudprovider.Offering) bool {
+				return o.Requirements.Get(corev1.LabelTopologyZone).Any() == "test-zone-1a" && o.Requirements.Get(karpv1.CapacityTypeLabelKey).Any() == karpv1.CapacityTypeSpot
+			})
+			Expect(ok).To(BeTrue())
+			Expect(offering.Available).To(BeFalse())
+			Expect(offering.Price).To(BeNumerically(">", 0))
+		})
+	})
+	Context("Simulate", func() {
+		var nodeClass *v1.EC2NodeClass
+		var nodePool *karpv1.NodePool
+		serveSimulate := func(method, query string, body []byte) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, debug.SimulatePath+query, bytes.NewReader(body)).WithContext(ctx)
+			req.Header.Set("Authorization", "Bearer test-token")
+			rec := httptest.NewRecorder()
+			simulateHandler.ServeHTTP(rec, req)
+			return rec
+		}
+		simulate := func(query string, obj any) debug.Simulation {
+			body, err := json.Marshal(obj)
+			Expect(err).ToNot(HaveOccurred())
+			rec := serveSimulate(http.MethodPost, query, body)
+			Expect(rec.Code).To(Equal(http.StatusOK), rec.Body.String())
+			simulation := debug.Simulation{}
+			Expect(json.Unmarshal(rec.Body.Bytes(), &simulation)).To(Succeed())
+			return simulation
+		}
+		BeforeEach(func() {
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+			nodeClass = test.EC2NodeClass()
+			nodeClass.StatusConditions().SetTrue(status.ConditionReady)
+			nodePool = coretest.NodePool(karpv1.NodePool{
+				Spec: karpv1.NodePoolSpec{
+					Template: karpv1.NodeClaimTemplate{
+						Spec: karpv1.NodeClaimTemplateSpec{
+							NodeClassRef: &karpv1.NodeClassReference{
+								Group: "karpenter.k8s.aws",
+								Kind:  "EC2NodeClass",
+								Name:  nodeClass.Name,
+							},
+						},
+					},
+				},
+			})
+		})
+		It("should reject requests that aren't a POST", func() {
+			Expect(serveSimulate(http.MethodGet, "", nil).Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+		It("should reject request bodies that aren't a pod", func() {
+			Expect(serveSimulate(http.MethodPost, "", []byte("{")).Code).To(Equal(http.StatusBadRequest))
+			Expect(serveSimulate(http.MethodPost, "", []byte(`{"kind":"Deployment"}`)).Code).To(Equal(http.StatusBadRequest))
+			Expect(serveSimulate(http.MethodPost, "?replicas=0", []byte(`{"kind":"Pod"}`)).Code).To(Equal(http.StatusBadRequest))
+		})
+		It("should return the NodeClaims that would be launched for a pod without creating them", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				ResourceRequirements: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+			})
+			simulation := simulate("", pod)
+			Expect(simulation.PodErrors).To(BeEmpty())
+			Expect(simulation.NodeClaims).To(HaveLen(1))
+			Expect(simulation.NodeClaims[0].NodePool).To(Equal(nodePool.Name))
+			Expect(simulation.NodeClaims[0].Pods).To(ConsistOf(client.ObjectKeyFromObject(pod).String()))
+			Expect(simulation.NodeClaims[0].InstanceTypes).ToNot(BeEmpty())
+			Expect(simulation.NodeClaims[0].CheapestPrice).To(BeNumerically(">", 0))
+
+			nodeClaims := &karpv1.NodeClaimList{}
+			Expect(env.Client.List(ctx, nodeClaims)).To(Succeed())
+			Expect(nodeClaims.Items).To(BeEmpty())
+		})
+		It("should simulate replicas of each pod in a pod list", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			pods := &corev1.PodList{TypeMeta: metav1.TypeMeta{Kind: "PodList"}, Items: []corev1.Pod{*coretest.UnschedulablePod(), *coretest.UnschedulablePod()}}
+			simulation := simulate("?replicas=3", pods)
+			Expect(simulation.PodErrors).To(BeEmpty())
+			Expect(lo.SumBy(simulation.NodeClaims, func(nc debug.SimulatedNodeClaim) int { return len(nc.Pods) })).To(Equal(6))
+		})
+		It("should report pods that can't be scheduled", func() {
+			ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{corev1.LabelInstanceTypeStable: "unknown"}})
+			simulation := simulate("", pod)
+			Expect(simulation.NodeClaims).To(BeEmpty())
+			Expect(simulation.PodErrors).To(HaveKey(client.ObjectKeyFromObject(pod).String()))
+		})
+		It("should report pods as unschedulable when there are no NodePools", func() {
+			pod := coretest.UnschedulablePod()
+			simulation := simulate("", pod)
+			Expect(simulation.PodErrors).To(HaveKey(client.ObjectKeyFromObject(pod).String()))
+		})
+	})
+})
@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faultinjection
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+const (
+	// ErrorCode is the code of the errors returned by calls that a fault was injected into
+	ErrorCode = "InternalError"
+
+	faultTypeError = "error"
+	faultTypeDelay = "delay"
+)
+
+// serviceIDs maps the names accepted by --fault-injection-services to the service IDs of their SDK clients
+var serviceIDs = map[string]string{
+	"ec2":     ec2.ServiceID,
+	"pricing": pricing.ServiceID,
+	"sqs":     sqs.ServiceID,
+}
+
+var FaultsInjected = opmetrics.NewPrometheusCounter(
+	crmetrics.Registry,
+	prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "cloudprovider",
+		Name:      "faults_injected_total",
+		Help:      "Number of faults injected into AWS API calls by the FaultInjection AWS feature gate, broken down by service, operation and fault type.",
+	},
+	[]string{"service", "operation", "type"},
+)
+
+// Middleware delays and fails a random percentage of the calls to the targeted services, before they're sent. Faults are
+// injected ahead of the SDK's retries so that the controllers see them, as they would a persistent API failure.
+type Middleware struct {
+	services     sets.Set[string]
+	errorPercent float64
+	delayPercent float64
+	delay        time.Duration
+	// random returns a pseudo-random number in [0, 1)
+	random func() float64
+}
+
+func NewMiddleware(ctx context.Context) *Middleware {
+	return &Middleware{
+		services: sets.New(lo.FilterMap(options.FromContext(ctx).FaultInjectionServiceNames(), func(name string, _ int) (string, bool) {
+			id, ok := serviceIDs[name]
+			return id, ok
+		})...),
+		errorPercent: options.FromContext(ctx).FaultInjectionErrorPercent,
+		delayPercent: options.FromContext(ctx).FaultInjectionDelayPercent,
+		delay:        options.FromContext(ctx).FaultInjectionDelay,
+		random:       rand.Float64,
+	}
+}
+
+// WithFaultInjection adds the fault injection middleware to every client that's created from the config
+func WithFaultInjection(ctx context.Context, cfg aws.Config) aws.Config {
+	m := NewMiddleware(ctx)
+	log.FromContext(ctx).WithValues(
+		"services", sets.List(m.services),
+		"error-percent", m.errorPercent,
+		"delay-percent", m.delayPercent,
+		"delay", m.delay,
+	).Info("injecting faults into AWS API calls, this must never be enabled in production clusters")
+	cfg.APIOptions = append(cfg.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(m, middleware.After)
+	})
+	return cfg
+}
+
+func (m *Middleware) ID() string {
+	return "KarpenterFaultInjection"
+}
+
+func (m *Middleware) HandleInitialize(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+	if !m.services.Has(service) {
+		return next.HandleInitialize(ctx, in)
+	}
+	if m.delay > 0 && m.random()*100 < m.delayPercent {
+		FaultsInjected.Inc(map[string]string{"service": service, "operation": operation, "type": faultTypeDelay})
+		select {
+		case <-time.After(time.Duration(m.random() * float64(m.delay))):
+		case <-ctx.Done():
+			return middleware.InitializeOutput{}, middleware.Metadata{}, ctx.Err()
+		}
+	}
+	if m.random()*100 < m.errorPercent {
+		FaultsInjected.Inc(map[string]string{"service": service, "operation": operation, "type": faultTypeError})
+		return middleware.InitializeOutput{}, middleware.Metadata{}, &smithy.GenericAPIError{
+			Code:    ErrorCode,
+			Message: fmt.Sprintf("fault injected by the %s aws feature gate", options.FaultInjection),
+			Fault:   smithy.FaultServer,
+		}
+	}
+	return next.HandleInitialize(ctx, in)
+}
@@ -0,0 +1,111 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package faultinjection_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/samber/lo"
+	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
+	coretest "sigs.k8s.io/karpenter/pkg/test"
+
+	"github.com/aws/karpenter-provider-aws/pkg/operator/faultinjection"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+var calls int
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "FaultInjection")
+}
+
+var _ = BeforeEach(func() {
+	ctx = coreoptions.ToContext(ctx, coretest.Options())
+	calls = 0
+})
+
+var next = middleware.InitializeHandlerFunc(func(context.Context, middleware.InitializeInput) (middleware.InitializeOutput, middleware.Metadata, error) {
+	calls++
+	return middleware.InitializeOutput{}, middleware.Metadata{}, nil
+})
+
+func handle(service string) error {
+	_, _, err := faultinjection.NewMiddleware(ctx).HandleInitialize(awsmiddleware.SetServiceID(ctx, service), middleware.InitializeInput{}, next)
+	return err
+}
+
+var _ = Describe("FaultInjection", func() {
+	It("should fail every call when the error percent is 100", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{FaultInjectionErrorPercent: lo.ToPtr(100.0)}))
+		err := handle("EC2")
+		var apiErr smithy.APIError
+		Expect(errors.As(err, &apiErr)).To(BeTrue())
+		Expect(apiErr.ErrorCode()).To(Equal(faultinjection.ErrorCode))
+		Expect(calls).To(Equal(0))
+	})
+	It("should pass calls through when the error and delay percents are 0", func() {
+		ctx = options.ToContext(ctx, test.Options())
+		Expect(handle("EC2")).To(Succeed())
+		Expect(handle("Pricing")).To(Succeed())
+		Expect(handle("SQS")).To(Succeed())
+		Expect(calls).To(Equal(3))
+	})
+	It("should only inject faults into the targeted services", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			FaultInjectionErrorPercent: lo.ToPtr(100.0),
+			FaultInjectionServices:     lo.ToPtr("pricing, SQS"),
+		}))
+		Expect(handle("EC2")).To(Succeed())
+		Expect(handle("IAM")).To(Succeed())
+		Expect(handle("Pricing")).ToNot(Succeed())
+		Expect(handle("SQS")).ToNot(Succeed())
+		Expect(calls).To(Equal(2))
+	})
+	It("should delay calls by up to the fault injection delay", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			FaultInjectionDelayPercent: lo.ToPtr(100.0),
+			FaultInjectionDelay:        lo.ToPtr(50 * time.Millisecond),
+		}))
+		start := time.Now()
+		Expect(handle("EC2")).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(calls).To(Equal(1))
+	})
+	It("should stop delaying calls when the context is cancelled", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			FaultInjectionDelayPercent: lo.ToPtr(100.0),
+			FaultInjectionDelay:        lo.ToPtr(time.Hour),
+		}))
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		cancel()
+		Expect(handle("EC2")).To(MatchError(context.Canceled))
+		Expect(calls).To(Equal(0))
+	})
+})
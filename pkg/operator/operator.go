@@ -15,31 +15,41 @@ limitations under the License.
 package operator
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	stdlog "log"
 	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	config "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 
 	"github.com/aws/smithy-go"
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
+	"golang.org/x/net/http/httpproxy"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/transport"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	clinetconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -47,6 +57,7 @@ import (
 	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/events"
 	"sigs.k8s.io/karpenter/pkg/operator"
 
 	prometheusv2 "github.com/jonathan-innis/aws-sdk-go-prometheus/v2"
@@ -55,17 +66,31 @@ import (
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
+	awsevents "github.com/aws/karpenter-provider-aws/pkg/events"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/debug"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/faultinjection"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accessentry"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amiprovenance"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/carbonintensity"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/diagnostics"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/elasticip"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchrole"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/leakedresource"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	ssmp "github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
 )
 
 func init() {
@@ -75,20 +100,30 @@ func init() {
 // Operator is injected into the AWS CloudProvider's factories
 type Operator struct {
 	*operator.Operator
-	Config                    aws.Config
-	UnavailableOfferingsCache *awscache.UnavailableOfferings
-	SSMCache                  *cache.Cache
-	SubnetProvider            subnet.Provider
-	SecurityGroupProvider     securitygroup.Provider
-	InstanceProfileProvider   instanceprofile.Provider
-	AMIProvider               amifamily.Provider
-	AMIResolver               amifamily.Resolver
-	LaunchTemplateProvider    launchtemplate.Provider
-	PricingProvider           pricing.Provider
-	VersionProvider           *version.DefaultProvider
-	InstanceTypesProvider     *instancetype.DefaultProvider
-	InstanceProvider          instance.Provider
-	SSMProvider               ssmp.Provider
+	Config                      aws.Config
+	UnavailableOfferingsCache   *awscache.UnavailableOfferings
+	SSMCache                    *cache.Cache
+	SubnetProvider              subnet.Provider
+	SecurityGroupProvider       securitygroup.Provider
+	InstanceProfileProvider     instanceprofile.Provider
+	AMIProvider                 amifamily.Provider
+	AMIResolver                 amifamily.Resolver
+	AMIProvenanceProvider       amiprovenance.Provider
+	LaunchTemplateProvider      launchtemplate.Provider
+	PricingProvider             pricing.Provider
+	QuotaProvider               quota.Provider
+	CarbonIntensityProvider     carbonintensity.Provider
+	ElasticIPProvider           elasticip.Provider
+	LeakedResourceProvider      leakedresource.Provider
+	KMSProvider                 kms.Provider
+	CapacityReservationProvider capacityreservation.Provider
+	VPCEndpointProvider         vpcendpoint.Provider
+	AccessEntryProvider         accessentry.Provider
+	DiagnosticsProvider         diagnostics.Provider
+	VersionProvider             *version.DefaultProvider
+	InstanceTypesProvider       *instancetype.DefaultProvider
+	InstanceProvider            instance.Provider
+	SSMProvider                 ssmp.Provider
 }
 
 func NewOperator(ctx context.Context, operator *operator.Operator) (context.Context, *Operator) {
@@ -107,7 +142,9 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		stdlog.Fatalf("The kubelet compatibility annotation, %s, is not supported on Karpenter v1.1+. Please refer to the upgrade guide in the docs. The following NodePools still have the compatibility annotation: %s", kubeletCompatibilityAnnotationKey, strings.Join(npNames, ", "))
 	}
 
-	cfg := prometheusv2.WithPrometheusMetrics(WithUserAgent(lo.Must(config.LoadDefaultConfig(ctx))), crmetrics.Registry)
+	log.FromContext(ctx).WithValues("aws-feature-gates", options.FromContext(ctx).AWSFeatureGates.String()).Info("configured feature gates")
+
+	cfg := prometheusv2.WithPrometheusMetrics(WithUserAgent(lo.Must(config.LoadDefaultConfig(ctx, append(WithProxy(ctx), WithFIPSEndpoints(ctx)...)...))), crmetrics.Registry)
 	if cfg.Region == "" {
 		log.FromContext(ctx).V(1).Info("retrieving region from IMDS")
 		region := lo.Must(imds.NewFromConfig(cfg).GetRegion(ctx, nil))
@@ -136,35 +173,62 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 	} else {
 		log.FromContext(ctx).WithValues("kube-dns-ip", kubeDNSIP).V(1).Info("discovered kube dns")
 	}
+	// Faults are only injected once the startup checks have passed, so that they don't crash loop the controller
+	if options.FromContext(ctx).AWSFeatureGates.Enabled(options.FaultInjection) {
+		cfg = faultinjection.WithFaultInjection(ctx, cfg)
+		ec2api = ec2.NewFromConfig(cfg)
+	}
 	unavailableOfferingsCache := awscache.NewUnavailableOfferings()
 	ssmCache := cache.New(awscache.SSMCacheTTL, awscache.DefaultCleanupInterval)
 
 	subnetProvider := subnet.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval), cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval))
 	securityGroupProvider := securitygroup.NewDefaultProvider(ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
-	instanceProfileProvider := instanceprofile.NewDefaultProvider(cfg.Region, iam.NewFromConfig(cfg), cache.New(awscache.InstanceProfileTTL, awscache.DefaultCleanupInterval))
+	iamapi := iam.NewFromConfig(cfg)
+	instanceProfileProvider := instanceprofile.NewDefaultProvider(operator.Clock, cfg.Region, iamapi, cache.New(awscache.InstanceProfileTTL, awscache.DefaultCleanupInterval))
 	pricingProvider := pricing.NewDefaultProvider(
 		ctx,
 		pricing.NewAPI(cfg),
 		ec2api,
 		cfg.Region,
 	)
+	// Events are annotated with structured details of the involved object, including the events of upstream controllers
+	operator.EventRecorder = events.NewRecorder(awsevents.NewEventRecorder(operator.GetEventRecorderFor("karpenter"), pricingProvider))
+	var capacityLedger quota.Ledger
+	if kubeconfig := options.FromContext(ctx).CapacityLedgerKubeconfig; kubeconfig != "" {
+		hubConfig := lo.Must(clientcmd.BuildConfigFromFlags("", kubeconfig))
+		capacityLedger = quota.NewConfigMapLedger(kubernetes.NewForConfigOrDie(hubConfig), options.FromContext(ctx).CapacityLedgerNamespace, options.FromContext(ctx).ClusterName, operator.Clock)
+	}
+	quotaProvider := quota.NewDefaultProvider(operator.Clock, ec2api, servicequotas.NewFromConfig(cfg), capacityLedger)
+	elasticIPProvider := elasticip.NewDefaultProvider(ec2api)
+	leakedResourceProvider := leakedresource.NewDefaultProvider(operator.Clock, ec2api)
+	capacityReservationProvider := capacityreservation.NewDefaultProvider(ec2api)
+	vpcEndpointProvider := vpcendpoint.NewDefaultProvider(cfg.Region, ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
+	accessEntryProvider := accessentry.NewDefaultProvider(eksapi, iamapi, operator.KubernetesInterface, cache.New(awscache.InstanceProfileTTL, awscache.DefaultCleanupInterval))
+	diagnosticsProvider := diagnostics.NewDefaultProvider(ec2api, sqs.NewFromConfig(cfg), clusterEndpoint)
+	kmsProvider := kms.NewDefaultProvider(awskms.NewFromConfig(cfg), cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
 	versionProvider := version.NewDefaultProvider(operator.KubernetesInterface, eksapi)
 	// Ensure we're able to hydrate the version before starting any reliant controllers.
 	// Version updates are hydrated asynchronously after this, in the event of a failure
 	// the previously resolved value will be used.
 	lo.Must0(versionProvider.UpdateVersion(ctx))
-	ssmProvider := ssmp.NewDefaultProvider(ssm.NewFromConfig(cfg), ssmCache)
+	ssmapi := ssm.NewFromConfig(cfg)
+	ssmProvider := ssmp.NewDefaultProvider(ssmapi, ssmCache)
 	amiProvider := amifamily.NewDefaultProvider(operator.Clock, versionProvider, ssmProvider, ec2api, cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval))
 	amiResolver := amifamily.NewDefaultResolver()
+	amiProvenanceProvider := amiprovenance.NewDefaultProvider(ec2api, ssmapi, cache.New(awscache.AMIProvenanceTTL, awscache.DefaultCleanupInterval))
+	carbonIntensityProvider := carbonintensity.NewDefaultProvider(ssmapi, cfg.Region)
 	launchTemplateProvider := launchtemplate.NewDefaultProvider(
 		ctx,
+		operator.Clock,
 		cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval),
 		ec2api,
 		eksapi,
 		amiResolver,
 		securityGroupProvider,
 		subnetProvider,
-		lo.Must(GetCABundle(ctx, operator.GetConfig())),
+		operator.KubernetesInterface,
+		cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval),
+		lo.Must(GetCABundle(ctx, operator.GetConfig(), eksapi)),
 		operator.Elected(),
 		kubeDNSIP,
 		clusterEndpoint,
@@ -174,33 +238,57 @@ func NewOperator(ctx context.Context, operator *operator.Operator) (context.Cont
 		cache.New(awscache.DiscoveredCapacityCacheTTL, awscache.DefaultCleanupInterval),
 		ec2api,
 		subnetProvider,
-		instancetype.NewDefaultResolver(cfg.Region, pricingProvider, unavailableOfferingsCache),
+		instancetype.NewDefaultResolver(cfg.Region, pricingProvider, unavailableOfferingsCache, quotaProvider),
+	)
+	// Seed the calibrated memory overheads, e.g. with those exported from another cluster, so they don't need to be relearned
+	instanceTypeProvider.SetMemoryOverheads(options.FromContext(ctx).VMMemoryOverheadsByInstanceType())
+	launchRoleProvider := launchrole.NewDefaultProvider(
+		cfg,
+		sts.NewFromConfig(cfg),
+		func(cfg aws.Config) sdk.EC2API { return ec2.NewFromConfig(cfg) },
+		cache.New(awscache.LaunchRoleSessionTTL, awscache.DefaultCleanupInterval),
 	)
 	instanceProvider := instance.NewDefaultProvider(
 		ctx,
 		cfg.Region,
 		ec2api,
+		ssmapi,
 		unavailableOfferingsCache,
 		subnetProvider,
 		launchTemplateProvider,
+		launchRoleProvider,
+		quotaProvider,
 	)
+	if token := options.FromContext(ctx).DebugEndpointToken; token != "" {
+		lo.Must0(operator.AddMetricsServerExtraHandler(debug.Path, debug.NewHandler(token, operator.Clock, operator.GetClient(), unavailableOfferingsCache, pricingProvider)))
+	}
 
 	return ctx, &Operator{
-		Operator:                  operator,
-		Config:                    cfg,
-		UnavailableOfferingsCache: unavailableOfferingsCache,
-		SSMCache:                  ssmCache,
-		SubnetProvider:            subnetProvider,
-		SecurityGroupProvider:     securityGroupProvider,
-		InstanceProfileProvider:   instanceProfileProvider,
-		AMIProvider:               amiProvider,
-		AMIResolver:               amiResolver,
-		VersionProvider:           versionProvider,
-		LaunchTemplateProvider:    launchTemplateProvider,
-		PricingProvider:           pricingProvider,
-		InstanceTypesProvider:     instanceTypeProvider,
-		InstanceProvider:          instanceProvider,
-		SSMProvider:               ssmProvider,
+		Operator:                    operator,
+		Config:                      cfg,
+		UnavailableOfferingsCache:   unavailableOfferingsCache,
+		SSMCache:                    ssmCache,
+		SubnetProvider:              subnetProvider,
+		SecurityGroupProvider:       securityGroupProvider,
+		InstanceProfileProvider:     instanceProfileProvider,
+		AMIProvider:                 amiProvider,
+		AMIResolver:                 amiResolver,
+		AMIProvenanceProvider:       amiProvenanceProvider,
+		VersionProvider:             versionProvider,
+		LaunchTemplateProvider:      launchTemplateProvider,
+		PricingProvider:             pricingProvider,
+		QuotaProvider:               quotaProvider,
+		CarbonIntensityProvider:     carbonIntensityProvider,
+		ElasticIPProvider:           elasticIPProvider,
+		LeakedResourceProvider:      leakedResourceProvider,
+		KMSProvider:                 kmsProvider,
+		CapacityReservationProvider: capacityReservationProvider,
+		VPCEndpointProvider:         vpcEndpointProvider,
+		AccessEntryProvider:         accessEntryProvider,
+		DiagnosticsProvider:         diagnosticsProvider,
+		InstanceTypesProvider:       instanceTypeProvider,
+		InstanceProvider:            instanceProvider,
+		SSMProvider:                 ssmProvider,
 	}
 }
 
@@ -213,6 +301,33 @@ func WithUserAgent(cfg aws.Config) aws.Config {
 	return cfg
 }
 
+// WithProxy configures the HTTP client shared by every AWS SDK client with the proxy and custom CA bundle from the options.
+// Only HTTPS requests are proxied, so the instance metadata service and EKS Pod Identity agent are still reached directly.
+func WithProxy(ctx context.Context) []func(*config.LoadOptions) error {
+	var opts []func(*config.LoadOptions) error
+	if httpsProxy := options.FromContext(ctx).AWSHTTPSProxy; httpsProxy != "" {
+		proxy := (&httpproxy.Config{
+			HTTPSProxy: httpsProxy,
+			NoProxy:    options.FromContext(ctx).AWSNoProxy,
+		}).ProxyFunc()
+		opts = append(opts, config.WithHTTPClient(awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+			t.Proxy = func(req *http.Request) (*url.URL, error) { return proxy(req.URL) }
+		})))
+	}
+	if caBundle := options.FromContext(ctx).AWSCustomCABundle; caBundle != "" {
+		opts = append(opts, config.WithCustomCABundle(bytes.NewReader(lo.Must(base64.StdEncoding.DecodeString(caBundle)))))
+	}
+	return opts
+}
+
+// WithFIPSEndpoints configures every AWS SDK client to resolve FIPS endpoints when enabled by the options
+func WithFIPSEndpoints(ctx context.Context) []func(*config.LoadOptions) error {
+	if !options.FromContext(ctx).FIPSEndpoints {
+		return nil
+	}
+	return []func(*config.LoadOptions) error{config.WithUseFIPSEndpoint(aws.FIPSEndpointStateEnabled)}
+}
+
 // CheckEC2Connectivity makes a dry-run call to DescribeInstanceTypes.  If it fails, we provide an early indicator that we
 // are having issues connecting to the EC2 API.
 func CheckEC2Connectivity(ctx context.Context, api sdk.EC2API) error {
@@ -240,7 +355,7 @@ func ResolveClusterEndpoint(ctx context.Context, eksAPI sdk.EKSAPI) (string, err
 	return *out.Cluster.Endpoint, nil
 }
 
-func GetCABundle(ctx context.Context, restConfig *rest.Config) (*string, error) {
+func GetCABundle(ctx context.Context, restConfig *rest.Config, eksAPI sdk.EKSAPI) (*string, error) {
 	// Discover CA Bundle from the REST client. We could alternatively
 	// have used the simpler client-go InClusterConfig() method.
 	// However, that only works when Karpenter is running as a Pod
@@ -256,7 +371,20 @@ func GetCABundle(ctx context.Context, restConfig *rest.Config) (*string, error)
 	if err != nil {
 		return nil, fmt.Errorf("discovering caBundle, loading TLS config, %w", err)
 	}
-	return lo.ToPtr(base64.StdEncoding.EncodeToString(transportConfig.TLS.CAData)), nil
+	if len(transportConfig.TLS.CAData) != 0 {
+		return lo.ToPtr(base64.StdEncoding.EncodeToString(transportConfig.TLS.CAData)), nil
+	}
+	// The REST client may trust the API server through the system certificate authorities rather than a CA bundle, so we
+	// fall back to the cluster's certificate authority from the DescribeCluster API. Clusters which aren't EKS clusters
+	// can't be described, so they continue without a CA bundle.
+	out, err := eksAPI.DescribeCluster(ctx, &eks.DescribeClusterInput{
+		Name: aws.String(options.FromContext(ctx).ClusterName),
+	})
+	if err != nil || out.Cluster.CertificateAuthority == nil || lo.FromPtr(out.Cluster.CertificateAuthority.Data) == "" {
+		log.FromContext(ctx).V(1).Info("unable to discover the cluster CA bundle from the DescribeCluster API", "error", err)
+		return lo.ToPtr(""), nil
+	}
+	return out.Cluster.CertificateAuthority.Data, nil
 }
 
 func KubeDNSIP(ctx context.Context, kubernetesInterface kubernetes.Interface) (net.IP, error) {
@@ -0,0 +1,133 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Feature is the name of an AWS provider feature gate. These are separate from the upstream feature gates, which are
+// set through --feature-gates, and gate the provider's behaviors that diverge from upstream.
+type Feature string
+
+const (
+	// NodeMetadataSync keeps the synced labels and annotations of a NodePool's template in sync onto its Nodes
+	NodeMetadataSync Feature = "NodeMetadataSync"
+	// NodeAdoption adopts Nodes that weren't launched by Karpenter into the NodePool named by their adopt-nodepool label
+	NodeAdoption Feature = "NodeAdoption"
+	// NodePinning blocks voluntary disruption of Nodes running pods with the pin-node annotation
+	NodePinning Feature = "NodePinning"
+	// DisruptionApproval blocks voluntary disruption of Nodes running pods that require approval until it's approved
+	DisruptionApproval Feature = "DisruptionApproval"
+	// MemoryOverheadCalibration calibrates the VM memory overhead of each instance type from the memory capacity of
+	// registered nodes, rather than using VM_MEMORY_OVERHEAD_PERCENT until a node has registered with the current AMIs
+	MemoryOverheadCalibration Feature = "MemoryOverheadCalibration"
+	// FaultInjection randomly delays and fails a percentage of calls to EC2, pricing and SQS so that operators can
+	// validate that provisioning and termination degrade gracefully. It must never be enabled in production clusters.
+	FaultInjection Feature = "FaultInjection"
+	// KubeletVersionSkew refuses to launch nodes from AMIs whose kubelet version is newer than the control plane, or more
+	// than max-kubelet-version-skew minor versions older than it
+	KubeletVersionSkew Feature = "KubeletVersionSkew"
+	// RescheduleOutOfPods deletes the pods that the kubelet rejected because their Node reports capacity for fewer pods than
+	// Karpenter advertised for it, so that their owners recreate them and they're scheduled to other Nodes
+	RescheduleOutOfPods Feature = "RescheduleOutOfPods"
+	// RemoveTerminationProtection removes termination protection that was enabled out of band from the instances of
+	// deleted NodeClaims before terminating them, rather than retrying termination until it's removed
+	RemoveTerminationProtection Feature = "RemoveTerminationProtection"
+	// RespectExternalDrains excludes Nodes that were cordoned outside of Karpenter, e.g. with kubectl drain, from
+	// consolidation and drift until they're uncordoned
+	RespectExternalDrains Feature = "RespectExternalDrains"
+	// KubeletUpgradeRollout drifts Nodes whose kubelet is older than the control plane once it's upgraded, one NodePool at
+	// a time in the order of their names
+	KubeletUpgradeRollout Feature = "KubeletUpgradeRollout"
+)
+
+// Maturity is the stage of a feature gate. Alpha features are disabled by default and may change or be removed between
+// releases. Beta features are enabled by default and are only removed once they graduate.
+type Maturity string
+
+const (
+	MaturityAlpha Maturity = "Alpha"
+	MaturityBeta  Maturity = "Beta"
+)
+
+type FeatureSpec struct {
+	Default  bool
+	Maturity Maturity
+}
+
+// Features are all of the known feature gates
+var Features = map[Feature]FeatureSpec{
+	NodeMetadataSync:            {Default: true, Maturity: MaturityBeta},
+	NodeAdoption:                {Default: true, Maturity: MaturityBeta},
+	NodePinning:                 {Default: true, Maturity: MaturityBeta},
+	DisruptionApproval:          {Default: true, Maturity: MaturityBeta},
+	MemoryOverheadCalibration:   {Default: false, Maturity: MaturityAlpha},
+	FaultInjection:              {Default: false, Maturity: MaturityAlpha},
+	KubeletVersionSkew:          {Default: false, Maturity: MaturityAlpha},
+	RescheduleOutOfPods:         {Default: false, Maturity: MaturityAlpha},
+	RemoveTerminationProtection: {Default: false, Maturity: MaturityAlpha},
+	RespectExternalDrains:       {Default: false, Maturity: MaturityAlpha},
+	KubeletUpgradeRollout:       {Default: false, Maturity: MaturityAlpha},
+}
+
+// FeatureGates holds the feature gates that were explicitly set. Gates that weren't set take their default.
+type FeatureGates map[Feature]bool
+
+// Enabled returns whether the feature gate is enabled. Unknown feature gates are disabled.
+func (g FeatureGates) Enabled(feature Feature) bool {
+	if enabled, ok := g[feature]; ok {
+		return enabled
+	}
+	return Features[feature].Default
+}
+
+// String returns the state and maturity of every known feature gate, sorted by name, e.g. "NodeAdoption=true (Beta)"
+func (g FeatureGates) String() string {
+	var gates []string
+	for feature, spec := range Features {
+		gates = append(gates, fmt.Sprintf("%s=%t (%s)", feature, g.Enabled(feature), spec.Maturity))
+	}
+	sort.Strings(gates)
+	return strings.Join(gates, ",")
+}
+
+// ParseFeatureGates parses a comma separated list of <feature>=<bool> pairs, e.g. "NodeAdoption=false,NodePinning=true"
+func ParseFeatureGates(gateStr string) (FeatureGates, error) {
+	gates := FeatureGates{}
+	for _, entry := range strings.Split(gateStr, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not in the form <feature>=<bool>", entry)
+		}
+		feature := Feature(strings.TrimSpace(name))
+		if _, ok := Features[feature]; !ok {
+			return nil, fmt.Errorf("unknown feature gate %q", feature)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("parsing feature gate %q, %w", feature, err)
+		}
+		gates[feature] = enabled
+	}
+	return gates, nil
+}
@@ -19,7 +19,14 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+	"k8s.io/apimachinery/pkg/types"
 
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
 	"sigs.k8s.io/karpenter/pkg/utils/env"
@@ -31,6 +38,44 @@ func init() {
 	coreoptions.Injectables = append(coreoptions.Injectables, &Options{})
 }
 
+// RegistrationTTL is the time that Karpenter waits for a launched NodeClaim to register before it terminates the NodeClaim
+// and relaunches. This mirrors the registration TTL in the upstream liveness controller.
+const RegistrationTTL = time.Minute * 15
+
+// DeprovisioningWebhookFailurePolicy controls how Karpenter reacts to a deprovisioning webhook that fails or times out
+type DeprovisioningWebhookFailurePolicy string
+
+const (
+	// DeprovisioningWebhookFailurePolicyIgnore drops the event after a single failed delivery
+	DeprovisioningWebhookFailurePolicyIgnore DeprovisioningWebhookFailurePolicy = "Ignore"
+	// DeprovisioningWebhookFailurePolicyFail retries the event until it's delivered, holding the NodeClaim until then
+	DeprovisioningWebhookFailurePolicyFail DeprovisioningWebhookFailurePolicy = "Fail"
+)
+
+// StoppedInstancePolicy controls how Karpenter reacts to an instance that was stopped out of band
+type StoppedInstancePolicy string
+
+const (
+	// StoppedInstancePolicyIgnore leaves the instance stopped, without checking NodeClaims for stopped instances
+	StoppedInstancePolicyIgnore StoppedInstancePolicy = "Ignore"
+	// StoppedInstancePolicyStart starts the instance again so that its Node rejoins the cluster
+	StoppedInstancePolicyStart StoppedInstancePolicy = "Start"
+	// StoppedInstancePolicyReplace deletes the NodeClaim so that its pods are rescheduled and the instance is terminated
+	StoppedInstancePolicyReplace StoppedInstancePolicy = "Replace"
+)
+
+// StuckPodPolicy controls how Karpenter handles pods that are still terminating on a deleting Node after the drain deadline
+type StuckPodPolicy string
+
+const (
+	// StuckPodPolicyIgnore leaves stuck pods alone, so that their Node's termination waits for them
+	StuckPodPolicyIgnore StuckPodPolicy = "Ignore"
+	// StuckPodPolicyRemoveFinalizers removes the stuck-pod-finalizers from stuck pods
+	StuckPodPolicyRemoveFinalizers StuckPodPolicy = "RemoveFinalizers"
+	// StuckPodPolicyForceDelete removes every finalizer from stuck pods and deletes them without a grace period
+	StuckPodPolicyForceDelete StuckPodPolicy = "ForceDelete"
+)
+
 type optionsKey struct{}
 
 type Options struct {
@@ -40,8 +85,76 @@ type Options struct {
 	IsolatedVPC             bool
 	EKSControlPlane         bool
 	VMMemoryOverheadPercent float64
+	VMMemoryOverheads       string
 	InterruptionQueue       string
+	InterruptionDLQ         string
 	ReservedENIs            int
+	VCPUQuotaAwareness      bool
+	RegistrationRebootAfter time.Duration
+	LaunchTemplateGCTTL     time.Duration
+	LeakedResourceGCTTL     time.Duration
+	LeakedResourceGCDryRun  bool
+	ScheduledChangeLeadTime time.Duration
+	MaxNodePinDuration      time.Duration
+	BillingBoundaryWindow   time.Duration
+	ZonalShift              bool
+	InterruptionTaints      bool
+	InterruptionPDBOverride bool
+
+	RequireEncryptedRootVolumes bool
+	StoppedInstancePolicy       string
+	ForbidKeyPairs              bool
+
+	AdditionalInterruptionQueues string
+
+	DeprovisioningWebhookURL           string
+	DeprovisioningWebhookTimeout       time.Duration
+	DeprovisioningWebhookFailurePolicy string
+
+	LaunchValidationWebhookURL string
+	LaunchValidationTimeout    time.Duration
+
+	OfferingsWebhookURL     string
+	OfferingsWebhookTimeout time.Duration
+
+	InterruptionWebhookURL string
+
+	ResourceNamePrefix string
+
+	CapacityLedgerKubeconfig string
+	CapacityLedgerNamespace  string
+
+	FaultInjectionErrorPercent float64
+	FaultInjectionDelayPercent float64
+	FaultInjectionDelay        time.Duration
+	FaultInjectionServices     string
+
+	MaxKubeletVersionSkew int
+
+	StuckPodPolicy     string
+	StuckPodFinalizers string
+	StuckPodTimeout    time.Duration
+
+	ReadinessDaemonSets string
+
+	TrustedAMIsParameter string
+	TrustedAMIKMSKeyARN  string
+
+	CarbonIntensityParameter string
+	CarbonIntensityWeight    float64
+
+	DebugEndpointToken string
+
+	AWSHTTPSProxy           string
+	AWSNoProxy              string
+	AWSCustomCABundle       string
+	FIPSEndpoints           bool
+	ManageNodeAccessEntries bool
+
+	InstanceProfilePropagationDelay time.Duration
+
+	AWSFeatureGates    FeatureGates
+	awsFeatureGatesStr string
 }
 
 func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
@@ -51,8 +164,57 @@ func (o *Options) AddFlags(fs *coreoptions.FlagSet) {
 	fs.BoolVarWithEnv(&o.IsolatedVPC, "isolated-vpc", "ISOLATED_VPC", false, "If true, then assume we can't reach AWS services which don't have a VPC endpoint. This also has the effect of disabling look-ups to the AWS on-demand pricing endpoint.")
 	fs.BoolVarWithEnv(&o.EKSControlPlane, "eks-control-plane", "EKS_CONTROL_PLANE", false, "Marking this true means that your cluster is running with an EKS control plane and Karpenter should attempt to discover cluster details from the DescribeCluster API ")
 	fs.Float64Var(&o.VMMemoryOverheadPercent, "vm-memory-overhead-percent", utils.WithDefaultFloat64("VM_MEMORY_OVERHEAD_PERCENT", 0.075), "The VM memory overhead as a percent that will be subtracted from the total memory for all instance types when cached information is unavailable.")
+	fs.StringVar(&o.VMMemoryOverheads, "vm-memory-overheads", env.WithDefaultString("VM_MEMORY_OVERHEADS", ""), "A comma separated list of instance-type=overhead pairs that seed the VM memory overheads calibrated by the MemoryOverheadCalibration AWS feature gate, e.g. m5.large=0.05. This bootstraps a cluster with the overheads exported from the karpenter-memory-overhead ConfigMap of another cluster rather than relearning them. Overheads calibrated from the cluster's own nodes take precedence where they're larger.")
 	fs.StringVar(&o.InterruptionQueue, "interruption-queue", env.WithDefaultString("INTERRUPTION_QUEUE", ""), "Interruption queue is the name of the SQS queue used for processing interruption events from EC2. Interruption handling is disabled if not specified. Enabling interruption handling may require additional permissions on the controller service account. Additional permissions are outlined in the docs.")
+	fs.StringVar(&o.InterruptionDLQ, "interruption-dead-letter-queue", env.WithDefaultString("INTERRUPTION_DEAD_LETTER_QUEUE", ""), "The name of the SQS queue that the interruption queue's redrive policy moves messages to after repeated processing failures. Messages in the dead-letter queue are periodically moved back to the interruption queue so they're retried. Re-driving is disabled if not specified. Enabling re-driving requires additional permissions on the controller service account.")
+	fs.StringVar(&o.AdditionalInterruptionQueues, "additional-interruption-queues", env.WithDefaultString("ADDITIONAL_INTERRUPTION_QUEUES", ""), "A comma separated list of the URLs of SQS queues to process interruption events from in addition to the interruption queue, e.g. for NodeClasses that launch instances into other accounts or regions. Each URL may be followed by =<role ARN> to assume a role to consume the queue, otherwise the controller's credentials are used.")
 	fs.IntVar(&o.ReservedENIs, "reserved-enis", env.WithDefaultInt("RESERVED_ENIS", 0), "Reserved ENIs are not included in the calculations for max-pods or kube-reserved. This is most often used in the VPC CNI custom networking setup https://docs.aws.amazon.com/eks/latest/userguide/cni-custom-network.html.")
+	fs.BoolVarWithEnv(&o.VCPUQuotaAwareness, "vcpu-quota-awareness", "VCPU_QUOTA_AWARENESS", false, "If true, then Karpenter periodically reads the EC2 vCPU quotas from the Service Quotas API and avoids launching instance types that would exceed them. Enabling quota awareness requires additional permissions on the controller service account.")
+	fs.DurationVar(&o.RegistrationRebootAfter, "registration-reboot-after", env.WithDefaultDuration("REGISTRATION_REBOOT_AFTER", 0), "The duration after launch after which an instance that hasn't registered with the cluster is rebooted once, before it's terminated at the 15m registration TTL. Rebooting is disabled if not specified. Enabling reboots requires additional permissions on the controller service account.")
+	fs.DurationVar(&o.LaunchTemplateGCTTL, "launch-template-gc-ttl", env.WithDefaultDuration("LAUNCH_TEMPLATE_GC_TTL", 0), "The duration after creation after which a launch template created by Karpenter for the cluster is deleted if it isn't in use. Launch templates are normally deleted as they fall out of use, so this removes templates that were leaked, e.g. by a controller restart. Launch template garbage collection is disabled if not specified.")
+	fs.DurationVar(&o.LeakedResourceGCTTL, "leaked-resource-gc-ttl", env.WithDefaultDuration("LEAKED_RESOURCE_GC_TTL", 0), "The duration that a network interface or volume tagged for the cluster must stay detached before it's deleted. Network interfaces left behind by the VPC CNI and volumes that weren't deleted with their instance are otherwise leaked, exhausting subnet IPs and accruing cost. Leaked resource garbage collection is disabled if not specified. Enabling garbage collection requires additional permissions on the controller service account.")
+	fs.BoolVarWithEnv(&o.LeakedResourceGCDryRun, "leaked-resource-gc-dry-run", "LEAKED_RESOURCE_GC_DRY_RUN", false, "If true, leaked network interfaces and volumes are logged and counted but not deleted.")
+	fs.DurationVar(&o.ScheduledChangeLeadTime, "scheduled-change-lead-time", env.WithDefaultDuration("SCHEDULED_CHANGE_LEAD_TIME", 0), "The duration before an AWS Health scheduled change, e.g. an instance retirement or system reboot, that affected nodes are drifted so they're replaced within the NodePool's disruption budgets. If not specified, affected nodes are deleted as soon as the scheduled change is received.")
+	fs.DurationVar(&o.MaxNodePinDuration, "max-node-pin-duration", env.WithDefaultDuration("MAX_NODE_PIN_DURATION", 24*time.Hour), "The maximum duration that a pod with the karpenter.k8s.aws/pin-node annotation can block voluntary disruption of its node for, measured from when the pod started.")
+	fs.DurationVar(&o.BillingBoundaryWindow, "billing-boundary-window", env.WithDefaultDuration("BILLING_BOUNDARY_WINDOW", 5*time.Minute), "The duration before the end of a billing period that voluntary disruption of a node in a NodePool with the karpenter.k8s.aws/billing-period annotation is allowed. Outside of this window, voluntary disruption is deferred until the node's current billing period is nearly used.")
+	fs.BoolVarWithEnv(&o.ZonalShift, "zonal-shift", "ZONAL_SHIFT", false, "If true, then Karpenter tracks launch failures and spot interruptions per availability zone, and temporarily stops launching into a zone that they're concentrated in so that replacements are launched into other zones.")
+	fs.BoolVarWithEnv(&o.InterruptionTaints, "interruption-taints", "INTERRUPTION_TAINTS", false, "If true, then Karpenter taints Nodes with karpenter.k8s.aws/spot-interrupting:NoExecute when it receives a spot interruption warning and with karpenter.k8s.aws/rebalance-recommended:PreferNoSchedule when it receives a rebalance recommendation, so that workloads can respond to each with tolerations.")
+	fs.BoolVarWithEnv(&o.InterruptionPDBOverride, "interruption-pdb-override", "INTERRUPTION_PDB_OVERRIDE", false, "If true, then pods that are still blocked from eviction by a PodDisruptionBudget 30 seconds before a spot interruption reclaims their node are deleted, rather than being left to stop when the instance is terminated.")
+	fs.StringVar(&o.StoppedInstancePolicy, "stopped-instance-policy", env.WithDefaultString("STOPPED_INSTANCE_POLICY", string(StoppedInstancePolicyIgnore)), "How Karpenter handles an instance that was stopped out of band. One of 'Ignore' (leave the instance stopped), 'Start' (mark its NodeClaim with the InstanceStopped condition and start the instance again) or 'Replace' (mark its NodeClaim with the InstanceStopped condition and delete it so that it's replaced). Starting instances requires the ec2:StartInstances permission on the controller role.")
+	fs.BoolVarWithEnv(&o.ForbidKeyPairs, "forbid-key-pairs", "FORBID_KEY_PAIRS", false, "If true, then EC2NodeClasses that inject an EC2 key pair into launched instances through keyName are marked as not ready and aren't launched from.")
+	fs.BoolVarWithEnv(&o.RequireEncryptedRootVolumes, "require-encrypted-root-volumes", "REQUIRE_ENCRYPTED_ROOT_VOLUMES", false, "If true, then EC2NodeClasses whose root volume isn't configured to be encrypted are marked as not ready and aren't launched from.")
+	fs.StringVar(&o.DeprovisioningWebhookURL, "deprovisioning-webhook-url", env.WithDefaultString("DEPROVISIONING_WEBHOOK_URL", ""), "The URL that Karpenter sends a POST request to when a NodeClaim begins terminating and after its instance has been terminated. Deprovisioning webhooks are disabled if not specified.")
+	fs.DurationVar(&o.DeprovisioningWebhookTimeout, "deprovisioning-webhook-timeout", env.WithDefaultDuration("DEPROVISIONING_WEBHOOK_TIMEOUT", 10*time.Second), "The maximum duration that Karpenter waits for the deprovisioning webhook to respond.")
+	fs.StringVar(&o.DeprovisioningWebhookFailurePolicy, "deprovisioning-webhook-failure-policy", env.WithDefaultString("DEPROVISIONING_WEBHOOK_FAILURE_POLICY", string(DeprovisioningWebhookFailurePolicyIgnore)), "How Karpenter handles a deprovisioning webhook that fails or times out. One of 'Ignore' (drop the event) or 'Fail' (retry until delivered, holding the NodeClaim until then).")
+	fs.StringVar(&o.LaunchValidationWebhookURL, "launch-validation-webhook-url", env.WithDefaultString("LAUNCH_VALIDATION_WEBHOOK_URL", ""), "The URL that Karpenter sends a POST request to once the Node of a NodeClaim with the karpenter.k8s.aws/launch-validation startup taint has registered. The taint is removed if the webhook allows the Node, and the NodeClaim is replaced if it's denied. Launch validation is disabled if not specified.")
+	fs.DurationVar(&o.LaunchValidationTimeout, "launch-validation-timeout", env.WithDefaultDuration("LAUNCH_VALIDATION_TIMEOUT", 5*time.Minute), "The maximum duration after a Node registers that Karpenter retries the launch validation webhook for, before the NodeClaim is replaced.")
+	fs.StringVar(&o.OfferingsWebhookURL, "offerings-webhook-url", env.WithDefaultString("OFFERINGS_WEBHOOK_URL", ""), "The URL that Karpenter sends a POST request to with the available offerings of a NodePool's instance types when they're resolved for scheduling and launches. The webhook responds with the offerings that may be launched and their prices, so that offerings can be filtered and prices adjusted out of process. Offerings are used unchanged if not specified.")
+	fs.DurationVar(&o.OfferingsWebhookTimeout, "offerings-webhook-timeout", env.WithDefaultDuration("OFFERINGS_WEBHOOK_TIMEOUT", 5*time.Second), "The timeout for requests to the offerings webhook. Offerings are used unchanged if the webhook doesn't respond in time.")
+	fs.StringVar(&o.InterruptionWebhookURL, "interruption-webhook-url", env.WithDefaultString("INTERRUPTION_WEBHOOK_URL", ""), "The URL that Karpenter sends a POST request to with a normalized event when an interruption message is received for one of its instances, so that workloads can react to spot interruptions, rebalance recommendations and scheduled changes without parsing the raw AWS events. Interruption notifications are disabled if not specified.")
+	fs.StringVar(&o.ResourceNamePrefix, "resource-name-prefix", env.WithDefaultString("RESOURCE_NAME_PREFIX", ""), "A prefix that's prepended to the names of the launch templates and instance profiles that Karpenter creates, for accounts with naming conventions. May contain up to 32 letters, digits, '.', '_' and '-'.")
+	fs.StringVar(&o.CapacityLedgerKubeconfig, "capacity-ledger-kubeconfig", env.WithDefaultString("CAPACITY_LEDGER_KUBECONFIG", ""), "The path to a kubeconfig for a hub cluster that's shared by the clusters in the account. Karpenter records its launches in a ConfigMap in the hub cluster and counts the launches of the other clusters against the vCPU quotas, so that clusters don't race each other for the remaining quota. Requires vcpu-quota-awareness. The capacity ledger is disabled if not specified.")
+	fs.StringVar(&o.CapacityLedgerNamespace, "capacity-ledger-namespace", env.WithDefaultString("CAPACITY_LEDGER_NAMESPACE", "karpenter"), "The namespace in the hub cluster of the capacity ledger ConfigMap.")
+	fs.Float64Var(&o.FaultInjectionErrorPercent, "fault-injection-error-percent", utils.WithDefaultFloat64("FAULT_INJECTION_ERROR_PERCENT", 0), "The percentage, between 0 and 100, of calls to the fault-injection-services that fail with an injected InternalError. Requires the FaultInjection AWS feature gate. Only for validating resilience in non-production clusters.")
+	fs.Float64Var(&o.FaultInjectionDelayPercent, "fault-injection-delay-percent", utils.WithDefaultFloat64("FAULT_INJECTION_DELAY_PERCENT", 0), "The percentage, between 0 and 100, of calls to the fault-injection-services that are delayed by a random duration of up to fault-injection-delay. Requires the FaultInjection AWS feature gate. Only for validating resilience in non-production clusters.")
+	fs.DurationVar(&o.FaultInjectionDelay, "fault-injection-delay", env.WithDefaultDuration("FAULT_INJECTION_DELAY", 5*time.Second), "The maximum duration that calls selected by fault-injection-delay-percent are delayed by.")
+	fs.StringVar(&o.FaultInjectionServices, "fault-injection-services", env.WithDefaultString("FAULT_INJECTION_SERVICES", "ec2,pricing,sqs"), "A comma separated list of the AWS services that faults are injected into. Current options are: ec2, pricing, sqs")
+	fs.IntVar(&o.MaxKubeletVersionSkew, "max-kubelet-version-skew", env.WithDefaultInt("MAX_KUBELET_VERSION_SKEW", 3), "The number of minor versions, between 0 and 3, that the kubelet of AMIs can be older than the control plane. AMIs outside of the skew aren't launched. Requires the KubeletVersionSkew AWS feature gate.")
+	fs.StringVar(&o.StuckPodPolicy, "stuck-pod-policy", env.WithDefaultString("STUCK_POD_POLICY", string(StuckPodPolicyIgnore)), "How Karpenter handles pods that are still terminating on a deleting Node once stuck-pod-timeout has passed, e.g. because of an orphaned finalizer. One of 'Ignore' (wait for the pods), 'RemoveFinalizers' (remove the stuck-pod-finalizers from the pods) or 'ForceDelete' (remove every finalizer from the pods and delete them without a grace period).")
+	fs.StringVar(&o.StuckPodFinalizers, "stuck-pod-finalizers", env.WithDefaultString("STUCK_POD_FINALIZERS", ""), "A comma separated list of the finalizers that are removed from stuck pods when stuck-pod-policy is 'RemoveFinalizers'.")
+	fs.DurationVar(&o.StuckPodTimeout, "stuck-pod-timeout", env.WithDefaultDuration("STUCK_POD_TIMEOUT", 10*time.Minute), "The duration after a Node starts deleting that its drain deadline passes, after which pods that are still terminating past their grace period are handled by stuck-pod-policy. The drain deadline is earlier if the NodeClaim's terminationGracePeriod expires first.")
+	fs.StringVar(&o.ReadinessDaemonSets, "readiness-daemonsets", env.WithDefaultString("READINESS_DAEMONSETS", "kube-system/aws-node,kube-system/ebs-csi-node,kube-system/kube-proxy"), "A comma separated list of namespace/name DaemonSets whose pods must be ready on the Nodes of NodeClaims with the karpenter.k8s.aws/daemon-readiness startup taint before they're initialized. DaemonSets that don't exist or that don't schedule to the Node aren't waited for.")
+	fs.StringVar(&o.TrustedAMIsParameter, "trusted-amis-parameter", env.WithDefaultString("TRUSTED_AMIS_PARAMETER", ""), "The name of an SSM parameter holding a comma separated list of trusted AMI IDs. The Nodes of NodeClaims with the karpenter.k8s.aws/ami-provenance startup taint aren't initialized until their AMI is trusted.")
+	fs.StringVar(&o.TrustedAMIKMSKeyARN, "trusted-ami-kms-key-arn", env.WithDefaultString("TRUSTED_AMI_KMS_KEY_ARN", ""), "The ARN of a KMS key that trusted AMIs are signed with. AMIs whose EBS snapshots are all encrypted with the key are trusted.")
+	fs.StringVar(&o.CarbonIntensityParameter, "carbon-intensity-parameter", env.WithDefaultString("CARBON_INTENSITY_PARAMETER", ""), "The name of an SSM parameter holding a JSON object that maps regions and availability zones to their grid carbon intensity in gCO2eq/kWh. NodePools with the karpenter.k8s.aws/sustainability annotation weight or restrict their launches by the carbon intensity of each zone. Carbon intensity weighting is disabled if not specified.")
+	fs.Float64Var(&o.CarbonIntensityWeight, "carbon-intensity-weight", utils.WithDefaultFloat64("CARBON_INTENSITY_WEIGHT", 0.5), "The fraction by which the prices of offerings in the most carbon intensive zone are raised, relative to the least carbon intensive zone, for NodePools that prefer sustainable capacity.")
+	fs.StringVar(&o.DebugEndpointToken, "debug-endpoint-token", env.WithDefaultString("DEBUG_ENDPOINT_TOKEN", ""), "The bearer token required to read internal controller state from the /debug/karpenter/state, /debug/karpenter/instancetypes and /debug/karpenter/snapshot endpoints on the metrics server. The debug endpoints are disabled if not specified.")
+	fs.StringVar(&o.AWSHTTPSProxy, "aws-https-proxy", env.WithDefaultString("AWS_HTTPS_PROXY", ""), "The URL of the proxy that the controller sends requests to AWS APIs through. If not specified, the HTTPS_PROXY environment variable is respected.")
+	fs.StringVar(&o.AWSNoProxy, "aws-no-proxy", env.WithDefaultString("AWS_NO_PROXY", ""), "A comma separated list of hosts, domains and CIDRs that the controller connects to directly rather than through aws-https-proxy, e.g. VPC endpoints.")
+	fs.StringVar(&o.AWSCustomCABundle, "aws-custom-ca-bundle", env.WithDefaultString("AWS_CUSTOM_CA_BUNDLE", ""), "A base64 encoded bundle of PEM certificate authorities that the controller trusts for TLS connections to AWS APIs, in addition to the system certificate authorities. This is most often used with a TLS intercepting proxy.")
+	fs.BoolVarWithEnv(&o.FIPSEndpoints, "fips-endpoints", "FIPS_ENDPOINTS", false, "If true, then the controller sends requests to the FIPS endpoints of AWS APIs where they're available, e.g. in GovCloud (US) regions. The pricing API doesn't have FIPS endpoints, so it's always reached through its standard endpoint.")
+	fs.DurationVar(&o.InstanceProfilePropagationDelay, "instance-profile-propagation-delay", env.WithDefaultDuration("INSTANCE_PROFILE_PROPAGATION_DELAY", 10*time.Second), "The duration after Karpenter creates an EC2NodeClass's instance profile, or changes its role, that the EC2NodeClass isn't launched from, since IAM is eventually consistent and EC2 may reject launches with the instance profile until it has propagated. The role is verified to be attached once the delay has passed, backing off if it isn't. Launches aren't delayed if set to 0.")
+	fs.BoolVarWithEnv(&o.ManageNodeAccessEntries, "manage-node-access-entries", "MANAGE_NODE_ACCESS_ENTRIES", false, "If true, then the controller grants the node role of each EC2NodeClass access to join the cluster, through an EKS access entry or through the aws-auth ConfigMap for clusters that use the CONFIG_MAP authentication mode. The access is removed when the last EC2NodeClass using the role is deleted.")
+	fs.StringVar(&o.awsFeatureGatesStr, "aws-feature-gates", env.WithDefaultString("AWS_FEATURE_GATES", ""), "Behaviors of the AWS provider that diverge from upstream can be enabled / disabled using feature gates, separately from --feature-gates. Current options are: DisruptionApproval, FaultInjection, KubeletUpgradeRollout, KubeletVersionSkew, MemoryOverheadCalibration, NodeAdoption, NodeMetadataSync, NodePinning, RemoveTerminationProtection, RescheduleOutOfPods, RespectExternalDrains")
 }
 
 func (o *Options) Parse(fs *coreoptions.FlagSet, args ...string) error {
@@ -62,12 +224,91 @@ func (o *Options) Parse(fs *coreoptions.FlagSet, args ...string) error {
 		}
 		return fmt.Errorf("parsing flags, %w", err)
 	}
+	gates, err := ParseFeatureGates(o.awsFeatureGatesStr)
+	if err != nil {
+		return fmt.Errorf("parsing aws feature gates, %w", err)
+	}
+	o.AWSFeatureGates = gates
 	if err := o.Validate(); err != nil {
 		return fmt.Errorf("validating options, %w", err)
 	}
 	return nil
 }
 
+// ReadinessDaemonSetKeys returns the namespace/name keys of the readiness DaemonSets. Entries without a namespace are
+// returned with an empty namespace, and are rejected by validation.
+func (o Options) ReadinessDaemonSetKeys() []types.NamespacedName {
+	return lo.FilterMap(strings.Split(o.ReadinessDaemonSets, ","), func(entry string, _ int) (types.NamespacedName, bool) {
+		entry = strings.TrimSpace(entry)
+		namespace, name, ok := strings.Cut(entry, "/")
+		if !ok {
+			return types.NamespacedName{Name: entry}, entry != ""
+		}
+		return types.NamespacedName{Namespace: namespace, Name: name}, true
+	})
+}
+
+// FaultInjectionServiceNames returns the lowercased names of the services that faults are injected into
+func (o Options) FaultInjectionServiceNames() []string {
+	return lo.FilterMap(strings.Split(o.FaultInjectionServices, ","), func(entry string, _ int) (string, bool) {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		return entry, entry != ""
+	})
+}
+
+// StuckPodFinalizerNames returns the finalizers that are removed from stuck pods when stuck-pod-policy is RemoveFinalizers
+func (o Options) StuckPodFinalizerNames() []string {
+	return lo.FilterMap(strings.Split(o.StuckPodFinalizers, ","), func(entry string, _ int) (string, bool) {
+		entry = strings.TrimSpace(entry)
+		return entry, entry != ""
+	})
+}
+
+// VMMemoryOverheadsByInstanceType returns the seeded VM memory overheads, keyed by instance type. Entries that can't be
+// parsed are returned with a negative overhead, and are rejected by validation.
+func (o Options) VMMemoryOverheadsByInstanceType() map[string]float64 {
+	return lo.SliceToMap(lo.Filter(strings.Split(o.VMMemoryOverheads, ","), func(entry string, _ int) bool {
+		return strings.TrimSpace(entry) != ""
+	}), func(entry string) (string, float64) {
+		instanceType, value, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		overhead, err := strconv.ParseFloat(value, 64)
+		return instanceType, lo.Ternary(err != nil, -1, overhead)
+	})
+}
+
+// InterruptionQueueConfig is an additional interruption queue, along with the role that's assumed to consume it
+type InterruptionQueueConfig struct {
+	URL     string
+	Region  string
+	RoleARN string
+}
+
+// AdditionalInterruptionQueueConfigs returns the additional interruption queues. Each queue's region is derived from the
+// queue URL, and is empty if the URL isn't an SQS queue URL, which is rejected by validation.
+func (o Options) AdditionalInterruptionQueueConfigs() []InterruptionQueueConfig {
+	return lo.FilterMap(strings.Split(o.AdditionalInterruptionQueues, ","), func(entry string, _ int) (InterruptionQueueConfig, bool) {
+		queueURL, roleARN, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		return InterruptionQueueConfig{URL: queueURL, Region: queueRegion(queueURL), RoleARN: roleARN}, queueURL != ""
+	})
+}
+
+// queueRegion returns the region of an SQS queue URL, e.g. https://sqs.us-west-2.amazonaws.com/111122223333/queue, or the
+// legacy https://us-west-2.queue.amazonaws.com/111122223333/queue
+func queueRegion(queueURL string) string {
+	u, err := url.Parse(queueURL)
+	if err != nil || u.Scheme != "https" || len(strings.Split(strings.Trim(u.Path, "/"), "/")) != 2 {
+		return ""
+	}
+	parts := strings.Split(u.Hostname(), ".")
+	switch {
+	case len(parts) > 2 && parts[0] == "sqs":
+		return parts[1]
+	case len(parts) > 2 && parts[1] == "queue":
+		return parts[0]
+	}
+	return ""
+}
+
 func (o *Options) ToContext(ctx context.Context) context.Context {
 	return ToContext(ctx, o)
 }
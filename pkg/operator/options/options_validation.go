@@ -15,9 +15,15 @@ limitations under the License.
 package options
 
 import (
+	"encoding/base64"
 	"fmt"
+	"math"
 	"net/url"
+	"regexp"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/samber/lo"
 	"go.uber.org/multierr"
 )
 
@@ -25,7 +31,31 @@ func (o Options) Validate() error {
 	return multierr.Combine(
 		o.validateEndpoint(),
 		o.validateVMMemoryOverheadPercent(),
+		o.validateVMMemoryOverheads(),
 		o.validateReservedENIs(),
+		o.validateRegistrationRebootAfter(),
+		o.validateInstanceProfilePropagationDelay(),
+		o.validateLaunchTemplateGCTTL(),
+		o.validateLeakedResourceGCTTL(),
+		o.validateInterruptionDLQ(),
+		o.validateAdditionalInterruptionQueues(),
+		o.validateScheduledChangeLeadTime(),
+		o.validateMaxNodePinDuration(),
+		o.validateBillingBoundaryWindow(),
+		o.validateStoppedInstancePolicy(),
+		o.validateDeprovisioningWebhook(),
+		o.validateLaunchValidationWebhook(),
+		o.validateOfferingsWebhook(),
+		o.validateInterruptionWebhook(),
+		o.validateResourceNamePrefix(),
+		o.validateCapacityLedger(),
+		o.validateFaultInjection(),
+		o.validateMaxKubeletVersionSkew(),
+		o.validateStuckPodPolicy(),
+		o.validateReadinessDaemonSets(),
+		o.validateTrustedAMIKMSKeyARN(),
+		o.validateCarbonIntensityWeight(),
+		o.validateAWSProxy(),
 		o.validateRequiredFields(),
 	)
 }
@@ -50,6 +80,19 @@ func (o Options) validateVMMemoryOverheadPercent() error {
 	return nil
 }
 
+func (o Options) validateVMMemoryOverheads() error {
+	overheads := o.VMMemoryOverheadsByInstanceType()
+	if len(overheads) > 0 && !o.AWSFeatureGates.Enabled(MemoryOverheadCalibration) {
+		return fmt.Errorf("vm-memory-overheads requires the %s aws feature gate to be enabled", MemoryOverheadCalibration)
+	}
+	for instanceType, overhead := range overheads {
+		if instanceType == "" || overhead < 0 || overhead >= 1 {
+			return fmt.Errorf("%q is not a valid vm-memory-overheads entry, expected instance-type=overhead with an overhead between 0 and 1", instanceType)
+		}
+	}
+	return nil
+}
+
 func (o Options) validateReservedENIs() error {
 	if o.ReservedENIs < 0 {
 		return fmt.Errorf("reserved-enis cannot be negative")
@@ -57,9 +100,261 @@ func (o Options) validateReservedENIs() error {
 	return nil
 }
 
+func (o Options) validateRegistrationRebootAfter() error {
+	if o.RegistrationRebootAfter < 0 {
+		return fmt.Errorf("registration-reboot-after cannot be negative")
+	}
+	if o.RegistrationRebootAfter >= RegistrationTTL {
+		return fmt.Errorf("registration-reboot-after must be less than the registration ttl of %s", RegistrationTTL)
+	}
+	return nil
+}
+
+func (o Options) validateScheduledChangeLeadTime() error {
+	if o.ScheduledChangeLeadTime < 0 {
+		return fmt.Errorf("scheduled-change-lead-time cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateMaxNodePinDuration() error {
+	if o.MaxNodePinDuration <= 0 {
+		return fmt.Errorf("max-node-pin-duration must be positive")
+	}
+	return nil
+}
+
+func (o Options) validateBillingBoundaryWindow() error {
+	if o.BillingBoundaryWindow <= 0 {
+		return fmt.Errorf("billing-boundary-window must be positive")
+	}
+	return nil
+}
+
+func (o Options) validateInterruptionDLQ() error {
+	if o.InterruptionDLQ != "" && o.InterruptionQueue == "" {
+		return fmt.Errorf("interruption-dead-letter-queue requires interruption-queue to be set")
+	}
+	if o.InterruptionDLQ != "" && o.InterruptionDLQ == o.InterruptionQueue {
+		return fmt.Errorf("interruption-dead-letter-queue must be different from interruption-queue")
+	}
+	return nil
+}
+
+func (o Options) validateAdditionalInterruptionQueues() error {
+	queues := o.AdditionalInterruptionQueueConfigs()
+	if len(queues) > 0 && o.InterruptionQueue == "" {
+		return fmt.Errorf("additional-interruption-queues requires interruption-queue to be set")
+	}
+	for _, queue := range queues {
+		if queue.Region == "" {
+			return fmt.Errorf("%q is not a valid additional-interruption-queues entry, expected an SQS queue URL", queue.URL)
+		}
+		if queue.RoleARN == "" {
+			continue
+		}
+		if parsed, err := arn.Parse(queue.RoleARN); err != nil || parsed.Service != "iam" || !strings.HasPrefix(parsed.Resource, "role/") {
+			return fmt.Errorf("%q is not a valid additional-interruption-queues role ARN", queue.RoleARN)
+		}
+	}
+	return nil
+}
+
+func (o Options) validateInstanceProfilePropagationDelay() error {
+	if o.InstanceProfilePropagationDelay < 0 {
+		return fmt.Errorf("instance-profile-propagation-delay cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateLaunchTemplateGCTTL() error {
+	if o.LaunchTemplateGCTTL < 0 {
+		return fmt.Errorf("launch-template-gc-ttl cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateLeakedResourceGCTTL() error {
+	if o.LeakedResourceGCTTL < 0 {
+		return fmt.Errorf("leaked-resource-gc-ttl cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateStoppedInstancePolicy() error {
+	if !lo.Contains([]StoppedInstancePolicy{StoppedInstancePolicyIgnore, StoppedInstancePolicyStart, StoppedInstancePolicyReplace},
+		StoppedInstancePolicy(o.StoppedInstancePolicy)) {
+		return fmt.Errorf("stopped-instance-policy must be one of %q, %q or %q", StoppedInstancePolicyIgnore, StoppedInstancePolicyStart, StoppedInstancePolicyReplace)
+	}
+	return nil
+}
+
+func (o Options) validateDeprovisioningWebhook() error {
+	if o.DeprovisioningWebhookURL != "" {
+		u, err := url.Parse(o.DeprovisioningWebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return fmt.Errorf("%q is not a valid deprovisioning-webhook-url", o.DeprovisioningWebhookURL)
+		}
+	}
+	if o.DeprovisioningWebhookTimeout <= 0 {
+		return fmt.Errorf("deprovisioning-webhook-timeout must be positive")
+	}
+	if !lo.Contains([]DeprovisioningWebhookFailurePolicy{DeprovisioningWebhookFailurePolicyIgnore, DeprovisioningWebhookFailurePolicyFail},
+		DeprovisioningWebhookFailurePolicy(o.DeprovisioningWebhookFailurePolicy)) {
+		return fmt.Errorf("deprovisioning-webhook-failure-policy must be one of %q or %q", DeprovisioningWebhookFailurePolicyIgnore, DeprovisioningWebhookFailurePolicyFail)
+	}
+	return nil
+}
+
+func (o Options) validateLaunchValidationWebhook() error {
+	if o.LaunchValidationWebhookURL != "" {
+		u, err := url.Parse(o.LaunchValidationWebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return fmt.Errorf("%q is not a valid launch-validation-webhook-url", o.LaunchValidationWebhookURL)
+		}
+	}
+	if o.LaunchValidationTimeout <= 0 {
+		return fmt.Errorf("launch-validation-timeout must be positive")
+	}
+	return nil
+}
+
+func (o Options) validateOfferingsWebhook() error {
+	if o.OfferingsWebhookURL != "" {
+		u, err := url.Parse(o.OfferingsWebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return fmt.Errorf("%q is not a valid offerings-webhook-url", o.OfferingsWebhookURL)
+		}
+	}
+	if o.OfferingsWebhookTimeout <= 0 {
+		return fmt.Errorf("offerings-webhook-timeout must be positive")
+	}
+	return nil
+}
+
+func (o Options) validateInterruptionWebhook() error {
+	if o.InterruptionWebhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(o.InterruptionWebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%q is not a valid interruption-webhook-url", o.InterruptionWebhookURL)
+	}
+	return nil
+}
+
+// resourceNamePrefixRegex matches the characters that are valid in both launch template and instance profile names
+var resourceNamePrefixRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]{0,32}$`)
+
+func (o Options) validateResourceNamePrefix() error {
+	if !resourceNamePrefixRegex.MatchString(o.ResourceNamePrefix) {
+		return fmt.Errorf("resource-name-prefix %q must be at most 32 letters, digits, '.', '_' or '-'", o.ResourceNamePrefix)
+	}
+	return nil
+}
+
+func (o Options) validateCapacityLedger() error {
+	if o.CapacityLedgerKubeconfig == "" {
+		return nil
+	}
+	if !o.VCPUQuotaAwareness {
+		return fmt.Errorf("capacity-ledger-kubeconfig requires vcpu-quota-awareness to be enabled")
+	}
+	if o.CapacityLedgerNamespace == "" {
+		return fmt.Errorf("capacity-ledger-namespace is required when capacity-ledger-kubeconfig is set")
+	}
+	return nil
+}
+
+// faultInjectionServices are the services that faults can be injected into
+var faultInjectionServices = []string{"ec2", "pricing", "sqs"}
+
+func (o Options) validateFaultInjection() error {
+	if o.FaultInjectionErrorPercent < 0 || o.FaultInjectionErrorPercent > 100 {
+		return fmt.Errorf("fault-injection-error-percent must be between 0 and 100")
+	}
+	if o.FaultInjectionDelayPercent < 0 || o.FaultInjectionDelayPercent > 100 {
+		return fmt.Errorf("fault-injection-delay-percent must be between 0 and 100")
+	}
+	if o.FaultInjectionDelay < 0 {
+		return fmt.Errorf("fault-injection-delay cannot be negative")
+	}
+	if (o.FaultInjectionErrorPercent > 0 || o.FaultInjectionDelayPercent > 0) && !o.AWSFeatureGates.Enabled(FaultInjection) {
+		return fmt.Errorf("fault-injection-error-percent and fault-injection-delay-percent require the %s aws feature gate to be enabled", FaultInjection)
+	}
+	for _, service := range o.FaultInjectionServiceNames() {
+		if !lo.Contains(faultInjectionServices, service) {
+			return fmt.Errorf("%q is not a valid fault-injection-services entry, expected one of %s", service, strings.Join(faultInjectionServices, ", "))
+		}
+	}
+	return nil
+}
+
+func (o Options) validateMaxKubeletVersionSkew() error {
+	// The Kubernetes version skew policy allows the kubelet to be up to three minor versions older than the control plane
+	if o.MaxKubeletVersionSkew < 0 || o.MaxKubeletVersionSkew > 3 {
+		return fmt.Errorf("max-kubelet-version-skew must be between 0 and 3")
+	}
+	return nil
+}
+
+func (o Options) validateReadinessDaemonSets() error {
+	for _, daemonSet := range o.ReadinessDaemonSetKeys() {
+		if daemonSet.Namespace == "" || daemonSet.Name == "" || strings.Contains(daemonSet.Name, "/") {
+			return fmt.Errorf("%q is not a valid readiness-daemonsets entry, expected namespace/name", strings.TrimPrefix(daemonSet.String(), "/"))
+		}
+	}
+	return nil
+}
+
+func (o Options) validateTrustedAMIKMSKeyARN() error {
+	if o.TrustedAMIKMSKeyARN == "" {
+		return nil
+	}
+	if parsed, err := arn.Parse(o.TrustedAMIKMSKeyARN); err != nil || parsed.Service != "kms" || !strings.HasPrefix(parsed.Resource, "key/") {
+		return fmt.Errorf("%q is not a valid trusted-ami-kms-key-arn", o.TrustedAMIKMSKeyARN)
+	}
+	return nil
+}
+
+func (o Options) validateCarbonIntensityWeight() error {
+	if o.CarbonIntensityWeight < 0 || math.IsNaN(o.CarbonIntensityWeight) {
+		return fmt.Errorf("carbon-intensity-weight cannot be negative")
+	}
+	return nil
+}
+
+func (o Options) validateAWSProxy() error {
+	if o.AWSHTTPSProxy != "" {
+		u, err := url.Parse(o.AWSHTTPSProxy)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+			return fmt.Errorf("%q is not a valid aws-https-proxy", o.AWSHTTPSProxy)
+		}
+	}
+	if o.AWSCustomCABundle != "" {
+		if _, err := base64.StdEncoding.DecodeString(o.AWSCustomCABundle); err != nil {
+			return fmt.Errorf("aws-custom-ca-bundle must be base64 encoded, %w", err)
+		}
+	}
+	return nil
+}
+
 func (o Options) validateRequiredFields() error {
 	if o.ClusterName == "" {
 		return fmt.Errorf("missing field, cluster-name")
 	}
 	return nil
 }
+
+func (o Options) validateStuckPodPolicy() error {
+	if !lo.Contains([]StuckPodPolicy{StuckPodPolicyIgnore, StuckPodPolicyRemoveFinalizers, StuckPodPolicyForceDelete}, StuckPodPolicy(o.StuckPodPolicy)) {
+		return fmt.Errorf("stuck-pod-policy must be one of %q, %q or %q", StuckPodPolicyIgnore, StuckPodPolicyRemoveFinalizers, StuckPodPolicyForceDelete)
+	}
+	if StuckPodPolicy(o.StuckPodPolicy) == StuckPodPolicyRemoveFinalizers && len(o.StuckPodFinalizerNames()) == 0 {
+		return fmt.Errorf("stuck-pod-finalizers must be set when stuck-pod-policy is %q", StuckPodPolicyRemoveFinalizers)
+	}
+	if o.StuckPodTimeout <= 0 {
+		return fmt.Errorf("stuck-pod-timeout must be positive")
+	}
+	return nil
+}
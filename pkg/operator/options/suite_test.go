@@ -18,7 +18,9 @@ import (
 	"context"
 	"flag"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/samber/lo"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
@@ -61,8 +63,57 @@ var _ = Describe("Options", func() {
 			"--cluster-endpoint", "https://env-cluster",
 			"--isolated-vpc",
 			"--vm-memory-overhead-percent", "0.1",
+			"--vm-memory-overheads", "m5.large=0.05,r5.24xlarge=0.03",
 			"--interruption-queue", "env-cluster",
-			"--reserved-enis", "10")
+			"--interruption-dead-letter-queue", "env-cluster-dlq",
+			"--reserved-enis", "10",
+			"--vcpu-quota-awareness",
+			"--registration-reboot-after", "5m",
+			"--launch-template-gc-ttl", "24h",
+			"--leaked-resource-gc-ttl", "1h",
+			"--leaked-resource-gc-dry-run",
+			"--scheduled-change-lead-time", "48h",
+			"--max-node-pin-duration", "72h",
+			"--billing-boundary-window", "10m",
+			"--zonal-shift",
+			"--interruption-taints",
+			"--interruption-pdb-override",
+			"--stopped-instance-policy", "Replace",
+			"--forbid-key-pairs",
+			"--require-encrypted-root-volumes",
+			"--additional-interruption-queues", "https://sqs.us-east-1.amazonaws.com/111122223333/env-queue=arn:aws:iam::111122223333:role/env-role",
+			"--deprovisioning-webhook-url", "https://env-webhook",
+			"--deprovisioning-webhook-timeout", "30s",
+			"--deprovisioning-webhook-failure-policy", "Fail",
+			"--launch-validation-webhook-url", "https://env-validation-webhook",
+			"--launch-validation-timeout", "10m",
+			"--offerings-webhook-url", "https://env-offerings-webhook",
+			"--offerings-webhook-timeout", "10s",
+			"--interruption-webhook-url", "https://env-interruption-webhook",
+			"--resource-name-prefix", "env-",
+			"--capacity-ledger-kubeconfig", "/etc/karpenter/hub/kubeconfig",
+			"--capacity-ledger-namespace", "env-ledger",
+			"--fault-injection-error-percent", "10",
+			"--fault-injection-delay-percent", "20",
+			"--fault-injection-delay", "1s",
+			"--fault-injection-services", "ec2",
+			"--max-kubelet-version-skew", "1",
+			"--stuck-pod-policy", "RemoveFinalizers",
+			"--stuck-pod-finalizers", "example.com/finalizer",
+			"--stuck-pod-timeout", "5m",
+			"--readiness-daemonsets", "kube-system/aws-node",
+			"--trusted-amis-parameter", "/env/trusted-amis",
+			"--trusted-ami-kms-key-arn", "arn:aws:kms:us-west-2:111122223333:key/env-key",
+			"--carbon-intensity-parameter", "/env/carbon-intensity",
+			"--carbon-intensity-weight", "0.8",
+			"--debug-endpoint-token", "env-token",
+			"--aws-https-proxy", "http://env-proxy:3128",
+			"--aws-no-proxy", "env-endpoint",
+			"--aws-custom-ca-bundle", "ZW52LWNh",
+			"--fips-endpoints",
+			"--manage-node-access-entries",
+			"--instance-profile-propagation-delay", "30s",
+			"--aws-feature-gates", "FaultInjection=true,MemoryOverheadCalibration=true,NodeAdoption=false,NodePinning=true")
 		Expect(err).ToNot(HaveOccurred())
 		expectOptionsEqual(opts, test.Options(test.OptionsFields{
 			ClusterCABundle:         lo.ToPtr("env-bundle"),
@@ -70,8 +121,67 @@ var _ = Describe("Options", func() {
 			ClusterEndpoint:         lo.ToPtr("https://env-cluster"),
 			IsolatedVPC:             lo.ToPtr(true),
 			VMMemoryOverheadPercent: lo.ToPtr[float64](0.1),
+			VMMemoryOverheads:       lo.ToPtr("m5.large=0.05,r5.24xlarge=0.03"),
 			InterruptionQueue:       lo.ToPtr("env-cluster"),
+			InterruptionDLQ:         lo.ToPtr("env-cluster-dlq"),
 			ReservedENIs:            lo.ToPtr(10),
+			VCPUQuotaAwareness:      lo.ToPtr(true),
+			RegistrationRebootAfter: lo.ToPtr(5 * time.Minute),
+			LaunchTemplateGCTTL:     lo.ToPtr(24 * time.Hour),
+			LeakedResourceGCTTL:     lo.ToPtr(time.Hour),
+			LeakedResourceGCDryRun:  lo.ToPtr(true),
+			ScheduledChangeLeadTime: lo.ToPtr(48 * time.Hour),
+			MaxNodePinDuration:      lo.ToPtr(72 * time.Hour),
+			BillingBoundaryWindow:   lo.ToPtr(10 * time.Minute),
+			ZonalShift:              lo.ToPtr(true),
+			InterruptionTaints:      lo.ToPtr(true),
+			InterruptionPDBOverride: lo.ToPtr(true),
+
+			RequireEncryptedRootVolumes: lo.ToPtr(true),
+			StoppedInstancePolicy:       lo.ToPtr("Replace"),
+			ForbidKeyPairs:              lo.ToPtr(true),
+
+			AdditionalInterruptionQueues: lo.ToPtr("https://sqs.us-east-1.amazonaws.com/111122223333/env-queue=arn:aws:iam::111122223333:role/env-role"),
+
+			DeprovisioningWebhookURL:           lo.ToPtr("https://env-webhook"),
+			DeprovisioningWebhookTimeout:       lo.ToPtr(30 * time.Second),
+			DeprovisioningWebhookFailurePolicy: lo.ToPtr("Fail"),
+
+			LaunchValidationWebhookURL: lo.ToPtr("https://env-validation-webhook"),
+			LaunchValidationTimeout:    lo.ToPtr(10 * time.Minute),
+			OfferingsWebhookURL:        lo.ToPtr("https://env-offerings-webhook"),
+			OfferingsWebhookTimeout:    lo.ToPtr(10 * time.Second),
+			InterruptionWebhookURL:     lo.ToPtr("https://env-interruption-webhook"),
+			ResourceNamePrefix:         lo.ToPtr("env-"),
+			CapacityLedgerKubeconfig:   lo.ToPtr("/etc/karpenter/hub/kubeconfig"),
+			CapacityLedgerNamespace:    lo.ToPtr("env-ledger"),
+			FaultInjectionErrorPercent: lo.ToPtr(10.0),
+			FaultInjectionDelayPercent: lo.ToPtr(20.0),
+			FaultInjectionDelay:        lo.ToPtr(time.Second),
+			FaultInjectionServices:     lo.ToPtr("ec2"),
+			MaxKubeletVersionSkew:      lo.ToPtr(1),
+			StuckPodPolicy:             lo.ToPtr("RemoveFinalizers"),
+			StuckPodFinalizers:         lo.ToPtr("example.com/finalizer"),
+			StuckPodTimeout:            lo.ToPtr(5 * time.Minute),
+			ReadinessDaemonSets:        lo.ToPtr("kube-system/aws-node"),
+
+			TrustedAMIsParameter: lo.ToPtr("/env/trusted-amis"),
+			TrustedAMIKMSKeyARN:  lo.ToPtr("arn:aws:kms:us-west-2:111122223333:key/env-key"),
+
+			CarbonIntensityParameter: lo.ToPtr("/env/carbon-intensity"),
+			CarbonIntensityWeight:    lo.ToPtr(0.8),
+
+			DebugEndpointToken: lo.ToPtr("env-token"),
+
+			AWSHTTPSProxy:           lo.ToPtr("http://env-proxy:3128"),
+			AWSNoProxy:              lo.ToPtr("env-endpoint"),
+			AWSCustomCABundle:       lo.ToPtr("ZW52LWNh"),
+			FIPSEndpoints:           lo.ToPtr(true),
+			ManageNodeAccessEntries: lo.ToPtr(true),
+
+			InstanceProfilePropagationDelay: lo.ToPtr(30 * time.Second),
+
+			AWSFeatureGates: options.FeatureGates{options.FaultInjection: true, options.MemoryOverheadCalibration: true, options.NodeAdoption: false, options.NodePinning: true},
 		}))
 	})
 	It("should correctly fallback to env vars when CLI flags aren't set", func() {
@@ -80,8 +190,57 @@ var _ = Describe("Options", func() {
 		os.Setenv("CLUSTER_ENDPOINT", "https://env-cluster")
 		os.Setenv("ISOLATED_VPC", "true")
 		os.Setenv("VM_MEMORY_OVERHEAD_PERCENT", "0.1")
+		os.Setenv("VM_MEMORY_OVERHEADS", "m5.large=0.05,r5.24xlarge=0.03")
 		os.Setenv("INTERRUPTION_QUEUE", "env-cluster")
+		os.Setenv("INTERRUPTION_DEAD_LETTER_QUEUE", "env-cluster-dlq")
 		os.Setenv("RESERVED_ENIS", "10")
+		os.Setenv("VCPU_QUOTA_AWARENESS", "true")
+		os.Setenv("REGISTRATION_REBOOT_AFTER", "5m")
+		os.Setenv("LAUNCH_TEMPLATE_GC_TTL", "24h")
+		os.Setenv("LEAKED_RESOURCE_GC_TTL", "1h")
+		os.Setenv("LEAKED_RESOURCE_GC_DRY_RUN", "true")
+		os.Setenv("SCHEDULED_CHANGE_LEAD_TIME", "48h")
+		os.Setenv("MAX_NODE_PIN_DURATION", "72h")
+		os.Setenv("BILLING_BOUNDARY_WINDOW", "10m")
+		os.Setenv("ZONAL_SHIFT", "true")
+		os.Setenv("INTERRUPTION_TAINTS", "true")
+		os.Setenv("INTERRUPTION_PDB_OVERRIDE", "true")
+		os.Setenv("STOPPED_INSTANCE_POLICY", "Replace")
+		os.Setenv("FORBID_KEY_PAIRS", "true")
+		os.Setenv("REQUIRE_ENCRYPTED_ROOT_VOLUMES", "true")
+		os.Setenv("ADDITIONAL_INTERRUPTION_QUEUES", "https://sqs.us-east-1.amazonaws.com/111122223333/env-queue=arn:aws:iam::111122223333:role/env-role")
+		os.Setenv("DEPROVISIONING_WEBHOOK_URL", "https://env-webhook")
+		os.Setenv("DEPROVISIONING_WEBHOOK_TIMEOUT", "30s")
+		os.Setenv("DEPROVISIONING_WEBHOOK_FAILURE_POLICY", "Fail")
+		os.Setenv("LAUNCH_VALIDATION_WEBHOOK_URL", "https://env-validation-webhook")
+		os.Setenv("LAUNCH_VALIDATION_TIMEOUT", "10m")
+		os.Setenv("OFFERINGS_WEBHOOK_URL", "https://env-offerings-webhook")
+		os.Setenv("OFFERINGS_WEBHOOK_TIMEOUT", "10s")
+		os.Setenv("INTERRUPTION_WEBHOOK_URL", "https://env-interruption-webhook")
+		os.Setenv("RESOURCE_NAME_PREFIX", "env-")
+		os.Setenv("CAPACITY_LEDGER_KUBECONFIG", "/etc/karpenter/hub/kubeconfig")
+		os.Setenv("CAPACITY_LEDGER_NAMESPACE", "env-ledger")
+		os.Setenv("FAULT_INJECTION_ERROR_PERCENT", "10")
+		os.Setenv("FAULT_INJECTION_DELAY_PERCENT", "20")
+		os.Setenv("FAULT_INJECTION_DELAY", "1s")
+		os.Setenv("FAULT_INJECTION_SERVICES", "ec2")
+		os.Setenv("MAX_KUBELET_VERSION_SKEW", "1")
+		os.Setenv("STUCK_POD_POLICY", "RemoveFinalizers")
+		os.Setenv("STUCK_POD_FINALIZERS", "example.com/finalizer")
+		os.Setenv("STUCK_POD_TIMEOUT", "5m")
+		os.Setenv("READINESS_DAEMONSETS", "kube-system/aws-node")
+		os.Setenv("TRUSTED_AMIS_PARAMETER", "/env/trusted-amis")
+		os.Setenv("TRUSTED_AMI_KMS_KEY_ARN", "arn:aws:kms:us-west-2:111122223333:key/env-key")
+		os.Setenv("CARBON_INTENSITY_PARAMETER", "/env/carbon-intensity")
+		os.Setenv("CARBON_INTENSITY_WEIGHT", "0.8")
+		os.Setenv("DEBUG_ENDPOINT_TOKEN", "env-token")
+		os.Setenv("AWS_HTTPS_PROXY", "http://env-proxy:3128")
+		os.Setenv("AWS_NO_PROXY", "env-endpoint")
+		os.Setenv("AWS_CUSTOM_CA_BUNDLE", "ZW52LWNh")
+		os.Setenv("FIPS_ENDPOINTS", "true")
+		os.Setenv("MANAGE_NODE_ACCESS_ENTRIES", "true")
+		os.Setenv("INSTANCE_PROFILE_PROPAGATION_DELAY", "30s")
+		os.Setenv("AWS_FEATURE_GATES", "FaultInjection=true,MemoryOverheadCalibration=true,NodeAdoption=false,NodePinning=true")
 
 		// Add flags after we set the environment variables so that the parsing logic correctly refers
 		// to the new environment variable values
@@ -94,8 +253,67 @@ var _ = Describe("Options", func() {
 			ClusterEndpoint:         lo.ToPtr("https://env-cluster"),
 			IsolatedVPC:             lo.ToPtr(true),
 			VMMemoryOverheadPercent: lo.ToPtr[float64](0.1),
+			VMMemoryOverheads:       lo.ToPtr("m5.large=0.05,r5.24xlarge=0.03"),
 			InterruptionQueue:       lo.ToPtr("env-cluster"),
+			InterruptionDLQ:         lo.ToPtr("env-cluster-dlq"),
 			ReservedENIs:            lo.ToPtr(10),
+			VCPUQuotaAwareness:      lo.ToPtr(true),
+			RegistrationRebootAfter: lo.ToPtr(5 * time.Minute),
+			LaunchTemplateGCTTL:     lo.ToPtr(24 * time.Hour),
+			LeakedResourceGCTTL:     lo.ToPtr(time.Hour),
+			LeakedResourceGCDryRun:  lo.ToPtr(true),
+			ScheduledChangeLeadTime: lo.ToPtr(48 * time.Hour),
+			MaxNodePinDuration:      lo.ToPtr(72 * time.Hour),
+			BillingBoundaryWindow:   lo.ToPtr(10 * time.Minute),
+			ZonalShift:              lo.ToPtr(true),
+			InterruptionTaints:      lo.ToPtr(true),
+			InterruptionPDBOverride: lo.ToPtr(true),
+
+			RequireEncryptedRootVolumes: lo.ToPtr(true),
+			StoppedInstancePolicy:       lo.ToPtr("Replace"),
+			ForbidKeyPairs:              lo.ToPtr(true),
+
+			AdditionalInterruptionQueues: lo.ToPtr("https://sqs.us-east-1.amazonaws.com/111122223333/env-queue=arn:aws:iam::111122223333:role/env-role"),
+
+			DeprovisioningWebhookURL:           lo.ToPtr("https://env-webhook"),
+			DeprovisioningWebhookTimeout:       lo.ToPtr(30 * time.Second),
+			DeprovisioningWebhookFailurePolicy: lo.ToPtr("Fail"),
+
+			LaunchValidationWebhookURL: lo.ToPtr("https://env-validation-webhook"),
+			LaunchValidationTimeout:    lo.ToPtr(10 * time.Minute),
+			OfferingsWebhookURL:        lo.ToPtr("https://env-offerings-webhook"),
+			OfferingsWebhookTimeout:    lo.ToPtr(10 * time.Second),
+			InterruptionWebhookURL:     lo.ToPtr("https://env-interruption-webhook"),
+			ResourceNamePrefix:         lo.ToPtr("env-"),
+			CapacityLedgerKubeconfig:   lo.ToPtr("/etc/karpenter/hub/kubeconfig"),
+			CapacityLedgerNamespace:    lo.ToPtr("env-ledger"),
+			FaultInjectionErrorPercent: lo.ToPtr(10.0),
+			FaultInjectionDelayPercent: lo.ToPtr(20.0),
+			FaultInjectionDelay:        lo.ToPtr(time.Second),
+			FaultInjectionServices:     lo.ToPtr("ec2"),
+			MaxKubeletVersionSkew:      lo.ToPtr(1),
+			StuckPodPolicy:             lo.ToPtr("RemoveFinalizers"),
+			StuckPodFinalizers:         lo.ToPtr("example.com/finalizer"),
+			StuckPodTimeout:            lo.ToPtr(5 * time.Minute),
+			ReadinessDaemonSets:        lo.ToPtr("kube-system/aws-node"),
+
+			TrustedAMIsParameter: lo.ToPtr("/env/trusted-amis"),
+			TrustedAMIKMSKeyARN:  lo.ToPtr("arn:aws:kms:us-west-2:111122223333:key/env-key"),
+
+			CarbonIntensityParameter: lo.ToPtr("/env/carbon-intensity"),
+			CarbonIntensityWeight:    lo.ToPtr(0.8),
+
+			DebugEndpointToken: lo.ToPtr("env-token"),
+
+			AWSHTTPSProxy:           lo.ToPtr("http://env-proxy:3128"),
+			AWSNoProxy:              lo.ToPtr("env-endpoint"),
+			AWSCustomCABundle:       lo.ToPtr("ZW52LWNh"),
+			FIPSEndpoints:           lo.ToPtr(true),
+			ManageNodeAccessEntries: lo.ToPtr(true),
+
+			InstanceProfilePropagationDelay: lo.ToPtr(30 * time.Second),
+
+			AWSFeatureGates: options.FeatureGates{options.FaultInjection: true, options.MemoryOverheadCalibration: true, options.NodeAdoption: false, options.NodePinning: true},
 		}))
 	})
 
@@ -103,6 +321,14 @@ var _ = Describe("Options", func() {
 		BeforeEach(func() {
 			opts.AddFlags(fs)
 		})
+		It("should fail when an unknown aws feature gate is set", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--aws-feature-gates", "NodeAdoption=false,Unknown=true")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when an aws feature gate isn't set to a bool", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--aws-feature-gates", "NodeAdoption=maybe")
+			Expect(err).To(HaveOccurred())
+		})
 		It("should fail when cluster name is not set", func() {
 			err := opts.Parse(fs)
 			Expect(err).To(HaveOccurred())
@@ -115,10 +341,197 @@ var _ = Describe("Options", func() {
 			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--vm-memory-overhead-percent", "-0.01")
 			Expect(err).To(HaveOccurred())
 		})
+		It("should fail when vmMemoryOverheads is set without the MemoryOverheadCalibration feature gate", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--vm-memory-overheads", "m5.large=0.05")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when vmMemoryOverheads has an entry that isn't a fraction", func() {
+			for _, overheads := range []string{"m5.large", "m5.large=five", "m5.large=1.5", "=0.05"} {
+				err := opts.Parse(fs, "--cluster-name", "test-cluster", "--aws-feature-gates", "MemoryOverheadCalibration=true", "--vm-memory-overheads", overheads)
+				Expect(err).To(HaveOccurred(), overheads)
+			}
+		})
 		It("should fail when reservedENIs is negative", func() {
 			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--reserved-enis", "-1")
 			Expect(err).To(HaveOccurred())
 		})
+		It("should fail when registrationRebootAfter is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--registration-reboot-after", "-1m")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when registrationRebootAfter is not less than the registration ttl", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--registration-reboot-after", "15m")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when launchTemplateGCTTL is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--launch-template-gc-ttl", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when leakedResourceGCTTL is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--leaked-resource-gc-ttl", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when scheduledChangeLeadTime is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--scheduled-change-lead-time", "-1h")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when maxNodePinDuration is not positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--max-node-pin-duration", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when billingBoundaryWindow is not positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--billing-boundary-window", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionDLQ is set without interruptionQueue", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-dead-letter-queue", "test-cluster-dlq")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionDLQ is the same as interruptionQueue", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue", "test-cluster", "--interruption-dead-letter-queue", "test-cluster")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when additionalInterruptionQueues is set without interruptionQueue", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--additional-interruption-queues", "https://sqs.us-east-1.amazonaws.com/111122223333/queue")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when additionalInterruptionQueues has an entry that isn't a queue URL", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue", "test-cluster", "--additional-interruption-queues", "queue")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when additionalInterruptionQueues has an invalid role ARN", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue", "test-cluster", "--additional-interruption-queues", "https://sqs.us-east-1.amazonaws.com/111122223333/queue=role")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should resolve the region of each additional interruption queue", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-queue", "test-cluster", "--additional-interruption-queues",
+				"https://sqs.us-east-1.amazonaws.com/111122223333/a=arn:aws:iam::111122223333:role/a, https://eu-west-1.queue.amazonaws.com/444455556666/b")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opts.AdditionalInterruptionQueueConfigs()).To(Equal([]options.InterruptionQueueConfig{
+				{URL: "https://sqs.us-east-1.amazonaws.com/111122223333/a", Region: "us-east-1", RoleARN: "arn:aws:iam::111122223333:role/a"},
+				{URL: "https://eu-west-1.queue.amazonaws.com/444455556666/b", Region: "eu-west-1"},
+			}))
+		})
+		It("should fail when deprovisioningWebhookURL is not an http(s) URL", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--deprovisioning-webhook-url", "ftp://webhook")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when deprovisioningWebhookTimeout is not positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--deprovisioning-webhook-timeout", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when stoppedInstancePolicy is unknown", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--stopped-instance-policy", "Reboot")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when instanceProfilePropagationDelay is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--instance-profile-propagation-delay", "-1s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when stuckPodPolicy is unknown", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--stuck-pod-policy", "Evict")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when stuckPodPolicy is RemoveFinalizers without stuckPodFinalizers", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--stuck-pod-policy", "RemoveFinalizers")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when stuckPodTimeout is not positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--stuck-pod-timeout", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when deprovisioningWebhookFailurePolicy is unknown", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--deprovisioning-webhook-failure-policy", "Retry")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when launchValidationWebhookURL is not an http(s) URL", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--launch-validation-webhook-url", "ftp://webhook")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when launchValidationTimeout is not positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--launch-validation-timeout", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when offeringsWebhookURL is not an http(s) URL", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--offerings-webhook-url", "ftp://webhook")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when offeringsWebhookTimeout is not positive", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--offerings-webhook-timeout", "0s")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when interruptionWebhookURL is not an http(s) URL", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--interruption-webhook-url", "ftp://webhook")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when resourceNamePrefix contains invalid characters", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--resource-name-prefix", "acme/")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when resourceNamePrefix is too long", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--resource-name-prefix", strings.Repeat("a", 33))
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when capacityLedgerKubeconfig is set without vcpuQuotaAwareness", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--capacity-ledger-kubeconfig", "/etc/karpenter/hub/kubeconfig")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when faultInjectionErrorPercent is set without the FaultInjection feature gate", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--fault-injection-error-percent", "10")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when faultInjectionDelayPercent is greater than 100", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--aws-feature-gates", "FaultInjection=true", "--fault-injection-delay-percent", "101")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when faultInjectionServices has an unknown service", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--fault-injection-services", "ec2,iam")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when maxKubeletVersionSkew is greater than 3", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--max-kubelet-version-skew", "4")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when readinessDaemonSets has an entry without a namespace", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--readiness-daemonsets", "kube-system/aws-node,kube-proxy")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when trustedAMIKMSKeyARN is not a KMS key ARN", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--trusted-ami-kms-key-arn", "arn:aws:kms:us-west-2:111122223333:alias/ami-signing")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when carbonIntensityWeight is negative", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--carbon-intensity-weight", "-0.5")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when awsHTTPSProxy is not an http(s) URL", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--aws-https-proxy", "socks5://proxy:1080")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail when awsCustomCABundle is not base64 encoded", func() {
+			err := opts.Parse(fs, "--cluster-name", "test-cluster", "--aws-custom-ca-bundle", "-----BEGIN CERTIFICATE-----")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("FeatureGates", func() {
+	It("should default feature gates that aren't set", func() {
+		gates, err := options.ParseFeatureGates("NodeAdoption=false")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gates.Enabled(options.NodeAdoption)).To(BeFalse())
+		Expect(gates.Enabled(options.NodePinning)).To(Equal(options.Features[options.NodePinning].Default))
+	})
+	It("should disable alpha feature gates by default", func() {
+		Expect(options.FeatureGates{}.Enabled(options.MemoryOverheadCalibration)).To(BeFalse())
+	})
+	It("should disable unknown feature gates", func() {
+		Expect(options.FeatureGates{}.Enabled("Unknown")).To(BeFalse())
+	})
+	It("should summarize every known feature gate with its maturity", func() {
+		Expect(options.FeatureGates{options.NodeAdoption: false}.String()).To(Equal(
+			"DisruptionApproval=true (Beta),FaultInjection=false (Alpha),KubeletUpgradeRollout=false (Alpha),KubeletVersionSkew=false (Alpha),MemoryOverheadCalibration=false (Alpha),NodeAdoption=false (Beta),NodeMetadataSync=true (Beta),NodePinning=true (Beta),RemoveTerminationProtection=false (Alpha),RescheduleOutOfPods=false (Alpha),RespectExternalDrains=false (Alpha)",
+		))
 	})
 })
 
@@ -129,6 +542,55 @@ func expectOptionsEqual(optsA *options.Options, optsB *options.Options) {
 	Expect(optsA.ClusterEndpoint).To(Equal(optsB.ClusterEndpoint))
 	Expect(optsA.IsolatedVPC).To(Equal(optsB.IsolatedVPC))
 	Expect(optsA.VMMemoryOverheadPercent).To(Equal(optsB.VMMemoryOverheadPercent))
+	Expect(optsA.VMMemoryOverheads).To(Equal(optsB.VMMemoryOverheads))
 	Expect(optsA.InterruptionQueue).To(Equal(optsB.InterruptionQueue))
+	Expect(optsA.InterruptionDLQ).To(Equal(optsB.InterruptionDLQ))
 	Expect(optsA.ReservedENIs).To(Equal(optsB.ReservedENIs))
+	Expect(optsA.VCPUQuotaAwareness).To(Equal(optsB.VCPUQuotaAwareness))
+	Expect(optsA.RegistrationRebootAfter).To(Equal(optsB.RegistrationRebootAfter))
+	Expect(optsA.LaunchTemplateGCTTL).To(Equal(optsB.LaunchTemplateGCTTL))
+	Expect(optsA.LeakedResourceGCTTL).To(Equal(optsB.LeakedResourceGCTTL))
+	Expect(optsA.LeakedResourceGCDryRun).To(Equal(optsB.LeakedResourceGCDryRun))
+	Expect(optsA.ScheduledChangeLeadTime).To(Equal(optsB.ScheduledChangeLeadTime))
+	Expect(optsA.MaxNodePinDuration).To(Equal(optsB.MaxNodePinDuration))
+	Expect(optsA.BillingBoundaryWindow).To(Equal(optsB.BillingBoundaryWindow))
+	Expect(optsA.ZonalShift).To(Equal(optsB.ZonalShift))
+	Expect(optsA.InterruptionTaints).To(Equal(optsB.InterruptionTaints))
+	Expect(optsA.InterruptionPDBOverride).To(Equal(optsB.InterruptionPDBOverride))
+	Expect(optsA.StoppedInstancePolicy).To(Equal(optsB.StoppedInstancePolicy))
+	Expect(optsA.ForbidKeyPairs).To(Equal(optsB.ForbidKeyPairs))
+	Expect(optsA.RequireEncryptedRootVolumes).To(Equal(optsB.RequireEncryptedRootVolumes))
+	Expect(optsA.AdditionalInterruptionQueues).To(Equal(optsB.AdditionalInterruptionQueues))
+	Expect(optsA.DeprovisioningWebhookURL).To(Equal(optsB.DeprovisioningWebhookURL))
+	Expect(optsA.DeprovisioningWebhookTimeout).To(Equal(optsB.DeprovisioningWebhookTimeout))
+	Expect(optsA.DeprovisioningWebhookFailurePolicy).To(Equal(optsB.DeprovisioningWebhookFailurePolicy))
+	Expect(optsA.LaunchValidationWebhookURL).To(Equal(optsB.LaunchValidationWebhookURL))
+	Expect(optsA.LaunchValidationTimeout).To(Equal(optsB.LaunchValidationTimeout))
+	Expect(optsA.OfferingsWebhookURL).To(Equal(optsB.OfferingsWebhookURL))
+	Expect(optsA.OfferingsWebhookTimeout).To(Equal(optsB.OfferingsWebhookTimeout))
+	Expect(optsA.InterruptionWebhookURL).To(Equal(optsB.InterruptionWebhookURL))
+	Expect(optsA.ResourceNamePrefix).To(Equal(optsB.ResourceNamePrefix))
+	Expect(optsA.CapacityLedgerKubeconfig).To(Equal(optsB.CapacityLedgerKubeconfig))
+	Expect(optsA.CapacityLedgerNamespace).To(Equal(optsB.CapacityLedgerNamespace))
+	Expect(optsA.FaultInjectionErrorPercent).To(Equal(optsB.FaultInjectionErrorPercent))
+	Expect(optsA.FaultInjectionDelayPercent).To(Equal(optsB.FaultInjectionDelayPercent))
+	Expect(optsA.FaultInjectionDelay).To(Equal(optsB.FaultInjectionDelay))
+	Expect(optsA.FaultInjectionServices).To(Equal(optsB.FaultInjectionServices))
+	Expect(optsA.MaxKubeletVersionSkew).To(Equal(optsB.MaxKubeletVersionSkew))
+	Expect(optsA.StuckPodPolicy).To(Equal(optsB.StuckPodPolicy))
+	Expect(optsA.StuckPodFinalizers).To(Equal(optsB.StuckPodFinalizers))
+	Expect(optsA.StuckPodTimeout).To(Equal(optsB.StuckPodTimeout))
+	Expect(optsA.ReadinessDaemonSets).To(Equal(optsB.ReadinessDaemonSets))
+	Expect(optsA.TrustedAMIsParameter).To(Equal(optsB.TrustedAMIsParameter))
+	Expect(optsA.TrustedAMIKMSKeyARN).To(Equal(optsB.TrustedAMIKMSKeyARN))
+	Expect(optsA.CarbonIntensityParameter).To(Equal(optsB.CarbonIntensityParameter))
+	Expect(optsA.CarbonIntensityWeight).To(Equal(optsB.CarbonIntensityWeight))
+	Expect(optsA.DebugEndpointToken).To(Equal(optsB.DebugEndpointToken))
+	Expect(optsA.AWSHTTPSProxy).To(Equal(optsB.AWSHTTPSProxy))
+	Expect(optsA.AWSNoProxy).To(Equal(optsB.AWSNoProxy))
+	Expect(optsA.AWSCustomCABundle).To(Equal(optsB.AWSCustomCABundle))
+	Expect(optsA.FIPSEndpoints).To(Equal(optsB.FIPSEndpoints))
+	Expect(optsA.ManageNodeAccessEntries).To(Equal(optsB.ManageNodeAccessEntries))
+	Expect(optsA.InstanceProfilePropagationDelay).To(Equal(optsB.InstanceProfilePropagationDelay))
+	Expect(optsA.AWSFeatureGates).To(Equal(optsB.AWSFeatureGates))
 }
@@ -16,6 +16,7 @@ package operator_test
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"testing"
 
@@ -24,6 +25,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	"github.com/samber/lo"
+	"k8s.io/client-go/rest"
 
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
@@ -104,4 +106,46 @@ var _ = Describe("Operator", func() {
 		_, err := awscontext.ResolveClusterEndpoint(ctx, fakeEKSAPI)
 		Expect(err).To(HaveOccurred())
 	})
+	It("should resolve the CA bundle if set via configuration", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			ClusterCABundle: lo.ToPtr("Y2EtYnVuZGxlCg=="),
+		}))
+		caBundle, err := awscontext.GetCABundle(ctx, &rest.Config{}, fakeEKSAPI)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(caBundle).To(Equal(lo.ToPtr("Y2EtYnVuZGxlCg==")))
+		Expect(fakeEKSAPI.DescribeClusterBehavior.Calls()).To(BeZero())
+	})
+	It("should resolve the CA bundle from the REST config", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			ClusterCABundle: lo.ToPtr(""),
+		}))
+		caBundle, err := awscontext.GetCABundle(ctx, &rest.Config{TLSClientConfig: rest.TLSClientConfig{CAData: []byte("ca-bundle")}}, fakeEKSAPI)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(caBundle).To(Equal(lo.ToPtr(base64.StdEncoding.EncodeToString([]byte("ca-bundle")))))
+		Expect(fakeEKSAPI.DescribeClusterBehavior.Calls()).To(BeZero())
+	})
+	It("should resolve the CA bundle via call to API if the REST config doesn't have one", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			ClusterCABundle: lo.ToPtr(""),
+		}))
+		fakeEKSAPI.DescribeClusterBehavior.Output.Set(
+			&eks.DescribeClusterOutput{
+				Cluster: &ekstypes.Cluster{
+					CertificateAuthority: &ekstypes.Certificate{Data: lo.ToPtr("Y2x1c3Rlci1jYQo=")},
+				},
+			},
+		)
+		caBundle, err := awscontext.GetCABundle(ctx, &rest.Config{}, fakeEKSAPI)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(caBundle).To(Equal(lo.ToPtr("Y2x1c3Rlci1jYQo=")))
+	})
+	It("should continue without a CA bundle if the API fails", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{
+			ClusterCABundle: lo.ToPtr(""),
+		}))
+		fakeEKSAPI.DescribeClusterBehavior.Error.Set(errors.New("test error"))
+		caBundle, err := awscontext.GetCABundle(ctx, &rest.Config{}, fakeEKSAPI)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(caBundle).To(Equal(lo.ToPtr("")))
+	})
 })
@@ -0,0 +1,315 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accessentry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	yaml "sigs.k8s.io/yaml/goyaml.v3"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+const (
+	// AWSAuthConfigMapName is the ConfigMap in kube-system that maps IAM principals to Kubernetes identities for clusters that
+	// authenticate with the CONFIG_MAP authentication mode
+	AWSAuthConfigMapName = "aws-auth"
+
+	AccessEntryTypeLinux   = "EC2_LINUX"
+	AccessEntryTypeWindows = "EC2_WINDOWS"
+
+	nodeUsername          = "system:node:{{EC2PrivateDNSName}}"
+	authenticationModeKey = "authentication-mode"
+)
+
+// nodeGroups are the Kubernetes groups that EKS grants to EC2_LINUX and EC2_WINDOWS access entries. Windows nodes also need
+// the eks:kube-proxy-windows group so that kube-proxy can run with the node's identity.
+var nodeGroups = map[string][]string{
+	AccessEntryTypeLinux:   {"system:bootstrappers", "system:nodes"},
+	AccessEntryTypeWindows: {"eks:kube-proxy-windows", "system:bootstrappers", "system:nodes"},
+}
+
+type Provider interface {
+	// PrincipalARN returns the ARN of the role that nodes launched from the EC2NodeClass assume
+	PrincipalARN(context.Context, *v1.EC2NodeClass) (string, error)
+	// Ensure grants nodes that assume the principal access to join the cluster, either through an EKS access entry or through
+	// the aws-auth ConfigMap for clusters which use the CONFIG_MAP authentication mode
+	Ensure(context.Context, string, string) error
+	// Delete removes the access that Ensure granted to the principal. Access that wasn't granted by Karpenter isn't removed.
+	Delete(context.Context, string) error
+}
+
+type DefaultProvider struct {
+	eksapi              sdk.EKSAPI
+	iamapi              sdk.IAMAPI
+	kubernetesInterface kubernetes.Interface
+	cache               *cache.Cache
+}
+
+// NewDefaultProvider takes a kubernetes.Interface rather than a cached client so that Karpenter doesn't need to watch
+// every ConfigMap in the cluster to read aws-auth
+func NewDefaultProvider(eksapi sdk.EKSAPI, iamapi sdk.IAMAPI, kubernetesInterface kubernetes.Interface, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		eksapi:              eksapi,
+		iamapi:              iamapi,
+		kubernetesInterface: kubernetesInterface,
+		cache:               cache,
+	}
+}
+
+func (p *DefaultProvider) PrincipalARN(ctx context.Context, nodeClass *v1.EC2NodeClass) (string, error) {
+	var roleARN string
+	if nodeClass.Spec.Role != "" {
+		out, err := p.iamapi.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(nodeClass.InstanceProfileRole())})
+		if err != nil {
+			return "", fmt.Errorf("getting role %q, %w", nodeClass.InstanceProfileRole(), err)
+		}
+		roleARN = lo.FromPtr(out.Role.Arn)
+	} else {
+		out, err := p.iamapi.GetInstanceProfile(ctx, &iam.GetInstanceProfileInput{InstanceProfileName: aws.String(nodeClass.SpecInstanceProfileName())})
+		if err != nil {
+			return "", fmt.Errorf("getting instance profile %q, %w", nodeClass.SpecInstanceProfileName(), err)
+		}
+		if len(out.InstanceProfile.Roles) == 0 {
+			return "", fmt.Errorf("instance profile %q doesn't have a role", nodeClass.SpecInstanceProfileName())
+		}
+		roleARN = lo.FromPtr(out.InstanceProfile.Roles[0].Arn)
+	}
+	// Neither access entries nor the aws-auth ConfigMap match principals by ARNs which include the role's path
+	parsed, err := arn.Parse(roleARN)
+	if err != nil {
+		return "", fmt.Errorf("parsing role arn %q, %w", roleARN, err)
+	}
+	parsed.Resource = "role/" + parsed.Resource[strings.LastIndex(parsed.Resource, "/")+1:]
+	return parsed.String(), nil
+}
+
+func (p *DefaultProvider) Ensure(ctx context.Context, principalARN string, accessEntryType string) error {
+	if _, ok := p.cache.Get(principalARN); ok {
+		return nil
+	}
+	mode, err := p.authenticationMode(ctx)
+	if err != nil {
+		return err
+	}
+	if mode == ekstypes.AuthenticationModeConfigMap {
+		err = p.ensureRoleMapping(ctx, principalARN, accessEntryType)
+	} else {
+		err = p.ensureAccessEntry(ctx, principalARN, accessEntryType)
+	}
+	if err != nil {
+		return err
+	}
+	p.cache.SetDefault(principalARN, nil)
+	return nil
+}
+
+func (p *DefaultProvider) Delete(ctx context.Context, principalARN string) error {
+	mode, err := p.authenticationMode(ctx)
+	if err != nil {
+		return err
+	}
+	if mode == ekstypes.AuthenticationModeConfigMap {
+		err = p.deleteRoleMapping(ctx, principalARN)
+	} else {
+		err = p.deleteAccessEntry(ctx, principalARN)
+	}
+	if err != nil {
+		return err
+	}
+	p.cache.Delete(principalARN)
+	return nil
+}
+
+func (p *DefaultProvider) authenticationMode(ctx context.Context) (ekstypes.AuthenticationMode, error) {
+	if mode, ok := p.cache.Get(authenticationModeKey); ok {
+		return mode.(ekstypes.AuthenticationMode), nil
+	}
+	out, err := p.eksapi.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: aws.String(options.FromContext(ctx).ClusterName)})
+	if err != nil {
+		return "", fmt.Errorf("describing cluster, %w", err)
+	}
+	// Clusters created before access entries were introduced don't report an authentication mode and use the aws-auth ConfigMap
+	mode := ekstypes.AuthenticationModeConfigMap
+	if out.Cluster.AccessConfig != nil && out.Cluster.AccessConfig.AuthenticationMode != "" {
+		mode = out.Cluster.AccessConfig.AuthenticationMode
+	}
+	p.cache.SetDefault(authenticationModeKey, mode)
+	return mode, nil
+}
+
+func (p *DefaultProvider) ensureAccessEntry(ctx context.Context, principalARN string, accessEntryType string) error {
+	if _, err := p.eksapi.DescribeAccessEntry(ctx, &eks.DescribeAccessEntryInput{
+		ClusterName:  aws.String(options.FromContext(ctx).ClusterName),
+		PrincipalArn: aws.String(principalARN),
+	}); err == nil {
+		return nil
+	} else if !awserrors.IsNotFound(err) {
+		return fmt.Errorf("describing access entry for %q, %w", principalARN, err)
+	}
+	if _, err := p.eksapi.CreateAccessEntry(ctx, &eks.CreateAccessEntryInput{
+		ClusterName:  aws.String(options.FromContext(ctx).ClusterName),
+		PrincipalArn: aws.String(principalARN),
+		Type:         aws.String(accessEntryType),
+		Tags:         map[string]string{fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName): "owned"},
+	}); awserrors.IgnoreAlreadyExists(err) != nil {
+		return fmt.Errorf("creating access entry for %q, %w", principalARN, err)
+	}
+	log.FromContext(ctx).WithValues("principal-arn", principalARN, "type", accessEntryType).Info("created access entry")
+	return nil
+}
+
+func (p *DefaultProvider) deleteAccessEntry(ctx context.Context, principalARN string) error {
+	out, err := p.eksapi.DescribeAccessEntry(ctx, &eks.DescribeAccessEntryInput{
+		ClusterName:  aws.String(options.FromContext(ctx).ClusterName),
+		PrincipalArn: aws.String(principalARN),
+	})
+	if err != nil {
+		return awserrors.IgnoreNotFound(fmt.Errorf("describing access entry for %q, %w", principalARN, err))
+	}
+	if out.AccessEntry.Tags[fmt.Sprintf("kubernetes.io/cluster/%s", options.FromContext(ctx).ClusterName)] != "owned" {
+		return nil
+	}
+	if _, err = p.eksapi.DeleteAccessEntry(ctx, &eks.DeleteAccessEntryInput{
+		ClusterName:  aws.String(options.FromContext(ctx).ClusterName),
+		PrincipalArn: aws.String(principalARN),
+	}); err != nil {
+		return awserrors.IgnoreNotFound(fmt.Errorf("deleting access entry for %q, %w", principalARN, err))
+	}
+	log.FromContext(ctx).WithValues("principal-arn", principalARN).Info("deleted access entry")
+	return nil
+}
+
+// ensureRoleMapping adds a mapRoles entry for the principal to the aws-auth ConfigMap, creating the ConfigMap if it doesn't
+// exist. Principals that Karpenter adds are recorded in an annotation so that entries which were added by other tools, such
+// as those for EKS managed node groups, are never removed.
+func (p *DefaultProvider) ensureRoleMapping(ctx context.Context, principalARN string, accessEntryType string) error {
+	configMaps := p.kubernetesInterface.CoreV1().ConfigMaps(metav1.NamespaceSystem)
+	configMap, err := configMaps.Get(ctx, AWSAuthConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("getting aws-auth configmap, %w", err)
+		}
+		if configMap, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: AWSAuthConfigMapName},
+		}, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("creating aws-auth configmap, %w", err)
+		}
+	}
+	roles, err := roleMappings(configMap)
+	if err != nil {
+		return err
+	}
+	if lo.ContainsBy(roles, func(r map[string]any) bool { return r["rolearn"] == principalARN }) {
+		return nil
+	}
+	roles = append(roles, map[string]any{
+		"rolearn":  principalARN,
+		"username": nodeUsername,
+		"groups":   nodeGroups[accessEntryType],
+	})
+	if err = setRoleMappings(configMap, roles); err != nil {
+		return err
+	}
+	configMap.Annotations = lo.Assign(configMap.Annotations, map[string]string{
+		v1.AnnotationManagedNodeRoles: strings.Join(lo.Uniq(append(managedRoles(configMap), principalARN)), ","),
+	})
+	// Updates are rejected if the ConfigMap's resourceVersion has changed, so concurrent updates to mapRoles by other tools
+	// aren't overwritten
+	if _, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("updating aws-auth configmap, %w", err)
+	}
+	log.FromContext(ctx).WithValues("principal-arn", principalARN).Info("added role mapping to aws-auth configmap")
+	return nil
+}
+
+func (p *DefaultProvider) deleteRoleMapping(ctx context.Context, principalARN string) error {
+	configMaps := p.kubernetesInterface.CoreV1().ConfigMaps(metav1.NamespaceSystem)
+	configMap, err := configMaps.Get(ctx, AWSAuthConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("getting aws-auth configmap, %w", err)
+	}
+	if !lo.Contains(managedRoles(configMap), principalARN) {
+		return nil
+	}
+	roles, err := roleMappings(configMap)
+	if err != nil {
+		return err
+	}
+	if err = setRoleMappings(configMap, lo.Reject(roles, func(r map[string]any, _ int) bool { return r["rolearn"] == principalARN })); err != nil {
+		return err
+	}
+	configMap.Annotations[v1.AnnotationManagedNodeRoles] = strings.Join(lo.Without(managedRoles(configMap), principalARN), ",")
+	if _, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("updating aws-auth configmap, %w", err)
+	}
+	log.FromContext(ctx).WithValues("principal-arn", principalARN).Info("removed role mapping from aws-auth configmap")
+	return nil
+}
+
+// roleMappings returns the mapRoles entries of the aws-auth ConfigMap. Entries are decoded generically so that any fields
+// which Karpenter doesn't set are preserved when the ConfigMap is updated. They're decoded with YAML 1.2 semantics so that
+// unquoted values such as "y" or "on" aren't rewritten as booleans.
+func roleMappings(configMap *corev1.ConfigMap) ([]map[string]any, error) {
+	var roles []map[string]any
+	if err := yaml.Unmarshal([]byte(configMap.Data["mapRoles"]), &roles); err != nil {
+		return nil, fmt.Errorf("parsing mapRoles of aws-auth configmap, %w", err)
+	}
+	return roles, nil
+}
+
+func setRoleMappings(configMap *corev1.ConfigMap, roles []map[string]any) error {
+	mapRoles := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(mapRoles)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(roles); err != nil {
+		return fmt.Errorf("serializing mapRoles of aws-auth configmap, %w", err)
+	}
+	configMap.Data = lo.Assign(configMap.Data, map[string]string{"mapRoles": mapRoles.String()})
+	return nil
+}
+
+func managedRoles(configMap *corev1.ConfigMap) []string {
+	roles := lo.Compact(strings.Split(configMap.Annotations[v1.AnnotationManagedNodeRoles], ","))
+	sort.Strings(roles)
+	return roles
+}
+
+// AccessEntryType returns the type of access entry that nodes launched from the EC2NodeClass need
+func AccessEntryType(nodeClass *v1.EC2NodeClass) string {
+	return lo.Ternary(lo.Contains([]string{v1.AMIFamilyWindows2019, v1.AMIFamilyWindows2022}, nodeClass.AMIFamily()), AccessEntryTypeWindows, AccessEntryTypeLinux)
+}
@@ -0,0 +1,105 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accessentry_test
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+
+	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accessentry"
+	"github.com/aws/karpenter-provider-aws/pkg/test"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "sigs.k8s.io/karpenter/pkg/utils/testing"
+)
+
+var ctx context.Context
+
+func TestAWS(t *testing.T) {
+	ctx = TestContextWithLogger(t)
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AccessEntryProvider")
+}
+
+// chartRole returns the kube-system Role of the Helm chart. Template directives are stripped rather than rendered, which
+// keeps every conditional rule.
+func chartRole() *rbacv1.Role {
+	raw, err := os.ReadFile("../../../charts/karpenter/templates/role.yaml")
+	Expect(err).ToNot(HaveOccurred())
+	directive := regexp.MustCompile(`^\s*\{\{.*\}\}\s*$`)
+	for _, document := range strings.Split(string(raw), "\n---\n") {
+		lines := lo.Reject(strings.Split(document, "\n"), func(l string, _ int) bool { return directive.MatchString(l) })
+		rendered := regexp.MustCompile(`\{\{.*?\}\}`).ReplaceAllString(strings.Join(lines, "\n"), "karpenter")
+		role := &rbacv1.Role{}
+		Expect(yaml.Unmarshal([]byte(rendered), role)).To(Succeed())
+		if role.Kind == "Role" && role.Namespace == metav1.NamespaceSystem {
+			return role
+		}
+	}
+	Fail("chart doesn't have a Role in kube-system")
+	return nil
+}
+
+// allowed returns whether one of the Role's rules grants the verb on the aws-auth ConfigMap. Rules with resourceNames
+// don't apply to create, since the name of an object isn't known when it's authorized.
+func allowed(role *rbacv1.Role, verb string) bool {
+	return lo.ContainsBy(role.Rules, func(r rbacv1.PolicyRule) bool {
+		return lo.Contains(r.APIGroups, "") && lo.Contains(r.Resources, "configmaps") && lo.Contains(r.Verbs, verb) &&
+			(len(r.ResourceNames) == 0 || (verb != "create" && lo.Contains(r.ResourceNames, accessentry.AWSAuthConfigMapName)))
+	})
+}
+
+var _ = Describe("AccessEntryProvider", func() {
+	var kubernetesInterface *kubefake.Clientset
+	var provider *accessentry.DefaultProvider
+	BeforeEach(func() {
+		ctx = options.ToContext(ctx, test.Options())
+		eksapi := fake.NewEKSAPI()
+		eksapi.DescribeClusterBehavior.Output.Set(&eks.DescribeClusterOutput{
+			Cluster: &ekstypes.Cluster{AccessConfig: &ekstypes.AccessConfigResponse{AuthenticationMode: ekstypes.AuthenticationModeConfigMap}},
+		})
+		kubernetesInterface = kubefake.NewSimpleClientset()
+		provider = accessentry.NewDefaultProvider(eksapi, fake.NewIAMAPI(), kubernetesInterface, cache.New(cache.NoExpiration, cache.NoExpiration))
+	})
+	It("should only make aws-auth ConfigMap calls that the chart's Role grants", func() {
+		Expect(provider.Ensure(ctx, fake.RoleARN("test-role"), accessentry.AccessEntryTypeLinux)).To(Succeed())
+		Expect(provider.Delete(ctx, fake.RoleARN("test-role"))).To(Succeed())
+
+		verbs := lo.Uniq(lo.FilterMap(kubernetesInterface.Actions(), func(a k8stesting.Action, _ int) (string, bool) {
+			return a.GetVerb(), a.GetResource().Resource == "configmaps"
+		}))
+		Expect(verbs).To(ContainElements("get", "create", "update"))
+		role := chartRole()
+		for _, verb := range verbs {
+			Expect(allowed(role, verb)).To(BeTrue(), "the chart's Role doesn't grant %q on the aws-auth ConfigMap", verb)
+		}
+	})
+})
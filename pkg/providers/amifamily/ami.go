@@ -30,6 +30,7 @@ import (
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
@@ -144,7 +145,10 @@ func (p *DefaultProvider) DescribeImageQueries(ctx context.Context, nodeClass *v
 
 //nolint:gocyclo
 func (p *DefaultProvider) amis(ctx context.Context, queries []DescribeImageQuery) (AMIs, error) {
-	hash, err := hashstructure.Hash(queries, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
+	// AMIs outside of the kubelet version skew are filtered before the newest AMI for each set of requirements is chosen,
+	// so the skew is part of the cache key
+	skew := p.versionSkew(ctx)
+	hash, err := hashstructure.Hash([]any{queries, skew}, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	if err != nil {
 		return nil, err
 	}
@@ -166,6 +170,11 @@ func (p *DefaultProvider) amis(ctx context.Context, queries []DescribeImageQuery
 				if !ok {
 					continue
 				}
+				if !skew.allows(lo.FromPtr(image.Name)) {
+					log.FromContext(ctx).WithValues("id", lo.FromPtr(image.ImageId), "name", lo.FromPtr(image.Name), "kubernetes-version", skew.KubernetesVersion).
+						V(1).Info("ignoring ami outside of the kubelet version skew")
+					continue
+				}
 				// Each image may have multiple associated sets of requirements. For example, an image may be compatible with Neuron instances
 				// and GPU instances. In that case, we'll have a set of requirements for each, and will create one "image" for each.
 				for _, reqs := range query.RequirementsForImageWithArchitecture(lo.FromPtr(image.ImageId), arch) {
@@ -196,6 +205,28 @@ func (p *DefaultProvider) amis(ctx context.Context, queries []DescribeImageQuery
 	return lo.Values(images), nil
 }
 
+// versionSkew is the kubelet version skew that AMIs are resolved within. A nil versionSkew allows every AMI.
+type versionSkew struct {
+	KubernetesVersion string
+	MaxSkew           int
+}
+
+func (p *DefaultProvider) versionSkew(ctx context.Context) *versionSkew {
+	if !options.FromContext(ctx).AWSFeatureGates.Enabled(options.KubeletVersionSkew) {
+		return nil
+	}
+	return &versionSkew{KubernetesVersion: p.versionProvider.Get(ctx), MaxSkew: options.FromContext(ctx).MaxKubeletVersionSkew}
+}
+
+// allows returns true if the kubelet version in the AMI's name is within the skew, or if the name doesn't contain a version
+func (s *versionSkew) allows(name string) bool {
+	if s == nil {
+		return true
+	}
+	kubeletVersion, ok := version.AMIKubeletVersion(name)
+	return !ok || version.WithinSkew(kubeletVersion, s.KubernetesVersion, s.MaxSkew)
+}
+
 // MapToInstanceTypes returns a map of AMIIDs that are the most recent on creationDate to compatible instancetypes
 func MapToInstanceTypes(instanceTypes []*cloudprovider.InstanceType, amis []v1.AMI) map[string][]*cloudprovider.InstanceType {
 	amiIDs := map[string][]*cloudprovider.InstanceType{}
@@ -15,6 +15,7 @@ limitations under the License.
 package bootstrap
 
 import (
+	"bytes"
 	"fmt"
 	"sort"
 	"strings"
@@ -38,6 +39,7 @@ type Options struct {
 	ContainerRuntime    *string
 	CustomUserData      *string
 	InstanceStorePolicy *v1.InstanceStorePolicy
+	Proxy               *v1.Proxy
 }
 
 func (o Options) kubeletExtraArgs() (args []string) {
@@ -95,6 +97,38 @@ func (o Options) nodeLabelArg() string {
 	return fmt.Sprintf("--node-labels=%q", strings.Join(labelStrings, ","))
 }
 
+// noProxy returns the destinations that are connected to directly rather than through the proxy
+func (o Options) noProxy() string {
+	noProxy := []string{"localhost", "127.0.0.1", "169.254.169.254"}
+	if cidr := lo.FromPtr(o.ClusterCIDR); cidr != "" {
+		noProxy = append(noProxy, cidr)
+	}
+	return strings.Join(lo.Uniq(append(noProxy, o.Proxy.NoProxy...)), ",")
+}
+
+// linuxProxyScript returns the shell commands which add the proxy's certificate authorities to the system trust store
+// and configure containerd and the kubelet to connect through the proxy
+func (o Options) linuxProxyScript() string {
+	if o.Proxy == nil {
+		return ""
+	}
+	var script bytes.Buffer
+	if caBundle := lo.FromPtr(o.Proxy.CABundle); caBundle != "" {
+		script.WriteString(fmt.Sprintf("echo '%s' | base64 -d > /etc/pki/ca-trust/source/anchors/karpenter-proxy-ca.pem\n", caBundle))
+		script.WriteString("update-ca-trust extract\n")
+	}
+	if httpsProxy := lo.FromPtr(o.Proxy.HTTPSProxy); httpsProxy != "" {
+		for _, unit := range []string{"containerd", "kubelet"} {
+			script.WriteString(fmt.Sprintf("mkdir -p /etc/systemd/system/%s.service.d\n", unit))
+			script.WriteString(fmt.Sprintf("cat <<EOF > /etc/systemd/system/%s.service.d/http-proxy.conf\n[Service]\nEnvironment=\"HTTPS_PROXY=%s\"\nEnvironment=\"NO_PROXY=%s\"\nEOF\n",
+				unit, httpsProxy, o.noProxy()))
+		}
+		script.WriteString("systemctl daemon-reload\n")
+		script.WriteString("systemctl try-restart containerd\n")
+	}
+	return script.String()
+}
+
 // joinParameterArgs joins a map of keys and values by their separator. The separator will sit between the
 // arguments in a comma-separated list i.e. arg1<sep>val1,arg2<sep>val2
 func joinParameterArgs[K comparable, V any](name string, m map[K]V, separator string) string {
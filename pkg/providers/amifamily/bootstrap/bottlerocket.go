@@ -18,13 +18,12 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/imdario/mergo"
 	"github.com/samber/lo"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-
-	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 )
 
 type Bottlerocket struct {
@@ -79,7 +78,7 @@ func (b Bottlerocket) Script() (string, error) {
 		s.Settings.Kubernetes.NodeTaints[taint.Key] = append(s.Settings.Kubernetes.NodeTaints[taint.Key], fmt.Sprintf("%s:%s", taint.Value, taint.Effect))
 	}
 
-	if lo.FromPtr(b.InstanceStorePolicy) == v1.InstanceStorePolicyRAID0 {
+	if lo.FromPtr(b.InstanceStorePolicy).UsesRAID0() {
 		if s.Settings.BootstrapCommands == nil {
 			s.Settings.BootstrapCommands = map[string]BootstrapCommand{}
 		}
@@ -89,9 +88,33 @@ func (b Bottlerocket) Script() (string, error) {
 			Mode:      BootstrapCommandModeAlways,
 		}
 	}
+	if b.Proxy != nil {
+		b.configureProxy(s)
+	}
 	script, err := s.MarshalTOML()
 	if err != nil {
 		return "", fmt.Errorf("constructing toml UserData %w", err)
 	}
 	return base64.StdEncoding.EncodeToString(script), nil
 }
+
+// configureProxy merges the proxy and its certificate authorities into the network and pki settings. These settings aren't
+// modeled by BottlerocketSettings, so they're merged into the raw settings to preserve any others set in the custom UserData.
+func (b Bottlerocket) configureProxy(s *BottlerocketConfig) {
+	if s.SettingsRaw == nil {
+		s.SettingsRaw = map[string]interface{}{}
+	}
+	if httpsProxy := lo.FromPtr(b.Proxy.HTTPSProxy); httpsProxy != "" {
+		network, _ := s.SettingsRaw["network"].(map[string]interface{})
+		s.SettingsRaw["network"] = lo.Assign(network, map[string]interface{}{
+			"https-proxy": httpsProxy,
+			"no-proxy":    strings.Split(b.noProxy(), ","),
+		})
+	}
+	if caBundle := lo.FromPtr(b.Proxy.CABundle); caBundle != "" {
+		pki, _ := s.SettingsRaw["pki"].(map[string]interface{})
+		s.SettingsRaw["pki"] = lo.Assign(pki, map[string]interface{}{
+			"karpenter-proxy-ca": map[string]interface{}{"data": caBundle, "trusted": true},
+		})
+	}
+}
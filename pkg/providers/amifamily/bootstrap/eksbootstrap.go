@@ -28,8 +28,6 @@ import (
 	"strings"
 
 	"github.com/samber/lo"
-
-	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 )
 
 type EKS struct {
@@ -62,6 +60,7 @@ func (e EKS) eksBootstrapScript() string {
 	var userData bytes.Buffer
 	userData.WriteString("#!/bin/bash -xe\n")
 	userData.WriteString("exec > >(tee /var/log/user-data.log|logger -t user-data -s 2>/dev/console) 2>&1\n")
+	userData.WriteString(e.linuxProxyScript())
 	// Due to the way bootstrap.sh is written, parameters should not be passed to it with an equal sign
 	userData.WriteString(fmt.Sprintf("/etc/eks/bootstrap.sh '%s' --apiserver-endpoint '%s' %s", e.ClusterName, e.ClusterEndpoint, caBundleArg))
 
@@ -77,7 +76,7 @@ func (e EKS) eksBootstrapScript() string {
 	if args := e.kubeletExtraArgs(); len(args) > 0 {
 		userData.WriteString(fmt.Sprintf(" \\\n--kubelet-extra-args '%s'", strings.Join(args, " ")))
 	}
-	if lo.FromPtr(e.InstanceStorePolicy) == v1.InstanceStorePolicyRAID0 {
+	if lo.FromPtr(e.InstanceStorePolicy).UsesRAID0() {
 		userData.WriteString(" \\\n--local-disks raid0")
 	}
 	return userData.String()
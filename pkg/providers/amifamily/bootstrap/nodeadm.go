@@ -28,7 +28,6 @@ import (
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/yaml"
 
-	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily/bootstrap/mime"
 )
 
@@ -45,6 +44,12 @@ func (n Nodeadm) Script() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("parsing custom UserData, %w", err)
 	}
+	if proxyScript := n.linuxProxyScript(); proxyScript != "" {
+		customEntries = append([]mime.Entry{{
+			ContentType: mime.ContentTypeShellScript,
+			Content:     "#!/bin/bash -xe\n" + proxyScript,
+		}}, customEntries...)
+	}
 	mimeArchive := mime.Archive(append(customEntries, mime.Entry{
 		ContentType: mime.ContentTypeNodeConfig,
 		Content:     nodeConfigYAML,
@@ -83,7 +88,7 @@ func (n Nodeadm) getNodeConfigYAML() (string, error) {
 	} else {
 		return "", cloudprovider.NewNodeClassNotReadyError(fmt.Errorf("resolving cluster CIDR"))
 	}
-	if lo.FromPtr(n.InstanceStorePolicy) == v1.InstanceStorePolicyRAID0 {
+	if lo.FromPtr(n.InstanceStorePolicy).UsesRAID0() {
 		config.Spec.Instance.LocalStorage.Strategy = admv1alpha1.LocalStorageRAID0
 	}
 	inlineConfig, err := n.generateInlineKubeletConfiguration()
@@ -37,6 +37,7 @@ func (w Windows) Script() (string, error) {
 		userData.WriteString(customUserData + "\n")
 	}
 
+	userData.WriteString(w.proxyScript())
 	userData.WriteString("[string]$EKSBootstrapScriptFile = \"$env:ProgramFiles\\Amazon\\EKS\\Start-EKSBootstrap.ps1\"\n")
 	userData.WriteString(fmt.Sprintf(`& $EKSBootstrapScriptFile -EKSClusterName '%s' -APIServerEndpoint '%s'`, w.ClusterName, w.ClusterEndpoint))
 	if w.CABundle != nil {
@@ -51,3 +52,23 @@ func (w Windows) Script() (string, error) {
 	userData.WriteString("\n</powershell>")
 	return base64.StdEncoding.EncodeToString(userData.Bytes()), nil
 }
+
+// proxyScript returns the PowerShell commands which import the proxy's certificate authorities into the machine's trusted
+// root store and set the proxy for the machine, which is inherited by containerd and the kubelet
+func (w Windows) proxyScript() string {
+	if w.Proxy == nil {
+		return ""
+	}
+	var script bytes.Buffer
+	if caBundle := lo.FromPtr(w.Proxy.CABundle); caBundle != "" {
+		script.WriteString(fmt.Sprintf("[IO.File]::WriteAllBytes(\"$env:TEMP\\karpenter-proxy-ca.pem\", [Convert]::FromBase64String('%s'))\n", caBundle))
+		script.WriteString("Import-Certificate -FilePath \"$env:TEMP\\karpenter-proxy-ca.pem\" -CertStoreLocation Cert:\\LocalMachine\\Root\n")
+	}
+	if httpsProxy := lo.FromPtr(w.Proxy.HTTPSProxy); httpsProxy != "" {
+		script.WriteString(fmt.Sprintf("[Environment]::SetEnvironmentVariable('HTTPS_PROXY', '%s', 'Machine')\n", httpsProxy))
+		script.WriteString(fmt.Sprintf("[Environment]::SetEnvironmentVariable('NO_PROXY', '%s', 'Machine')\n", w.noProxy()))
+		script.WriteString("$env:HTTPS_PROXY = [Environment]::GetEnvironmentVariable('HTTPS_PROXY', 'Machine')\n")
+		script.WriteString("$env:NO_PROXY = [Environment]::GetEnvironmentVariable('NO_PROXY', 'Machine')\n")
+	}
+	return script.String()
+}
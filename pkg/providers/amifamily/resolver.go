@@ -17,6 +17,7 @@ package amifamily
 import (
 	"context"
 	"fmt"
+	"math"
 	"net"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -56,6 +57,7 @@ type Options struct {
 	InstanceProfile     string
 	CABundle            *string `hash:"ignore"`
 	InstanceStorePolicy *v1.InstanceStorePolicy
+	Proxy               *v1.Proxy
 	// Level-triggered fields that may change out of sync.
 	SecurityGroups           []v1.SecurityGroup
 	Tags                     map[string]string
@@ -68,20 +70,28 @@ type Options struct {
 // LaunchTemplate holds the dynamically generated launch template parameters
 type LaunchTemplate struct {
 	*Options
-	UserData            bootstrap.Bootstrapper
-	BlockDeviceMappings []*v1.BlockDeviceMapping
-	MetadataOptions     *v1.MetadataOptions
-	AMIID               string
-	InstanceTypes       []*cloudprovider.InstanceType `hash:"ignore"`
-	DetailedMonitoring  bool
-	EFACount            int
-	CapacityType        string
+	UserData              bootstrap.Bootstrapper
+	BlockDeviceMappings   []*v1.BlockDeviceMapping
+	MetadataOptions       *v1.MetadataOptions
+	AMIID                 string
+	InstanceTypes         []*cloudprovider.InstanceType `hash:"ignore"`
+	DetailedMonitoring    bool
+	EnclavesEnabled       bool
+	CPUOptions            *v1.CPUOptions
+	CPUCredits            string
+	PrivateDNSNameOptions *v1.PrivateDNSNameOptions
+	LicenseARNs           []string
+	KeyName               string
+	EFACount              int
+	CapacityType          string
+	// CapacityReservationID is the Capacity Block that instances are launched into, if any
+	CapacityReservationID string
 }
 
 // AMIFamily can be implemented to override the default logic for generating dynamic launch template parameters
 type AMIFamily interface {
 	DescribeImageQuery(ctx context.Context, ssmProvider ssm.Provider, k8sVersion string, amiVersion string) (DescribeImageQuery, error)
-	UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, caBundle *string, instanceTypes []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1.InstanceStorePolicy) bootstrap.Bootstrapper
+	UserData(kubeletConfig *v1.KubeletConfiguration, taints []corev1.Taint, labels map[string]string, caBundle *string, instanceTypes []*cloudprovider.InstanceType, customUserData *string, instanceStorePolicy *v1.InstanceStorePolicy, proxy *v1.Proxy) bootstrap.Bootstrapper
 	DefaultBlockDeviceMappings() []*v1.BlockDeviceMapping
 	DefaultMetadataOptions() *v1.MetadataOptions
 	EphemeralBlockDevice() *string
@@ -136,9 +146,13 @@ func (r DefaultResolver) Resolve(nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.N
 		// This requires that we resolve a unique launch template per max-pods value.
 		// Similarly, instance types configured with EfAs require unique launch templates depending on the number of
 		// EFAs they support.
+		// Reservations which are computed from the size of the instance type also need to be passed down to the kubelet, and
+		// so require unique launch templates as well.
 		type launchTemplateParams struct {
-			efaCount int
-			maxPods  int
+			efaCount       int
+			maxPods        int
+			reserved       string
+			rootVolumeSize string
 		}
 		paramsToInstanceTypes := lo.GroupBy(instanceTypes, func(instanceType *cloudprovider.InstanceType) launchTemplateParams {
 			return launchTemplateParams{
@@ -147,17 +161,69 @@ func (r DefaultResolver) Resolve(nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.N
 					int(lo.ToPtr(instanceType.Capacity[v1.ResourceEFA]).Value()),
 					0,
 				),
-				maxPods: int(instanceType.Capacity.Pods().Value()),
+				maxPods:        int(instanceType.Capacity.Pods().Value()),
+				reserved:       lo.Ternary(autoSystemReserved(nodeClass), fmt.Sprint(reservedResources(instanceType)), ""),
+				rootVolumeSize: fmt.Sprint(RootVolumeSize(nodeClass, nodeClaim, instanceType)),
 			}
 		})
 		for params, instanceTypes := range paramsToInstanceTypes {
 			resolved := r.resolveLaunchTemplate(nodeClass, nodeClaim, instanceTypes, capacityType, amiFamily, amiID, params.maxPods, params.efaCount, options)
+			// The instance types share the same root volume size since they were grouped by it
+			if size := RootVolumeSize(nodeClass, nodeClaim, instanceTypes[0]); size != nil {
+				resolved.BlockDeviceMappings = withRootVolumeSize(resolved.BlockDeviceMappings, size)
+			}
 			resolvedTemplates = append(resolvedTemplates, resolved)
 		}
 	}
 	return resolvedTemplates, nil
 }
 
+func autoSystemReserved(nodeClass *v1.EC2NodeClass) bool {
+	return nodeClass.Spec.Kubelet != nil && nodeClass.Spec.Kubelet.AutoSystemReserved != nil
+}
+
+// reservedResources returns the kubeReserved and systemReserved that Karpenter computed for the instance type, formatted as
+// kubelet arguments
+func reservedResources(instanceType *cloudprovider.InstanceType) (map[string]string, map[string]string) {
+	format := func(resources corev1.ResourceList) map[string]string {
+		return lo.MapEntries(resources, func(k corev1.ResourceName, v resource.Quantity) (string, string) { return string(k), v.String() })
+	}
+	return format(instanceType.Overhead.KubeReserved), format(instanceType.Overhead.SystemReserved)
+}
+
+// RootVolumeSize returns the size that an auto-sized root volume is launched with for the instance type, or nil if the root
+// volume isn't auto-sized. The volume is sized to fit the ephemeral-storage requests of the NodeClaim and the ephemeral-storage
+// overhead of the instance type, rounded up to the nearest Gi and bounded by the volumeSize and maxVolumeSize of the volume.
+func RootVolumeSize(nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.NodeClaim, instanceType *cloudprovider.InstanceType) *resource.Quantity {
+	blockDeviceMapping, ok := lo.Find(nodeClass.Spec.BlockDeviceMappings, func(bdm *v1.BlockDeviceMapping) bool {
+		return bdm.RootVolume && bdm.EBS != nil && bdm.EBS.VolumeSize != nil && bdm.EBS.MaxVolumeSize != nil
+	})
+	if !ok {
+		return nil
+	}
+	// The overhead is computed from the maxVolumeSize that the instance type advertises, so it's never less than the
+	// overhead of the chosen size
+	size := nodeClaim.Spec.Resources.Requests.StorageEphemeral().DeepCopy()
+	size.Add(lo.ToPtr(instanceType.Overhead.Total()[corev1.ResourceEphemeralStorage]).DeepCopy())
+	gi := math.Ceil(size.AsApproximateFloat64() / math.Pow(2, 30))
+	gi = math.Max(gi, math.Ceil(blockDeviceMapping.EBS.VolumeSize.AsApproximateFloat64()/math.Pow(2, 30)))
+	gi = math.Min(gi, math.Floor(blockDeviceMapping.EBS.MaxVolumeSize.AsApproximateFloat64()/math.Pow(2, 30)))
+	return resource.NewQuantity(int64(gi)*int64(math.Pow(2, 30)), resource.BinarySI)
+}
+
+// withRootVolumeSize returns a copy of the block device mappings with the size of the root volume set
+func withRootVolumeSize(blockDeviceMappings []*v1.BlockDeviceMapping, size *resource.Quantity) []*v1.BlockDeviceMapping {
+	return lo.Map(blockDeviceMappings, func(bdm *v1.BlockDeviceMapping, _ int) *v1.BlockDeviceMapping {
+		if !bdm.RootVolume || bdm.EBS == nil {
+			return bdm
+		}
+		bdm = bdm.DeepCopy()
+		bdm.EBS.VolumeSize = size
+		bdm.EBS.MaxVolumeSize = nil
+		return bdm
+	})
+}
+
 func GetAMIFamily(amiFamily string, options *Options) AMIFamily {
 	switch amiFamily {
 	case v1.AMIFamilyBottlerocket:
@@ -207,6 +273,11 @@ func (r DefaultResolver) resolveLaunchTemplate(nodeClass *v1.EC2NodeClass, nodeC
 	if nodeClass.Spec.Kubelet != nil {
 		kubeletConfig = nodeClass.Spec.Kubelet.DeepCopy()
 	}
+	if kubeletConfig.AutoSystemReserved != nil {
+		// The instance types share the same reservations since they were grouped by them
+		kubeletConfig.KubeReserved, kubeletConfig.SystemReserved = reservedResources(instanceTypes[0])
+		kubeletConfig.AutoSystemReserved = nil
+	}
 	if kubeletConfig.MaxPods == nil {
 		// nolint:gosec
 		// We know that it's not possible to have values that would overflow int32 here since we control
@@ -233,14 +304,22 @@ func (r DefaultResolver) resolveLaunchTemplate(nodeClass *v1.EC2NodeClass, nodeC
 			instanceTypes,
 			nodeClass.Spec.UserData,
 			options.InstanceStorePolicy,
+			options.Proxy,
 		),
-		BlockDeviceMappings: nodeClass.Spec.BlockDeviceMappings,
-		MetadataOptions:     nodeClass.Spec.MetadataOptions,
-		DetailedMonitoring:  aws.ToBool(nodeClass.Spec.DetailedMonitoring),
-		AMIID:               amiID,
-		InstanceTypes:       instanceTypes,
-		EFACount:            efaCount,
-		CapacityType:        capacityType,
+		BlockDeviceMappings:   nodeClass.Spec.BlockDeviceMappings,
+		MetadataOptions:       nodeClass.Spec.MetadataOptions,
+		DetailedMonitoring:    aws.ToBool(nodeClass.Spec.DetailedMonitoring),
+		EnclavesEnabled:       nodeClass.EnclavesEnabled(),
+		CPUOptions:            nodeClass.Spec.CPUOptions,
+		CPUCredits:            lo.FromPtr(lo.FromPtr(nodeClass.Spec.CreditSpecification).CPUCredits),
+		PrivateDNSNameOptions: nodeClass.Spec.PrivateDNSNameOptions,
+		LicenseARNs:           lo.FromPtr(nodeClass.Spec.Licensing).LicenseConfigurationARNs,
+		KeyName:               lo.FromPtr(nodeClass.Spec.KeyName),
+		AMIID:                 amiID,
+		InstanceTypes:         instanceTypes,
+		EFACount:              efaCount,
+		CapacityType:          capacityType,
+		CapacityReservationID: lo.FromPtr(nodeClass.Status.CapacityBlock).ID,
 	}
 	if len(resolved.BlockDeviceMappings) == 0 {
 		resolved.BlockDeviceMappings = amiFamily.DefaultBlockDeviceMappings()
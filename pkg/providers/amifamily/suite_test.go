@@ -34,6 +34,7 @@ import (
 
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 	coreoptions "sigs.k8s.io/karpenter/pkg/operator/options"
@@ -480,6 +481,54 @@ var _ = Describe("AMIProvider", func() {
 			}))
 		})
 	})
+	Context("Kubelet Version Skew", func() {
+		var names map[int]string
+		BeforeEach(func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+			minor := k8sversion.MustParseGeneric(version).Minor()
+			names = map[int]string{}
+			var images []ec2types.Image
+			// Images one minor version newer than the control plane, and up to four minor versions older than it
+			for skew := -1; skew <= 4; skew++ {
+				names[skew] = fmt.Sprintf("amazon-eks-node-al2023-x86_64-standard-1.%d-v20240807", int(minor)-skew)
+				images = append(images, ec2types.Image{
+					Name:         aws.String(names[skew]),
+					ImageId:      aws.String(fmt.Sprintf("ami-%d", skew+1)),
+					CreationDate: aws.String(fmt.Sprintf("2024-08-%02dT00:00:00.000Z", 10-skew)),
+					Architecture: "x86_64",
+				})
+			}
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: images})
+		})
+		It("should resolve the newest AMI regardless of its kubelet version when the feature gate is disabled", func() {
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].Name).To(Equal(names[-1]))
+		})
+		It("should ignore AMIs newer than the control plane", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AWSFeatureGates: options.FeatureGates{options.KubeletVersionSkew: true}}))
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].Name).To(Equal(names[0]))
+		})
+		It("should ignore AMIs older than the max kubelet version skew", func() {
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AWSFeatureGates: options.FeatureGates{options.KubeletVersionSkew: true}}))
+			awsEnv.EC2API.DescribeImagesOutput.Set(&ec2.DescribeImagesOutput{Images: lo.Filter(awsEnv.EC2API.DescribeImagesOutput.Clone().Images, func(image ec2types.Image, _ int) bool {
+				return lo.FromPtr(image.Name) == names[2] || lo.FromPtr(image.Name) == names[4]
+			})})
+			amis, err := awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(HaveLen(1))
+			Expect(amis[0].Name).To(Equal(names[2]))
+
+			ctx = options.ToContext(ctx, test.Options(test.OptionsFields{AWSFeatureGates: options.FeatureGates{options.KubeletVersionSkew: true}, MaxKubeletVersionSkew: lo.ToPtr(1)}))
+			amis, err = awsEnv.AMIProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(amis).To(BeEmpty())
+		})
+	})
 	Context("AMI Selectors", func() {
 		// When you tag public or shared resources, the tags you assign are available only to your AWS account; no other AWS account will have access to those tags
 		// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/Using_Tags.html#tag-restrictions
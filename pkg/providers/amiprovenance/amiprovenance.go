@@ -0,0 +1,131 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amiprovenance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+type Provider interface {
+	// Verify returns true if the AMI is listed in the trusted AMIs parameter, or all of its EBS snapshots are encrypted
+	// with the trusted KMS key. If the AMI isn't trusted, the returned message describes why.
+	Verify(context.Context, string) (bool, string, error)
+}
+
+type result struct {
+	verified bool
+	message  string
+}
+
+type DefaultProvider struct {
+	ec2api sdk.EC2API
+	ssmapi sdk.SSMAPI
+	cache  *cache.Cache
+}
+
+func NewDefaultProvider(ec2api sdk.EC2API, ssmapi sdk.SSMAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api: ec2api,
+		ssmapi: ssmapi,
+		cache:  cache,
+	}
+}
+
+func (p *DefaultProvider) Verify(ctx context.Context, imageID string) (bool, string, error) {
+	if r, ok := p.cache.Get(imageID); ok {
+		return r.(result).verified, r.(result).message, nil
+	}
+	var messages []string
+	if parameter := options.FromContext(ctx).TrustedAMIsParameter; parameter != "" {
+		trusted, err := p.trustedAMIs(ctx, parameter)
+		if err != nil {
+			return false, "", err
+		}
+		if lo.Contains(trusted, imageID) {
+			p.cache.SetDefault(imageID, result{verified: true})
+			return true, "", nil
+		}
+		messages = append(messages, fmt.Sprintf("isn't listed in the trusted AMIs parameter %q", parameter))
+	}
+	if keyARN := options.FromContext(ctx).TrustedAMIKMSKeyARN; keyARN != "" {
+		signed, message, err := p.signedWith(ctx, imageID, keyARN)
+		if err != nil {
+			return false, "", err
+		}
+		if signed {
+			p.cache.SetDefault(imageID, result{verified: true})
+			return true, "", nil
+		}
+		messages = append(messages, message)
+	}
+	r := result{message: fmt.Sprintf("AMI %s %s", imageID, strings.Join(messages, " and "))}
+	p.cache.SetDefault(imageID, r)
+	return false, r.message, nil
+}
+
+func (p *DefaultProvider) trustedAMIs(ctx context.Context, parameter string) ([]string, error) {
+	out, err := p.ssmapi.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(parameter), WithDecryption: aws.Bool(true)})
+	if err != nil {
+		return nil, fmt.Errorf("getting trusted amis parameter %q, %w", parameter, err)
+	}
+	if out.Parameter == nil {
+		return nil, nil
+	}
+	return lo.FilterMap(strings.Split(aws.ToString(out.Parameter.Value), ","), func(id string, _ int) (string, bool) {
+		return strings.TrimSpace(id), strings.TrimSpace(id) != ""
+	}), nil
+}
+
+// signedWith checks that the AMI is backed by at least one EBS snapshot, and that all of its snapshots are encrypted with
+// the passed key. Only the owner of a key can encrypt snapshots with it, so this proves that the owner produced the AMI.
+func (p *DefaultProvider) signedWith(ctx context.Context, imageID, keyARN string) (bool, string, error) {
+	images, err := p.ec2api.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		Filters: []ec2types.Filter{{Name: aws.String("image-id"), Values: []string{imageID}}},
+	})
+	if err != nil {
+		return false, "", fmt.Errorf("describing image %s, %w", imageID, err)
+	}
+	if len(images.Images) == 0 {
+		return false, "couldn't be found", nil
+	}
+	snapshotIDs := lo.FilterMap(images.Images[0].BlockDeviceMappings, func(m ec2types.BlockDeviceMapping, _ int) (string, bool) {
+		return aws.ToString(lo.FromPtr(m.Ebs).SnapshotId), m.Ebs != nil && m.Ebs.SnapshotId != nil
+	})
+	if len(snapshotIDs) == 0 {
+		return false, "isn't backed by EBS snapshots", nil
+	}
+	snapshots, err := p.ec2api.DescribeSnapshots(ctx, &ec2.DescribeSnapshotsInput{SnapshotIds: snapshotIDs})
+	if err != nil {
+		return false, "", fmt.Errorf("describing snapshots of image %s, %w", imageID, err)
+	}
+	if untrusted, ok := lo.Find(snapshots.Snapshots, func(s ec2types.Snapshot) bool {
+		return !aws.ToBool(s.Encrypted) || aws.ToString(s.KmsKeyId) != keyARN
+	}); ok {
+		return false, fmt.Sprintf("has snapshot %s which isn't encrypted with the trusted KMS key %q", aws.ToString(untrusted.SnapshotId), keyARN), nil
+	}
+	return true, "", nil
+}
@@ -0,0 +1,69 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacityreservation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+)
+
+// CapacityBlockReclaimLead is how long before a Capacity Block's end time that EC2 begins terminating the instances
+// running in it
+const CapacityBlockReclaimLead = 30 * time.Minute
+
+type Provider interface {
+	Get(context.Context, string) (*ec2types.CapacityReservation, error)
+}
+
+// DefaultProvider resolves capacity reservations by id. Reservations aren't cached since their state changes as they become
+// active and expire.
+type DefaultProvider struct {
+	ec2api sdk.EC2API
+}
+
+func NewDefaultProvider(ec2api sdk.EC2API) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api: ec2api,
+	}
+}
+
+// Get returns the capacity reservation with the passed id, or nil if it doesn't exist
+func (p *DefaultProvider) Get(ctx context.Context, id string) (*ec2types.CapacityReservation, error) {
+	out, err := p.ec2api.DescribeCapacityReservations(ctx, &ec2.DescribeCapacityReservationsInput{
+		CapacityReservationIds: []string{id},
+	})
+	if err != nil {
+		if awserrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("describing capacity reservation %s, %w", id, err)
+	}
+	if len(out.CapacityReservations) == 0 {
+		return nil, nil
+	}
+	return &out.CapacityReservations[0], nil
+}
+
+// ReclaimTime returns the time at which EC2 begins terminating the instances running in a Capacity Block that ends at end
+func ReclaimTime(end time.Time) time.Time {
+	return end.Add(-CapacityBlockReclaimLead)
+}
@@ -0,0 +1,107 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package carbonintensity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+type Provider interface {
+	// Intensity returns the grid carbon intensity of the zone in gCO2eq/kWh, falling back to the intensity of the region if
+	// the zone isn't listed. The second return value is false if neither is known.
+	Intensity(zone string) (float64, bool)
+	UpdateIntensities(context.Context) error
+}
+
+// DefaultProvider reads grid carbon intensities from the SSM parameter configured by the carbon-intensity-parameter option.
+// The parameter holds a JSON object mapping region and zone names to their carbon intensity, e.g. {"us-west-2": 250,
+// "us-west-2a": 240}, so that it can be kept up to date from whichever published data source an operator prefers.
+type DefaultProvider struct {
+	ssmapi sdk.SSMAPI
+	region string
+	cm     *pretty.ChangeMonitor
+
+	mu          sync.RWMutex
+	intensities map[string]float64
+}
+
+func NewDefaultProvider(ssmapi sdk.SSMAPI, region string) *DefaultProvider {
+	return &DefaultProvider{
+		ssmapi:      ssmapi,
+		region:      region,
+		cm:          pretty.NewChangeMonitor(),
+		intensities: map[string]float64{},
+	}
+}
+
+func (p *DefaultProvider) Intensity(zone string) (float64, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if intensity, ok := p.intensities[zone]; ok {
+		return intensity, true
+	}
+	intensity, ok := p.intensities[p.region]
+	return intensity, ok
+}
+
+func (p *DefaultProvider) UpdateIntensities(ctx context.Context) error {
+	parameter := options.FromContext(ctx).CarbonIntensityParameter
+	if parameter == "" {
+		return nil
+	}
+	out, err := p.ssmapi.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String(parameter), WithDecryption: aws.Bool(true)})
+	if err != nil {
+		return fmt.Errorf("getting carbon intensity parameter %q, %w", parameter, err)
+	}
+	intensities := map[string]float64{}
+	if out.Parameter != nil {
+		if err = json.Unmarshal([]byte(aws.ToString(out.Parameter.Value)), &intensities); err != nil {
+			return fmt.Errorf("parsing carbon intensity parameter %q, %w", parameter, err)
+		}
+	}
+	for name, intensity := range intensities {
+		if intensity < 0 || math.IsNaN(intensity) || math.IsInf(intensity, 0) {
+			return fmt.Errorf("parsing carbon intensity parameter %q, invalid carbon intensity %v for %q", parameter, intensity, name)
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.intensities = intensities
+	for name, intensity := range intensities {
+		CarbonIntensity.Set(intensity, map[string]string{locationLabel: name})
+	}
+	if p.cm.HasChanged("carbon-intensities", intensities) {
+		log.FromContext(ctx).WithValues("locations", len(intensities)).V(1).Info("updated carbon intensities")
+	}
+	return nil
+}
+
+func (p *DefaultProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.intensities = map[string]float64{}
+}
@@ -0,0 +1,225 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnostics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/samber/lo"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+)
+
+type Status string
+
+const (
+	StatusPassed  Status = "Passed"
+	StatusWarning Status = "Warning"
+	StatusFailed  Status = "Failed"
+	StatusSkipped Status = "Skipped"
+)
+
+const (
+	CheckCreateFleet           = "ec2-create-fleet"
+	CheckDescribeInstanceTypes = "ec2-describe-instance-types"
+	CheckClusterEndpoint       = "cluster-endpoint"
+	CheckInterruptionQueue     = "interruption-queue"
+
+	// interruptionQueueBacklogThreshold is the number of messages in the interruption queue above which we warn that
+	// interruption messages aren't being processed quickly enough
+	interruptionQueueBacklogThreshold = 1000
+	clusterEndpointDialTimeout        = 5 * time.Second
+)
+
+type Result struct {
+	Name    string
+	Status  Status
+	Message string
+}
+
+func (r Result) String() string {
+	if r.Message == "" {
+		return string(r.Status)
+	}
+	return fmt.Sprintf("%s: %s", r.Status, r.Message)
+}
+
+type Provider interface {
+	// Run executes every diagnostic check and returns the results, which are retained for Ready
+	Run(context.Context) []Result
+	// Ready returns an error naming the checks which failed during the last run. Checks that haven't run yet, or that
+	// only raised warnings, don't affect readiness.
+	Ready() error
+}
+
+// DefaultProvider checks that the controller has the IAM permissions and connectivity that it needs to launch nodes and
+// handle interruptions, so misconfigurations are surfaced at startup rather than on the first launch
+type DefaultProvider struct {
+	ec2api          sdk.EC2API
+	sqsapi          sdk.SQSAPI
+	clusterEndpoint string
+
+	mu      sync.RWMutex
+	results []Result
+}
+
+func NewDefaultProvider(ec2api sdk.EC2API, sqsapi sdk.SQSAPI, clusterEndpoint string) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api:          ec2api,
+		sqsapi:          sqsapi,
+		clusterEndpoint: clusterEndpoint,
+	}
+}
+
+func (p *DefaultProvider) Run(ctx context.Context) []Result {
+	results := []Result{
+		p.checkCreateFleet(ctx),
+		p.checkDescribeInstanceTypes(ctx),
+		p.checkClusterEndpoint(ctx),
+		p.checkInterruptionQueue(ctx),
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.results = results
+	return results
+}
+
+func (p *DefaultProvider) Ready() error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	failed := lo.FilterMap(p.results, func(r Result, _ int) (string, bool) { return r.Name, r.Status == StatusFailed })
+	if len(failed) > 0 {
+		return fmt.Errorf("diagnostic checks failed, %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// checkCreateFleet makes a dry-run CreateFleet call with the tags that Karpenter launches with. The launch template doesn't
+// exist, so EC2 may reject the request before evaluating permissions, in which case the result is only a warning.
+func (p *DefaultProvider) checkCreateFleet(ctx context.Context) Result {
+	clusterName := options.FromContext(ctx).ClusterName
+	tags := []ec2types.Tag{
+		{Key: aws.String(fmt.Sprintf("kubernetes.io/cluster/%s", clusterName)), Value: aws.String("owned")},
+		{Key: aws.String(karpv1.NodePoolLabelKey), Value: aws.String("diagnostics")},
+	}
+	_, err := p.ec2api.CreateFleet(ctx, &ec2.CreateFleetInput{
+		DryRun: aws.Bool(true),
+		Type:   ec2types.FleetTypeInstant,
+		LaunchTemplateConfigs: []ec2types.FleetLaunchTemplateConfigRequest{{
+			LaunchTemplateSpecification: &ec2types.FleetLaunchTemplateSpecificationRequest{
+				LaunchTemplateName: aws.String("karpenter-diagnostics"),
+				Version:            aws.String("$Latest"),
+			},
+		}},
+		TargetCapacitySpecification: &ec2types.TargetCapacitySpecificationRequest{
+			DefaultTargetCapacityType: ec2types.DefaultTargetCapacityTypeOnDemand,
+			TotalTargetCapacity:       aws.Int32(1),
+		},
+		TagSpecifications: []ec2types.TagSpecification{
+			{ResourceType: ec2types.ResourceTypeInstance, Tags: tags},
+			{ResourceType: ec2types.ResourceTypeVolume, Tags: tags},
+			{ResourceType: ec2types.ResourceTypeFleet, Tags: tags},
+		},
+	})
+	return dryRunResult(CheckCreateFleet, err)
+}
+
+func (p *DefaultProvider) checkDescribeInstanceTypes(ctx context.Context) Result {
+	_, err := p.ec2api.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{DryRun: aws.Bool(true)})
+	return dryRunResult(CheckDescribeInstanceTypes, err)
+}
+
+func dryRunResult(name string, err error) Result {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		if err == nil {
+			return Result{Name: name, Status: StatusPassed}
+		}
+		return Result{Name: name, Status: StatusFailed, Message: err.Error()}
+	}
+	switch apiErr.ErrorCode() {
+	case "DryRunOperation":
+		return Result{Name: name, Status: StatusPassed}
+	case "UnauthorizedOperation":
+		return Result{Name: name, Status: StatusFailed, Message: "the controller role isn't authorized to make this request"}
+	default:
+		return Result{Name: name, Status: StatusWarning, Message: fmt.Sprintf("unable to verify permissions, %s", apiErr.ErrorCode())}
+	}
+}
+
+// checkClusterEndpoint verifies that the cluster endpoint which nodes are bootstrapped with accepts connections. The
+// controller's network may differ from the nodes', so this doesn't guarantee that nodes can reach the endpoint.
+func (p *DefaultProvider) checkClusterEndpoint(ctx context.Context) Result {
+	endpoint, err := url.Parse(p.clusterEndpoint)
+	if err != nil || endpoint.Hostname() == "" {
+		return Result{Name: CheckClusterEndpoint, Status: StatusFailed, Message: fmt.Sprintf("%q is not a valid URL", p.clusterEndpoint)}
+	}
+	port := lo.Ternary(endpoint.Port() != "", endpoint.Port(), "443")
+	conn, err := (&net.Dialer{Timeout: clusterEndpointDialTimeout}).DialContext(ctx, "tcp", net.JoinHostPort(endpoint.Hostname(), port))
+	if err != nil {
+		return Result{Name: CheckClusterEndpoint, Status: StatusFailed, Message: fmt.Sprintf("connecting to %s, %s", endpoint.Host, err)}
+	}
+	_ = conn.Close()
+	return Result{Name: CheckClusterEndpoint, Status: StatusPassed}
+}
+
+func (p *DefaultProvider) checkInterruptionQueue(ctx context.Context) Result {
+	queueName := options.FromContext(ctx).InterruptionQueue
+	if queueName == "" {
+		return Result{Name: CheckInterruptionQueue, Status: StatusSkipped, Message: "interruption handling is disabled"}
+	}
+	out, err := p.sqsapi.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(queueName)})
+	if err != nil {
+		return Result{Name: CheckInterruptionQueue, Status: StatusFailed, Message: fmt.Sprintf("getting queue url, %s", err)}
+	}
+	attrs, err := p.sqsapi.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       out.QueueUrl,
+		AttributeNames: []sqstypes.QueueAttributeName{sqstypes.QueueAttributeNameApproximateNumberOfMessages},
+	})
+	if err != nil {
+		if awserrors.IsAccessDenied(err) {
+			return Result{Name: CheckInterruptionQueue, Status: StatusWarning, Message: "unable to read the queue backlog, the controller role isn't authorized to call sqs:GetQueueAttributes"}
+		}
+		return Result{Name: CheckInterruptionQueue, Status: StatusWarning, Message: fmt.Sprintf("getting queue attributes, %s", err)}
+	}
+	messages, _ := strconv.Atoi(attrs.Attributes[string(sqstypes.QueueAttributeNameApproximateNumberOfMessages)])
+	if messages >= interruptionQueueBacklogThreshold {
+		return Result{Name: CheckInterruptionQueue, Status: StatusWarning, Message: fmt.Sprintf("%d messages are waiting to be processed", messages)}
+	}
+	return Result{Name: CheckInterruptionQueue, Status: StatusPassed}
+}
+
+func (p *DefaultProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.results = nil
+}
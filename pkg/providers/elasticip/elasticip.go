@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticip
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+type Provider interface {
+	List(context.Context, *v1.EC2NodeClass) ([]ec2types.Address, error)
+	Associate(context.Context, string, string) error
+}
+
+// DefaultProvider resolves the Elastic IPs selected by an EC2NodeClass. Addresses aren't cached since their associations
+// change as instances are launched and terminated.
+type DefaultProvider struct {
+	ec2api sdk.EC2API
+}
+
+func NewDefaultProvider(ec2api sdk.EC2API) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api: ec2api,
+	}
+}
+
+// List returns the Elastic IPs selected by the nodeclass's elasticIPSelectorTerms, sorted by allocation id
+func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass) ([]ec2types.Address, error) {
+	addresses := map[string]ec2types.Address{}
+	for _, filters := range getFilterSets(nodeClass.Spec.ElasticIPSelectorTerms) {
+		output, err := p.ec2api.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{Filters: filters})
+		if err != nil {
+			return nil, fmt.Errorf("describing addresses %+v, %w", filters, err)
+		}
+		for i := range output.Addresses {
+			addresses[aws.ToString(output.Addresses[i].AllocationId)] = output.Addresses[i]
+		}
+	}
+	result := lo.Values(addresses)
+	sort.Slice(result, func(i, j int) bool {
+		return aws.ToString(result[i].AllocationId) < aws.ToString(result[j].AllocationId)
+	})
+	return result, nil
+}
+
+// Associate associates the Elastic IP with the instance's primary network interface. Addresses which are already associated
+// aren't reassociated, so that concurrent associations can't steal an address from another instance.
+func (p *DefaultProvider) Associate(ctx context.Context, allocationID string, instanceID string) error {
+	if _, err := p.ec2api.AssociateAddress(ctx, &ec2.AssociateAddressInput{
+		AllocationId:       aws.String(allocationID),
+		InstanceId:         aws.String(instanceID),
+		AllowReassociation: aws.Bool(false),
+	}); err != nil {
+		return fmt.Errorf("associating address %s, %w", allocationID, err)
+	}
+	return nil
+}
+
+func getFilterSets(terms []v1.ElasticIPSelectorTerm) (res [][]ec2types.Filter) {
+	idFilter := ec2types.Filter{Name: aws.String("allocation-id")}
+	for _, term := range terms {
+		switch {
+		case term.ID != "":
+			idFilter.Values = append(idFilter.Values, term.ID)
+		default:
+			var filters []ec2types.Filter
+			for k, v := range term.Tags {
+				if v == "*" {
+					filters = append(filters, ec2types.Filter{
+						Name:   aws.String("tag-key"),
+						Values: []string{k},
+					})
+				} else {
+					filters = append(filters, ec2types.Filter{
+						Name:   aws.String(fmt.Sprintf("tag:%s", k)),
+						Values: []string{v},
+					})
+				}
+			}
+			res = append(res, filters)
+		}
+	}
+	if len(idFilter.Values) > 0 {
+		res = append(res, []ec2types.Filter{idFilter})
+	}
+	return res
+}
@@ -20,7 +20,9 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 
@@ -28,6 +30,7 @@ import (
 	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/samber/lo"
 	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
@@ -42,7 +45,9 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/cache"
 	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchrole"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/utils"
 
@@ -53,6 +58,7 @@ import (
 const (
 	instanceTypeFlexibilityThreshold = 5 // falling back to on-demand without flexibility risks insufficient capacity errors
 	maxInstanceTypes                 = 60
+	commandDeliveryTimeoutSeconds    = 60
 )
 
 var (
@@ -74,25 +80,35 @@ type Provider interface {
 	List(context.Context) ([]*Instance, error)
 	Delete(context.Context, string) error
 	CreateTags(context.Context, string, map[string]string) error
+	Reboot(context.Context, string) error
+	Start(context.Context, string) error
+	SendCommand(context.Context, string, string, []string, time.Duration) (string, error)
+	GetCommandInvocation(context.Context, string, string) (*CommandInvocation, error)
 }
 
 type DefaultProvider struct {
 	region                 string
 	ec2api                 sdk.EC2API
+	ssmapi                 sdk.SSMAPI
 	unavailableOfferings   *cache.UnavailableOfferings
 	subnetProvider         subnet.Provider
 	launchTemplateProvider launchtemplate.Provider
+	launchRoleProvider     launchrole.Provider
+	quotaProvider          quota.Provider
 	ec2Batcher             *batcher.EC2API
 }
 
-func NewDefaultProvider(ctx context.Context, region string, ec2api sdk.EC2API, unavailableOfferings *cache.UnavailableOfferings,
-	subnetProvider subnet.Provider, launchTemplateProvider launchtemplate.Provider) *DefaultProvider {
+func NewDefaultProvider(ctx context.Context, region string, ec2api sdk.EC2API, ssmapi sdk.SSMAPI, unavailableOfferings *cache.UnavailableOfferings,
+	subnetProvider subnet.Provider, launchTemplateProvider launchtemplate.Provider, launchRoleProvider launchrole.Provider, quotaProvider quota.Provider) *DefaultProvider {
 	return &DefaultProvider{
 		region:                 region,
 		ec2api:                 ec2api,
+		ssmapi:                 ssmapi,
 		unavailableOfferings:   unavailableOfferings,
 		subnetProvider:         subnetProvider,
 		launchTemplateProvider: launchTemplateProvider,
+		launchRoleProvider:     launchRoleProvider,
+		quotaProvider:          quotaProvider,
 		ec2Batcher:             batcher.EC2(ctx, ec2api),
 	}
 }
@@ -117,7 +133,11 @@ func (p *DefaultProvider) Create(ctx context.Context, nodeClass *v1.EC2NodeClass
 		return nil, err
 	}
 	efaEnabled := lo.Contains(lo.Keys(nodeClaim.Spec.Resources.Requests), v1.ResourceEFA)
-	return NewInstanceFromFleet(fleetInstance, tags, efaEnabled), nil
+	instance := NewInstanceFromFleet(fleetInstance, tags, efaEnabled)
+	if instanceType, ok := lo.Find(instanceTypes, func(i *cloudprovider.InstanceType) bool { return i.Name == string(instance.Type) }); ok {
+		p.quotaProvider.RecordLaunch(ctx, instance.Type, instance.CapacityType, float64(instanceType.Capacity.Cpu().Value()))
+	}
+	return instance, nil
 }
 
 func (p *DefaultProvider) Get(ctx context.Context, id string) (*Instance, error) {
@@ -174,9 +194,22 @@ func (p *DefaultProvider) List(ctx context.Context) ([]*Instance, error) {
 }
 
 func (p *DefaultProvider) Delete(ctx context.Context, id string) error {
-	if _, err := p.ec2Batcher.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+	_, err := p.ec2Batcher.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
 		InstanceIds: []string{id},
-	}); err != nil {
+	})
+	if awserrors.IsTerminationProtected(err) && options.FromContext(ctx).AWSFeatureGates.Enabled(options.RemoveTerminationProtection) {
+		if _, err = p.ec2api.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+			InstanceId:            aws.String(id),
+			DisableApiTermination: &ec2types.AttributeBooleanValue{Value: aws.Bool(false)},
+		}); err != nil {
+			return fmt.Errorf("removing termination protection, %w", err)
+		}
+		log.FromContext(ctx).Info("removed instance termination protection")
+		_, err = p.ec2Batcher.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+			InstanceIds: []string{id},
+		})
+	}
+	if err != nil {
 		if awserrors.IsNotFound(err) {
 			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("instance already terminated"))
 		}
@@ -207,6 +240,62 @@ func (p *DefaultProvider) CreateTags(ctx context.Context, id string, tags map[st
 	return nil
 }
 
+func (p *DefaultProvider) Reboot(ctx context.Context, id string) error {
+	if _, err := p.ec2api.RebootInstances(ctx, &ec2.RebootInstancesInput{
+		InstanceIds: []string{id},
+	}); err != nil {
+		if awserrors.IsNotFound(err) {
+			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("rebooting instance, %w", err))
+		}
+		return fmt.Errorf("rebooting instance, %w", err)
+	}
+	return nil
+}
+
+// Start starts an instance that was stopped. EC2 rejects the request while the instance is still stopping.
+func (p *DefaultProvider) Start(ctx context.Context, id string) error {
+	if _, err := p.ec2api.StartInstances(ctx, &ec2.StartInstancesInput{
+		InstanceIds: []string{id},
+	}); err != nil {
+		if awserrors.IsNotFound(err) {
+			return cloudprovider.NewNodeClaimNotFoundError(fmt.Errorf("starting instance, %w", err))
+		}
+		return fmt.Errorf("starting instance, %w", err)
+	}
+	return nil
+}
+
+// SendCommand runs the commands on the instance with the passed SSM document and returns the ID of the command. SSM stops
+// the commands if they're still running once the timeout has elapsed.
+func (p *DefaultProvider) SendCommand(ctx context.Context, id string, document string, commands []string, timeout time.Duration) (string, error) {
+	out, err := p.ssmapi.SendCommand(ctx, &ssm.SendCommandInput{
+		DocumentName: aws.String(document),
+		InstanceIds:  []string{id},
+		Parameters: map[string][]string{
+			"commands":         commands,
+			"executionTimeout": {strconv.Itoa(int(math.Ceil(timeout.Seconds())))},
+		},
+		// The delivery timeout bounds how long SSM waits for the instance to pick up the command, not how long it runs for
+		TimeoutSeconds: aws.Int32(commandDeliveryTimeoutSeconds),
+		Comment:        aws.String("karpenter pre-termination"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("sending command, %w", err)
+	}
+	return aws.ToString(out.Command.CommandId), nil
+}
+
+func (p *DefaultProvider) GetCommandInvocation(ctx context.Context, commandID string, id string) (*CommandInvocation, error) {
+	out, err := p.ssmapi.GetCommandInvocation(ctx, &ssm.GetCommandInvocationInput{
+		CommandId:  aws.String(commandID),
+		InstanceId: aws.String(id),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getting command invocation, %w", err)
+	}
+	return NewCommandInvocation(out), nil
+}
+
 func (p *DefaultProvider) launchInstance(ctx context.Context, nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.NodeClaim, instanceTypes []*cloudprovider.InstanceType, tags map[string]string) (ec2types.CreateFleetInstance, error) {
 	capacityType := p.getCapacityType(nodeClaim, instanceTypes)
 	zonalSubnets, err := p.subnetProvider.ZonalSubnetsForLaunch(ctx, nodeClass, instanceTypes, capacityType)
@@ -237,13 +326,27 @@ func (p *DefaultProvider) launchInstance(ctx context.Context, nodeClass *v1.EC2N
 			{ResourceType: ec2types.ResourceTypeFleet, Tags: utils.MergeTags(tags)},
 		},
 	}
-	if capacityType == karpv1.CapacityTypeSpot {
+	if nodeClass.Status.CapacityBlock != nil {
+		// Capacity Blocks are launched with their own target capacity type, and the launch template targets the reservation
+		createFleetInput.TargetCapacitySpecification.DefaultTargetCapacityType = ec2types.DefaultTargetCapacityTypeCapacityBlock
+	} else if capacityType == karpv1.CapacityTypeSpot {
 		createFleetInput.SpotOptions = &ec2types.SpotOptionsRequest{AllocationStrategy: ec2types.SpotAllocationStrategyPriceCapacityOptimized}
 	} else {
 		createFleetInput.OnDemandOptions = &ec2types.OnDemandOptionsRequest{AllocationStrategy: ec2types.FleetOnDemandAllocationStrategyLowestPrice}
 	}
 
-	createFleetOutput, err := p.ec2Batcher.CreateFleet(ctx, createFleetInput)
+	createFleet := p.ec2Batcher.CreateFleet
+	if nodeClass.Spec.LaunchRole != nil {
+		ec2api, err := p.launchRoleProvider.EC2API(ctx, nodeClass, nodeClaim.Labels[karpv1.NodePoolLabelKey])
+		if err != nil {
+			return ec2types.CreateFleetInstance{}, cloudprovider.NewCreateError(fmt.Errorf("resolving launch role, %w", err), "Error assuming launch role")
+		}
+		// Launches aren't batched with a launch role, since every request in a batch is sent with the same credentials
+		createFleet = func(ctx context.Context, input *ec2.CreateFleetInput) (*ec2.CreateFleetOutput, error) {
+			return ec2api.CreateFleet(ctx, input)
+		}
+	}
+	createFleetOutput, err := createFleet(ctx, createFleetInput)
 	p.subnetProvider.UpdateInflightIPs(createFleetInput, createFleetOutput, instanceTypes, lo.Values(zonalSubnets), capacityType)
 	if err != nil {
 		conditionMessage := "Error creating fleet"
@@ -354,9 +457,17 @@ func (p *DefaultProvider) getOverrides(instanceTypes []*cloudprovider.InstanceTy
 }
 
 func (p *DefaultProvider) updateUnavailableOfferingsCache(ctx context.Context, errors []ec2types.CreateFleetError, capacityType string) {
+	zones := sets.New[string]()
 	for _, err := range errors {
 		if awserrors.IsUnfulfillableCapacity(err) {
 			p.unavailableOfferings.MarkUnavailableForFleetErr(ctx, err, capacityType)
+			zones.Insert(aws.ToString(err.LaunchTemplateAndOverrides.Overrides.AvailabilityZone))
+		}
+	}
+	// A single launch returns an error for every override that failed, so each zone is only counted once per launch
+	if options.FromContext(ctx).ZonalShift {
+		for zone := range zones {
+			p.unavailableOfferings.RecordZoneFailure(ctx, "InsufficientCapacity", zone)
 		}
 	}
 }
@@ -460,7 +571,8 @@ func filterExoticInstanceTypes(instanceTypes []*cloudprovider.InstanceType) []*c
 			!resources.IsZero(it.Capacity[v1.ResourceAWSNeuronCore]) ||
 			!resources.IsZero(it.Capacity[v1.ResourceAMDGPU]) ||
 			!resources.IsZero(it.Capacity[v1.ResourceNVIDIAGPU]) ||
-			!resources.IsZero(it.Capacity[v1.ResourceHabanaGaudi]) {
+			!resources.IsZero(it.Capacity[v1.ResourceHabanaGaudi]) ||
+			lo.SomeBy(lo.Keys(it.Capacity), func(r corev1.ResourceName) bool { return strings.HasPrefix(string(r), v1.ResourceNVIDIAMIGPrefix) }) {
 			continue
 		}
 		genericInstanceTypes = append(genericInstanceTypes, it)
@@ -497,6 +609,10 @@ func combineFleetErrors(fleetErrs []ec2types.CreateFleetError) (errs error) {
 	for errorCode := range unique {
 		errs = multierr.Append(errs, errors.New(errorCode))
 	}
+	// Every instance type is associated with the same license configurations, so retrying with other offerings won't help
+	if lo.ContainsBy(fleetErrs, awserrors.IsLicenseLimitExceeded) {
+		return cloudprovider.NewCreateError(fmt.Errorf("license configuration limit exceeded, %w", errs), "License configuration limit exceeded")
+	}
 	// If all the Fleet errors are ICE errors then we should wrap the combined error in the generic ICE error
 	iceErrorCount := lo.CountBy(fleetErrs, func(err ec2types.CreateFleetError) bool { return awserrors.IsUnfulfillableCapacity(err) })
 	if iceErrorCount == len(fleetErrs) {
@@ -16,6 +16,7 @@ package instance_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -23,7 +24,9 @@ import (
 	"sigs.k8s.io/karpenter/pkg/test/v1alpha1"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
 	"github.com/awslabs/operatorpkg/object"
 	"github.com/samber/lo"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,6 +40,7 @@ import (
 
 	"github.com/aws/karpenter-provider-aws/pkg/apis"
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/cloudprovider"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
@@ -66,7 +70,7 @@ var _ = BeforeSuite(func() {
 	ctx = options.ToContext(ctx, test.Options())
 	awsEnv = test.NewEnvironment(ctx, env)
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
 })
 
 var _ = AfterSuite(func() {
@@ -137,6 +141,111 @@ var _ = Describe("InstanceProvider", func() {
 		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
 		Expect(instance).To(BeNil())
 	})
+	It("should return a create error when a license configuration limit is exceeded", func() {
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		awsEnv.EC2API.CreateFleetBehavior.Output.Set(&ec2.CreateFleetOutput{
+			Errors: []ec2types.CreateFleetError{
+				{ErrorCode: aws.String("LicenseUsageException"), ErrorMessage: aws.String("license limit exceeded")},
+				{ErrorCode: aws.String("InsufficientInstanceCapacity"), ErrorMessage: aws.String("insufficient capacity")},
+			},
+		})
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+		Expect(instance).To(BeNil())
+		Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeFalse())
+		createErr := &corecloudprovider.CreateError{}
+		Expect(errors.As(err, &createErr)).To(BeTrue())
+		Expect(createErr.ConditionMessage).To(Equal("License configuration limit exceeded"))
+	})
+	It("should shift launches away from a zone when insufficient capacity errors are concentrated in it", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ZonalShift: lo.ToPtr(true)}))
+		ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+		nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+		iceErr := func(instanceType ec2types.InstanceType) ec2types.CreateFleetError {
+			return ec2types.CreateFleetError{
+				ErrorCode: aws.String("InsufficientInstanceCapacity"),
+				LaunchTemplateAndOverrides: &ec2types.LaunchTemplateAndOverridesResponse{
+					Overrides: &ec2types.FleetLaunchTemplateOverrides{InstanceType: instanceType, AvailabilityZone: aws.String("test-zone-1a")},
+				},
+			}
+		}
+		awsEnv.EC2API.CreateFleetBehavior.Output.Set(&ec2.CreateFleetOutput{
+			Errors: []ec2types.CreateFleetError{iceErr("m5.large"), iceErr("m5.xlarge")},
+		})
+		instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+		Expect(err).ToNot(HaveOccurred())
+
+		// Each launch only counts once towards the zone, regardless of how many of its overrides failed
+		for i := 0; i < awscache.ZoneFailureThreshold; i++ {
+			Expect(awsEnv.UnavailableOfferingsCache.ImpairedZones()).To(BeEmpty())
+			_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+			Expect(corecloudprovider.IsInsufficientCapacityError(err)).To(BeTrue())
+		}
+		Expect(awsEnv.UnavailableOfferingsCache.ImpairedZones()).To(HaveKey("test-zone-1a"))
+		Expect(awsEnv.UnavailableOfferingsCache.IsUnavailable("c5.large", "test-zone-1a", karpv1.CapacityTypeOnDemand)).To(BeTrue())
+		Expect(awsEnv.UnavailableOfferingsCache.IsUnavailable("c5.large", "test-zone-1b", karpv1.CapacityTypeOnDemand)).To(BeFalse())
+	})
+	Context("Launch Role", func() {
+		BeforeEach(func() {
+			nodeClass.Spec.LaunchRole = &v1.LaunchRole{
+				ARN:            "arn:aws:iam::123456789012:role/team-a-launch",
+				SessionTags:    map[string]string{"team": "team-a"},
+				SourceIdentity: aws.String("team-a"),
+			}
+		})
+		It("should launch instances with a role session tagged with the NodePool and EC2NodeClass", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instance).ToNot(BeNil())
+
+			Expect(awsEnv.STSAPI.AssumeRoleBehavior.Calls()).To(Equal(1))
+			input := awsEnv.STSAPI.AssumeRoleBehavior.CalledWithInput.Pop()
+			Expect(aws.ToString(input.RoleArn)).To(Equal("arn:aws:iam::123456789012:role/team-a-launch"))
+			Expect(aws.ToString(input.RoleSessionName)).To(Equal(fmt.Sprintf("karpenter-%s", nodePool.Name)))
+			Expect(aws.ToString(input.SourceIdentity)).To(Equal("team-a"))
+			Expect(lo.SliceToMap(input.Tags, func(t ststypes.Tag) (string, string) { return aws.ToString(t.Key), aws.ToString(t.Value) })).To(Equal(map[string]string{
+				"team":                  "team-a",
+				karpv1.NodePoolLabelKey: nodePool.Name,
+				v1.LabelNodeClass:       nodeClass.Name,
+			}))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(1))
+		})
+		It("should reuse the role session across launches", func() {
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			for range 2 {
+				_, err = awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(awsEnv.STSAPI.AssumeRoleBehavior.Calls()).To(Equal(1))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(Equal(2))
+		})
+		It("should return a create error without launching when the role can't be assumed", func() {
+			awsEnv.STSAPI.AssumeRoleBehavior.Error.Set(fmt.Errorf("access denied"))
+			ExpectApplied(ctx, env.Client, nodeClaim, nodePool, nodeClass)
+			nodeClass = ExpectExists(ctx, env.Client, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+
+			instance, err := awsEnv.InstanceProvider.Create(ctx, nodeClass, nodeClaim, nil, instanceTypes)
+			Expect(instance).To(BeNil())
+			createErr := &corecloudprovider.CreateError{}
+			Expect(errors.As(err, &createErr)).To(BeTrue())
+			Expect(createErr.ConditionMessage).To(Equal("Error assuming launch role"))
+			Expect(awsEnv.EC2API.CreateFleetBehavior.Calls()).To(BeZero())
+		})
+	})
 	It("should return all NodePool-owned instances from List", func() {
 		ids := sets.New[string]()
 		// Provision instances that have the karpenter.sh/nodepool key
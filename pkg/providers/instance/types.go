@@ -19,6 +19,8 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
 	"github.com/samber/lo"
 
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
@@ -75,3 +77,34 @@ func NewInstanceFromFleet(out ec2types.CreateFleetInstance, tags map[string]stri
 		EFAEnabled:   efaEnabled,
 	}
 }
+
+// CommandInvocation is the result of an SSM command that was run on an instance
+type CommandInvocation struct {
+	Status        ssmtypes.CommandInvocationStatus
+	StatusDetails string
+	Output        string
+	Error         string
+}
+
+func NewCommandInvocation(out *ssm.GetCommandInvocationOutput) *CommandInvocation {
+	return &CommandInvocation{
+		Status:        out.Status,
+		StatusDetails: aws.ToString(out.StatusDetails),
+		Output:        aws.ToString(out.StandardOutputContent),
+		Error:         aws.ToString(out.StandardErrorContent),
+	}
+}
+
+// IsComplete returns true once the command is no longer pending or running on the instance
+func (c *CommandInvocation) IsComplete() bool {
+	return !lo.Contains([]ssmtypes.CommandInvocationStatus{
+		ssmtypes.CommandInvocationStatusPending,
+		ssmtypes.CommandInvocationStatusInProgress,
+		ssmtypes.CommandInvocationStatusDelayed,
+		ssmtypes.CommandInvocationStatusCancelling,
+	}, c.Status)
+}
+
+func (c *CommandInvocation) Succeeded() bool {
+	return c.Status == ssmtypes.CommandInvocationStatusSuccess
+}
@@ -17,6 +17,7 @@ package instanceprofile
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
@@ -25,8 +26,10 @@ import (
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
 
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 )
@@ -39,27 +42,42 @@ type ResourceOwner interface {
 	InstanceProfileTags(string) map[string]string
 }
 
+// maxPropagationBackoff is the longest that the verification of an instance profile's role is backed off to
+const maxPropagationBackoff = 2 * time.Minute
+
 type Provider interface {
 	Create(context.Context, ResourceOwner) (string, error)
+	Propagating(context.Context, ResourceOwner) (time.Duration, error)
 	Delete(context.Context, ResourceOwner) error
 }
 
 type DefaultProvider struct {
+	clk    clock.Clock
 	region string
 	iamapi sdk.IAMAPI
 	cache  *cache.Cache
+	// propagating records when the role of each owner's instance profile was attached, until it's verified to have propagated
+	propagating *cache.Cache
+}
+
+// propagation tracks the verification of an instance profile's role after it was attached
+type propagation struct {
+	verifyAt time.Time
+	attempts int
 }
 
-func NewDefaultProvider(region string, iamapi sdk.IAMAPI, cache *cache.Cache) *DefaultProvider {
+func NewDefaultProvider(clk clock.Clock, region string, iamapi sdk.IAMAPI, instanceProfileCache *cache.Cache) *DefaultProvider {
 	return &DefaultProvider{
-		region: region,
-		iamapi: iamapi,
-		cache:  cache,
+		clk:         clk,
+		region:      region,
+		iamapi:      iamapi,
+		cache:       instanceProfileCache,
+		propagating: cache.New(awscache.InstanceProfileTTL, awscache.DefaultCleanupInterval),
 	}
 }
 
 func (p *DefaultProvider) Create(ctx context.Context, m ResourceOwner) (string, error) {
-	profileName := m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
+	profileName := options.FromContext(ctx).ResourceNamePrefix + m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
 	tags := map[string]string{}
 	if len(m.InstanceProfileTags(options.FromContext(ctx).ClusterName)) != 0 {
 		tags = lo.Assign(m.InstanceProfileTags(options.FromContext(ctx).ClusterName), map[string]string{corev1.LabelTopologyRegion: p.region})
@@ -105,12 +123,55 @@ func (p *DefaultProvider) Create(ctx context.Context, m ResourceOwner) (string,
 	}); err != nil {
 		return "", fmt.Errorf("adding role %q to instance profile %q, %w", m.InstanceProfileRole(), profileName, err)
 	}
+	if delay := options.FromContext(ctx).InstanceProfilePropagationDelay; delay > 0 {
+		p.propagating.SetDefault(string(m.GetUID()), propagation{verifyAt: p.clk.Now().Add(delay)})
+	}
 	p.cache.SetDefault(string(m.GetUID()), nil)
 	return aws.ToString(instanceProfile.InstanceProfileName), nil
 }
 
+// Propagating returns how long to wait before launching with the owner's instance profile, or 0 once it can be launched
+// with. IAM is eventually consistent, so EC2 may reject launches with an instance profile that was just created or had its
+// role changed. Once the propagation delay has passed, the role is verified to be attached, and the verification is backed
+// off exponentially until it is.
+func (p *DefaultProvider) Propagating(ctx context.Context, m ResourceOwner) (time.Duration, error) {
+	val, ok := p.propagating.Get(string(m.GetUID()))
+	if !ok {
+		return 0, nil
+	}
+	prop := val.(propagation)
+	if remaining := prop.verifyAt.Sub(p.clk.Now()); remaining > 0 {
+		return remaining, nil
+	}
+	profileName := options.FromContext(ctx).ResourceNamePrefix + m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
+	out, err := p.iamapi.GetInstanceProfile(ctx, &iam.GetInstanceProfileInput{InstanceProfileName: aws.String(profileName)})
+	if err != nil && !awserrors.IsNotFound(err) {
+		return 0, fmt.Errorf("getting instance profile %q, %w", profileName, err)
+	}
+	if err == nil && lo.ContainsBy(out.InstanceProfile.Roles, func(r iamtypes.Role) bool { return aws.ToString(r.RoleName) == m.InstanceProfileRole() }) {
+		p.propagating.Delete(string(m.GetUID()))
+		return 0, nil
+	}
+	prop.attempts++
+	backoff := min(options.FromContext(ctx).InstanceProfilePropagationDelay<<prop.attempts, maxPropagationBackoff)
+	prop.verifyAt = p.clk.Now().Add(backoff)
+	p.propagating.SetDefault(string(m.GetUID()), prop)
+	return backoff, nil
+}
+
+// Delete deletes the owner's instance profile. Instance profiles that were created before the resource name prefix was set
+// are still in use by the owner's existing instances, so they're deleted along with it.
 func (p *DefaultProvider) Delete(ctx context.Context, m ResourceOwner) error {
-	profileName := m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
+	name := m.InstanceProfileName(options.FromContext(ctx).ClusterName, p.region)
+	for _, profileName := range lo.Uniq([]string{options.FromContext(ctx).ResourceNamePrefix + name, name}) {
+		if err := p.delete(ctx, profileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *DefaultProvider) delete(ctx context.Context, profileName string) error {
 	out, err := p.iamapi.GetInstanceProfile(ctx, &iam.GetInstanceProfileInput{
 		InstanceProfileName: aws.String(profileName),
 	})
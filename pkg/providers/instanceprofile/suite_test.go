@@ -17,6 +17,9 @@ package instanceprofile_test
 import (
 	"context"
 	"testing"
+	"time"
+
+	"github.com/samber/lo"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 
@@ -100,4 +103,28 @@ var _ = Describe("InstanceProfileProvider", func() {
 		Expect(instanceProfile).ToNot(BeNil())
 		Expect(awsEnv.IAMAPI.InstanceProfiles[instanceProfile].Tags).To(HaveLen(0))
 	})
+	It("should not wait for propagation when the propagation delay is 0", func() {
+		nodeClass.Spec.Role = "test-role"
+		_, err := awsEnv.InstanceProfileProvider.Create(ctx, &nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.InstanceProfileProvider.Propagating(ctx, &nodeClass)).To(BeZero())
+	})
+	It("should wait for the propagation delay and back off until the role is verified", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{InstanceProfilePropagationDelay: lo.ToPtr(10 * time.Second)}))
+		nodeClass.Spec.Role = "test-role"
+		instanceProfile, err := awsEnv.InstanceProfileProvider.Create(ctx, &nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(awsEnv.InstanceProfileProvider.Propagating(ctx, &nodeClass)).To(Equal(10 * time.Second))
+
+		// The role isn't visible yet, so verification is backed off
+		roles := awsEnv.IAMAPI.InstanceProfiles[instanceProfile].Roles
+		awsEnv.IAMAPI.InstanceProfiles[instanceProfile].Roles = nil
+		awsEnv.Clock.Step(10 * time.Second)
+		Expect(awsEnv.InstanceProfileProvider.Propagating(ctx, &nodeClass)).To(Equal(20 * time.Second))
+
+		awsEnv.IAMAPI.InstanceProfiles[instanceProfile].Roles = roles
+		awsEnv.Clock.Step(20 * time.Second)
+		Expect(awsEnv.InstanceProfileProvider.Propagating(ctx, &nodeClass)).To(BeZero())
+		Expect(awsEnv.InstanceProfileProvider.Propagating(ctx, &nodeClass)).To(BeZero())
+	})
 })
@@ -17,6 +17,8 @@ package instancetype
 import (
 	"context"
 	"fmt"
+	"maps"
+	"math"
 	"sync"
 	"sync/atomic"
 
@@ -39,6 +41,7 @@ import (
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 
@@ -48,6 +51,8 @@ import (
 
 type Provider interface {
 	List(context.Context, *v1.EC2NodeClass) ([]*cloudprovider.InstanceType, error)
+	// PerformanceMultipliers returns the operator provided performance multipliers, keyed by instance type or family
+	PerformanceMultipliers() map[string]float64
 }
 
 type DefaultProvider struct {
@@ -69,10 +74,22 @@ type DefaultProvider struct {
 	instanceTypesCache      *cache.Cache
 	discoveredCapacityCache *cache.Cache
 	cm                      *pretty.ChangeMonitor
+
+	muMemoryOverheads sync.RWMutex
+	// memoryOverheads are the VM memory overheads, as a fraction of memory, calibrated per instance type from the memory
+	// capacity reported by registered nodes
+	memoryOverheads map[string]float64
+
+	muPerformanceMultipliers sync.RWMutex
+	// performanceMultipliers scale the effective price of instance types by their relative performance, keyed by
+	// instance type or instance family
+	performanceMultipliers map[string]float64
 	// instanceTypesSeqNum is a monotonically increasing change counter used to avoid the expensive hashing operation on instance types
 	instanceTypesSeqNum uint64
 	// instanceTypesOfferingsSeqNum is a monotonically increasing change counter used to avoid the expensive hashing operation on instance types
 	instanceTypesOfferingsSeqNum uint64
+	// memoryOverheadsSeqNum is a monotonically increasing change counter for the calibrated memory overheads
+	memoryOverheadsSeqNum uint64
 }
 
 func NewDefaultProvider(instanceTypesCache *cache.Cache, discoveredCapacityCache *cache.Cache, ec2api sdk.EC2API, subnetProvider subnet.Provider, instanceTypesResolver Resolver) *DefaultProvider {
@@ -85,6 +102,8 @@ func NewDefaultProvider(instanceTypesCache *cache.Cache, discoveredCapacityCache
 		instanceTypesCache:      instanceTypesCache,
 		discoveredCapacityCache: discoveredCapacityCache,
 		cm:                      pretty.NewChangeMonitor(),
+		memoryOverheads:         map[string]float64{},
+		performanceMultipliers:  map[string]float64{},
 		instanceTypesSeqNum:     0,
 	}
 }
@@ -116,9 +135,10 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass)
 	// Compute hash key against node class AMIs (used to force cache rebuild when AMIs change)
 	amiHash, _ := hashstructure.Hash(nodeClass.Status.AMIs, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 
-	key := fmt.Sprintf("%d-%d-%016x-%016x-%016x",
+	key := fmt.Sprintf("%d-%d-%d-%016x-%016x-%016x",
 		p.instanceTypesSeqNum,
 		p.instanceTypesOfferingsSeqNum,
+		atomic.LoadUint64(&p.memoryOverheadsSeqNum),
 		amiHash,
 		subnetZonesHash,
 		p.instanceTypesResolver.CacheKey(nodeClass),
@@ -140,10 +160,18 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass)
 	if p.cm.HasChanged("zones", allZones) {
 		log.FromContext(ctx).WithValues("zones", allZones.UnsortedList()).V(1).Info("discovered zones")
 	}
+	memoryOverheads := map[string]float64{}
+	if options.FromContext(ctx).AWSFeatureGates.Enabled(options.MemoryOverheadCalibration) {
+		memoryOverheads = p.MemoryOverheads()
+	}
 	subnetZoneToID := lo.SliceToMap(nodeClass.Status.Subnets, func(s v1.Subnet) (string, string) {
 		return s.Zone, s.ZoneID
 	})
-	result := lo.Map(p.instanceTypesInfo, func(i ec2types.InstanceTypeInfo, _ int) *cloudprovider.InstanceType {
+	// Instance types which don't support the EC2NodeClass's enclave and confidential computing options can't be launched
+	instanceTypesInfo := lo.Filter(p.instanceTypesInfo, func(i ec2types.InstanceTypeInfo, _ int) bool {
+		return supportsLaunchOptions(i, nodeClass)
+	})
+	result := lo.Map(instanceTypesInfo, func(i ec2types.InstanceTypeInfo, _ int) *cloudprovider.InstanceType {
 		InstanceTypeVCPU.Set(float64(lo.FromPtr(i.VCpuInfo.DefaultVCpus)), map[string]string{
 			instanceTypeLabel: string(i.InstanceType),
 		})
@@ -168,6 +196,10 @@ func (p *DefaultProvider) List(ctx context.Context, nodeClass *v1.EC2NodeClass)
 		it := p.instanceTypesResolver.Resolve(ctx, i, zoneData, nodeClass)
 		if cached, ok := p.discoveredCapacityCache.Get(fmt.Sprintf("%s-%016x", it.Name, amiHash)); ok {
 			it.Capacity[corev1.ResourceMemory] = cached.(resource.Quantity)
+		} else if overhead, ok := memoryOverheads[it.Name]; ok {
+			// Nodes haven't registered with the current AMIs, so fall back to the overhead calibrated from previous nodes
+			// of the instance type rather than the static VM_MEMORY_OVERHEAD_PERCENT
+			it.Capacity[corev1.ResourceMemory] = *memoryWithOverhead(i, overhead)
 		}
 		for _, of := range it.Offerings {
 			InstanceTypeOfferingAvailable.Set(float64(lo.Ternary(of.Available, 1, 0)), map[string]string{
@@ -271,8 +303,12 @@ func (p *DefaultProvider) UpdateInstanceTypeOfferings(ctx context.Context) error
 }
 
 func (p *DefaultProvider) UpdateInstanceTypeCapacityFromNode(ctx context.Context, node *corev1.Node, nodeClaim *karpv1.NodeClaim, nodeClass *v1.EC2NodeClass) error {
-	// Get mappings for most recent AMIs
 	instanceTypeName := node.Labels[corev1.LabelInstanceTypeStable]
+	// The VM overhead depends on the instance type rather than the AMI, so it's calibrated from nodes of any AMI
+	if options.FromContext(ctx).AWSFeatureGates.Enabled(options.MemoryOverheadCalibration) {
+		p.calibrateMemoryOverhead(ctx, instanceTypeName, node.Status.Capacity.Memory())
+	}
+	// Get mappings for most recent AMIs
 	amiMap := amifamily.MapToInstanceTypes([]*cloudprovider.InstanceType{{
 		Name:         instanceTypeName,
 		Requirements: scheduling.NewNodeSelectorRequirementsWithMinValues(nodeClaim.Spec.Requirements...),
@@ -296,9 +332,75 @@ func (p *DefaultProvider) UpdateInstanceTypeCapacityFromNode(ctx context.Context
 	return nil
 }
 
+// calibrateMemoryOverhead records the VM memory overhead of the instance type implied by a node's memory capacity. The
+// largest overhead observed is kept, in the same way that the smallest discovered capacity is, so that we never overestimate
+// the memory available on a node.
+func (p *DefaultProvider) calibrateMemoryOverhead(ctx context.Context, instanceTypeName string, actualCapacity *resource.Quantity) {
+	p.muInstanceTypesInfo.RLock()
+	info, ok := lo.Find(p.instanceTypesInfo, func(i ec2types.InstanceTypeInfo) bool { return string(i.InstanceType) == instanceTypeName })
+	p.muInstanceTypesInfo.RUnlock()
+	if !ok || actualCapacity.IsZero() {
+		return
+	}
+	overhead := math.Max(0, 1-float64(actualCapacity.Value())/float64(memoryWithOverhead(info, 0).Value()))
+	if p.SetMemoryOverheads(map[string]float64{instanceTypeName: overhead}) {
+		log.FromContext(ctx).WithValues("instance-type", instanceTypeName, "memory-overhead-percent", overhead).V(1).Info("calibrated vm memory overhead")
+	}
+}
+
+// MemoryOverheads returns a copy of the calibrated VM memory overheads, keyed by instance type
+func (p *DefaultProvider) MemoryOverheads() map[string]float64 {
+	p.muMemoryOverheads.RLock()
+	defer p.muMemoryOverheads.RUnlock()
+	return lo.Assign(p.memoryOverheads)
+}
+
+// SetMemoryOverheads merges calibrated VM memory overheads into those that are already known, keeping the largest overhead
+// for each instance type. It returns whether any of the overheads changed.
+func (p *DefaultProvider) SetMemoryOverheads(overheads map[string]float64) bool {
+	p.muMemoryOverheads.Lock()
+	defer p.muMemoryOverheads.Unlock()
+	changed := false
+	for instanceTypeName, overhead := range overheads {
+		if current, ok := p.memoryOverheads[instanceTypeName]; ok && current >= overhead {
+			continue
+		}
+		p.memoryOverheads[instanceTypeName] = overhead
+		changed = true
+	}
+	if changed {
+		atomic.AddUint64(&p.memoryOverheadsSeqNum, 1)
+	}
+	return changed
+}
+
+// PerformanceMultipliers returns a copy of the performance multipliers, keyed by instance type or instance family
+func (p *DefaultProvider) PerformanceMultipliers() map[string]float64 {
+	p.muPerformanceMultipliers.RLock()
+	defer p.muPerformanceMultipliers.RUnlock()
+	return lo.Assign(p.performanceMultipliers)
+}
+
+// SetPerformanceMultipliers replaces the performance multipliers. It returns whether any of the multipliers changed.
+func (p *DefaultProvider) SetPerformanceMultipliers(multipliers map[string]float64) bool {
+	p.muPerformanceMultipliers.Lock()
+	defer p.muPerformanceMultipliers.Unlock()
+	if maps.Equal(p.performanceMultipliers, multipliers) {
+		return false
+	}
+	p.performanceMultipliers = lo.Assign(multipliers)
+	return true
+}
+
 func (p *DefaultProvider) Reset() {
 	p.instanceTypesInfo = []ec2types.InstanceTypeInfo{}
 	p.instanceTypesOfferings = map[string]sets.Set[string]{}
 	p.instanceTypesCache.Flush()
 	p.discoveredCapacityCache.Flush()
+	p.muMemoryOverheads.Lock()
+	p.memoryOverheads = map[string]float64{}
+	p.muMemoryOverheads.Unlock()
+	p.muPerformanceMultipliers.Lock()
+	p.performanceMultipliers = map[string]float64{}
+	p.muPerformanceMultipliers.Unlock()
 }
@@ -88,7 +88,7 @@ var _ = BeforeSuite(func() {
 	awsEnv = test.NewEnvironment(ctx, env)
 	fakeClock = &clock.FakeClock{}
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster, fakeClock)
 })
@@ -235,11 +235,14 @@ var _ = Describe("InstanceTypeProvider", func() {
 			// Well Known to AWS
 			v1.LabelInstanceHypervisor:                   "nitro",
 			v1.LabelInstanceEncryptionInTransitSupported: "true",
+			v1.LabelInstanceNitroEnclavesSupported:       "false",
+			v1.LabelInstanceAMDSEVSNPSupported:           "false",
 			v1.LabelInstanceCategory:                     "g",
 			v1.LabelInstanceGeneration:                   "4",
 			v1.LabelInstanceFamily:                       "g4dn",
 			v1.LabelInstanceSize:                         "8xlarge",
 			v1.LabelInstanceCPU:                          "32",
+			v1.LabelInstanceThreadsPerCore:               "2",
 			v1.LabelInstanceCPUManufacturer:              "intel",
 			v1.LabelInstanceCPUSustainedClockSpeedMhz:    "2500",
 			v1.LabelInstanceMemory:                       "131072",
@@ -292,11 +295,14 @@ var _ = Describe("InstanceTypeProvider", func() {
 			// Well Known to AWS
 			v1.LabelInstanceHypervisor:                   "nitro",
 			v1.LabelInstanceEncryptionInTransitSupported: "true",
+			v1.LabelInstanceNitroEnclavesSupported:       "false",
+			v1.LabelInstanceAMDSEVSNPSupported:           "false",
 			v1.LabelInstanceCategory:                     "g",
 			v1.LabelInstanceGeneration:                   "4",
 			v1.LabelInstanceFamily:                       "g4dn",
 			v1.LabelInstanceSize:                         "8xlarge",
 			v1.LabelInstanceCPU:                          "32",
+			v1.LabelInstanceThreadsPerCore:               "2",
 			v1.LabelInstanceCPUManufacturer:              "intel",
 			v1.LabelInstanceCPUSustainedClockSpeedMhz:    "2500",
 			v1.LabelInstanceMemory:                       "131072",
@@ -346,11 +352,14 @@ var _ = Describe("InstanceTypeProvider", func() {
 			// Well Known to AWS
 			v1.LabelInstanceHypervisor:                   "nitro",
 			v1.LabelInstanceEncryptionInTransitSupported: "true",
+			v1.LabelInstanceNitroEnclavesSupported:       "false",
+			v1.LabelInstanceAMDSEVSNPSupported:           "false",
 			v1.LabelInstanceCategory:                     "inf",
 			v1.LabelInstanceGeneration:                   "2",
 			v1.LabelInstanceFamily:                       "inf2",
 			v1.LabelInstanceSize:                         "xlarge",
 			v1.LabelInstanceCPU:                          "4",
+			v1.LabelInstanceThreadsPerCore:               "2",
 			v1.LabelInstanceCPUSustainedClockSpeedMhz:    "3600",
 			v1.LabelInstanceCPUManufacturer:              "amd",
 			v1.LabelInstanceMemory:                       "16384",
@@ -398,6 +407,46 @@ var _ = Describe("InstanceTypeProvider", func() {
 		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
 		ExpectNotScheduled(ctx, env.Client, pod)
 	})
+	It("should only offer instance types that support nitro enclaves when enclaves are enabled", func() {
+		instances := fake.MakeInstances()
+		for i := range instances {
+			if lo.Contains([]ec2types.InstanceType{"m5.large", "m5.xlarge"}, instances[i].InstanceType) {
+				instances[i].NitroEnclavesSupport = ec2types.NitroEnclavesSupportSupported
+			}
+		}
+		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: instances})
+		awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+			InstanceTypeOfferings: fake.MakeInstanceOfferings(instances),
+		})
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		nodeClass.Spec.EnclaveOptions = &v1.EnclaveOptions{Enabled: true}
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("m5.large", "m5.xlarge"))
+		for _, it := range instanceTypes {
+			Expect(it.Requirements.Get(v1.LabelInstanceNitroEnclavesSupported).Values()).To(ConsistOf("true"))
+		}
+	})
+	It("should only offer instance types that support AMD SEV-SNP when it is enabled", func() {
+		instances := fake.MakeInstances()
+		for i := range instances {
+			if instances[i].InstanceType == "m6a.large" {
+				instances[i].ProcessorInfo.SupportedFeatures = []ec2types.SupportedAdditionalProcessorFeature{ec2types.SupportedAdditionalProcessorFeatureAmdSevSnp}
+			}
+		}
+		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: instances})
+		awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+			InstanceTypeOfferings: fake.MakeInstanceOfferings(instances),
+		})
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+		Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		nodeClass.Spec.CPUOptions = &v1.CPUOptions{AMDSEVSNP: lo.ToPtr("enabled")}
+		instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("m6a.large"))
+		Expect(instanceTypes[0].Requirements.Get(v1.LabelInstanceAMDSEVSNPSupported).Values()).To(ConsistOf("true"))
+	})
 	It("should order the instance types by price and only consider the cheapest ones", func() {
 		instances := fake.MakeInstances()
 		awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{
@@ -944,6 +993,22 @@ var _ = Describe("InstanceTypeProvider", func() {
 		Expect(node.Labels[corev1.LabelInstanceTypeStable]).To(Equal("m6idn.32xlarge"))
 		Expect(*node.Status.Capacity.StorageEphemeral()).To(Equal(resource.MustParse("7600G")))
 	})
+	It("should only launch instances with NVMe instance storage when the instance store policy is EphemeralOS", func() {
+		nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyEphemeralOS)
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod()
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		node := ExpectScheduled(ctx, env.Client, pod)
+		instanceInfo, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+		Expect(err).To(BeNil())
+		info, ok := lo.Find(instanceInfo.InstanceTypes, func(info ec2types.InstanceTypeInfo) bool {
+			return string(info.InstanceType) == node.Labels[corev1.LabelInstanceTypeStable]
+		})
+		Expect(ok).To(BeTrue())
+		Expect(info.InstanceStorageInfo).ToNot(BeNil())
+		Expect(info.InstanceStorageInfo.NvmeSupport).ToNot(Equal(ec2types.EphemeralNvmeSupportUnsupported))
+		Expect(*node.Status.Capacity.StorageEphemeral()).To(Equal(resource.MustParse(fmt.Sprintf("%dG", lo.FromPtr(info.InstanceStorageInfo.TotalSizeInGB)))))
+	})
 	It("should not set pods to 110 if using ENI-based pod density", func() {
 		instanceInfo, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
 		Expect(err).To(BeNil())
@@ -988,6 +1053,220 @@ var _ = Describe("InstanceTypeProvider", func() {
 			Expect(it.Capacity.Pods().Value()).To(BeNumerically("==", 110))
 		}
 	})
+	Context("CPU Options", func() {
+		var m5 ec2types.InstanceTypeInfo
+		var resolver *instancetype.DefaultResolver
+		BeforeEach(func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).ToNot(HaveOccurred())
+			var ok bool
+			m5, ok = lo.Find(out.InstanceTypes, func(info ec2types.InstanceTypeInfo) bool { return info.InstanceType == "m5.metal" })
+			Expect(ok).To(BeTrue())
+			resolver = instancetype.NewDefaultResolver(fake.DefaultRegion, awsEnv.PricingProvider, awsEnv.UnavailableOfferingsCache, awsEnv.QuotaProvider)
+		})
+		It("should advertise the effective vCPUs when multithreading is disabled", func() {
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{ThreadsPerCore: lo.ToPtr[int32](1)}
+			it := resolver.Resolve(ctx, m5, nil, nodeClass)
+			Expect(it.Capacity.Cpu().Value()).To(BeNumerically("==", 48))
+			Expect(it.Requirements.Get(v1.LabelInstanceCPU).Values()).To(ConsistOf("48"))
+			Expect(it.Requirements.Get(v1.LabelInstanceThreadsPerCore).Values()).To(ConsistOf("1"))
+		})
+		It("should advertise the effective vCPUs when the core count is reduced", func() {
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{CoreCount: lo.ToPtr[int32](8)}
+			it := resolver.Resolve(ctx, m5, nil, nodeClass)
+			Expect(it.Capacity.Cpu().Value()).To(BeNumerically("==", 16))
+			Expect(it.Requirements.Get(v1.LabelInstanceThreadsPerCore).Values()).To(ConsistOf("2"))
+		})
+		It("should not change the capacity of instance types without cpu options", func() {
+			expected := resolver.Resolve(ctx, m5, nil, nodeClass)
+			Expect(expected.Capacity.Cpu().Value()).To(BeNumerically("==", 96))
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{AMDSEVSNP: lo.ToPtr("disabled")}
+			Expect(resolver.Resolve(ctx, m5, nil, nodeClass).Capacity).To(Equal(expected.Capacity))
+		})
+		It("should only offer instance types that support the requested core count and threads per core", func() {
+			instances := fake.MakeInstances()
+			for i := range instances {
+				switch instances[i].InstanceType {
+				case "m5.xlarge":
+					instances[i].VCpuInfo.ValidCores = []int32{1, 2}
+					instances[i].VCpuInfo.ValidThreadsPerCore = []int32{1, 2}
+				case "m5.2xlarge":
+					instances[i].VCpuInfo.ValidCores = []int32{2, 4}
+					instances[i].VCpuInfo.ValidThreadsPerCore = []int32{1, 2}
+				case "c5.xlarge":
+					instances[i].VCpuInfo.ValidCores = []int32{2}
+					instances[i].VCpuInfo.ValidThreadsPerCore = []int32{2}
+				}
+			}
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: instances})
+			awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+				InstanceTypeOfferings: fake.MakeInstanceOfferings(instances),
+			})
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{CoreCount: lo.ToPtr[int32](2), ThreadsPerCore: lo.ToPtr[int32](1)}
+			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lo.Map(instanceTypes, func(it *corecloudprovider.InstanceType, _ int) string { return it.Name })).To(ConsistOf("m5.xlarge", "m5.2xlarge"))
+			for _, it := range instanceTypes {
+				Expect(it.Capacity.Cpu().Value()).To(BeNumerically("==", 2))
+			}
+		})
+		It("should not cache instance types across cpu options changes", func() {
+			key := resolver.CacheKey(nodeClass)
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{ThreadsPerCore: lo.ToPtr[int32](1)}
+			Expect(resolver.CacheKey(nodeClass)).ToNot(Equal(key))
+		})
+	})
+	Context("Burstable Baseline Capacity", func() {
+		var t3, m5 ec2types.InstanceTypeInfo
+		var resolver *instancetype.DefaultResolver
+		BeforeEach(func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).ToNot(HaveOccurred())
+			var ok bool
+			t3, ok = lo.Find(out.InstanceTypes, func(info ec2types.InstanceTypeInfo) bool { return info.InstanceType == "t3.large" })
+			Expect(ok).To(BeTrue())
+			m5, ok = lo.Find(out.InstanceTypes, func(info ec2types.InstanceTypeInfo) bool { return info.InstanceType == "m5.large" })
+			Expect(ok).To(BeTrue())
+			resolver = instancetype.NewDefaultResolver(fake.DefaultRegion, awsEnv.PricingProvider, awsEnv.UnavailableOfferingsCache, awsEnv.QuotaProvider)
+		})
+		It("should advertise the vCPU count of burstable instance types by default", func() {
+			nodeClass.Spec.CreditSpecification = &v1.CreditSpecification{CPUCredits: lo.ToPtr("standard")}
+			it := resolver.Resolve(ctx, t3, nil, nodeClass)
+			Expect(it.Capacity.Cpu().MilliValue()).To(BeNumerically("==", 2000))
+		})
+		It("should advertise the baseline CPU of burstable instance types", func() {
+			nodeClass.Spec.CreditSpecification = &v1.CreditSpecification{BaselineCapacity: lo.ToPtr(true)}
+			it := resolver.Resolve(ctx, t3, nil, nodeClass)
+			Expect(it.Capacity.Cpu().MilliValue()).To(BeNumerically("==", 600))
+		})
+		It("should not change the capacity of instance types that aren't burstable", func() {
+			expected := resolver.Resolve(ctx, m5, nil, nodeClass)
+			nodeClass.Spec.CreditSpecification = &v1.CreditSpecification{BaselineCapacity: lo.ToPtr(true)}
+			Expect(resolver.Resolve(ctx, m5, nil, nodeClass).Capacity).To(Equal(expected.Capacity))
+		})
+		It("should not cache instance types across baseline capacity changes", func() {
+			key := resolver.CacheKey(nodeClass)
+			nodeClass.Spec.CreditSpecification = &v1.CreditSpecification{BaselineCapacity: lo.ToPtr(true)}
+			Expect(resolver.CacheKey(nodeClass)).ToNot(Equal(key))
+		})
+	})
+	Context("GPU Partitioning", func() {
+		var p3, p4d ec2types.InstanceTypeInfo
+		var resolver *instancetype.DefaultResolver
+		BeforeEach(func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).ToNot(HaveOccurred())
+			var ok bool
+			p3, ok = lo.Find(out.InstanceTypes, func(info ec2types.InstanceTypeInfo) bool { return info.InstanceType == "p3.8xlarge" })
+			Expect(ok).To(BeTrue())
+			p4d = p3
+			p4d.InstanceType = "p4d.24xlarge"
+			p4d.GpuInfo = &ec2types.GpuInfo{
+				Gpus: []ec2types.GpuDeviceInfo{{
+					Name:         aws.String("A100"),
+					Manufacturer: aws.String("NVIDIA"),
+					Count:        aws.Int32(8),
+					MemoryInfo:   &ec2types.GpuDeviceMemoryInfo{SizeInMiB: aws.Int32(40960)},
+				}},
+			}
+			resolver = instancetype.NewDefaultResolver(fake.DefaultRegion, awsEnv.PricingProvider, awsEnv.UnavailableOfferingsCache, awsEnv.QuotaProvider)
+		})
+		It("should advertise MIG devices as nvidia.com/gpu with the single strategy", func() {
+			nodeClass.Spec.GPUPartitioning = &v1.GPUPartitioning{MIG: &v1.MIGPartitioning{Profile: "1g.5gb", Strategy: v1.MIGStrategySingle}}
+			it := resolver.Resolve(ctx, p4d, nil, nodeClass)
+			Expect(it.Capacity.Name(v1.ResourceNVIDIAGPU, resource.DecimalSI).Value()).To(BeNumerically("==", 56))
+			Expect(it.Capacity).ToNot(HaveKey(corev1.ResourceName("nvidia.com/mig-1g.5gb")))
+		})
+		It("should advertise MIG devices by profile with the mixed strategy", func() {
+			nodeClass.Spec.GPUPartitioning = &v1.GPUPartitioning{MIG: &v1.MIGPartitioning{Profile: "3g.20gb", Strategy: v1.MIGStrategyMixed}}
+			it := resolver.Resolve(ctx, p4d, nil, nodeClass)
+			Expect(it.Capacity.Name(v1.ResourceNVIDIAGPU, resource.DecimalSI).Value()).To(BeNumerically("==", 0))
+			Expect(it.Capacity.Name("nvidia.com/mig-3g.20gb", resource.DecimalSI).Value()).To(BeNumerically("==", 16))
+		})
+		It("should advertise whole GPUs when the GPU doesn't support the MIG profile", func() {
+			nodeClass.Spec.GPUPartitioning = &v1.GPUPartitioning{MIG: &v1.MIGPartitioning{Profile: "1g.20gb", Strategy: v1.MIGStrategyMixed}}
+			for _, info := range []ec2types.InstanceTypeInfo{p3, p4d} {
+				it := resolver.Resolve(ctx, info, nil, nodeClass)
+				Expect(it.Capacity.Name(v1.ResourceNVIDIAGPU, resource.DecimalSI).Value()).To(Equal(int64(lo.FromPtr(info.GpuInfo.Gpus[0].Count))))
+				Expect(it.Capacity).ToNot(HaveKey(corev1.ResourceName("nvidia.com/mig-1g.20gb")))
+			}
+		})
+		It("should advertise time-sliced replicas of each GPU", func() {
+			nodeClass.Spec.GPUPartitioning = &v1.GPUPartitioning{TimeSlicingReplicas: lo.ToPtr[int32](4)}
+			it := resolver.Resolve(ctx, p3, nil, nodeClass)
+			Expect(it.Capacity.Name(v1.ResourceNVIDIAGPU, resource.DecimalSI).Value()).To(BeNumerically("==", 16))
+		})
+		It("should not change the capacity of instance types without NVIDIA GPUs", func() {
+			out, err := awsEnv.EC2API.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{})
+			Expect(err).ToNot(HaveOccurred())
+			m5, ok := lo.Find(out.InstanceTypes, func(info ec2types.InstanceTypeInfo) bool { return info.InstanceType == "m5.large" })
+			Expect(ok).To(BeTrue())
+			expected := resolver.Resolve(ctx, m5, nil, nodeClass)
+			nodeClass.Spec.GPUPartitioning = &v1.GPUPartitioning{TimeSlicingReplicas: lo.ToPtr[int32](4)}
+			Expect(resolver.Resolve(ctx, m5, nil, nodeClass).Capacity).To(Equal(expected.Capacity))
+		})
+		It("should not cache instance types across GPU partitioning changes", func() {
+			key := resolver.CacheKey(nodeClass)
+			nodeClass.Spec.GPUPartitioning = &v1.GPUPartitioning{TimeSlicingReplicas: lo.ToPtr[int32](4)}
+			Expect(resolver.CacheKey(nodeClass)).ToNot(Equal(key))
+		})
+	})
+	Context("Extended Resources", func() {
+		It("should advertise the extended resources as capacity", func() {
+			nodeClass.Spec.ExtendedResources = corev1.ResourceList{"example.com/fpga": resource.MustParse("2")}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(instanceTypes).ToNot(BeEmpty())
+			for _, it := range instanceTypes {
+				Expect(it.Capacity.Name("example.com/fpga", resource.DecimalSI).Value()).To(BeNumerically("==", 2))
+				allocatable := it.Allocatable()
+				Expect(allocatable.Name("example.com/fpga", resource.DecimalSI).Value()).To(BeNumerically("==", 2))
+			}
+		})
+		It("should replace discovered resources with the same name", func() {
+			nodeClass.Spec.ExtendedResources = corev1.ResourceList{v1.ResourceNVIDIAGPU: resource.MustParse("16")}
+			ExpectApplied(ctx, env.Client, nodeClass)
+			instanceTypes, err := cloudProvider.GetInstanceTypes(ctx, nodePool)
+			Expect(err).ToNot(HaveOccurred())
+			it, ok := lo.Find(instanceTypes, func(it *corecloudprovider.InstanceType) bool { return it.Name == "p3.8xlarge" })
+			Expect(ok).To(BeTrue())
+			Expect(it.Capacity.Name(v1.ResourceNVIDIAGPU, resource.DecimalSI).Value()).To(BeNumerically("==", 16))
+		})
+		It("should launch nodes for pods requesting the extended resources", func() {
+			nodeClass.Spec.ExtendedResources = corev1.ResourceList{"example.com/fpga": resource.MustParse("2")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				ResourceRequirements: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{"example.com/fpga": resource.MustParse("2")},
+					Limits:   corev1.ResourceList{"example.com/fpga": resource.MustParse("2")},
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+		})
+		It("should not launch nodes for pods requesting more of the extended resources than nodes provide", func() {
+			nodeClass.Spec.ExtendedResources = corev1.ResourceList{"example.com/fpga": resource.MustParse("2")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{
+				ResourceRequirements: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{"example.com/fpga": resource.MustParse("3")},
+					Limits:   corev1.ResourceList{"example.com/fpga": resource.MustParse("3")},
+				},
+			})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
+		It("should not cache instance types across extended resource changes", func() {
+			resolver := instancetype.NewDefaultResolver(fake.DefaultRegion, awsEnv.PricingProvider, awsEnv.UnavailableOfferingsCache, awsEnv.QuotaProvider)
+			nodeClass.Spec.ExtendedResources = corev1.ResourceList{"example.com/fpga": resource.MustParse("2")}
+			key := resolver.CacheKey(nodeClass)
+			nodeClass.Spec.ExtendedResources = corev1.ResourceList{"example.com/fpga": resource.MustParse("4")}
+			Expect(resolver.CacheKey(nodeClass)).ToNot(Equal(key))
+		})
+	})
 	Context("Metrics", func() {
 		It("should expose vcpu metrics for instance types", func() {
 			instanceTypes, err := awsEnv.InstanceTypesProvider.List(ctx, nodeClass)
@@ -1135,6 +1414,45 @@ var _ = Describe("InstanceTypeProvider", func() {
 				Expect(it.Overhead.SystemReserved.StorageEphemeral().String()).To(Equal("10Gi"))
 			})
 		})
+		Context("Auto System Reserved Resources", func() {
+			var resolver *instancetype.DefaultResolver
+			BeforeEach(func() {
+				resolver = instancetype.NewDefaultResolver(fake.DefaultRegion, awsEnv.PricingProvider, awsEnv.UnavailableOfferingsCache, awsEnv.QuotaProvider)
+			})
+			It("should compute system reserved resources from the size of the instance type", func() {
+				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{AutoSystemReserved: &v1.AutoSystemReserved{}}
+				it := resolver.Resolve(ctx, info, nil, nodeClass)
+				// 10m for each vCPU, and 100Mi plus 2% of memory
+				Expect(it.Overhead.SystemReserved.Cpu().MilliValue()).To(BeNumerically("==", 10*lo.FromPtr(info.VCpuInfo.DefaultVCpus)))
+				memory := it.Capacity.Memory().Value() / 1024 / 1024
+				Expect(it.Overhead.SystemReserved.Memory().Value()).To(BeNumerically("==", (100+memory*2/100)*1024*1024))
+			})
+			It("should scale system reserved resources by the configured formula", func() {
+				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{AutoSystemReserved: &v1.AutoSystemReserved{
+					CPUMillicoresPerVCPU: lo.ToPtr[int32](50),
+					MemoryPercent:        lo.ToPtr[int32](10),
+				}}
+				it := resolver.Resolve(ctx, info, nil, nodeClass)
+				Expect(it.Overhead.SystemReserved.Cpu().MilliValue()).To(BeNumerically("==", 50*lo.FromPtr(info.VCpuInfo.DefaultVCpus)))
+				memory := it.Capacity.Memory().Value() / 1024 / 1024
+				Expect(it.Overhead.SystemReserved.Memory().Value()).To(BeNumerically("==", (100+memory*10/100)*1024*1024))
+			})
+			It("should prefer system reserved values when specified", func() {
+				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{
+					AutoSystemReserved: &v1.AutoSystemReserved{},
+					SystemReserved:     map[string]string{string(corev1.ResourceMemory): "1Gi"},
+				}
+				it := resolver.Resolve(ctx, info, nil, nodeClass)
+				Expect(it.Overhead.SystemReserved.Cpu().MilliValue()).To(BeNumerically("==", 10*lo.FromPtr(info.VCpuInfo.DefaultVCpus)))
+				Expect(it.Overhead.SystemReserved.Memory().String()).To(Equal("1Gi"))
+			})
+			It("should not cache instance types across autoSystemReserved changes", func() {
+				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{AutoSystemReserved: &v1.AutoSystemReserved{}}
+				key := resolver.CacheKey(nodeClass)
+				nodeClass.Spec.Kubelet.AutoSystemReserved.MemoryPercent = lo.ToPtr[int32](5)
+				Expect(resolver.CacheKey(nodeClass)).ToNot(Equal(key))
+			})
+		})
 		Context("Kube Reserved Resources", func() {
 			It("should use defaults when no kubelet is specified", func() {
 				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{}
@@ -37,6 +37,7 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
 
 	"sigs.k8s.io/karpenter/pkg/cloudprovider"
 	"sigs.k8s.io/karpenter/pkg/scheduling"
@@ -69,13 +70,15 @@ type DefaultResolver struct {
 	region               string
 	pricingProvider      pricing.Provider
 	unavailableOfferings *awscache.UnavailableOfferings
+	quotaProvider        quota.Provider
 }
 
-func NewDefaultResolver(region string, pricingProvider pricing.Provider, unavailableOfferingsCache *awscache.UnavailableOfferings) *DefaultResolver {
+func NewDefaultResolver(region string, pricingProvider pricing.Provider, unavailableOfferingsCache *awscache.UnavailableOfferings, quotaProvider quota.Provider) *DefaultResolver {
 	return &DefaultResolver{
 		region:               region,
 		pricingProvider:      pricingProvider,
 		unavailableOfferings: unavailableOfferingsCache,
+		quotaProvider:        quotaProvider,
 	}
 }
 
@@ -86,12 +89,24 @@ func (d *DefaultResolver) CacheKey(nodeClass *v1.EC2NodeClass) string {
 	}
 	kcHash, _ := hashstructure.Hash(kc, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
 	blockDeviceMappingsHash, _ := hashstructure.Hash(nodeClass.Spec.BlockDeviceMappings, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})
-	return fmt.Sprintf("%016x-%016x-%s-%s-%d",
+	gpuPartitioningHash, _ := hashstructure.Hash(nodeClass.Spec.GPUPartitioning, hashstructure.FormatV2, nil)
+	cpuOptionsHash, _ := hashstructure.Hash(nodeClass.Spec.CPUOptions, hashstructure.FormatV2, nil)
+	// Quantities are hashed by their string representation since their values are held in unexported fields
+	extendedResourcesHash, _ := hashstructure.Hash(lo.MapValues(nodeClass.Spec.ExtendedResources, func(q resource.Quantity, _ corev1.ResourceName) string {
+		return q.String()
+	}), hashstructure.FormatV2, nil)
+	return fmt.Sprintf("%016x-%016x-%016x-%016x-%016x-%s-%s-%t-%t-%d-%d",
 		kcHash,
 		blockDeviceMappingsHash,
+		gpuPartitioningHash,
+		cpuOptionsHash,
+		extendedResourcesHash,
 		lo.FromPtr((*string)(nodeClass.Spec.InstanceStorePolicy)),
 		nodeClass.AMIFamily(),
+		nodeClass.EnclavesEnabled(),
+		nodeClass.BaselineCapacityEnabled(),
 		d.unavailableOfferings.SeqNum,
+		d.quotaProvider.SeqNum(),
 	)
 }
 
@@ -104,8 +119,24 @@ func (d *DefaultResolver) Resolve(ctx context.Context, info ec2types.InstanceTyp
 	if nodeClass.Spec.Kubelet != nil {
 		kc = nodeClass.Spec.Kubelet
 	}
-	return NewInstanceType(ctx, info, d.region, nodeClass.Spec.BlockDeviceMappings, nodeClass.Spec.InstanceStorePolicy, kc.MaxPods, kc.PodsPerCore, kc.KubeReserved,
+	effectiveInfo := withCPUOptions(info, nodeClass.Spec.CPUOptions)
+	it := NewInstanceType(ctx, effectiveInfo, d.region, nodeClass.Spec.BlockDeviceMappings, nodeClass.Spec.InstanceStorePolicy, kc.MaxPods, kc.PodsPerCore, kc.KubeReserved,
 		kc.SystemReserved, kc.EvictionHard, kc.EvictionSoft, nodeClass.AMIFamily(), d.createOfferings(ctx, info, zoneData))
+	if nodeClass.Spec.GPUPartitioning != nil {
+		it.Capacity = lo.Assign(it.Capacity, partitionedNVIDIAGPUs(info, nodeClass.Spec.GPUPartitioning))
+	}
+	if nodeClass.BaselineCapacityEnabled() {
+		if baseline, ok := baselineCPU(effectiveInfo); ok {
+			it.Capacity[corev1.ResourceCPU] = *baseline
+		}
+	}
+	if kc.AutoSystemReserved != nil {
+		it.Overhead.SystemReserved = lo.Assign(autoSystemReservedResources(ctx, effectiveInfo, kc.AutoSystemReserved), systemReservedResources(kc.SystemReserved))
+	}
+	if len(nodeClass.Spec.ExtendedResources) > 0 {
+		it.Capacity = lo.Assign(it.Capacity, nodeClass.Spec.ExtendedResources)
+	}
+	return it
 }
 
 // createOfferings creates a set of mutually exclusive offerings for a given instance type. This provider maintains an
@@ -138,7 +169,10 @@ func (d *DefaultResolver) createOfferings(ctx context.Context, instanceType ec2t
 				log.FromContext(ctx).WithValues("capacity-type", capacityType, "instance-type", instanceType.InstanceType).Error(fmt.Errorf("received unknown capacity type"), "failed parsing offering")
 				continue
 			}
-			available := !isUnavailable && ok && zone.Available
+			// exclude any offerings that would push the account over its vCPU quota for the instance type's quota class
+			remaining, hasQuota := d.quotaProvider.Remaining(instanceType.InstanceType, string(capacityType))
+			exceedsQuota := hasQuota && remaining < float64(lo.FromPtr(instanceType.VCpuInfo.DefaultVCpus))
+			available := !isUnavailable && !exceedsQuota && ok && zone.Available
 			offering := cloudprovider.Offering{
 				Requirements: scheduling.NewRequirements(
 					scheduling.NewRequirement(karpv1.CapacityTypeLabelKey, corev1.NodeSelectorOpIn, string(capacityType)),
@@ -197,6 +231,7 @@ func computeRequirements(info ec2types.InstanceTypeInfo, offerings cloudprovider
 		})...),
 		// Well Known to AWS
 		scheduling.NewRequirement(v1.LabelInstanceCPU, corev1.NodeSelectorOpIn, fmt.Sprint(lo.FromPtr(info.VCpuInfo.DefaultVCpus))),
+		scheduling.NewRequirement(v1.LabelInstanceThreadsPerCore, corev1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1.LabelInstanceCPUManufacturer, corev1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1.LabelInstanceCPUSustainedClockSpeedMhz, corev1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1.LabelInstanceMemory, corev1.NodeSelectorOpIn, fmt.Sprint(lo.FromPtr(info.MemoryInfo.SizeInMiB))),
@@ -216,7 +251,12 @@ func computeRequirements(info ec2types.InstanceTypeInfo, offerings cloudprovider
 		scheduling.NewRequirement(v1.LabelInstanceAcceleratorCount, corev1.NodeSelectorOpDoesNotExist),
 		scheduling.NewRequirement(v1.LabelInstanceHypervisor, corev1.NodeSelectorOpIn, string(info.Hypervisor)),
 		scheduling.NewRequirement(v1.LabelInstanceEncryptionInTransitSupported, corev1.NodeSelectorOpIn, fmt.Sprint(aws.ToBool(info.NetworkInfo.EncryptionInTransitSupported))),
+		scheduling.NewRequirement(v1.LabelInstanceNitroEnclavesSupported, corev1.NodeSelectorOpIn, fmt.Sprint(nitroEnclavesSupported(info))),
+		scheduling.NewRequirement(v1.LabelInstanceAMDSEVSNPSupported, corev1.NodeSelectorOpIn, fmt.Sprint(amdSEVSNPSupported(info))),
 	)
+	if threads := threadsPerCore(info); threads > 0 {
+		requirements[v1.LabelInstanceThreadsPerCore].Insert(fmt.Sprint(threads))
+	}
 	// Only add zone-id label when available in offerings. It may not be available if a user has upgraded from a
 	// previous version of Karpenter w/o zone-id support and the nodeclass subnet status has not yet updated.
 	if zoneIDs := lo.FilterMap(offerings.Available(), func(o cloudprovider.Offering, _ int) (string, bool) {
@@ -285,6 +325,72 @@ func computeRequirements(info ec2types.InstanceTypeInfo, offerings cloudprovider
 	return requirements
 }
 
+func nitroEnclavesSupported(info ec2types.InstanceTypeInfo) bool {
+	return info.NitroEnclavesSupport == ec2types.NitroEnclavesSupportSupported
+}
+
+func amdSEVSNPSupported(info ec2types.InstanceTypeInfo) bool {
+	return info.ProcessorInfo != nil && lo.Contains(info.ProcessorInfo.SupportedFeatures, ec2types.SupportedAdditionalProcessorFeatureAmdSevSnp)
+}
+
+// threadsPerCore returns the number of threads that run on each core of the instance type, or 0 if it's unknown
+func threadsPerCore(info ec2types.InstanceTypeInfo) int32 {
+	if info.VCpuInfo == nil {
+		return 0
+	}
+	if info.VCpuInfo.DefaultThreadsPerCore != nil {
+		return lo.FromPtr(info.VCpuInfo.DefaultThreadsPerCore)
+	}
+	if lo.FromPtr(info.VCpuInfo.DefaultCores) == 0 {
+		return 0
+	}
+	return lo.FromPtr(info.VCpuInfo.DefaultVCpus) / lo.FromPtr(info.VCpuInfo.DefaultCores)
+}
+
+// withCPUOptions returns a copy of the instance type info whose vCPU info reflects the core count and threads per core that
+// instances are launched with, so that the capacity and overhead of the instance type are computed from the effective vCPUs
+func withCPUOptions(info ec2types.InstanceTypeInfo, cpuOptions *v1.CPUOptions) ec2types.InstanceTypeInfo {
+	if cpuOptions == nil || (cpuOptions.CoreCount == nil && cpuOptions.ThreadsPerCore == nil) || info.VCpuInfo == nil || info.VCpuInfo.DefaultCores == nil {
+		return info
+	}
+	vcpuInfo := *info.VCpuInfo
+	vcpuInfo.DefaultCores = lo.ToPtr(lo.FromPtrOr(cpuOptions.CoreCount, lo.FromPtr(info.VCpuInfo.DefaultCores)))
+	vcpuInfo.DefaultThreadsPerCore = lo.ToPtr(lo.FromPtrOr(cpuOptions.ThreadsPerCore, threadsPerCore(info)))
+	vcpuInfo.DefaultVCpus = lo.ToPtr(lo.FromPtr(vcpuInfo.DefaultCores) * lo.FromPtr(vcpuInfo.DefaultThreadsPerCore))
+	info.VCpuInfo = &vcpuInfo
+	return info
+}
+
+// supportsLaunchOptions returns true if the instance type supports the Nitro Enclaves, CPU, confidential computing and instance storage options
+// that the EC2NodeClass launches instances with
+func supportsLaunchOptions(info ec2types.InstanceTypeInfo, nodeClass *v1.EC2NodeClass) bool {
+	if nodeClass.EnclavesEnabled() && !nitroEnclavesSupported(info) {
+		return false
+	}
+	if nodeClass.AMDSEVSNPEnabled() && !amdSEVSNPSupported(info) {
+		return false
+	}
+	// Nodes would fall back to writing their state to EBS on instance types without instance storage
+	if lo.FromPtr(nodeClass.Spec.InstanceStorePolicy) == v1.InstanceStorePolicyEphemeralOS && !nvmeInstanceStorageSupported(info) {
+		return false
+	}
+	if cpuOptions := nodeClass.Spec.CPUOptions; cpuOptions != nil {
+		// Instance types that don't support customizing their CPU options don't report any valid core counts or threads per core
+		if cpuOptions.CoreCount != nil && (info.VCpuInfo == nil || !lo.Contains(info.VCpuInfo.ValidCores, lo.FromPtr(cpuOptions.CoreCount))) {
+			return false
+		}
+		if cpuOptions.ThreadsPerCore != nil && (info.VCpuInfo == nil || !lo.Contains(info.VCpuInfo.ValidThreadsPerCore, lo.FromPtr(cpuOptions.ThreadsPerCore))) {
+			return false
+		}
+	}
+	return true
+}
+
+func nvmeInstanceStorageSupported(info ec2types.InstanceTypeInfo) bool {
+	return info.InstanceStorageInfo != nil && lo.FromPtr(info.InstanceStorageInfo.TotalSizeInGB) > 0 &&
+		info.InstanceStorageInfo.NvmeSupport != ec2types.EphemeralNvmeSupportUnsupported
+}
+
 func getOS(info ec2types.InstanceTypeInfo, amiFamily amifamily.AMIFamily) []string {
 	if _, ok := amiFamily.(*amifamily.Windows); ok {
 		if getArchitecture(info) == karpv1.ArchitectureAmd64 {
@@ -328,7 +434,34 @@ func cpu(info ec2types.InstanceTypeInfo) *resource.Quantity {
 	return resources.Quantity(fmt.Sprint(*info.VCpuInfo.DefaultVCpus))
 }
 
+// burstableBaselines is the baseline performance of each vCPU of burstable instance types, as a fraction of a full vCPU.
+// Baselines aren't returned by DescribeInstanceTypes, see https://docs.aws.amazon.com/ec2/latest/instancetypes/gp.html#gp_cpu-credits
+var burstableBaselines = map[string]map[string]float64{
+	"t2":  {"nano": 0.05, "micro": 0.10, "small": 0.20, "medium": 0.20, "large": 0.30, "xlarge": 0.225, "2xlarge": 0.16875},
+	"t3":  {"nano": 0.05, "micro": 0.10, "small": 0.20, "medium": 0.20, "large": 0.30, "xlarge": 0.40, "2xlarge": 0.40},
+	"t3a": {"nano": 0.05, "micro": 0.10, "small": 0.20, "medium": 0.20, "large": 0.30, "xlarge": 0.40, "2xlarge": 0.40},
+	"t4g": {"nano": 0.05, "micro": 0.10, "small": 0.20, "medium": 0.20, "large": 0.30, "xlarge": 0.40, "2xlarge": 0.40},
+}
+
+// baselineCPU returns the CPU that a burstable instance type can sustain without spending CPU credits
+func baselineCPU(info ec2types.InstanceTypeInfo) (*resource.Quantity, bool) {
+	if !lo.FromPtr(info.BurstablePerformanceSupported) {
+		return nil, false
+	}
+	family, size, _ := strings.Cut(string(info.InstanceType), ".")
+	baseline, ok := burstableBaselines[family][size]
+	if !ok {
+		return nil, false
+	}
+	return resource.NewMilliQuantity(int64(float64(lo.FromPtr(info.VCpuInfo.DefaultVCpus))*baseline*1000), resource.DecimalSI), true
+}
+
 func memory(ctx context.Context, info ec2types.InstanceTypeInfo) *resource.Quantity {
+	return memoryWithOverhead(info, options.FromContext(ctx).VMMemoryOverheadPercent)
+}
+
+// memoryWithOverhead returns the memory of the instance type less a VM overhead, given as a fraction of its memory
+func memoryWithOverhead(info ec2types.InstanceTypeInfo, overheadPercent float64) *resource.Quantity {
 	sizeInMib := *info.MemoryInfo.SizeInMiB
 	// Gravitons have an extra 64 MiB of cma reserved memory that we can't use
 	if len(info.ProcessorInfo.SupportedArchitectures) > 0 && info.ProcessorInfo.SupportedArchitectures[0] == "arm64" {
@@ -336,14 +469,14 @@ func memory(ctx context.Context, info ec2types.InstanceTypeInfo) *resource.Quant
 	}
 	mem := resources.Quantity(fmt.Sprintf("%dMi", sizeInMib))
 	// Account for VM overhead in calculation
-	mem.Sub(resource.MustParse(fmt.Sprintf("%dMi", int64(math.Ceil(float64(mem.Value())*options.FromContext(ctx).VMMemoryOverheadPercent/1024/1024)))))
+	mem.Sub(resource.MustParse(fmt.Sprintf("%dMi", int64(math.Ceil(float64(mem.Value())*overheadPercent/1024/1024)))))
 	return mem
 }
 
 // Setting ephemeral-storage to be either the default value, what is defined in blockDeviceMappings, or the combined size of local store volumes.
 func ephemeralStorage(info ec2types.InstanceTypeInfo, amiFamily amifamily.AMIFamily, blockDeviceMappings []*v1.BlockDeviceMapping, instanceStorePolicy *v1.InstanceStorePolicy) *resource.Quantity {
 	// If local store disks have been configured for node ephemeral-storage, use the total size of the disks.
-	if lo.FromPtr(instanceStorePolicy) == v1.InstanceStorePolicyRAID0 {
+	if lo.FromPtr(instanceStorePolicy).UsesRAID0() {
 		if info.InstanceStorageInfo != nil && info.InstanceStorageInfo.TotalSizeInGB != nil {
 			return resources.Quantity(fmt.Sprintf("%dG", *info.InstanceStorageInfo.TotalSizeInGB))
 		}
@@ -353,6 +486,10 @@ func ephemeralStorage(info ec2types.InstanceTypeInfo, amiFamily amifamily.AMIFam
 		if blockDeviceMapping, ok := lo.Find(blockDeviceMappings, func(bdm *v1.BlockDeviceMapping) bool {
 			return bdm.RootVolume
 		}); ok && blockDeviceMapping.EBS.VolumeSize != nil {
+			// Auto-sized root volumes are sized for each launch, so the instance type advertises the largest size that it can be launched with
+			if blockDeviceMapping.EBS.MaxVolumeSize != nil {
+				return blockDeviceMapping.EBS.MaxVolumeSize
+			}
 			return blockDeviceMapping.EBS.VolumeSize
 		}
 		switch amiFamily.(type) {
@@ -400,6 +537,53 @@ func nvidiaGPUs(info ec2types.InstanceTypeInfo) *resource.Quantity {
 	return resources.Quantity(fmt.Sprint(count))
 }
 
+// migProfiles is the number of GPU instances of each MIG profile that fit on a single GPU, keyed by the GPU's name and memory
+// in MiB as reported by EC2. GPUs which aren't listed don't support MIG.
+var migProfiles = map[string]map[string]int64{
+	"A100-40960":  {"1g.5gb": 7, "1g.10gb": 4, "2g.10gb": 3, "3g.20gb": 2, "4g.20gb": 1, "7g.40gb": 1},
+	"A100-81920":  {"1g.10gb": 7, "1g.20gb": 4, "2g.20gb": 3, "3g.40gb": 2, "4g.40gb": 1, "7g.80gb": 1},
+	"H100-81920":  {"1g.10gb": 7, "1g.20gb": 4, "2g.20gb": 3, "3g.40gb": 2, "4g.40gb": 1, "7g.80gb": 1},
+	"H200-144384": {"1g.18gb": 7, "1g.35gb": 4, "2g.35gb": 3, "3g.71gb": 2, "4g.71gb": 1, "7g.141gb": 1},
+}
+
+// partitionedNVIDIAGPUs returns the resources that the NVIDIA device plugin exposes for the instance type's GPUs once they
+// have been partitioned. GPUs that don't support the MIG profile are exposed as whole GPUs.
+func partitionedNVIDIAGPUs(info ec2types.InstanceTypeInfo, partitioning *v1.GPUPartitioning) corev1.ResourceList {
+	var gpus, migDevices int64
+	if info.GpuInfo != nil {
+		for _, gpu := range info.GpuInfo.Gpus {
+			if lo.FromPtr(gpu.Manufacturer) != "NVIDIA" {
+				continue
+			}
+			count := int64(lo.FromPtr(gpu.Count))
+			switch {
+			case partitioning.TimeSlicingReplicas != nil:
+				gpus += count * int64(lo.FromPtr(partitioning.TimeSlicingReplicas))
+			case partitioning.MIG != nil:
+				var memory int32
+				if gpu.MemoryInfo != nil {
+					memory = lo.FromPtr(gpu.MemoryInfo.SizeInMiB)
+				}
+				if n, ok := migProfiles[fmt.Sprintf("%s-%d", lo.FromPtr(gpu.Name), memory)][partitioning.MIG.Profile]; ok {
+					migDevices += count * n
+				} else {
+					gpus += count
+				}
+			default:
+				gpus += count
+			}
+		}
+	}
+	if partitioning.MIG == nil || partitioning.MIG.Strategy != v1.MIGStrategyMixed {
+		return corev1.ResourceList{v1.ResourceNVIDIAGPU: *resources.Quantity(fmt.Sprint(gpus + migDevices))}
+	}
+	resourceList := corev1.ResourceList{v1.ResourceNVIDIAGPU: *resources.Quantity(fmt.Sprint(gpus))}
+	if migDevices > 0 {
+		resourceList[corev1.ResourceName(v1.ResourceNVIDIAMIGPrefix+partitioning.MIG.Profile)] = *resources.Quantity(fmt.Sprint(migDevices))
+	}
+	return resourceList
+}
+
 func amdGPUs(info ec2types.InstanceTypeInfo) *resource.Quantity {
 	count := int32(0)
 	if info.GpuInfo != nil {
@@ -483,6 +667,17 @@ func systemReservedResources(systemReserved map[string]string) corev1.ResourceLi
 	})
 }
 
+// autoSystemReservedResources reserves the configured CPU for each vCPU of the instance type, along with a fixed 100Mi and
+// the configured percentage of its memory
+func autoSystemReservedResources(ctx context.Context, info ec2types.InstanceTypeInfo, autoSystemReserved *v1.AutoSystemReserved) corev1.ResourceList {
+	cpuMillicores := cpu(info).Value() * int64(lo.FromPtrOr(autoSystemReserved.CPUMillicoresPerVCPU, 10))
+	memoryMi := 100 + memory(ctx, info).Value()/1024/1024*int64(lo.FromPtrOr(autoSystemReserved.MemoryPercent, 2))/100
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(fmt.Sprintf("%dm", cpuMillicores)),
+		corev1.ResourceMemory: resource.MustParse(fmt.Sprintf("%dMi", memoryMi)),
+	}
+}
+
 func kubeReservedResources(cpus, pods, eniLimitedPods *resource.Quantity, amiFamily amifamily.AMIFamily, kubeReserved map[string]string) corev1.ResourceList {
 	if amiFamily.FeatureFlags().UsesENILimitedMemoryOverhead {
 		pods = eniLimitedPods
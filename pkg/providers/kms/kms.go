@@ -0,0 +1,174 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+// ServiceLinkedRoleName is the role that EC2 Fleet uses to create encrypted volumes on behalf of the caller
+const ServiceLinkedRoleName = "AWSServiceRoleForEC2Fleet"
+
+const serviceLinkedRoleSuffix = ":role/aws-service-role/ec2fleet.amazonaws.com/" + ServiceLinkedRoleName
+
+// requiredActions are the actions that the service-linked role needs to launch instances with volumes encrypted by a key
+var requiredActions = []string{
+	"kms:CreateGrant",
+	"kms:Decrypt",
+	"kms:DescribeKey",
+	"kms:GenerateDataKeyWithoutPlaintext",
+	"kms:ReEncryptFrom",
+	"kms:ReEncryptTo",
+}
+
+type Provider interface {
+	// PermitsServiceLinkedRole returns false if the key policy of the passed key is known not to allow the EC2 Fleet
+	// service-linked role to encrypt volumes with it. Keys whose policy can't be read or evaluated are assumed to be permitted.
+	PermitsServiceLinkedRole(context.Context, string) bool
+}
+
+type DefaultProvider struct {
+	kmsapi sdk.KMSAPI
+	cache  *cache.Cache
+}
+
+func NewDefaultProvider(kmsapi sdk.KMSAPI, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		kmsapi: kmsapi,
+		cache:  cache,
+	}
+}
+
+func (p *DefaultProvider) PermitsServiceLinkedRole(ctx context.Context, keyID string) bool {
+	if permitted, ok := p.cache.Get(keyID); ok {
+		return permitted.(bool)
+	}
+	key, err := p.kmsapi.DescribeKey(ctx, &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return p.skipValidation(ctx, keyID, fmt.Errorf("describing key, %w", err))
+	}
+	// AWS managed keys (e.g. aws/ebs) can be used by any principal in the account through the service they belong to
+	if key.KeyMetadata.KeyManager != kmstypes.KeyManagerTypeCustomer {
+		p.cache.SetDefault(keyID, true)
+		return true
+	}
+	out, err := p.kmsapi.GetKeyPolicy(ctx, &kms.GetKeyPolicyInput{KeyId: key.KeyMetadata.KeyId, PolicyName: aws.String("default")})
+	if err != nil {
+		return p.skipValidation(ctx, keyID, fmt.Errorf("getting key policy, %w", err))
+	}
+	permitted, err := permitsServiceLinkedRole(aws.ToString(out.Policy))
+	if err != nil {
+		return p.skipValidation(ctx, keyID, fmt.Errorf("evaluating key policy, %w", err))
+	}
+	p.cache.SetDefault(keyID, permitted)
+	return permitted
+}
+
+// skipValidation assumes that the key is set up correctly when its policy can't be read or evaluated. This is the case for
+// keys owned by other accounts, since GetKeyPolicy can't be called across accounts, when the controller hasn't been granted
+// kms:DescribeKey and kms:GetKeyPolicy, and for policies that the check doesn't understand.
+func (p *DefaultProvider) skipValidation(ctx context.Context, keyID string, err error) bool {
+	log.FromContext(ctx).WithValues("key", keyID).V(1).Info(fmt.Sprintf("unable to verify key policy, skipping validation, %s", err))
+	p.cache.SetDefault(keyID, true)
+	return true
+}
+
+type policy struct {
+	Statement []statement `json:"Statement"`
+}
+
+type statement struct {
+	Effect       string          `json:"Effect"`
+	Principal    json.RawMessage `json:"Principal"`
+	NotPrincipal json.RawMessage `json:"NotPrincipal"`
+	Action       stringOrSlice   `json:"Action"`
+	NotAction    stringOrSlice   `json:"NotAction"`
+}
+
+// errUnverifiable is returned for key policies whose effect on the service-linked role can't be determined from the Allow
+// statements that name it
+var errUnverifiable = errors.New("key policy uses NotPrincipal or NotAction")
+
+// stringOrSlice unmarshals policy elements that may either be a single string or a list of strings
+type stringOrSlice []string
+
+func (s *stringOrSlice) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = []string{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*s = multiple
+	return nil
+}
+
+// permitsServiceLinkedRole checks that the Allow statements of the key policy that name the service-linked role (or any
+// principal) grant all of the required actions between them. Conditions are not evaluated, since the
+// kms:GrantIsForAWSResource condition that is typically attached to kms:CreateGrant is always satisfied by EC2. Grants
+// aren't part of the key policy, so a key that is only shared with the role through a grant isn't permitted by this check.
+func permitsServiceLinkedRole(document string) (bool, error) {
+	p := policy{}
+	if err := json.Unmarshal([]byte(document), &p); err != nil {
+		return false, err
+	}
+	var actions []string
+	for _, s := range p.Statement {
+		if len(s.NotPrincipal) != 0 || len(s.NotAction) != 0 {
+			return false, errUnverifiable
+		}
+		if s.Effect != "Allow" || !principalMatches(s.Principal) {
+			continue
+		}
+		actions = append(actions, s.Action...)
+	}
+	return lo.EveryBy(requiredActions, func(required string) bool {
+		return lo.ContainsBy(actions, func(action string) bool {
+			matched, _ := path.Match(strings.ToLower(action), strings.ToLower(required))
+			return matched
+		})
+	}), nil
+}
+
+func principalMatches(raw json.RawMessage) bool {
+	var wildcard string
+	if err := json.Unmarshal(raw, &wildcard); err == nil {
+		return wildcard == "*"
+	}
+	principals := map[string]stringOrSlice{}
+	if err := json.Unmarshal(raw, &principals); err != nil {
+		return false
+	}
+	return lo.ContainsBy(principals["AWS"], func(arn string) bool {
+		return arn == "*" || strings.HasSuffix(arn, serviceLinkedRoleSuffix)
+	})
+}
@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchrole
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/mitchellh/hashstructure/v2"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+type Provider interface {
+	// EC2API returns an EC2 client which makes requests with the credentials of a role session for the EC2NodeClass' launch
+	// role. The session is tagged with the NodePool and EC2NodeClass so that requests can be attributed to them.
+	EC2API(context.Context, *v1.EC2NodeClass, string) (sdk.EC2API, error)
+}
+
+type DefaultProvider struct {
+	cfg       aws.Config
+	stsapi    sdk.STSAPI
+	newEC2API func(aws.Config) sdk.EC2API
+	cache     *cache.Cache
+}
+
+// NewDefaultProvider takes the function that constructs EC2 clients so that clients for each role session share the
+// configuration of Karpenter's own clients, e.g. its region, retryer and endpoints
+func NewDefaultProvider(cfg aws.Config, stsapi sdk.STSAPI, newEC2API func(aws.Config) sdk.EC2API, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		cfg:       cfg,
+		stsapi:    stsapi,
+		newEC2API: newEC2API,
+		cache:     cache,
+	}
+}
+
+type session struct {
+	credentials *aws.CredentialsCache
+	ec2api      sdk.EC2API
+}
+
+func (p *DefaultProvider) EC2API(ctx context.Context, nodeClass *v1.EC2NodeClass, nodePoolName string) (sdk.EC2API, error) {
+	tags := lo.Assign(nodeClass.Spec.LaunchRole.SessionTags, map[string]string{
+		karpv1.NodePoolLabelKey: nodePoolName,
+		v1.LabelNodeClass:       nodeClass.Name,
+	})
+	key := fmt.Sprint(lo.Must(hashstructure.Hash([]any{nodeClass.Spec.LaunchRole.ARN, tags, nodeClass.Spec.LaunchRole.SourceIdentity}, hashstructure.FormatV2, nil)))
+	s, ok := p.cache.Get(key)
+	if !ok {
+		credentials := aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(p.stsapi, nodeClass.Spec.LaunchRole.ARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName(nodePoolName)
+			o.SourceIdentity = nodeClass.Spec.LaunchRole.SourceIdentity
+			o.Tags = lo.MapToSlice(tags, func(k, v string) ststypes.Tag { return ststypes.Tag{Key: aws.String(k), Value: aws.String(v)} })
+		}))
+		cfg := p.cfg.Copy()
+		cfg.Credentials = credentials
+		s = &session{credentials: credentials, ec2api: p.newEC2API(cfg)}
+		p.cache.SetDefault(key, s)
+	}
+	// Credentials are retrieved before they're used so that a role which can't be assumed fails the launch before any other
+	// requests are made. Credentials are only refreshed once they're about to expire.
+	if _, err := s.(*session).credentials.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("assuming launch role %q, %w", nodeClass.Spec.LaunchRole.ARN, err)
+	}
+	return s.(*session).ec2api, nil
+}
+
+// sessionName returns the role session name, which is recorded in CloudTrail along with the session's tags. Role session
+// names are limited to 64 characters.
+func sessionName(nodePoolName string) string {
+	name := fmt.Sprintf("karpenter-%s", nodePoolName)
+	return name[:min(len(name), 64)]
+}
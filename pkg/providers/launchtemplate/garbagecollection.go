@@ -0,0 +1,156 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+const (
+	// LaunchTemplateQuota is the maximum number of launch templates per region, which isn't adjustable through Service Quotas
+	LaunchTemplateQuota = 5000
+	// launchTemplateQuotaWarningThreshold is the fraction of the launch template quota above which we warn that launch
+	// template creation will soon fail
+	launchTemplateQuotaWarningThreshold = 0.8
+	// launchTemplateIDTagKey is the tag that EC2 adds to instances launched from a launch template
+	launchTemplateIDTagKey = "aws:ec2launchtemplate:id"
+)
+
+// GarbageCollect deletes the launch templates created by Karpenter for the cluster which are older than the passed TTL and
+// aren't in use, and reports the number of launch templates in the region against the launch template quota. A launch
+// template is in use if it's in the launch template cache or a pending or running instance was launched from it. The
+// launch template cache already deletes templates as they expire, so this catches templates that were leaked by a failed
+// deletion or a controller restart. Deletion is disabled if the TTL is zero.
+func (p *DefaultProvider) GarbageCollect(ctx context.Context, ttl time.Duration) error {
+	launchTemplates, err := p.listLaunchTemplates(ctx)
+	if err != nil {
+		return err
+	}
+	utilization := float64(len(launchTemplates)) / LaunchTemplateQuota
+	LaunchTemplateQuotaUsage.Set(float64(len(launchTemplates)), map[string]string{})
+	LaunchTemplateQuotaUtilization.Set(utilization, map[string]string{})
+	// Only warn when the utilization crosses a whole percentage so that we don't log on every reconcile
+	if utilization >= launchTemplateQuotaWarningThreshold && p.cm.HasChanged("launch-template-quota-utilization", int(utilization*100)) {
+		log.FromContext(ctx).WithValues("count", len(launchTemplates), "quota", LaunchTemplateQuota).
+			Info("launch templates in the region are approaching the quota, launch template creation will fail once the quota is reached")
+	}
+	if ttl == 0 {
+		return nil
+	}
+	candidates := lo.Filter(launchTemplates, func(lt ec2types.LaunchTemplate, _ int) bool {
+		return isManaged(ctx, lt) && p.clk.Since(aws.ToTime(lt.CreateTime)) > ttl
+	})
+	if len(candidates) == 0 {
+		return nil
+	}
+	inUse, err := p.launchTemplatesInUse(ctx)
+	if err != nil {
+		return err
+	}
+	p.Lock()
+	defer p.Unlock()
+	var deleted []*string
+	var errs error
+	for _, lt := range candidates {
+		if _, ok := p.cache.Get(aws.ToString(lt.LaunchTemplateName)); ok || inUse.Has(aws.ToString(lt.LaunchTemplateId)) {
+			continue
+		}
+		if _, err := p.ec2api.DeleteLaunchTemplate(ctx, &ec2.DeleteLaunchTemplateInput{LaunchTemplateId: lt.LaunchTemplateId}); awserrors.IgnoreNotFound(err) != nil {
+			errs = multierr.Append(errs, fmt.Errorf("deleting launch template %s, %w", aws.ToString(lt.LaunchTemplateName), err))
+			continue
+		}
+		deleted = append(deleted, lt.LaunchTemplateName)
+	}
+	if len(deleted) > 0 {
+		LaunchTemplatesGarbageCollected.Add(float64(len(deleted)), map[string]string{})
+		log.FromContext(ctx).WithValues("launchTemplates", utils.PrettySlice(deleted, 5), "count", len(deleted)).V(1).Info("garbage collected launch templates")
+	}
+	return errs
+}
+
+// isManaged returns whether the launch template was created by Karpenter for the cluster. Other tools may tag their launch
+// templates with the cluster name, so the launch template must also be tagged with its EC2NodeClass and named like the
+// launch templates that Karpenter creates, with or without the resource name prefix.
+func isManaged(ctx context.Context, lt ec2types.LaunchTemplate) bool {
+	tags := lo.SliceToMap(lt.Tags, func(t ec2types.Tag) (string, string) { return aws.ToString(t.Key), aws.ToString(t.Value) })
+	if tags[v1.EKSClusterNameTagKey] != options.FromContext(ctx).ClusterName || tags[v1.NodeClassTagKey] == "" {
+		return false
+	}
+	name := aws.ToString(lt.LaunchTemplateName)
+	return strings.HasPrefix(name, v1.LaunchTemplateNamePrefix+"/") ||
+		strings.HasPrefix(name, options.FromContext(ctx).ResourceNamePrefix+v1.LaunchTemplateNamePrefix+"/")
+}
+
+// listLaunchTemplates returns every launch template in the region, since the quota applies to the region as a whole
+func (p *DefaultProvider) listLaunchTemplates(ctx context.Context) ([]ec2types.LaunchTemplate, error) {
+	var launchTemplates []ec2types.LaunchTemplate
+	paginator := ec2.NewDescribeLaunchTemplatesPaginator(p.ec2api, &ec2.DescribeLaunchTemplatesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing launch templates, %w", err)
+		}
+		launchTemplates = append(launchTemplates, page.LaunchTemplates...)
+	}
+	return launchTemplates, nil
+}
+
+// launchTemplatesInUse returns the IDs of the launch templates that the cluster's pending and running instances were
+// launched from
+func (p *DefaultProvider) launchTemplatesInUse(ctx context.Context) (sets.Set[string], error) {
+	inUse := sets.New[string]()
+	paginator := ec2.NewDescribeInstancesPaginator(p.ec2api, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String(fmt.Sprintf("tag:%s", v1.EKSClusterNameTagKey)),
+				Values: []string{options.FromContext(ctx).ClusterName},
+			},
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: []string{string(ec2types.InstanceStateNamePending), string(ec2types.InstanceStateNameRunning)},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing instances, %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				if tag, ok := lo.Find(instance.Tags, func(t ec2types.Tag) bool { return aws.ToString(t.Key) == launchTemplateIDTagKey }); ok {
+					inUse.Insert(aws.ToString(tag.Value))
+				}
+			}
+		}
+	}
+	return inUse, nil
+}
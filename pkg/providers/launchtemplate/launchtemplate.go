@@ -36,6 +36,8 @@ import (
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
@@ -58,6 +60,7 @@ type Provider interface {
 	DeleteAll(context.Context, *v1.EC2NodeClass) error
 	InvalidateCache(context.Context, string, string)
 	ResolveClusterCIDR(context.Context) error
+	GarbageCollect(context.Context, time.Duration) error
 }
 type LaunchTemplate struct {
 	Name          string
@@ -67,12 +70,15 @@ type LaunchTemplate struct {
 
 type DefaultProvider struct {
 	sync.Mutex
+	clk                   clock.Clock
 	ec2api                sdk.EC2API
 	eksapi                sdk.EKSAPI
 	amiFamily             amifamily.Resolver
 	securityGroupProvider securitygroup.Provider
 	subnetProvider        subnet.Provider
+	kubernetesInterface   kubernetes.Interface
 	cache                 *cache.Cache
+	secretCache           *cache.Cache
 	cm                    *pretty.ChangeMonitor
 	KubeDNSIP             net.IP
 	CABundle              *string
@@ -81,16 +87,19 @@ type DefaultProvider struct {
 	ClusterIPFamily       corev1.IPFamily
 }
 
-func NewDefaultProvider(ctx context.Context, cache *cache.Cache, ec2api sdk.EC2API, eksapi sdk.EKSAPI, amiFamily amifamily.Resolver,
-	securityGroupProvider securitygroup.Provider, subnetProvider subnet.Provider,
-	caBundle *string, startAsync <-chan struct{}, kubeDNSIP net.IP, clusterEndpoint string) *DefaultProvider {
+func NewDefaultProvider(ctx context.Context, clk clock.Clock, cache *cache.Cache, ec2api sdk.EC2API, eksapi sdk.EKSAPI, amiFamily amifamily.Resolver,
+	securityGroupProvider securitygroup.Provider, subnetProvider subnet.Provider, kubernetesInterface kubernetes.Interface,
+	secretCache *cache.Cache, caBundle *string, startAsync <-chan struct{}, kubeDNSIP net.IP, clusterEndpoint string) *DefaultProvider {
 	l := &DefaultProvider{
+		clk:                   clk,
 		ec2api:                ec2api,
 		eksapi:                eksapi,
 		amiFamily:             amiFamily,
 		securityGroupProvider: securityGroupProvider,
 		subnetProvider:        subnetProvider,
+		kubernetesInterface:   kubernetesInterface,
 		cache:                 cache,
+		secretCache:           secretCache,
 		CABundle:              caBundle,
 		cm:                    pretty.NewChangeMonitor(),
 		KubeDNSIP:             kubeDNSIP,
@@ -109,11 +118,27 @@ func NewDefaultProvider(ctx context.Context, cache *cache.Cache, ec2api sdk.EC2A
 	}()
 	return l
 }
+
+// migConfigLabels selects the MIG manager's configuration that partitions every GPU into the EC2NodeClass's MIG profile. The
+// label is passed to the kubelet rather than set on the NodeClaim so that it's present as soon as the node registers.
+func migConfigLabels(nodeClass *v1.EC2NodeClass) map[string]string {
+	if nodeClass.Spec.GPUPartitioning == nil || nodeClass.Spec.GPUPartitioning.MIG == nil {
+		return nil
+	}
+	return map[string]string{v1.LabelNVIDIAMIGConfig: "all-" + nodeClass.Spec.GPUPartitioning.MIG.Profile}
+}
+
 func (p *DefaultProvider) EnsureAll(ctx context.Context, nodeClass *v1.EC2NodeClass, nodeClaim *karpv1.NodeClaim,
 	instanceTypes []*cloudprovider.InstanceType, capacityType string, tags map[string]string) ([]*LaunchTemplate, error) {
 	p.Lock()
 	defer p.Unlock()
-	options, err := p.createAMIOptions(ctx, nodeClass, lo.Assign(nodeClaim.Labels, map[string]string{karpv1.CapacityTypeLabelKey: capacityType}), tags)
+	// The drift hash is computed from the EC2NodeClass spec, so rotating a secret referenced from userData results in new
+	// launch templates for new nodes but doesn't drift existing nodes
+	nodeClass, err := p.renderUserDataSecrets(ctx, nodeClass)
+	if err != nil {
+		return nil, err
+	}
+	options, err := p.createAMIOptions(ctx, nodeClass, lo.Assign(nodeClaim.Labels, map[string]string{karpv1.CapacityTypeLabelKey: capacityType}, migConfigLabels(nodeClass)), tags)
 	if err != nil {
 		return nil, err
 	}
@@ -143,8 +168,12 @@ func (p *DefaultProvider) InvalidateCache(ctx context.Context, ltName string, lt
 	log.FromContext(ctx).V(1).Info("invalidating launch template in the cache because it no longer exists")
 	p.cache.Delete(ltName)
 }
-func LaunchTemplateName(options *amifamily.LaunchTemplate) string {
-	return fmt.Sprintf("%s/%d", v1.LaunchTemplateNamePrefix, lo.Must(hashstructure.Hash(options, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})))
+
+// LaunchTemplateName is a hash of the launch template's options, prefixed with the resource name prefix. Launch templates
+// created with a different prefix are no longer found in the cache once the prefix changes, and so are deleted when they expire.
+func LaunchTemplateName(ctx context.Context, launchTemplate *amifamily.LaunchTemplate) string {
+	return fmt.Sprintf("%s%s/%d", options.FromContext(ctx).ResourceNamePrefix, v1.LaunchTemplateNamePrefix,
+		lo.Must(hashstructure.Hash(launchTemplate, hashstructure.FormatV2, &hashstructure.HashOptions{SlicesAsSets: true})))
 }
 func (p *DefaultProvider) createAMIOptions(ctx context.Context, nodeClass *v1.EC2NodeClass, labels, tags map[string]string) (*amifamily.Options, error) {
 	// Remove any labels passed into userData that are prefixed with "node-restriction.kubernetes.io" or "kops.k8s.io" since the kubelet can't
@@ -171,6 +200,7 @@ func (p *DefaultProvider) createAMIOptions(ctx context.Context, nodeClass *v1.EC
 		ClusterCIDR:              p.ClusterCIDR.Load(),
 		InstanceProfile:          nodeClass.Status.InstanceProfile,
 		InstanceStorePolicy:      nodeClass.Spec.InstanceStorePolicy,
+		Proxy:                    nodeClass.Spec.Proxy,
 		SecurityGroups:           nodeClass.Status.SecurityGroups,
 		Tags:                     tags,
 		Labels:                   labels,
@@ -183,7 +213,7 @@ func (p *DefaultProvider) createAMIOptions(ctx context.Context, nodeClass *v1.EC
 
 func (p *DefaultProvider) ensureLaunchTemplate(ctx context.Context, options *amifamily.LaunchTemplate) (ec2types.LaunchTemplate, error) {
 	var launchTemplate ec2types.LaunchTemplate
-	name := LaunchTemplateName(options)
+	name := LaunchTemplateName(ctx, options)
 	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("launch-template-name", name))
 	// Read from cache
 	if launchTemplate, ok := p.cache.Get(name); ok {
@@ -197,7 +227,20 @@ func (p *DefaultProvider) ensureLaunchTemplate(ctx context.Context, options *ami
 	// Create LT if one doesn't exist
 	if awserrors.IsNotFound(err) {
 		launchTemplate, err = p.createLaunchTemplate(ctx, options)
-		if err != nil {
+		// Launch template names are a deterministic hash of their options, so a template with the same name that was created
+		// concurrently (e.g. by a previous leader) is identical and can be used in place of creating our own
+		if awserrors.IsAlreadyExists(err) {
+			output, err = p.ec2api.DescribeLaunchTemplates(ctx, &ec2.DescribeLaunchTemplatesInput{
+				LaunchTemplateNames: []string{name},
+			})
+			if err != nil {
+				return ec2types.LaunchTemplate{}, fmt.Errorf("describing launch templates, %w", err)
+			}
+			if len(output.LaunchTemplates) != 1 {
+				return ec2types.LaunchTemplate{}, fmt.Errorf("expected to find one launch template, but found %d", len(output.LaunchTemplates))
+			}
+			launchTemplate = output.LaunchTemplates[0]
+		} else if err != nil {
 			return ec2types.LaunchTemplate{}, fmt.Errorf("creating launch template, %w", err)
 		}
 	} else if err != nil {
@@ -226,8 +269,8 @@ func (p *DefaultProvider) createLaunchTemplate(ctx context.Context, options *ami
 		launchTemplateDataTags = append(launchTemplateDataTags, ec2types.LaunchTemplateTagSpecificationRequest{ResourceType: ec2types.ResourceTypeSpotInstancesRequest, Tags: utils.MergeTags(options.Tags)})
 	}
 	networkInterfaces := p.generateNetworkInterfaces(options)
-	output, err := p.ec2api.CreateLaunchTemplate(ctx, &ec2.CreateLaunchTemplateInput{
-		LaunchTemplateName: aws.String(LaunchTemplateName(options)),
+	input := &ec2.CreateLaunchTemplateInput{
+		LaunchTemplateName: aws.String(LaunchTemplateName(ctx, options)),
 		LaunchTemplateData: &ec2types.RequestLaunchTemplateData{
 			BlockDeviceMappings: p.blockDeviceMappings(options.BlockDeviceMappings),
 			IamInstanceProfile: &ec2types.LaunchTemplateIamInstanceProfileSpecificationRequest{
@@ -255,6 +298,9 @@ func (p *DefaultProvider) createLaunchTemplate(ctx context.Context, options *ami
 				InstanceMetadataTags: ec2types.LaunchTemplateInstanceMetadataTagsStateDisabled,
 			},
 			NetworkInterfaces: networkInterfaces,
+			LicenseSpecifications: lo.Map(options.LicenseARNs, func(arn string, _ int) ec2types.LaunchTemplateLicenseConfigurationRequest {
+				return ec2types.LaunchTemplateLicenseConfigurationRequest{LicenseConfigurationArn: aws.String(arn)}
+			}),
 			TagSpecifications: launchTemplateDataTags,
 		},
 		TagSpecifications: []ec2types.TagSpecification{
@@ -263,7 +309,37 @@ func (p *DefaultProvider) createLaunchTemplate(ctx context.Context, options *ami
 				Tags:         utils.MergeTags(options.Tags),
 			},
 		},
-	})
+	}
+	if options.EnclavesEnabled {
+		input.LaunchTemplateData.EnclaveOptions = &ec2types.LaunchTemplateEnclaveOptionsRequest{Enabled: aws.Bool(true)}
+	}
+	if options.CPUOptions != nil {
+		input.LaunchTemplateData.CpuOptions = &ec2types.LaunchTemplateCpuOptionsRequest{
+			CoreCount:      options.CPUOptions.CoreCount,
+			ThreadsPerCore: options.CPUOptions.ThreadsPerCore,
+			AmdSevSnp:      ec2types.AmdSevSnpSpecification(lo.FromPtr(options.CPUOptions.AMDSEVSNP)),
+		}
+	}
+	if options.KeyName != "" {
+		input.LaunchTemplateData.KeyName = aws.String(options.KeyName)
+	}
+	if options.CPUCredits != "" {
+		input.LaunchTemplateData.CreditSpecification = &ec2types.CreditSpecificationRequest{CpuCredits: aws.String(options.CPUCredits)}
+	}
+	if options.PrivateDNSNameOptions != nil {
+		input.LaunchTemplateData.PrivateDnsNameOptions = &ec2types.LaunchTemplatePrivateDnsNameOptionsRequest{
+			HostnameType:                    ec2types.HostnameType(lo.FromPtr(options.PrivateDNSNameOptions.HostnameType)),
+			EnableResourceNameDnsARecord:    options.PrivateDNSNameOptions.EnableResourceNameDNSARecord,
+			EnableResourceNameDnsAAAARecord: options.PrivateDNSNameOptions.EnableResourceNameDNSAAAARecord,
+		}
+	}
+	if options.CapacityReservationID != "" {
+		input.LaunchTemplateData.InstanceMarketOptions = &ec2types.LaunchTemplateInstanceMarketOptionsRequest{MarketType: ec2types.MarketTypeCapacityBlock}
+		input.LaunchTemplateData.CapacityReservationSpecification = &ec2types.LaunchTemplateCapacityReservationSpecificationRequest{
+			CapacityReservationTarget: &ec2types.CapacityReservationTarget{CapacityReservationId: aws.String(options.CapacityReservationID)},
+		}
+	}
+	output, err := p.ec2api.CreateLaunchTemplate(ctx, input)
 	if err != nil {
 		return ec2types.LaunchTemplate{}, err
 	}
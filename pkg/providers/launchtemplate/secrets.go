@@ -0,0 +1,100 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package launchtemplate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+)
+
+const (
+	// MaxUserDataSize is the maximum size of the userData after secret references have been resolved. EC2 rejects user
+	// data larger than 16 KiB, so we fail before the launch template is created rather than on launch.
+	MaxUserDataSize = 16 * 1024
+	// MaxUserDataSecretSize is the maximum size of a single secret value referenced from userData
+	MaxUserDataSecretSize = 4 * 1024
+)
+
+// secretReference matches {{ secret "<namespace>/<name>" "<key>" }}. We match the reference directly rather than parsing
+// the userData as a go template so that userData which already contains template-like syntax (e.g. "{{.ID}}") is untouched.
+var secretReference = regexp.MustCompile(`\{\{\s*secret\s+"([^"/\s]+)/([^"/\s]+)"\s+"([^"\s]+)"\s*\}\}`)
+
+// HasSecretReferences returns true if the userData references any Kubernetes Secrets
+func HasSecretReferences(userData *string) bool {
+	return userData != nil && secretReference.MatchString(*userData)
+}
+
+// renderUserDataSecrets returns a copy of the EC2NodeClass with every secret reference in its userData replaced by the
+// referenced secret value. Errors identify the secret and key which couldn't be resolved, but never include secret values.
+func (p *DefaultProvider) renderUserDataSecrets(ctx context.Context, nodeClass *v1.EC2NodeClass) (*v1.EC2NodeClass, error) {
+	if !HasSecretReferences(nodeClass.Spec.UserData) {
+		return nodeClass, nil
+	}
+	var errs []error
+	var refs []string
+	rendered := secretReference.ReplaceAllStringFunc(*nodeClass.Spec.UserData, func(match string) string {
+		groups := secretReference.FindStringSubmatch(match)
+		ref := fmt.Sprintf("%s/%s[%s]", groups[1], groups[2], groups[3])
+		refs = append(refs, ref)
+		value, err := p.getSecretValue(ctx, groups[1], groups[2], groups[3])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("resolving userData secret %s, %w", ref, err))
+			return ""
+		}
+		return value
+	})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if len(rendered) > MaxUserDataSize {
+		return nil, fmt.Errorf("userData is %d bytes after resolving secrets, exceeding the maximum of %d bytes", len(rendered), MaxUserDataSize)
+	}
+	log.FromContext(ctx).WithValues("secrets", lo.Uniq(refs)).V(1).Info("resolved userData secrets")
+	nodeClass = nodeClass.DeepCopy()
+	nodeClass.Spec.UserData = lo.ToPtr(rendered)
+	return nodeClass, nil
+}
+
+func (p *DefaultProvider) getSecretValue(ctx context.Context, namespace, name, key string) (string, error) {
+	cacheKey := strings.Join([]string{namespace, name}, "/")
+	var secret *corev1.Secret
+	if cached, ok := p.secretCache.Get(cacheKey); ok {
+		secret = cached.(*corev1.Secret)
+	} else {
+		s, err := p.kubernetesInterface.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("getting secret, %w", err)
+		}
+		secret = s
+		p.secretCache.SetDefault(cacheKey, secret)
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key not found in secret")
+	}
+	if len(value) > MaxUserDataSecretSize {
+		return "", fmt.Errorf("value is %d bytes, exceeding the maximum of %d bytes", len(value), MaxUserDataSecretSize)
+	}
+	return string(value), nil
+}
@@ -97,7 +97,7 @@ var _ = BeforeSuite(func() {
 
 	fakeClock = &clock.FakeClock{}
 	cloudProvider = cloudprovider.New(awsEnv.InstanceTypesProvider, awsEnv.InstanceProvider, events.NewRecorder(&record.FakeRecorder{}),
-		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider)
+		env.Client, awsEnv.AMIProvider, awsEnv.SecurityGroupProvider, awsEnv.CarbonIntensityProvider, awsEnv.VersionProvider)
 	cluster = state.NewCluster(fakeClock, env.Client, cloudProvider)
 	prov = provisioning.NewProvisioner(env.Client, events.NewRecorder(&record.FakeRecorder{}), cloudProvider, cluster, fakeClock)
 })
@@ -281,6 +281,22 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			}
 		})
 	})
+	It("should prefix launch template names with the resource name prefix", func() {
+		ctx = options.ToContext(ctx, test.Options(test.OptionsFields{ResourceNamePrefix: lo.ToPtr("acme-")}))
+		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+		pod := coretest.UnschedulablePod()
+		ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+		ExpectScheduled(ctx, env.Client, pod)
+
+		Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+		awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+			Expect(*ltInput.LaunchTemplateName).To(HavePrefix("acme-karpenter.k8s.aws/"))
+		})
+		createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+		for _, ltConfig := range createFleetInput.LaunchTemplateConfigs {
+			Expect(*ltConfig.LaunchTemplateSpecification.LaunchTemplateName).To(HavePrefix("acme-karpenter.k8s.aws/"))
+		}
+	})
 	It("should default to a generated launch template", func() {
 		ExpectApplied(ctx, env.Client, nodePool, nodeClass)
 		pod := coretest.UnschedulablePod()
@@ -424,7 +440,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			launchtemplateResult := []string{}
 			for _, option := range options {
 				lt := &amifamily.LaunchTemplate{Options: option}
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(ctx, lt))
 			}
 			Expect(len(launchtemplateResult)).To(BeNumerically("==", 11))
 			Expect(lo.Uniq(launchtemplateResult)).To(Equal(launchtemplateResult))
@@ -438,10 +454,10 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			launchtemplateResult := []string{}
 			for _, option := range options {
 				lt := &amifamily.LaunchTemplate{Options: option}
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(ctx, lt))
 			}
 			Expect(len(lo.Uniq(launchtemplateResult))).To(BeNumerically("==", 1))
-			Expect(lo.Uniq(launchtemplateResult)[0]).To(Equal(launchtemplate.LaunchTemplateName(&amifamily.LaunchTemplate{Options: &amifamily.Options{}})))
+			Expect(lo.Uniq(launchtemplateResult)[0]).To(Equal(launchtemplate.LaunchTemplateName(ctx, &amifamily.LaunchTemplate{Options: &amifamily.Options{}})))
 		})
 		It("should generate different launch template names based on kubelet configuration", func() {
 			kubeletChanges := []*v1.KubeletConfiguration{
@@ -455,7 +471,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			launchtemplateResult := []string{}
 			for _, kubelet := range kubeletChanges {
 				lt := &amifamily.LaunchTemplate{UserData: bootstrap.EKS{Options: bootstrap.Options{KubeletConfig: kubelet}}}
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(ctx, lt))
 			}
 			Expect(len(launchtemplateResult)).To(BeNumerically("==", 6))
 			Expect(lo.Uniq(launchtemplateResult)).To(Equal(launchtemplateResult))
@@ -475,7 +491,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			launchtemplateResult := []string{}
 			for _, option := range bootstrapOptions {
 				lt := &amifamily.LaunchTemplate{UserData: bootstrap.EKS{Options: *option}}
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(ctx, lt))
 			}
 			Expect(len(launchtemplateResult)).To(BeNumerically("==", 9))
 			Expect(lo.Uniq(launchtemplateResult)).To(Equal(launchtemplateResult))
@@ -491,7 +507,7 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			}
 			launchtemplateResult := []string{}
 			for _, lt := range launchtemplates {
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(ctx, lt))
 			}
 			Expect(len(launchtemplateResult)).To(BeNumerically("==", 6))
 			Expect(lo.Uniq(launchtemplateResult)).To(Equal(launchtemplateResult))
@@ -503,10 +519,10 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			}
 			launchtemplateResult := []string{}
 			for _, lt := range launchtemplates {
-				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(lt))
+				launchtemplateResult = append(launchtemplateResult, launchtemplate.LaunchTemplateName(ctx, lt))
 			}
 			Expect(len(lo.Uniq(launchtemplateResult))).To(BeNumerically("==", 1))
-			Expect(lo.Uniq(launchtemplateResult)[0]).To(Equal(launchtemplate.LaunchTemplateName(&amifamily.LaunchTemplate{})))
+			Expect(lo.Uniq(launchtemplateResult)[0]).To(Equal(launchtemplate.LaunchTemplateName(ctx, &amifamily.LaunchTemplate{})))
 		})
 	})
 	Context("Labels", func() {
@@ -978,6 +994,77 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			// capacity isn't recorded on the node any longer, but we know the pod should schedule
 			ExpectScheduled(ctx, env.Client, pod)
 		})
+		It("should auto-size the root volume to fit the ephemeral-storage requests of the pods", func() {
+			nodeClass.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{
+				{
+					DeviceName: aws.String("/dev/xvda"),
+					EBS: &v1.BlockDevice{
+						VolumeSize:    lo.ToPtr(resource.MustParse("20Gi")),
+						MaxVolumeSize: lo.ToPtr(resource.MustParse("200Gi")),
+					},
+					RootVolume: true,
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{ResourceRequirements: corev1.ResourceRequirements{
+				Requests: map[corev1.ResourceName]resource.Quantity{
+					corev1.ResourceEphemeralStorage: resource.MustParse("100Gi"),
+				},
+			}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			// 100Gi of requests, 1Gi of kube-reserved and a 10% eviction threshold of the 200Gi maxVolumeSize
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(lo.FromPtr(ltInput.LaunchTemplateData.BlockDeviceMappings[0].Ebs.VolumeSize)).To(Equal(int32(121)))
+			})
+			nodeClaims := ExpectNodeClaims(ctx, env.Client)
+			Expect(nodeClaims).To(HaveLen(1))
+			Expect(nodeClaims[0].Annotations).To(HaveKeyWithValue(v1.AnnotationRootVolumeSize, "121Gi"))
+			Expect(nodeClaims[0].Status.Capacity.StorageEphemeral().String()).To(Equal("121Gi"))
+		})
+		It("should auto-size the root volume within the volumeSize and maxVolumeSize", func() {
+			nodeClass.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{
+				{
+					DeviceName: aws.String("/dev/xvda"),
+					EBS: &v1.BlockDevice{
+						VolumeSize:    lo.ToPtr(resource.MustParse("50Gi")),
+						MaxVolumeSize: lo.ToPtr(resource.MustParse("200Gi")),
+					},
+					RootVolume: true,
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(lo.FromPtr(ltInput.LaunchTemplateData.BlockDeviceMappings[0].Ebs.VolumeSize)).To(Equal(int32(50)))
+			})
+			nodeClaims := ExpectNodeClaims(ctx, env.Client)
+			Expect(nodeClaims).To(HaveLen(1))
+			Expect(nodeClaims[0].Annotations).To(HaveKeyWithValue(v1.AnnotationRootVolumeSize, "50Gi"))
+		})
+		It("should not pack pods if their ephemeral-storage requests exceed the maxVolumeSize", func() {
+			nodeClass.Spec.BlockDeviceMappings = []*v1.BlockDeviceMapping{
+				{
+					DeviceName: aws.String("/dev/xvda"),
+					EBS: &v1.BlockDevice{
+						VolumeSize:    lo.ToPtr(resource.MustParse("20Gi")),
+						MaxVolumeSize: lo.ToPtr(resource.MustParse("100Gi")),
+					},
+					RootVolume: true,
+				},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{ResourceRequirements: corev1.ResourceRequirements{
+				Requests: map[corev1.ResourceName]resource.Quantity{
+					corev1.ResourceEphemeralStorage: resource.MustParse("100Gi"),
+				},
+			}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectNotScheduled(ctx, env.Client, pod)
+		})
 	})
 	Context("AL2", func() {
 		var info ec2types.InstanceTypeInfo
@@ -1113,6 +1200,22 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			ExpectScheduled(ctx, env.Client, pod)
 			ExpectLaunchTemplatesCreatedWithUserDataContaining("--use-max-pods false")
 		})
+		It("should label nodes with the MIG configuration when MIG partitioning is specified", func() {
+			nodeClass.Spec.GPUPartitioning = &v1.GPUPartitioning{MIG: &v1.MIGPartitioning{Profile: "1g.10gb", Strategy: v1.MIGStrategySingle}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining("nvidia.com/mig.config=all-1g.10gb")
+		})
+		It("should not label nodes with a MIG configuration when GPUs are time-sliced", func() {
+			nodeClass.Spec.GPUPartitioning = &v1.GPUPartitioning{TimeSlicingReplicas: aws.Int32(4)}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataNotContaining("nvidia.com/mig.config")
+		})
 		It("should specify --use-max-pods=false and --max-pods user value when user specifies maxPods in NodePool", func() {
 			nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: aws.Int32(10)}
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -1188,6 +1291,48 @@ var _ = Describe("LaunchTemplate Provider", func() {
 				}
 			})
 		})
+		It("should specify the computed --system-reserved and --kube-reserved when autoSystemReserved is enabled", func() {
+			nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{
+				AutoSystemReserved: &v1.AutoSystemReserved{CPUMillicoresPerVCPU: lo.ToPtr[int32](50)},
+				KubeReserved:       map[string]string{string(corev1.ResourceMemory): "1Gi"},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod(coretest.PodOptions{NodeSelector: map[string]string{corev1.LabelInstanceTypeStable: "m5.large"}})
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically("==", 1))
+			ltInput := awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Pop()
+			userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+			Expect(err).To(BeNil())
+			for arg, expected := range map[string][]string{
+				// m5.large has 2 vCPUs
+				"--system-reserved=": {"cpu=100m", "memory="},
+				"--kube-reserved=":   {"cpu=70m", "memory=1Gi", "ephemeral-storage=1Gi"},
+			} {
+				i := strings.Index(string(userData), arg)
+				Expect(i).To(BeNumerically(">=", 0), arg)
+				rem := string(userData)[(i + len(arg)):]
+				i = strings.Index(rem, "'")
+				for _, v := range expected {
+					Expect(rem[:i]).To(ContainSubstring(v))
+				}
+			}
+		})
+		It("should generate different launch templates for instance types with different computed reservations", func() {
+			nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{AutoSystemReserved: &v1.AutoSystemReserved{}}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			// Without autoSystemReserved, instance types are only split by image and max-pods into 5 launch templates
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 5))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				userData, err := base64.StdEncoding.DecodeString(*ltInput.LaunchTemplateData.UserData)
+				Expect(err).To(BeNil())
+				Expect(string(userData)).To(ContainSubstring("--system-reserved="))
+				Expect(string(userData)).ToNot(ContainSubstring("autoSystemReserved"))
+			})
+		})
 		It("should pass eviction hard threshold values when specified", func() {
 			nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{
 				EvictionHard: map[string]string{
@@ -1378,6 +1523,23 @@ var _ = Describe("LaunchTemplate Provider", func() {
 			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically("==", 5))
 			ExpectLaunchTemplatesCreatedWithUserDataContaining("--local-disks raid0")
 		})
+		It("should specify --local-disks raid0 when the EphemeralOS instance-store policy is set on AL2", func() {
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyEphemeralOS)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining("--local-disks raid0")
+		})
+		It("should specify the ephemeral-storage bootstrap-command when the EphemeralOS instance-store policy is set on Bottlerocket", func() {
+			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyEphemeralOS)
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			ExpectLaunchTemplatesCreatedWithUserDataContaining("[settings.bootstrap-commands.000-mount-instance-storage]")
+		})
 		It("should specify RAID0 bootstrap-command when instance-store policy is set on Bottlerocket", func() {
 			nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
 			nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyRAID0)
@@ -1641,6 +1803,95 @@ essential = true
 				})
 			})
 		})
+		Context("Proxy", func() {
+			BeforeEach(func() {
+				nodeClass.Spec.Proxy = &v1.Proxy{
+					HTTPSProxy: lo.ToPtr("http://proxy.example.com:3128"),
+					NoProxy:    []string{"10.0.0.0/16", ".eks.amazonaws.com"},
+					CABundle:   lo.ToPtr("cHJveHktY2EK"),
+				}
+				awsEnv.LaunchTemplateProvider.ClusterCIDR.Store(lo.ToPtr("10.100.0.0/16"))
+			})
+			It("should configure containerd and the kubelet to use the proxy for AL2", func() {
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2@latest"}}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining(
+					"echo 'cHJveHktY2EK' | base64 -d > /etc/pki/ca-trust/source/anchors/karpenter-proxy-ca.pem\nupdate-ca-trust extract",
+					"/etc/systemd/system/containerd.service.d/http-proxy.conf",
+					"/etc/systemd/system/kubelet.service.d/http-proxy.conf",
+					`Environment="HTTPS_PROXY=http://proxy.example.com:3128"`,
+					`Environment="NO_PROXY=localhost,127.0.0.1,169.254.169.254,10.100.0.0/16,10.0.0.0/16,.eks.amazonaws.com"`,
+				)
+			})
+			It("should configure containerd and the kubelet to use the proxy for AL2023", func() {
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "al2023@latest"}}
+				awsEnv.LaunchTemplateProvider.CABundle = lo.ToPtr("Y2EtYnVuZGxlCg==")
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					archive, err := mime.NewArchive(userData)
+					Expect(err).To(BeNil())
+					Expect(archive[0].ContentType).To(Equal(mime.ContentTypeShellScript))
+					Expect(archive[0].Content).To(ContainSubstring("update-ca-trust extract"))
+					Expect(archive[0].Content).To(ContainSubstring(`Environment="NO_PROXY=localhost,127.0.0.1,169.254.169.254,10.100.0.0/16,10.0.0.0/16,.eks.amazonaws.com"`))
+					ExpectUserDataCreatedWithNodeConfigs(userData)
+				}
+			})
+			It("should set the network and pki settings for Bottlerocket", func() {
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "bottlerocket@latest"}}
+				nodeClass.Spec.UserData = aws.String("[settings.network]\nhostname = \"my-host\"\n")
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					config := &bootstrap.BottlerocketConfig{}
+					Expect(config.UnmarshalTOML([]byte(userData))).To(Succeed())
+					network := config.SettingsRaw["network"].(map[string]interface{})
+					Expect(network).To(HaveKeyWithValue("hostname", "my-host"))
+					Expect(network).To(HaveKeyWithValue("https-proxy", "http://proxy.example.com:3128"))
+					Expect(network).To(HaveKeyWithValue("no-proxy", ConsistOf("localhost", "127.0.0.1", "169.254.169.254", "10.100.0.0/16", "10.0.0.0/16", ".eks.amazonaws.com")))
+					pki := config.SettingsRaw["pki"].(map[string]interface{})
+					ca := pki["karpenter-proxy-ca"].(map[string]interface{})
+					Expect(ca).To(HaveKeyWithValue("data", "cHJveHktY2EK"))
+					Expect(ca).To(HaveKeyWithValue("trusted", true))
+				}
+			})
+			It("should set the machine proxy and import the certificate authorities for Windows", func() {
+				nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{{NodeSelectorRequirement: corev1.NodeSelectorRequirement{Key: corev1.LabelOSStable, Operator: corev1.NodeSelectorOpIn, Values: []string{string(corev1.Windows)}}}}
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Alias: "windows2022@latest"}}
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod(coretest.PodOptions{
+					NodeSelector: map[string]string{
+						corev1.LabelOSStable:     string(corev1.Windows),
+						corev1.LabelWindowsBuild: "10.0.20348",
+					},
+				})
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataContaining(
+					"[Convert]::FromBase64String('cHJveHktY2EK')",
+					"-CertStoreLocation Cert:\\LocalMachine\\Root",
+					"[Environment]::SetEnvironmentVariable('HTTPS_PROXY', 'http://proxy.example.com:3128', 'Machine')",
+					"[Environment]::SetEnvironmentVariable('NO_PROXY', 'localhost,127.0.0.1,169.254.169.254,10.100.0.0/16,10.0.0.0/16,.eks.amazonaws.com', 'Machine')",
+				)
+			})
+			It("should not modify the user data for Custom", func() {
+				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+				nodeClass.Spec.UserData = aws.String("#!/bin/bash\necho custom")
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserDataNotContaining("HTTPS_PROXY", "karpenter-proxy-ca")
+			})
+		})
 		Context("AL2 Custom UserData", func() {
 			BeforeEach(func() {
 				nodeClass.Spec.Kubelet = &v1.KubeletConfiguration{MaxPods: lo.ToPtr[int32](110)}
@@ -1865,6 +2116,18 @@ essential = true
 					Expect(configs[0].Spec.Instance.LocalStorage.Strategy).To(Equal(admv1alpha1.LocalStorageRAID0))
 				}
 			})
+			It("should set LocalDiskStrategy to Raid0 when the InstanceStorePolicy is EphemeralOS", func() {
+				nodeClass.Spec.InstanceStorePolicy = lo.ToPtr(v1.InstanceStorePolicyEphemeralOS)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				for _, userData := range ExpectUserDataExistsFromCreatedLaunchTemplates() {
+					configs := ExpectUserDataCreatedWithNodeConfigs(userData)
+					Expect(len(configs)).To(Equal(1))
+					Expect(configs[0].Spec.Instance.LocalStorage.Strategy).To(Equal(admv1alpha1.LocalStorageRAID0))
+				}
+			})
 			DescribeTable(
 				"should merge custom user data",
 				func(inputFile *string, mergedFile string) {
@@ -1897,6 +2160,71 @@ essential = true
 				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(0))
 			})
 		})
+		Context("Secret References", func() {
+			var secret *corev1.Secret
+			BeforeEach(func() {
+				secret = &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: coretest.RandomName(), Namespace: "default"},
+					Data:       map[string][]byte{"token": []byte("super-secret-token")},
+				}
+				ExpectApplied(ctx, env.Client, secret)
+				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
+				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
+			})
+			AfterEach(func() {
+				ExpectDeleted(ctx, env.Client, secret)
+			})
+			It("should resolve secret references in userData", func() {
+				nodeClass.Spec.UserData = aws.String(fmt.Sprintf("#!/bin/bash\nexport TOKEN={{ secret \"default/%s\" \"token\" }}\necho {{.ID}}", secret.Name))
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				ExpectLaunchTemplatesCreatedWithUserData("#!/bin/bash\nexport TOKEN=super-secret-token\necho {{.ID}}")
+			})
+			It("should not modify the userData stored on the EC2NodeClass", func() {
+				userData := fmt.Sprintf("export TOKEN={{secret \"default/%s\" \"token\"}}", secret.Name)
+				nodeClass.Spec.UserData = aws.String(userData)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectScheduled(ctx, env.Client, pod)
+				Expect(lo.FromPtr(ExpectExists(ctx, env.Client, nodeClass).Spec.UserData)).To(Equal(userData))
+			})
+			It("should fail to launch when the secret doesn't exist", func() {
+				nodeClass.Spec.UserData = aws.String(`{{ secret "default/does-not-exist" "token" }}`)
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				_, err := awsEnv.LaunchTemplateProvider.EnsureAll(ctx, nodeClass, coretest.NodeClaim(), nil, karpv1.CapacityTypeOnDemand, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("default/does-not-exist[token]"))
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(0))
+			})
+			It("should fail to launch when the key doesn't exist", func() {
+				nodeClass.Spec.UserData = aws.String(fmt.Sprintf(`{{ secret "default/%s" "missing" }}`, secret.Name))
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				pod := coretest.UnschedulablePod()
+				ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+				ExpectNotScheduled(ctx, env.Client, pod)
+				Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(Equal(0))
+			})
+			It("should fail to launch when a secret value exceeds the size limit", func() {
+				secret.Data["token"] = []byte(strings.Repeat("a", launchtemplate.MaxUserDataSecretSize+1))
+				ExpectApplied(ctx, env.Client, secret)
+				nodeClass.Spec.UserData = aws.String(fmt.Sprintf(`{{ secret "default/%s" "token" }}`, secret.Name))
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				_, err := awsEnv.LaunchTemplateProvider.EnsureAll(ctx, nodeClass, coretest.NodeClaim(), nil, karpv1.CapacityTypeOnDemand, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("exceeding the maximum"))
+				Expect(err.Error()).ToNot(ContainSubstring("aaaa"))
+			})
+			It("should fail to launch when the rendered userData exceeds the size limit", func() {
+				nodeClass.Spec.UserData = aws.String(strings.Repeat("a", launchtemplate.MaxUserDataSize) + fmt.Sprintf(`{{ secret "default/%s" "token" }}`, secret.Name))
+				ExpectApplied(ctx, env.Client, nodeClass, nodePool)
+				_, err := awsEnv.LaunchTemplateProvider.EnsureAll(ctx, nodeClass, coretest.NodeClaim(), nil, karpv1.CapacityTypeOnDemand, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).ToNot(ContainSubstring("super-secret-token"))
+			})
+		})
 		Context("Custom AMI Selector", func() {
 			It("should use ami selector specified in EC2NodeClass", func() {
 				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
@@ -2025,7 +2353,7 @@ essential = true
 				nodeClass.Spec.AMIFamily = lo.ToPtr(v1.AMIFamilyCustom)
 				nodeClass.Spec.AMISelectorTerms = []v1.AMISelectorTerm{{Tags: map[string]string{"*": "*"}}}
 				ExpectApplied(ctx, env.Client, nodeClass)
-				controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider)
+				controller := status.NewController(env.Client, awsEnv.SubnetProvider, awsEnv.SecurityGroupProvider, awsEnv.AMIProvider, awsEnv.InstanceProfileProvider, awsEnv.LaunchTemplateProvider, awsEnv.KMSProvider, awsEnv.CapacityReservationProvider, awsEnv.VPCEndpointProvider, awsEnv.AccessEntryProvider)
 				ExpectObjectReconciled(ctx, env.Client, controller, nodeClass)
 				nodePool.Spec.Template.Spec.Requirements = []karpv1.NodeSelectorRequirementWithMinValues{
 					{
@@ -2195,6 +2523,189 @@ essential = true
 			})
 		})
 	})
+	Context("Launch Options", func() {
+		BeforeEach(func() {
+			instances := fake.MakeInstances()
+			for i := range instances {
+				if instances[i].InstanceType == "m6a.large" {
+					instances[i].NitroEnclavesSupport = ec2types.NitroEnclavesSupportSupported
+					instances[i].ProcessorInfo.SupportedFeatures = []ec2types.SupportedAdditionalProcessorFeature{ec2types.SupportedAdditionalProcessorFeatureAmdSevSnp}
+					instances[i].VCpuInfo.ValidCores = []int32{1}
+					instances[i].VCpuInfo.ValidThreadsPerCore = []int32{1, 2}
+				}
+			}
+			awsEnv.EC2API.DescribeInstanceTypesOutput.Set(&ec2.DescribeInstanceTypesOutput{InstanceTypes: instances})
+			awsEnv.EC2API.DescribeInstanceTypeOfferingsOutput.Set(&ec2.DescribeInstanceTypeOfferingsOutput{
+				InstanceTypeOfferings: fake.MakeInstanceOfferings(instances),
+			})
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypes(ctx)).To(Succeed())
+			Expect(awsEnv.InstanceTypesProvider.UpdateInstanceTypeOfferings(ctx)).To(Succeed())
+		})
+		It("should not set enclave or cpu options by default", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.EnclaveOptions).To(BeNil())
+				Expect(ltInput.LaunchTemplateData.CpuOptions).To(BeNil())
+			})
+		})
+		It("should enable nitro enclaves in the launch template", func() {
+			nodeClass.Spec.EnclaveOptions = &v1.EnclaveOptions{Enabled: true}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(corev1.LabelInstanceTypeStable, "m6a.large"))
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.ToBool(ltInput.LaunchTemplateData.EnclaveOptions.Enabled)).To(BeTrue())
+			})
+		})
+		It("should enable AMD SEV-SNP in the launch template", func() {
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{AMDSEVSNP: lo.ToPtr("enabled")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(corev1.LabelInstanceTypeStable, "m6a.large"))
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.CpuOptions.AmdSevSnp).To(Equal(ec2types.AmdSevSnpSpecificationEnabled))
+			})
+		})
+		It("should pass the core count and threads per core to the launch template", func() {
+			nodeClass.Spec.CPUOptions = &v1.CPUOptions{CoreCount: lo.ToPtr[int32](1), ThreadsPerCore: lo.ToPtr[int32](1)}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			node := ExpectScheduled(ctx, env.Client, pod)
+			Expect(node.Labels).To(HaveKeyWithValue(corev1.LabelInstanceTypeStable, "m6a.large"))
+			Expect(node.Labels).To(HaveKeyWithValue(v1.LabelInstanceThreadsPerCore, "1"))
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">=", 1))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(lo.FromPtr(ltInput.LaunchTemplateData.CpuOptions.CoreCount)).To(BeNumerically("==", 1))
+				Expect(lo.FromPtr(ltInput.LaunchTemplateData.CpuOptions.ThreadsPerCore)).To(BeNumerically("==", 1))
+				Expect(ltInput.LaunchTemplateData.CpuOptions.AmdSevSnp).To(BeEmpty())
+			})
+		})
+	})
+	Context("Credit Specification", func() {
+		It("should not set a credit specification by default", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically("==", 5))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.CreditSpecification).To(BeNil())
+			})
+		})
+		It("should pass the cpu credits to the launch template", func() {
+			nodeClass.Spec.CreditSpecification = &v1.CreditSpecification{CPUCredits: lo.ToPtr("unlimited")}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically("==", 5))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.ToString(ltInput.LaunchTemplateData.CreditSpecification.CpuCredits)).To(Equal("unlimited"))
+			})
+		})
+	})
+	Context("Private DNS Name Options", func() {
+		It("should not set private dns name options by default", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically("==", 5))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.PrivateDnsNameOptions).To(BeNil())
+			})
+		})
+		It("should pass the private dns name options to the launch template", func() {
+			nodeClass.Spec.PrivateDNSNameOptions = &v1.PrivateDNSNameOptions{
+				HostnameType:                    lo.ToPtr("resource-name"),
+				EnableResourceNameDNSARecord:    lo.ToPtr(true),
+				EnableResourceNameDNSAAAARecord: lo.ToPtr(false),
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically("==", 5))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.PrivateDnsNameOptions.HostnameType).To(Equal(ec2types.HostnameTypeResourceName))
+				Expect(aws.ToBool(ltInput.LaunchTemplateData.PrivateDnsNameOptions.EnableResourceNameDnsARecord)).To(BeTrue())
+				Expect(aws.ToBool(ltInput.LaunchTemplateData.PrivateDnsNameOptions.EnableResourceNameDnsAAAARecord)).To(BeFalse())
+			})
+		})
+	})
+	Context("Licensing", func() {
+		It("should associate license configurations with the launch template", func() {
+			nodeClass.Spec.Licensing = &v1.Licensing{
+				LicenseConfigurationARNs: []string{"arn:aws:license-manager:us-west-2:111122223333:license-configuration:lic-0123456789abcdef"},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.LicenseSpecifications).To(HaveLen(1))
+				Expect(aws.ToString(ltInput.LaunchTemplateData.LicenseSpecifications[0].LicenseConfigurationArn)).To(Equal(nodeClass.Spec.Licensing.LicenseConfigurationARNs[0]))
+			})
+		})
+		It("should apply compliance tags over the nodeclass tags", func() {
+			nodeClass.Spec.Tags = map[string]string{
+				"cost-center": "default",
+				"team":        "platform",
+			}
+			nodeClass.Spec.Licensing = &v1.Licensing{
+				LicenseConfigurationARNs: []string{"arn:aws:license-manager:us-west-2:111122223333:license-configuration:lic-0123456789abcdef"},
+				ComplianceTags:           map[string]string{"cost-center": "licensed"},
+			}
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Len()).To(Equal(1))
+			createFleetInput := awsEnv.EC2API.CreateFleetBehavior.CalledWithInput.Pop()
+			expected := map[string]string{"cost-center": "licensed", "team": "platform"}
+			for _, tagSpecification := range createFleetInput.TagSpecifications {
+				ExpectTags(tagSpecification.Tags, expected)
+			}
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				ExpectTags(ltInput.LaunchTemplateData.TagSpecifications[0].Tags, expected)
+			})
+		})
+	})
+	Context("Key Pairs", func() {
+		It("should not set a key pair by default", func() {
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(ltInput.LaunchTemplateData.KeyName).To(BeNil())
+			})
+		})
+		It("should set the key pair on the launch template", func() {
+			nodeClass.Spec.KeyName = aws.String("break-glass")
+			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
+			pod := coretest.UnschedulablePod()
+			ExpectProvisioned(ctx, env.Client, cluster, cloudProvider, prov, pod)
+			ExpectScheduled(ctx, env.Client, pod)
+			Expect(awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.Len()).To(BeNumerically(">", 0))
+			awsEnv.EC2API.CalledWithCreateLaunchTemplateInput.ForEach(func(ltInput *ec2.CreateLaunchTemplateInput) {
+				Expect(aws.ToString(ltInput.LaunchTemplateData.KeyName)).To(Equal("break-glass"))
+			})
+		})
+	})
 	Context("Instance Metadata", func() {
 		It("should set the default instance metadata settings on instances", func() {
 			ExpectApplied(ctx, env.Client, nodePool, nodeClass)
@@ -2227,12 +2738,15 @@ essential = true
 				func(ipFamily corev1.IPFamily) {
 					provider := launchtemplate.NewDefaultProvider(
 						ctx,
+						awsEnv.Clock,
 						awsEnv.LaunchTemplateCache,
 						awsEnv.EC2API,
 						awsEnv.EKSAPI,
 						awsEnv.AMIResolver,
 						awsEnv.SecurityGroupProvider,
 						awsEnv.SubnetProvider,
+						env.KubernetesInterface,
+						awsEnv.UserDataSecretCache,
 						awsEnv.LaunchTemplateProvider.CABundle,
 						make(chan struct{}),
 						net.ParseIP(lo.Ternary(ipFamily == corev1.IPv4Protocol, "10.0.100.10", "fd01:99f0:d47b::a")),
@@ -0,0 +1,189 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leakedresource
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+	awserrors "github.com/aws/karpenter-provider-aws/pkg/errors"
+	"github.com/aws/karpenter-provider-aws/pkg/operator/options"
+	"github.com/aws/karpenter-provider-aws/pkg/utils"
+)
+
+const (
+	// VPCCNIClusterNameTagKey is the tag that the VPC CNI applies to the network interfaces it creates for a cluster
+	VPCCNIClusterNameTagKey = "cluster.k8s.amazonaws.com/name"
+	// pvcNameTagKey is the tag that the EBS CSI driver applies to the volumes it provisions for PersistentVolumeClaims
+	pvcNameTagKey = "kubernetes.io/created-for/pvc/name"
+)
+
+type Provider interface {
+	// GarbageCollect deletes the network interfaces and volumes tagged for the cluster which have been detached for at
+	// least the passed TTL. Resources are only logged and counted if dryRun is set.
+	GarbageCollect(ctx context.Context, ttl time.Duration, dryRun bool) error
+}
+
+type resource struct {
+	id           string
+	resourceType ec2types.ResourceType
+}
+
+// DefaultProvider tracks when each detached resource was first seen, since EC2 doesn't report when a network interface or
+// volume was detached. The TTL is therefore measured from when the controller first observes the resource detached, so
+// a controller restart delays, but never hastens, deletion.
+type DefaultProvider struct {
+	ec2api sdk.EC2API
+	clk    clock.Clock
+
+	mu            sync.Mutex
+	detachedSince map[resource]time.Time
+}
+
+func NewDefaultProvider(clk clock.Clock, ec2api sdk.EC2API) *DefaultProvider {
+	return &DefaultProvider{
+		ec2api:        ec2api,
+		clk:           clk,
+		detachedSince: map[resource]time.Time{},
+	}
+}
+
+func (p *DefaultProvider) GarbageCollect(ctx context.Context, ttl time.Duration, dryRun bool) error {
+	clusterName := options.FromContext(ctx).ClusterName
+	networkInterfaces, err := p.detachedNetworkInterfaces(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	volumes, err := p.detachedVolumes(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	detached := append(networkInterfaces, volumes...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.clk.Now()
+	// Resources that are no longer detached, e.g. because they were reattached or deleted, are no longer tracked
+	p.detachedSince = lo.SliceToMap(detached, func(r resource) (resource, time.Time) {
+		return r, lo.ValueOr(p.detachedSince, r, now)
+	})
+	for _, resourceType := range []ec2types.ResourceType{ec2types.ResourceTypeNetworkInterface, ec2types.ResourceTypeVolume} {
+		LeakedResources.Set(float64(lo.CountBy(detached, func(r resource) bool { return r.resourceType == resourceType })),
+			map[string]string{resourceTypeLabel: string(resourceType)})
+	}
+	expired := lo.Filter(detached, func(r resource, _ int) bool { return now.Sub(p.detachedSince[r]) >= ttl })
+	if len(expired) == 0 {
+		return nil
+	}
+	if dryRun {
+		log.FromContext(ctx).WithValues("resources", utils.PrettySlice(lo.Map(expired, func(r resource, _ int) string { return r.id }), 5), "count", len(expired)).
+			Info("found leaked resources, skipping deletion in dry run")
+		return nil
+	}
+	var deleted []string
+	var errs error
+	for _, r := range expired {
+		if err := p.delete(ctx, r); awserrors.IgnoreNotFound(err) != nil {
+			errs = multierr.Append(errs, fmt.Errorf("deleting %s %s, %w", r.resourceType, r.id, err))
+			continue
+		}
+		delete(p.detachedSince, r)
+		deleted = append(deleted, r.id)
+		LeakedResourcesGarbageCollected.Inc(map[string]string{resourceTypeLabel: string(r.resourceType)})
+	}
+	if len(deleted) > 0 {
+		log.FromContext(ctx).WithValues("resources", utils.PrettySlice(deleted, 5), "count", len(deleted)).Info("garbage collected leaked resources")
+	}
+	return errs
+}
+
+func (p *DefaultProvider) delete(ctx context.Context, r resource) error {
+	if r.resourceType == ec2types.ResourceTypeNetworkInterface {
+		_, err := p.ec2api.DeleteNetworkInterface(ctx, &ec2.DeleteNetworkInterfaceInput{NetworkInterfaceId: aws.String(r.id)})
+		return err
+	}
+	_, err := p.ec2api.DeleteVolume(ctx, &ec2.DeleteVolumeInput{VolumeId: aws.String(r.id)})
+	return err
+}
+
+// detachedNetworkInterfaces returns the available network interfaces that were either launched by Karpenter with an
+// instance or created by the VPC CNI for the cluster
+func (p *DefaultProvider) detachedNetworkInterfaces(ctx context.Context, clusterName string) ([]resource, error) {
+	var resources []resource
+	for _, tagKey := range []string{v1.EKSClusterNameTagKey, VPCCNIClusterNameTagKey} {
+		paginator := ec2.NewDescribeNetworkInterfacesPaginator(p.ec2api, &ec2.DescribeNetworkInterfacesInput{
+			Filters: []ec2types.Filter{
+				{Name: aws.String("status"), Values: []string{string(ec2types.NetworkInterfaceStatusAvailable)}},
+				{Name: aws.String(fmt.Sprintf("tag:%s", tagKey)), Values: []string{clusterName}},
+			},
+		})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("describing network interfaces, %w", err)
+			}
+			for _, ni := range page.NetworkInterfaces {
+				resources = append(resources, resource{id: aws.ToString(ni.NetworkInterfaceId), resourceType: ec2types.ResourceTypeNetworkInterface})
+			}
+		}
+	}
+	return lo.Uniq(resources), nil
+}
+
+// detachedVolumes returns the available volumes that were launched by Karpenter with an instance. Volumes without the
+// NodePool tag, or that were provisioned for a PersistentVolumeClaim, may hold data that outlives the instance, so they
+// are never deleted.
+func (p *DefaultProvider) detachedVolumes(ctx context.Context, clusterName string) ([]resource, error) {
+	var resources []resource
+	paginator := ec2.NewDescribeVolumesPaginator(p.ec2api, &ec2.DescribeVolumesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("status"), Values: []string{string(ec2types.VolumeStateAvailable)}},
+			{Name: aws.String(fmt.Sprintf("tag:%s", v1.EKSClusterNameTagKey)), Values: []string{clusterName}},
+			{Name: aws.String("tag-key"), Values: []string{karpv1.NodePoolLabelKey}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing volumes, %w", err)
+		}
+		for _, volume := range page.Volumes {
+			if lo.ContainsBy(volume.Tags, func(t ec2types.Tag) bool { return aws.ToString(t.Key) == pvcNameTagKey }) {
+				continue
+			}
+			resources = append(resources, resource{id: aws.ToString(volume.VolumeId), resourceType: ec2types.ResourceTypeVolume})
+		}
+	}
+	return resources, nil
+}
+
+func (p *DefaultProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.detachedSince = map[resource]time.Time{}
+}
@@ -0,0 +1,51 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leakedresource
+
+import (
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	resourceTypeLabel      = "resource_type"
+)
+
+var (
+	LeakedResources = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "leaked_resources",
+			Help:      "Number of detached network interfaces and volumes tagged for the cluster, including those that haven't been detached for the garbage collection TTL yet. Labeled by resource type.",
+		},
+		[]string{resourceTypeLabel},
+	)
+	LeakedResourcesGarbageCollected = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "leaked_resources_garbage_collected_total",
+			Help:      "Number of leaked network interfaces and volumes deleted by the leaked resource garbage collector. Labeled by resource type.",
+		},
+		[]string{resourceTypeLabel},
+	)
+)
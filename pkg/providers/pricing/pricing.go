@@ -49,6 +49,9 @@ type Provider interface {
 	SpotPrice(ec2types.InstanceType, string) (float64, bool)
 	UpdateOnDemandPricing(context.Context) error
 	UpdateSpotPricing(context.Context) error
+	// LastUpdated returns the times of the last successful on-demand and spot pricing updates. The times are zero until
+	// the first successful update, which means the static initial pricing data is still in use.
+	LastUpdated() (onDemand time.Time, spot time.Time)
 }
 
 // DefaultProvider provides actual pricing data to the AWS cloud provider to allow it to make more informed decisions
@@ -63,12 +66,14 @@ type DefaultProvider struct {
 	region  string
 	cm      *pretty.ChangeMonitor
 
-	muOnDemand     sync.RWMutex
-	onDemandPrices map[ec2types.InstanceType]float64
+	muOnDemand      sync.RWMutex
+	onDemandPrices  map[ec2types.InstanceType]float64
+	onDemandUpdated time.Time
 
 	muSpot             sync.RWMutex
 	spotPrices         map[ec2types.InstanceType]zonal
 	spotPricingUpdated bool
+	spotUpdated        time.Time
 }
 
 // zonalPricing is used to capture the per-zone price
@@ -102,7 +107,10 @@ func NewAPI(cfg aws.Config) *pricing.Client {
 	//create pricing config using pricing endpoint
 	pricingCfg := cfg.Copy()
 	pricingCfg.Region = pricingAPIRegion
-	return pricing.NewFromConfig(pricingCfg)
+	// pricing API doesn't have FIPS endpoints, so we always use the standard endpoint
+	return pricing.NewFromConfig(pricingCfg, func(o *pricing.Options) {
+		o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateDisabled
+	})
 }
 
 func NewDefaultProvider(_ context.Context, pricing sdk.PricingAPI, ec2Api sdk.EC2API, region string) *DefaultProvider {
@@ -170,6 +178,13 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 		}
 		return nil
 	}
+	// the pricing api doesn't cover the GovCloud (US) partition, so we continue to use the static pricing data
+	if strings.HasPrefix(p.region, "us-gov-") {
+		if p.cm.HasChanged("on-demand-prices", nil) {
+			log.FromContext(ctx).V(1).Info("running in a GovCloud (US) region, on-demand pricing information will not be updated")
+		}
+		return nil
+	}
 
 	p.muOnDemand.Lock()
 	defer p.muOnDemand.Unlock()
@@ -219,6 +234,7 @@ func (p *DefaultProvider) UpdateOnDemandPricing(ctx context.Context) error {
 	}
 
 	p.onDemandPrices = lo.Assign(onDemandPrices, onDemandMetalPrices)
+	p.onDemandUpdated = time.Now()
 	if p.cm.HasChanged("on-demand-prices", p.onDemandPrices) {
 		log.FromContext(ctx).WithValues("instance-type-count", len(p.onDemandPrices)).V(1).Info("updated on-demand pricing")
 	}
@@ -390,6 +406,7 @@ func (p *DefaultProvider) UpdateSpotPricing(ctx context.Context) error {
 	}
 
 	p.spotPricingUpdated = true
+	p.spotUpdated = time.Now()
 	if p.cm.HasChanged("spot-prices", p.spotPrices) {
 		log.FromContext(ctx).WithValues(
 			"instance-type-count", len(p.onDemandPrices),
@@ -398,6 +415,14 @@ func (p *DefaultProvider) UpdateSpotPricing(ctx context.Context) error {
 	return nil
 }
 
+func (p *DefaultProvider) LastUpdated() (time.Time, time.Time) {
+	p.muOnDemand.RLock()
+	defer p.muOnDemand.RUnlock()
+	p.muSpot.RLock()
+	defer p.muSpot.RUnlock()
+	return p.onDemandUpdated, p.spotUpdated
+}
+
 func (p *DefaultProvider) LivenessProbe(_ *http.Request) error {
 	// ensure we don't deadlock and nolint for the empty critical section
 	p.muOnDemand.Lock()
@@ -428,4 +453,6 @@ func (p *DefaultProvider) Reset() {
 	// default our spot pricing to the same as the on-demand pricing until a price update
 	p.spotPrices = populateInitialSpotPricing(staticPricing)
 	p.spotPricingUpdated = false
+	p.onDemandUpdated = time.Time{}
+	p.spotUpdated = time.Time{}
 }
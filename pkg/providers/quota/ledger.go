@@ -0,0 +1,146 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// LedgerConfigMapName is the ConfigMap in the hub cluster that the launches of every cluster are recorded in, keyed by
+	// cluster name
+	LedgerConfigMapName = "karpenter-capacity-ledger"
+	// LedgerTTL is how long a launch is kept in the ledger. It's longer than the interval that quotas and usage are
+	// refreshed at, so every cluster has counted a launch among its running instances before it's dropped.
+	LedgerTTL = 10 * time.Minute
+)
+
+// Launch is the vCPUs of an instance that was launched against one of the vCPU quotas
+type Launch struct {
+	Class        Class     `json:"class"`
+	CapacityType string    `json:"capacityType"`
+	VCPUs        float64   `json:"vcpus"`
+	Time         time.Time `json:"time"`
+}
+
+// Ledger shares the launches of the Karpenter controllers of every cluster in an account, so that each controller accounts
+// for the launches of the others that it hasn't yet seen among the account's running instances, rather than racing them
+// for the remaining quota.
+type Ledger interface {
+	// Record adds the launch to the ledger, returning the launches that every cluster has recorded
+	Record(context.Context, Launch) ([]Launch, error)
+	// List returns the launches that every cluster has recorded
+	List(context.Context) ([]Launch, error)
+}
+
+// ConfigMapLedger records launches in a ConfigMap in a hub cluster that's shared by the clusters in the account. Each
+// cluster only writes its own key, and writes are serialized by the ConfigMap's resource version.
+type ConfigMapLedger struct {
+	kubernetesInterface kubernetes.Interface
+	namespace           string
+	clusterName         string
+	clk                 clock.Clock
+}
+
+func NewConfigMapLedger(kubernetesInterface kubernetes.Interface, namespace string, clusterName string, clk clock.Clock) *ConfigMapLedger {
+	return &ConfigMapLedger{
+		kubernetesInterface: kubernetesInterface,
+		namespace:           namespace,
+		clusterName:         clusterName,
+		clk:                 clk,
+	}
+}
+
+func (l *ConfigMapLedger) Record(ctx context.Context, launch Launch) ([]Launch, error) {
+	var launches []Launch
+	if err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+	}, func() error {
+		configMap, err := l.kubernetesInterface.CoreV1().ConfigMaps(l.namespace).Get(ctx, LedgerConfigMapName, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("getting configmap, %w", err)
+		}
+		found := err == nil
+		if !found {
+			configMap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: LedgerConfigMapName, Namespace: l.namespace}}
+		}
+		ledger := l.parse(ctx, configMap)
+		ledger[l.clusterName] = append(ledger[l.clusterName], launch)
+		data, err := json.Marshal(ledger[l.clusterName])
+		if err != nil {
+			return fmt.Errorf("marshaling launches, %w", err)
+		}
+		configMap.Data = lo.Assign(configMap.Data, map[string]string{l.clusterName: string(data)})
+		if !found {
+			_, err = l.kubernetesInterface.CoreV1().ConfigMaps(l.namespace).Create(ctx, configMap, metav1.CreateOptions{})
+		} else {
+			_, err = l.kubernetesInterface.CoreV1().ConfigMaps(l.namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return err
+		}
+		launches = lo.Flatten(lo.Values(ledger))
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("recording launch in capacity ledger, %w", err)
+	}
+	return sortLaunches(launches), nil
+}
+
+func (l *ConfigMapLedger) List(ctx context.Context) ([]Launch, error) {
+	configMap, err := l.kubernetesInterface.CoreV1().ConfigMaps(l.namespace).Get(ctx, LedgerConfigMapName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting capacity ledger configmap, %w", err)
+	}
+	return sortLaunches(lo.Flatten(lo.Values(l.parse(ctx, configMap)))), nil
+}
+
+// parse returns the launches in the ConfigMap that haven't expired, keyed by cluster name. Clusters whose launches can't
+// be parsed are ignored rather than blocking the ledger.
+func (l *ConfigMapLedger) parse(ctx context.Context, configMap *corev1.ConfigMap) map[string][]Launch {
+	ledger := map[string][]Launch{}
+	for clusterName, value := range configMap.Data {
+		var launches []Launch
+		if err := json.Unmarshal([]byte(value), &launches); err != nil {
+			log.FromContext(ctx).WithValues("cluster", clusterName).Error(err, "ignoring invalid capacity ledger entry")
+			continue
+		}
+		ledger[clusterName] = lo.Filter(launches, func(launch Launch, _ int) bool {
+			return l.clk.Since(launch.Time) < LedgerTTL
+		})
+	}
+	return ledger
+}
+
+func sortLaunches(launches []Launch) []Launch {
+	sort.SliceStable(launches, func(i, j int) bool { return launches[i].Time.Before(launches[j].Time) })
+	return launches
+}
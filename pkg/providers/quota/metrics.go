@@ -0,0 +1,71 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	cloudProviderSubsystem = "cloudprovider"
+	quotaClassLabel        = "quota_class"
+	capacityTypeLabel      = "capacity_type"
+)
+
+var (
+	VCPUQuota = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "vcpu_quota",
+			Help:      "EC2 vCPU quota for running instances, based on quota class and capacity type.",
+		},
+		[]string{
+			quotaClassLabel,
+			capacityTypeLabel,
+		},
+	)
+	VCPUQuotaUsage = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "vcpu_quota_usage",
+			Help:      "vCPUs of running instances counted against the EC2 vCPU quota, based on quota class and capacity type.",
+		},
+		[]string{
+			quotaClassLabel,
+			capacityTypeLabel,
+		},
+	)
+	VCPUQuotaUtilization = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: cloudProviderSubsystem,
+			Name:      "vcpu_quota_utilization",
+			Help:      "Fraction of the EC2 vCPU quota in use, based on quota class and capacity type. Values approaching 1 indicate that launches will soon be refused.",
+		},
+		[]string{
+			quotaClassLabel,
+			capacityTypeLabel,
+		},
+	)
+)
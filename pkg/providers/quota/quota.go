@@ -0,0 +1,336 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/servicequotas"
+	servicequotastypes "github.com/aws/aws-sdk-go-v2/service/servicequotas/types"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+	"sigs.k8s.io/karpenter/pkg/utils/pretty"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+// Class is an EC2 vCPU quota class. Running On-Demand and Spot vCPU quotas are applied per class rather than per instance type.
+type Class string
+
+const (
+	ClassStandard Class = "standard"
+	ClassF        Class = "f"
+	ClassG        Class = "g"
+	ClassInf      Class = "inf"
+	ClassP        Class = "p"
+	ClassX        Class = "x"
+	ClassDL       Class = "dl"
+	ClassTrn      Class = "trn"
+	ClassHPC      Class = "hpc"
+)
+
+type Key struct {
+	Class        Class
+	CapacityType string
+}
+
+// quotaCodes maps each quota class and capacity type to the Service Quotas code of the corresponding EC2 vCPU quota
+var quotaCodes = map[Key]string{
+	{ClassStandard, karpv1.CapacityTypeOnDemand}: "L-1216C47A",
+	{ClassF, karpv1.CapacityTypeOnDemand}:        "L-74FC7D96",
+	{ClassG, karpv1.CapacityTypeOnDemand}:        "L-DB2E81BA",
+	{ClassInf, karpv1.CapacityTypeOnDemand}:      "L-1945791B",
+	{ClassP, karpv1.CapacityTypeOnDemand}:        "L-417A185B",
+	{ClassX, karpv1.CapacityTypeOnDemand}:        "L-7295265B",
+	{ClassDL, karpv1.CapacityTypeOnDemand}:       "L-6E869C2A",
+	{ClassTrn, karpv1.CapacityTypeOnDemand}:      "L-2C3B7624",
+	{ClassHPC, karpv1.CapacityTypeOnDemand}:      "L-F7808C92",
+	{ClassStandard, karpv1.CapacityTypeSpot}:     "L-34B43A08",
+	{ClassF, karpv1.CapacityTypeSpot}:            "L-88CF9481",
+	{ClassG, karpv1.CapacityTypeSpot}:            "L-3819A6DF",
+	{ClassInf, karpv1.CapacityTypeSpot}:          "L-B5D1601B",
+	{ClassP, karpv1.CapacityTypeSpot}:            "L-7212CCBC",
+	{ClassX, karpv1.CapacityTypeSpot}:            "L-E3A00192",
+	{ClassDL, karpv1.CapacityTypeSpot}:           "L-85EED4F7",
+	{ClassTrn, karpv1.CapacityTypeSpot}:          "L-6B0D517C",
+}
+
+// familyPrefixClasses maps instance family prefixes that don't fall into the standard quota class
+var familyPrefixClasses = map[string]Class{
+	"f":   ClassF,
+	"g":   ClassG,
+	"gr":  ClassG,
+	"vt":  ClassG,
+	"inf": ClassInf,
+	"p":   ClassP,
+	"x":   ClassX,
+	"dl":  ClassDL,
+	"trn": ClassTrn,
+	"hpc": ClassHPC,
+}
+
+type Provider interface {
+	// Remaining returns the number of vCPUs that may still be launched for the instance type and capacity type before
+	// exceeding the applicable EC2 quota. The second return value is false if the quota is not known.
+	Remaining(ec2types.InstanceType, string) (float64, bool)
+	// SeqNum is a monotonically increasing counter that changes whenever quota or usage data changes
+	SeqNum() uint64
+	UpdateQuotas(context.Context) error
+	// RecordLaunch counts the vCPUs of a launched instance against its quota until the account's running instances are
+	// next refreshed, sharing it with the other clusters in the account if there's a capacity ledger
+	RecordLaunch(context.Context, ec2types.InstanceType, string, float64)
+	// SyncLedger refreshes the launches that the other clusters in the account have recorded in the capacity ledger
+	SyncLedger(context.Context) error
+}
+
+// DefaultProvider tracks EC2 vCPU quotas from the Service Quotas API along with the vCPUs consumed by running instances in
+// the region. Quotas are not enforced until they have been successfully retrieved, so the provider is a no-op until updated.
+// Launches since the running instances were last described, including those of other clusters if there's a capacity
+// ledger, are counted as consumed as well.
+type DefaultProvider struct {
+	clk           clock.Clock
+	ec2           sdk.EC2API
+	serviceQuotas sdk.ServiceQuotasAPI
+	ledger        Ledger
+	cm            *pretty.ChangeMonitor
+
+	mu        sync.RWMutex
+	quotas    map[Key]float64
+	usage     map[Key]float64
+	usageTime time.Time
+	launches  []Launch
+	seqNum    uint64
+}
+
+// NewDefaultProvider returns a quota provider. The ledger is optional, and launches are only counted by the cluster that
+// made them if it's nil.
+func NewDefaultProvider(clk clock.Clock, ec2api sdk.EC2API, serviceQuotasAPI sdk.ServiceQuotasAPI, ledger Ledger) *DefaultProvider {
+	return &DefaultProvider{
+		clk:           clk,
+		ec2:           ec2api,
+		serviceQuotas: serviceQuotasAPI,
+		ledger:        ledger,
+		cm:            pretty.NewChangeMonitor(),
+		quotas:        map[Key]float64{},
+		usage:         map[Key]float64{},
+	}
+}
+
+func (p *DefaultProvider) Remaining(instanceType ec2types.InstanceType, capacityType string) (float64, bool) {
+	class, ok := ClassFor(instanceType)
+	if !ok {
+		return 0, false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	key := Key{Class: class, CapacityType: capacityType}
+	quota, ok := p.quotas[key]
+	if !ok {
+		return 0, false
+	}
+	return quota - p.usage[key] - p.launched(key), true
+}
+
+// launched sums the vCPUs of the launches against the quota that weren't yet running when the running instances were
+// last described
+func (p *DefaultProvider) launched(key Key) float64 {
+	return lo.SumBy(p.launches, func(launch Launch) float64 {
+		return lo.Ternary(launch.Class == key.Class && launch.CapacityType == key.CapacityType && !launch.Time.Before(p.usageTime), launch.VCPUs, 0)
+	})
+}
+
+func (p *DefaultProvider) RecordLaunch(ctx context.Context, instanceType ec2types.InstanceType, capacityType string, vcpus float64) {
+	class, ok := ClassFor(instanceType)
+	if !ok {
+		return
+	}
+	launch := Launch{Class: class, CapacityType: capacityType, VCPUs: vcpus, Time: p.clk.Now()}
+	p.mu.Lock()
+	p.launches = append(p.launches, launch)
+	p.mu.Unlock()
+	atomic.AddUint64(&p.seqNum, 1)
+	if p.ledger == nil {
+		return
+	}
+	// Failing to share the launch doesn't fail it, since the other clusters will still see the instance once they next
+	// describe the running instances
+	launches, err := p.ledger.Record(ctx, launch)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "failed recording launch in capacity ledger")
+		return
+	}
+	p.setLaunches(ctx, launches)
+}
+
+func (p *DefaultProvider) SyncLedger(ctx context.Context) error {
+	if p.ledger == nil {
+		return nil
+	}
+	launches, err := p.ledger.List(ctx)
+	if err != nil {
+		return err
+	}
+	p.setLaunches(ctx, launches)
+	return nil
+}
+
+func (p *DefaultProvider) setLaunches(ctx context.Context, launches []Launch) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.launches = launches
+	if p.cm.HasChanged("capacity-ledger", launches) {
+		atomic.AddUint64(&p.seqNum, 1)
+		log.FromContext(ctx).WithValues("launches", len(launches)).V(1).Info("synced capacity ledger")
+	}
+}
+
+func (p *DefaultProvider) SeqNum() uint64 {
+	return atomic.LoadUint64(&p.seqNum)
+}
+
+func (p *DefaultProvider) UpdateQuotas(ctx context.Context) error {
+	quotas, err := p.getQuotas(ctx)
+	if err != nil {
+		return err
+	}
+	// Instances launched while the running instances are being described may or may not be included, so the launches
+	// from then on are still counted
+	usageTime := p.clk.Now()
+	usage, err := p.getUsage(ctx)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.quotas = quotas
+	p.usage = usage
+	p.usageTime = usageTime
+	if p.ledger == nil {
+		p.launches = lo.Filter(p.launches, func(launch Launch, _ int) bool { return !launch.Time.Before(usageTime) })
+	}
+	for key, quota := range quotas {
+		labels := map[string]string{quotaClassLabel: string(key.Class), capacityTypeLabel: key.CapacityType}
+		VCPUQuota.Set(quota, labels)
+		VCPUQuotaUsage.Set(usage[key], labels)
+		VCPUQuotaUtilization.Set(lo.Ternary(quota > 0, usage[key]/quota, 1), labels)
+	}
+	quotasChanged := p.cm.HasChanged("vcpu-quotas", quotas)
+	if usageChanged := p.cm.HasChanged("vcpu-usage", usage); quotasChanged || usageChanged {
+		atomic.AddUint64(&p.seqNum, 1)
+		log.FromContext(ctx).WithValues("quotas", len(quotas)).V(1).Info("updated vcpu quotas and usage")
+	}
+	return nil
+}
+
+func (p *DefaultProvider) getQuotas(ctx context.Context) (map[Key]float64, error) {
+	quotas := map[Key]float64{}
+	var errs error
+	for key, code := range quotaCodes {
+		out, err := p.serviceQuotas.GetServiceQuota(ctx, &servicequotas.GetServiceQuotaInput{
+			ServiceCode: aws.String("ec2"),
+			QuotaCode:   aws.String(code),
+		})
+		if err != nil {
+			// Not every quota exists in every region, so we don't enforce quotas which can't be found
+			var nsr *servicequotastypes.NoSuchResourceException
+			if errors.As(err, &nsr) {
+				continue
+			}
+			errs = multierr.Append(errs, fmt.Errorf("getting service quota %s, %w", code, err))
+			continue
+		}
+		if out.Quota == nil || out.Quota.Value == nil {
+			continue
+		}
+		quotas[key] = lo.FromPtr(out.Quota.Value)
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return quotas, nil
+}
+
+// getUsage sums the vCPUs of all pending and running instances in the region, not just those launched by Karpenter,
+// since the quotas are applied to the account as a whole
+func (p *DefaultProvider) getUsage(ctx context.Context) (map[Key]float64, error) {
+	usage := map[Key]float64{}
+	paginator := ec2.NewDescribeInstancesPaginator(p.ec2, &ec2.DescribeInstancesInput{
+		Filters: []ec2types.Filter{
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: []string{string(ec2types.InstanceStateNamePending), string(ec2types.InstanceStateNameRunning)},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing instances, %w", err)
+		}
+		for _, reservation := range page.Reservations {
+			for _, instance := range reservation.Instances {
+				class, ok := ClassFor(instance.InstanceType)
+				if !ok || instance.CpuOptions == nil {
+					continue
+				}
+				key := Key{
+					Class:        class,
+					CapacityType: lo.Ternary(instance.InstanceLifecycle == ec2types.InstanceLifecycleTypeSpot, karpv1.CapacityTypeSpot, karpv1.CapacityTypeOnDemand),
+				}
+				usage[key] += float64(lo.FromPtr(instance.CpuOptions.CoreCount) * lo.FromPtr(instance.CpuOptions.ThreadsPerCore))
+			}
+		}
+	}
+	return usage, nil
+}
+
+// ClassFor returns the vCPU quota class that an instance type counts against. Instance types which aren't covered by a
+// vCPU quota (e.g. high memory "u-" and "mac" instances) return false.
+func ClassFor(instanceType ec2types.InstanceType) (Class, bool) {
+	family := strings.ToLower(strings.Split(string(instanceType), ".")[0])
+	prefix := family[:strings.IndexFunc(family+"0", unicode.IsDigit)]
+	if prefix == "" || strings.HasPrefix(family, "u-") || strings.HasPrefix(family, "mac") {
+		return "", false
+	}
+	if class, ok := familyPrefixClasses[prefix]; ok {
+		return class, true
+	}
+	if strings.ContainsRune("acdhimrtz", rune(prefix[0])) {
+		return ClassStandard, true
+	}
+	return "", false
+}
+
+func (p *DefaultProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.quotas = map[Key]float64{}
+	p.usage = map[Key]float64{}
+	p.usageTime = time.Time{}
+	p.launches = nil
+}
@@ -0,0 +1,61 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqs
+
+import (
+	opmetrics "github.com/awslabs/operatorpkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"sigs.k8s.io/karpenter/pkg/metrics"
+)
+
+const (
+	interruptionSubsystem = "interruption"
+	queueLabel            = "queue"
+)
+
+var (
+	QueueHealthy = opmetrics.NewPrometheusGauge(
+		crmetrics.Registry,
+		prometheus.GaugeOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "queue_healthy",
+			Help:      "Whether the last attempt to receive messages from the interruption queue succeeded, 1 if it did and 0 otherwise. Labeled by queue.",
+		},
+		[]string{queueLabel},
+	)
+	QueueReceivedMessages = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "queue_received_messages_total",
+			Help:      "Count of messages received from the interruption queue. Labeled by queue.",
+		},
+		[]string{queueLabel},
+	)
+	QueueReceiveErrors = opmetrics.NewPrometheusCounter(
+		crmetrics.Registry,
+		prometheus.CounterOpts{
+			Namespace: metrics.Namespace,
+			Subsystem: interruptionSubsystem,
+			Name:      "queue_receive_errors_total",
+			Help:      "Count of failed attempts to receive messages from the interruption queue. Labeled by queue.",
+		},
+		[]string{queueLabel},
+	)
+)
@@ -0,0 +1,118 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/samber/lo"
+	"go.uber.org/multierr"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// MultiProvider multiplexes several interruption queues, e.g. the queues of NodeClasses that launch into other accounts or
+// regions, into a single Provider. Messages are received from every queue concurrently, and deleting a message or changing
+// its visibility is routed back to the queue that it was received from. A queue that can't be received from doesn't block
+// the others, so an error is only returned if every queue fails. Messages are sent to the first queue.
+type MultiProvider struct {
+	providers []Provider
+
+	mu sync.RWMutex
+	// sources maps the IDs of the messages from the latest receive to the provider that they were received from. Messages
+	// are expected to be deleted or left to become visible again before the next receive.
+	sources map[string]Provider
+}
+
+func NewMultiProvider(primary Provider, additional ...Provider) *MultiProvider {
+	return &MultiProvider{
+		providers: append([]Provider{primary}, additional...),
+		sources:   map[string]Provider{},
+	}
+}
+
+func (p *MultiProvider) Name() string {
+	return strings.Join(lo.Map(p.providers, func(provider Provider, _ int) string { return provider.Name() }), ",")
+}
+
+func (p *MultiProvider) GetSQSMessages(ctx context.Context) ([]*sqstypes.Message, error) {
+	msgs := make([][]*sqstypes.Message, len(p.providers))
+	errs := make([]error, len(p.providers))
+	workqueue.ParallelizeUntil(ctx, len(p.providers), len(p.providers), func(i int) {
+		labels := map[string]string{queueLabel: p.providers[i].Name()}
+		msgs[i], errs[i] = p.providers[i].GetSQSMessages(ctx)
+		if errs[i] != nil {
+			QueueHealthy.Set(0, labels)
+			QueueReceiveErrors.Inc(labels)
+			return
+		}
+		QueueHealthy.Set(1, labels)
+		QueueReceivedMessages.Add(float64(len(msgs[i])), labels)
+	})
+	sources := map[string]Provider{}
+	for i, provider := range p.providers {
+		if errs[i] != nil && len(p.providers) > 1 {
+			log.FromContext(ctx).WithValues("queue", provider.Name()).Error(errs[i], "failed receiving interruption messages")
+		}
+		for _, msg := range msgs[i] {
+			sources[lo.FromPtr(msg.MessageId)] = provider
+		}
+	}
+	p.mu.Lock()
+	p.sources = sources
+	p.mu.Unlock()
+	if lo.EveryBy(errs, func(err error) bool { return err != nil }) {
+		return nil, multierr.Combine(errs...)
+	}
+	return lo.Flatten(msgs), nil
+}
+
+func (p *MultiProvider) SendMessage(ctx context.Context, body interface{}) (string, error) {
+	return p.providers[0].SendMessage(ctx, body)
+}
+
+func (p *MultiProvider) SendSQSMessage(ctx context.Context, msg *sqstypes.Message) (string, error) {
+	return p.providers[0].SendSQSMessage(ctx, msg)
+}
+
+func (p *MultiProvider) DeleteSQSMessage(ctx context.Context, msg *sqstypes.Message) error {
+	return p.source(msg).DeleteSQSMessage(ctx, msg)
+}
+
+func (p *MultiProvider) ChangeSQSMessageVisibility(ctx context.Context, msgs []*sqstypes.Message, timeout time.Duration) error {
+	var errs error
+	for provider, providerMsgs := range lo.GroupBy(msgs, p.source) {
+		if err := provider.ChangeSQSMessageVisibility(ctx, providerMsgs, timeout); err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("queue %s, %w", provider.Name(), err))
+		}
+	}
+	return errs
+}
+
+// source returns the provider that the message was received from, falling back to the first queue for messages that
+// weren't received by the latest receive
+func (p *MultiProvider) source(msg *sqstypes.Message) Provider {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if provider, ok := p.sources[lo.FromPtr(msg.MessageId)]; ok {
+		return provider
+	}
+	return p.providers[0]
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
@@ -28,11 +29,19 @@ import (
 	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 )
 
+// VisibilityTimeout is the time that received messages are hidden from other consumers. Messages that take longer than
+// this to process must have their visibility extended, otherwise they're redelivered while they're still being processed.
+const VisibilityTimeout = 20 * time.Second
+
 type Provider interface {
 	Name() string
 	GetSQSMessages(context.Context) ([]*sqstypes.Message, error)
 	SendMessage(context.Context, interface{}) (string, error)
+	// SendSQSMessage sends a copy of the passed message's body and message attributes to the queue
+	SendSQSMessage(context.Context, *sqstypes.Message) (string, error)
 	DeleteSQSMessage(context.Context, *sqstypes.Message) error
+	// ChangeSQSMessageVisibility hides the passed messages from other consumers for the passed duration from now
+	ChangeSQSMessageVisibility(context.Context, []*sqstypes.Message, time.Duration) error
 }
 
 type DefaultProvider struct {
@@ -56,7 +65,7 @@ func (p *DefaultProvider) Name() string {
 func (p *DefaultProvider) GetSQSMessages(ctx context.Context) ([]*sqstypes.Message, error) {
 	input := &sqs.ReceiveMessageInput{
 		MaxNumberOfMessages: int32(10),
-		VisibilityTimeout:   int32(20), // Seconds
+		VisibilityTimeout:   int32(VisibilityTimeout.Seconds()),
 		WaitTimeSeconds:     int32(20), // Seconds, maximum for long polling
 		AttributeNames: []sqstypes.QueueAttributeName{
 			sqstypes.QueueAttributeName(sqstypes.MessageSystemAttributeNameSentTimestamp),
@@ -91,6 +100,19 @@ func (p *DefaultProvider) SendMessage(ctx context.Context, body interface{}) (st
 	return aws.ToString(result.MessageId), nil
 }
 
+func (p *DefaultProvider) SendSQSMessage(ctx context.Context, msg *sqstypes.Message) (string, error) {
+	input := &sqs.SendMessageInput{
+		MessageBody:       msg.Body,
+		MessageAttributes: msg.MessageAttributes,
+		QueueUrl:          aws.String(p.queueURL),
+	}
+	result, err := p.client.SendMessage(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("sending messages to sqs queue, %w", err)
+	}
+	return aws.ToString(result.MessageId), nil
+}
+
 func (p *DefaultProvider) DeleteSQSMessage(ctx context.Context, msg *sqstypes.Message) error {
 	input := &sqs.DeleteMessageInput{
 		QueueUrl:      aws.String(p.queueURL),
@@ -102,3 +124,28 @@ func (p *DefaultProvider) DeleteSQSMessage(ctx context.Context, msg *sqstypes.Me
 	}
 	return nil
 }
+
+func (p *DefaultProvider) ChangeSQSMessageVisibility(ctx context.Context, msgs []*sqstypes.Message, timeout time.Duration) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	// ReceiveMessage returns at most 10 messages, which is also the maximum number of entries in a batch
+	input := &sqs.ChangeMessageVisibilityBatchInput{
+		QueueUrl: aws.String(p.queueURL),
+		Entries: lo.Map(msgs, func(msg *sqstypes.Message, i int) sqstypes.ChangeMessageVisibilityBatchRequestEntry {
+			return sqstypes.ChangeMessageVisibilityBatchRequestEntry{
+				Id:                aws.String(fmt.Sprint(i)),
+				ReceiptHandle:     msg.ReceiptHandle,
+				VisibilityTimeout: int32(timeout.Seconds()),
+			}
+		}),
+	}
+	out, err := p.client.ChangeMessageVisibilityBatch(ctx, input)
+	if err != nil {
+		return fmt.Errorf("changing sqs message visibility, %w", err)
+	}
+	if len(out.Failed) > 0 {
+		return fmt.Errorf("changing sqs message visibility, %d of %d messages failed, %s", len(out.Failed), len(msgs), aws.ToString(out.Failed[0].Message))
+	}
+	return nil
+}
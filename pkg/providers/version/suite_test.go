@@ -28,6 +28,7 @@ import (
 	"github.com/aws/karpenter-provider-aws/pkg/test"
 
 	controllersversion "github.com/aws/karpenter-provider-aws/pkg/controllers/providers/version"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
 	environmentaws "github.com/aws/karpenter-provider-aws/test/pkg/environment/aws"
 	"github.com/aws/karpenter-provider-aws/test/pkg/environment/common"
 
@@ -94,3 +95,33 @@ var _ = Describe("Operator", func() {
 		})
 	})
 })
+
+var _ = Describe("Kubelet Version Skew", func() {
+	DescribeTable("should parse the kubelet version from the AMI name",
+		func(name string, expected string, expectedOK bool) {
+			kubeletVersion, ok := version.AMIKubeletVersion(name)
+			Expect(ok).To(Equal(expectedOK))
+			Expect(kubeletVersion).To(Equal(expected))
+		},
+		Entry("AL2", "amazon-eks-node-1.30-v20240807", "1.30", true),
+		Entry("AL2023", "amazon-eks-node-al2023-arm64-standard-1.29-v20240807", "1.29", true),
+		Entry("Bottlerocket", "bottlerocket-aws-k8s-1.30-nvidia-x86_64-v1.21.0-d3bfc2de", "1.30", true),
+		Entry("Windows", "Windows_Server-2022-English-Core-EKS_Optimized-1.28-2024.08.13", "1.28", true),
+		Entry("Custom", "my-golden-image-2024.08.13", "", false),
+	)
+	DescribeTable("should enforce the kubelet version skew",
+		func(kubeletVersion string, expected bool) {
+			Expect(version.WithinSkew(kubeletVersion, "1.30", 2)).To(Equal(expected))
+		},
+		Entry("newer", "1.31", false),
+		Entry("same", "1.30", true),
+		Entry("older within the skew", "v1.28.5-eks-5e0fdde", true),
+		Entry("older outside of the skew", "1.27", false),
+		Entry("unparseable", "latest", true),
+	)
+	It("should detect kubelets older than the control plane", func() {
+		Expect(version.IsOlderMinor("v1.29.3-eks-ae9a62a", "1.30")).To(BeTrue())
+		Expect(version.IsOlderMinor("v1.30.3-eks-ae9a62a", "1.30")).To(BeFalse())
+		Expect(version.IsOlderMinor("", "1.30")).To(BeFalse())
+	})
+})
@@ -17,6 +17,7 @@ package version
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync/atomic"
@@ -119,6 +120,51 @@ func validateK8sVersion(v string) error {
 	return nil
 }
 
+// amiNameVersion matches the Kubernetes version in the name of an EKS optimized AMI, e.g. amazon-eks-node-al2023-x86_64-standard-1.30-v20240807,
+// bottlerocket-aws-k8s-1.30-x86_64-v1.21.0-d3bfc2de or Windows_Server-2022-English-Core-EKS_Optimized-1.30-2024.08.13
+var amiNameVersion = regexp.MustCompile(`(?:^|[-_])(1\.[0-9]+)(?:[-_]|$)`)
+
+// AMIKubeletVersion returns the major.minor version of the kubelet of an AMI from its name, or false if the name doesn't
+// contain a Kubernetes version
+func AMIKubeletVersion(name string) (string, bool) {
+	match := amiNameVersion.FindStringSubmatch(name)
+	if match == nil {
+		return "", false
+	}
+	return match[1], true
+}
+
+// WithinSkew returns true if the kubelet version is no newer than the control plane version, and no more than maxSkew
+// minor versions older than it. Versions that can't be parsed are treated as being within the skew.
+func WithinSkew(kubeletVersion string, controlPlaneVersion string, maxSkew int) bool {
+	kubelet, err := version.ParseGeneric(kubeletVersion)
+	if err != nil {
+		return true
+	}
+	controlPlane, err := version.ParseGeneric(controlPlaneVersion)
+	if err != nil {
+		return true
+	}
+	if kubelet.Major() != controlPlane.Major() {
+		return false
+	}
+	return kubelet.Minor() <= controlPlane.Minor() && controlPlane.Minor()-kubelet.Minor() <= uint(maxSkew) //nolint:gosec
+}
+
+// IsOlderMinor returns true if the kubelet version is at least one minor version older than the control plane version.
+// Versions that can't be parsed are treated as being up to date.
+func IsOlderMinor(kubeletVersion string, controlPlaneVersion string) bool {
+	kubelet, err := version.ParseGeneric(kubeletVersion)
+	if err != nil {
+		return false
+	}
+	controlPlane, err := version.ParseGeneric(controlPlaneVersion)
+	if err != nil {
+		return false
+	}
+	return kubelet.Major() < controlPlane.Major() || (kubelet.Major() == controlPlane.Major() && kubelet.Minor() < controlPlane.Minor())
+}
+
 func (p *DefaultProvider) getEKSVersion(ctx context.Context) (string, error) {
 	output, err := p.eksapi.DescribeCluster(ctx, &eks.DescribeClusterInput{
 		Name: lo.ToPtr(options.FromContext(ctx).ClusterName),
@@ -0,0 +1,81 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vpcendpoint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/patrickmn/go-cache"
+	"github.com/samber/lo"
+
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
+)
+
+// RequiredServices are the services that nodes in a VPC without internet egress need VPC endpoints for to bootstrap.
+// Nodes pull images from ECR, which stores image layers in S3, and authenticate to the cluster through STS.
+var RequiredServices = []string{"ec2", "ecr.api", "ecr.dkr", "s3", "sts"}
+
+type Provider interface {
+	// MissingServices returns the RequiredServices which the VPC doesn't have an available VPC endpoint for
+	MissingServices(context.Context, string) ([]string, error)
+}
+
+type DefaultProvider struct {
+	region string
+	ec2api sdk.EC2API
+	cache  *cache.Cache
+}
+
+func NewDefaultProvider(region string, ec2api sdk.EC2API, cache *cache.Cache) *DefaultProvider {
+	return &DefaultProvider{
+		region: region,
+		ec2api: ec2api,
+		cache:  cache,
+	}
+}
+
+func (p *DefaultProvider) MissingServices(ctx context.Context, vpcID string) ([]string, error) {
+	if missing, ok := p.cache.Get(vpcID); ok {
+		return missing.([]string), nil
+	}
+	var serviceNames []string
+	paginator := ec2.NewDescribeVpcEndpointsPaginator(p.ec2api, &ec2.DescribeVpcEndpointsInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("vpc-id"), Values: []string{vpcID}},
+			{Name: aws.String("vpc-endpoint-state"), Values: []string{string(ec2types.StateAvailable)}},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describing vpc endpoints for %s, %w", vpcID, err)
+		}
+		serviceNames = append(serviceNames, lo.Map(page.VpcEndpoints, func(e ec2types.VpcEndpoint, _ int) string {
+			return lo.FromPtr(e.ServiceName)
+		})...)
+	}
+	// Service names are prefixed by the reverse DNS name of the partition, e.g. com.amazonaws.us-west-2.ec2 or
+	// cn.com.amazonaws.cn-north-1.ec2, so they're matched by their region and service
+	missing := lo.Reject(RequiredServices, func(service string, _ int) bool {
+		return lo.ContainsBy(serviceNames, func(name string) bool { return strings.HasSuffix(name, fmt.Sprintf(".%s.%s", p.region, service)) })
+	})
+	p.cache.SetDefault(vpcID, missing)
+	return missing, nil
+}
@@ -0,0 +1,222 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
+)
+
+type EventType string
+
+const (
+	// EventTypePreDrain is sent when a NodeClaim begins terminating, before its Node is drained
+	EventTypePreDrain EventType = "PreDrain"
+	// EventTypePostTermination is sent once the NodeClaim's instance has been terminated
+	EventTypePostTermination EventType = "PostTermination"
+	// EventTypeLaunchValidation is sent to the launch validation webhook once a NodeClaim's Node has registered, before the
+	// NodeClaim is initialized
+	EventTypeLaunchValidation EventType = "LaunchValidation"
+	// EventTypeOfferings is sent to the offerings webhook with the available offerings of a NodePool's instance types when
+	// they're resolved for scheduling
+	EventTypeOfferings EventType = "Offerings"
+	// EventTypeInterruption is sent to the interruption webhook when an interruption message is received for a NodeClaim's
+	// instance
+	EventTypeInterruption EventType = "Interruption"
+)
+
+// Event is the JSON payload that's POSTed to the deprovisioning webhook
+type Event struct {
+	Type         EventType         `json:"type"`
+	Time         time.Time         `json:"time"`
+	NodeClaim    string            `json:"nodeClaim"`
+	Node         string            `json:"node,omitempty"`
+	NodePool     string            `json:"nodePool,omitempty"`
+	ProviderID   string            `json:"providerID,omitempty"`
+	InstanceType string            `json:"instanceType,omitempty"`
+	Zone         string            `json:"zone,omitempty"`
+	CapacityType string            `json:"capacityType,omitempty"`
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+func NewEvent(eventType EventType, nodeClaim *karpv1.NodeClaim, now time.Time) Event {
+	return Event{
+		Type:         eventType,
+		Time:         now.UTC(),
+		NodeClaim:    nodeClaim.Name,
+		Node:         nodeClaim.Status.NodeName,
+		NodePool:     nodeClaim.Labels[karpv1.NodePoolLabelKey],
+		ProviderID:   nodeClaim.Status.ProviderID,
+		InstanceType: nodeClaim.Labels[corev1.LabelInstanceTypeStable],
+		Zone:         nodeClaim.Labels[corev1.LabelTopologyZone],
+		CapacityType: nodeClaim.Labels[karpv1.CapacityTypeLabelKey],
+		Labels:       nodeClaim.Labels,
+	}
+}
+
+// InterruptionEvent is the JSON payload that's POSTed to the interruption webhook. Kind normalizes the AWS event that was
+// received, and Deadline is the time by which the instance is expected to go away, if the event specifies one.
+type InterruptionEvent struct {
+	Event
+	Kind       string     `json:"kind"`
+	InstanceID string     `json:"instanceID"`
+	Action     string     `json:"action"`
+	Deadline   *time.Time `json:"deadline,omitempty"`
+}
+
+// ValidationResponse is the JSON body that the launch validation webhook responds with
+type ValidationResponse struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// OfferingsRequest is the JSON payload that's POSTed to the offerings webhook
+type OfferingsRequest struct {
+	Type      EventType  `json:"type"`
+	Time      time.Time  `json:"time"`
+	NodePool  string     `json:"nodePool"`
+	NodeClass string     `json:"nodeClass,omitempty"`
+	Offerings []Offering `json:"offerings"`
+}
+
+type Offering struct {
+	InstanceType string  `json:"instanceType"`
+	Zone         string  `json:"zone"`
+	CapacityType string  `json:"capacityType"`
+	Price        float64 `json:"price"`
+}
+
+// OfferingsResponse is the JSON body that the offerings webhook responds with. Offerings that are omitted from the response
+// aren't launched, and the prices of the returned offerings replace the prices that Karpenter resolved.
+type OfferingsResponse struct {
+	Offerings []Offering `json:"offerings"`
+}
+
+type Provider interface {
+	// Send delivers the event to the webhook, returning an error if the webhook couldn't be reached or responded with a
+	// non-2xx status code
+	Send(context.Context, Event) error
+}
+
+type InterruptionNotifier interface {
+	// NotifyInterruption delivers the interruption event to the interruption webhook, returning an error if the webhook
+	// couldn't be reached or responded with a non-2xx status code
+	NotifyInterruption(context.Context, InterruptionEvent) error
+}
+
+type Validator interface {
+	// Validate sends the event to the launch validation webhook and returns its response, returning an error if the webhook
+	// couldn't be reached, responded with a non-2xx status code or its response couldn't be decoded
+	Validate(context.Context, Event) (ValidationResponse, error)
+}
+
+type OfferingsAdjuster interface {
+	// AdjustOfferings sends the offerings to the offerings webhook and returns its response, returning an error if the
+	// webhook couldn't be reached, responded with a non-2xx status code or its response couldn't be decoded
+	AdjustOfferings(context.Context, OfferingsRequest) (OfferingsResponse, error)
+}
+
+type DefaultProvider struct {
+	url    string
+	client *http.Client
+}
+
+func NewDefaultProvider(url string, timeout time.Duration) *DefaultProvider {
+	return &DefaultProvider{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *DefaultProvider) Send(ctx context.Context, event Event) error {
+	resp, err := p.post(ctx, event.Type, event)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (p *DefaultProvider) NotifyInterruption(ctx context.Context, event InterruptionEvent) error {
+	resp, err := p.post(ctx, event.Type, event)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (p *DefaultProvider) Validate(ctx context.Context, event Event) (ValidationResponse, error) {
+	resp, err := p.post(ctx, event.Type, event)
+	if err != nil {
+		return ValidationResponse{}, err
+	}
+	defer resp.Body.Close()
+	response := ValidationResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return ValidationResponse{}, fmt.Errorf("decoding %s response, %w", event.Type, err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return response, nil
+}
+
+func (p *DefaultProvider) AdjustOfferings(ctx context.Context, request OfferingsRequest) (OfferingsResponse, error) {
+	resp, err := p.post(ctx, request.Type, request)
+	if err != nil {
+		return OfferingsResponse{}, err
+	}
+	defer resp.Body.Close()
+	response := OfferingsResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return OfferingsResponse{}, fmt.Errorf("decoding %s response, %w", request.Type, err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return response, nil
+}
+
+// post sends the payload to the webhook, returning the response if the webhook responded with a 2xx status code. The caller
+// is responsible for closing the response body.
+func (p *DefaultProvider) post(ctx context.Context, eventType EventType, payload any) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling %s event, %w", eventType, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating webhook request, %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending %s event, %w", eventType, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("sending %s event, webhook responded with status %d", eventType, resp.StatusCode)
+	}
+	return resp, nil
+}
@@ -19,6 +19,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/patrickmn/go-cache"
 	"github.com/samber/lo"
 	corev1 "k8s.io/api/core/v1"
@@ -27,18 +28,29 @@ import (
 	karpv1 "sigs.k8s.io/karpenter/pkg/apis/v1"
 
 	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
+	sdk "github.com/aws/karpenter-provider-aws/pkg/aws"
 	awscache "github.com/aws/karpenter-provider-aws/pkg/cache"
 	"github.com/aws/karpenter-provider-aws/pkg/fake"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/accessentry"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/amifamily"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/amiprovenance"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/capacityreservation"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/carbonintensity"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/elasticip"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instance"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instanceprofile"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/instancetype"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/kms"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/launchrole"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/launchtemplate"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/leakedresource"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/pricing"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/quota"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/securitygroup"
 	ssmp "github.com/aws/karpenter-provider-aws/pkg/providers/ssm"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/subnet"
 	"github.com/aws/karpenter-provider-aws/pkg/providers/version"
+	"github.com/aws/karpenter-provider-aws/pkg/providers/vpcendpoint"
 
 	coretest "sigs.k8s.io/karpenter/pkg/test"
 
@@ -55,37 +67,56 @@ type Environment struct {
 	Clock *clock.FakeClock
 
 	// API
-	EC2API     *fake.EC2API
-	EKSAPI     *fake.EKSAPI
-	SSMAPI     *fake.SSMAPI
-	IAMAPI     *fake.IAMAPI
-	PricingAPI *fake.PricingAPI
+	EC2API           *fake.EC2API
+	EKSAPI           *fake.EKSAPI
+	SSMAPI           *fake.SSMAPI
+	IAMAPI           *fake.IAMAPI
+	PricingAPI       *fake.PricingAPI
+	ServiceQuotasAPI *fake.ServiceQuotasAPI
+	KMSAPI           *fake.KMSAPI
+	STSAPI           *fake.STSAPI
 
 	// Cache
 	EC2Cache                      *cache.Cache
 	InstanceTypeCache             *cache.Cache
 	UnavailableOfferingsCache     *awscache.UnavailableOfferings
 	LaunchTemplateCache           *cache.Cache
+	UserDataSecretCache           *cache.Cache
 	SubnetCache                   *cache.Cache
 	AvailableIPAdressCache        *cache.Cache
 	AssociatePublicIPAddressCache *cache.Cache
 	SecurityGroupCache            *cache.Cache
 	InstanceProfileCache          *cache.Cache
 	SSMCache                      *cache.Cache
+	KMSCache                      *cache.Cache
+	VPCEndpointCache              *cache.Cache
+	AccessEntryCache              *cache.Cache
+	LaunchRoleCache               *cache.Cache
 	DiscoveredCapacityCache       *cache.Cache
+	AMIProvenanceCache            *cache.Cache
 
 	// Providers
-	InstanceTypesResolver   *instancetype.DefaultResolver
-	InstanceTypesProvider   *instancetype.DefaultProvider
-	InstanceProvider        *instance.DefaultProvider
-	SubnetProvider          *subnet.DefaultProvider
-	SecurityGroupProvider   *securitygroup.DefaultProvider
-	InstanceProfileProvider *instanceprofile.DefaultProvider
-	PricingProvider         *pricing.DefaultProvider
-	AMIProvider             *amifamily.DefaultProvider
-	AMIResolver             *amifamily.DefaultResolver
-	VersionProvider         *version.DefaultProvider
-	LaunchTemplateProvider  *launchtemplate.DefaultProvider
+	InstanceTypesResolver       *instancetype.DefaultResolver
+	InstanceTypesProvider       *instancetype.DefaultProvider
+	InstanceProvider            *instance.DefaultProvider
+	SubnetProvider              *subnet.DefaultProvider
+	SecurityGroupProvider       *securitygroup.DefaultProvider
+	InstanceProfileProvider     *instanceprofile.DefaultProvider
+	PricingProvider             *pricing.DefaultProvider
+	QuotaProvider               *quota.DefaultProvider
+	CarbonIntensityProvider     *carbonintensity.DefaultProvider
+	ElasticIPProvider           *elasticip.DefaultProvider
+	LeakedResourceProvider      *leakedresource.DefaultProvider
+	KMSProvider                 *kms.DefaultProvider
+	CapacityReservationProvider *capacityreservation.DefaultProvider
+	VPCEndpointProvider         *vpcendpoint.DefaultProvider
+	AccessEntryProvider         *accessentry.DefaultProvider
+	LaunchRoleProvider          *launchrole.DefaultProvider
+	AMIProvider                 *amifamily.DefaultProvider
+	AMIResolver                 *amifamily.DefaultResolver
+	AMIProvenanceProvider       *amiprovenance.DefaultProvider
+	VersionProvider             *version.DefaultProvider
+	LaunchTemplateProvider      *launchtemplate.DefaultProvider
 }
 
 func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment {
@@ -97,6 +128,9 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 	eksapi := fake.NewEKSAPI()
 	ssmapi := fake.NewSSMAPI()
 	iamapi := fake.NewIAMAPI()
+	servicequotasapi := fake.NewServiceQuotasAPI()
+	kmsapi := fake.NewKMSAPI()
+	stsapi := fake.NewSTSAPI()
 
 	// cache
 	ec2Cache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
@@ -104,16 +138,28 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 	discoveredCapacityCache := cache.New(awscache.DiscoveredCapacityCacheTTL, awscache.DefaultCleanupInterval)
 	unavailableOfferingsCache := awscache.NewUnavailableOfferings()
 	launchTemplateCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	userDataSecretCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	subnetCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	availableIPAdressCache := cache.New(awscache.AvailableIPAddressTTL, awscache.DefaultCleanupInterval)
 	associatePublicIPAddressCache := cache.New(awscache.AssociatePublicIPAddressTTL, awscache.DefaultCleanupInterval)
 	securityGroupCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	instanceProfileCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
 	ssmCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	kmsCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	vpcEndpointCache := cache.New(awscache.DefaultTTL, awscache.DefaultCleanupInterval)
+	accessEntryCache := cache.New(awscache.InstanceProfileTTL, awscache.DefaultCleanupInterval)
+	launchRoleCache := cache.New(awscache.LaunchRoleSessionTTL, awscache.DefaultCleanupInterval)
+	amiProvenanceCache := cache.New(awscache.AMIProvenanceTTL, awscache.DefaultCleanupInterval)
 	fakePricingAPI := &fake.PricingAPI{}
 
 	// Providers
 	pricingProvider := pricing.NewDefaultProvider(ctx, fakePricingAPI, ec2api, fake.DefaultRegion)
+	quotaProvider := quota.NewDefaultProvider(clock, ec2api, servicequotasapi, nil)
+	elasticIPProvider := elasticip.NewDefaultProvider(ec2api)
+	leakedResourceProvider := leakedresource.NewDefaultProvider(clock, ec2api)
+	capacityReservationProvider := capacityreservation.NewDefaultProvider(ec2api)
+	vpcEndpointProvider := vpcendpoint.NewDefaultProvider(fake.DefaultRegion, ec2api, vpcEndpointCache)
+	kmsProvider := kms.NewDefaultProvider(kmsapi, kmsCache)
 	subnetProvider := subnet.NewDefaultProvider(ec2api, subnetCache, availableIPAdressCache, associatePublicIPAddressCache)
 	securityGroupProvider := securitygroup.NewDefaultProvider(ec2api, securityGroupCache)
 	versionProvider := version.NewDefaultProvider(env.KubernetesInterface, eksapi)
@@ -121,47 +167,62 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 	// Version updates are hydrated asynchronously after this, in the event of a failure
 	// the previously resolved value will be used.
 	lo.Must0(versionProvider.UpdateVersion(ctx))
-	instanceProfileProvider := instanceprofile.NewDefaultProvider(fake.DefaultRegion, iamapi, instanceProfileCache)
+	instanceProfileProvider := instanceprofile.NewDefaultProvider(clock, fake.DefaultRegion, iamapi, instanceProfileCache)
+	accessEntryProvider := accessentry.NewDefaultProvider(eksapi, iamapi, env.KubernetesInterface, accessEntryCache)
 	ssmProvider := ssmp.NewDefaultProvider(ssmapi, ssmCache)
 	amiProvider := amifamily.NewDefaultProvider(clock, versionProvider, ssmProvider, ec2api, ec2Cache)
 	amiResolver := amifamily.NewDefaultResolver()
-	instanceTypesResolver := instancetype.NewDefaultResolver(fake.DefaultRegion, pricingProvider, unavailableOfferingsCache)
+	amiProvenanceProvider := amiprovenance.NewDefaultProvider(ec2api, ssmapi, amiProvenanceCache)
+	carbonIntensityProvider := carbonintensity.NewDefaultProvider(ssmapi, fake.DefaultRegion)
+	instanceTypesResolver := instancetype.NewDefaultResolver(fake.DefaultRegion, pricingProvider, unavailableOfferingsCache, quotaProvider)
 	instanceTypesProvider := instancetype.NewDefaultProvider(instanceTypeCache, discoveredCapacityCache, ec2api, subnetProvider, instanceTypesResolver)
 	launchTemplateProvider :=
 		launchtemplate.NewDefaultProvider(
 			ctx,
+			clock,
 			launchTemplateCache,
 			ec2api,
 			eksapi,
 			amiResolver,
 			securityGroupProvider,
 			subnetProvider,
+			env.KubernetesInterface,
+			userDataSecretCache,
 			lo.ToPtr("ca-bundle"),
 			make(chan struct{}),
 			net.ParseIP("10.0.100.10"),
 			"https://test-cluster",
 		)
+	// Launch role sessions share the fake EC2 API, so tests can only observe the role sessions through the fake STS API
+	launchRoleProvider := launchrole.NewDefaultProvider(aws.Config{}, stsapi, func(aws.Config) sdk.EC2API { return ec2api }, launchRoleCache)
 	instanceProvider :=
 		instance.NewDefaultProvider(ctx,
 			"",
 			ec2api,
+			ssmapi,
 			unavailableOfferingsCache,
 			subnetProvider,
 			launchTemplateProvider,
+			launchRoleProvider,
+			quotaProvider,
 		)
 
 	return &Environment{
 		Clock: clock,
 
-		EC2API:     ec2api,
-		EKSAPI:     eksapi,
-		SSMAPI:     ssmapi,
-		IAMAPI:     iamapi,
-		PricingAPI: fakePricingAPI,
+		EC2API:           ec2api,
+		EKSAPI:           eksapi,
+		SSMAPI:           ssmapi,
+		IAMAPI:           iamapi,
+		PricingAPI:       fakePricingAPI,
+		ServiceQuotasAPI: servicequotasapi,
+		KMSAPI:           kmsapi,
+		STSAPI:           stsapi,
 
 		EC2Cache:                      ec2Cache,
 		InstanceTypeCache:             instanceTypeCache,
 		LaunchTemplateCache:           launchTemplateCache,
+		UserDataSecretCache:           userDataSecretCache,
 		SubnetCache:                   subnetCache,
 		AvailableIPAdressCache:        availableIPAdressCache,
 		AssociatePublicIPAddressCache: associatePublicIPAddressCache,
@@ -169,19 +230,34 @@ func NewEnvironment(ctx context.Context, env *coretest.Environment) *Environment
 		InstanceProfileCache:          instanceProfileCache,
 		UnavailableOfferingsCache:     unavailableOfferingsCache,
 		SSMCache:                      ssmCache,
+		KMSCache:                      kmsCache,
+		VPCEndpointCache:              vpcEndpointCache,
+		AccessEntryCache:              accessEntryCache,
+		LaunchRoleCache:               launchRoleCache,
 		DiscoveredCapacityCache:       discoveredCapacityCache,
+		AMIProvenanceCache:            amiProvenanceCache,
 
-		InstanceTypesResolver:   instanceTypesResolver,
-		InstanceTypesProvider:   instanceTypesProvider,
-		InstanceProvider:        instanceProvider,
-		SubnetProvider:          subnetProvider,
-		SecurityGroupProvider:   securityGroupProvider,
-		LaunchTemplateProvider:  launchTemplateProvider,
-		InstanceProfileProvider: instanceProfileProvider,
-		PricingProvider:         pricingProvider,
-		AMIProvider:             amiProvider,
-		AMIResolver:             amiResolver,
-		VersionProvider:         versionProvider,
+		InstanceTypesResolver:       instanceTypesResolver,
+		InstanceTypesProvider:       instanceTypesProvider,
+		InstanceProvider:            instanceProvider,
+		SubnetProvider:              subnetProvider,
+		SecurityGroupProvider:       securityGroupProvider,
+		LaunchTemplateProvider:      launchTemplateProvider,
+		InstanceProfileProvider:     instanceProfileProvider,
+		PricingProvider:             pricingProvider,
+		QuotaProvider:               quotaProvider,
+		CarbonIntensityProvider:     carbonIntensityProvider,
+		ElasticIPProvider:           elasticIPProvider,
+		LeakedResourceProvider:      leakedResourceProvider,
+		KMSProvider:                 kmsProvider,
+		CapacityReservationProvider: capacityReservationProvider,
+		VPCEndpointProvider:         vpcEndpointProvider,
+		AccessEntryProvider:         accessEntryProvider,
+		LaunchRoleProvider:          launchRoleProvider,
+		AMIProvider:                 amiProvider,
+		AMIResolver:                 amiResolver,
+		AMIProvenanceProvider:       amiProvenanceProvider,
+		VersionProvider:             versionProvider,
 	}
 }
 
@@ -193,18 +269,30 @@ func (env *Environment) Reset() {
 	env.IAMAPI.Reset()
 	env.PricingAPI.Reset()
 	env.PricingProvider.Reset()
+	env.ServiceQuotasAPI.Reset()
+	env.KMSAPI.Reset()
+	env.STSAPI.Reset()
+	env.QuotaProvider.Reset()
+	env.LeakedResourceProvider.Reset()
+	env.CarbonIntensityProvider.Reset()
 	env.InstanceTypesProvider.Reset()
 
 	env.EC2Cache.Flush()
 	env.UnavailableOfferingsCache.Flush()
 	env.LaunchTemplateCache.Flush()
+	env.UserDataSecretCache.Flush()
 	env.SubnetCache.Flush()
 	env.AssociatePublicIPAddressCache.Flush()
 	env.AvailableIPAdressCache.Flush()
 	env.SecurityGroupCache.Flush()
 	env.InstanceProfileCache.Flush()
 	env.SSMCache.Flush()
+	env.KMSCache.Flush()
+	env.VPCEndpointCache.Flush()
+	env.AccessEntryCache.Flush()
+	env.LaunchRoleCache.Flush()
 	env.DiscoveredCapacityCache.Flush()
+	env.AMIProvenanceCache.Flush()
 	mfs, err := crmetrics.Registry.Gather()
 	if err != nil {
 		for _, mf := range mfs {
@@ -16,6 +16,7 @@ package test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/imdario/mergo"
 	"github.com/samber/lo"
@@ -30,8 +31,67 @@ type OptionsFields struct {
 	IsolatedVPC             *bool
 	EKSControlPlane         *bool
 	VMMemoryOverheadPercent *float64
+	VMMemoryOverheads       *string
 	InterruptionQueue       *string
+	InterruptionDLQ         *string
 	ReservedENIs            *int
+	VCPUQuotaAwareness      *bool
+	RegistrationRebootAfter *time.Duration
+	LaunchTemplateGCTTL     *time.Duration
+	LeakedResourceGCTTL     *time.Duration
+	LeakedResourceGCDryRun  *bool
+	ScheduledChangeLeadTime *time.Duration
+	MaxNodePinDuration      *time.Duration
+	BillingBoundaryWindow   *time.Duration
+	ZonalShift              *bool
+	InterruptionTaints      *bool
+	InterruptionPDBOverride *bool
+
+	RequireEncryptedRootVolumes *bool
+	StoppedInstancePolicy       *string
+	ForbidKeyPairs              *bool
+
+	AdditionalInterruptionQueues *string
+
+	DeprovisioningWebhookURL           *string
+	DeprovisioningWebhookTimeout       *time.Duration
+	DeprovisioningWebhookFailurePolicy *string
+
+	LaunchValidationWebhookURL *string
+	LaunchValidationTimeout    *time.Duration
+	OfferingsWebhookURL        *string
+	OfferingsWebhookTimeout    *time.Duration
+	InterruptionWebhookURL     *string
+	ResourceNamePrefix         *string
+	CapacityLedgerKubeconfig   *string
+	CapacityLedgerNamespace    *string
+	FaultInjectionErrorPercent *float64
+	FaultInjectionDelayPercent *float64
+	FaultInjectionDelay        *time.Duration
+	FaultInjectionServices     *string
+	MaxKubeletVersionSkew      *int
+	StuckPodPolicy             *string
+	StuckPodFinalizers         *string
+	StuckPodTimeout            *time.Duration
+	ReadinessDaemonSets        *string
+
+	TrustedAMIsParameter *string
+	TrustedAMIKMSKeyARN  *string
+
+	CarbonIntensityParameter *string
+	CarbonIntensityWeight    *float64
+
+	DebugEndpointToken *string
+
+	AWSHTTPSProxy           *string
+	AWSNoProxy              *string
+	AWSCustomCABundle       *string
+	FIPSEndpoints           *bool
+	ManageNodeAccessEntries *bool
+
+	InstanceProfilePropagationDelay *time.Duration
+
+	AWSFeatureGates options.FeatureGates
 }
 
 func Options(overrides ...OptionsFields) *options.Options {
@@ -48,7 +108,73 @@ func Options(overrides ...OptionsFields) *options.Options {
 		IsolatedVPC:             lo.FromPtrOr(opts.IsolatedVPC, false),
 		EKSControlPlane:         lo.FromPtrOr(opts.EKSControlPlane, false),
 		VMMemoryOverheadPercent: lo.FromPtrOr(opts.VMMemoryOverheadPercent, 0.075),
+		VMMemoryOverheads:       lo.FromPtrOr(opts.VMMemoryOverheads, ""),
 		InterruptionQueue:       lo.FromPtrOr(opts.InterruptionQueue, ""),
+		InterruptionDLQ:         lo.FromPtrOr(opts.InterruptionDLQ, ""),
 		ReservedENIs:            lo.FromPtrOr(opts.ReservedENIs, 0),
+		VCPUQuotaAwareness:      lo.FromPtrOr(opts.VCPUQuotaAwareness, false),
+		RegistrationRebootAfter: lo.FromPtrOr(opts.RegistrationRebootAfter, 0),
+		LaunchTemplateGCTTL:     lo.FromPtrOr(opts.LaunchTemplateGCTTL, 0),
+		LeakedResourceGCTTL:     lo.FromPtrOr(opts.LeakedResourceGCTTL, 0),
+		LeakedResourceGCDryRun:  lo.FromPtrOr(opts.LeakedResourceGCDryRun, false),
+		ScheduledChangeLeadTime: lo.FromPtrOr(opts.ScheduledChangeLeadTime, 0),
+		MaxNodePinDuration:      lo.FromPtrOr(opts.MaxNodePinDuration, 24*time.Hour),
+		BillingBoundaryWindow:   lo.FromPtrOr(opts.BillingBoundaryWindow, 5*time.Minute),
+		ZonalShift:              lo.FromPtrOr(opts.ZonalShift, false),
+		InterruptionTaints:      lo.FromPtrOr(opts.InterruptionTaints, false),
+		InterruptionPDBOverride: lo.FromPtrOr(opts.InterruptionPDBOverride, false),
+
+		RequireEncryptedRootVolumes: lo.FromPtrOr(opts.RequireEncryptedRootVolumes, false),
+		StoppedInstancePolicy:       lo.FromPtrOr(opts.StoppedInstancePolicy, string(options.StoppedInstancePolicyIgnore)),
+		ForbidKeyPairs:              lo.FromPtrOr(opts.ForbidKeyPairs, false),
+
+		AdditionalInterruptionQueues: lo.FromPtrOr(opts.AdditionalInterruptionQueues, ""),
+
+		DeprovisioningWebhookURL:           lo.FromPtrOr(opts.DeprovisioningWebhookURL, ""),
+		DeprovisioningWebhookTimeout:       lo.FromPtrOr(opts.DeprovisioningWebhookTimeout, 10*time.Second),
+		DeprovisioningWebhookFailurePolicy: lo.FromPtrOr(opts.DeprovisioningWebhookFailurePolicy, string(options.DeprovisioningWebhookFailurePolicyIgnore)),
+
+		LaunchValidationWebhookURL: lo.FromPtrOr(opts.LaunchValidationWebhookURL, ""),
+		LaunchValidationTimeout:    lo.FromPtrOr(opts.LaunchValidationTimeout, 5*time.Minute),
+
+		OfferingsWebhookURL:     lo.FromPtrOr(opts.OfferingsWebhookURL, ""),
+		OfferingsWebhookTimeout: lo.FromPtrOr(opts.OfferingsWebhookTimeout, 5*time.Second),
+
+		InterruptionWebhookURL: lo.FromPtrOr(opts.InterruptionWebhookURL, ""),
+		ResourceNamePrefix:     lo.FromPtrOr(opts.ResourceNamePrefix, ""),
+
+		CapacityLedgerKubeconfig: lo.FromPtrOr(opts.CapacityLedgerKubeconfig, ""),
+		CapacityLedgerNamespace:  lo.FromPtrOr(opts.CapacityLedgerNamespace, "karpenter"),
+
+		FaultInjectionErrorPercent: lo.FromPtrOr(opts.FaultInjectionErrorPercent, 0),
+		FaultInjectionDelayPercent: lo.FromPtrOr(opts.FaultInjectionDelayPercent, 0),
+		FaultInjectionDelay:        lo.FromPtrOr(opts.FaultInjectionDelay, 5*time.Second),
+		FaultInjectionServices:     lo.FromPtrOr(opts.FaultInjectionServices, "ec2,pricing,sqs"),
+
+		MaxKubeletVersionSkew: lo.FromPtrOr(opts.MaxKubeletVersionSkew, 3),
+
+		StuckPodPolicy:     lo.FromPtrOr(opts.StuckPodPolicy, string(options.StuckPodPolicyIgnore)),
+		StuckPodFinalizers: lo.FromPtrOr(opts.StuckPodFinalizers, ""),
+		StuckPodTimeout:    lo.FromPtrOr(opts.StuckPodTimeout, 10*time.Minute),
+
+		ReadinessDaemonSets: lo.FromPtrOr(opts.ReadinessDaemonSets, "kube-system/aws-node,kube-system/ebs-csi-node,kube-system/kube-proxy"),
+
+		TrustedAMIsParameter: lo.FromPtrOr(opts.TrustedAMIsParameter, ""),
+		TrustedAMIKMSKeyARN:  lo.FromPtrOr(opts.TrustedAMIKMSKeyARN, ""),
+
+		CarbonIntensityParameter: lo.FromPtrOr(opts.CarbonIntensityParameter, ""),
+		CarbonIntensityWeight:    lo.FromPtrOr(opts.CarbonIntensityWeight, 0.5),
+
+		DebugEndpointToken: lo.FromPtrOr(opts.DebugEndpointToken, ""),
+
+		AWSHTTPSProxy:           lo.FromPtrOr(opts.AWSHTTPSProxy, ""),
+		AWSNoProxy:              lo.FromPtrOr(opts.AWSNoProxy, ""),
+		AWSCustomCABundle:       lo.FromPtrOr(opts.AWSCustomCABundle, ""),
+		FIPSEndpoints:           lo.FromPtrOr(opts.FIPSEndpoints, false),
+		ManageNodeAccessEntries: lo.FromPtrOr(opts.ManageNodeAccessEntries, false),
+
+		InstanceProfilePropagationDelay: lo.FromPtrOr(opts.InstanceProfilePropagationDelay, 0),
+
+		AWSFeatureGates: lo.Ternary(opts.AWSFeatureGates != nil, opts.AWSFeatureGates, options.FeatureGates{}),
 	}
 }
@@ -25,6 +25,9 @@ import (
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 
 	"github.com/samber/lo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/aws/karpenter-provider-aws/pkg/apis/v1"
 )
 
 var (
@@ -83,3 +86,10 @@ func WithDefaultFloat64(key string, def float64) float64 {
 	}
 	return f
 }
+
+// IsPaused returns true if any of the passed objects has the karpenter.sh/paused annotation set to "true"
+func IsPaused(objs ...metav1.Object) bool {
+	return lo.ContainsBy(objs, func(o metav1.Object) bool {
+		return o != nil && o.GetAnnotations()[v1.AnnotationPaused] == "true"
+	})
+}